@@ -14,7 +14,12 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"GusSync/app/services"
+	"GusSync/internal/crash"
 	"GusSync/internal/adapters/api"
+	"GusSync/internal/adapters/grpcapi"
+	"GusSync/pkg/controlpb"
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
 )
 
 //go:embed all:frontend_dist
@@ -29,13 +34,24 @@ type App struct {
 	verifyService  *services.VerifyService
 	cleanupService *services.CleanupService
 	logService     *services.LogService
+	catalogService *services.CatalogService
+	restoreService *services.RestoreService
 	jobManager     *services.JobManager
 	systemService  *services.SystemService
+	events         *engine.EventBus // per-file events of backups and verifications
 	configService  *services.ConfigService
 	apiServer      *api.Server
+	grpcServer     *grpcapi.Server
+	crashHandler   *crash.Handler
+	startupGuard   *crash.StartupGuard
+	safeMode       bool
+	unstableStarts int
 	logger         *log.Logger
 }
 
+// stableAfter is how long the app must run before a startup counts as successful
+const stableAfter = 30 * time.Second
+
 // NewApp creates a new App instance
 func NewApp() *App {
 	// Create logger
@@ -66,8 +82,12 @@ func (a *App) OnStartup(ctx context.Context) {
 		logger.Printf("[TIMING %s] [App] OnStartup: Config service initialized (took %v)", time.Now().Format("2006-01-02 15:04:05.000"), configDuration)
 	}
 
-	// Restore window geometry if available
-	if a.configService != nil {
+	if a.safeMode {
+		logger.Printf("[App] OnStartup: SAFE MODE - %d previous startups did not complete; skipping cached window state, device polling, auto prereq checks and API server", a.unstableStarts)
+	}
+
+	// Restore window geometry if available (skipped in safe mode - bad geometry can hide the window)
+	if a.configService != nil && !a.safeMode {
 		cfg := a.configService.GetConfig()
 		if cfg.WindowWidth > 0 && cfg.WindowHeight > 0 {
 			logger.Printf("[App] OnStartup: Restoring window geometry: %dx%d at (%d,%d)", cfg.WindowWidth, cfg.WindowHeight, cfg.WindowX, cfg.WindowY)
@@ -88,6 +108,9 @@ func (a *App) OnStartup(ctx context.Context) {
 	// Instead, we call SetContext() on each existing instance.
 	serviceStart := time.Now()
 	a.jobManager.SetContext(ctx)
+	if configService != nil {
+		a.jobManager.SetMaxConcurrentJobs(configService.GetConfig().MaxConcurrentJobs)
+	}
 	jobDuration := time.Since(serviceStart)
 	logger.Printf("[TIMING %s] [App] OnStartup: JobManager context updated (took %v)", time.Now().Format("2006-01-02 15:04:05.000"), jobDuration)
 	
@@ -123,15 +146,42 @@ func (a *App) OnStartup(ctx context.Context) {
 	a.logService.SetContext(ctx)
 	logDuration := time.Since(logStart)
 	logger.Printf("[TIMING %s] [App] OnStartup: LogService context updated (took %v)", time.Now().Format("2006-01-02 15:04:05.000"), logDuration)
+	a.catalogService.SetContext(ctx)
+	a.restoreService.SetContext(ctx)
 
 	systemStart := time.Now()
 	a.systemService.SetContext(ctx)
+	if configService != nil {
+		cfg := configService.GetConfig()
+		a.crashHandler.SetUpload(cfg.CrashUploadEnabled, cfg.CrashUploadURL)
+	}
+	a.systemService.SetCrashHandler(a.crashHandler, configService)
+	a.systemService.SetSafeMode(a.safeMode, a.unstableStarts, a.startupGuard)
 	systemDuration := time.Since(systemStart)
 	logger.Printf("[TIMING %s] [App] OnStartup: SystemService context updated (took %v)", time.Now().Format("2006-01-02 15:04:05.000"), systemDuration)
 
 	totalServiceDuration := time.Since(startTime)
 	logger.Printf("[TIMING %s] [App] OnStartup: All services updated (took %v total)", time.Now().Format("2006-01-02 15:04:05.000"), totalServiceDuration)
 
+	if a.safeMode {
+		// Only the UI, settings and diagnostics are available in safe mode
+		runtime.EventsEmit(ctx, "app:safe-mode", a.systemService.GetSafeModeStatus())
+		totalDuration := time.Since(startTime)
+		logger.Printf("[TIMING %s] [App] OnStartup: EXIT (safe mode) - Total startup time: %v", time.Now().Format("2006-01-02 15:04:05.000"), totalDuration)
+		return
+	}
+
+	// Count this startup as successful once the app has been running for a while
+	go func() {
+		select {
+		case <-time.After(stableAfter):
+			if err := a.startupGuard.MarkStable(); err != nil {
+				logger.Printf("[App] OnStartup: Failed to reset startup counter: %v", err)
+			}
+		case <-ctx.Done():
+		}
+	}()
+
 	// Start device polling for immediate UI updates when phone is plugged in
 	a.deviceService.StartPolling(ctx)
 
@@ -149,7 +199,9 @@ func (a *App) OnStartup(ctx context.Context) {
 	go a.monitorWindowPosition(ctx)
 
 	// Start API server if enabled via environment variable
-	// Set GUSSYNC_API_PORT to enable (e.g., GUSSYNC_API_PORT=8080)
+	// Set GUSSYNC_API_PORT to enable (e.g., GUSSYNC_API_PORT=8080);
+	// GUSSYNC_API_DASHBOARD=1 also serves the web dashboard at http://host:port/.
+	// It listens on localhost only unless GUSSYNC_API_HOST is set (e.g., GUSSYNC_API_HOST=0.0.0.0)
 	if apiPort := os.Getenv("GUSSYNC_API_PORT"); apiPort != "" {
 		port, err := strconv.Atoi(apiPort)
 		if err != nil {
@@ -159,6 +211,16 @@ func (a *App) OnStartup(ctx context.Context) {
 		}
 	}
 
+	// Start gRPC control server if enabled (e.g., GUSSYNC_GRPC_PORT=8091)
+	if grpcPort := os.Getenv("GUSSYNC_GRPC_PORT"); grpcPort != "" {
+		port, err := strconv.Atoi(grpcPort)
+		if err != nil {
+			logger.Printf("[App] OnStartup: Invalid gRPC port '%s': %v", grpcPort, err)
+		} else {
+			a.startGRPCServer(ctx, port, logger)
+		}
+	}
+
 	totalDuration := time.Since(startTime)
 	logger.Printf("[TIMING %s] [App] OnStartup: EXIT - Total startup time: %v", time.Now().Format("2006-01-02 15:04:05.000"), totalDuration)
 }
@@ -191,11 +253,9 @@ func (a *App) OnBeforeClose(ctx context.Context) bool {
 func (a *App) OnShutdown(ctx context.Context) {
 	a.logger.Printf("[App] OnShutdown: Shutting down...")
 
-	// Cancel any running jobs
+	// Cancel any running and queued jobs
 	if a.jobManager != nil {
-		if err := a.jobManager.CancelJob(); err != nil {
-			a.logger.Printf("[App] OnShutdown: Error cancelling job: %v", err)
-		}
+		a.jobManager.CancelAllTasks()
 	}
 
 	a.logger.Printf("[App] OnShutdown: Shutdown complete")
@@ -208,7 +268,7 @@ func (a *App) startAPIServer(ctx context.Context, port int, logger *log.Logger)
 	// Create the API server with the core job manager
 	coreJobManager := a.jobManager.GetCoreJobManager()
 
-	a.apiServer = api.NewServer(port, logger, coreJobManager,
+	opts := []api.ServerOption{
 		// Provider for prerequisites
 		api.WithPrereqProvider(func() interface{} {
 			return a.prereqService.GetPrereqReport()
@@ -235,6 +295,62 @@ func (a *App) startAPIServer(ctx context.Context, port int, logger *log.Logger)
 			}
 			return nil
 		}),
+		// Provider for quarantined files
+		api.WithQuarantineProvider(func() (interface{}, error) {
+			if a.configService == nil {
+				return []services.QuarantineItem{}, nil
+			}
+			return a.verifyService.ListQuarantine(a.configService.GetConfig().DestinationPath)
+		}),
+		// Provider for the error summary
+		api.WithErrorsProvider(func() (interface{}, error) {
+			if a.configService == nil {
+				return []services.ModeErrorSummary{}, nil
+			}
+			return a.copyService.ErrorSummaries(a.configService.GetConfig().DestinationPath)
+		}),
+		// Provider for the backup catalog
+		api.WithCatalogProvider(func(req api.CatalogRequest) (interface{}, error) {
+			dest := ""
+			if a.configService != nil {
+				dest = a.configService.GetConfig().DestinationPath
+			}
+			return a.catalogService.BrowseBackup(dest, services.CatalogRequest{
+				Mode: req.Mode,
+				CatalogQuery: state.CatalogQuery{
+					Folder:     req.Folder,
+					Recursive:  req.Recursive,
+					Name:       req.Name,
+					Extensions: req.Extensions,
+					After:      req.After,
+					Before:     req.Before,
+					Offset:     req.Offset,
+					Limit:      req.Limit,
+				},
+			})
+		}),
+		// Provider for thumbnails in the backup browser
+		api.WithThumbnailProvider(func(hash string) (string, error) {
+			dest := ""
+			if a.configService != nil {
+				dest = a.configService.GetConfig().DestinationPath
+			}
+			return a.catalogService.ThumbnailPath(dest, hash)
+		}),
+		// Function to restore backed-up files to the device
+		api.WithRestoreFunc(func(reqCtx context.Context, req api.RestoreRequest) (string, error) {
+			dest := ""
+			if a.configService != nil {
+				dest = a.configService.GetConfig().DestinationPath
+			}
+			return a.restoreService.StartRestore(services.RestoreRequest{
+				DestPath:  dest,
+				Mode:      req.Mode,
+				Paths:     req.Paths,
+				Overwrite: req.Overwrite,
+				DryRun:    req.DryRun,
+			})
+		}),
 		// Function to start a copy operation
 		api.WithStartCopyFunc(func(reqCtx context.Context, req api.StartCopyRequest) (string, error) {
 			// Use config values if not provided in request
@@ -246,7 +362,27 @@ func (a *App) startAPIServer(ctx context.Context, port int, logger *log.Logger)
 			// Use default mode "smart"
 			return a.copyService.StartBackup("", dest, "smart")
 		}),
-	)
+	}
+	if a.events != nil {
+		// Per-file events for SSE clients that ask for them
+		opts = append(opts, api.WithEngineEvents(func(kinds []string, send func(kind string, data interface{})) func() {
+			eventKinds := make([]engine.EventKind, len(kinds))
+			for i, kind := range kinds {
+				eventKinds[i] = engine.EventKind(kind)
+			}
+			return a.events.Subscribe(func(ev engine.Event) {
+				send(string(ev.Kind), ev)
+			}, eventKinds...)
+		}))
+	}
+	if os.Getenv("GUSSYNC_API_DASHBOARD") == "1" {
+		opts = append(opts, api.WithDashboard())
+		logger.Printf("[App] Web dashboard enabled at http://localhost:%d/", port)
+	}
+	if host := os.Getenv("GUSSYNC_API_HOST"); host != "" {
+		opts = append(opts, api.WithListenHost(host))
+	}
+	a.apiServer = api.NewServer(port, logger, coreJobManager, opts...)
 
 	// Register the API server as an additional event emitter
 	// This allows SSE clients to receive job updates alongside the Wails UI
@@ -256,6 +392,39 @@ func (a *App) startAPIServer(ctx context.Context, port int, logger *log.Logger)
 	a.apiServer.StartBackground(ctx)
 }
 
+// startGRPCServer initializes and starts the gRPC control server
+func (a *App) startGRPCServer(ctx context.Context, port int, logger *log.Logger) {
+	logger.Printf("[App] Starting gRPC server on port %d", port)
+
+	coreJobManager := a.jobManager.GetCoreJobManager()
+	configuredDestination := func() string {
+		if a.configService != nil {
+			return a.configService.GetConfig().DestinationPath
+		}
+		return ""
+	}
+
+	a.grpcServer = grpcapi.NewServer(port, logger, coreJobManager,
+		grpcapi.WithStartBackupFunc(func(reqCtx context.Context, req *controlpb.StartBackupRequest) (string, error) {
+			dest := req.DestinationPath
+			if dest == "" {
+				dest = configuredDestination()
+			}
+			mode := req.Mode
+			if mode == "" {
+				mode = "smart"
+			}
+			return a.copyService.StartBackup(req.SourcePath, dest, mode)
+		}),
+		grpcapi.WithDestinationProvider(configuredDestination),
+	)
+	coreJobManager.AddEmitter(a.grpcServer)
+
+	if err := a.grpcServer.StartBackground(ctx); err != nil {
+		logger.Printf("[App] Failed to start gRPC server: %v", err)
+	}
+}
+
 // monitorWindowPosition watches for window position/size changes and saves them
 // This ensures the position is saved even if the app is killed unexpectedly
 func (a *App) monitorWindowPosition(ctx context.Context) {
@@ -344,6 +513,19 @@ func Run() error {
 
 	appInstance := NewApp()
 
+	// Crash reports are written locally; upload is enabled later from config if the user opted in
+	appInstance.crashHandler = crash.Install(crash.Options{})
+	defer crash.Recover("app")
+
+	// Detect crash loops: repeated startups that never became stable start in safe mode
+	appInstance.startupGuard = crash.DefaultStartupGuard("gui")
+	safeMode, unstable, err := appInstance.startupGuard.Begin()
+	if err != nil {
+		logger.Printf("[App] Run(): Startup guard unavailable: %v", err)
+	}
+	appInstance.safeMode = safeMode
+	appInstance.unstableStarts = unstable
+
 	// Create a temporary context for service initialization
 	// Services will be fully initialized in OnStartup
 	ctx := context.Background()
@@ -358,6 +540,8 @@ func Run() error {
 	verifyService := services.NewVerifyService(ctx, logger, jobManager, deviceService)
 	cleanupService := services.NewCleanupService(ctx, logger, jobManager, deviceService)
 	logService := services.NewLogService(ctx, logger)
+	catalogService := services.NewCatalogService(ctx, logger)
+	restoreService := services.NewRestoreService(ctx, logger, jobManager, deviceService)
 	systemService := services.NewSystemService(ctx, logger)
 	serviceInitDuration := time.Since(serviceInitStart)
 	logger.Printf("[TIMING %s] [App] Run(): Services pre-initialized (took %v)", time.Now().Format("2006-01-02 15:04:05.000"), serviceInitDuration)
@@ -370,13 +554,20 @@ func Run() error {
 	appInstance.verifyService = verifyService
 	appInstance.cleanupService = cleanupService
 	appInstance.logService = logService
+	appInstance.catalogService = catalogService
+	appInstance.restoreService = restoreService
 	appInstance.systemService = systemService
 
+	// One bus carries the engine's per-file events to the API's SSE clients
+	appInstance.events = engine.NewEventBus()
+	copyService.SetEvents(appInstance.events)
+	verifyService.SetEvents(appInstance.events)
+
 	wailsCallStart := time.Now()
 	logger.Printf("[TIMING %s] [App] Run(): About to call wails.Run() - initialization took %v so far", time.Now().Format("2006-01-02 15:04:05.000"), time.Since(appStartTime))
 	logger.Printf("[TIMING %s] [App] Run(): ⚠️  BLOCKING CALL ⚠️  - wails.Run() will block until frontend loads...", time.Now().Format("2006-01-02 15:04:05.000"))
 
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:  "GusSync",
 		Width:  1024,
 		Height: 768,
@@ -395,6 +586,8 @@ func Run() error {
 			verifyService,
 			cleanupService,
 			logService,
+			catalogService,
+			restoreService,
 			jobManager,
 			systemService,
 		},
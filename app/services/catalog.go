@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/state"
+)
+
+// CatalogService lets the GUI browse what is in a backup. Everything comes from the
+// backup's state file; the backed-up files themselves are not touched.
+type CatalogService struct {
+	ctx    context.Context
+	logger *log.Logger
+}
+
+// NewCatalogService creates a new CatalogService
+func NewCatalogService(ctx context.Context, logger *log.Logger) *CatalogService {
+	return &CatalogService{
+		ctx:    ctx,
+		logger: logger,
+	}
+}
+
+// SetContext updates the service context
+func (s *CatalogService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// CatalogRequest selects a page of a backup's catalog
+type CatalogRequest struct {
+	Mode string `json:"mode"` // "mount", "adb", ... ("" = the first backup found)
+	state.CatalogQuery
+}
+
+// CatalogResult is a page of a backup's catalog. File and folder paths are relative to
+// ModeDir.
+type CatalogResult struct {
+	Mode    string   `json:"mode"`
+	Modes   []string `json:"modes"` // all backups under the destination
+	ModeDir string   `json:"modeDir"`
+	state.CatalogPage
+}
+
+// BrowseBackup lists the backed-up files of a folder, or searches them, in the backup of
+// req.Mode under destPath. Without a backup it returns an empty result.
+func (s *CatalogService) BrowseBackup(destPath string, req CatalogRequest) (*CatalogResult, error) {
+	modes := gussync.DetectModes(destPath)
+	mode := req.Mode
+	if mode == "" && len(modes) > 0 {
+		mode = modes[0]
+	}
+	result := &CatalogResult{Mode: mode, Modes: append([]string{}, modes...)}
+	if !slices.Contains(modes, mode) {
+		if req.Mode != "" {
+			return nil, fmt.Errorf("no %s backup in %s", req.Mode, destPath)
+		}
+		result.CatalogPage = state.CatalogPage{Folders: []state.CatalogFolder{}, Files: []state.CatalogEntry{}}
+		return result, nil
+	}
+
+	stateManager, err := gussync.OpenState(destPath, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s state: %w", mode, err)
+	}
+	result.ModeDir = gussync.ModeDir(destPath, mode)
+	result.CatalogPage = stateManager.Catalog(req.CatalogQuery)
+	return result, nil
+}
+
+// ThumbnailPath returns the cached thumbnail (see gussync -thumbnails) of the file with the
+// given content hash from any backup under destPath, or an error wrapping os.ErrNotExist
+func (s *CatalogService) ThumbnailPath(destPath, hash string) (string, error) {
+	if _, err := hex.DecodeString(hash); err != nil || hash == "" {
+		return "", fmt.Errorf("invalid content hash %q", hash)
+	}
+	for _, mode := range gussync.DetectModes(destPath) {
+		path := engine.ThumbPath(gussync.ModeDir(destPath, mode), hash)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no thumbnail for %s: %w", hash, os.ErrNotExist)
+}
+
+// Thumbnail returns the cached thumbnail of a file as a data: URL for the GUI's <img>, or ""
+// if there is none
+func (s *CatalogService) Thumbnail(destPath, hash string) (string, error) {
+	path, err := s.ThumbnailPath(destPath, hash)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -1,13 +1,16 @@
 package services
 
 import (
-	"GusSync/pkg/engine"
-	"GusSync/pkg/state"
+	"GusSync/internal/crash"
+	"GusSync/pkg/gussync"
 	"context"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // CleanupService handles cleanup operations using the core engine
@@ -16,9 +19,17 @@ type CleanupService struct {
 	logger        *log.Logger
 	jobManager    *JobManager
 	deviceService *DeviceService
+
+	mu          sync.Mutex
+	lastResults []ModeCleanupResults
 }
 
-const stateFileName = "gus_state.md"
+// ModeCleanupResults are the results of cleaning up after one mode's backup
+type ModeCleanupResults struct {
+	Mode string `json:"mode"`
+	gussync.CleanupResults
+	Error string `json:"error,omitempty"` // why the mode stopped early (results are partial)
+}
 
 // NewCleanupService creates a new CleanupService
 func NewCleanupService(ctx context.Context, logger *log.Logger, jobManager *JobManager, deviceService *DeviceService) *CleanupService {
@@ -55,26 +66,13 @@ func (s *CleanupService) DetectStateFiles(destRoot string) ([]StateFileInfo, err
 
 	stateFiles := []StateFileInfo{}
 
-	// Check mount mode state file
-	mountDestPath := filepath.Join(destRoot, "mount")
-	mountStateFile := filepath.Join(mountDestPath, stateFileName)
-	if _, err := os.Stat(mountStateFile); err == nil {
-		stateFiles = append(stateFiles, StateFileInfo{
-			Path: mountStateFile,
-			Mode: "mount",
-		})
-		s.logger.Printf("[CleanupService] DetectStateFiles: Found mount state file: %s", mountStateFile)
-	}
-
-	// Check adb mode state file
-	adbDestPath := filepath.Join(destRoot, "adb")
-	adbStateFile := filepath.Join(adbDestPath, stateFileName)
-	if _, err := os.Stat(adbStateFile); err == nil {
+	for _, mode := range gussync.DetectModes(destRoot) {
+		stateFile := gussync.StateFile(destRoot, mode)
 		stateFiles = append(stateFiles, StateFileInfo{
-			Path: adbStateFile,
-			Mode: "adb",
+			Path: stateFile,
+			Mode: mode,
 		})
-		s.logger.Printf("[CleanupService] DetectStateFiles: Found adb state file: %s", adbStateFile)
+		s.logger.Printf("[CleanupService] DetectStateFiles: Found %s state file: %s", mode, stateFile)
 	}
 
 	return stateFiles, nil
@@ -89,12 +87,6 @@ func (s *CleanupService) StartCleanup(req CleanupRequest) (string, error) {
 		"destRoot":   req.DestRoot,
 	}
 
-	// Start job
-	jobID, jobCtx, err := s.jobManager.startTask("cleanup.sync", "Initializing cleanup...", params)
-	if err != nil {
-		return "", fmt.Errorf("failed to start cleanup task: %w", err)
-	}
-
 	// Determine which state files to process
 	stateFilesToProcess := req.StateFiles
 	if req.ProcessBoth || len(stateFilesToProcess) == 0 {
@@ -113,70 +105,124 @@ func (s *CleanupService) StartCleanup(req CleanupRequest) (string, error) {
 		}
 	}
 
-	// Run cleanup in goroutine (non-blocking)
-	go func() {
-		for _, mode := range stateFilesToProcess {
+	// Queue the cleanup (non-blocking); it starts once this device and destination are free
+	jobID, err := s.jobManager.queueTask("cleanup.sync", "Initializing cleanup...", params, func(jobCtx context.Context, jobID string) error {
+		defer crash.Recover("cleanup_service")
+		var summaries []string
+		var all []ModeCleanupResults
+		// finish records the results so far, whether all modes ran or one stopped the job
+		finish := func() {
+			s.mu.Lock()
+			s.lastResults = all
+			s.mu.Unlock()
+			var stats struct{ deleted, failed, skipped, freed int64 }
+			for _, r := range all {
+				stats.deleted += int64(r.Deleted)
+				stats.failed += int64(r.Failed)
+				stats.skipped += int64(r.Skipped + r.AlreadyDeleted + r.TooRecent)
+				stats.freed += r.FreedBytes
+			}
+			s.jobManager.setTaskStats(jobID, map[string]int64{
+				"deleted":    stats.deleted,
+				"failed":     stats.failed,
+				"skipped":    stats.skipped,
+				"freedBytes": stats.freed,
+			})
+			runtime.EventsEmit(s.ctx, "cleanup:complete", map[string]interface{}{
+				"jobId":   jobID,
+				"results": all,
+			})
+		}
+		for i, mode := range stateFilesToProcess {
 			// Check if job was cancelled
 			select {
 			case <-jobCtx.Done():
 				s.logger.Printf("[CleanupService] StartCleanup: Job cancelled")
-				return
+				finish()
+				return jobCtx.Err()
 			default:
 			}
 
-			// Process this state file
-			_ = s.processCleanupForMode(jobCtx, jobID, req.SourceRoot, req.DestRoot, mode)
+			runtime.EventsEmit(s.ctx, "cleanup:mode", map[string]interface{}{
+				"jobId": jobID,
+				"mode":  mode,
+				"index": i,
+				"total": len(stateFilesToProcess),
+			})
+
+			// Process this state file; the task settles once all modes are done
+			results, err := s.processCleanupForMode(jobCtx, jobID, req.SourceRoot, req.DestRoot, mode)
+			if err != nil {
+				all = append(all, ModeCleanupResults{Mode: mode, CleanupResults: results, Error: err.Error()})
+				finish()
+				if jobCtx.Err() != nil {
+					return jobCtx.Err()
+				}
+				return err
+			}
+			all = append(all, ModeCleanupResults{Mode: mode, CleanupResults: results})
+			summaries = append(summaries, fmt.Sprintf("%s: %d deleted, %d failed", mode, results.Deleted, results.Failed))
 		}
-	}()
-
+		finish()
+		s.jobManager.completeTask(jobID, "Cleanup complete ("+strings.Join(summaries, "; ")+")")
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start cleanup task: %w", err)
+	}
 	return jobID, nil
 }
 
-// processCleanupForMode processes cleanup for a specific mode (mount or adb)
-func (s *CleanupService) processCleanupForMode(ctx context.Context, jobID string, sourceRoot, destRoot, mode string) error {
+// processCleanupForMode runs the engine's cleanup for a specific mode (mount or adb). On an
+// error the results cover the files processed before it.
+func (s *CleanupService) processCleanupForMode(ctx context.Context, jobID string, sourceRoot, destRoot, mode string) (gussync.CleanupResults, error) {
 	s.logger.Printf("[CleanupService] processCleanupForMode: mode=%s sourceRoot=%s destRoot=%s", mode, sourceRoot, destRoot)
 
 	// Build state file path
-	destPath := filepath.Join(destRoot, mode)
-	stateFile := filepath.Join(destPath, stateFileName)
+	stateFile := gussync.StateFile(destRoot, mode)
 
 	// Check if state file exists
 	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
-		return fmt.Errorf("state file not found: %s", stateFile)
+		return gussync.CleanupResults{}, fmt.Errorf("state file not found: %s", stateFile)
 	}
 
-	reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager}
+	reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager, phase: "cleaning", mode: mode}
 	reporter.ReportLog("info", fmt.Sprintf("Processing cleanup for %s mode (state file: %s)", mode, stateFile))
 	reporter.ReportLog("info", "Loading state file...")
 
-	stateManager, err := state.NewStateManager(stateFile)
-	if err != nil {
-		return err
-	}
-	defer stateManager.Close()
-
-	cfg := engine.EngineConfig{
+	cfg := gussync.Config{
 		SourcePath: sourceRoot,
-		DestRoot:   destPath,
 		Mode:       mode,
 		NumWorkers: 2,
 		Reporter:   reporter,
+
+		PanicHandler: crash.Capture,
+	}
+
+	e, err := gussync.Open(destRoot, cfg)
+	if err != nil {
+		return gussync.CleanupResults{}, err
 	}
+	defer e.Close()
 
-	e := engine.NewEngine(cfg, stateManager)
-	
-	s.jobManager.updateTaskProgress(jobID, TaskProgress{Phase: "cleaning"}, "Cleanup in progress...", nil)
+	s.jobManager.updateTaskProgress(jobID, TaskProgress{Phase: "cleaning"}, fmt.Sprintf("Cleaning up %s...", mode), nil)
 
-	results, err := e.RunCleanup(ctx)
+	results, err := e.Cleanup(ctx)
 	if err != nil {
-		s.jobManager.failTask(jobID, err, "")
-		return err
+		reporter.ReportLog("error", fmt.Sprintf("Cleanup for %s stopped: %v", mode, err))
+		return results, err
 	}
 
-	message := fmt.Sprintf("Cleanup complete for %s: %d deleted, %d failed", mode, results.Deleted, results.Failed)
-	s.jobManager.completeTask(jobID, message)
+	reporter.ReportLog("info", fmt.Sprintf("Cleanup complete for %s: %d deleted, %d failed", mode, results.Deleted, results.Failed))
+	return results, nil
+}
 
-	return nil
+// LastCleanupResults returns the per-mode results of the last cleanup (empty before the
+// first one finishes)
+func (s *CleanupService) LastCleanupResults() []ModeCleanupResults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ModeCleanupResults{}, s.lastResults...)
 }
 
 // CancelCleanup cancels the current cleanup operation
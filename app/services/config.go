@@ -1,11 +1,14 @@
 package services
 
 import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/profile"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ConfigService manages application configuration
@@ -13,6 +16,7 @@ type ConfigService struct {
 	configPath string
 	logger     *log.Logger
 	config     *Config
+	profiles   *profile.Store
 }
 
 // Config represents the application configuration
@@ -25,6 +29,30 @@ type Config struct {
 	WindowHeight    int    `json:"windowHeight"`
 	WindowX         int    `json:"windowX"`
 	WindowY         int    `json:"windowY"`
+
+	// Crash reporting: reports are always kept locally, upload is opt-in
+	CrashUploadEnabled bool   `json:"crashUploadEnabled"`
+	CrashUploadURL     string `json:"crashUploadUrl,omitempty"`
+
+	// MaxConcurrentJobs is how many jobs may run at once; 0 or 1 = one at a time (others queue),
+	// -1 = no limit. Jobs on the same device or destination never run together.
+	MaxConcurrentJobs int `json:"maxConcurrentJobs,omitempty"`
+
+	// NotifyWebhook receives a JSON POST on job completion/failure, disconnects and a full destination
+	NotifyWebhook string `json:"notifyWebhook,omitempty"`
+
+	// BackupReport writes a report of each backup into its destination: "html", "csv" or "" (none)
+	BackupReport string `json:"backupReport,omitempty"`
+
+	// AuditLog records every file operation of a backup in gus_audit.jsonl in its destination
+	AuditLog bool `json:"auditLog,omitempty"`
+
+	// NoAutoRemount stops a backup whose MTP mount goes stale instead of remounting it with gio
+	NoAutoRemount bool `json:"noAutoRemount,omitempty"`
+
+	// Thumbnails caches a preview of each copied image and video for the backup browser
+	// (videos and HEIC need ffmpeg)
+	Thumbnails bool `json:"thumbnails,omitempty"`
 }
 
 // NewConfigService creates a new ConfigService
@@ -54,6 +82,7 @@ func NewConfigService(logger *log.Logger) (*ConfigService, error) {
 		config: &Config{
 			LogDir: logDir,
 		},
+		profiles: profile.NewStore(filepath.Join(configDir, "profiles.json")),
 	}
 
 	// Load existing config if it exists
@@ -156,3 +185,103 @@ func (s *ConfigService) SetWindowGeometry(x, y, width, height int) error {
 	return s.Save()
 }
 
+
+// SetCrashUpload sets the opt-in crash report upload settings and saves the config
+func (s *ConfigService) SetCrashUpload(enabled bool, url string) error {
+	s.logger.Printf("[ConfigService] SetCrashUpload: enabled=%v", enabled)
+
+	if s.config == nil {
+		s.config = &Config{}
+	}
+
+	s.config.CrashUploadEnabled = enabled
+	s.config.CrashUploadURL = url
+	return s.Save()
+}
+
+// SetNotifyWebhook sets (or clears, with "") the notification webhook URL and saves the config
+func (s *ConfigService) SetNotifyWebhook(url string) error {
+	if url != "" && !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("webhook must be an http(s) URL")
+	}
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.NotifyWebhook = url
+	return s.Save()
+}
+
+// SetMaxConcurrentJobs sets how many jobs may run at once and saves the config (applies on next start)
+func (s *ConfigService) SetMaxConcurrentJobs(n int) error {
+	if n < -1 {
+		return fmt.Errorf("max concurrent jobs must be -1 (no limit) or more")
+	}
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.MaxConcurrentJobs = n
+	return s.Save()
+}
+
+// SetBackupReport sets the report format written after each backup ("" = none) and saves the config
+func (s *ConfigService) SetBackupReport(format string) error {
+	switch format {
+	case "", engine.ReportHTML, engine.ReportCSV:
+	default:
+		return fmt.Errorf("unknown report format %q (use %s or %s)", format, engine.ReportHTML, engine.ReportCSV)
+	}
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.BackupReport = format
+	return s.Save()
+}
+
+// SetAuditLog turns the per-file audit log on or off and saves the config
+func (s *ConfigService) SetAuditLog(enabled bool) error {
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.AuditLog = enabled
+	return s.Save()
+}
+
+// SetAutoRemount turns remounting stale MTP mounts during a backup on or off and saves the config
+func (s *ConfigService) SetAutoRemount(enabled bool) error {
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.NoAutoRemount = !enabled
+	return s.Save()
+}
+
+// SetThumbnails turns caching previews of copied images and videos on or off and saves the config
+func (s *ConfigService) SetThumbnails(enabled bool) error {
+	if s.config == nil {
+		s.config = &Config{}
+	}
+	s.config.Thumbnails = enabled
+	return s.Save()
+}
+
+// ListProfiles returns all saved backup profiles (shared with `gussync profile`)
+func (s *ConfigService) ListProfiles() ([]profile.Profile, error) {
+	return s.profiles.List()
+}
+
+// GetProfile returns the named backup profile
+func (s *ConfigService) GetProfile(name string) (profile.Profile, error) {
+	return s.profiles.Get(name)
+}
+
+// SaveProfile creates or replaces a backup profile
+func (s *ConfigService) SaveProfile(p profile.Profile) error {
+	s.logger.Printf("[ConfigService] SaveProfile: %s", p.Name)
+	return s.profiles.Save(p)
+}
+
+// DeleteProfile removes a backup profile
+func (s *ConfigService) DeleteProfile(name string) error {
+	s.logger.Printf("[ConfigService] DeleteProfile: %s", name)
+	return s.profiles.Delete(name)
+}
@@ -1,13 +1,18 @@
 package services
 
 import (
+	"GusSync/internal/core"
+	"GusSync/internal/crash"
 	"GusSync/pkg/engine"
-	"GusSync/pkg/state"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/notify"
+	"GusSync/pkg/profile"
 	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -18,6 +23,8 @@ type WailsReporter struct {
 	ctx        context.Context
 	jobID      string
 	jobManager *JobManager
+	phase      string // task phase while running: "verifying", "cleaning" ("" = copying)
+	mode       string // mode being processed, added to progress events when set
 }
 
 func (r *WailsReporter) ReportProgress(update engine.ProgressUpdate) {
@@ -34,6 +41,25 @@ func (r *WailsReporter) ReportProgress(update engine.ProgressUpdate) {
 		"speedUnit":        "MB/s",
 		"deltaMB":          update.DeltaMB,
 		"progressFiles":    0.0,
+		"speedHistory":     update.SpeedSamples,
+		"workerBytes":      update.WorkerBytes,
+		"avgSpeed":         update.AvgSpeed / (1024 * 1024),
+		"peakSpeed":        update.PeakSpeed / (1024 * 1024),
+		"etaSeconds":       float64(update.ETASeconds),
+		"backupCompleted":  float64(update.BackupCompleted),
+		"backupExpected":   float64(update.BackupExpected),
+		"backupPercent":    update.BackupPercent,
+		"backupBytes":      float64(update.BackupBytes),
+		"dirs":             update.Dirs,
+		"symlinksSkipped":  float64(update.SpecialFiles.SymlinksSkipped),
+		"symlinksCopied":   float64(update.SpecialFiles.SymlinksCopied),
+		"symlinksRecorded": float64(update.SpecialFiles.SymlinksRecorded),
+		"specialSkipped":   float64(update.SpecialFiles.Special),
+		"smallQueue":       float64(update.SmallQueue),
+		"largeQueue":       float64(update.LargeQueue),
+	}
+	if r.mode != "" {
+		stats["mode"] = r.mode
 	}
 
 	if update.TotalFiles > 0 {
@@ -42,6 +68,16 @@ func (r *WailsReporter) ReportProgress(update engine.ProgressUpdate) {
 
 	runtime.EventsEmit(r.ctx, "job:progress", stats)
 
+	if r.jobManager != nil {
+		r.jobManager.setTaskStats(r.jobID, map[string]int64{
+			"totalFiles": int64(update.TotalFiles),
+			"completed":  int64(update.Completed),
+			"skipped":    int64(update.Skipped),
+			"failed":     int64(update.Failed),
+			"bytes":      update.TotalBytes,
+		})
+	}
+
 	// 2. New unified TaskUpdateEvent
 	if r.jobManager != nil {
 		progress := TaskProgress{
@@ -52,9 +88,37 @@ func (r *WailsReporter) ReportProgress(update engine.ProgressUpdate) {
 			Rate:    update.Rate / (1024 * 1024), // MB/s
 		}
 		
+		progress.ETASeconds = update.ETASeconds
+		
 		message := fmt.Sprintf("Copied %d/%d files", update.Completed, update.TotalFiles)
+		switch r.phase {
+		case "":
+			// A backup: scanning, copying, then finalizing
+			progress.Phases = backupPhases(update)
+			current := progress.Phases[len(progress.Phases)-1]
+			progress.Phase = current.Name
+			if current.Name == core.PhaseScanning {
+				progress.Current = current.Current
+				message = fmt.Sprintf("Scanning: %d files found", update.TotalFiles)
+			}
+		case "verifying":
+			progress.Phase = r.phase
+			progress.Current = int64(update.Completed + update.Failed)
+			if update.TotalFiles > 0 {
+				progress.Percent = float64(progress.Current) / float64(update.TotalFiles) * 100.0
+			}
+			progress.Phases = []core.JobPhase{{Name: core.PhaseVerifying, Current: progress.Current, Total: int64(update.TotalFiles),
+				Unit: "files", Done: update.ScanComplete}}
+			message = fmt.Sprintf("Verified %d/%d files (%d issues)", update.Completed+update.Failed, update.TotalFiles, update.Failed)
+		case "cleaning":
+			progress.Phase = r.phase
+			message = fmt.Sprintf("Deleted %d/%d files (%d failed)", update.Completed, update.TotalFiles, update.Failed)
+		}
+		if r.mode != "" {
+			message = fmt.Sprintf("%s: %s", r.mode, message)
+		}
 		if update.ScanComplete {
-			progress.Phase = "finishing"
+			progress.Phase = core.PhaseFinalizing
 		}
 		
 		r.jobManager.updateTaskProgress(r.jobID, progress, message, update.WorkerStatuses)
@@ -113,6 +177,7 @@ type CopyService struct {
 	jobManager    *JobManager
 	deviceService *DeviceService
 	config        *ConfigService
+	events        *engine.EventBus // receives the engine's per-file events (nil = none)
 }
 
 // NewCopyService creates a new CopyService
@@ -135,6 +200,11 @@ func (s *CopyService) SetConfig(config *ConfigService) {
 	s.config = config
 }
 
+// SetEvents sets the bus backups publish their per-file events on
+func (s *CopyService) SetEvents(events *engine.EventBus) {
+	s.events = events
+}
+
 // ChooseDestination opens a directory selection dialog
 func (s *CopyService) ChooseDestination() (string, error) {
 	path, err := runtime.OpenDirectoryDialog(s.ctx, runtime.OpenDialogOptions{
@@ -153,7 +223,187 @@ func (s *CopyService) ChooseDestination() (string, error) {
 
 // StartBackup starts a backup operation
 func (s *CopyService) StartBackup(sourcePath, destPath, mode string) (string, error) {
-	s.logger.Printf("[CopyService] StartBackup CALLED: sourcePath=%s destPath=%s mode=%s", sourcePath, destPath, mode)
+	return s.StartSelectiveBackup(sourcePath, destPath, mode, nil)
+}
+
+// StartSelectiveBackup starts a backup limited to the given folders.
+// Folders are relative to sourcePath (e.g. "DCIM"); an empty list backs up everything.
+func (s *CopyService) StartSelectiveBackup(sourcePath, destPath, mode string, folders []string) (string, error) {
+	return s.startBackup(sourcePath, destPath, mode, backupOptions{Folders: folders})
+}
+
+// StartFilteredBackup starts a backup limited to the given folders and media types (the
+// backup options' checkboxes, see MediaPresets); empty lists mean everything
+func (s *CopyService) StartFilteredBackup(sourcePath, destPath, mode string, folders, only []string) (string, error) {
+	return s.startBackup(sourcePath, destPath, mode, backupOptions{Folders: folders, Only: only})
+}
+
+// MediaPresets returns the media types a backup can be limited to ("photos", "videos", ...)
+func (s *CopyService) MediaPresets() []string {
+	return engine.MediaPresetNames()
+}
+
+// AppPresets returns the messenger apps the backup options offer as units (WhatsApp,
+// Telegram, ...), with the folders each covers and what it leaves out
+func (s *CopyService) AppPresets() []engine.AppPreset {
+	return append([]engine.AppPreset(nil), engine.AppPresets...)
+}
+
+// StartAppBackup starts a backup of the given messenger apps (see AppPresets), their chats
+// and media, along with the given folders; with no folders only the apps are backed up
+func (s *CopyService) StartAppBackup(sourcePath, destPath, mode string, folders, apps []string) (string, error) {
+	return s.startBackup(sourcePath, destPath, mode, backupOptions{Folders: folders, Apps: apps})
+}
+
+// ListProfiles returns the saved backup profiles for the profile picker
+func (s *CopyService) ListProfiles() ([]profile.Profile, error) {
+	if s.config == nil {
+		return nil, fmt.Errorf("config service not initialized")
+	}
+	return s.config.ListProfiles()
+}
+
+// SaveProfile creates or replaces a backup profile
+func (s *CopyService) SaveProfile(p profile.Profile) error {
+	if s.config == nil {
+		return fmt.Errorf("config service not initialized")
+	}
+	return s.config.SaveProfile(p)
+}
+
+// DeleteProfile removes a backup profile
+func (s *CopyService) DeleteProfile(name string) error {
+	if s.config == nil {
+		return fmt.Errorf("config service not initialized")
+	}
+	return s.config.DeleteProfile(name)
+}
+
+// PriorityPaths returns the folders a backup scans first, in order: the profile's own
+// order, or the built-in one ("" or a profile without one). The GUI's reorderable list
+// starts from it.
+func (s *CopyService) PriorityPaths(profileName string) ([]string, error) {
+	if profileName != "" {
+		if s.config == nil {
+			return nil, fmt.Errorf("config service not initialized")
+		}
+		p, err := s.config.GetProfile(profileName)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Priority) > 0 {
+			return p.Priority, nil
+		}
+	}
+	return append([]string(nil), engine.PriorityPaths...), nil
+}
+
+// SetProfilePriority saves the folder order the user dragged into place to a profile;
+// enabled false makes its backups scan without priority folders. An empty order
+// restores the built-in one.
+func (s *CopyService) SetProfilePriority(profileName string, paths []string, enabled bool) error {
+	if s.config == nil {
+		return fmt.Errorf("config service not initialized")
+	}
+	p, err := s.config.GetProfile(profileName)
+	if err != nil {
+		return err
+	}
+	priority, err := engine.NormalizePriorityPaths(paths)
+	if err != nil {
+		return err
+	}
+	if len(priority) == 0 {
+		priority = nil
+	}
+	p.Priority, p.NoPriority = priority, !enabled
+	return s.config.SaveProfile(p)
+}
+
+// StartProfileBackup starts a backup from a saved profile.
+// This builds the same engine config as `gussync backup --profile <name>`.
+func (s *CopyService) StartProfileBackup(name string) (string, error) {
+	if s.config == nil {
+		return "", fmt.Errorf("config service not initialized")
+	}
+	p, err := s.config.GetProfile(name)
+	if err != nil {
+		return "", err
+	}
+	sourcePath, err := p.ResolveSource()
+	if err != nil {
+		return "", fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	var serial string
+	if p.Mode == "adb" {
+		serial = p.DeviceSerial
+	}
+	return s.startBackup(sourcePath, p.Destination, p.Mode, backupOptions{
+		Serial:     serial,
+		Folders:    p.ScanRoots,
+		Excludes:   p.Excludes,
+		Only:       p.Only,
+		Apps:       p.Apps,
+		PhoneData:  p.PhoneData,
+		APKs:       p.APKs,
+		Priority:   p.Priority,
+		NoPriority: p.NoPriority,
+		Workers:    p.Workers,
+		Bandwidth:  p.Bandwidth,
+		Hooks:      engine.Hooks{PreBackup: p.PreBackup, PostBackup: p.PostBackup},
+	})
+}
+
+// backupOptions carries the optional engine settings a backup can be started with
+type backupOptions struct {
+	Serial     string // adb serial of the device ("" = the one adb picks)
+	Folders    []string
+	Excludes   []string
+	Only       []string // media presets, see engine.MediaPresets
+	Apps       []string // messenger apps, see engine.AppPresets
+	PhoneData  []string // contacts, sms and/or calls to export (adb mode), see engine.PhoneDataKinds
+	APKs       bool     // back up the installed apps' APKs (adb mode)
+	Priority   []string // folders scanned first, in order (nil = engine.PriorityPaths)
+	NoPriority bool
+	Workers    int
+	Bandwidth  string
+	Hooks      engine.Hooks
+}
+
+func (s *CopyService) startBackup(sourcePath, destPath, mode string, opts backupOptions) (string, error) {
+	s.logger.Printf("[CopyService] StartBackup CALLED: sourcePath=%s destPath=%s mode=%s folders=%v", sourcePath, destPath, mode, opts.Folders)
+
+	scanRoots, err := engine.NormalizeScanRoots(opts.Folders)
+	if err != nil {
+		return "", err
+	}
+	filter, err := engine.NewFilter(opts.Excludes)
+	if err != nil {
+		return "", err
+	}
+	if err := filter.SetPresets(opts.Only); err != nil {
+		return "", err
+	}
+	if err := filter.SetApps(opts.Apps); err != nil {
+		return "", err
+	}
+	var priority []string
+	if len(opts.Priority) > 0 {
+		if priority, err = engine.NormalizePriorityPaths(opts.Priority); err != nil {
+			return "", err
+		}
+	}
+	var schedule *engine.BandwidthSchedule
+	if opts.Bandwidth != "" {
+		schedule, err = engine.ParseBandwidthSchedule(opts.Bandwidth)
+		if err != nil {
+			return "", err
+		}
+	}
+	numWorkers := opts.Workers
+	if numWorkers < 1 {
+		numWorkers = 2 // Default
+	}
 	
 	// Auto-detect source if empty
 	if sourcePath == "" {
@@ -170,6 +420,7 @@ func (s *CopyService) StartBackup(sourcePath, destPath, mode string) (string, er
 		sourcePath = "/sdcard" // Default
 		s.logger.Printf("[CopyService] No device found, using default: %s", sourcePath)
 	}
+	mode = resolveMode(mode, sourcePath)
 
 	// Get destination from config if empty
 	if destPath == "" && s.config != nil {
@@ -189,44 +440,121 @@ func (s *CopyService) StartBackup(sourcePath, destPath, mode string) (string, er
 		"destPath":   destPath,
 		"mode":       mode,
 	}
-
-	jobID, jobCtx, err := s.jobManager.startTask("copy.sync", "Initializing backup...", params)
-	if err != nil {
-		return "", err
+	if len(scanRoots) > 0 {
+		params["folders"] = strings.Join(scanRoots, ",")
+	}
+	if len(opts.Apps) > 0 {
+		params["apps"] = strings.Join(opts.Apps, ",")
+	}
+	if opts.Serial != "" {
+		// Jobs for different phones may run side by side, jobs for one phone queue
+		params[core.ParamDevice] = opts.Serial
 	}
 
 	// Update destination with mode
-	fullDestPath := filepath.Join(destPath, mode)
-	_ = os.MkdirAll(fullDestPath, 0755)
+	fullDestPath := gussync.ModeDir(destPath, mode)
 
-	// Run engine in goroutine
-	go func() {
+	// Queue the engine run; it starts once this device and destination are free
+	return s.jobManager.queueTask("copy.sync", "Initializing backup...", params, func(jobCtx context.Context, jobID string) error {
+		defer crash.Recover("copy_service")
 		reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager}
 		reporter.ReportLog("info", fmt.Sprintf("Starting backup from %s to %s...", sourcePath, fullDestPath))
 
-		// Initialize state inside goroutine to prevent blocking the UI
-		// Large state files can take seconds to load
-		stateFile := filepath.Join(fullDestPath, "gus_state.md")
-		reporter.ReportLog("info", "Loading state file...")
-		stateManager, err := state.NewStateManager(stateFile)
-		if err != nil {
-			reporter.ReportError(fmt.Errorf("CRITICAL: failed to initialize state: %w", err))
-			s.jobManager.completeJob(false)
-			return
+		// Check the phone first: a locked phone's MTP mount lists nothing, and the backup
+		// would finish having found 0 files
+		health := s.deviceService.checkDeviceHealth(sourcePath, mode, opts.Serial)
+		runtime.EventsEmit(s.ctx, "device:health", map[string]interface{}{"id": jobID, "health": health})
+		for _, warning := range health.Warnings() {
+			reporter.ReportLog("warn", "Device check: "+warning)
+		}
+		if failures := health.Failures(); len(failures) > 0 {
+			err := fmt.Errorf("device not ready: %s", strings.Join(failures, "; "))
+			reporter.ReportError(err)
+			return err
 		}
-		defer stateManager.Close()
-		defer s.jobManager.completeJob(true)
 
-		cfg := engine.EngineConfig{
+		// Load state inside the job to prevent blocking the UI
+		// Large state files can take seconds to load
+		reporter.ReportLog("info", "Loading state file...")
+		cfg := gussync.Config{
 			SourcePath: sourcePath,
-			DestRoot:   fullDestPath,
 			Mode:       mode,
-			NumWorkers: 2, // Default
+			Serial:     opts.Serial,
+			NumWorkers: numWorkers,
 			Reporter:   reporter,
+			ScanRoots:  scanRoots,
+			Excludes:   opts.Excludes,
+			Only:       opts.Only,
+			Apps:       opts.Apps,
+			PhoneData:  opts.PhoneData,
+			APKs:       opts.APKs,
+			Bandwidth:  schedule,
+
+			PriorityPaths: priority,
+			NoPriority:    opts.NoPriority,
+
+			PanicHandler:  crash.Capture,
+			ReconnectWait: engine.DefaultReconnectWait,
+			RemountStale:  true,
+			Notifier:      s.notifier(),
+			Hooks:         opts.Hooks,
+			MinFreeSpace:  engine.DefaultMinFreeSpace,
+			Pause:         s.jobManager.core.PauseGate(jobID),
+			Events:        s.jobEvents(jobID),
+		}
+		if s.config != nil {
+			cfg.Report = s.config.GetConfig().BackupReport
+			cfg.Audit = s.config.GetConfig().AuditLog
+			cfg.RemountStale = !s.config.GetConfig().NoAutoRemount
+			if s.config.GetConfig().Thumbnails {
+				var frames engine.FrameGrabber
+				if converter, err := engine.NewExecConverter(); err == nil {
+					frames = converter
+				} else {
+					reporter.ReportLog("warn", fmt.Sprintf("%v; videos and HEIC images get no thumbnails", err))
+				}
+				cfg.PostProcessors = append(cfg.PostProcessors, engine.NewThumbnailer(fullDestPath, frames))
+			}
+		}
+		if mode == gussync.ModeSSH {
+			host, remotePath, err := engine.ParseSSHSource(sourcePath)
+			if err != nil {
+				reporter.ReportError(err)
+				return err
+			}
+			cfg.SourcePath = remotePath
+			cfg.SSH = gussync.SSHOptions{Host: host, Password: os.Getenv("GUSSYNC_SSH_PASSWORD")}
 		}
+		if mode == gussync.ModeSMB {
+			opts, sharePath, err := engine.ParseSMBSource(sourcePath)
+			if err != nil {
+				reporter.ReportError(err)
+				return err
+			}
+			if password := os.Getenv("GUSSYNC_SMB_PASSWORD"); password != "" {
+				opts.Password = password
+			}
+			cfg.SourcePath = sharePath
+			cfg.SMB = opts
+		}
+		if mode == gussync.ModeKDEConnect {
+			device, phonePath, err := engine.ParseKDEConnectSource(sourcePath)
+			if err != nil {
+				reporter.ReportError(err)
+				return err
+			}
+			cfg.SourcePath = phonePath
+			cfg.KDEConnect = gussync.KDEConnectOptions{Device: device}
+		}
+		e, err := gussync.Open(destPath, cfg)
+		if err != nil {
+			reporter.ReportError(fmt.Errorf("CRITICAL: failed to initialize state: %w", err))
+			return err
+		}
+		defer e.Close()
+		// The GUI's skip button (JobManager.SkipFile) reaches the run's workers through the job
+		s.jobManager.core.SetSkipper(jobID, e.SkipFile)
 
-		e := engine.NewEngine(cfg, stateManager)
-		
 		runtime.EventsEmit(s.ctx, "job:status", map[string]interface{}{
 			"id":         jobID,
 			"state":      "running",
@@ -235,14 +563,99 @@ func (s *CopyService) StartBackup(sourcePath, destPath, mode string) (string, er
 			"destPath":   fullDestPath,
 		})
 
-		if err := e.Run(jobCtx); err != nil {
-			reporter.ReportError(err)
+		ev := notify.Event{Kind: notify.JobComplete, Title: "Backup complete", Source: sourcePath, Dest: fullDestPath,
+			Message: fmt.Sprintf("Backup of %s finished.", sourcePath)}
+		runErr := e.Backup(jobCtx)
+		s.recordArtifacts(jobID, fullDestPath, e.ReportPath())
+		if runErr != nil {
+			reporter.ReportError(runErr)
+			ev.Kind, ev.Title, ev.Message = notify.JobFailed, "Backup failed", fmt.Sprintf("Backup of %s failed: %v", sourcePath, runErr)
+		}
+		if jobCtx.Err() == nil || ev.Kind == notify.JobFailed {
+			if err := notify.Send(s.ctx, cfg.Notifier, ev); err != nil {
+				s.logger.Printf("[CopyService] %v", err)
+			}
+		}
+
+		if runErr == nil {
+			s.jobManager.completeTask(jobID, "Backup completed successfully")
+		}
+		return runErr
+	})
+}
+
+// resolveMode turns "smart" (or no mode) into the transport named by a URL source (ssh://,
+// smb://, kdeconnect://), mount when the source is a local directory and adb otherwise
+// (e.g. /sdcard on a phone reached over adb)
+func resolveMode(mode, sourcePath string) string {
+	if mode != "" && mode != "smart" {
+		return mode
+	}
+	if strings.HasPrefix(sourcePath, "ssh://") || strings.HasPrefix(sourcePath, "sftp://") {
+		return gussync.ModeSSH
+	}
+	if strings.HasPrefix(sourcePath, "smb://") || strings.HasPrefix(sourcePath, "cifs://") {
+		return gussync.ModeSMB
+	}
+	if strings.HasPrefix(sourcePath, "kdeconnect://") {
+		return gussync.ModeKDEConnect
+	}
+	if info, err := os.Stat(sourcePath); err == nil && info.IsDir() {
+		return gussync.ModeMount
+	}
+	return gussync.ModeADB
+}
+
+// recordArtifacts attaches the run's report, its error log and a count of its errors to the
+// job, so the history shows what went wrong after the destination is unplugged
+func (s *CopyService) recordArtifacts(jobID, fullDestPath, reportPath string) {
+	errorLogFile := filepath.Join(fullDestPath, gussync.ErrorLogFileName)
+	if _, err := os.Stat(errorLogFile); err != nil {
+		if reportPath != "" {
+			s.jobManager.setTaskArtifact(jobID, TaskArtifact{ReportPath: reportPath})
 		}
+		return
+	}
+	s.jobManager.setTaskArtifact(jobID, TaskArtifact{LogPath: errorLogFile, OpenLogHint: "Errors from this backup", ReportPath: reportPath})
 
-		s.jobManager.completeTask(jobID, "Backup completed successfully")
-	}()
+	summary, err := engine.SummarizeErrors(errorLogFile)
+	if err != nil {
+		s.logger.Printf("[CopyService] Failed to summarize error log: %v", err)
+		return
+	}
+	task, err := s.jobManager.core.GetJob(jobID)
+	if err != nil {
+		return
+	}
+	stats := make(map[string]int64, len(task.Stats)+5)
+	for k, v := range task.Stats {
+		stats[k] = v
+	}
+	stats["errors"] = int64(summary.TotalErrors)
+	stats["criticalErrors"] = int64(summary.CriticalErrors)
+	stats["copyErrors"] = int64(summary.CopyErrors)
+	stats["hashMismatches"] = int64(summary.HashMismatches)
+	stats["directoryTimeouts"] = int64(summary.DirectoryTimeouts)
+	s.jobManager.setTaskStats(jobID, stats)
+}
+
+// ModeErrorSummary is the error summary of one mode's backup at a destination
+type ModeErrorSummary struct {
+	Mode string `json:"mode"`
+	engine.ErrorSummary
+}
 
-	return jobID, nil
+// ErrorSummaries summarizes the error log of each backup mode under destPath
+func (s *CopyService) ErrorSummaries(destPath string) ([]ModeErrorSummary, error) {
+	summaries := []ModeErrorSummary{}
+	for _, mode := range gussync.DetectModes(destPath) {
+		summary, err := engine.SummarizeErrors(gussync.ErrorLogFile(destPath, mode))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s error log: %w", mode, err)
+		}
+		summaries = append(summaries, ModeErrorSummary{Mode: mode, ErrorSummary: summary})
+	}
+	return summaries, nil
 }
 
 func (s *CopyService) CancelCopy() error {
@@ -9,8 +9,11 @@ import (
 	"path/filepath"
 	goruntime "runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"GusSync/pkg/engine"
+
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -21,6 +24,9 @@ type DeviceService struct {
 	lastDevices  []DeviceInfo
 	isPolling    bool
 	pollInterval time.Duration
+
+	storageMu    sync.Mutex
+	storageCache map[string]engine.StorageUsage // device ID -> last analysis
 }
 
 // NewDeviceService creates a new DeviceService
@@ -92,9 +98,13 @@ func (s *DeviceService) checkAndEmit() {
 		s.logger.Printf("[DeviceService] Device status changed: %d devices found", len(devices))
 		s.lastDevices = devices
 		
-		// Emit device:connection (boolean for simple UI)
+		// Emit device:connection (boolean for simple UI); unmounted phones don't count yet
+		connected := false
+		for _, d := range devices {
+			connected = connected || d.Connected
+		}
 		runtime.EventsEmit(s.ctx, "device:connection", map[string]interface{}{
-			"connected": len(devices) > 0,
+			"connected": connected,
 			"count":     len(devices),
 		})
 
@@ -112,9 +122,12 @@ func (s *DeviceService) checkAndEmit() {
 type DeviceInfo struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
-	Type      string `json:"type"` // "mtp", "adb", "gphoto2"
+	Type      string `json:"type"` // "mtp", "adb", "gphoto2", "kdeconnect"
 	Path      string `json:"path"`
 	Connected bool   `json:"connected"`
+	// Mountable is set for a phone that is plugged in but not mounted (no Path yet); ID is
+	// its mtp:// or gphoto2:// URI for MountDevice
+	Mountable bool `json:"mountable,omitempty"`
 }
 
 // GetDeviceStatus returns the current device connection status
@@ -191,9 +204,243 @@ func (s *DeviceService) GetDeviceStatus() ([]DeviceInfo, error) {
 		} else {
 			s.logger.Printf("[DeviceService] GVFS path read failed: %v", err)
 		}
+
+		// Phones plugged in but not mounted yet can be mounted with MountDevice
+		ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+		volumes, err := engine.GioVolumes(ctx)
+		cancel()
+		if err == nil {
+			for _, v := range volumes {
+				if path, err := engine.GvfsPath(v.URI); v.Mounted || err != nil || pathExists(path) {
+					continue
+				}
+				deviceType := "mtp"
+				if strings.HasPrefix(v.URI, "gphoto2://") {
+					deviceType = "gphoto2"
+				}
+				s.logger.Printf("[DeviceService] Found unmounted %s device: %s (%s)", deviceType, v.Name, v.URI)
+				devices = append(devices, DeviceInfo{
+					ID:        v.URI,
+					Name:      v.Name + " (not mounted)",
+					Type:      deviceType,
+					Mountable: true,
+				})
+			}
+		} else {
+			s.logger.Printf("[DeviceService] gio not available: %v", err)
+		}
+	}
+
+	// Check phones paired with KDE Connect (reachable over Wi-Fi, no cable needed)
+	if goruntime.GOOS == "linux" {
+		ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+		kdeDevices, err := engine.KDEConnectDevices(ctx)
+		cancel()
+		if err == nil {
+			for _, d := range kdeDevices {
+				if !d.Browsable {
+					s.logger.Printf("[DeviceService] KDE Connect device %s doesn't share its files", d.Name)
+					continue
+				}
+				s.logger.Printf("[DeviceService] Found KDE Connect device: %s (%s)", d.Name, d.ID)
+				devices = append(devices, DeviceInfo{
+					ID:        d.ID,
+					Name:      d.Name + " (KDE Connect)",
+					Type:      "kdeconnect",
+					Path:      "kdeconnect://" + d.ID + "/",
+					Connected: true,
+				})
+			}
+		} else {
+			s.logger.Printf("[DeviceService] KDE Connect not available: %v", err)
+		}
 	}
 
 	s.logger.Printf("[DeviceService] Scan complete: %d devices found", len(devices))
 	return devices, nil
 }
 
+
+// DeviceFolder is a directory on the device that can be selected for backup
+type DeviceFolder struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`      // Full path on the device (mount path or Android path)
+	SizeBytes int64  `json:"sizeBytes"` // Total size of files below this folder
+	FileCount int    `json:"fileCount"` // Number of files (mount mode only)
+	SizeKnown bool   `json:"sizeKnown"` // False if sizing timed out (slow MTP)
+}
+
+// folderSizeTimeout bounds how long sizing a single folder may take over MTP
+const folderSizeTimeout = 5 * time.Second
+
+// ListDeviceFolders lists the directories directly under path with their sizes.
+// For adb devices path is an Android path (e.g. /sdcard) on the device with adb serial
+// deviceID; otherwise a mount path.
+func (s *DeviceService) ListDeviceFolders(deviceType, deviceID, path string) ([]DeviceFolder, error) {
+	s.logger.Printf("[DeviceService] ListDeviceFolders: type=%s device=%s path=%s", deviceType, deviceID, path)
+	if deviceType == "adb" {
+		return s.listADBFolders(deviceID, path)
+	}
+	return s.listMountFolders(path)
+}
+
+func (s *DeviceService) listMountFolders(path string) ([]DeviceFolder, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	folders := []DeviceFolder{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		folder := DeviceFolder{Name: entry.Name(), Path: filepath.Join(path, entry.Name())}
+		folder.SizeBytes, folder.FileCount, folder.SizeKnown = s.sizeMountFolder(folder.Path)
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// sizeMountFolder walks a folder until folderSizeTimeout expires
+func (s *DeviceService) sizeMountFolder(path string) (int64, int, bool) {
+	deadline := time.Now().Add(folderSizeTimeout)
+	var size int64
+	var count int
+	timedOut := false
+
+	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if time.Now().After(deadline) {
+			timedOut = true
+			return filepath.SkipAll
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+				count++
+			}
+		}
+		return nil
+	})
+	return size, count, !timedOut
+}
+
+func (s *DeviceService) listADBFolders(serial, path string) ([]DeviceFolder, error) {
+	if path == "" {
+		path = "/sdcard"
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	ctx, cancel := context.WithTimeout(s.ctx, 60*time.Second)
+	defer cancel()
+
+	// du -sk on each subdirectory gives sizes in one round trip
+	sizes, err := engine.ADBFolderSizes(ctx, serial, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders on device: %w", err)
+	}
+
+	folders := []DeviceFolder{}
+	for _, f := range sizes {
+		folders = append(folders, DeviceFolder{
+			Name:      f.Path,
+			Path:      path + "/" + f.Path,
+			SizeBytes: f.Bytes,
+			SizeKnown: true,
+		})
+	}
+	return folders, nil
+}
+
+// storageAnalysisTimeout bounds a storage analysis; slower devices get partial figures
+const storageAnalysisTimeout = 3 * time.Minute
+
+// AnalyzeStorage returns what takes up space on a device, by folder and kind of file. The
+// result is cached per device; refresh analyzes the device again.
+func (s *DeviceService) AnalyzeStorage(deviceID string, refresh bool) (engine.StorageUsage, error) {
+	s.logger.Printf("[DeviceService] AnalyzeStorage: device=%s refresh=%v", deviceID, refresh)
+	s.storageMu.Lock()
+	cached, ok := s.storageCache[deviceID]
+	s.storageMu.Unlock()
+	if ok && !refresh {
+		return cached, nil
+	}
+
+	devices, err := s.GetDeviceStatus()
+	if err != nil {
+		return engine.StorageUsage{}, err
+	}
+	var device *DeviceInfo
+	for i := range devices {
+		if devices[i].ID == deviceID {
+			device = &devices[i]
+			break
+		}
+	}
+	if device == nil {
+		return engine.StorageUsage{}, fmt.Errorf("device %s is not connected", deviceID)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, storageAnalysisTimeout)
+	defer cancel()
+	var usage engine.StorageUsage
+	switch device.Type {
+	case "adb":
+		usage, err = engine.AnalyzeADBStorage(ctx, device.ID, device.Path)
+	case "kdeconnect":
+		return engine.StorageUsage{}, fmt.Errorf("storage analysis is not available for KDE Connect devices")
+	default:
+		usage, err = engine.AnalyzeStorage(ctx, device.Path)
+	}
+	if err != nil {
+		return engine.StorageUsage{}, fmt.Errorf("failed to analyze %s: %w", device.Name, err)
+	}
+	s.logger.Printf("[DeviceService] AnalyzeStorage: %d files, %d bytes (complete=%v)", usage.Files, usage.Bytes, usage.Complete)
+
+	s.storageMu.Lock()
+	if s.storageCache == nil {
+		s.storageCache = make(map[string]engine.StorageUsage)
+	}
+	s.storageCache[deviceID] = usage
+	s.storageMu.Unlock()
+	return usage, nil
+}
+
+// CheckDeviceHealth checks a phone before a backup from sourcePath: battery, free space,
+// lock state and whether the source lists any files (a locked phone's MTP mount is empty)
+func (s *DeviceService) CheckDeviceHealth(sourcePath, mode string) engine.DeviceHealth {
+	return s.checkDeviceHealth(sourcePath, mode, "")
+}
+
+// checkDeviceHealth is CheckDeviceHealth for the phone with this adb serial ("" = the one
+// adb picks)
+func (s *DeviceService) checkDeviceHealth(sourcePath, mode, serial string) engine.DeviceHealth {
+	s.logger.Printf("[DeviceService] CheckDeviceHealth: sourcePath=%s mode=%s serial=%s", sourcePath, mode, serial)
+	health := engine.CheckDeviceHealthOn(s.ctx, serial, resolveMode(mode, sourcePath), sourcePath)
+	for _, p := range health.Problems {
+		s.logger.Printf("[DeviceService] CheckDeviceHealth: %s: %s", p.Severity, p.Message)
+	}
+	return health
+}
+
+// MountDevice mounts a phone that is plugged in but not mounted (a Mountable DeviceInfo's
+// ID, e.g. mtp://Google_Pixel_6_1A2B3C/) with gio and returns its mount path, for use as
+// the backup source
+func (s *DeviceService) MountDevice(deviceID string) (string, error) {
+	s.logger.Printf("[DeviceService] MountDevice: device=%s", deviceID)
+	path, err := engine.GioMount(s.ctx, deviceID, engine.DefaultGioMountTimeout)
+	if err != nil {
+		s.logger.Printf("[DeviceService] MountDevice: %v", err)
+		return "", err
+	}
+	s.logger.Printf("[DeviceService] MountDevice: mounted at %s", path)
+	return path, nil
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
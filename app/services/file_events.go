@@ -0,0 +1,51 @@
+package services
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+)
+
+// FileEvent is the payload of the "file:completed" and "file:failed" events, emitted as each
+// file of a backup is copied or given up on, for a live feed of the files backed up. Hash
+// identifies the copy's thumbnail (CatalogService.Thumbnail) when thumbnails are on.
+type FileEvent struct {
+	JobID      string `json:"jobId"`
+	SourcePath string `json:"sourcePath"`
+	RelPath    string `json:"relPath"` // relative to the mode's destination directory
+	Size       int64  `json:"size"`
+	DurationMS int64  `json:"durationMs"`
+	Hash       string `json:"hash,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorCode  string `json:"errorCode,omitempty"`
+	Time       int64  `json:"time"` // Unix milliseconds
+}
+
+// newFileEvent converts an engine event of job jobID into its Wails payload
+func newFileEvent(jobID string, ev engine.Event) FileEvent {
+	return FileEvent{
+		JobID:      jobID,
+		SourcePath: ev.Path,
+		RelPath:    ev.Dest,
+		Size:       ev.Bytes,
+		DurationMS: ev.Duration.Milliseconds(),
+		Hash:       ev.Hash,
+		Error:      ev.Error,
+		ErrorCode:  ev.Code,
+		Time:       ev.Time.UnixMilli(),
+	}
+}
+
+// jobEvents returns the event bus of one backup job: its events go on to the app's bus (see
+// SetEvents), and completed and failed files to the GUI as "file:completed" and "file:failed"
+func (s *CopyService) jobEvents(jobID string) *gussync.EventBus {
+	bus := gussync.NewEventBus()
+	if s.events != nil {
+		bus.Subscribe(s.events.Publish)
+	}
+	bus.Subscribe(func(ev engine.Event) {
+		runtime.EventsEmit(s.ctx, string(ev.Kind), newFileEvent(jobID, ev))
+	}, engine.EventFileCompleted, engine.EventFileFailed)
+	return bus
+}
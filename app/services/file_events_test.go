@@ -0,0 +1,24 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"GusSync/pkg/engine"
+)
+
+func TestNewFileEvent(t *testing.T) {
+	at := time.UnixMilli(1700000000000)
+	got := newFileEvent("copy-1", engine.Event{Kind: engine.EventFileCompleted, Time: at, Path: "/sdcard/DCIM/a.jpg",
+		Dest: "DCIM/a.jpg", Bytes: 2048, Duration: 1500 * time.Millisecond, Hash: "abc"})
+	want := FileEvent{JobID: "copy-1", SourcePath: "/sdcard/DCIM/a.jpg", RelPath: "DCIM/a.jpg", Size: 2048,
+		DurationMS: 1500, Hash: "abc", Time: 1700000000000}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	failed := newFileEvent("copy-1", engine.Event{Kind: engine.EventFileFailed, Error: "stalled", Code: "stalled"})
+	if failed.Error != "stalled" || failed.ErrorCode != "stalled" || failed.Hash != "" {
+		t.Errorf("failed file: %+v", failed)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"GusSync/internal/core"
 	"context"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -59,6 +61,16 @@ func NewJobManager(ctx context.Context, logger *log.Logger) *JobManager {
 	emitter := &WailsJobEmitter{ctx: ctx, logger: logger}
 	coreManager := core.NewJobManager(emitter)
 
+	// Keep finished jobs across restarts in ~/.gussync/history.jsonl
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		history := core.NewFileHistory(filepath.Join(homeDir, ".gussync", "history.jsonl"), 0)
+		coreManager.SetHistory(history, func(err error) {
+			logger.Printf("[JobManager] %v", err)
+		})
+	} else {
+		logger.Printf("[JobManager] Job history disabled: %v", err)
+	}
+
 	return &JobManager{
 		core:    coreManager,
 		emitter: emitter,
@@ -79,6 +91,28 @@ func (jm *JobManager) startTask(taskType string, message string, params map[stri
 	return jm.core.StartJob(jm.ctx, taskType, message, params)
 }
 
+// queueTask queues a task that runs once a slot is free and no other task uses its device or
+// destination, and returns its taskId immediately. run's error (if it hasn't settled the task
+// itself) fails the task.
+func (jm *JobManager) queueTask(taskType string, message string, params map[string]string, run core.JobFunc) (string, error) {
+	jm.logger.Printf("[JobManager] queueTask: type=%s msg=%s", taskType, message)
+	return jm.core.EnqueueJob(jm.ctx, taskType, message, params, run)
+}
+
+// SetMaxConcurrentJobs sets how many tasks may run at once, as in Config.MaxConcurrentJobs
+// (0 = one, -1 = as many as devices and destinations allow). Tasks for the same device or
+// destination always run one at a time.
+func (jm *JobManager) SetMaxConcurrentJobs(n int) {
+	jm.logger.Printf("[JobManager] SetMaxConcurrentJobs: n=%d", n)
+	switch {
+	case n == 0:
+		n = 1
+	case n < 0:
+		n = 0
+	}
+	jm.core.SetMaxConcurrent(n)
+}
+
 // updateTaskProgress updates the progress of a task
 func (jm *JobManager) updateTaskProgress(taskID string, progress TaskProgress, message string, workers map[int]string) {
 	coreProgress := core.JobProgress{
@@ -87,10 +121,23 @@ func (jm *JobManager) updateTaskProgress(taskID string, progress TaskProgress, m
 		Total:   progress.Total,
 		Percent: progress.Percent,
 		Rate:    progress.Rate,
+
+		Phases:     progress.Phases,
+		ETASeconds: progress.ETASeconds,
 	}
 	jm.core.UpdateProgress(taskID, coreProgress, message, workers)
 }
 
+// setTaskStats records a task's counters for the job history
+func (jm *JobManager) setTaskStats(taskID string, stats map[string]int64) {
+	jm.core.SetStats(taskID, stats)
+}
+
+// setTaskArtifact records a task's output files for the job history
+func (jm *JobManager) setTaskArtifact(taskID string, artifact TaskArtifact) {
+	jm.core.SetArtifact(taskID, core.JobArtifact(artifact))
+}
+
 // completeTask marks a task as succeeded
 func (jm *JobManager) completeTask(taskID string, message string) {
 	jm.core.CompleteJob(taskID, message)
@@ -132,6 +179,26 @@ func (jm *JobManager) ListTasks() []*TaskSnapshot {
 	return result
 }
 
+// ListHistory returns a page of finished tasks (including those from earlier sessions), newest first.
+// limit 0 returns everything.
+func (jm *JobManager) ListHistory(offset, limit int) (*TaskHistoryPage, error) {
+	page, err := jm.core.History(offset, limit)
+	if err != nil {
+		jm.logger.Printf("[JobManager] ListHistory: error=%v", err)
+		return nil, err
+	}
+	result := &TaskHistoryPage{
+		Tasks:  make([]*TaskSnapshot, len(page.Jobs)),
+		Total:  page.Total,
+		Offset: page.Offset,
+		Limit:  page.Limit,
+	}
+	for i, cs := range page.Jobs {
+		result.Tasks[i] = coreSnapshotToTask(cs)
+	}
+	return result, nil
+}
+
 // CancelTask cancels a running task
 func (jm *JobManager) CancelTask(taskID string) error {
 	jm.logger.Printf("[JobManager] CancelTask: taskID=%s", taskID)
@@ -147,7 +214,32 @@ func (jm *JobManager) GetCoreJobManager() *core.JobManager {
 	return jm.core
 }
 
-// CancelJob cancels the currently active job
+// PauseTask holds a running task between files (backups only)
+func (jm *JobManager) PauseTask(taskID string) error {
+	jm.logger.Printf("[JobManager] PauseTask: taskID=%s", taskID)
+	return jm.core.PauseJob(taskID)
+}
+
+// ResumeTask continues a paused task
+func (jm *JobManager) ResumeTask(taskID string) error {
+	jm.logger.Printf("[JobManager] ResumeTask: taskID=%s", taskID)
+	return jm.core.ResumeJob(taskID)
+}
+
+// SkipFile makes a worker of a running backup give up on the file it is copying (e.g. a stuck
+// transfer); the file counts as failed once and the backup goes on. Returns the file's path.
+func (jm *JobManager) SkipFile(taskID string, worker int) (string, error) {
+	jm.logger.Printf("[JobManager] SkipFile: taskID=%s worker=%d", taskID, worker)
+	return jm.core.SkipFile(taskID, worker)
+}
+
+// CancelAllTasks cancels every running and queued task
+func (jm *JobManager) CancelAllTasks() {
+	jm.logger.Printf("[JobManager] CancelAllTasks")
+	jm.core.CancelAll()
+}
+
+// CancelJob cancels the most recently started running job
 func (jm *JobManager) CancelJob() error {
 	jm.logger.Printf("[JobManager] CancelJob: cancelling active job")
 	err := jm.core.CancelActiveJob()
@@ -190,11 +282,6 @@ func (jm *JobManager) startJob(jobType string) (string, context.Context, error)
 	return jm.startTask(jobType, "Starting "+jobType, nil)
 }
 
-// SetProcessPID is not used in the new model but kept for compatibility
-func (jm *JobManager) SetProcessPID(pid int, pgid int) {
-	// Not used in new Task model
-}
-
 // EmitLogLine emits a log line event for a specific job
 func (jm *JobManager) EmitLogLine(jobID string, logLine string) {
 	jm.core.EmitLogLine(jobID, logLine)
@@ -226,6 +313,10 @@ func coreSnapshotToTask(cs *core.JobSnapshot) *TaskSnapshot {
 		Artifact:  TaskArtifact(cs.Artifact),
 		CreatedAt: cs.CreatedAt,
 		UpdatedAt: cs.UpdatedAt,
+
+		Stats:      cs.Stats,
+		FinishedAt: cs.FinishedAt,
+		DurationMs: cs.DurationMs,
 	}
 
 	if cs.Error != nil {
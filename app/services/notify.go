@@ -0,0 +1,32 @@
+package services
+
+import (
+	"GusSync/pkg/notify"
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// notifier delivers backup notifications for the app: a "notification" event for the
+// frontend, a native desktop notification and the configured webhook
+func (s *CopyService) notifier() notify.Notifier {
+	targets := notify.Multi{
+		notify.Func(func(ctx context.Context, ev notify.Event) error {
+			runtime.EventsEmit(s.ctx, "notification", ev)
+			return nil
+		}),
+		notify.Func(func(ctx context.Context, ev notify.Event) error {
+			// No notification daemon is common enough that it only goes to the log
+			if err := (notify.Desktop{}).Notify(ctx, ev); err != nil {
+				s.logger.Printf("[CopyService] %v", err)
+			}
+			return nil
+		}),
+	}
+	if s.config != nil {
+		if url := s.config.GetConfig().NotifyWebhook; url != "" {
+			targets = append(targets, notify.Webhook{URL: url})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"GusSync/internal/core"
+	"GusSync/pkg/engine"
+)
+
+// backupPhases returns the phases of a backup run so far: the scan, the copy (whose total is
+// known once the scan is done) and, after the last file, finalizing (the report, the hooks
+// and the state). The phase in progress is the last one.
+func backupPhases(update engine.ProgressUpdate) []core.JobPhase {
+	processed := int64(update.Completed + update.Skipped + update.Failed + update.TimeoutSkips)
+	scan := core.JobPhase{Name: core.PhaseScanning, Current: int64(update.TotalFiles), Unit: "files", Done: update.ScanDone}
+	if update.ScanDone {
+		scan.Total = scan.Current
+	}
+	phases := []core.JobPhase{scan}
+	if processed == 0 && !update.ScanDone {
+		return phases
+	}
+	phases = append(phases, core.JobPhase{Name: core.PhaseCopying, Current: processed, Total: scan.Total, Unit: "files", Done: update.ScanComplete})
+	if update.ScanComplete {
+		phases = append(phases, core.JobPhase{Name: core.PhaseFinalizing})
+	}
+	return phases
+}
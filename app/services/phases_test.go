@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"GusSync/internal/core"
+	"GusSync/pkg/engine"
+)
+
+func TestBackupPhases(t *testing.T) {
+	phases := backupPhases(engine.ProgressUpdate{TotalFiles: 40})
+	if len(phases) != 1 || phases[0].Name != core.PhaseScanning || phases[0].Current != 40 || phases[0].Total != 0 {
+		t.Errorf("while scanning: %+v", phases)
+	}
+
+	phases = backupPhases(engine.ProgressUpdate{TotalFiles: 100, Completed: 20, Skipped: 5, ScanDone: true})
+	if len(phases) != 2 || !phases[0].Done || phases[0].Total != 100 {
+		t.Fatalf("after the scan: %+v", phases)
+	}
+	if copying := phases[1]; copying.Name != core.PhaseCopying || copying.Current != 25 || copying.Total != 100 || copying.Done {
+		t.Errorf("copying: %+v", copying)
+	}
+
+	phases = backupPhases(engine.ProgressUpdate{TotalFiles: 100, Completed: 100, ScanDone: true, ScanComplete: true})
+	if len(phases) != 3 || !phases[1].Done || phases[2].Name != core.PhaseFinalizing {
+		t.Errorf("at the end: %+v", phases)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"GusSync/pkg/engine"
 	"context"
 	"fmt"
 	"log"
@@ -189,6 +190,7 @@ func (s *PrereqService) getPrereqReportInternal(forceRefresh bool) PrereqReport
 	}{
 		{"adb", "Android Debug Bridge (ADB)", s.checkADB},
 		{"mtp_tools", "MTP/GVFS Support", s.checkMTPTools},
+		{"confinement", "Sandbox/SELinux/AppArmor", s.checkConfinement},
 		{"device_connection", "Device Connection", s.checkDeviceConnection},
 		{"destination_write", "Destination Write Access", s.checkDestinationWriteAccess},
 		{"disk_space", "Disk Space", s.checkDiskSpace},
@@ -507,6 +509,36 @@ func (s *PrereqService) checkMTPTools() PrereqCheck {
 	return check
 }
 
+// checkConfinement checks for SELinux/AppArmor denials or sandboxing that block gvfs reads
+func (s *PrereqService) checkConfinement() PrereqCheck {
+	check := PrereqCheck{
+		ID:      "confinement",
+		Name:    "Sandbox/SELinux/AppArmor",
+		Status:  "ok",
+		Details: "No confinement blocking MTP/GVFS access was detected.",
+	}
+
+	if goruntime.GOOS != "linux" {
+		check.Details = "Confinement check skipped (not Linux)."
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	diag := engine.DiagnoseConfinement(ctx)
+	if diag == nil {
+		return check
+	}
+
+	check.Status = "warn"
+	check.Details = "Permission errors on the phone are likely caused by " + diag.Mechanism + ": " + diag.Cause
+	check.RemediationSteps = diag.Remediation
+	if len(diag.Evidence) > 0 {
+		check.RemediationSteps = append(check.RemediationSteps, "Recent denial: "+diag.Evidence[len(diag.Evidence)-1])
+	}
+	return check
+}
+
 // checkDeviceConnection checks if any device is connected (ADB or MTP)
 func (s *PrereqService) checkDeviceConnection() PrereqCheck {
 	check := PrereqCheck{
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"GusSync/internal/crash"
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// RestoreService pushes backed-up files picked in the backup browser back to the device
+type RestoreService struct {
+	ctx           context.Context
+	logger        *log.Logger
+	jobManager    *JobManager
+	deviceService *DeviceService
+
+	mu          sync.Mutex
+	lastResults gussync.RestoreResults
+}
+
+// NewRestoreService creates a new RestoreService
+func NewRestoreService(ctx context.Context, logger *log.Logger, jobManager *JobManager, deviceService *DeviceService) *RestoreService {
+	return &RestoreService{
+		ctx:           ctx,
+		logger:        logger,
+		jobManager:    jobManager,
+		deviceService: deviceService,
+	}
+}
+
+// SetContext updates the service context
+func (s *RestoreService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// RestoreRequest selects files of a backup to write back to the device
+type RestoreRequest struct {
+	SourcePath string `json:"sourcePath"` // the device's mount point or adb path ("" = the first connected device)
+	DestPath   string `json:"destPath"`
+	Mode       string `json:"mode"` // "mount" or "adb" ("" = the first backup found)
+	// Paths are files or folders as listed by BrowseBackup, e.g. "DCIM/Camera/IMG_1234.jpg"
+	Paths []string `json:"paths"`
+	// Overwrite replaces device files whose content differs from the backup
+	Overwrite bool `json:"overwrite"`
+	DryRun    bool `json:"dryRun"`
+}
+
+// StartRestore starts restoring files (non-blocking); the results come with the
+// "restore:complete" event and from LastRestoreResults
+func (s *RestoreService) StartRestore(req RestoreRequest) (string, error) {
+	s.logger.Printf("[RestoreService] StartRestore: sourcePath=%s destPath=%s mode=%s paths=%v", req.SourcePath, req.DestPath, req.Mode, req.Paths)
+	if len(req.Paths) == 0 {
+		return "", fmt.Errorf("no files selected")
+	}
+
+	sourcePath := req.SourcePath
+	if sourcePath == "" {
+		devices, _ := s.deviceService.GetDeviceStatus()
+		if len(devices) > 0 {
+			sourcePath = devices[0].Path
+		}
+	}
+	if sourcePath == "" {
+		return "", fmt.Errorf("no device connected")
+	}
+	mode := req.Mode
+	if mode == "" || mode == "auto" {
+		modes := gussync.DetectModes(req.DestPath)
+		if len(modes) == 0 {
+			return "", fmt.Errorf("no backup found in %s", req.DestPath)
+		}
+		mode = modes[0]
+	}
+
+	params := map[string]string{
+		"sourcePath": sourcePath,
+		"destPath":   req.DestPath,
+		"mode":       mode,
+	}
+	return s.jobManager.queueTask("restore.files", "Initializing restore...", params, func(jobCtx context.Context, jobID string) error {
+		defer crash.Recover("restore_service")
+		reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager, phase: "restoring", mode: mode}
+
+		cfg := gussync.Config{
+			SourcePath: sourcePath,
+			Mode:       mode,
+			Reporter:   reporter,
+			Restore:    engine.RestoreOptions{Paths: req.Paths, Overwrite: req.Overwrite, DryRun: req.DryRun},
+
+			PanicHandler: crash.Capture,
+		}
+		e, err := gussync.Open(req.DestPath, cfg)
+		if err != nil {
+			return err
+		}
+		defer e.Close()
+
+		s.jobManager.updateTaskProgress(jobID, TaskProgress{Phase: "restoring"}, fmt.Sprintf("Restoring %d selected items...", len(req.Paths)), nil)
+		results, err := e.Restore(jobCtx)
+
+		s.mu.Lock()
+		s.lastResults = results
+		s.mu.Unlock()
+		s.jobManager.setTaskStats(jobID, map[string]int64{
+			"restored":  int64(results.Restored),
+			"present":   int64(results.Present),
+			"conflicts": int64(results.Conflicts),
+			"failed":    int64(results.Failed),
+			"bytes":     results.Bytes,
+		})
+		runtime.EventsEmit(s.ctx, "restore:complete", map[string]interface{}{
+			"jobId":   jobID,
+			"results": results,
+		})
+		if err != nil {
+			return err
+		}
+		s.jobManager.completeTask(jobID, fmt.Sprintf("Restore complete: %d restored, %d already on the device, %d conflicts, %d failed",
+			results.Restored, results.Present, results.Conflicts, results.Failed))
+		return nil
+	})
+}
+
+// LastRestoreResults returns the results of the last restore (zero before the first one
+// finishes)
+func (s *RestoreService) LastRestoreResults() gussync.RestoreResults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastResults
+}
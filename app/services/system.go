@@ -1,7 +1,9 @@
 package services
 
 import (
+	"GusSync/internal/crash"
 	"context"
+	"fmt"
 	"log"
 	"os/exec"
 	goruntime "runtime"
@@ -10,6 +12,31 @@ import (
 type SystemService struct {
 	ctx    context.Context
 	logger *log.Logger
+	config *ConfigService
+	crash  *crash.Handler
+
+	safeMode       bool
+	unstableStarts int
+	startupGuard   *crash.StartupGuard
+}
+
+// SafeModeStatus explains why the app started in safe mode and what to look at
+type SafeModeStatus struct {
+	Active           bool     `json:"active"`
+	UnstableStartups int      `json:"unstableStartups"`
+	Reason           string   `json:"reason"`
+	Disabled         []string `json:"disabled"`
+	RecentCrashes    []string `json:"recentCrashes"`
+	CrashReportDir   string   `json:"crashReportDir"`
+}
+
+// CrashSettings is the crash reporting state shown in the settings panel
+type CrashSettings struct {
+	UploadEnabled bool   `json:"uploadEnabled"`
+	UploadURL     string `json:"uploadUrl"`
+	ReportDir     string `json:"reportDir"`
+	ReportCount   int    `json:"reportCount"`
+	DataPolicy    string `json:"dataPolicy"`
 }
 
 func NewSystemService(ctx context.Context, logger *log.Logger) *SystemService {
@@ -23,6 +50,94 @@ func (s *SystemService) SetContext(ctx context.Context) {
 	s.ctx = ctx
 }
 
+// SetCrashHandler connects the crash handler and the config that stores its settings
+func (s *SystemService) SetCrashHandler(handler *crash.Handler, config *ConfigService) {
+	s.crash = handler
+	s.config = config
+}
+
+// SetSafeMode records whether this startup is running in safe mode
+func (s *SystemService) SetSafeMode(active bool, unstableStarts int, guard *crash.StartupGuard) {
+	s.safeMode = active
+	s.unstableStarts = unstableStarts
+	s.startupGuard = guard
+}
+
+// GetSafeModeStatus returns safe mode diagnostics for the UI
+func (s *SystemService) GetSafeModeStatus() SafeModeStatus {
+	status := SafeModeStatus{Active: s.safeMode, UnstableStartups: s.unstableStarts}
+	if !s.safeMode {
+		return status
+	}
+
+	status.Reason = fmt.Sprintf("GusSync did not finish starting %d times in a row", s.unstableStarts)
+	status.Disabled = []string{
+		"Restoring saved window position",
+		"Device polling",
+		"Automatic prerequisite checks",
+		"HTTP API server",
+	}
+	if s.crash != nil {
+		status.CrashReportDir = s.crash.Dir()
+		reports, _ := s.crash.Reports()
+		if len(reports) > 5 {
+			reports = reports[:5]
+		}
+		status.RecentCrashes = reports
+	}
+	return status
+}
+
+// ExitSafeMode resets the crash counter so the next launch starts normally
+func (s *SystemService) ExitSafeMode() error {
+	s.logger.Printf("[SystemService] ExitSafeMode: next startup will be normal")
+	if s.startupGuard == nil {
+		return nil
+	}
+	return s.startupGuard.MarkStable()
+}
+
+// GetCrashSettings returns the crash reporting settings and data policy
+func (s *SystemService) GetCrashSettings() CrashSettings {
+	settings := CrashSettings{DataPolicy: crash.DataPolicy}
+	if s.config != nil {
+		cfg := s.config.GetConfig()
+		settings.UploadEnabled = cfg.CrashUploadEnabled
+		settings.UploadURL = cfg.CrashUploadURL
+	}
+	if s.crash != nil {
+		settings.ReportDir = s.crash.Dir()
+		reports, _ := s.crash.Reports()
+		settings.ReportCount = len(reports)
+	}
+	return settings
+}
+
+// SetCrashUpload toggles opt-in upload of crash reports
+func (s *SystemService) SetCrashUpload(enabled bool, url string) error {
+	s.logger.Printf("[SystemService] SetCrashUpload: enabled=%v", enabled)
+	if enabled && url == "" {
+		return fmt.Errorf("an upload URL is required to enable crash report upload")
+	}
+	if s.crash != nil {
+		s.crash.SetUpload(enabled, url)
+	}
+	if s.config == nil {
+		return nil
+	}
+	return s.config.SetCrashUpload(enabled, url)
+}
+
+// ClearCrashReports deletes all local crash reports
+func (s *SystemService) ClearCrashReports() (int, error) {
+	if s.crash == nil {
+		return 0, nil
+	}
+	removed, err := s.crash.ClearReports()
+	s.logger.Printf("[SystemService] ClearCrashReports: removed %d reports", removed)
+	return removed, err
+}
+
 func (s *SystemService) OpenPath(path string) error {
 	s.logger.Printf("[SystemService] OpenPath: %s", path)
 	var cmd *exec.Cmd
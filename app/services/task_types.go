@@ -2,6 +2,8 @@ package services
 
 import (
 	"time"
+
+	"GusSync/internal/core"
 )
 
 type TaskState string
@@ -20,6 +22,9 @@ type TaskProgress struct {
 	Total   int64   `json:"total"`
 	Percent float64 `json:"percent"`
 	Rate    float64 `json:"rate"`
+
+	Phases     []core.JobPhase `json:"phases,omitempty"`     // each phase's totals, in order
+	ETASeconds int64           `json:"etaSeconds,omitempty"` // estimated time left (0 = unknown)
 }
 
 type TaskError struct {
@@ -31,6 +36,7 @@ type TaskError struct {
 type TaskArtifact struct {
 	LogPath     string `json:"logPath"`
 	OpenLogHint string `json:"openLogHint"`
+	ReportPath  string `json:"reportPath,omitempty"`
 }
 
 type TaskSnapshot struct {
@@ -46,6 +52,18 @@ type TaskSnapshot struct {
 	Artifact  TaskArtifact      `json:"artifact"`
 	CreatedAt time.Time         `json:"createdAt"`
 	UpdatedAt time.Time         `json:"updatedAt"`
+
+	Stats      map[string]int64 `json:"stats,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	DurationMs int64            `json:"durationMs,omitempty"`
+}
+
+// TaskHistoryPage is one page of finished tasks, newest first
+type TaskHistoryPage struct {
+	Tasks  []*TaskSnapshot `json:"tasks"`
+	Total  int             `json:"total"`
+	Offset int             `json:"offset"`
+	Limit  int             `json:"limit"`
 }
 
 type TaskUpdateEvent struct {
@@ -1,13 +1,16 @@
 package services
 
 import (
-	"GusSync/pkg/engine"
+	"GusSync/internal/crash"
+	"GusSync/pkg/gussync"
 	"GusSync/pkg/state"
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
+	"sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // VerifyService handles verification operations using the core engine
@@ -16,6 +19,25 @@ type VerifyService struct {
 	logger        *log.Logger
 	jobManager    *JobManager
 	deviceService *DeviceService
+	events        *gussync.EventBus // receives verify:mismatch events (nil = none)
+
+	mu          sync.Mutex
+	lastResults []ModeVerifyResults
+}
+
+// ModeVerifyResults are the results of verifying one mode's backup, including the files
+// that did not verify
+type ModeVerifyResults struct {
+	Mode string `json:"mode"`
+	gussync.VerifyResults
+}
+
+// VerifyIssueEvent is the payload of the "verify:issue" event, emitted as each file that
+// did not verify is found. DestPath is the copy on disk, for opening it.
+type VerifyIssueEvent struct {
+	JobID string `json:"jobId"`
+	Mode  string `json:"mode"`
+	gussync.VerifyIssue
 }
 
 // NewVerifyService creates a new VerifyService
@@ -33,11 +55,22 @@ func (s *VerifyService) SetContext(ctx context.Context) {
 	s.ctx = ctx
 }
 
+// SetEvents sets the bus verifications publish their mismatches on
+func (s *VerifyService) SetEvents(events *gussync.EventBus) {
+	s.events = events
+}
+
 // VerifyRequest represents a verification operation request
 type VerifyRequest struct {
 	SourcePath string `json:"sourcePath"`
 	DestPath   string `json:"destPath"`
 	Mode       string `json:"mode"`
+	// SamplePercent checks only this share of files (0 = all); with Scrub the least
+	// recently verified files are picked instead of a random sample
+	SamplePercent float64 `json:"samplePercent"`
+	Scrub         bool    `json:"scrub"`
+	// DestOnly checks the copies against the hashes in the state, without the phone
+	DestOnly bool `json:"destOnly"`
 }
 
 // StartVerify starts a verification operation (non-blocking)
@@ -63,72 +96,125 @@ func (s *VerifyService) StartVerify(req VerifyRequest) (string, error) {
 		"mode":       req.Mode,
 	}
 
-	jobID, jobCtx, err := s.jobManager.startTask("verify.backup", "Initializing verification...", params)
-	if err != nil {
-		return "", err
-	}
-
 	// Modes to process
 	modes := []string{req.Mode}
 	if req.Mode == "" || req.Mode == "auto" {
-		modes = []string{}
-		if _, err := os.Stat(filepath.Join(req.DestPath, "mount", "gus_state.md")); err == nil {
-			modes = append(modes, "mount")
-		}
-		if _, err := os.Stat(filepath.Join(req.DestPath, "adb", "gus_state.md")); err == nil {
-			modes = append(modes, "adb")
-		}
-	}
-
-	if len(modes) == 0 {
-		s.jobManager.failTask(jobID, fmt.Errorf("no state files found"), "No gus_state.md found in destination")
-		return jobID, nil
+		modes = gussync.DetectModes(req.DestPath)
 	}
 
-	go func() {
+	return s.jobManager.queueTask("verify.backup", "Initializing verification...", params, func(jobCtx context.Context, jobID string) error {
+		defer crash.Recover("verify_service")
+		if len(modes) == 0 {
+			s.jobManager.failTask(jobID, fmt.Errorf("no state files found"), "No gus_state.md found in destination")
+			return nil
+		}
+		var all []ModeVerifyResults
+		var stats struct{ verified, mismatches, missing, quarantined int64 }
 		for _, mode := range modes {
 			select {
 			case <-jobCtx.Done():
-				return
+				return jobCtx.Err()
 			default:
 			}
 
-			fullDestPath := filepath.Join(req.DestPath, mode)
-			reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager}
+			reporter := &WailsReporter{ctx: s.ctx, jobID: jobID, jobManager: s.jobManager, phase: "verifying", mode: mode}
 			reporter.ReportLog("info", fmt.Sprintf("Verifying %s mode...", mode))
-			
-			stateFile := filepath.Join(fullDestPath, "gus_state.md")
-			stateManager, err := state.NewStateManager(stateFile)
-			if err != nil {
-				reporter.ReportError(fmt.Errorf("failed to initialize state for %s: %w", mode, err))
-				continue
-			}
 
-			cfg := engine.EngineConfig{
+			cfg := gussync.Config{
 				SourcePath: sourcePath,
-				DestRoot:   fullDestPath,
 				Mode:       mode,
 				NumWorkers: 2,
 				Reporter:   reporter,
+
+				VerifySample:   req.SamplePercent / 100,
+				VerifyScrub:    req.Scrub,
+				VerifyDestOnly: req.DestOnly,
+				PanicHandler:   crash.Capture,
+				Events:         s.events,
+			}
+			mode := mode
+			cfg.OnVerifyIssue = func(issue gussync.VerifyIssue) {
+				runtime.EventsEmit(s.ctx, "verify:issue", VerifyIssueEvent{
+					JobID:       jobID,
+					Mode:        mode,
+					VerifyIssue: issue,
+				})
 			}
 
-			e := engine.NewEngine(cfg, stateManager)
+			e, err := gussync.Open(req.DestPath, cfg)
+			if err != nil {
+				reporter.ReportError(fmt.Errorf("failed to initialize state for %s: %w", mode, err))
+				continue
+			}
 			s.jobManager.updateTaskProgress(jobID, TaskProgress{Phase: "verifying"}, fmt.Sprintf("Verifying %s...", mode), nil)
 
-			results, err := e.VerifyBackup(jobCtx)
-			stateManager.Close()
+			results, err := e.Verify(jobCtx)
+			e.Close()
 
 			if err != nil {
 				reporter.ReportLog("error", fmt.Sprintf("%s verification failed: %v", mode, err))
 			} else {
 				message := fmt.Sprintf("%s verification complete: %d verified, %d mismatches", mode, results.Verified, results.Mismatches)
 				reporter.ReportLog("info", message)
+				all = append(all, ModeVerifyResults{Mode: mode, VerifyResults: results})
+				stats.verified += int64(results.Verified)
+				stats.mismatches += int64(results.Mismatches)
+				stats.missing += int64(results.MissingSource + results.MissingDest)
+				stats.quarantined += int64(results.Quarantined)
 			}
 		}
+		s.mu.Lock()
+		s.lastResults = all
+		s.mu.Unlock()
+		s.jobManager.setTaskStats(jobID, map[string]int64{
+			"verified":    stats.verified,
+			"mismatches":  stats.mismatches,
+			"missing":     stats.missing,
+			"quarantined": stats.quarantined,
+		})
+		runtime.EventsEmit(s.ctx, "verify:complete", map[string]interface{}{
+			"jobId":   jobID,
+			"results": all,
+		})
 		s.jobManager.completeTask(jobID, "Verification process finished")
-	}()
+		return nil
+	})
+}
+
+// LastVerifyResults returns the results of the last verification, per mode, with the files
+// that did not verify (empty before the first one finishes)
+func (s *VerifyService) LastVerifyResults() []ModeVerifyResults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ModeVerifyResults{}, s.lastResults...)
+}
+
+// QuarantineItem is a quarantined file as shown in the GUI/API
+type QuarantineItem struct {
+	Mode string `json:"mode"`
+	state.QuarantineEntry
+	FullPath string `json:"fullPath"`
+}
 
-	return jobID, nil
+// ListQuarantine returns destination copies that failed verification and were moved
+// to _quarantine, for both mount and adb backups under destPath
+func (s *VerifyService) ListQuarantine(destPath string) ([]QuarantineItem, error) {
+	items := []QuarantineItem{}
+	for _, mode := range gussync.DetectModes(destPath) {
+		stateManager, err := state.NewStateManager(gussync.StateFile(destPath, mode))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s state: %w", mode, err)
+		}
+		for _, entry := range stateManager.GetQuarantined() {
+			items = append(items, QuarantineItem{
+				Mode:            mode,
+				QuarantineEntry: entry,
+				FullPath:        filepath.Join(gussync.ModeDir(destPath, mode), entry.Path),
+			})
+		}
+		stateManager.Close()
+	}
+	return items, nil
 }
 
 // CancelVerify cancels the current verification operation
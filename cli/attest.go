@@ -0,0 +1,181 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/state"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// attestCmd writes a signed manifest of a backup from its state file:
+//
+//	gussync attest -dest <dir> [-mode mount|adb] [-key file]
+//
+// The attestation goes next to the backup in <dest>/<mode>; the key stays on this machine.
+func attestCmd(args []string) int {
+	fs := flag.NewFlagSet("attest", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	modeFlag := fs.String("mode", "", "Backup mode; default: whichever state file exists")
+	keyFlag := fs.String("key", "", "Signing key file, created if missing (default ~/.gussync/attest_key)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	keyPath, err := attestKeyPath(*keyFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	key, err := engine.LoadAttestKey(keyPath, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	m := backupMode(*dest, *modeFlag, "mount")
+	sm, err := state.OpenReadOnly(gussync.StateFile(*dest, m))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer sm.Close()
+
+	a := engine.NewAttestation(sm, key, time.Now())
+	path, err := engine.WriteAttestation(gussync.ModeDir(*dest, m), a)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Attested %d files in %s\n", len(a.Files), path)
+	fmt.Printf("Root hash:  %s\n", a.Root)
+	fmt.Printf("Public key: %s\n", a.PublicKey)
+	return 0
+}
+
+// verifyManifestCmd checks an attestation and then every file of the backup against it:
+//
+//	gussync verify-manifest -dest <dir> [-mode mount|adb] [-manifest file] [-key file | -pubkey base64]
+//
+// The attestation must be signed with the local key (or -pubkey); without either, only its
+// own signature is checked. Exits 1 if anything doesn't match.
+func verifyManifestCmd(args []string) int {
+	fs := flag.NewFlagSet("verify-manifest", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	modeFlag := fs.String("mode", "", "Backup mode; default: whichever state file exists")
+	manifestFlag := fs.String("manifest", "", "Attestation to check (default: the newest in <dest>/<mode>)")
+	keyFlag := fs.String("key", "", "Signing key file whose public key must have signed it (default ~/.gussync/attest_key)")
+	pubKey := fs.String("pubkey", "", "Base64 public key that must have signed it, e.g. on another machine than the one that signed")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	dir := gussync.ModeDir(*dest, backupMode(*dest, *modeFlag, "mount"))
+	path := *manifestFlag
+	if path == "" {
+		var err error
+		if path, err = engine.LatestAttestation(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	a, err := engine.ReadAttestation(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	trusted, err := trustedAttestKey(*pubKey, *keyFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if trusted == nil {
+		fmt.Fprintln(os.Stderr, "Warning: no signing key on this machine (-key or -pubkey); only checking the attestation against its own key")
+	}
+	if err := a.Check(trusted); err != nil {
+		fmt.Fprintf(os.Stderr, "FAILED: %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("%s: signature OK (%d files, root %s, created %s)\n", path, len(a.Files), a.Root, a.CreatedAt.Local().Format("2006-01-02 15:04"))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// A run with -sign-key also signed it with gpg
+	if _, err := os.Stat(path + engine.SignatureSuffix); err == nil {
+		signer, err := engine.NewGPGSigner("")
+		if err == nil {
+			var who string
+			if who, err = signer.Verify(ctx, path); err == nil {
+				fmt.Printf("GPG signature OK (%s)\n", who)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FAILED: %v\n", err)
+			return 1
+		}
+	}
+	results, err := engine.VerifyAttestation(ctx, a, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, p := range results.Missing {
+		fmt.Printf("MISSING   %s\n", p)
+	}
+	for _, p := range results.Mismatched {
+		fmt.Printf("MODIFIED  %s\n", p)
+	}
+	fmt.Printf("%d verified, %d missing, %d modified\n", results.Verified, len(results.Missing), len(results.Mismatched))
+	if !results.OK() {
+		return 1
+	}
+	return 0
+}
+
+// attestKeyPath returns the key file to sign with: explicit, or DefaultAttestKeyPath
+func attestKeyPath(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	return engine.DefaultAttestKeyPath()
+}
+
+// trustedAttestKey returns the public key an attestation must be signed with: pubKey if set,
+// else that of the key file (nil if there is none)
+func trustedAttestKey(pubKey, keyFile string) (ed25519.PublicKey, error) {
+	if pubKey != "" {
+		key, err := base64.StdEncoding.DecodeString(pubKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid -pubkey")
+		}
+		return ed25519.PublicKey(key), nil
+	}
+	keyPath, err := attestKeyPath(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := engine.LoadAttestKey(keyPath, false)
+	if errors.Is(err, os.ErrNotExist) && keyFile == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key.Public().(ed25519.PublicKey), nil
+}
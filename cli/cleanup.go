@@ -0,0 +1,101 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseAge parses a minimum age such as "30d", "2w" or any time.ParseDuration value ("36h")
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", value)
+	}
+	return d, nil
+}
+
+// cleanupPrompter asks once per directory before cleanup deletes files
+type cleanupPrompter struct {
+	in     *bufio.Reader
+	out    io.Writer
+	all    bool   // "a": delete everything from here on without asking
+	cancel func() // "q": stop the cleanup
+}
+
+func newCleanupPrompter(cancel func()) *cleanupPrompter {
+	return &cleanupPrompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, cancel: cancel}
+}
+
+// confirm implements engine.CleanupOptions.Confirm
+func (p *cleanupPrompter) confirm(dir string, files []engine.CleanupCandidate) bool {
+	if p.all {
+		return true
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	fmt.Fprintf(p.out, "\n%s: %d verified files (%s)\n", dir, len(files), engine.FormatSize(total))
+	for i, f := range files {
+		if i == 5 {
+			fmt.Fprintf(p.out, "  ... and %d more\n", len(files)-i)
+			break
+		}
+		fmt.Fprintf(p.out, "  %s (%s)\n", filepath.Base(f.SourcePath), engine.FormatSize(f.Size))
+	}
+
+	for {
+		fmt.Fprint(p.out, "Delete from device? [y]es / [n]o / [a]ll remaining / [q]uit: ")
+		answer, err := p.in.ReadString('\n')
+		if err != nil {
+			// stdin closed: never delete without an answer
+			p.cancel()
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		case "n", "no", "":
+			return false
+		case "a", "all":
+			p.all = true
+			return true
+		case "q", "quit":
+			p.cancel()
+			return false
+		}
+	}
+}
+
+// printCleanupPlan lists the files a dry run would delete, with sizes
+func printCleanupPlan(planned []engine.CleanupCandidate) {
+	var total int64
+	for _, c := range planned {
+		total += c.Size
+		backedUp := "unknown"
+		if !c.BackedUpAt.IsZero() {
+			backedUp = c.BackedUpAt.Local().Format("2006-01-02")
+		}
+		fmt.Printf("  would delete %10s  backed up %s  %s\n", engine.FormatSize(c.Size), backedUp, c.SourcePath)
+	}
+	fmt.Printf("\nDry run: %d files, %s would be freed. Nothing was deleted.\n", len(planned), engine.FormatSize(total))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"2w":   14 * 24 * time.Hour,
+		"12h":  12 * time.Hour,
+		"1.5d": 36 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := parseAge(in)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "thirty days", "-3d"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("parseAge(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseTimeLimit(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	got, err := parseTimeLimit("30d", now)
+	if err != nil || !got.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("parseTimeLimit(30d) = %v, %v", got, err)
+	}
+	got, err = parseTimeLimit("2024-06-01", now)
+	if err != nil || !got.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("parseTimeLimit(2024-06-01) = %v, %v", got, err)
+	}
+	if _, err := parseTimeLimit("last month", now); err == nil {
+		t.Error("parseTimeLimit should reject an unknown value")
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/profile"
+	"GusSync/pkg/state"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// subcommands maps the first CLI argument to its handler; handlers return the exit code
+var subcommands = map[string]func(args []string) int{
+	"attest":          attestCmd,
+	"backup":          backupCmd,
+	"cleanup":         cleanupCmd,
+	"daemon":          daemonCmd,
+	"export-hashes":   exportHashesCmd,
+	"install-udev":    installUdevCmd,
+	"jobs":            jobsCmd,
+	"migrate":         migrateCmd,
+	"profile":         profileCmd,
+	"quarantine":      quarantineCmd,
+	"restore":         restoreCmd,
+	"state":           stateCmd,
+	"status":          statusCmd,
+	"trigger":         triggerCmd,
+	"verify-manifest": verifyManifestCmd,
+	"watch":           watchCmd,
+}
+
+// backupCmd runs a backup, optionally from a saved profile:
+//
+//	gussync backup --profile pixel7 [-workers 4 ...]
+//
+// Explicit flags override the profile's values.
+func backupCmd(args []string) int {
+	profileName, rest := extractFlag(args, "profile")
+
+	if profileName != "" {
+		store, err := profile.OpenDefault()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		p, err := store.Get(profileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := applyProfile(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: profile %s: %v\n", p.Name, err)
+			return 1
+		}
+	}
+
+	if err := flag.CommandLine.Parse(rest); err != nil {
+		return 2
+	}
+	run()
+	return 0
+}
+
+// cleanupCmd is shorthand for -mode cleanup:
+//
+//	gussync cleanup --free 20G -source /sdcard -dest /backup
+func cleanupCmd(args []string) int {
+	mode = "cleanup"
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return 2
+	}
+	run()
+	return 0
+}
+
+// watchCmd backs up a mounted source and then keeps copying new files until interrupted:
+//
+//	gussync watch -source /run/user/1000/gvfs/mtp:host=.../Internal\ shared\ storage -dest /backup [-poll 10m] [-settle 5s]
+func watchCmd(args []string) int {
+	watch = true
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return 2
+	}
+	if mode != "mount" {
+		fmt.Fprintln(os.Stderr, "Error: watch needs a mounted source (-mode mount)")
+		return 2
+	}
+	run()
+	return 0
+}
+
+// applyProfile sets the global flag values from a profile (before explicit flags are parsed)
+func applyProfile(p profile.Profile) error {
+	source, err := p.ResolveSource()
+	if err != nil {
+		return err
+	}
+	sourcePath = source
+	destPath = p.Destination
+	mode = p.Mode
+	if p.Workers > 0 {
+		numWorkers = p.Workers
+	}
+	folders = strings.Join(p.ScanRoots, ",")
+	excludes = strings.Join(p.Excludes, ",")
+	only = strings.Join(p.Only, ",")
+	presets = strings.Join(p.Apps, ",")
+	phoneData = strings.Join(p.PhoneData, ",")
+	apks = p.APKs
+	priority = strings.Join(p.Priority, ",")
+	noPriority = p.NoPriority
+	bandwidth = p.Bandwidth
+	preBackup = p.PreBackup
+	postBackup = p.PostBackup
+
+	// The engine's adb calls go to this device when several are attached
+	if p.Mode == "adb" {
+		serial = p.DeviceSerial
+	}
+	return nil
+}
+
+// profileCmd manages saved profiles:
+//
+//	gussync profile list
+//	gussync profile show <name>
+//	gussync profile save -name <name> -dest <dir> [-source ... -serial ... -mode ... -folders ... -exclude ... -priority ... -workers ... -bandwidth ... -pre-backup ... -post-backup ...]
+//	gussync profile delete <name>
+func profileCmd(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: gussync profile <list|show|save|delete> [args]")
+		return 2
+	}
+
+	store, err := profile.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		profiles, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tMODE\tSOURCE\tDESTINATION\tFOLDERS")
+		for _, p := range profiles {
+			source := p.SourcePath
+			if source == "" {
+				source = "device:" + p.DeviceSerial
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Mode, source, p.Destination, strings.Join(p.ScanRoots, ","))
+		}
+		w.Flush()
+		return 0
+
+	case "show":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: gussync profile show <name>")
+			return 2
+		}
+		p, err := store.Get(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Name:        %s\n", p.Name)
+		fmt.Printf("Mode:        %s\n", p.Mode)
+		fmt.Printf("Device:      %s\n", p.DeviceSerial)
+		fmt.Printf("Source:      %s\n", p.SourcePath)
+		fmt.Printf("Folders:     %s\n", strings.Join(p.ScanRoots, ", "))
+		fmt.Printf("Destination: %s\n", p.Destination)
+		fmt.Printf("Excludes:    %s\n", strings.Join(p.Excludes, ", "))
+		fmt.Printf("Only:        %s\n", strings.Join(p.Only, ", "))
+		if len(p.Apps) > 0 {
+			fmt.Printf("Apps:        %s\n", strings.Join(p.Apps, ", "))
+		}
+		if len(p.PhoneData) > 0 {
+			fmt.Printf("Phone data:  %s\n", strings.Join(p.PhoneData, ", "))
+		}
+		if p.APKs {
+			fmt.Printf("APKs:        yes\n")
+		}
+		switch {
+		case p.NoPriority:
+			fmt.Printf("Priority:    off\n")
+		case len(p.Priority) > 0:
+			fmt.Printf("Priority:    %s\n", strings.Join(p.Priority, ", "))
+		}
+		fmt.Printf("Workers:     %d\n", p.Workers)
+		fmt.Printf("Bandwidth:   %s\n", p.Bandwidth)
+		if p.PreBackup != "" {
+			fmt.Printf("Pre-backup:  %s\n", p.PreBackup)
+		}
+		if p.PostBackup != "" {
+			fmt.Printf("Post-backup: %s\n", p.PostBackup)
+		}
+		return 0
+
+	case "save":
+		fs := flag.NewFlagSet("profile save", flag.ContinueOnError)
+		var p profile.Profile
+		var scanRoots, excludeList, onlyList, appList, phoneDataList, priorityList string
+		fs.StringVar(&p.Name, "name", "", "Profile name")
+		fs.StringVar(&p.DeviceSerial, "serial", "", "ADB serial or MTP device id")
+		fs.StringVar(&p.SourcePath, "source", "", "Source root (defaults to the device's mount or /sdcard)")
+		fs.StringVar(&p.Destination, "dest", "", "Destination directory")
+		fs.StringVar(&p.Mode, "mode", "mount", "Backup mode: 'mount' or 'adb'")
+		fs.StringVar(&scanRoots, "folders", "", "Comma-separated folders to back up")
+		fs.StringVar(&excludeList, "exclude", "", "Comma-separated exclude globs")
+		fs.StringVar(&onlyList, "only", "", "Comma-separated media types to back up ("+strings.Join(engine.MediaPresetNames(), ", ")+")")
+		fs.StringVar(&appList, "preset", "", "Comma-separated messenger apps to back up ("+strings.Join(engine.AppPresetNames(), ", ")+")")
+		fs.StringVar(&phoneDataList, "phone-data", "", "ADB mode: comma-separated phone data to export ("+strings.Join(engine.PhoneDataKinds, ", ")+")")
+		fs.BoolVar(&p.APKs, "apks", false, "ADB mode: also back up the installed apps' APKs")
+		fs.StringVar(&priorityList, "priority", "", "Comma-separated folders to scan first, in order")
+		fs.BoolVar(&p.NoPriority, "no-priority", false, "Scan without priority folders")
+		fs.IntVar(&p.Workers, "workers", 2, "Number of worker threads")
+		fs.StringVar(&p.Bandwidth, "bandwidth", "", "Bandwidth limit or schedule")
+		fs.StringVar(&p.PreBackup, "pre-backup", "", "Command to run before each backup")
+		fs.StringVar(&p.PostBackup, "post-backup", "", "Command to run after each backup")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		p.ScanRoots = splitList(scanRoots)
+		p.Excludes = splitList(excludeList)
+		p.Only = splitList(onlyList)
+		p.Apps = splitList(appList)
+		p.PhoneData = splitList(phoneDataList)
+		if p.Priority, err = engine.NormalizePriorityPaths(splitList(priorityList)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(p.Priority) == 0 {
+			p.Priority = nil
+		}
+		if err := store.Save(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Saved profile %s\n", p.Name)
+		return 0
+
+	case "delete":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: gussync profile delete <name>")
+			return 2
+		}
+		if err := store.Delete(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Deleted profile %s\n", args[1])
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown profile command %q\n", args[0])
+	return 2
+}
+
+// quarantineCmd lists destination copies that failed verification and were moved to _quarantine:
+//
+//	gussync quarantine list -dest <dir> [-mode mount|adb] [-json]
+func quarantineCmd(args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "Usage: gussync quarantine list -dest <dir> [-mode mount|adb] [-json]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("quarantine list", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	backupMode := fs.String("mode", "", "Backup mode to list ('mount' or 'adb'); default both")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	modes := []string{"mount", "adb"}
+	if *backupMode != "" {
+		modes = []string{*backupMode}
+	}
+
+	type item struct {
+		Mode string `json:"mode"`
+		state.QuarantineEntry
+	}
+	var items []item
+	for _, m := range modes {
+		stateFile := gussync.StateFile(*dest, m)
+		if _, err := os.Stat(stateFile); err != nil {
+			continue
+		}
+		sm, err := state.OpenReadOnly(stateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		for _, entry := range sm.GetQuarantined() {
+			items = append(items, item{Mode: m, QuarantineEntry: entry})
+		}
+		sm.Close()
+	}
+
+	if *asJSON {
+		if items == nil {
+			items = []item{}
+		}
+		json.NewEncoder(os.Stdout).Encode(items)
+		return 0
+	}
+	if len(items) == 0 {
+		fmt.Println("No quarantined files")
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WHEN\tMODE\tSOURCE\tQUARANTINED\tREASON")
+	for _, it := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", it.At.Local().Format("2006-01-02 15:04"), it.Mode, it.SourcePath,
+			filepath.Join(*dest, it.Mode, it.Path), it.Reason)
+	}
+	w.Flush()
+	return 0
+}
+
+// extractFlag removes -name/--name value (or -name=value) from args and returns its value
+func extractFlag(args []string, name string) (string, []string) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		trimmed := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		if trimmed == name && i+1 < len(args) {
+			value = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(trimmed, name+"=") {
+			value = strings.TrimPrefix(trimmed, name+"=")
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return value, rest
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"GusSync/pkg/apiclient"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultSocketPath is where gussync daemon listens and gussync status and jobs connect:
+// $XDG_RUNTIME_DIR/gussync.sock, or ~/.gussync/daemon.sock without a runtime directory
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gussync.sock")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "gussync.sock")
+	}
+	return filepath.Join(home, ".gussync", "daemon.sock")
+}
+
+// daemonClient connects to the daemon's control socket, failing with a hint if no daemon
+// answers there
+func daemonClient(ctx context.Context, socket string) (*apiclient.Client, error) {
+	if socket == "" {
+		socket = defaultSocketPath()
+	}
+	client := apiclient.NewUnix(socket)
+	if _, err := client.GetHealth(ctx); err != nil {
+		return nil, fmt.Errorf("no gussync daemon on %s (start one with gussync daemon): %w", socket, err)
+	}
+	return client, nil
+}
+
+// statusCmd shows what a running daemon is doing:
+//
+//	gussync status [-socket path] [-json]
+func statusCmd(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	socket := fs.String("socket", "", "Daemon control socket (default: as gussync daemon)")
+	asJSON := fs.Bool("json", false, "Print the devices and jobs as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := daemonClient(ctx, *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	devices, err := client.ListDevices(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	jobs, err := client.ListJobs(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"devices": devices.Devices, "jobs": jobs})
+		return 0
+	}
+	fmt.Printf("Daemon running: %d running, %d queued\n", len(jobs.Running), len(jobs.Queued))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tDEVICE\tCONNECTED\tSOURCE")
+	for _, d := range devices.Devices {
+		connected := "no"
+		if d.Connected {
+			connected = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Name, d.ID, connected, d.Path)
+	}
+	w.Flush()
+	for _, job := range jobs.Jobs {
+		if job.State == apiclient.JobRunning || job.State == apiclient.JobPaused {
+			fmt.Printf("\n%s (%s): %s\n", job.JobID, job.State, job.Message)
+			if job.Progress.ETASeconds > 0 {
+				fmt.Printf("  %.1f%% at %.1f MB/s, %s left\n", job.Progress.Percent, job.Progress.Rate, time.Duration(job.Progress.ETASeconds)*time.Second)
+			}
+		}
+	}
+	return 0
+}
+
+// jobsCmd lists and controls a running daemon's jobs:
+//
+//	gussync jobs [-socket path] [-json]
+//	gussync jobs [-socket path] cancel|pause|resume <job id>
+func jobsCmd(args []string) int {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	socket := fs.String("socket", "", "Daemon control socket (default: as gussync daemon)")
+	asJSON := fs.Bool("json", false, "Print the jobs as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := daemonClient(ctx, *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if fs.NArg() > 0 {
+		actions := map[string]func(context.Context, string) error{
+			"cancel": client.CancelJob,
+			"pause":  client.PauseJob,
+			"resume": client.ResumeJob,
+		}
+		action, ok := actions[fs.Arg(0)]
+		if !ok || fs.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: gussync jobs [cancel|pause|resume <job id>]")
+			return 2
+		}
+		if err := action(ctx, fs.Arg(1)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	list, err := client.ListJobs(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(list.Jobs)
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTATE\tPROFILE\tPROGRESS\tSTARTED\tMESSAGE")
+	for _, job := range list.Jobs {
+		progress := ""
+		if job.Progress.Total > 0 {
+			progress = fmt.Sprintf("%d/%d", job.Progress.Current, job.Progress.Total)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", job.JobID, job.State, job.Params["profile"], progress,
+			job.CreatedAt.Local().Format("2006-01-02 15:04"), job.Message)
+	}
+	w.Flush()
+	return 0
+}
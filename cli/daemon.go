@@ -0,0 +1,492 @@
+package main
+
+import (
+	"GusSync/internal/adapters/api"
+	"GusSync/internal/core"
+	"GusSync/internal/crash"
+	"GusSync/pkg/apiclient"
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/profile"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// daemonCmd runs GusSync as a long-lived service:
+//
+//	gussync daemon [-profile a,b] [-every 24h] [-poll 30s] [-socket path] [-port 8090]
+//
+// It watches for the profiles' devices, backs one up when it connects (and again every
+// -every while it stays connected), and serves the API on a Unix control socket for
+// gussync status and gussync jobs. Under systemd the socket can be passed in by a .socket
+// unit (socket activation) instead.
+func daemonCmd(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	profiles := fs.String("profile", "", "Comma-separated profiles to back up (default: all saved profiles)")
+	every := fs.Duration("every", 24*time.Hour, "Back up a connected device again after this long (0 = only when it connects)")
+	poll := fs.Duration("poll", 30*time.Second, "How often to check which devices are connected")
+	socket := fs.String("socket", "", "Control socket (default $XDG_RUNTIME_DIR/gussync.sock or ~/.gussync/daemon.sock)")
+	port := fs.Int("port", 0, "Also serve the HTTP API on this TCP port on localhost (0 = only the control socket)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *poll <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -poll must be positive")
+		return 2
+	}
+
+	crash.Install(crash.Options{
+		UploadEnabled: os.Getenv("GUSSYNC_CRASH_UPLOAD") != "",
+		Endpoint:      os.Getenv("GUSSYNC_CRASH_UPLOAD"),
+	})
+	defer crash.Recover("daemon")
+
+	store, err := profile.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	jm := core.NewJobManager(nil)
+	if home, err := os.UserHomeDir(); err == nil {
+		jm.SetHistory(core.NewFileHistory(filepath.Join(home, ".gussync", "history.jsonl"), 0), func(err error) {
+			logger.Printf("[daemon] %v", err)
+		})
+	}
+	d := newDaemon(jm, store, splitList(*profiles), *every, logger)
+
+	server := api.NewServer(*port, logger, jm,
+		api.WithDeviceProvider(func() interface{} { return d.devices() }),
+		api.WithStartCopyFunc(func(ctx context.Context, req api.StartCopyRequest) (string, error) {
+			return d.start(ctx, req.Profile)
+		}),
+	)
+	jm.AddEmitter(server)
+
+	path := *socket
+	if path == "" {
+		path = defaultSocketPath()
+	}
+	listener, activated, err := controlListener(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !activated {
+		defer os.Remove(path)
+	}
+	control := &http.Server{Handler: server.Handler()}
+	go func() {
+		if err := control.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Printf("[daemon] Control socket: %v", err)
+		}
+	}()
+	logger.Printf("[daemon] Listening on %s", path)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *port > 0 {
+		server.StartBackground(ctx)
+	}
+
+	d.run(ctx, *poll)
+
+	logger.Printf("[daemon] Shutting down")
+	jm.CancelAll()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	control.Shutdown(shutdownCtx)
+	return 0
+}
+
+// controlListener returns the control socket: the one systemd passed in (LISTEN_FDS), or a
+// new one at path that only this user can connect to
+func controlListener(path string) (l net.Listener, activated bool, err error) {
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid == os.Getpid() {
+		if n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS")); n >= 1 {
+			os.Unsetenv("LISTEN_PID")
+			os.Unsetenv("LISTEN_FDS")
+			os.Unsetenv("LISTEN_FDNAMES")
+			// Passed sockets start at fd 3 (SD_LISTEN_FDS_START)
+			l, err := net.FileListener(os.NewFile(3, "systemd-socket"))
+			if err != nil {
+				return nil, false, fmt.Errorf("socket passed by systemd: %w", err)
+			}
+			return l, true, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, false, err
+	}
+	// A socket left behind by a daemon that didn't shut down cleanly: remove it, unless
+	// another daemon still answers on it
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("a daemon is already running on %s", path)
+	}
+	os.Remove(path)
+	l, err = net.Listen("unix", path)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, false, err
+	}
+	return l, false, nil
+}
+
+// daemon decides when each profile is backed up
+type daemon struct {
+	jobs   *core.JobManager
+	store  *profile.Store
+	names  []string // profiles to watch; nil = all
+	every  time.Duration
+	logger *log.Logger
+
+	// present reports whether a profile's device is connected, and its source root;
+	// replaced in tests
+	present func(ctx context.Context, p profile.Profile) (string, bool)
+	// backup is the body of a profile's backup job; replaced in tests
+	backup func(ctx context.Context, jobID string, p profile.Profile, source string) error
+
+	mu        sync.Mutex
+	connected map[string]string    // profile -> source root, for the profiles seen at the last poll
+	lastRun   map[string]time.Time // profile -> when its last backup was queued
+	jobIDs    map[string]string    // profile -> its last backup job
+	watched   []profile.Profile    // as of the last poll
+}
+
+func newDaemon(jm *core.JobManager, store *profile.Store, names []string, every time.Duration, logger *log.Logger) *daemon {
+	d := &daemon{
+		jobs:      jm,
+		store:     store,
+		names:     names,
+		every:     every,
+		logger:    logger,
+		present:   devicePresent,
+		connected: make(map[string]string),
+		lastRun:   make(map[string]time.Time),
+		jobIDs:    make(map[string]string),
+	}
+	d.backup = d.runBackup
+	return d
+}
+
+// run polls until ctx is done
+func (d *daemon) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.poll(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll checks every watched profile's device and queues a backup for each that just
+// connected, or has stayed connected for -every since its last backup. Profiles are read
+// again each time, so saved changes apply without a restart.
+func (d *daemon) poll(ctx context.Context, now time.Time) {
+	profiles, err := d.profiles()
+	if err != nil {
+		d.logger.Printf("[daemon] %v", err)
+		return
+	}
+
+	// Looking for devices can take a while (adb); the lock is only held for the decisions
+	connected := make(map[string]string)
+	for _, p := range profiles {
+		if source, ok := d.present(ctx, p); ok {
+			connected[p.Name] = source
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.watched = profiles
+	for _, p := range profiles {
+		source, ok := connected[p.Name]
+		if !ok {
+			if _, was := d.connected[p.Name]; was {
+				d.logger.Printf("[daemon] %s disconnected", p.Name)
+			}
+			continue
+		}
+		_, was := d.connected[p.Name]
+		if !was {
+			d.logger.Printf("[daemon] %s connected (%s)", p.Name, source)
+		}
+		due := !was || (d.every > 0 && now.Sub(d.lastRun[p.Name]) >= d.every)
+		if !due || d.busyLocked(p.Name) {
+			continue
+		}
+		if err := d.enqueueLocked(ctx, p, source, now); err != nil {
+			d.logger.Printf("[daemon] %s: %v", p.Name, err)
+		}
+	}
+	d.connected = connected
+}
+
+// start backs up a profile now, if its device is connected (gussync trigger, from udev);
+// it returns the backup's job, which is the one already queued or running if there is one
+func (d *daemon) start(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("the daemon backs up saved profiles: no profile given")
+	}
+	p, err := d.store.Get(name)
+	if err != nil {
+		return "", err
+	}
+	source, ok := d.present(ctx, p)
+	if !ok {
+		return "", fmt.Errorf("%s is not connected", name)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.connected[name] = source // so the next poll doesn't take it for a new connection
+	if d.busyLocked(name) {
+		return d.jobIDs[name], nil
+	}
+	if err := d.enqueueLocked(context.Background(), p, source, time.Now()); err != nil {
+		return "", err
+	}
+	return d.jobIDs[name], nil
+}
+
+// profiles returns the watched profiles
+func (d *daemon) profiles() ([]profile.Profile, error) {
+	if len(d.names) == 0 {
+		return d.store.List()
+	}
+	profiles := make([]profile.Profile, 0, len(d.names))
+	for _, name := range d.names {
+		p, err := d.store.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// busyLocked reports whether the profile's last backup is still queued or running
+func (d *daemon) busyLocked(name string) bool {
+	id, ok := d.jobIDs[name]
+	if !ok {
+		return false
+	}
+	job, err := d.jobs.GetJob(id)
+	if err != nil {
+		return false
+	}
+	switch job.State {
+	case core.JobSucceeded, core.JobFailed, core.JobCanceled:
+		return false
+	}
+	return true
+}
+
+func (d *daemon) enqueueLocked(ctx context.Context, p profile.Profile, source string, now time.Time) error {
+	params := map[string]string{
+		core.ParamDevice:     p.DeviceSerial,
+		core.ParamSourcePath: source,
+		core.ParamDestPath:   p.Destination,
+		"mode":               p.Mode,
+		"profile":            p.Name,
+	}
+	jobID, err := d.jobs.EnqueueJob(ctx, "copy.sync", "Backing up "+p.Name, params, func(jobCtx context.Context, jobID string) error {
+		defer crash.Recover("daemon")
+		return d.backup(jobCtx, jobID, p, source)
+	})
+	if err != nil {
+		return err
+	}
+	d.lastRun[p.Name] = now
+	d.jobIDs[p.Name] = jobID
+	d.logger.Printf("[daemon] %s: backup queued (job %s)", p.Name, jobID)
+	return nil
+}
+
+// devices lists the watched profiles for GET /api/devices
+func (d *daemon) devices() apiclient.Devices {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := apiclient.Devices{Devices: []apiclient.Device{}}
+	for _, p := range d.watched {
+		source, connected := d.connected[p.Name]
+		kind := "mtp"
+		if p.Mode == "adb" {
+			kind = "adb"
+		}
+		list.Devices = append(list.Devices, apiclient.Device{ID: p.DeviceSerial, Name: p.Name, Type: kind, Path: source, Connected: connected})
+		list.Connected = list.Connected || connected
+	}
+	return list
+}
+
+// runBackup backs up a profile as a job of the daemon: progress goes to the job, and the
+// API's pause and skip controls reach the run
+func (d *daemon) runBackup(ctx context.Context, jobID string, p profile.Profile, source string) error {
+	cfg, err := profileConfig(p, source)
+	if err != nil {
+		return err
+	}
+	cfg.Reporter = &jobReporter{jobs: d.jobs, jobID: jobID, name: p.Name, logger: d.logger}
+	cfg.Pause = d.jobs.PauseGate(jobID)
+	e, err := gussync.Open(p.Destination, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+	d.jobs.SetSkipper(jobID, e.SkipFile)
+	if err := e.Backup(ctx); err != nil {
+		return err
+	}
+	if path := e.ReportPath(); path != "" {
+		d.jobs.SetArtifact(jobID, core.JobArtifact{ReportPath: path})
+	}
+	return nil
+}
+
+// profileConfig is the engine configuration for a profile's backup, with the defaults of
+// gussync backup --profile
+func profileConfig(p profile.Profile, source string) (gussync.Config, error) {
+	workers := p.Workers
+	if workers < 1 {
+		workers = 2
+	}
+	cfg := gussync.Config{
+		SourcePath:    source,
+		Mode:          p.Mode,
+		NumWorkers:    workers,
+		Retry:         engine.DefaultRetryPolicy(),
+		ScanRoots:     p.ScanRoots,
+		Excludes:      p.Excludes,
+		Only:          p.Only,
+		Apps:          p.Apps,
+		PhoneData:     p.PhoneData,
+		APKs:          p.APKs,
+		PriorityPaths: p.Priority,
+		NoPriority:    p.NoPriority,
+		PanicHandler:  crash.Capture,
+		ReconnectWait: engine.DefaultReconnectWait,
+		RemountStale:  true,
+		MinFreeSpace:  engine.DefaultMinFreeSpace,
+		Hooks:         engine.Hooks{PreBackup: p.PreBackup, PostBackup: p.PostBackup},
+	}
+	if p.Mode == "adb" {
+		cfg.Serial = p.DeviceSerial
+	}
+	if p.Bandwidth != "" {
+		schedule, err := engine.ParseBandwidthSchedule(p.Bandwidth)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid bandwidth: %w", err)
+		}
+		cfg.Bandwidth = schedule
+	}
+	return cfg, nil
+}
+
+// devicePresent finds a profile's device: its mount in mount mode, or an authorized adb
+// device with its serial (any, without one) in adb mode
+func devicePresent(ctx context.Context, p profile.Profile) (string, bool) {
+	source, err := p.ResolveSource()
+	if err != nil {
+		return "", false
+	}
+	if p.Mode != "adb" {
+		info, err := os.Stat(source)
+		return source, err == nil && info.IsDir()
+	}
+	serials, err := adbDevices(ctx)
+	if err != nil {
+		return "", false
+	}
+	for _, serial := range serials {
+		if p.DeviceSerial == "" || serial == p.DeviceSerial {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// adbDevices returns the serials of the authorized devices adb sees
+func adbDevices(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "adb", "devices").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseADBDevices(string(output)), nil
+}
+
+// parseADBDevices picks the serials in the "device" state out of adb devices' output
+// (not "unauthorized" or "offline")
+func parseADBDevices(output string) []string {
+	var serials []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "device" {
+			serials = append(serials, fields[0])
+		}
+	}
+	return serials
+}
+
+// jobReporter turns a daemon backup's progress into job updates
+type jobReporter struct {
+	jobs   *core.JobManager
+	jobID  string
+	name   string
+	logger *log.Logger
+}
+
+func (r *jobReporter) ReportProgress(update engine.ProgressUpdate) {
+	progress := core.JobProgress{
+		Phase:      core.PhaseCopying,
+		Current:    int64(update.Completed),
+		Total:      int64(update.TotalFiles),
+		Rate:       update.Rate / (1024 * 1024), // MB/s
+		ETASeconds: update.ETASeconds,
+	}
+	if update.TotalFiles > 0 {
+		progress.Percent = float64(update.Completed) / float64(update.TotalFiles) * 100
+	}
+	if !update.ScanComplete {
+		progress.Phase = core.PhaseScanning
+	}
+	message := fmt.Sprintf("%s: copied %d/%d files", r.name, update.Completed, update.TotalFiles)
+	r.jobs.UpdateProgress(r.jobID, progress, message, update.WorkerStatuses)
+}
+
+func (r *jobReporter) ReportError(err error) {
+	r.logger.Printf("[daemon] %s: %v", r.name, err)
+}
+
+func (r *jobReporter) ReportLog(level, message string) {
+	if level == "warn" || level == "error" {
+		r.logger.Printf("[daemon] %s: %s", r.name, message)
+	}
+	r.jobs.EmitLogLine(r.jobID, message)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"GusSync/internal/adapters/api"
+	"GusSync/internal/core"
+	"GusSync/pkg/apiclient"
+	"GusSync/pkg/profile"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDaemonPoll(t *testing.T) {
+	store := profile.NewStore(filepath.Join(t.TempDir(), "profiles.json"))
+	for _, name := range []string{"pixel", "tablet"} {
+		if err := store.Save(profile.Profile{Name: name, Mode: "mount", SourcePath: "/phone/" + name, Destination: "/backup/" + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jm := core.NewJobManager(nil)
+	jm.SetMaxConcurrent(0)
+	d := newDaemon(jm, store, nil, time.Hour, log.New(io.Discard, "", 0))
+	var mu sync.Mutex
+	connected := map[string]bool{}
+	var backups []string
+	release := make(chan struct{})
+	d.present = func(ctx context.Context, p profile.Profile) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return p.SourcePath, connected[p.Name]
+	}
+	d.backup = func(ctx context.Context, jobID string, p profile.Profile, source string) error {
+		mu.Lock()
+		backups = append(backups, p.Name)
+		mu.Unlock()
+		<-release
+		return nil
+	}
+	setConnected := func(name string, ok bool) {
+		mu.Lock()
+		connected[name] = ok
+		mu.Unlock()
+	}
+	waitIdle := func() {
+		t.Helper()
+		for i := 0; i < 200 && len(jm.RunningJobs())+len(jm.QueuedJobs()) > 0; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d.poll(ctx, now)
+	if len(jm.ListJobs()) != 0 {
+		t.Fatal("backed up with nothing connected")
+	}
+
+	// Connecting starts a backup; polls while it runs don't start another
+	setConnected("pixel", true)
+	d.poll(ctx, now)
+	d.poll(ctx, now.Add(2*time.Hour))
+	if jobs := jm.ListJobs(); len(jobs) != 1 || jobs[0].Params["profile"] != "pixel" {
+		t.Fatalf("jobs after connecting: %+v", jobs)
+	}
+	close(release)
+	waitIdle()
+
+	// Staying connected: again after -every, not before
+	d.poll(ctx, now.Add(30*time.Minute))
+	if n := len(jm.ListJobs()); n != 1 {
+		t.Errorf("%d jobs before -every passed, want 1", n)
+	}
+	d.poll(ctx, now.Add(61*time.Minute))
+	waitIdle()
+	if n := len(jm.ListJobs()); n != 2 {
+		t.Errorf("%d jobs after -every passed, want 2", n)
+	}
+
+	// Reconnecting backs up at once
+	setConnected("pixel", false)
+	d.poll(ctx, now.Add(62*time.Minute))
+	setConnected("pixel", true)
+	setConnected("tablet", true)
+	d.poll(ctx, now.Add(63*time.Minute))
+	waitIdle()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(backups) != 4 {
+		t.Errorf("backups = %v, want pixel three times and tablet once", backups)
+	}
+
+	devices := d.devices()
+	if len(devices.Devices) != 2 || !devices.Connected || devices.Devices[0].Path != "/phone/pixel" {
+		t.Errorf("devices = %+v", devices)
+	}
+}
+
+func TestDaemonStart(t *testing.T) {
+	store := profile.NewStore(filepath.Join(t.TempDir(), "profiles.json"))
+	store.Save(profile.Profile{Name: "pixel", Mode: "mount", SourcePath: "/phone", Destination: "/backup"})
+	jm := core.NewJobManager(nil)
+	d := newDaemon(jm, store, nil, 0, log.New(io.Discard, "", 0))
+	connected := false
+	release := make(chan struct{})
+	defer close(release)
+	d.present = func(ctx context.Context, p profile.Profile) (string, bool) { return p.SourcePath, connected }
+	d.backup = func(ctx context.Context, jobID string, p profile.Profile, source string) error {
+		<-release
+		return nil
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{"", "tablet", "pixel"} {
+		if _, err := d.start(ctx, name); err == nil {
+			t.Errorf("start(%q): expected an error", name)
+		}
+	}
+	connected = true
+	first, err := d.start(ctx, "pixel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := d.start(ctx, "pixel"); err != nil || again != first {
+		t.Errorf("start while running = %q, %v; want %q", again, err, first)
+	}
+	// The poll that sees it connected next doesn't back it up again
+	d.poll(ctx, time.Now())
+	if n := len(jm.ListJobs()); n != 1 {
+		t.Errorf("%d jobs, want 1", n)
+	}
+}
+
+func TestParseADBDevices(t *testing.T) {
+	output := "List of devices attached\nR58M1234\tdevice\nemulator-5554\tunauthorized\n192.168.1.5:5555\tdevice\n\n"
+	if got, want := parseADBDevices(output), []string{"R58M1234", "192.168.1.5:5555"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseADBDevices = %v, want %v", got, want)
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gus") // socket paths must stay short
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "daemon.sock")
+
+	l, activated, err := controlListener(socket)
+	if err != nil {
+		t.Skipf("no unix sockets: %v", err)
+	}
+	defer l.Close()
+	if activated {
+		t.Error("not started by systemd")
+	}
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode %v, %v", info.Mode().Perm(), err)
+	}
+	jm := core.NewJobManager(nil)
+	server := api.NewServer(0, log.New(io.Discard, "", 0), jm)
+	go http.Serve(l, server.Handler())
+
+	// A second daemon must not take over the socket
+	if _, _, err := controlListener(socket); err == nil {
+		t.Error("expected a running daemon to keep its socket")
+	}
+
+	ctx := context.Background()
+	client, err := daemonClient(ctx, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobID, _, _ := jm.StartJob(ctx, "copy.sync", "Backing up pixel", map[string]string{"profile": "pixel"})
+	jm.PauseGate(jobID) // as the daemon's backups do
+	if code := jobsCmd([]string{"-socket", socket, "pause", jobID}); code != 0 {
+		t.Errorf("jobs pause: exit %d", code)
+	}
+	if job, _ := client.GetJob(ctx, jobID); job.State != apiclient.JobPaused {
+		t.Errorf("job %s after jobs pause", job.State)
+	}
+	if code := jobsCmd([]string{"-socket", socket, "cancel", jobID}); code != 0 {
+		t.Errorf("jobs cancel: exit %d", code)
+	}
+	if job, _ := client.GetJob(ctx, jobID); job.State != apiclient.JobCanceled {
+		t.Errorf("job %s after jobs cancel", job.State)
+	}
+
+	if _, err := daemonClient(ctx, filepath.Join(dir, "none.sock")); err == nil {
+		t.Error("expected an error without a daemon")
+	}
+}
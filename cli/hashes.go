@@ -0,0 +1,66 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/state"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// exportHashesCmd writes a checksum manifest of a backup from its state file:
+//
+//	gussync export-hashes -dest <dir> [-mode mount|adb] [-format sha256sum|bsd] [-o file]
+//
+// Paths are relative to <dest>/<mode>, so `cd <dest>/<mode> && sha256sum -c <file>` checks
+// the backup without GusSync.
+func exportHashesCmd(args []string) int {
+	fs := flag.NewFlagSet("export-hashes", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	modeFlag := fs.String("mode", "", "Backup mode; default: whichever state file exists")
+	format := fs.String("format", engine.ChecksumSHA256Sum, "'sha256sum' (GNU coreutils) or 'bsd' (shasum, BSD sha256)")
+	output := fs.String("o", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	m := backupMode(*dest, *modeFlag, "mount")
+	sm, err := state.OpenReadOnly(gussync.StateFile(*dest, m))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer sm.Close()
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	n, err := engine.WriteChecksums(out, sm, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *output != "" {
+		check := "sha256sum -c"
+		if *format == engine.ChecksumBSD {
+			check = "shasum -a 256 -c"
+		}
+		abs, _ := filepath.Abs(*output)
+		fmt.Printf("Wrote %d checksums to %s; check with: cd %s && %s %s\n", n, *output, gussync.ModeDir(*dest, m), check, abs)
+	}
+	return 0
+}
@@ -1,40 +1,223 @@
 package main
 
 import (
+	"GusSync/internal/crash"
 	"GusSync/pkg/engine"
-	"GusSync/pkg/state"
+	"GusSync/pkg/gussync"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
 const (
-	stateFileName = "gus_state.md"
+	stateFileName = gussync.StateFileName
 )
 
 var (
-	sourcePath string
-	destPath   string
-	numWorkers int
-	mode       string
-	jsonOutput bool
+	sourcePath   string
+	extraSources []string
+	destPath     string
+	numWorkers   int
+	mode         string
+	serial       string // adb serial of a profile's device ("" = the one adb picks)
+	jsonOutput   bool
+	tuiMode      bool
+	convert      bool
+	thumbnails   bool
+	adaptive     bool
+	minWorkers   int
+	folders      string
+	excludes     string
+	only         string
+	presets      string
+	priority     string
+	noPriority   bool
+	minSize      string
+	maxSize      string
+	newerThan    string
+	olderThan    string
+	report       string
+	audit        bool
+	attest       bool
+	attestKey    string
+	signKey      string
+	chown        string
+	chmod        string
+	bandwidth    string
+	retries      int
+	retryDelay   time.Duration
+	verifySample string
+	scrub        bool
+	destOnly     bool
+	verifyPar    int
+	verifySeq    bool
+	readAhead    string
+	dryRun       bool
+	interactive  bool
+	minAge       string
+	freeTarget   string
+	cleanupOrder string
+	onChange     string
+	rehash       bool
+	detectMoves  bool
+	sourceMode   string
+	manifest     bool
+	fromManifest bool
+	incremental  bool
+	watch        bool
+	watchPoll    time.Duration
+	watchSettle  time.Duration
+	dirTimeout   time.Duration
+	stallTimeout time.Duration
+	minRate      string
+	rateWindow   time.Duration
+	scanWorkers  int
+	hashWorkers  int
+	mediaStore   bool
+	bulkTar      bool
+	phoneData    string
+	apks         bool
+	reconnect    time.Duration
+	breaker      int
+	remount      bool
+	notifyHook   string
+	notifyEmail  string
+	smtpServer   string
+	smtpUser     string
+	smtpFrom     string
+	notifyDesk   bool
+	sshKey       string
+	sshKnown     string
+	sshInsecure  bool
+	preBackup    string
+	postBackup   string
+	destMinFree  string
+	chunkSize    string
+	bufferSize   string
+	largeSize    string
+	largeWorkers int
+	batchSmall   string
+	destNames    string
+	symlinks     string
+	diskCheck    time.Duration
 )
 
 func init() {
-	flag.StringVar(&sourcePath, "source", "", "Source directory to backup")
+	flag.Var(&sourceFlag{}, "source", "Source directory to backup (ssh mode: [user@]host:path or ssh://[user@]host:port/path; smb mode: smb://[user@]server/share/path; kdeconnect mode: kdeconnect://<device>/path). Mount and adb mode: repeat to back up more folders in the same run, e.g. internal storage and the SD card")
 	flag.StringVar(&destPath, "dest", "", "Destination directory")
-	flag.IntVar(&numWorkers, "workers", 2, "Number of worker threads")
-	flag.StringVar(&mode, "mode", "mount", "Backup mode: 'mount', 'adb', 'cleanup', or 'verify'")
+	flag.IntVar(&numWorkers, "workers", 2, "Number of worker threads (files copied at once; in cleanup mode, files verified and deleted at once)")
+	flag.StringVar(&mode, "mode", "mount", "Backup mode: a transport ("+strings.Join(engine.Transports(), ", ")+"), 'cleanup', or 'verify'")
 	flag.BoolVar(&jsonOutput, "json", false, "Output machine-readable JSON (one event per line)")
+	flag.BoolVar(&tuiMode, "tui", false, "Show a full-screen dashboard of the backup (workers, folders, errors); keys: p pause/resume, q cancel")
+	flag.BoolVar(&adaptive, "adaptive", false, "Auto-tune active workers between -min-workers and -workers based on throughput and stalls")
+	flag.IntVar(&minWorkers, "min-workers", 1, "Minimum active workers in -adaptive mode")
+	flag.StringVar(&largeSize, "large-size", "", "Files of at least this size go to a queue of their own, so long videos don't hold up photos (e.g. 256M; default: 64M)")
+	flag.IntVar(&largeWorkers, "large-workers", 0, "Workers that take large files first (default: a quarter of -workers, at least one)")
+	flag.StringVar(&folders, "folders", "", "Comma-separated folders (relative to -source) to back up, e.g. 'DCIM,Pictures'; default is everything")
+	flag.StringVar(&excludes, "exclude", "", "Comma-separated exclude globs, e.g. '*.mp3,WhatsApp/**'")
+	flag.StringVar(&only, "only", "", "Comma-separated media types to back up ("+strings.Join(engine.MediaPresetNames(), ", ")+"); their usual folders are scanned first")
+	flag.StringVar(&presets, "preset", "", "Comma-separated messenger apps to back up, chats and media ("+strings.Join(engine.AppPresetNames(), ", ")+"); with no -folders, only them")
+	flag.StringVar(&priority, "priority", "", "Comma-separated folders to scan first, in this order, e.g. 'DCIM,WhatsApp/Media' (default: DCIM, Camera, Pictures, ...)")
+	flag.BoolVar(&noPriority, "no-priority", false, "Scan the folders in their usual order instead of the priority folders first")
+	flag.StringVar(&minSize, "min-size", "", "Skip files smaller than this, e.g. '100K'")
+	flag.StringVar(&maxSize, "max-size", "", "Skip files larger than this, e.g. '4G' on a slow link")
+	flag.StringVar(&newerThan, "newer-than", "", "Only back up files modified within this long or since this date, e.g. '30d' or '2024-06-01'")
+	flag.StringVar(&olderThan, "older-than", "", "Only back up files modified at least this long ago or before this date, e.g. '52w' or '2024-01-01'")
+	flag.StringVar(&report, "report", "", "Write a report of the run into the destination: 'html' (summary, failures, slowest files, throughput) or 'csv' (one row per file)")
+	flag.BoolVar(&audit, "audit", false, "Record every copy, retry, verification and deletion in gus_audit.jsonl in the destination")
+	flag.BoolVar(&attest, "attest", false, "Write a signed manifest of the whole backup (gus_attestation_<time>.json) when the run ends; check it with gussync verify-manifest")
+	flag.StringVar(&attestKey, "attest-key", "", "Key file -attest signs with, created if missing (default ~/.gussync/attest_key)")
+	flag.StringVar(&chown, "chown", "", "Set the owner of copied files and folders: user:group, user or :group (names or ids), e.g. when running as root")
+	flag.StringVar(&chmod, "chmod", "", "Set the permissions of copied files and folders: octal like 644 (folders get 755) or F644,D755")
+	flag.StringVar(&signKey, "sign-key", "", "GPG key (id or email) to sign the state file and manifests with when the run ends; the next run warns if the signatures don't match")
+	flag.StringVar(&bandwidth, "bandwidth", "", "Bandwidth limit or schedule, e.g. '5MB' or '01:00-06:00=unlimited,*=5MB' (mount and ssh mode)")
+	flag.IntVar(&retries, "retries", engine.DefaultRetryPolicy().MaxAttempts, "Attempts per file for transient errors (I/O error, stall) before recording a failure")
+	flag.DurationVar(&retryDelay, "retry-backoff", engine.DefaultRetryPolicy().InitialBackoff, "Initial delay between retries (doubles each attempt, with jitter)")
+	flag.StringVar(&verifySample, "verify-sample", "", "Verify mode: only check this share of files, e.g. '5%' for a quick spot check")
+	flag.BoolVar(&scrub, "scrub", false, "Verify mode: check the least recently verified files (10% per run unless -verify-sample is set)")
+	flag.BoolVar(&destOnly, "dest-only", false, "Verify mode: check the backup against the hashes in the state without reading the phone (it needn't be attached); reports missing and corrupted copies")
+	flag.IntVar(&verifyPar, "verify-workers", 0, "Verify mode: files (or disks with -verify-sequential) to check at once (default: -workers)")
+	flag.BoolVar(&verifySeq, "verify-sequential", false, "Verify mode: read the copies on each backup disk one after another in folder order (for spinning disks); only different disks are read in parallel")
+	flag.StringVar(&readAhead, "verify-readahead", "", "Verify mode: hash the copies in reads of this size with kernel read-ahead, e.g. '8MB' (for large files on spinning disks)")
+	flag.BoolVar(&dryRun, "dry-run", false, "Cleanup mode: list the files that would be deleted (with sizes) without deleting")
+	flag.BoolVar(&interactive, "interactive", false, "Cleanup mode: ask for confirmation before deleting each directory's files")
+	flag.StringVar(&minAge, "min-age", "", "Cleanup mode: only delete files backed up at least this long ago, e.g. '30d'")
+	flag.StringVar(&freeTarget, "free", "", "Cleanup mode: only delete until the phone has this much free space, e.g. '20G'")
+	flag.StringVar(&cleanupOrder, "cleanup-order", engine.CleanupLargestFirst, "Cleanup mode with -free: delete 'largest' or 'oldest' backed-up files first")
+	flag.StringVar(&onChange, "on-change", "", "Re-check backed-up files and handle ones changed on the phone since: 'overwrite', 'keep-both' (previous version kept with a date suffix) or 'report' (default: don't check)")
+	flag.BoolVar(&rehash, "rehash", false, "Mount mode with -on-change: detect changes by hashing each backed-up file instead of comparing modification times (slow)")
+	flag.BoolVar(&detectMoves, "detect-moves", false, "Recognize files moved or renamed on the phone by their content and link their existing backup into place instead of copying them again")
+	flag.StringVar(&sourceMode, "source-mode", "", "Cleanup/verify mode: how the backup was made, 'mount', 'adb' or 'ssh' (default: detected from the state files in -dest)")
+	flag.BoolVar(&manifest, "manifest-first", false, "Scan the whole source and save the file list (with sizes) before copying, for accurate totals and a stable copy order")
+	flag.BoolVar(&fromManifest, "from-manifest", false, "Copy the files in the manifest saved by an earlier -manifest-first run instead of rescanning")
+	flag.BoolVar(&incremental, "incremental", false, "Mount mode: don't re-list completed directories whose mtime and size are unchanged (needs a filesystem that updates directory mtimes)")
+	flag.DurationVar(&dirTimeout, "dir-timeout", engine.DirReadTimeout, "Mount mode: give up reading a directory after this long and continue with the entries found so far")
+	flag.StringVar(&bufferSize, "buffer-size", "auto", "Mount mode: size of the copy buffer, e.g. 1MB, or 'auto' to tune it from the measured throughput")
+	flag.StringVar(&chunkSize, "chunk-size", "", "Mount mode: copy files larger than this in checksummed chunks of this size, retrying a bad chunk instead of the whole file (e.g. 8M; default: whole files)")
+	flag.StringVar(&destNames, "dest-names", "", "How to write file names Windows can't store (a:b.txt, CON.jpg, trailing dots): 'percent' (a%3Ab.txt), 'unicode' (full-width look-alikes) or 'none'; default: percent on Windows, none elsewhere. Recorded in the state; an existing backup keeps its scheme")
+	flag.StringVar(&symlinks, "symlinks", engine.SymlinksSkip, "What to do with symbolic links on the source: 'skip', 'copy-target' (copy a link to a file as that file; links to folders are skipped) or 'record-symlink' (recreate the link in the backup). Not supported in adb mode, which skips them. Sockets and FIFOs are always skipped")
+	flag.DurationVar(&stallTimeout, "stall-timeout", 0, "Abandon a copy (and retry it) when no bytes arrive for this long (default: scaled to the file's size, 10s for tiny files up to 10m for huge ones)")
+	flag.StringVar(&minRate, "min-throughput", "", "Abandon a copy (and retry it) whose bytes arrive slower than this per second for -throughput-window, e.g. '100K' (default: 50K; 'off' = no minimum)")
+	flag.DurationVar(&rateWindow, "throughput-window", engine.ThroughputWindow, "How long a copy may stay below -min-throughput")
+	flag.BoolVar(&mediaStore, "mediastore", false, "ADB mode: list DCIM, Pictures, Movies and other media folders from Android's MediaStore instead of walking them with find (much faster on large photo libraries)")
+	flag.StringVar(&batchSmall, "batch-small", "", "ADB mode: copy files smaller than this together, one tar stream per folder, instead of one adb pull each (e.g. 1M; default: off)")
+	flag.StringVar(&phoneData, "phone-data", "", "ADB mode: also export these to 'Phone data' in the destination, comma-separated: "+strings.Join(engine.PhoneDataKinds, ", ")+" (e.g. 'contacts,sms,calls')")
+	flag.BoolVar(&apks, "apks", false, "ADB mode: also back up the APKs of the apps you installed to 'APKs' in the destination, with a list of the apps and how to reinstall them")
+	flag.BoolVar(&bulkTar, "bulk", false, "ADB mode: start a new backup by streaming the media folders (or -folders) as one tar archive instead of pulling file by file")
+	flag.IntVar(&hashWorkers, "hash-workers", 0, "Files hashed on this machine at once: backed-up copies checked by verify, cleanup and -detect-moves, and adb copies (default: number of CPUs)")
+	flag.IntVar(&scanWorkers, "scan-workers", engine.DefaultScanWorkers, "Mount mode: directories read at the same time while scanning (1 = one at a time, best for slow MTP devices)")
+	flag.DurationVar(&reconnect, "reconnect-wait", engine.DefaultReconnectWait, "Pause when the phone disconnects and resume if it comes back within this long (0 = stop)")
+	flag.IntVar(&breaker, "timeout-breaker", engine.DefaultTimeoutBreaker, "After this many files in a row time out, hold all workers, check (and reconnect or remount) the source, then resume (0 = off)")
+	flag.BoolVar(&remount, "remount-stale", true, "Mount mode: unmount and remount an MTP source with gio when it goes stale ('Transport endpoint is not connected')")
+	flag.StringVar(&notifyHook, "notify-webhook", "", "POST a JSON event to this URL when the run completes or fails, the phone disconnects or the destination fills up")
+	flag.StringVar(&notifyEmail, "notify-email", "", "Comma-separated addresses to mail the same events to (needs -smtp; password from $GUSSYNC_SMTP_PASSWORD)")
+	flag.StringVar(&smtpServer, "smtp", "", "SMTP server for -notify-email, host:port")
+	flag.StringVar(&smtpUser, "smtp-user", "", "SMTP username (empty = no authentication)")
+	flag.StringVar(&smtpFrom, "smtp-from", "", "Sender address for -notify-email (default: -smtp-user)")
+	flag.BoolVar(&notifyDesk, "notify-desktop", false, "Also show the events as desktop notifications")
+	flag.StringVar(&preBackup, "pre-backup", "", "Shell command to run before the backup, e.g. to mount the destination; the backup doesn't start if it fails")
+	flag.StringVar(&postBackup, "post-backup", "", "Shell command to run after the backup (GUSSYNC_STATUS, GUSSYNC_COMPLETED, GUSSYNC_FAILED, ... describe the result)")
+	flag.StringVar(&destMinFree, "dest-min-free", "1G", "Pause copying while the destination has less than this free, resuming once space is freed (0 = don't check)")
+	flag.DurationVar(&diskCheck, "disk-check", engine.DefaultDiskCheckInterval, "How often to check the destination's free space for -dest-min-free")
+	flag.DurationVar(&watchPoll, "poll", engine.DefaultWatchPoll, "Watch mode: rescan the whole source this often, for changes no filesystem event reports (0 = never)")
+	flag.DurationVar(&watchSettle, "settle", engine.DefaultWatchSettle, "Watch mode: wait this long after the last change in a folder before copying it")
+	flag.StringVar(&sshKey, "ssh-key", "", "SSH mode: private key to log in with (default: ssh agent, then ~/.ssh/id_*; password from $GUSSYNC_SSH_PASSWORD)")
+	flag.StringVar(&sshKnown, "ssh-known-hosts", "", "SSH mode: known_hosts file to verify the host key against (default ~/.ssh/known_hosts)")
+	flag.BoolVar(&sshInsecure, "ssh-insecure", false, "SSH mode: don't verify the host key (trusted networks only)")
+	flag.BoolVar(&convert, "convert", false, "Also store a JPEG/H.264 copy of HEIC/HEVC files under _converted (requires ffmpeg)")
+	flag.BoolVar(&thumbnails, "thumbnails", false, "Cache a preview of each copied image and video under .guscache/thumbs, for the backup browser (videos and HEIC need ffmpeg)")
 }
 
 func main() {
+	// Subcommands (gussync backup --profile x, gussync profile ...); plain flags keep the legacy behaviour
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	flag.Parse()
+	run()
+}
+
+// run executes a backup/verify/cleanup using the parsed global flags and exits the process
+func run() {
+	// Crash reports are always kept locally; upload is opt-in via GUSSYNC_CRASH_UPLOAD=<url>
+	crashHandler := crash.Install(crash.Options{
+		UploadEnabled: os.Getenv("GUSSYNC_CRASH_UPLOAD") != "",
+		Endpoint:      os.Getenv("GUSSYNC_CRASH_UPLOAD"),
+	})
+	defer crash.Recover("cli")
+	crashHandler.SetContext("mode", mode)
+	crashHandler.SetContext("workers", fmt.Sprint(numWorkers))
 
 	if sourcePath == "" || destPath == "" {
 		if jsonOutput {
@@ -47,7 +230,7 @@ func main() {
 	}
 
 	// Validate mode
-	if mode != "mount" && mode != "adb" && mode != "cleanup" && mode != "verify" {
+	if mode != "cleanup" && mode != "verify" && !engine.HasTransport(mode) {
 		if jsonOutput {
 			emitJSONError(fmt.Sprintf("invalid mode '%s'", mode))
 		} else {
@@ -56,29 +239,13 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Update destination path to include mode
-	fullDestPath := filepath.Join(destPath, mode)
-	if err := os.MkdirAll(fullDestPath, 0755); err != nil {
-		if jsonOutput {
-			emitJSONError(fmt.Sprintf("failed to create destination directory: %v", err))
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: failed to create destination directory: %v\n", err)
-		}
-		os.Exit(1)
-	}
-
-	// Initialize state manager
-	stateFile := filepath.Join(fullDestPath, stateFileName)
-	stateManager, err := state.NewStateManager(stateFile)
-	if err != nil {
-		if jsonOutput {
-			emitJSONError(fmt.Sprintf("failed to create state manager: %v", err))
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: failed to create state manager: %v\n", err)
-		}
-		os.Exit(1)
+	// Cleanup and verify work on an existing backup: its mode picks the state directory
+	// and how source files are reached (filesystem or adb)
+	engineMode := mode
+	if mode == "cleanup" || mode == "verify" {
+		engineMode = backupMode(destPath, sourceMode, gussync.ModeMount)
 	}
-	defer stateManager.Close()
+	fullDestPath := gussync.ModeDir(destPath, engineMode)
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -94,10 +261,25 @@ func main() {
 		cancel()
 	}()
 
+	if tuiMode {
+		err := canRunTUI()
+		if err == nil && (jsonOutput || watch || !engine.HasTransport(mode)) {
+			err = fmt.Errorf("-tui only works for a backup, without -json")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create reporter based on output mode
 	var reporter engine.ProgressReporter
 	var jsonReporter *JSONReporter
-	if jsonOutput {
+	var tui *TUIReporter
+	if tuiMode {
+		tui = NewTUIReporter(numWorkers, fmt.Sprintf("%s  %s -> %s", mode, sourcePath, fullDestPath))
+		reporter = tui
+	} else if jsonOutput {
 		jsonReporter = NewJSONReporter()
 		reporter = jsonReporter
 		// Emit start event
@@ -115,21 +297,392 @@ func main() {
 	}
 
 	// Create and run engine
-	cfg := engine.EngineConfig{
+	cfg := gussync.Config{
 		SourcePath: sourcePath,
-		DestRoot:   fullDestPath,
-		Mode:       mode,
+		Mode:       engineMode,
+		Serial:     serial,
 		NumWorkers: numWorkers,
 		Reporter:   reporter,
+		Retry:      engine.DefaultRetryPolicy(),
+		ScanRoots:  splitList(folders),
+		Excludes:   splitList(excludes),
+		Only:       splitList(only),
+		Apps:       splitList(presets),
+		Report:     report,
+		Audit:      audit,
+
+		PanicHandler: crash.Capture,
+
+		AdaptiveWorkers:  adaptive,
+		MinWorkers:       minWorkers,
+		LargeFileWorkers: largeWorkers,
+
+		ManifestFirst: manifest,
+		FromManifest:  fromManifest,
+
+		PriorityPaths: splitList(priority),
+		NoPriority:    noPriority,
+
+		IncrementalScan:  incremental,
+		DirReadTimeout:   dirTimeout,
+		StallTimeout:     stallTimeout,
+		ThroughputWindow: rateWindow,
+		DestNames:        destNames,
+		Symlinks:         symlinks,
+		Chown:            chown,
+		Chmod:            chmod,
+		ScanWorkers:      scanWorkers,
+		HashWorkers:      hashWorkers,
+		MediaStoreScan:   mediaStore,
+		BulkTar:          bulkTar,
+		PhoneData:        splitList(phoneData),
+		APKs:             apks,
+		ReconnectWait:    reconnect,
+		TimeoutBreaker:   breaker,
+		RemountStale:     remount,
+		ExtraSources:     extraSources,
+		Hooks:            engine.Hooks{PreBackup: preBackup, PostBackup: postBackup},
+	}
+	if breaker == 0 {
+		cfg.TimeoutBreaker = -1
+	}
+	if attest {
+		keyPath, err := attestKeyPath(attestKey)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.AttestKey = keyPath
+	}
+	if signKey != "" {
+		signer, err := engine.NewGPGSigner(signKey)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.Signer = signer
+	}
+	if tui != nil {
+		cfg.Pause = &tui.pause
+	}
+	cfg.Retry.MaxAttempts = retries
+	cfg.Retry.InitialBackoff = retryDelay
+
+	if len(extraSources) > 0 && engineMode != engine.TransportMount && engineMode != engine.TransportADB {
+		if jsonOutput {
+			emitJSONError("-source can only be repeated in mount and adb mode")
+		} else {
+			fmt.Fprintln(os.Stderr, "Error: -source can only be repeated in mount and adb mode")
+		}
+		os.Exit(1)
+	}
+	if engineMode == engine.TransportSSH {
+		host, remotePath, err := engine.ParseSSHSource(sourcePath)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.SourcePath = remotePath
+		cfg.SSH = engine.SSHOptions{
+			Host:                  host,
+			KeyFile:               sshKey,
+			Password:              os.Getenv("GUSSYNC_SSH_PASSWORD"),
+			KnownHostsFile:        sshKnown,
+			InsecureIgnoreHostKey: sshInsecure,
+		}
+	}
+	if engineMode == engine.TransportSMB {
+		opts, sharePath, err := engine.ParseSMBSource(sourcePath)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		if password := os.Getenv("GUSSYNC_SMB_PASSWORD"); password != "" {
+			opts.Password = password
+		}
+		cfg.SourcePath = sharePath
+		cfg.SMB = opts
+	}
+	if engineMode == engine.TransportKDEConnect {
+		device, phonePath, err := engine.ParseKDEConnectSource(sourcePath)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.SourcePath = phonePath
+		cfg.KDEConnect = engine.KDEConnectOptions{Device: device}
+	}
+
+	limits, err := parseLimits(time.Now())
+	if err != nil {
+		if jsonOutput {
+			emitJSONError(err.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	cfg.Limits = limits
+
+	switch minRate {
+	case "":
+	case "off", "0":
+		cfg.MinThroughput = -1
+	default:
+		rate, err := engine.ParseSize(minRate)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -min-throughput: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -min-throughput: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.MinThroughput = rate
 	}
 
-	e := engine.NewEngine(cfg, stateManager)
+	if chunkSize != "" && chunkSize != "0" {
+		size, err := engine.ParseSize(chunkSize)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -chunk-size: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -chunk-size: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.ChunkSize = size
+	}
+
+	if bufferSize != "" && bufferSize != "auto" {
+		size, err := engine.ParseSize(bufferSize)
+		if err == nil && (size <= 0 || size > 1<<30) {
+			err = fmt.Errorf("must be between 1 byte and 1GB")
+		}
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -buffer-size: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -buffer-size: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.BufferSize = int(size)
+	}
+
+	if largeSize != "" && largeSize != "0" {
+		size, err := engine.ParseSize(largeSize)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -large-size: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -large-size: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.LargeFileSize = size
+	}
+
+	if batchSmall != "" && batchSmall != "0" {
+		size, err := engine.ParseSize(batchSmall)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -batch-small: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -batch-small: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.BatchSmallFiles = size
+	}
+
+	if destMinFree != "" && destMinFree != "0" {
+		minFree, err := engine.ParseSize(destMinFree)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -dest-min-free: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -dest-min-free: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.MinFreeSpace = minFree
+		cfg.DiskCheckInterval = diskCheck
+	}
+
+	notifier, err := buildNotifier()
+	if err != nil {
+		if jsonOutput {
+			emitJSONError(err.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	cfg.Notifier = notifier
+
+	if watch {
+		cfg.Watch.Settle = watchSettle
+		cfg.Watch.PollInterval = watchPoll
+		if watchPoll == 0 {
+			cfg.Watch.PollInterval = -1 // never
+		}
+	}
+
+	if bandwidth != "" {
+		schedule, err := engine.ParseBandwidthSchedule(bandwidth)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -bandwidth: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -bandwidth: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.Bandwidth = schedule
+	}
+
+	if convert && engine.HasTransport(mode) {
+		converter, err := engine.NewExecConverter()
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("cannot enable conversion: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: cannot enable conversion: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.PostProcessors = append(cfg.PostProcessors, engine.NewTranscoder(converter))
+	}
+
+	if thumbnails && engine.HasTransport(mode) {
+		var frames engine.FrameGrabber
+		if converter, err := engine.NewExecConverter(); err == nil {
+			frames = converter
+		} else if !jsonOutput {
+			fmt.Fprintf(os.Stderr, "Warning: %v; videos and HEIC images get no thumbnails\n", err)
+		}
+		cfg.PostProcessors = append(cfg.PostProcessors, engine.NewThumbnailer(fullDestPath, frames))
+	}
+
+	if verifySample != "" {
+		fraction, err := parsePercent(verifySample)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -verify-sample: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -verify-sample: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.VerifySample = fraction
+	}
+	cfg.VerifyScrub = scrub
+	cfg.VerifyDestOnly = destOnly
+	cfg.VerifyWorkers = verifyPar
+	cfg.VerifySequential = verifySeq
+	if readAhead != "" && readAhead != "0" {
+		size, err := engine.ParseSize(readAhead)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(fmt.Sprintf("invalid -verify-readahead: %v", err))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid -verify-readahead: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.VerifyReadAhead = size
+	}
+
+	if onChange != "" && !slices.Contains(engine.ChangedPolicies(), onChange) {
+		msg := fmt.Sprintf("-on-change must be one of %s", strings.Join(engine.ChangedPolicies(), ", "))
+		if jsonOutput {
+			emitJSONError(msg)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+		}
+		os.Exit(1)
+	}
+	cfg.Changed = onChange
+	cfg.ChangeRehash = rehash
+	cfg.DetectMoves = detectMoves
+
+	cfg.Cleanup.DryRun = dryRun
+	cfg.Cleanup.Workers = numWorkers
+	if minAge != "" {
+		age, err := parseAge(minAge)
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: -min-age: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.Cleanup.MinAge = age
+	}
+	if freeTarget != "" {
+		target, err := engine.ParseSize(freeTarget)
+		if err == nil && cleanupOrder != engine.CleanupLargestFirst && cleanupOrder != engine.CleanupOldestFirst {
+			err = fmt.Errorf("-cleanup-order must be %q or %q", engine.CleanupLargestFirst, engine.CleanupOldestFirst)
+		}
+		if err != nil {
+			if jsonOutput {
+				emitJSONError(err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: -free: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		cfg.Cleanup.FreeTarget = target
+		cfg.Cleanup.Order = cleanupOrder
+	}
+	if interactive && !dryRun {
+		if jsonOutput {
+			emitJSONError("-interactive cannot be combined with -json")
+			os.Exit(1)
+		}
+		cfg.Cleanup.Confirm = newCleanupPrompter(cancel).confirm
+	}
+
+	e, err := gussync.Open(destPath, cfg)
+	if err != nil {
+		if jsonOutput {
+			emitJSONError(err.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	defer e.Close()
 
 	var exitCode int
+	var runErr error
 
 	if mode == "verify" {
-		results, err := e.VerifyBackup(ctx)
+		results, err := e.Verify(ctx)
 		if err != nil {
+			runErr = err
 			if jsonOutput {
 				jsonReporter.ReportError(err)
 				jsonReporter.EmitComplete(false, err.Error())
@@ -143,15 +696,22 @@ func main() {
 				jsonReporter.EmitComplete(true, "Verification complete")
 			} else {
 				fmt.Printf("\nVerification complete:\n")
+				if results.Sampled < results.Total {
+					fmt.Printf("  Checked: %d of %d files\n", results.Sampled, results.Total)
+				}
 				fmt.Printf("  Verified: %d\n", results.Verified)
 				fmt.Printf("  Missing Source: %d\n", results.MissingSource)
 				fmt.Printf("  Missing Destination: %d\n", results.MissingDest)
 				fmt.Printf("  Mismatches: %d\n", results.Mismatches)
+				if results.Quarantined > 0 {
+					fmt.Printf("  Quarantined: %d (see 'gussync quarantine list')\n", results.Quarantined)
+				}
 			}
 		}
 	} else if mode == "cleanup" {
-		results, err := e.RunCleanup(ctx)
+		results, err := e.Cleanup(ctx)
 		if err != nil {
+			runErr = err
 			if jsonOutput {
 				jsonReporter.ReportError(err)
 				jsonReporter.EmitComplete(false, err.Error())
@@ -163,6 +723,8 @@ func main() {
 			if jsonOutput {
 				jsonReporter.EmitCleanupResults(results)
 				jsonReporter.EmitComplete(true, "Cleanup complete")
+			} else if dryRun {
+				printCleanupPlan(results.Planned)
 			} else {
 				fmt.Printf("\nCleanup complete:\n")
 				fmt.Printf("  Deleted: %d\n", results.Deleted)
@@ -170,10 +732,44 @@ func main() {
 				fmt.Printf("  Failed: %d\n", results.Failed)
 				fmt.Printf("  Skipped: %d\n", results.Skipped)
 				fmt.Printf("  I/O Errors: %d\n", results.IOErrors)
+				if results.TooRecent > 0 {
+					fmt.Printf("  Too Recent: %d\n", results.TooRecent)
+				}
+				if results.Declined > 0 {
+					fmt.Printf("  Kept (declined): %d\n", results.Declined)
+				}
+				fmt.Printf("  Freed: %s\n", engine.FormatSize(results.FreedBytes))
+			}
+		}
+	} else if watch {
+		if err := e.Watch(ctx); err != nil {
+			runErr = err
+			if jsonOutput {
+				jsonReporter.ReportError(err)
+				jsonReporter.EmitComplete(false, err.Error())
+			} else {
+				fmt.Fprintf(os.Stderr, "Watch failed: %v\n", err)
+			}
+			exitCode = 1
+		} else {
+			if jsonOutput {
+				jsonReporter.EmitComplete(true, "Watch stopped")
+			} else {
+				fmt.Println("\nWatch stopped.")
 			}
 		}
 	} else {
-		if err := e.Run(ctx); err != nil {
+		if tui != nil {
+			tui.SetSkip(e.SkipFile)
+			tui.Start(cancel)
+		}
+		err := e.Backup(ctx)
+		if tui != nil {
+			tui.Stop()
+			fmt.Println(tui.Summary())
+		}
+		if err != nil {
+			runErr = err
 			if jsonOutput {
 				jsonReporter.ReportError(err)
 				jsonReporter.EmitComplete(false, err.Error())
@@ -190,9 +786,10 @@ func main() {
 		}
 	}
 
+	notifyOutcome(notifier, fullDestPath, runErr)
+
 	// Error log summary
-	errorLogFile := filepath.Join(fullDestPath, "gus_errors.log")
-	summary, err := engine.SummarizeErrorLog(errorLogFile)
+	summary, err := e.ErrorSummary()
 	if err == nil && summary.TotalErrors > 0 {
 		if jsonOutput {
 			jsonReporter.EmitErrorSummary(summary)
@@ -215,3 +812,100 @@ func emitJSONError(message string) {
 	}
 	json.NewEncoder(os.Stderr).Encode(event)
 }
+
+// backupMode returns the mode ("mount", "adb", ...) of the backup under dest that cleanup or
+// verify should use: the explicit -source-mode, else whichever state file exists
+func backupMode(dest, explicit, fallback string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if modes := gussync.DetectModes(dest); len(modes) > 0 {
+		return modes[0]
+	}
+	return fallback
+}
+
+// parseLimits builds the size and time limits from -min-size, -max-size, -newer-than and -older-than
+func parseLimits(now time.Time) (engine.FileLimits, error) {
+	var limits engine.FileLimits
+	var err error
+	if minSize != "" {
+		if limits.MinSize, err = engine.ParseSize(minSize); err != nil {
+			return limits, fmt.Errorf("invalid -min-size: %w", err)
+		}
+	}
+	if maxSize != "" {
+		if limits.MaxSize, err = engine.ParseSize(maxSize); err != nil {
+			return limits, fmt.Errorf("invalid -max-size: %w", err)
+		}
+	}
+	if limits.MaxSize > 0 && limits.MinSize > limits.MaxSize {
+		return limits, fmt.Errorf("-min-size is larger than -max-size")
+	}
+	if newerThan != "" {
+		if limits.NewerThan, err = parseTimeLimit(newerThan, now); err != nil {
+			return limits, fmt.Errorf("invalid -newer-than: %w", err)
+		}
+	}
+	if olderThan != "" {
+		if limits.OlderThan, err = parseTimeLimit(olderThan, now); err != nil {
+			return limits, fmt.Errorf("invalid -older-than: %w", err)
+		}
+	}
+	if !limits.NewerThan.IsZero() && !limits.OlderThan.IsZero() && limits.NewerThan.After(limits.OlderThan) {
+		return limits, fmt.Errorf("-newer-than and -older-than leave no files")
+	}
+	return limits, nil
+}
+
+// parseTimeLimit parses an age ("30d", "2w", "12h", see parseAge) as that long before now,
+// or a local date "2024-06-01"
+func parseTimeLimit(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(value), time.Local); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an age (30d) nor a date (2024-06-01)", value)
+	}
+	return now.Add(-age), nil
+}
+
+// parsePercent parses "5%" or "5" as a fraction (0.05)
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("%q is not a percentage between 0 and 100", value)
+	}
+	return percent / 100, nil
+}
+
+// sourceFlag is -source: the first value replaces sourcePath (e.g. from a profile), later
+// ones are backed up too (extraSources)
+type sourceFlag struct {
+	set bool
+}
+
+func (f *sourceFlag) String() string {
+	return sourcePath
+}
+
+func (f *sourceFlag) Set(value string) error {
+	if !f.set {
+		sourcePath, extraSources, f.set = value, nil, true
+		return nil
+	}
+	extraSources = append(extraSources, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// migrateCmd copies an old phone's files onto a new phone over adb, in the same folders:
+//
+//	gussync migrate -to <serial> -source /sdcard -dest /backup [-path DCIM] [-overwrite] [-dry-run]
+//	gussync migrate -to <serial> -direct -source <mount point>
+//	gussync migrate -to <serial> -direct -mode adb -from <serial> [-source /sdcard]
+//
+// From the backup by default; -direct reads the old phone instead. Each file is checked on
+// the new phone after it is pushed, and the progress is reported folder by folder.
+func migrateCmd(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := fs.String("to", "", "adb serial of the new phone (see 'adb devices')")
+	toRoot := fs.String("to-root", "/sdcard", "Storage folder of the new phone to copy the files under")
+	source := fs.String("source", "", "Source the files were backed up from, or with -direct read from (the old phone's mount point, or e.g. /sdcard in adb mode)")
+	dest := fs.String("dest", "", "Destination directory of the backup to copy from (not with -direct)")
+	modeFlag := fs.String("mode", "", "How the backup was made or, with -direct, how the old phone is reached: 'mount' or 'adb' (default: detected from -dest, or mount)")
+	direct := fs.Bool("direct", false, "Copy straight from the old phone instead of from the backup")
+	from := fs.String("from", "", "adb serial of the old phone with -direct -mode adb (default: ANDROID_SERIAL)")
+	var paths pathList
+	fs.Var(&paths, "path", "Folder or file to copy, relative to the phone's storage, e.g. 'DCIM'; repeat or comma-separate for more (default: everything)")
+	overwrite := fs.Bool("overwrite", false, "Replace files on the new phone whose content differs (default: leave them and report a conflict)")
+	dry := fs.Bool("dry-run", false, "List the files that would be copied without writing")
+	asJSON := fs.Bool("json", false, "Output machine-readable JSON (one event per line)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *to == "" || *source == "" || (*dest == "") == !*direct {
+		fmt.Fprintln(os.Stderr, "Error: -to, -source and either -dest or -direct are required")
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var reporter engine.ProgressReporter = NewConsoleReporter(1)
+	var jsonReporter *JSONReporter
+	if *asJSON {
+		jsonReporter = NewJSONReporter()
+		reporter = jsonReporter
+	}
+	cfg := gussync.Config{
+		SourcePath: *source,
+		Mode:       *modeFlag,
+		Reporter:   reporter,
+		Migrate: engine.MigrateOptions{Target: *to, TargetRoot: *toRoot, Direct: *direct, From: *from,
+			Paths: paths, Overwrite: *overwrite, DryRun: *dry},
+	}
+
+	var results gussync.MigrateResults
+	var err error
+	if *direct {
+		if cfg.Mode == "" {
+			cfg.Mode = gussync.ModeMount
+		}
+		results, err = gussync.MigratePhone(ctx, cfg)
+	} else {
+		cfg.Mode = backupMode(*dest, *modeFlag, gussync.ModeMount)
+		var e *gussync.Engine
+		if e, err = gussync.Open(*dest, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer e.Close()
+		results, err = e.Migrate(ctx)
+	}
+	if err != nil {
+		if *asJSON {
+			jsonReporter.ReportError(err)
+			jsonReporter.EmitComplete(false, err.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+		}
+		return 1
+	}
+	if *asJSON {
+		jsonReporter.emit("migrate_complete", results)
+		jsonReporter.EmitComplete(results.Failed == 0, "Migration complete")
+	} else if *dry {
+		fmt.Printf("\nWould copy %d of %d files:\n", len(results.Planned), results.Total)
+		for _, p := range results.Planned {
+			fmt.Printf("  %s\n", p)
+		}
+	} else {
+		fmt.Printf("\nMigration complete:\n")
+		fmt.Printf("  %-24s %7s %7s %9s %9s %7s %10s\n", "Folder", "Files", "Copied", "Present", "Conflicts", "Failed", "Size")
+		for _, f := range results.Folders {
+			fmt.Printf("  %-24s %7d %7d %9d %9d %7d %10s\n", f.Folder, f.Files, f.Copied, f.Present, f.Conflicts, f.Failed, engine.FormatSize(f.Bytes))
+		}
+		fmt.Printf("  Copied: %d (%s), already on the new phone: %d, failed: %d\n",
+			results.Copied, engine.FormatSize(results.Bytes), results.Present, results.Failed)
+		if results.Conflicts > 0 {
+			fmt.Printf("  Conflicts: %d (different file on the new phone; use -overwrite to replace)\n", results.Conflicts)
+		}
+		for _, f := range results.Failures {
+			fmt.Printf("    %s: %s\n", f.SourcePath, f.Error)
+		}
+	}
+	if results.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"GusSync/pkg/notify"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// buildNotifier combines the notification targets selected by the -notify-* flags (nil if none)
+func buildNotifier() (notify.Notifier, error) {
+	var targets notify.Multi
+	if notifyHook != "" {
+		if !strings.HasPrefix(notifyHook, "http://") && !strings.HasPrefix(notifyHook, "https://") {
+			return nil, fmt.Errorf("-notify-webhook must be an http(s) URL")
+		}
+		targets = append(targets, notify.Webhook{URL: notifyHook})
+	}
+	if notifyEmail != "" {
+		if smtpServer == "" {
+			return nil, fmt.Errorf("-notify-email needs -smtp host:port")
+		}
+		targets = append(targets, notify.Email{
+			Server:   smtpServer,
+			Username: smtpUser,
+			Password: os.Getenv("GUSSYNC_SMTP_PASSWORD"),
+			From:     smtpFrom,
+			To:       splitList(notifyEmail),
+		})
+	}
+	if notifyDesk {
+		targets = append(targets, notify.Desktop{})
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	return targets, nil
+}
+
+// notifyOutcome reports how the run ended
+func notifyOutcome(n notify.Notifier, dest string, runErr error) {
+	if n == nil {
+		return
+	}
+	task := map[string]string{"verify": "Verification", "cleanup": "Cleanup"}[mode]
+	if task == "" {
+		task = "Backup"
+	}
+	ev := notify.Event{Kind: notify.JobComplete, Title: task + " complete", Source: sourcePath, Dest: dest}
+	ev.Message = fmt.Sprintf("%s of %s finished.", task, sourcePath)
+	if runErr != nil {
+		ev.Kind, ev.Title = notify.JobFailed, task+" failed"
+		ev.Message = fmt.Sprintf("%s of %s failed: %v", task, sourcePath, runErr)
+	}
+	if err := notify.Send(context.Background(), n, ev); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
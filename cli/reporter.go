@@ -29,6 +29,19 @@ func (r *ConsoleReporter) ReportProgress(update engine.ProgressUpdate) {
 			update.TotalFiles, update.Completed, update.Skipped, update.Failed, update.TimeoutSkips, update.ConsecutiveSkips, update.Rate/(1024*1024))
 	}
 
+	if update.AvgSpeed > 0 {
+		statusLine += fmt.Sprintf(" | Avg: %.2f MB/s | Peak: %.2f MB/s", update.AvgSpeed/(1024*1024), update.PeakSpeed/(1024*1024))
+	}
+	if update.ETASeconds > 0 {
+		statusLine += fmt.Sprintf(" | ETA: %s", (time.Duration(update.ETASeconds) * time.Second).String())
+	}
+	if update.BackupPercent > 0 {
+		statusLine += fmt.Sprintf(" | Backup: %.0f%% (%d/%d files)", update.BackupPercent, update.BackupCompleted, update.BackupExpected)
+	}
+	if update.ActiveWorkers > 0 && update.ActiveWorkers < r.numWorkers {
+		statusLine += fmt.Sprintf(" | Workers: %d/%d", update.ActiveWorkers, r.numWorkers)
+	}
+
 	fmt.Print(statusLine + "\n")
 
 	// Print worker activity
@@ -44,6 +57,43 @@ func (r *ConsoleReporter) ReportProgress(update engine.ProgressUpdate) {
 			fmt.Printf("  W%d: %s\n", id, update.WorkerStatuses[id])
 		}
 	}
+
+	if update.ScanComplete && len(update.Dirs) > 1 {
+		printDirStats(update.Dirs)
+	}
+	if update.ScanComplete {
+		printSpecialFiles(update.SpecialFiles)
+	}
+}
+
+// printSpecialFiles prints what became of the links and special files the scan found
+func printSpecialFiles(s engine.SpecialFileStats) {
+	links := s.SymlinksSkipped + s.SymlinksCopied + s.SymlinksRecorded
+	if links > 0 {
+		fmt.Printf("  Symlinks: %d (skipped: %d, copied as their target: %d, recorded: %d)\n", links, s.SymlinksSkipped, s.SymlinksCopied, s.SymlinksRecorded)
+	}
+	if s.Special > 0 {
+		fmt.Printf("  Special files skipped (sockets, FIFOs, devices): %d\n", s.Special)
+	}
+}
+
+// printDirStats prints the run's per-folder figures, most bytes first, flagging folders
+// with failures
+func printDirStats(dirs []engine.DirStats) {
+	width := len("Folder")
+	for _, d := range dirs {
+		if len(d.Dir) > width {
+			width = len(d.Dir)
+		}
+	}
+	fmt.Printf("\n  %-*s %8s %8s %10s %8s\n", width, "Folder", "Files", "Copied", "Size", "Failed")
+	for _, d := range dirs {
+		line := fmt.Sprintf("  %-*s %8d %8d %10s %8d", width, d.Dir, d.Files, d.Completed, engine.FormatSize(d.Bytes), d.Failed)
+		if d.Failed > 0 {
+			line += "  <- failing"
+		}
+		fmt.Println(line)
+	}
 }
 
 func (r *ConsoleReporter) ReportError(err error) {
@@ -63,18 +113,35 @@ type JSONEvent struct {
 
 // JSONProgressData contains progress information in structured form
 type JSONProgressData struct {
-	TotalFiles       int            `json:"totalFiles"`
-	Completed        int            `json:"completed"`
-	Failed           int            `json:"failed"`
-	Skipped          int            `json:"skipped"`
-	TimeoutSkips     int            `json:"timeoutSkips"`
-	ConsecutiveSkips int            `json:"consecutiveSkips"`
-	TotalBytes       int64          `json:"totalBytes"`
-	RateBytesPerSec  float64        `json:"rateBytesPerSec"`
-	RateMBPerSec     float64        `json:"rateMBPerSec"`
-	DeltaMB          float64        `json:"deltaMB"`
-	ScanComplete     bool           `json:"scanComplete"`
-	Workers          map[int]string `json:"workers,omitempty"`
+	TotalFiles       int               `json:"totalFiles"`
+	Completed        int               `json:"completed"`
+	Failed           int               `json:"failed"`
+	Skipped          int               `json:"skipped"`
+	TimeoutSkips     int               `json:"timeoutSkips"`
+	ConsecutiveSkips int               `json:"consecutiveSkips"`
+	TotalBytes       int64             `json:"totalBytes"`
+	RateBytesPerSec  float64           `json:"rateBytesPerSec"`
+	RateMBPerSec     float64           `json:"rateMBPerSec"`
+	DeltaMB          float64           `json:"deltaMB"`
+	ScanComplete     bool              `json:"scanComplete"`
+	Workers          map[int]string    `json:"workers,omitempty"`
+	ActiveWorkers    int               `json:"activeWorkers"`
+	SpeedSamples     []float64         `json:"speedSamples,omitempty"`
+	WorkerBytes      map[int]int64     `json:"workerBytes,omitempty"`
+	AvgSpeed         float64           `json:"avgSpeed"`
+	PeakSpeed        float64           `json:"peakSpeed"`
+	ETASeconds       int64             `json:"etaSeconds"`
+	BackupCompleted  int               `json:"backupCompleted"`
+	BackupExpected   int               `json:"backupExpected"`
+	BackupPercent    float64           `json:"backupPercent"`
+	BackupBytes      int64             `json:"backupBytes"`
+	Dirs             []engine.DirStats `json:"dirs,omitempty"`
+	SymlinksSkipped  int               `json:"symlinksSkipped"`
+	SymlinksCopied   int               `json:"symlinksCopied"`
+	SymlinksRecorded int               `json:"symlinksRecorded"`
+	SpecialSkipped   int               `json:"specialSkipped"`
+	SmallQueue       int               `json:"smallQueue"`
+	LargeQueue       int               `json:"largeQueue"`
 }
 
 // JSONLogData contains log information in structured form
@@ -85,6 +152,9 @@ type JSONLogData struct {
 
 // JSONErrorData contains error information in structured form
 type JSONErrorData struct {
+	Code    string `json:"code,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Path    string `json:"path,omitempty"`
 	Message string `json:"message"`
 }
 
@@ -122,12 +192,30 @@ func (r *JSONReporter) ReportProgress(update engine.ProgressUpdate) {
 		DeltaMB:          update.DeltaMB,
 		ScanComplete:     update.ScanComplete,
 		Workers:          update.WorkerStatuses,
+		ActiveWorkers:    update.ActiveWorkers,
+		SpeedSamples:     update.SpeedSamples,
+		WorkerBytes:      update.WorkerBytes,
+		AvgSpeed:         update.AvgSpeed,
+		PeakSpeed:        update.PeakSpeed,
+		ETASeconds:       update.ETASeconds,
+		BackupCompleted:  update.BackupCompleted,
+		BackupExpected:   update.BackupExpected,
+		BackupPercent:    update.BackupPercent,
+		BackupBytes:      update.BackupBytes,
+		Dirs:             update.Dirs,
+		SymlinksSkipped:  update.SpecialFiles.SymlinksSkipped,
+		SymlinksCopied:   update.SpecialFiles.SymlinksCopied,
+		SymlinksRecorded: update.SpecialFiles.SymlinksRecorded,
+		SpecialSkipped:   update.SpecialFiles.Special,
+		SmallQueue:       update.SmallQueue,
+		LargeQueue:       update.LargeQueue,
 	}
 	r.emit("progress", data)
 }
 
 func (r *JSONReporter) ReportError(err error) {
-	r.emit("error", JSONErrorData{Message: err.Error()})
+	ev := engine.NewErrorEvent(err)
+	r.emit("error", JSONErrorData{Code: ev.Code, Phase: ev.Phase, Path: ev.Path, Message: ev.Message})
 }
 
 func (r *JSONReporter) ReportLog(level, message string) {
@@ -136,52 +224,86 @@ func (r *JSONReporter) ReportLog(level, message string) {
 
 // VerifyResultsJSON is the structured output for verify results
 type VerifyResultsJSON struct {
+	Total         int `json:"total"`
+	Sampled       int `json:"sampled"`
 	Verified      int `json:"verified"`
 	MissingSource int `json:"missingSource"`
 	MissingDest   int `json:"missingDest"`
 	Mismatches    int `json:"mismatches"`
+	Quarantined   int `json:"quarantined"`
+	// Issues are the files that did not verify
+	Issues []engine.VerifyIssue `json:"issues,omitempty"`
 }
 
 // CleanupResultsJSON is the structured output for cleanup results
 type CleanupResultsJSON struct {
-	Deleted        int `json:"deleted"`
-	AlreadyDeleted int `json:"alreadyDeleted"`
-	Failed         int `json:"failed"`
-	Skipped        int `json:"skipped"`
-	IOErrors       int `json:"ioErrors"`
+	Deleted        int   `json:"deleted"`
+	AlreadyDeleted int   `json:"alreadyDeleted"`
+	Failed         int   `json:"failed"`
+	Skipped        int   `json:"skipped"`
+	IOErrors       int   `json:"ioErrors"`
+	TooRecent      int   `json:"tooRecent"`
+	Declined       int   `json:"declined"`
+	FreedBytes     int64 `json:"freedBytes"`
+	// Dry run only: files that would be deleted
+	Planned []CleanupCandidateJSON `json:"planned,omitempty"`
+}
+
+// CleanupCandidateJSON is a file a dry-run cleanup would delete
+type CleanupCandidateJSON struct {
+	SourcePath string `json:"sourcePath"`
+	Size       int64  `json:"size"`
+	BackedUpAt string `json:"backedUpAt,omitempty"`
 }
 
 // ErrorSummaryJSON is the structured output for error log summary
 type ErrorSummaryJSON struct {
-	TotalErrors       int      `json:"totalErrors"`
-	CriticalErrors    int      `json:"criticalErrors"`
-	DirectoryTimeouts int      `json:"directoryTimeouts"`
-	DirectoryErrors   int      `json:"directoryErrors"`
-	HashMismatches    int      `json:"hashMismatches"`
-	CopyErrors        int      `json:"copyErrors"`
-	OtherErrors       int      `json:"otherErrors"`
-	TimeoutDirs       []string `json:"timeoutDirs,omitempty"`
-	ErrorDirs         []string `json:"errorDirs,omitempty"`
+	TotalErrors       int            `json:"totalErrors"`
+	CriticalErrors    int            `json:"criticalErrors"`
+	DirectoryTimeouts int            `json:"directoryTimeouts"`
+	DirectoryErrors   int            `json:"directoryErrors"`
+	HashMismatches    int            `json:"hashMismatches"`
+	CopyErrors        int            `json:"copyErrors"`
+	OtherErrors       int            `json:"otherErrors"`
+	TimeoutDirs       []string       `json:"timeoutDirs,omitempty"`
+	ErrorDirs         []string       `json:"errorDirs,omitempty"`
+	Codes             map[string]int `json:"codes,omitempty"`
 }
 
 // EmitVerifyResults emits verify results as JSON
 func (r *JSONReporter) EmitVerifyResults(results engine.VerifyResults) {
 	r.emit("verify_complete", VerifyResultsJSON{
+		Total:         results.Total,
+		Sampled:       results.Sampled,
 		Verified:      results.Verified,
 		MissingSource: results.MissingSource,
 		MissingDest:   results.MissingDest,
 		Mismatches:    results.Mismatches,
+		Quarantined:   results.Quarantined,
+		Issues:        results.Issues,
 	})
 }
 
 // EmitCleanupResults emits cleanup results as JSON
 func (r *JSONReporter) EmitCleanupResults(results engine.CleanupResults) {
+	var planned []CleanupCandidateJSON
+	for _, c := range results.Planned {
+		item := CleanupCandidateJSON{SourcePath: c.SourcePath, Size: c.Size}
+		if !c.BackedUpAt.IsZero() {
+			item.BackedUpAt = c.BackedUpAt.Format(time.RFC3339)
+		}
+		planned = append(planned, item)
+	}
 	r.emit("cleanup_complete", CleanupResultsJSON{
 		Deleted:        results.Deleted,
 		AlreadyDeleted: results.AlreadyDeleted,
 		Failed:         results.Failed,
 		Skipped:        results.Skipped,
 		IOErrors:       results.IOErrors,
+		TooRecent:      results.TooRecent,
+		Declined:       results.Declined,
+		FreedBytes:     results.FreedBytes,
+		Planned:        planned,
 	})
 }
 
@@ -197,6 +319,7 @@ func (r *JSONReporter) EmitErrorSummary(summary engine.ErrorSummary) {
 		OtherErrors:       summary.OtherErrors,
 		TimeoutDirs:       summary.TimeoutDirs,
 		ErrorDirs:         summary.ErrorDirs,
+		Codes:             summary.Codes,
 	})
 }
 
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/gussync"
+	"GusSync/pkg/state"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const stateUsage = `Usage: gussync state <command> -dest <dir> [-mode mount|adb] [flags]
+
+Commands:
+  summary            count files and directories by status
+  failed             list files that never completed, with failure counts
+  ls -path <prefix>  list tracked files under a source or phone path
+  missing            list completed files whose copy is gone from the destination
+  export             write all records as CSV or JSON (-format csv|json, -o file)
+  merge -from <src>  fold another destination's state into this one (src: a backup
+                     directory, a gus_state.md, or a JSON export)
+  import -from <src> like merge, for a destination with no state yet
+  compact            rewrite the state file with one line per file (keeps a .bak)`
+
+// stateCmd inspects and maintains the gus_state.md files under a destination:
+//
+//	gussync state summary -dest <dir> [-mode mount|adb] [-json]
+//	gussync state compact -dest <dir> [-mode mount|adb]
+//
+// The query commands read the state file without modifying it. Do not run compact
+// while a backup to the same destination is in progress.
+func stateCmd(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "compact":
+		return stateCompactCmd(args[1:])
+	case "merge", "import":
+		return stateMergeCmd(args[0], args[1:])
+	case "summary", "failed", "ls", "missing", "export":
+		return stateQueryCmd(args[0], args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "Unknown state command %q\n\n%s\n", args[0], stateUsage)
+	return 2
+}
+
+func stateQueryCmd(command string, args []string) int {
+	fs := flag.NewFlagSet("state "+command, flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	modeFlag := fs.String("mode", "", "Backup mode ('mount' or 'adb'); default: whichever state file exists")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	prefix := fs.String("path", "", "ls: source path or phone path prefix, e.g. 'DCIM/Camera'")
+	format := fs.String("format", "csv", "export: 'csv' or 'json'")
+	output := fs.String("o", "", "export: write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	m := backupMode(*dest, *modeFlag, "mount")
+	stateFile := gussync.StateFile(*dest, m)
+	sm, err := state.OpenReadOnly(stateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer sm.Close()
+
+	switch command {
+	case "summary":
+		summary := sm.Summary()
+		if *asJSON {
+			json.NewEncoder(os.Stdout).Encode(summary)
+			return 0
+		}
+		printStateSummary(stateFile, summary)
+		return 0
+
+	case "failed":
+		var failed []state.FileRecord
+		for _, r := range sm.Records() {
+			if r.Status == state.StatusFailed {
+				failed = append(failed, r)
+			}
+		}
+		sort.SliceStable(failed, func(i, j int) bool { return failed[i].Failures > failed[j].Failures })
+		return printRecords(failed, *asJSON, "No failed files")
+
+	case "ls":
+		var matched []state.FileRecord
+		for _, r := range sm.Records() {
+			if matchesPrefix(r, *prefix) {
+				matched = append(matched, r)
+			}
+		}
+		return printRecords(matched, *asJSON, "No matching files")
+
+	case "missing":
+		destDir := filepath.Join(*dest, m)
+		var missing []state.FileRecord
+		unknown := 0
+		for _, r := range sm.Records() {
+			if r.Status == state.StatusFailed {
+				continue
+			}
+			rel := engine.DestRelPath(r.SourcePath, r.Path)
+			if rel == "" {
+				unknown++
+				continue
+			}
+			if _, err := os.Stat(engine.DestFilePath(sm, destDir, r.SourcePath, rel)); os.IsNotExist(err) {
+				missing = append(missing, r)
+			}
+		}
+		if unknown > 0 {
+			fmt.Fprintf(os.Stderr, "Note: %d old-format entries have no recorded destination path and were not checked\n", unknown)
+		}
+		return printRecords(missing, *asJSON, "No files missing from the destination")
+
+	case "export":
+		out := io.Writer(os.Stdout)
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := exportRecords(out, sm.Records(), *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return 2
+}
+
+// matchesPrefix reports whether a record lies under prefix, given as a source path or a phone path
+func matchesPrefix(r state.FileRecord, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	under := func(path string) bool {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+	return under(r.SourcePath) || (r.Path != "" && under(r.Path))
+}
+
+func printStateSummary(stateFile string, s state.Summary) {
+	fmt.Printf("State: %s\n", stateFile)
+	fmt.Printf("  Completed:        %d\n", s.Completed)
+	fmt.Printf("  Failed:           %d\n", s.Failed)
+	fmt.Printf("  Deleted (source): %d\n", s.Deleted)
+	fmt.Printf("  Cleanup failures: %d\n", s.CleanupFailures)
+	fmt.Printf("  Verified:         %d\n", s.Verified)
+	fmt.Printf("  Quarantined:      %d\n", s.Quarantined)
+	fmt.Printf("  Converted:        %d\n", s.Converted)
+	if t := s.Totals; t.Runs > 0 {
+		fmt.Printf("  Runs:             %d (last %s)\n", t.Runs, t.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("  Copied:           %s\n", engine.FormatSize(t.Bytes))
+		if t.ExpectedFiles > 0 {
+			fmt.Printf("  Source files:     %d (last complete scan)\n", t.ExpectedFiles)
+		}
+	}
+	if len(s.Dirs) > 0 {
+		statuses := make([]string, 0, len(s.Dirs))
+		for status := range s.Dirs {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		fmt.Printf("  Directories:\n")
+		for _, status := range statuses {
+			fmt.Printf("    %-14s  %d\n", status+":", s.Dirs[status])
+		}
+	}
+}
+
+func printRecords(records []state.FileRecord, asJSON bool, empty string) int {
+	if asJSON {
+		if records == nil {
+			records = []state.FileRecord{}
+		}
+		json.NewEncoder(os.Stdout).Encode(records)
+		return 0
+	}
+	if len(records) == 0 {
+		fmt.Println(empty)
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tFAILURES\tBACKED UP\tSOURCE")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", r.Status, r.Failures, formatStateTime(r.BackedUpAt), r.SourcePath)
+	}
+	w.Flush()
+	fmt.Printf("\n%d files\n", len(records))
+	return 0
+}
+
+// exportRecords writes records as CSV (one row per file) or a JSON array
+func exportRecords(w io.Writer, records []state.FileRecord, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"source_path", "status", "hash", "path", "failures", "cleanup_failures",
+			"backed_up_at", "verified_at", "deleted_at", "converted", "quarantined"})
+		for _, r := range records {
+			cw.Write([]string{r.SourcePath, r.Status, r.Hash, r.Path, strconv.Itoa(r.Failures), strconv.Itoa(r.CleanupFailures),
+				formatRFC3339(r.BackedUpAt), formatRFC3339(r.VerifiedAt), r.DeletedAt, r.Converted, r.Quarantined})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown export format %q (want csv or json)", format)
+}
+
+func formatStateTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatRFC3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// stateMergeCmd merges the records from -from into the state under -dest, matching files by
+// source path and resolving differing copies by hash (see state.Merge)
+func stateMergeCmd(command string, args []string) int {
+	fs := flag.NewFlagSet("state "+command, flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory whose state receives the records")
+	modeFlag := fs.String("mode", "", "Backup mode ('mount' or 'adb'); default: whichever state file exists")
+	from := fs.String("from", "", "Backup directory, gus_state.md or JSON export to read")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" || *from == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest and -from are required")
+		return 2
+	}
+
+	m := backupMode(*dest, *modeFlag, "mount")
+	records, err := loadRecords(*from, m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	stateFile := gussync.StateFile(*dest, m)
+	if command == "import" {
+		if info, err := os.Stat(stateFile); err == nil && info.Size() > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %s already has state; use 'gussync state merge'\n", stateFile)
+			return 1
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	sm, err := state.NewStateManager(stateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	stats, err := sm.Merge(records)
+	if closeErr := sm.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(stats)
+		return 0
+	}
+	fmt.Printf("\nMerged %d records into %s:\n", len(records), stateFile)
+	fmt.Printf("  Added:     %d\n", stats.Added)
+	fmt.Printf("  Updated:   %d\n", stats.Updated)
+	fmt.Printf("  Unchanged: %d\n", stats.Unchanged)
+	fmt.Printf("  Conflicts: %d\n", len(stats.Conflicts))
+	for _, c := range stats.Conflicts {
+		fmt.Printf("    %s: kept %s, dropped %s\n", c.SourcePath, shortHash(c.Hash), shortHash(c.OtherHash))
+	}
+	fmt.Println("Run 'gussync state missing' to find merged files whose copies are not in this destination yet.")
+	return 0
+}
+
+// loadRecords reads state records from a backup directory (<dir>/<mode>/gus_state.md),
+// a state file, or a JSON file written by 'gussync state export -format json'
+func loadRecords(from, mode string) ([]state.FileRecord, error) {
+	info, err := os.Stat(from)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		from = gussync.StateFile(from, mode)
+	}
+	if strings.EqualFold(filepath.Ext(from), ".json") {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return nil, err
+		}
+		var records []state.FileRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("%s: %w", from, err)
+		}
+		return records, nil
+	}
+	sm, err := state.OpenReadOnly(from)
+	if err != nil {
+		return nil, err
+	}
+	defer sm.Close()
+	return sm.Records(), nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func stateCompactCmd(args []string) int {
+	fs := flag.NewFlagSet("state compact", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory")
+	backupMode := fs.String("mode", "", "Backup mode to compact ('mount' or 'adb'); default both")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "Error: -dest is required")
+		return 2
+	}
+
+	modes := []string{"mount", "adb"}
+	if *backupMode != "" {
+		modes = []string{*backupMode}
+	}
+
+	found := false
+	for _, m := range modes {
+		stateFile := gussync.StateFile(*dest, m)
+		if _, err := os.Stat(stateFile); err != nil {
+			continue
+		}
+		found = true
+		sm, err := state.NewStateManager(stateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		stats, err := sm.Compact()
+		sm.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", stateFile, err)
+			return 1
+		}
+		fmt.Printf("Compacted %s: %d -> %d lines (previous file kept as %s)\n",
+			stateFile, stats.LinesBefore, stats.LinesAfter, filepath.Base(stats.BackupPath))
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Error: no %s found under %s\n", stateFileName, *dest)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// tuiTickerSize is how many log lines and errors the dashboard's ticker keeps
+const tuiTickerSize = 8
+
+// TUIReporter draws a full-screen dashboard of a backup (gussync backup -tui): overall
+// progress, a bar per worker, the folder breakdown and a ticker of errors and log lines.
+// Keys: p pauses and resumes between files, s then a worker's number skips the file that worker
+// is stuck on, q (or Ctrl+C) cancels the run.
+//
+// The dashboard is a bubbletea program; the engine's reports reach it as messages.
+type TUIReporter struct {
+	pause tuiPause
+
+	mu      sync.Mutex
+	model   tuiModel     // the dashboard's state while the program isn't running
+	program *tea.Program // nil before Start and once the program has exited
+	done    chan struct{}
+}
+
+// tuiModel is the dashboard's bubbletea model
+type tuiModel struct {
+	numWorkers    int
+	title         string // mode, source and destination
+	width, height int
+
+	update engine.ProgressUpdate
+	ticker []tickerLine
+
+	pause       *tuiPause
+	cancel      context.CancelFunc               // nil until Start
+	skip        func(worker int) (string, error) // nil until SetSkip
+	skipPending bool                             // s was pressed; the next digit picks the worker
+}
+
+// tickerLine is one entry of the dashboard's ticker; it is also the message that adds it
+type tickerLine struct {
+	at    time.Time
+	level string
+	text  string
+}
+
+// NewTUIReporter returns a dashboard for a run with numWorkers workers; it draws nothing
+// until Start
+func NewTUIReporter(numWorkers int, title string) *TUIReporter {
+	r := &TUIReporter{}
+	r.model = tuiModel{numWorkers: numWorkers, title: title, width: 80, height: 24, pause: &r.pause}
+	return r
+}
+
+// canRunTUI reports why the dashboard can't be shown, if it can't: it needs a terminal for
+// both the keys and the screen
+func canRunTUI() error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("-tui needs an interactive terminal")
+	}
+	return nil
+}
+
+// SetSkip enables the skip key: skip abandons the file a worker is copying (Engine.SkipFile).
+// Call it before Start.
+func (r *TUIReporter) SetSkip(skip func(worker int) (string, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.model.skip = skip
+}
+
+// Start switches the terminal to the dashboard and runs it until Stop; cancel is called
+// when the user quits
+func (r *TUIReporter) Start(cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.program != nil {
+		return
+	}
+	r.model.cancel = cancel
+	// Signals stay with the CLI's own handler, which cancels the run
+	p := tea.NewProgram(r.model, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	done := make(chan struct{})
+	r.program, r.done = p, done
+
+	go func() {
+		defer close(done)
+		final, err := p.Run()
+		r.mu.Lock()
+		if m, ok := final.(tuiModel); ok {
+			r.model = m
+		}
+		r.program = nil
+		r.mu.Unlock()
+		if err != nil {
+			// The terminal is restored by now; the run goes on without the dashboard
+			fmt.Fprintf(os.Stderr, "Error: -tui: %v\n", err)
+		}
+	}()
+}
+
+// Stop closes the dashboard and restores the terminal; the output of the run continues below
+// what was there before
+func (r *TUIReporter) Stop() {
+	r.mu.Lock()
+	p, done := r.program, r.done
+	r.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.Quit()
+	<-done
+}
+
+// Summary is the run's final counts, for printing once the dashboard is gone
+func (r *TUIReporter) Summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.model.update
+	return fmt.Sprintf("%d files: %d copied (%s), %d skipped, %d failed", u.TotalFiles, u.Completed,
+		engine.FormatSize(u.TotalBytes), u.Skipped, u.Failed)
+}
+
+func (r *TUIReporter) ReportProgress(update engine.ProgressUpdate) {
+	r.send(update)
+}
+
+func (r *TUIReporter) ReportError(err error) {
+	r.send(newTickerLine("error", err.Error()))
+}
+
+func (r *TUIReporter) ReportLog(level, message string) {
+	r.send(newTickerLine(level, message))
+}
+
+// send hands msg to the running dashboard, or applies it to the model when there is none
+func (r *TUIReporter) send(msg tea.Msg) {
+	r.mu.Lock()
+	p := r.program
+	if p == nil {
+		m, _ := r.model.Update(msg)
+		r.model = m.(tuiModel)
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	// Returns at once if the program has exited in the meantime
+	p.Send(msg)
+}
+
+func newTickerLine(level, text string) tickerLine {
+	return tickerLine{at: time.Now(), level: level, text: text}
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case engine.ProgressUpdate:
+		m.update = msg
+	case tickerLine:
+		m.addTicker(msg)
+	case tea.KeyMsg:
+		cmd := m.handleKey(msg.String())
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m tuiModel) View() string {
+	return m.render(m.width, m.height)
+}
+
+// handleKey acts on a key press
+func (m *tuiModel) handleKey(key string) tea.Cmd {
+	switch key {
+	case "p", "P", " ":
+		if m.pause.toggle() {
+			m.addTicker(newTickerLine("info", "Paused; files being copied finish first"))
+		} else {
+			m.addTicker(newTickerLine("info", "Resumed"))
+		}
+	case "s", "S":
+		if m.numWorkers == 1 {
+			return m.skipWorker(0)
+		}
+		m.skipPending = true
+		m.addTicker(newTickerLine("info", fmt.Sprintf("Skip which worker's file? Press 0-%d", min(m.numWorkers, 10)-1)))
+	case "q", "Q", "ctrl+c":
+		m.addTicker(newTickerLine("warn", "Canceling; waiting for the files being copied..."))
+		if m.cancel != nil {
+			m.cancel()
+		}
+	default:
+		pending := m.skipPending
+		m.skipPending = false
+		if pending && len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+			return m.skipWorker(int(key[0] - '0'))
+		}
+	}
+	return nil
+}
+
+// skipWorker returns the command that makes a worker give up on its current file
+func (m *tuiModel) skipWorker(worker int) tea.Cmd {
+	m.skipPending = false
+	if m.skip == nil {
+		m.addTicker(newTickerLine("warn", "Skipping files isn't available yet"))
+		return nil
+	}
+	skip := m.skip
+	return func() tea.Msg {
+		// The engine logs the skip itself
+		if _, err := skip(worker); err != nil {
+			return newTickerLine("warn", err.Error())
+		}
+		return nil
+	}
+}
+
+func (m *tuiModel) addTicker(line tickerLine) {
+	m.ticker = append(m.ticker, line)
+	if len(m.ticker) > tuiTickerSize {
+		m.ticker = m.ticker[len(m.ticker)-tuiTickerSize:]
+	}
+}
+
+// render lays out the dashboard for a width x height terminal
+func (m tuiModel) render(width, height int) string {
+	u := m.update
+	height = max(height, 10)
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fitWidth(fmt.Sprintf(format, args...), width))
+	}
+
+	state := "RUNNING"
+	if m.pause.Paused() {
+		state = "PAUSED"
+	} else if u.ScanComplete {
+		state = "DONE"
+	}
+	add("GusSync  %s  [%s]", m.title, state)
+
+	done := u.Completed + u.Skipped + u.Failed
+	var fraction float64
+	if u.TotalFiles > 0 {
+		fraction = float64(done) / float64(u.TotalFiles)
+	}
+	total := fmt.Sprint(u.TotalFiles)
+	if !u.ScanDone {
+		total += "+ (scanning)"
+	}
+	eta := "-"
+	if u.ETASeconds > 0 {
+		eta = (time.Duration(u.ETASeconds) * time.Second).String()
+	}
+	add("Files %d/%s %s %3.0f%%  %.2f MB/s  ETA %s", done, total, progressBar(fraction, 30), fraction*100, u.Rate/(1024*1024), eta)
+	add("Copied %d  Skipped %d  Failed %d  Timeouts %d  Queued %d small / %d large  %s copied",
+		u.Completed, u.Skipped, u.Failed, u.TimeoutSkips, u.SmallQueue, u.LargeQueue, engine.FormatSize(u.TotalBytes))
+	add("")
+
+	add("Workers")
+	for id := 0; id < m.numWorkers; id++ {
+		if f, ok := u.WorkerFiles[id]; ok {
+			if f.Size > 0 {
+				add(" W%-2d %s %3.0f%%  %s  %s / %s", id, progressBar(float64(f.Copied)/float64(f.Size), 20),
+					float64(f.Copied)*100/float64(f.Size), filepath.Base(f.Path), engine.FormatSize(f.Copied), engine.FormatSize(f.Size))
+			} else {
+				add(" W%-2d %s       %s  %s", id, progressBar(0, 20), filepath.Base(f.Path), engine.FormatSize(f.Copied))
+			}
+			continue
+		}
+		status := u.WorkerStatuses[id]
+		if status == "" {
+			status = "idle"
+		}
+		add(" W%-2d %s", id, status)
+	}
+	add("")
+
+	// The folders and the ticker share what's left, leaving a line for the keys
+	room := height - len(lines) - 1
+	tickerRoom := min(len(m.ticker)+1, max(room/2, 3))
+	if len(u.Dirs) > 0 && room-tickerRoom > 2 {
+		dirs := u.Dirs
+		if len(dirs) > room-tickerRoom-2 {
+			dirs = dirs[:room-tickerRoom-2]
+		}
+		nameWidth := len("Folder")
+		for _, d := range dirs {
+			nameWidth = max(nameWidth, len(d.Dir))
+		}
+		add(" %-*s %8s %8s %10s %8s", nameWidth, "Folder", "Files", "Copied", "Size", "Failed")
+		for _, d := range dirs {
+			add(" %-*s %8d %8d %10s %8d", nameWidth, d.Dir, d.Files, d.Completed, engine.FormatSize(d.Bytes), d.Failed)
+		}
+		add("")
+	}
+
+	if len(m.ticker) > 0 {
+		ticker := m.ticker
+		if keep := height - len(lines) - 2; keep < len(ticker) {
+			ticker = ticker[len(ticker)-max(keep, 0):]
+		}
+		add("Recent")
+		for _, t := range ticker {
+			add(" %s %-5s %s", t.at.Format("15:04:05"), t.level, t.text)
+		}
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:height-1], fitWidth("[p] pause/resume  [s] skip a worker's file  [q] cancel", width))
+	return strings.Join(lines, "\n")
+}
+
+// progressBar draws fraction (0-1) as a bar of width cells
+func progressBar(fraction float64, width int) string {
+	fraction = min(max(fraction, 0), 1)
+	filled := int(fraction * float64(width))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// fitWidth cuts a line to the terminal's width
+func fitWidth(line string, width int) string {
+	runes := []rune(line)
+	if width > 0 && len(runes) > width {
+		return string(runes[:width])
+	}
+	return line
+}
+
+// tuiPause is the run's engine.Pauser, toggled from the dashboard
+type tuiPause struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{} // closed on resume; replaced on each pause
+}
+
+func (p *tuiPause) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+func (p *tuiPause) WaitIfPaused(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return nil
+	}
+	resume := p.resume
+	p.mu.Unlock()
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// toggle pauses or resumes; true if now paused
+func (p *tuiPause) toggle() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = !p.paused
+	if p.paused {
+		p.resume = make(chan struct{})
+	} else {
+		close(p.resume)
+	}
+	return p.paused
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"GusSync/pkg/engine"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTUIRender(t *testing.T) {
+	m := NewTUIReporter(2, "mount  /phone -> /backup/mount").model
+	m.update = engine.ProgressUpdate{
+		TotalFiles: 10, Completed: 4, Failed: 1, ScanDone: true,
+		WorkerStatuses: map[int]string{1: "idle"},
+		WorkerFiles:    map[int]engine.WorkerFile{0: {Path: "/phone/DCIM/movie.mp4", Size: 1000, Copied: 250}},
+		Dirs:           []engine.DirStats{{Dir: "DCIM", Files: 10, Completed: 4, Failed: 1}},
+	}
+	m.ticker = []tickerLine{{at: time.Now(), level: "error", text: "copy /phone/DCIM/bad.jpg: stalled"}}
+
+	screen := m.render(100, 30)
+	lines := strings.Split(screen, "\n")
+	if len(lines) != 30 {
+		t.Fatalf("got %d lines, want the terminal's 30", len(lines))
+	}
+	for _, want := range []string{"[RUNNING]", "Files 5/10", "movie.mp4", " 25%", "W1  idle", "DCIM", "stalled", "[p] pause/resume"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("dashboard lacks %q:\n%s", want, screen)
+		}
+	}
+	for _, line := range lines {
+		if len([]rune(line)) > 100 {
+			t.Errorf("line wider than the terminal: %q", line)
+		}
+	}
+
+	// A small terminal still shows the keys on the last line
+	small := strings.Split(m.render(40, 12), "\n")
+	if len(small) != 12 || !strings.HasPrefix(small[11], "[p]") {
+		t.Errorf("small terminal: %q", small)
+	}
+}
+
+func TestTUIPause(t *testing.T) {
+	var p tuiPause
+	if !p.toggle() || !p.Paused() {
+		t.Fatal("toggle should pause")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.WaitIfPaused(ctx); err == nil {
+		t.Error("WaitIfPaused returned while paused")
+	}
+	p.toggle()
+	if err := p.WaitIfPaused(context.Background()); err != nil || p.Paused() {
+		t.Errorf("after resuming: %v", err)
+	}
+}
+
+// press feeds the dashboard a key the way bubbletea delivers it
+func press(m tuiModel, key string) (tuiModel, tea.Cmd) {
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return next.(tuiModel), cmd
+}
+
+func TestTUISkipWorker(t *testing.T) {
+	r := NewTUIReporter(4, "adb")
+	m, _ := press(r.model, "s")
+	m, cmd := press(m, "1") // before SetSkip: only a note
+	if cmd != nil || !strings.Contains(m.ticker[len(m.ticker)-1].text, "isn't available") {
+		t.Errorf("skip without SetSkip: ticker = %+v", m.ticker)
+	}
+
+	var skipped []int
+	r.SetSkip(func(worker int) (string, error) {
+		skipped = append(skipped, worker)
+		return "", fmt.Errorf("worker %d isn't copying a file", worker)
+	})
+	m, _ = press(r.model, "s")
+	if !m.skipPending {
+		t.Fatal("s should ask for a worker")
+	}
+	m, cmd = press(m, "2")
+	if cmd == nil || m.skipPending {
+		t.Fatalf("digit after s: cmd %v, pending %v", cmd, m.skipPending)
+	}
+	msg := cmd()
+	if len(skipped) != 1 || skipped[0] != 2 {
+		t.Errorf("skipped %v", skipped)
+	}
+	// The skip's error comes back as a ticker line
+	next, _ := m.Update(msg)
+	m = next.(tuiModel)
+	if !strings.Contains(m.ticker[len(m.ticker)-1].text, "worker 2") {
+		t.Errorf("ticker = %+v", m.ticker)
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	r := NewTUIReporter(2, "adb")
+	canceled := false
+	r.model.cancel = func() { canceled = true }
+
+	m, _ := press(r.model, "p")
+	if !r.pause.Paused() || !strings.Contains(m.View(), "[PAUSED]") {
+		t.Error("p should pause")
+	}
+	m, _ = press(m, "p")
+	if r.pause.Paused() {
+		t.Error("second p should resume")
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !canceled {
+		t.Error("Ctrl+C should cancel the run")
+	}
+	m = next.(tuiModel)
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 60, Height: 15})
+	if lines := strings.Split(next.View(), "\n"); len(lines) != 15 {
+		t.Errorf("got %d lines after a resize to 15", len(lines))
+	}
+}
+
+func TestTUIReportsBeforeStart(t *testing.T) {
+	r := NewTUIReporter(1, "mount")
+	r.ReportLog("info", "scanning")
+	r.ReportProgress(engine.ProgressUpdate{TotalFiles: 3, Completed: 2, Failed: 1})
+	r.Stop() // never started: nothing to do
+	if len(r.model.ticker) != 1 || r.model.ticker[0].text != "scanning" {
+		t.Errorf("ticker = %+v", r.model.ticker)
+	}
+	if got := r.Summary(); !strings.HasPrefix(got, "3 files: 2 copied") || !strings.Contains(got, "1 failed") {
+		t.Errorf("Summary() = %q", got)
+	}
+}
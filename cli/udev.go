@@ -0,0 +1,266 @@
+package main
+
+import (
+	"GusSync/pkg/apiclient"
+	"GusSync/pkg/profile"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sysUSBDevices is where Linux lists USB devices; replaced in tests
+var sysUSBDevices = "/sys/bus/usb/devices"
+
+var (
+	unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+	usbIDPattern    = regexp.MustCompile(`^[0-9a-fA-F]{4}$`)
+)
+
+// usbDevice identifies the USB device whose arrival starts a backup
+type usbDevice struct {
+	vendor, product string // 4 hex digits each
+	serial          string // "" = any device with this vendor and product
+}
+
+// installUdevCmd sets up plug-triggered backups for a profile:
+//
+//	gussync install-udev -profile <name> [-vendor 18d1 -product 4ee7] [-serial X] [-print]
+//
+// It writes a udev rule that starts the systemd user service gussync-backup@<name> when the
+// device is attached, and that service, which runs gussync trigger <name>. Without -vendor
+// and -product the device is looked up by the profile's serial among the attached ones.
+func installUdevCmd(args []string) int {
+	fs := flag.NewFlagSet("install-udev", flag.ContinueOnError)
+	name := fs.String("profile", "", "Profile to back up when the device is attached")
+	vendor := fs.String("vendor", "", "USB vendor id, as lsusb shows it (default: that of the attached device)")
+	product := fs.String("product", "", "USB product id (default: that of the attached device)")
+	serial := fs.String("serial", "", "USB serial number (default: the profile's device serial)")
+	rulesDir := fs.String("rules-dir", "/etc/udev/rules.d", "Where to install the udev rule")
+	unitDir := fs.String("unit-dir", "", "Where to install the systemd user service (default ~/.config/systemd/user)")
+	printOnly := fs.Bool("print", false, "Print the rule and the service instead of installing them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "Error: -profile is required")
+		return 2
+	}
+	if !unitNamePattern.MatchString(*name) {
+		fmt.Fprintf(os.Stderr, "Error: profile %q can't be used in a systemd unit name (letters, digits and - _ . : only)\n", *name)
+		return 1
+	}
+
+	store, err := profile.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	p, err := store.Get(*name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *serial == "" {
+		*serial = p.DeviceSerial
+	}
+	dev := usbDevice{vendor: *vendor, product: *product, serial: *serial}
+	if dev.vendor == "" || dev.product == "" {
+		if dev, err = findUSBDevice(*serial); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if !usbIDPattern.MatchString(dev.vendor) || !usbIDPattern.MatchString(dev.product) {
+		fmt.Fprintln(os.Stderr, "Error: -vendor and -product are 4 hex digits, e.g. 18d1 and 4ee7 (see lsusb)")
+		return 1
+	}
+	if strings.ContainsAny(dev.serial, "\"\\\n") {
+		fmt.Fprintf(os.Stderr, "Error: invalid serial %q\n", dev.serial)
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	rule, unit := udevRule(p.Name, dev), backupUnit(exe)
+	ruleFile := fmt.Sprintf("99-gussync-%s.rules", p.Name)
+	if *printOnly {
+		fmt.Printf("# %s\n%s\n# %s\n%s", filepath.Join(*rulesDir, ruleFile), rule, backupUnitName, unit)
+		return 0
+	}
+
+	if *unitDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		*unitDir = filepath.Join(home, ".config", "systemd", "user")
+	}
+	if err := os.MkdirAll(*unitDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	unitPath := filepath.Join(*unitDir, backupUnitName)
+	if err := os.WriteFile(unitPath, []byte(unit), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", unitPath)
+
+	rulePath := filepath.Join(*rulesDir, ruleFile)
+	err = os.WriteFile(rulePath, []byte(rule), 0644)
+	if errors.Is(err, os.ErrPermission) {
+		// The rule needs root, the service must not be root's: leave the rule for sudo
+		pending := filepath.Join(*unitDir, ruleFile)
+		if err := os.WriteFile(pending, []byte(rule), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Wrote %s; installing it needs root:\n", pending)
+		fmt.Printf("  sudo install -m 644 %s %s && sudo udevadm control --reload\n", pending, rulePath)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	} else {
+		fmt.Printf("Wrote %s; run: sudo udevadm control --reload\n", rulePath)
+	}
+	fmt.Println("Then: systemctl --user daemon-reload")
+	fmt.Println("On a machine nobody logs in to, also: loginctl enable-linger $USER")
+	return 0
+}
+
+// backupUnitName is the systemd user service the udev rule starts, one instance per profile
+const backupUnitName = "gussync-backup@.service"
+
+// udevRule starts gussync-backup@<profile> in the user's systemd when the device is attached
+func udevRule(name string, dev usbDevice) string {
+	match := fmt.Sprintf(`ACTION=="add", SUBSYSTEM=="usb", ENV{DEVTYPE}=="usb_device", ATTR{idVendor}=="%s", ATTR{idProduct}=="%s"`,
+		strings.ToLower(dev.vendor), strings.ToLower(dev.product))
+	if dev.serial != "" {
+		match += fmt.Sprintf(`, ATTR{serial}=="%s"`, dev.serial)
+	}
+	return fmt.Sprintf("# Back up GusSync profile %s when its device is attached (gussync install-udev)\n%s, TAG+=\"systemd\", ENV{SYSTEMD_USER_WANTS}+=\"gussync-backup@%s.service\"\n",
+		name, match, name)
+}
+
+// backupUnit is the service that runs gussync trigger for the profile named by its instance
+func backupUnit(exe string) string {
+	if strings.ContainsAny(exe, " \t") {
+		exe = strconv.Quote(exe)
+	}
+	return fmt.Sprintf(`[Unit]
+Description=GusSync backup of profile %%i (device attached)
+
+[Service]
+Type=oneshot
+ExecStart=%s trigger %%i
+`, exe)
+}
+
+// findUSBDevice looks among the attached USB devices for the one with this serial: equal,
+// or the end of an MTP device id such as Google_Pixel_7_2A111FDH200ABC
+func findUSBDevice(serial string) (usbDevice, error) {
+	if serial == "" {
+		return usbDevice{}, fmt.Errorf("the profile has no device serial: give -vendor and -product (see lsusb)")
+	}
+	entries, err := os.ReadDir(sysUSBDevices)
+	if err != nil {
+		return usbDevice{}, fmt.Errorf("can't list USB devices (%v): give -vendor and -product", err)
+	}
+	read := func(dir, attr string) string {
+		data, _ := os.ReadFile(filepath.Join(sysUSBDevices, dir, attr))
+		return strings.TrimSpace(string(data))
+	}
+	for _, entry := range entries {
+		got := read(entry.Name(), "serial")
+		if got == "" || (got != serial && !strings.HasSuffix(serial, "_"+got)) {
+			continue
+		}
+		return usbDevice{vendor: read(entry.Name(), "idVendor"), product: read(entry.Name(), "idProduct"), serial: got}, nil
+	}
+	return usbDevice{}, fmt.Errorf("no attached USB device with serial %s: plug it in, or give -vendor and -product", serial)
+}
+
+// triggerCmd backs up a profile whose device was just attached (from the udev rule's service):
+//
+//	gussync trigger [-wait 2m] [-socket path] <profile>
+//
+// A running daemon is asked to do it; otherwise the backup runs here. Either way it first
+// waits for the device to be ready (mounted, or authorized for adb), as it isn't right away.
+func triggerCmd(args []string) int {
+	fs := flag.NewFlagSet("trigger", flag.ContinueOnError)
+	wait := fs.Duration("wait", 2*time.Minute, "How long to wait for the device to be ready")
+	socket := fs.String("socket", "", "Daemon control socket (default: as gussync daemon)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gussync trigger [-wait 2m] <profile>")
+		return 2
+	}
+	name := fs.Arg(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *wait)
+	defer cancel()
+	if client, err := daemonClient(ctx, *socket); err == nil {
+		var started *apiclient.StartCopyResponse
+		err := retryUntilReady(ctx, func() (err error) {
+			started, err = client.StartCopy(ctx, apiclient.StartCopyRequest{Profile: name})
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Backup of %s: daemon job %s\n", name, started.JobID)
+		return 0
+	}
+
+	store, err := profile.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	p, err := store.Get(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	err = retryUntilReady(ctx, func() error {
+		if _, ok := devicePresent(ctx, p); !ok {
+			return fmt.Errorf("%s is not connected", name)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return backupCmd([]string{"-profile", name})
+}
+
+// retryUntilReady calls fn every few seconds until it succeeds or ctx ends, returning its
+// last error
+func retryUntilReady(ctx context.Context, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUdevRule(t *testing.T) {
+	rule := udevRule("pixel", usbDevice{vendor: "18D1", product: "4ee7", serial: "2A111FDH200ABC"})
+	for _, want := range []string{
+		`ACTION=="add", SUBSYSTEM=="usb"`,
+		`ATTR{idVendor}=="18d1", ATTR{idProduct}=="4ee7", ATTR{serial}=="2A111FDH200ABC"`,
+		`ENV{SYSTEMD_USER_WANTS}+="gussync-backup@pixel.service"`,
+	} {
+		if !strings.Contains(rule, want) {
+			t.Errorf("rule has no %s:\n%s", want, rule)
+		}
+	}
+	if rule := udevRule("pixel", usbDevice{vendor: "18d1", product: "4ee7"}); strings.Contains(rule, "serial") {
+		t.Errorf("rule without a serial matches one:\n%s", rule)
+	}
+	if unit := backupUnit("/opt/Gus Sync/gussync"); !strings.Contains(unit, `ExecStart="/opt/Gus Sync/gussync" trigger %i`) {
+		t.Errorf("unit:\n%s", unit)
+	}
+}
+
+func TestFindUSBDevice(t *testing.T) {
+	dir := t.TempDir()
+	for name, attrs := range map[string][3]string{
+		"1-1":   {"1d6b", "0002", "0000:00:14.0"},
+		"1-2.1": {"18d1", "4ee7", "2A111FDH200ABC"},
+	} {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+		os.WriteFile(filepath.Join(dir, name, "idVendor"), []byte(attrs[0]+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, name, "idProduct"), []byte(attrs[1]+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, name, "serial"), []byte(attrs[2]+"\n"), 0644)
+	}
+	defer func(old string) { sysUSBDevices = old }(sysUSBDevices)
+	sysUSBDevices = dir
+
+	want := usbDevice{vendor: "18d1", product: "4ee7", serial: "2A111FDH200ABC"}
+	for _, serial := range []string{"2A111FDH200ABC", "Google_Pixel_7_2A111FDH200ABC"} {
+		if got, err := findUSBDevice(serial); err != nil || got != want {
+			t.Errorf("findUSBDevice(%q) = %+v, %v", serial, got, err)
+		}
+	}
+	for _, serial := range []string{"", "111FDH200ABC", "OTHER"} {
+		if _, err := findUSBDevice(serial); err == nil {
+			t.Errorf("findUSBDevice(%q): expected an error", serial)
+		}
+	}
+}
@@ -47,6 +47,15 @@ function AppContent() {
         store.addLog(data)
       })
 
+      // Backup notifications (the native desktop popup is shown by the backend)
+      const cleanupNotification = EventsOn('notification', (ev) => {
+        store.addLog({
+          timestamp: ev?.time,
+          level: ev?.kind === 'job_complete' ? 'info' : 'warn',
+          message: `${ev?.title}: ${ev?.message}`,
+        })
+      })
+
       const cleanupCheckProgress = EventsOn('PrereqCheckProgress', (data) => {
         const checkID = data?.checkID
         const status = data?.status
@@ -126,6 +135,7 @@ function AppContent() {
       // Store cleanups so they can be called on unmount
       window._wails_cleanups = () => {
         cleanupLog()
+        cleanupNotification()
         cleanupCheckProgress()
         cleanupPrereqReport()
       }
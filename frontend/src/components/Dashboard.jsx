@@ -748,6 +748,15 @@ export default function Dashboard() {
                       {worker.speed && (
                         <span className="text-[10px] text-blue-400 font-bold whitespace-nowrap drop-shadow-sm">{worker.speed}</span>
                       )}
+                      {worker.status === 'copying' && backupState.activeTask?.taskId && (
+                        <button
+                          onClick={() => backupState.skipFile(backupState.activeTask.taskId, workerID)}
+                          className="text-[10px] px-1.5 py-0.5 rounded bg-slate-800 text-slate-400 hover:bg-amber-500/30 hover:text-amber-300 border border-slate-700"
+                          title="Give up on this file (e.g. a stuck transfer); it counts as failed and is retried next run"
+                        >
+                          Skip
+                        </button>
+                      )}
                     </div>
 
                     <div className="space-y-1">
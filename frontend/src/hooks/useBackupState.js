@@ -1,6 +1,9 @@
 import { useState, useEffect, useMemo, useRef } from 'react'
 import { EventsOn, EventsOff } from '../../wailsjs/runtime/runtime'
 
+// How many files the recently backed up feed keeps
+const MAX_RECENT_FILES = 100
+
 /**
  * Custom hook to manage GusSync tasks and UI state
  * Uses the "task:update" unified event stream as the single source of truth.
@@ -36,6 +39,12 @@ export function useBackupState() {
   // Track last seen sequence number for out-of-order protection
   const lastSeqRef = useRef(0)
 
+  // Running and queued tasks by taskId: several can be open at once (different devices/destinations)
+  const openTasksRef = useRef({})
+  const [openTasks, setOpenTasks] = useState([])
+  // Files just backed up or failed, newest first (file:completed / file:failed events)
+  const [recentFiles, setRecentFiles] = useState([])
+
   // Helper function to process task updates (used by both initial fetch and events)
   const processTaskUpdate = (task) => {
     if (!task) return false
@@ -54,6 +63,7 @@ export function useBackupState() {
     const stateMap = {
       'queued': 'running',
       'running': 'running',
+      'paused': 'running',
       'succeeded': 'success',
       'failed': 'error',
       'canceled': 'idle'
@@ -61,7 +71,15 @@ export function useBackupState() {
     
     const mappedStatus = stateMap[task.state] || 'idle'
     setStatus(mappedStatus)
-    setIsRunning(task.state === 'running' || task.state === 'queued')
+    const open = { ...openTasksRef.current }
+    if (task.state === 'running' || task.state === 'queued' || task.state === 'paused') {
+      open[task.taskId] = task
+    } else {
+      delete open[task.taskId]
+    }
+    openTasksRef.current = open
+    setOpenTasks(Object.values(open))
+    setIsRunning(Object.keys(open).length > 0)
     setProgress(task.progress?.percent || 0)
     setStatusMessage(task.message || '')
 
@@ -82,7 +100,7 @@ export function useBackupState() {
         timeoutDirectories: 0,
         errorDirectories: 0
       })
-    } else if (task.progress?.phase === 'copying' || task.progress?.phase === 'finishing' || 
+    } else if (task.progress?.phase === 'copying' || task.progress?.phase === 'finalizing' || 
                task.progress?.phase === 'verifying' || task.progress?.phase === 'cleaning') {
       setDiscoveryState(prev => ({
         ...prev,
@@ -137,11 +155,18 @@ export function useBackupState() {
     const fetchActiveTask = async () => {
       if (window.go?.services?.JobManager?.GetActiveTask) {
         try {
+          // Other running and queued tasks, oldest update first (seq protection drops anything older)
+          const tasks = (await window.go.services.JobManager.ListTasks?.()) || []
+          tasks
+            .filter(t => t.state === 'running' || t.state === 'queued' || t.state === 'paused')
+            .sort((a, b) => (a.seq || 0) - (b.seq || 0))
+            .forEach(t => processTaskUpdate(t))
+
           const task = await window.go.services.JobManager.GetActiveTask()
-          if (task) {
+          if (task && !openTasksRef.current[task.taskId]) {
             console.log('[useBackupState] Startup handshake - got active task:', task)
             processTaskUpdate(task)
-          } else {
+          } else if (!task) {
             console.log('[useBackupState] Startup handshake - no active task')
           }
         } catch (e) {
@@ -157,6 +182,13 @@ export function useBackupState() {
       processTaskUpdate(task)
     })
 
+    // Per-file feed: keep the latest files for a scrolling "recently backed up" list
+    const addRecentFile = (status) => (file) => {
+      setRecentFiles(prev => [{ ...file, status }, ...prev].slice(0, MAX_RECENT_FILES))
+    }
+    const cleanupFileCompleted = EventsOn('file:completed', addRecentFile('completed'))
+    const cleanupFileFailed = EventsOn('file:failed', addRecentFile('failed'))
+
     // Device List Listener (keeps device status updated independently of tasks)
     const cleanupDeviceList = EventsOn('device:list', (data) => {
       setDevices(data || [])
@@ -204,6 +236,8 @@ export function useBackupState() {
 
     return () => {
       cleanupTask()
+      cleanupFileCompleted()
+      cleanupFileFailed()
       cleanupDeviceList()
       cleanupPrereq()
     }
@@ -222,6 +256,43 @@ export function useBackupState() {
     return []
   }
 
+  // Action: Page through finished tasks (persisted across restarts), newest first
+  const listHistory = async (offset = 0, limit = 50) => {
+    if (window.go?.services?.JobManager?.ListHistory) {
+      try {
+        return await window.go.services.JobManager.ListHistory(offset, limit)
+      } catch (e) {
+        console.warn('Failed to list history:', e)
+      }
+    }
+    return { tasks: [], total: 0, offset, limit }
+  }
+
+  // Action: Pause / resume a running backup between files
+  const pauseTask = async (taskId) => {
+    try {
+      await window.go?.services?.JobManager?.PauseTask?.(taskId)
+    } catch (e) {
+      console.warn('Failed to pause task:', e)
+    }
+  }
+  const resumeTask = async (taskId) => {
+    try {
+      await window.go?.services?.JobManager?.ResumeTask?.(taskId)
+    } catch (e) {
+      console.warn('Failed to resume task:', e)
+    }
+  }
+
+  // Action: Make a worker give up on a stuck file; it counts as failed once and the backup goes on
+  const skipFile = async (taskId, worker) => {
+    try {
+      return await window.go?.services?.JobManager?.SkipFile?.(taskId, Number(worker))
+    } catch (e) {
+      console.warn('Failed to skip file:', e)
+    }
+  }
+
   // Action: Cancel task
   const cancelTask = async (taskId) => {
     if (window.go?.services?.JobManager?.CancelTask) {
@@ -249,12 +320,25 @@ export function useBackupState() {
     discoveryState,
     lastCompletedTask,
     listTasks,
+    listHistory,
+    runningTasks: openTasks.filter(t => t.state === 'running' || t.state === 'paused'),
+    queuedTasks: openTasks.filter(t => t.state === 'queued'),
     cancelTask,
+    pauseTask,
+    resumeTask,
+    skipFile,
     clearLastCompletedTask: () => setLastCompletedTask(null),
     // Helper fields
     isIdle: status === 'idle' || status === 'ready',
     isSuccess: status === 'success',
     isError: status === 'error',
+    // Per-phase totals (scanning, copying, ..., in order) for a multi-phase progress bar
+    phases: activeTask?.progress?.phases || [],
+    etaSeconds: activeTask?.progress?.etaSeconds || 0,
+    // Latest files copied or failed: { jobId, sourcePath, relPath, size, durationMs, hash, error, status };
+    // CatalogService.Thumbnail(destPath, hash) returns a completed file's thumbnail
+    recentFiles,
+    clearRecentFiles: () => setRecentFiles([]),
     summaryStats: activeTask ? {
       totalFiles: activeTask.progress.total,
       filesCompleted: activeTask.progress.current,
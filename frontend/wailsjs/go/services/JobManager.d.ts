@@ -21,5 +21,3 @@ export function GetTask(arg1:string):Promise<services.TaskSnapshot>;
 export function ListTasks():Promise<Array<services.TaskSnapshot>>;
 
 export function SetContext(arg1:context.Context):Promise<void>;
-
-export function SetProcessPID(arg1:number,arg2:number):Promise<void>;
@@ -37,7 +37,3 @@ export function ListTasks() {
 export function SetContext(arg1) {
   return window['go']['services']['JobManager']['SetContext'](arg1);
 }
-
-export function SetProcessPID(arg1, arg2) {
-  return window['go']['services']['JobManager']['SetProcessPID'](arg1, arg2);
-}
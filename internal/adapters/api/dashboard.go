@@ -0,0 +1,40 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// WithDashboard serves the embedded web dashboard (job list, live progress, devices,
+// start/cancel) at / so a headless machine can be watched from a browser
+func WithDashboard() ServerOption {
+	return func(s *Server) {
+		s.dashboard = true
+	}
+}
+
+// dashboardHandler serves the dashboard's static files; unknown /api/ paths stay JSON 404s
+func (s *Server) dashboardHandler() http.Handler {
+	root, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err) // the embedded directory is part of the build
+	}
+	files := http.FileServer(http.FS(root))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			s.writeError(w, http.StatusNotFound, "not_found", "Unknown endpoint: "+r.URL.Path)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
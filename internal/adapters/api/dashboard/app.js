@@ -0,0 +1,145 @@
+// GusSync web dashboard: REST for the initial state, /api/ws for live updates and commands.
+(() => {
+  const jobs = new Map() // jobId -> job snapshot / update event
+  let ws = null
+  let nextId = 1
+
+  const $ = (id) => document.getElementById(id)
+
+  async function api(path, options) {
+    const res = await fetch(path, options)
+    const body = await res.json()
+    if (!body.success) throw new Error(body.error?.message || res.statusText)
+    return body.data
+  }
+
+  function el(tag, text, className) {
+    const e = document.createElement(tag)
+    if (text !== undefined) e.textContent = text
+    if (className) e.className = className
+    return e
+  }
+
+  function renderJobs() {
+    const tbody = $('jobs')
+    tbody.replaceChildren()
+    const list = [...jobs.values()].sort((a, b) => (b.seq || 0) - (a.seq || 0))
+    if (list.length === 0) {
+      const td = el('td', 'No jobs yet', 'muted')
+      td.colSpan = 5
+      const tr = el('tr')
+      tr.append(td)
+      tbody.append(tr)
+      return
+    }
+    for (const job of list) {
+      const tr = el('tr')
+      tr.append(el('td', job.jobId))
+      tr.append(el('td', job.state, 'state-' + job.state))
+
+      const bar = el('div', undefined, 'bar')
+      const fill = el('div')
+      fill.style.width = Math.min(100, job.progress?.percent || 0).toFixed(1) + '%'
+      bar.append(fill)
+      const progress = el('td')
+      progress.append(bar)
+      if (job.progress?.total) {
+        progress.append(el('span', ` ${job.progress.current}/${job.progress.total}`, 'muted'))
+      }
+      tr.append(progress)
+      tr.append(el('td', job.message || ''))
+
+      const actions = el('td')
+      const open = ['queued', 'running', 'paused'].includes(job.state)
+      if (job.state === 'running' && job.type === 'copy.sync') {
+        actions.append(button('Pause', () => command('pause', job.jobId), true))
+      }
+      if (job.state === 'paused') {
+        actions.append(button('Resume', () => command('resume', job.jobId), true))
+      }
+      if (open) {
+        actions.append(button('Cancel', () => command('cancel', job.jobId)))
+      }
+      tr.append(actions)
+      tbody.append(tr)
+    }
+  }
+
+  function button(label, onClick, secondary) {
+    const b = el('button', label, secondary ? 'secondary' : '')
+    b.type = 'button'
+    b.addEventListener('click', onClick)
+    return b
+  }
+
+  function command(type, jobId) {
+    if (ws && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ id: String(nextId++), type, jobId }))
+    } else {
+      api(`/api/jobs/${encodeURIComponent(jobId)}/${type}`, { method: 'POST' }).then(loadJobs).catch(alert)
+    }
+  }
+
+  async function loadJobs() {
+    const data = await api('/api/jobs')
+    for (const job of data.jobs || []) jobs.set(job.jobId, job)
+    renderJobs()
+  }
+
+  async function loadDevices() {
+    const ul = $('devices')
+    try {
+      const data = await api('/api/devices')
+      ul.replaceChildren()
+      const devices = data?.devices || []
+      if (devices.length === 0) ul.append(el('li', 'No device connected', 'muted'))
+      for (const d of devices) ul.append(el('li', `${d.name || d.id} (${d.type}) ${d.path || ''}`))
+    } catch (e) {
+      ul.replaceChildren(el('li', 'Device status unavailable: ' + e.message, 'muted'))
+    }
+  }
+
+  function connect() {
+    const proto = location.protocol === 'https:' ? 'wss:' : 'ws:'
+    ws = new WebSocket(`${proto}//${location.host}/api/ws`)
+    ws.onopen = () => {
+      $('conn').textContent = 'live'
+      $('conn').className = 'badge on'
+      ws.send(JSON.stringify({ id: String(nextId++), type: 'subscribe' }))
+    }
+    ws.onmessage = (ev) => {
+      const msg = JSON.parse(ev.data)
+      if (msg.type.startsWith('job:') && msg.data?.jobId) {
+        jobs.set(msg.data.jobId, { ...jobs.get(msg.data.jobId), ...msg.data })
+        renderJobs()
+      } else if (msg.type === 'error') {
+        alert(msg.error?.message || 'Command failed')
+      }
+    }
+    ws.onclose = () => {
+      $('conn').textContent = 'offline'
+      $('conn').className = 'badge off'
+      setTimeout(() => { connect(); loadJobs().catch(() => {}) }, 3000)
+    }
+  }
+
+  $('start').addEventListener('submit', async (ev) => {
+    ev.preventDefault()
+    const msg = $('start-msg')
+    try {
+      const data = await api('/api/copy/start', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ destinationPath: $('dest').value.trim() })
+      })
+      msg.textContent = `${data.message}: ${data.jobId}`
+    } catch (e) {
+      msg.textContent = 'Could not start: ' + e.message
+    }
+  })
+
+  loadJobs().catch((e) => console.warn(e))
+  loadDevices()
+  setInterval(loadDevices, 10000)
+  connect()
+})()
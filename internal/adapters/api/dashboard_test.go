@@ -0,0 +1,42 @@
+package api
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GusSync/internal/core"
+)
+
+func TestDashboard(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+
+	get := func(s *Server, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	s := NewServer(0, logger, core.NewJobManager(nil), WithDashboard())
+	if rec := get(s, "/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>GusSync</title>") {
+		t.Errorf("expected the dashboard page, got %d: %.100s", rec.Code, rec.Body.String())
+	}
+	if rec := get(s, "/app.js"); rec.Code != http.StatusOK {
+		t.Errorf("expected app.js, got %d", rec.Code)
+	}
+	if rec := get(s, "/api/nope"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"not_found"`) {
+		t.Errorf("expected a JSON 404 for unknown API paths, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(s, "/api/health"); rec.Code != http.StatusOK {
+		t.Errorf("API routes must still work, got %d", rec.Code)
+	}
+
+	// Off unless asked for
+	s = NewServer(0, logger, core.NewJobManager(nil))
+	if rec := get(s, "/"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected no dashboard by default, got %d", rec.Code)
+	}
+}
@@ -1,12 +1,23 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"GusSync/internal/core"
 )
 
+// defaultHistoryPageSize is used when /api/history is called without a limit
+const defaultHistoryPageSize = 50
+
 // handleHealth returns server health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -27,6 +38,11 @@ func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.writeJSON(w, http.StatusOK, s.jobList())
+}
+
+// jobList builds the job list shared by GET /api/jobs and the WebSocket "list" command
+func (s *Server) jobList() JobListResponse {
 	jobs := s.jobManager.ListJobs()
 	activeJob := s.jobManager.GetActiveJob()
 
@@ -35,10 +51,48 @@ func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
 		activeJobID = activeJob.JobID
 	}
 
-	s.writeJSON(w, http.StatusOK, JobListResponse{
+	resp := JobListResponse{
 		Jobs:      jobs,
 		ActiveJob: activeJobID,
-	})
+		Running:   []string{},
+		Queued:    []string{},
+	}
+	for _, j := range s.jobManager.RunningJobs() {
+		resp.Running = append(resp.Running, j.JobID)
+	}
+	for _, j := range s.jobManager.QueuedJobs() {
+		resp.Queued = append(resp.Queued, j.JobID)
+	}
+	return resp
+}
+
+// handleHistory returns a page of finished jobs
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	offset, limit := 0, defaultHistoryPageSize
+	for name, dst := range map[string]*int{"offset": &offset, "limit": &limit} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, http.StatusBadRequest, "invalid_query", fmt.Sprintf("%s must be a non-negative integer", name))
+			return
+		}
+		*dst = n
+	}
+
+	page, err := s.jobManager.History(offset, limit)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "history_failed", err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, HistoryResponse(page))
 }
 
 // handleActiveJob returns the currently active job
@@ -57,7 +111,8 @@ func (s *Server) handleActiveJob(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, job)
 }
 
-// handleJob handles operations on a specific job: GET /api/jobs/{id} or DELETE /api/jobs/{id}
+// handleJob handles operations on a specific job: GET /api/jobs/{id} or DELETE /api/jobs/{id}.
+// Cancelling a queued job removes it from the queue.
 func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
 	// Parse job ID from path: /api/jobs/{id} or /api/jobs/{id}/cancel
 	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
@@ -68,7 +123,10 @@ func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	jobID := parts[0]
-	isCancel := len(parts) > 1 && parts[1] == "cancel"
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -89,21 +147,57 @@ func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
 		})
 
 	case http.MethodPost:
-		if isCancel {
-			if err := s.jobManager.CancelJob(jobID); err != nil {
-				s.writeError(w, http.StatusBadRequest, "cancel_failed", err.Error())
-				return
-			}
-			s.writeJSON(w, http.StatusOK, map[string]string{
-				"message": fmt.Sprintf("Job %s cancellation requested", jobID),
-			})
-		} else {
-			s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Use POST /api/jobs/{id}/cancel to cancel")
+		if action == "skip" {
+			s.handleSkipFile(w, r, jobID)
+			return
 		}
+		if action != "cancel" && action != "pause" && action != "resume" {
+			s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Use POST /api/jobs/{id}/cancel, /pause, /resume or /skip")
+			return
+		}
+		if err := s.jobCommand(action, jobID); err != nil {
+			s.writeError(w, http.StatusBadRequest, action+"_failed", err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"message": fmt.Sprintf("Job %s %s requested", jobID, action),
+		})
 
 	default:
-		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET, DELETE, or POST to /cancel allowed")
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET, DELETE, or POST to /cancel, /pause, /resume or /skip allowed")
+	}
+}
+
+// handleSkipFile makes a worker of a running backup give up on its current file
+// (POST /api/jobs/{id}/skip?worker=N); the file counts as failed once and the job goes on
+func (s *Server) handleSkipFile(w http.ResponseWriter, r *http.Request, jobID string) {
+	worker, err := strconv.Atoi(r.URL.Query().Get("worker"))
+	if err != nil || worker < 0 {
+		s.writeError(w, http.StatusBadRequest, "invalid_worker", "worker query parameter required (a worker number)")
+		return
+	}
+	path, err := s.jobManager.SkipFile(jobID, worker)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "skip_failed", err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, SkipFileResponse{
+		Message: fmt.Sprintf("Worker %d of job %s is skipping %s", worker, jobID, path),
+		Path:    path,
+	})
+}
+
+// jobCommand applies a control action (cancel, pause, resume) to a job; shared by REST and WebSocket
+func (s *Server) jobCommand(action, jobID string) error {
+	switch action {
+	case "cancel":
+		return s.jobManager.CancelJob(jobID)
+	case "pause":
+		return s.jobManager.PauseJob(jobID)
+	case "resume":
+		return s.jobManager.ResumeJob(jobID)
 	}
+	return fmt.Errorf("unknown action: %s", action)
 }
 
 // handlePrereqs returns the prerequisites report
@@ -154,6 +248,149 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, config)
 }
 
+// handleQuarantine returns files that failed verification and were moved to quarantine
+func (s *Server) handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	if s.quarantineProvider == nil {
+		s.writeError(w, http.StatusNotImplemented, "not_implemented", "Quarantine provider not configured")
+		return
+	}
+
+	items, err := s.quarantineProvider()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "quarantine_failed", err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, items)
+}
+
+// handleErrors returns the error summary of each backup mode at the destination
+func (s *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	if s.errorsProvider == nil {
+		s.writeError(w, http.StatusNotImplemented, "not_implemented", "Errors provider not configured")
+		return
+	}
+
+	summaries, err := s.errorsProvider()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "errors_failed", err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, summaries)
+}
+
+// handleCatalog returns a page of the backed-up files of a folder, or of a search
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	if s.catalogProvider == nil {
+		s.writeError(w, http.StatusNotImplemented, "not_implemented", "Catalog provider not configured")
+		return
+	}
+
+	req, err := parseCatalogRequest(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	page, err := s.catalogProvider(req)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "catalog_failed", err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
+
+// handleThumbnail serves the cached JPEG thumbnail of a backed-up file by content hash
+func (s *Server) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	if s.thumbnailProvider == nil {
+		s.writeError(w, http.StatusNotImplemented, "not_implemented", "Thumbnail provider not configured")
+		return
+	}
+
+	hash := strings.TrimPrefix(r.URL.Path, "/api/thumbnails/")
+	if _, err := hex.DecodeString(hash); err != nil || hash == "" {
+		s.writeError(w, http.StatusBadRequest, "invalid_hash", "Expected a hex content hash")
+		return
+	}
+	path, err := s.thumbnailProvider(hash)
+	if errors.Is(err, os.ErrNotExist) {
+		s.writeError(w, http.StatusNotFound, "not_found", "No thumbnail for "+hash)
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "thumbnail_failed", err.Error())
+		return
+	}
+	// A hash always has the same thumbnail
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "max-age=604800, immutable")
+	http.ServeFile(w, r, path)
+}
+
+// parseCatalogRequest reads the query of GET /api/catalog. ext may be repeated or a comma
+// list; after and before are RFC 3339 times or dates (YYYY-MM-DD, UTC).
+func parseCatalogRequest(r *http.Request) (CatalogRequest, error) {
+	q := r.URL.Query()
+	req := CatalogRequest{Mode: q.Get("mode"), Folder: q.Get("folder"), Name: q.Get("name")}
+	if v := q.Get("recursive"); v != "" {
+		recursive, err := strconv.ParseBool(v)
+		if err != nil {
+			return req, fmt.Errorf("recursive must be true or false")
+		}
+		req.Recursive = recursive
+	}
+	for _, v := range q["ext"] {
+		for _, ext := range strings.Split(v, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				req.Extensions = append(req.Extensions, ext)
+			}
+		}
+	}
+	for name, dst := range map[string]*time.Time{"after": &req.After, "before": &req.Before} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, v); err != nil {
+				return req, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", name)
+			}
+		}
+		*dst = t
+	}
+	for name, dst := range map[string]*int{"offset": &req.Offset, "limit": &req.Limit} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return req, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		*dst = n
+	}
+	return req, nil
+}
+
 // handleStartCopy starts a new copy operation
 func (s *Server) handleStartCopy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -166,6 +403,10 @@ func (s *Server) handleStartCopy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.ContentLength != 0 && !s.requireJSON(w, r) {
+		return
+	}
+
 	var req StartCopyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		// Empty body is OK - will use defaults from config
@@ -178,9 +419,65 @@ func (s *Server) handleStartCopy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	message := "Copy operation started"
+	if job, err := s.jobManager.GetJob(jobID); err == nil && job.State == core.JobQueued {
+		message = "Copy operation queued"
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]string{
+		"jobId":   jobID,
+		"message": message,
+	})
+}
+
+// requireJSON refuses a body that isn't declared as JSON. A web page can POST text/plain
+// or form bodies to any site without the browser asking first, so those are never decoded.
+func (s *Server) requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		s.writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		return false
+	}
+	return true
+}
+
+// handleRestore handles POST /api/restore
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
+		return
+	}
+	if s.restoreFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "not_implemented", "Restore function not configured")
+		return
+	}
+
+	if !s.requireJSON(w, r) {
+		return
+	}
+
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	if len(req.Paths) == 0 {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "paths is required")
+		return
+	}
+
+	jobID, err := s.restoreFunc(r.Context(), req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "start_failed", err.Error())
+		return
+	}
+
+	message := "Restore started"
+	if job, err := s.jobManager.GetJob(jobID); err == nil && job.State == core.JobQueued {
+		message = "Restore queued"
+	}
 	s.writeJSON(w, http.StatusAccepted, map[string]string{
 		"jobId":   jobID,
-		"message": "Copy operation started",
+		"message": message,
 	})
 }
 
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GusSync/internal/core"
+)
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(OpenAPISpec(), &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got %q", spec.OpenAPI)
+	}
+
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil))
+	for path, item := range spec.Paths {
+		if !strings.HasPrefix(path, "/api/") {
+			t.Errorf("%s: documented path outside /api/", path)
+		}
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			req := httptest.NewRequest(strings.ToUpper(method), strings.ReplaceAll(path, "{id}", "job-1"), nil)
+			if _, pattern := s.mux.Handler(req); pattern == "" {
+				t.Errorf("%s %s: documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil))
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected the JSON document, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>GusSync API</title>") {
+		t.Errorf("expected the docs page, got %d", rec.Code)
+	}
+}
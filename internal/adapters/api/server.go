@@ -3,32 +3,46 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+
 	"GusSync/internal/core"
 )
 
 // Server is the HTTP API server for GusSync
 type Server struct {
+	host       string
 	port       int
 	logger     *log.Logger
 	jobManager *core.JobManager
 	server     *http.Server
 	mux        *http.ServeMux
 
-	// SSE clients
-	sseClients   map[chan core.JobUpdateEvent]struct{}
-	sseClientsMu sync.Mutex
+	// Event subscribers (SSE and WebSocket connections)
+	eventClients   map[chan core.JobUpdateEvent]struct{}
+	eventClientsMu sync.Mutex
 
 	// Service providers (set via options)
-	prereqProvider  func() interface{}
-	deviceProvider  func() interface{}
-	configProvider  func() interface{}
-	startCopyFunc   func(ctx context.Context, req StartCopyRequest) (string, error)
+	prereqProvider     func() interface{}
+	deviceProvider     func() interface{}
+	configProvider     func() interface{}
+	quarantineProvider func() (interface{}, error)
+	errorsProvider     func() (interface{}, error)
+	catalogProvider    func(req CatalogRequest) (interface{}, error)
+	thumbnailProvider  func(hash string) (string, error)
+	startCopyFunc      func(ctx context.Context, req StartCopyRequest) (string, error)
+	restoreFunc        func(ctx context.Context, req RestoreRequest) (string, error)
+	engineEvents       EngineEventSource
+	dashboard          bool     // serve the embedded web UI at /
+	allowedOrigins     []string // other origins whose pages may control jobs
 }
 
 // ServerOption configures the Server
@@ -55,6 +69,51 @@ func WithConfigProvider(fn func() interface{}) ServerOption {
 	}
 }
 
+// WithQuarantineProvider sets the function to list quarantined files
+func WithQuarantineProvider(fn func() (interface{}, error)) ServerOption {
+	return func(s *Server) {
+		s.quarantineProvider = fn
+	}
+}
+
+// WithErrorsProvider sets the function to summarize the backups' error logs
+func WithErrorsProvider(fn func() (interface{}, error)) ServerOption {
+	return func(s *Server) {
+		s.errorsProvider = fn
+	}
+}
+
+// WithCatalogProvider sets the function to list the backed-up files
+func WithCatalogProvider(fn func(req CatalogRequest) (interface{}, error)) ServerOption {
+	return func(s *Server) {
+		s.catalogProvider = fn
+	}
+}
+
+// WithThumbnailProvider sets the function to find the cached thumbnail of a file by content
+// hash; it returns the thumbnail's path, or an error wrapping os.ErrNotExist if there is none
+func WithThumbnailProvider(fn func(hash string) (string, error)) ServerOption {
+	return func(s *Server) {
+		s.thumbnailProvider = fn
+	}
+}
+
+// WithListenHost sets the address the server listens on (default 127.0.0.1, this machine
+// only); "0.0.0.0" or "" serves every interface, letting anyone on the network use the API
+func WithListenHost(host string) ServerOption {
+	return func(s *Server) {
+		s.host = host
+	}
+}
+
+// WithAllowedOrigins lets web pages from these origins (e.g. "http://nas.local:3000") start
+// and control jobs, besides pages served by the API itself
+func WithAllowedOrigins(origins ...string) ServerOption {
+	return func(s *Server) {
+		s.allowedOrigins = append(s.allowedOrigins, origins...)
+	}
+}
+
 // WithStartCopyFunc sets the function to start a copy operation
 func WithStartCopyFunc(fn func(ctx context.Context, req StartCopyRequest) (string, error)) ServerOption {
 	return func(s *Server) {
@@ -62,13 +121,34 @@ func WithStartCopyFunc(fn func(ctx context.Context, req StartCopyRequest) (strin
 	}
 }
 
+// WithRestoreFunc sets the function to start restoring backed-up files to the device
+func WithRestoreFunc(fn func(ctx context.Context, req RestoreRequest) (string, error)) ServerOption {
+	return func(s *Server) {
+		s.restoreFunc = fn
+	}
+}
+
+// EngineEventSource subscribes send to the engine's events of the given kinds (nil = all
+// kinds) and returns the function that ends the subscription. send must not block.
+type EngineEventSource func(kinds []string, send func(kind string, data interface{})) (unsubscribe func())
+
+// WithEngineEvents lets SSE clients receive the engine's per-file events (file:started,
+// file:completed, file:failed, dir:scanned, connection:lost, verify:mismatch) by listing
+// the kinds they want in the events query parameter
+func WithEngineEvents(source EngineEventSource) ServerOption {
+	return func(s *Server) {
+		s.engineEvents = source
+	}
+}
+
 // NewServer creates a new API server
 func NewServer(port int, logger *log.Logger, jobManager *core.JobManager, opts ...ServerOption) *Server {
 	s := &Server{
-		port:       port,
-		logger:     logger,
-		jobManager: jobManager,
-		sseClients: make(map[chan core.JobUpdateEvent]struct{}),
+		host:         "127.0.0.1",
+		port:         port,
+		logger:       logger,
+		jobManager:   jobManager,
+		eventClients: make(map[chan core.JobUpdateEvent]struct{}),
 	}
 
 	for _, opt := range opts {
@@ -91,9 +171,15 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/jobs/active", s.handleActiveJob)
 	s.mux.HandleFunc("/api/jobs/", s.handleJob) // handles /api/jobs/{id} and /api/jobs/{id}/cancel
 
+	// Finished jobs, persisted across restarts: GET /api/history?offset=0&limit=50
+	s.mux.HandleFunc("/api/history", s.handleHistory)
+
 	// SSE events
 	s.mux.HandleFunc("/api/events", s.handleSSE)
 
+	// WebSocket: job events plus commands (subscribe, cancel, pause, resume, list)
+	s.mux.HandleFunc("/api/ws", s.handleWebSocket)
+
 	// Prerequisites
 	s.mux.HandleFunc("/api/prereqs", s.handlePrereqs)
 
@@ -105,19 +191,48 @@ func (s *Server) setupRoutes() {
 
 	// Copy operations
 	s.mux.HandleFunc("/api/copy/start", s.handleStartCopy)
+
+	// Quarantined files (failed verification)
+	s.mux.HandleFunc("/api/quarantine", s.handleQuarantine)
+
+	// Error summary (from the structured error logs)
+	s.mux.HandleFunc("/api/errors", s.handleErrors)
+
+	// Backed-up files, from the state: GET /api/catalog?folder=DCIM&offset=0&limit=100
+	s.mux.HandleFunc("/api/catalog", s.handleCatalog)
+
+	// Thumbnails of backed-up images and videos: GET /api/thumbnails/{hash} (image/jpeg)
+	s.mux.HandleFunc("/api/thumbnails/", s.handleThumbnail)
+
+	// Write backed-up files back to the device (as a job): POST /api/restore
+	s.mux.HandleFunc("/api/restore", s.handleRestore)
+
+	// API description: OpenAPI 3 document and a browsable reference
+	s.mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("/api/docs", s.handleDocs)
+
+	// Web dashboard (optional)
+	if s.dashboard {
+		s.mux.Handle("/", s.dashboardHandler())
+	}
 }
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: s.corsMiddleware(s.loggingMiddleware(s.mux)),
+		Addr:    net.JoinHostPort(s.host, strconv.Itoa(s.port)),
+		Handler: s.Handler(),
 	}
 
-	s.logger.Printf("[API] Starting HTTP server on port %d", s.port)
+	s.logger.Printf("[API] Starting HTTP server on %s", s.server.Addr)
 	return s.server.ListenAndServe()
 }
 
+// Handler returns the API with its middleware, for serving it from another listener (or a test server)
+func (s *Server) Handler() http.Handler {
+	return s.corsMiddleware(s.loggingMiddleware(s.originMiddleware(s.mux)))
+}
+
 // StartBackground starts the server in a goroutine
 func (s *Server) StartBackground(ctx context.Context) {
 	go func() {
@@ -147,10 +262,52 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// corsMiddleware adds CORS headers for cross-origin requests
+// originMiddleware refuses requests that change something (POST, DELETE, the WebSocket's
+// commands) from web pages the server doesn't trust
+func (s *Server) originMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		changes := r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+		if (changes || websocket.IsWebSocketUpgrade(r)) && !s.checkOrigin(r) {
+			s.writeError(w, http.StatusForbidden, "forbidden_origin", "Requests from "+r.Header.Get("Origin")+" are not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin allows requests from clients that send no Origin (not a browser), from pages
+// of the same host, and from the allowed origins. Any other page the user has open could
+// otherwise start, cancel or pause their jobs.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware adds CORS headers for cross-origin requests. Any page may read the API;
+// only the allowed origins may be let through for a request that changes something.
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin := r.Header.Get("Origin"); origin != "" && s.checkOrigin(r) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
@@ -163,36 +320,36 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// EmitJobUpdate implements core.JobEventEmitter to broadcast events to SSE clients
+// EmitJobUpdate implements core.JobEventEmitter to broadcast events to SSE and WebSocket clients
 func (s *Server) EmitJobUpdate(event core.JobUpdateEvent) {
-	s.sseClientsMu.Lock()
-	defer s.sseClientsMu.Unlock()
+	s.eventClientsMu.Lock()
+	defer s.eventClientsMu.Unlock()
 
-	for clientChan := range s.sseClients {
+	for clientChan := range s.eventClients {
 		select {
 		case clientChan <- event:
 		default:
 			// Client is slow, skip this event
-			s.logger.Printf("[API] SSE client slow, skipping event")
+			s.logger.Printf("[API] Event client slow, skipping event")
 		}
 	}
 }
 
-// addSSEClient registers a new SSE client
-func (s *Server) addSSEClient(ch chan core.JobUpdateEvent) {
-	s.sseClientsMu.Lock()
-	defer s.sseClientsMu.Unlock()
-	s.sseClients[ch] = struct{}{}
-	s.logger.Printf("[API] SSE client connected (total: %d)", len(s.sseClients))
+// addEventClient registers a new event subscriber (SSE or WebSocket)
+func (s *Server) addEventClient(ch chan core.JobUpdateEvent) {
+	s.eventClientsMu.Lock()
+	defer s.eventClientsMu.Unlock()
+	s.eventClients[ch] = struct{}{}
+	s.logger.Printf("[API] Event client connected (total: %d)", len(s.eventClients))
 }
 
-// removeSSEClient unregisters an SSE client
-func (s *Server) removeSSEClient(ch chan core.JobUpdateEvent) {
-	s.sseClientsMu.Lock()
-	defer s.sseClientsMu.Unlock()
-	delete(s.sseClients, ch)
+// removeEventClient unregisters an event subscriber
+func (s *Server) removeEventClient(ch chan core.JobUpdateEvent) {
+	s.eventClientsMu.Lock()
+	defer s.eventClientsMu.Unlock()
+	delete(s.eventClients, ch)
 	close(ch)
-	s.logger.Printf("[API] SSE client disconnected (total: %d)", len(s.sseClients))
+	s.logger.Printf("[API] Event client disconnected (total: %d)", len(s.eventClients))
 }
 
 // Helper functions for responses
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GusSync/internal/core"
+)
+
+func TestRestoreRequiresJSON(t *testing.T) {
+	restored := false
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil),
+		WithRestoreFunc(func(ctx context.Context, req RestoreRequest) (string, error) {
+			restored = true
+			return "job-1", nil
+		}))
+	body := `{"paths":["DCIM/IMG_0001.jpg"],"overwrite":true}`
+
+	// What a plain HTML form or a no-preflight fetch from another page would send
+	for _, contentType := range []string{"text/plain", "application/x-www-form-urlencoded", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/api/restore", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Content-Type %q: status %d, want 415", contentType, rec.Code)
+		}
+	}
+	if restored {
+		t.Fatal("a restore ran from a body that wasn't JSON")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/restore", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || !restored {
+		t.Errorf("JSON body: status %d, restored %v", rec.Code, restored)
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil), WithAllowedOrigins("http://nas.local:3000"))
+
+	for _, tc := range []struct {
+		method, origin, want string
+	}{
+		{http.MethodGet, "https://evil.example", "*"},    // reading is open to any page
+		{http.MethodOptions, "https://evil.example", ""}, // no preflight for a write
+		{http.MethodOptions, "http://nas.local:3000", "http://nas.local:3000"},
+		{http.MethodPost, "https://evil.example", ""},
+	} {
+		req := httptest.NewRequest(tc.method, "/api/jobs", nil)
+		req.Header.Set("Origin", tc.origin)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.want {
+			t.Errorf("%s from %s: Access-Control-Allow-Origin %q, want %q", tc.method, tc.origin, got, tc.want)
+		}
+	}
+}
+
+func TestOriginPolicy(t *testing.T) {
+	started := false
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil), WithAllowedOrigins("http://nas.local:3000"),
+		WithStartCopyFunc(func(ctx context.Context, req StartCopyRequest) (string, error) {
+			started = true
+			return "", context.Canceled
+		}))
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	post := func(method, path, origin string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/api/jobs/job-1/cancel"},
+		{http.MethodPost, "/api/jobs/job-1/pause"},
+		{http.MethodPost, "/api/jobs/job-1/skip?worker=0"},
+		{http.MethodDelete, "/api/jobs/job-1"},
+		{http.MethodPost, "/api/copy/start"},
+		{http.MethodPost, "/api/restore"},
+	} {
+		for _, origin := range []string{"https://evil.example", "null"} {
+			if code := post(route.method, route.path, origin); code != http.StatusForbidden {
+				t.Errorf("%s %s from %s: status %d, want 403", route.method, route.path, origin, code)
+			}
+		}
+	}
+	if started {
+		t.Fatal("a foreign page started a copy")
+	}
+
+	// The API's own pages, allowed origins and non-browser clients get through to the handler
+	for _, origin := range []string{ts.URL, "http://nas.local:3000", ""} {
+		if code := post(http.MethodPost, "/api/jobs/job-1/cancel", origin); code == http.StatusForbidden {
+			t.Errorf("cancel from %q was refused", origin)
+		}
+	}
+	if post(http.MethodPost, "/api/copy/start", ts.URL); !started {
+		t.Error("a copy from the API's own page didn't start")
+	}
+	if code := post(http.MethodGet, "/api/jobs", "https://evil.example"); code != http.StatusOK {
+		t.Errorf("GET /api/jobs from another origin: status %d", code)
+	}
+}
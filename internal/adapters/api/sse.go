@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"GusSync/internal/core"
 )
@@ -31,8 +32,14 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 	// Create a channel for this client
 	clientChan := make(chan core.JobUpdateEvent, 100) // Buffer to prevent blocking
-	s.addSSEClient(clientChan)
-	defer s.removeSSEClient(clientChan)
+	s.addEventClient(clientChan)
+	defer s.removeEventClient(clientChan)
+
+	// Engine events the client asked for: ?events=file:completed,file:failed (or all)
+	engineChan := s.subscribeEngineEvents(r)
+	if engineChan != nil {
+		defer engineChan.unsubscribe()
+	}
 
 	// Send initial connected event
 	s.sendSSEEvent(w, "connected", map[string]interface{}{
@@ -60,15 +67,7 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Determine event type based on job state
-			eventType := "job:update"
-			switch event.State {
-			case core.JobSucceeded:
-				eventType = "job:completed"
-			case core.JobFailed:
-				eventType = "job:failed"
-			case core.JobCanceled:
-				eventType = "job:canceled"
-			}
+			eventType := eventName(event)
 
 			// If there's a log line, emit a separate event
 			if event.LogLine != "" {
@@ -83,10 +82,72 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 			// Always emit the update event
 			s.sendSSEEvent(w, eventType, event)
 			flusher.Flush()
+		case ev := <-engineChan.events():
+			s.sendSSEEvent(w, ev.kind, ev.data)
+			flusher.Flush()
 		}
 	}
 }
 
+// engineEvent is an engine event waiting to be sent to an SSE client
+type engineEvent struct {
+	kind string
+	data interface{}
+}
+
+// engineSubscription is an SSE client's subscription to the engine's events
+type engineSubscription struct {
+	ch          chan engineEvent
+	unsubscribe func()
+}
+
+// events returns the channel of the subscription; a nil subscription never delivers
+func (sub *engineSubscription) events() <-chan engineEvent {
+	if sub == nil {
+		return nil
+	}
+	return sub.ch
+}
+
+// subscribeEngineEvents subscribes an SSE client to the engine event kinds listed in its
+// events query parameter ("all" = every kind). Nil when it asked for none or the server
+// has no event source. Events are dropped while the client falls behind.
+func (s *Server) subscribeEngineEvents(r *http.Request) *engineSubscription {
+	param := r.URL.Query().Get("events")
+	if param == "" || s.engineEvents == nil {
+		return nil
+	}
+	var kinds []string
+	if param != "all" {
+		for _, kind := range strings.Split(param, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	sub := &engineSubscription{ch: make(chan engineEvent, 256)}
+	sub.unsubscribe = s.engineEvents(kinds, func(kind string, data interface{}) {
+		select {
+		case sub.ch <- engineEvent{kind: kind, data: data}:
+		default:
+		}
+	})
+	return sub
+}
+
+// eventName is the SSE/WebSocket event type for a job update
+func eventName(event core.JobUpdateEvent) string {
+	switch event.State {
+	case core.JobSucceeded:
+		return "job:completed"
+	case core.JobFailed:
+		return "job:failed"
+	case core.JobCanceled:
+		return "job:canceled"
+	}
+	return "job:update"
+}
+
 // sendSSEEvent writes an SSE event to the response writer
 func (s *Server) sendSSEEvent(w http.ResponseWriter, eventType string, data interface{}) {
 	jsonData, err := json.Marshal(data)
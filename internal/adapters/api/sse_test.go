@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"GusSync/internal/core"
+)
+
+func TestSSEEngineEvents(t *testing.T) {
+	var mu sync.Mutex
+	var subscribed []string
+	var sends []func(kind string, data interface{})
+	source := func(kinds []string, send func(kind string, data interface{})) func() {
+		mu.Lock()
+		defer mu.Unlock()
+		subscribed = kinds
+		sends = append(sends, send)
+		return func() {}
+	}
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil), WithEngineEvents(source))
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	// A client that asks for no engine events isn't subscribed
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/api/events?events=file:completed,file:failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	lines.Scan() // the connected event is written once the client is subscribed
+
+	mu.Lock()
+	if len(sends) != 1 || strings.Join(subscribed, ",") != "file:completed,file:failed" {
+		t.Fatalf("subscribed %d clients to %v", len(sends), subscribed)
+	}
+	sends[0]("file:completed", map[string]string{"path": "/sdcard/DCIM/a.jpg"})
+	mu.Unlock()
+
+	done := make(chan string)
+	go func() {
+		for lines.Scan() {
+			if line := lines.Text(); strings.HasPrefix(line, "event: file:") {
+				lines.Scan()
+				done <- line + "\n" + lines.Text()
+				return
+			}
+		}
+	}()
+	select {
+	case got := <-done:
+		if !strings.Contains(got, "event: file:completed") || !strings.Contains(got, `"path":"/sdcard/DCIM/a.jpg"`) {
+			t.Errorf("got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("engine event not delivered")
+	}
+}
@@ -2,7 +2,11 @@
 // This adapter exposes REST endpoints and SSE event streaming for remote control.
 package api
 
-import "GusSync/internal/core"
+import (
+	"time"
+
+	"GusSync/internal/core"
+)
 
 // APIResponse wraps all API responses with a consistent structure
 type APIResponse struct {
@@ -20,14 +24,47 @@ type APIError struct {
 // JobListResponse contains a list of jobs
 type JobListResponse struct {
 	Jobs      []*core.JobSnapshot `json:"jobs"`
-	ActiveJob string              `json:"activeJob,omitempty"`
+	ActiveJob string              `json:"activeJob,omitempty"` // most recently started running job
+	Running   []string            `json:"running"`             // IDs of all running jobs
+	Queued    []string            `json:"queued"`              // IDs of waiting jobs, in start order
 }
 
+// HistoryResponse is one page of finished jobs, newest first
+type HistoryResponse = core.HistoryPage
+
 // StartCopyRequest is the request body for starting a copy operation
 type StartCopyRequest struct {
 	SourcePath      string `json:"sourcePath,omitempty"`
 	DestinationPath string `json:"destinationPath,omitempty"`
 	WorkerCount     int    `json:"workerCount,omitempty"`
+	Profile         string `json:"profile,omitempty"` // saved profile to back up (gussync daemon)
+}
+
+// SkipFileResponse is the reply to POST /api/jobs/{id}/skip
+type SkipFileResponse struct {
+	Message string `json:"message"`
+	Path    string `json:"path"` // source path of the skipped file
+}
+
+// CatalogRequest selects a page of a backup's catalog: GET /api/catalog?mode=&folder=&recursive=&name=&ext=&after=&before=&offset=&limit=
+type CatalogRequest struct {
+	Mode       string    `json:"mode,omitempty"` // "mount" or "adb" ("" = the first backup found)
+	Folder     string    `json:"folder,omitempty"`
+	Recursive  bool      `json:"recursive,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Extensions []string  `json:"extensions,omitempty"`
+	After      time.Time `json:"after"`
+	Before     time.Time `json:"before"`
+	Offset     int       `json:"offset,omitempty"`
+	Limit      int       `json:"limit,omitempty"`
+}
+
+// RestoreRequest selects backed-up files to write back to the device: POST /api/restore
+type RestoreRequest struct {
+	Mode      string   `json:"mode,omitempty"` // "mount" or "adb" ("" = the first backup found)
+	Paths     []string `json:"paths"`          // files or folders as listed by /api/catalog
+	Overwrite bool     `json:"overwrite,omitempty"`
+	DryRun    bool     `json:"dryRun,omitempty"`
 }
 
 // DeviceInfo represents device information
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"GusSync/internal/core"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 64 * 1024
+)
+
+// WSCommand is a message from a WebSocket client.
+//
+//	{"id": "1", "type": "subscribe"}                 all job events (optionally "jobId" for one job)
+//	{"id": "2", "type": "unsubscribe"}
+//	{"id": "3", "type": "cancel", "jobId": "..."}    also "pause" and "resume"
+//	{"id": "4", "type": "skip", "jobId": "...", "worker": 2}
+//	{"id": "5", "type": "list"}                      same payload as GET /api/jobs
+type WSCommand struct {
+	ID     string `json:"id,omitempty"` // echoed in the reply
+	Type   string `json:"type"`
+	JobID  string `json:"jobId,omitempty"`
+	Worker int    `json:"worker,omitempty"` // skip: the worker whose file to give up on
+}
+
+// WSMessage is a message to a WebSocket client: a job event ("job:update", "job:completed",
+// "job:failed", "job:canceled" with a core.JobUpdateEvent, "job:snapshot" with a core.JobSnapshot),
+// or the "result"/"error" reply to a command.
+type WSMessage struct {
+	Type  string      `json:"type"`
+	ID    string      `json:"id,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Error *APIError   `json:"error,omitempty"`
+}
+
+// wsConn is one WebSocket client. Only writeLoop writes to the socket.
+type wsConn struct {
+	s    *Server
+	conn *websocket.Conn
+	out  chan WSMessage
+	done chan struct{}
+
+	mu     sync.Mutex
+	events chan core.JobUpdateEvent // nil while not subscribed
+	filter string                   // job ID, or "" for all jobs
+}
+
+// handleWebSocket upgrades /api/ws to a WebSocket for two-way control: job events pushed
+// as they happen and commands (cancel, pause, resume, skip) sent back on the same connection
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error
+		s.logger.Printf("[API] WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	c := &wsConn{
+		s:    s,
+		conn: conn,
+		out:  make(chan WSMessage, 100),
+		done: make(chan struct{}),
+	}
+	s.logger.Printf("[API] WebSocket client connected: %s", r.RemoteAddr)
+
+	writerDone := make(chan struct{})
+	go func() {
+		c.writeLoop()
+		close(writerDone)
+	}()
+	c.readLoop()
+
+	close(c.done)
+	c.unsubscribe()
+	<-writerDone
+	conn.Close()
+	s.logger.Printf("[API] WebSocket client disconnected: %s", r.RemoteAddr)
+}
+
+// readLoop handles commands until the client goes away
+func (c *wsConn) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var cmd WSCommand
+		if err := c.conn.ReadJSON(&cmd); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				// A bad message, not a broken connection
+				c.send(WSMessage{Type: "error", Error: &APIError{Code: "invalid_json", Message: err.Error()}})
+				continue
+			}
+			return
+		}
+		c.handle(cmd)
+	}
+}
+
+// handle runs one command and sends its reply
+func (c *wsConn) handle(cmd WSCommand) {
+	reply := func(data interface{}) {
+		c.send(WSMessage{Type: "result", ID: cmd.ID, Data: data})
+	}
+	fail := func(code, message string) {
+		c.send(WSMessage{Type: "error", ID: cmd.ID, Error: &APIError{Code: code, Message: message}})
+	}
+
+	switch cmd.Type {
+	case "subscribe":
+		c.subscribe(cmd.JobID)
+		reply(map[string]string{"subscribed": "true", "jobId": cmd.JobID})
+		// Bring the client up to date, like the SSE stream's initial snapshot
+		for _, job := range append(c.s.jobManager.RunningJobs(), c.s.jobManager.QueuedJobs()...) {
+			if cmd.JobID == "" || cmd.JobID == job.JobID {
+				c.send(WSMessage{Type: "job:snapshot", Data: job})
+			}
+		}
+
+	case "unsubscribe":
+		c.unsubscribe()
+		reply(map[string]string{"subscribed": "false"})
+
+	case "cancel", "pause", "resume":
+		if cmd.JobID == "" {
+			fail("invalid_command", "jobId required")
+			return
+		}
+		if err := c.s.jobCommand(cmd.Type, cmd.JobID); err != nil {
+			fail(cmd.Type+"_failed", err.Error())
+			return
+		}
+		reply(map[string]string{"jobId": cmd.JobID, "action": cmd.Type})
+
+	case "skip":
+		if cmd.JobID == "" {
+			fail("invalid_command", "jobId required")
+			return
+		}
+		path, err := c.s.jobManager.SkipFile(cmd.JobID, cmd.Worker)
+		if err != nil {
+			fail("skip_failed", err.Error())
+			return
+		}
+		reply(map[string]interface{}{"jobId": cmd.JobID, "action": cmd.Type, "worker": cmd.Worker, "path": path})
+
+	case "list":
+		reply(c.s.jobList())
+
+	default:
+		fail("unknown_command", "unknown command type: "+cmd.Type)
+	}
+}
+
+// subscribe starts forwarding job events (for one job if jobID is set)
+func (c *wsConn) subscribe(jobID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filter = jobID
+	if c.events != nil {
+		return
+	}
+
+	events := make(chan core.JobUpdateEvent, 100)
+	c.events = events
+	c.s.addEventClient(events)
+	go func() {
+		for event := range events {
+			c.mu.Lock()
+			filter := c.filter
+			c.mu.Unlock()
+			if filter == "" || filter == event.JobID {
+				c.send(WSMessage{Type: eventName(event), Data: event})
+			}
+		}
+	}()
+}
+
+// unsubscribe stops forwarding job events
+func (c *wsConn) unsubscribe() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.events != nil {
+		c.s.removeEventClient(c.events) // closes the channel, ending the forwarder
+		c.events = nil
+	}
+}
+
+// send queues a message; like the SSE stream, a client too slow to keep up misses messages
+func (c *wsConn) send(msg WSMessage) {
+	select {
+	case c.out <- msg:
+	case <-c.done:
+	default:
+		c.s.logger.Printf("[API] WebSocket client slow, dropping %s message", msg.Type)
+	}
+}
+
+// writeLoop writes queued messages and keepalive pings
+func (c *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case msg := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				// The read loop notices the broken connection and cleans up
+				c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
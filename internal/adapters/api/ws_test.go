@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"GusSync/internal/core"
+)
+
+// readUntil reads messages until one of type want arrives
+func readUntil(t *testing.T, conn *websocket.Conn, want string) WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %q: %v", want, err)
+		}
+		if msg.Type == want {
+			return msg
+		}
+	}
+}
+
+func TestWebSocketEventsAndCommands(t *testing.T) {
+	jm := core.NewJobManager(nil)
+	s := NewServer(0, log.New(io.Discard, "", 0), jm)
+	jm.AddEmitter(s)
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(WSCommand{ID: "1", Type: "subscribe"})
+	if msg := readUntil(t, conn, "result"); msg.ID != "1" {
+		t.Fatalf("expected reply to command 1, got %+v", msg)
+	}
+
+	// Events arrive with the same payload as SSE
+	jobID, _, _ := jm.StartJob(context.Background(), "copy.sync", "", nil)
+	gate := jm.PauseGate(jobID)
+	jm.EmitLogLine(jobID, "hello")
+	if msg := readUntil(t, conn, "job:update"); msg.Data.(map[string]interface{})["jobId"] != jobID {
+		t.Errorf("unexpected event: %+v", msg)
+	}
+
+	conn.WriteJSON(WSCommand{ID: "2", Type: "pause", JobID: jobID})
+	if msg := readUntil(t, conn, "result"); msg.ID != "2" {
+		t.Fatalf("pause failed: %+v", msg)
+	}
+	if !gate.Paused() {
+		t.Error("expected job to be paused")
+	}
+
+	jm.SetSkipper(jobID, func(worker int) (string, error) { return "/sdcard/stuck.mp4", nil })
+	conn.WriteJSON(WSCommand{ID: "skip", Type: "skip", JobID: jobID, Worker: 1})
+	if msg := readUntil(t, conn, "result"); msg.ID != "skip" || msg.Data.(map[string]interface{})["path"] != "/sdcard/stuck.mp4" {
+		t.Fatalf("skip failed: %+v", msg)
+	}
+
+	conn.WriteJSON(WSCommand{ID: "3", Type: "cancel", JobID: jobID})
+	readUntil(t, conn, "job:canceled")
+	if job, _ := jm.GetJob(jobID); job.State != core.JobCanceled {
+		t.Errorf("expected canceled job, got %s", job.State)
+	}
+
+	// Errors are replies too, and leave the connection usable
+	conn.WriteJSON(WSCommand{ID: "4", Type: "resume", JobID: "nope"})
+	if msg := readUntil(t, conn, "error"); msg.ID != "4" || msg.Error == nil {
+		t.Errorf("expected error reply to command 4, got %+v", msg)
+	}
+	conn.WriteJSON(WSCommand{ID: "5", Type: "list"})
+	if msg := readUntil(t, conn, "result"); msg.ID != "5" {
+		t.Errorf("expected list reply, got %+v", msg)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s := NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil), WithAllowedOrigins("http://nas.local:3000"))
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/ws"
+
+	for origin, ok := range map[string]bool{
+		"":                      true, // not a browser
+		ts.URL:                  true,
+		"http://nas.local:3000": true,
+		"https://evil.example":  false,
+		"null":                  false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if ok && err != nil {
+			t.Errorf("origin %q: %v", origin, err)
+		}
+		if !ok && (err == nil || resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("origin %q: expected the upgrade to be refused", origin)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
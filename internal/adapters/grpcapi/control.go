@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"GusSync/internal/core"
+	"GusSync/pkg/controlpb"
+	"GusSync/pkg/gussync"
+)
+
+// StartBackup starts a backup, or queues it behind a conflicting job
+func (s *Server) StartBackup(ctx context.Context, req *controlpb.StartBackupRequest) (*controlpb.StartBackupResponse, error) {
+	if s.startBackupFunc == nil {
+		return nil, status.Error(codes.Unimplemented, "starting backups is not available")
+	}
+	jobID, err := s.startBackupFunc(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	job, err := s.jobManager.GetJob(jobID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &controlpb.StartBackupResponse{Job: toJob(job)}, nil
+}
+
+// GetProgress streams one job until it finishes, or every job until the client hangs up
+func (s *Server) GetProgress(req *controlpb.GetProgressRequest, stream controlpb.Control_GetProgressServer) error {
+	// Subscribe before taking the snapshot so no update falls in between
+	events, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	var lastSeq int64
+	if req.JobId != "" {
+		job, err := s.jobManager.GetJob(req.JobId)
+		if err != nil {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		if err := stream.Send(snapshotUpdate(job)); err != nil {
+			return err
+		}
+		if isFinished(job.State) {
+			return nil
+		}
+		lastSeq = job.Seq
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event := <-events:
+			if req.JobId != "" && (event.JobID != req.JobId || event.Seq < lastSeq) {
+				continue
+			}
+			if err := stream.Send(toUpdate(event)); err != nil {
+				return err
+			}
+			if req.JobId != "" && isFinished(event.State) {
+				return nil
+			}
+		}
+	}
+}
+
+// Cancel cancels a running job or removes a queued one
+func (s *Server) Cancel(ctx context.Context, req *controlpb.CancelRequest) (*controlpb.CancelResponse, error) {
+	if _, err := s.jobManager.GetJob(req.JobId); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err := s.jobManager.CancelJob(req.JobId); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &controlpb.CancelResponse{}, nil
+}
+
+// ListJobs lists this session's jobs, newest first, optionally followed by older finished jobs
+func (s *Server) ListJobs(ctx context.Context, req *controlpb.ListJobsRequest) (*controlpb.ListJobsResponse, error) {
+	resp := &controlpb.ListJobsResponse{}
+	seen := make(map[string]bool)
+	for _, job := range s.jobManager.ListJobs() {
+		resp.Jobs = append(resp.Jobs, toJob(job))
+		seen[job.JobID] = true
+	}
+	if req.IncludeHistory {
+		page, err := s.jobManager.History(0, 0)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		for _, job := range page.Jobs {
+			if !seen[job.JobID] {
+				resp.Jobs = append(resp.Jobs, toJob(job))
+			}
+		}
+	}
+	for _, job := range s.jobManager.RunningJobs() {
+		resp.Running = append(resp.Running, job.JobID)
+	}
+	for _, job := range s.jobManager.QueuedJobs() {
+		resp.Queued = append(resp.Queued, job.JobID)
+	}
+	return resp, nil
+}
+
+// GetState summarizes the state file of a backup destination
+func (s *Server) GetState(ctx context.Context, req *controlpb.GetStateRequest) (*controlpb.GetStateResponse, error) {
+	dest := req.DestinationPath
+	if dest == "" && s.destinationProvider != nil {
+		dest = s.destinationProvider()
+	}
+	if dest == "" {
+		return nil, status.Error(codes.InvalidArgument, "destination_path is required")
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = gussync.ModeMount
+	}
+	if mode != gussync.ModeMount && mode != gussync.ModeADB {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown mode %q (want mount or adb)", mode)
+	}
+
+	stateFile := gussync.StateFile(dest, mode)
+	sm, err := gussync.OpenState(dest, mode)
+	if os.IsNotExist(err) {
+		return nil, status.Errorf(codes.NotFound, "no backup state at %s", stateFile)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	summary := sm.Summary()
+	return &controlpb.GetStateResponse{
+		StateFile:       stateFile,
+		Completed:       int64(summary.Completed),
+		Failed:          int64(summary.Failed),
+		Deleted:         int64(summary.Deleted),
+		CleanupFailures: int64(summary.CleanupFailures),
+		Verified:        int64(summary.Verified),
+		Quarantined:     int64(summary.Quarantined),
+		Converted:       int64(summary.Converted),
+	}, nil
+}
+
+func isFinished(s core.JobState) bool {
+	return s == core.JobSucceeded || s == core.JobFailed || s == core.JobCanceled
+}
@@ -0,0 +1,105 @@
+package grpcapi
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"GusSync/internal/core"
+	"GusSync/pkg/controlpb"
+)
+
+var jobStates = map[core.JobState]controlpb.JobState{
+	core.JobQueued:    controlpb.JobState_JOB_STATE_QUEUED,
+	core.JobRunning:   controlpb.JobState_JOB_STATE_RUNNING,
+	core.JobPaused:    controlpb.JobState_JOB_STATE_PAUSED,
+	core.JobSucceeded: controlpb.JobState_JOB_STATE_SUCCEEDED,
+	core.JobFailed:    controlpb.JobState_JOB_STATE_FAILED,
+	core.JobCanceled:  controlpb.JobState_JOB_STATE_CANCELED,
+}
+
+func toProgress(p core.JobProgress) *controlpb.Progress {
+	progress := &controlpb.Progress{
+		Phase:      p.Phase,
+		Current:    p.Current,
+		Total:      p.Total,
+		Percent:    p.Percent,
+		RateMbps:   p.Rate,
+		EtaSeconds: p.ETASeconds,
+	}
+	for _, phase := range p.Phases {
+		progress.Phases = append(progress.Phases, &controlpb.Phase{
+			Name:    phase.Name,
+			Current: phase.Current,
+			Total:   phase.Total,
+			Unit:    phase.Unit,
+			Done:    phase.Done,
+		})
+	}
+	return progress
+}
+
+func toError(e *core.JobError) *controlpb.JobError {
+	if e == nil {
+		return nil
+	}
+	return &controlpb.JobError{Code: e.Code, Message: e.Message, Details: e.Details}
+}
+
+func toWorkers(workers map[int]string) map[int32]string {
+	if len(workers) == 0 {
+		return nil
+	}
+	out := make(map[int32]string, len(workers))
+	for id, status := range workers {
+		out[int32(id)] = status
+	}
+	return out
+}
+
+func toJob(j *core.JobSnapshot) *controlpb.Job {
+	job := &controlpb.Job{
+		JobId:     j.JobID,
+		Seq:       j.Seq,
+		Type:      j.Type,
+		State:     jobStates[j.State],
+		Params:    j.Params,
+		Progress:  toProgress(j.Progress),
+		Message:   j.Message,
+		Error:     toError(j.Error),
+		LogPath:   j.Artifact.LogPath,
+		CreatedAt: timestamppb.New(j.CreatedAt),
+		UpdatedAt: timestamppb.New(j.UpdatedAt),
+		Stats:     j.Stats,
+	}
+	if j.FinishedAt != nil {
+		job.FinishedAt = timestamppb.New(*j.FinishedAt)
+	}
+	return job
+}
+
+// snapshotUpdate is the first message of a GetProgress stream
+func snapshotUpdate(j *core.JobSnapshot) *controlpb.JobUpdate {
+	return &controlpb.JobUpdate{
+		JobId:    j.JobID,
+		Seq:      j.Seq,
+		Type:     j.Type,
+		State:    jobStates[j.State],
+		Progress: toProgress(j.Progress),
+		Message:  j.Message,
+		Workers:  toWorkers(j.Workers),
+		Error:    toError(j.Error),
+	}
+}
+
+func toUpdate(e core.JobUpdateEvent) *controlpb.JobUpdate {
+	return &controlpb.JobUpdate{
+		JobId:    e.JobID,
+		Seq:      e.Seq,
+		Type:     e.Type,
+		State:    jobStates[e.State],
+		Progress: toProgress(e.Progress),
+		Message:  e.Message,
+		LogLine:  e.LogLine,
+		Workers:  toWorkers(e.Workers),
+		Error:    toError(e.Error),
+	}
+}
@@ -0,0 +1,130 @@
+// Package grpcapi exposes the GusSync control service (pkg/controlpb) over gRPC.
+// It is the typed, streaming counterpart of the HTTP API in internal/adapters/api
+// and works on the same core JobManager.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"GusSync/internal/core"
+	"GusSync/pkg/controlpb"
+)
+
+// Server is the gRPC control server for GusSync
+type Server struct {
+	controlpb.UnimplementedControlServer
+
+	port       int
+	logger     *log.Logger
+	jobManager *core.JobManager
+	grpcServer *grpc.Server
+
+	// GetProgress subscribers
+	subscribers   map[chan core.JobUpdateEvent]struct{}
+	subscribersMu sync.Mutex
+
+	// Service providers (set via options)
+	startBackupFunc     func(ctx context.Context, req *controlpb.StartBackupRequest) (string, error)
+	destinationProvider func() string
+}
+
+// ServerOption configures the Server
+type ServerOption func(*Server)
+
+// WithStartBackupFunc sets the function that starts (or queues) a backup and returns its job ID
+func WithStartBackupFunc(fn func(ctx context.Context, req *controlpb.StartBackupRequest) (string, error)) ServerOption {
+	return func(s *Server) {
+		s.startBackupFunc = fn
+	}
+}
+
+// WithDestinationProvider sets the function returning the configured destination,
+// used by GetState when the request names none
+func WithDestinationProvider(fn func() string) ServerOption {
+	return func(s *Server) {
+		s.destinationProvider = fn
+	}
+}
+
+// NewServer creates a new gRPC server
+func NewServer(port int, logger *log.Logger, jobManager *core.JobManager, opts ...ServerOption) *Server {
+	s := &Server{
+		port:        port,
+		logger:      logger,
+		jobManager:  jobManager,
+		subscribers: make(map[chan core.JobUpdateEvent]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.grpcServer = grpc.NewServer()
+	controlpb.RegisterControlServer(s.grpcServer, s)
+	return s
+}
+
+// Serve serves gRPC on lis until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	s.logger.Printf("[gRPC] Serving on %s", lis.Addr())
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop stops the server, letting in-flight calls finish
+func (s *Server) Stop() {
+	s.grpcServer.GracefulStop()
+}
+
+// StartBackground listens on the configured port and serves until ctx is done
+func (s *Server) StartBackground(ctx context.Context) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			s.logger.Printf("[gRPC] Server error: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		s.logger.Printf("[gRPC] Shutting down gRPC server...")
+		// GetProgress streams only end with their job; don't let them hold up shutdown
+		s.grpcServer.Stop()
+	}()
+	return nil
+}
+
+// EmitJobUpdate implements core.JobEventEmitter to feed GetProgress streams
+func (s *Server) EmitJobUpdate(event core.JobUpdateEvent) {
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Stream is slow, skip this event
+			s.logger.Printf("[gRPC] Progress stream slow, skipping event")
+		}
+	}
+}
+
+// subscribe registers a GetProgress stream; call the returned function to unregister
+func (s *Server) subscribe() (chan core.JobUpdateEvent, func()) {
+	ch := make(chan core.JobUpdateEvent, 100)
+	s.subscribersMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subscribersMu.Unlock()
+
+	return ch, func() {
+		s.subscribersMu.Lock()
+		delete(s.subscribers, ch)
+		s.subscribersMu.Unlock()
+	}
+}
@@ -0,0 +1,159 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"GusSync/internal/core"
+	"GusSync/pkg/controlpb"
+	"GusSync/pkg/state"
+)
+
+// newTestClient serves s over an in-memory connection
+func newTestClient(t *testing.T, s *Server) controlpb.ControlClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return controlpb.NewControlClient(conn)
+}
+
+func TestStartBackupAndProgress(t *testing.T) {
+	jm := core.NewJobManagerWithThrottle(nil, core.ThrottleConfig{})
+	release := make(chan struct{})
+	s := NewServer(0, log.New(io.Discard, "", 0), jm, WithStartBackupFunc(func(ctx context.Context, req *controlpb.StartBackupRequest) (string, error) {
+		return jm.EnqueueJob(context.Background(), "copy.sync", "", map[string]string{"destPath": req.DestinationPath}, func(ctx context.Context, jobID string) error {
+			<-release
+			jm.UpdateProgress(jobID, core.JobProgress{Current: 1, Total: 2, Percent: 50}, "halfway", nil)
+			return nil
+		})
+	}))
+	jm.AddEmitter(s)
+	client := newTestClient(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started, err := client.StartBackup(ctx, &controlpb.StartBackupRequest{DestinationPath: "/backup"})
+	if err != nil {
+		t.Fatalf("StartBackup: %v", err)
+	}
+	jobID := started.Job.JobId
+	if started.Job.Params["destPath"] != "/backup" {
+		t.Errorf("unexpected job: %+v", started.Job)
+	}
+
+	stream, err := client.GetProgress(ctx, &controlpb.GetProgressRequest{JobId: jobID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := stream.Recv()
+	if err != nil || first.JobId != jobID {
+		t.Fatalf("expected a snapshot first, got %+v, %v", first, err)
+	}
+	close(release)
+
+	var updates []*controlpb.JobUpdate
+	for {
+		u, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		updates = append(updates, u)
+	}
+	if len(updates) == 0 || updates[len(updates)-1].State != controlpb.JobState_JOB_STATE_SUCCEEDED {
+		t.Fatalf("expected the stream to end with success, got %v", updates)
+	}
+	sawProgress := false
+	for _, u := range updates {
+		sawProgress = sawProgress || u.Progress.GetPercent() == 50
+	}
+	if !sawProgress {
+		t.Errorf("expected a progress update, got %v", updates)
+	}
+
+	// A finished job streams its final state and ends
+	stream, _ = client.GetProgress(ctx, &controlpb.GetProgressRequest{JobId: jobID})
+	if u, err := stream.Recv(); err != nil || u.State != controlpb.JobState_JOB_STATE_SUCCEEDED {
+		t.Errorf("expected the final state, got %+v, %v", u, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected the stream to end, got %v", err)
+	}
+
+	list, err := client.ListJobs(ctx, &controlpb.ListJobsRequest{})
+	if err != nil || len(list.Jobs) != 1 || list.Jobs[0].FinishedAt == nil {
+		t.Errorf("ListJobs: %+v, %v", list, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	jm := core.NewJobManager(nil)
+	client := newTestClient(t, NewServer(0, log.New(io.Discard, "", 0), jm))
+	ctx := context.Background()
+
+	jobID, jobCtx, _ := jm.StartJob(ctx, "verify.backup", "", nil)
+	if _, err := client.Cancel(ctx, &controlpb.CancelRequest{JobId: jobID}); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if jobCtx.Err() == nil {
+		t.Error("expected the job's context to be canceled")
+	}
+	if _, err := client.Cancel(ctx, &controlpb.CancelRequest{JobId: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if _, err := client.StartBackup(ctx, &controlpb.StartBackupRequest{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("expected Unimplemented without a start function, got %v", err)
+	}
+}
+
+func TestGetState(t *testing.T) {
+	dest := t.TempDir()
+	os.MkdirAll(filepath.Join(dest, "mount"), 0o755)
+	sm, err := state.NewStateManager(filepath.Join(dest, "mount", "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkDone("/phone/DCIM/a.jpg", "hash-a", "DCIM/a.jpg")
+	sm.MarkDone("/phone/DCIM/b.jpg", "hash-b", "DCIM/b.jpg")
+	sm.MarkSuccess()
+	sm.RecordFailure("/phone/DCIM/c.jpg")
+	sm.Close()
+
+	client := newTestClient(t, NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil),
+		WithDestinationProvider(func() string { return dest })))
+	ctx := context.Background()
+
+	got, err := client.GetState(ctx, &controlpb.GetStateRequest{})
+	if err != nil {
+		t.Fatalf("GetState: %v", err)
+	}
+	if got.Completed != 2 || got.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", got)
+	}
+	if _, err := client.GetState(ctx, &controlpb.GetStateRequest{Mode: "adb"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a missing state file, got %v", err)
+	}
+}
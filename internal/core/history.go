@@ -0,0 +1,210 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultHistoryLimit is how many finished jobs a FileHistory keeps before dropping the oldest
+const DefaultHistoryLimit = 500
+
+// HistoryStore persists finished jobs so the history survives restarts.
+// Save is called on every terminal transition; a later save of the same job ID replaces the earlier one.
+type HistoryStore interface {
+	Save(job JobSnapshot) error
+	// List returns finished jobs newest first, skipping offset and returning at most limit,
+	// along with the total number of stored jobs
+	List(offset, limit int) ([]*JobSnapshot, int, error)
+	Get(jobID string) (*JobSnapshot, error)
+}
+
+// HistoryPage is one page of job history
+type HistoryPage struct {
+	Jobs   []*JobSnapshot `json:"jobs"`
+	Total  int            `json:"total"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
+}
+
+// FileHistory is a HistoryStore backed by a JSON Lines file (one job per line, appended on save).
+// The file is compacted once it holds more than twice Limit lines.
+type FileHistory struct {
+	mu    sync.Mutex
+	path  string
+	limit int
+	lines int // lines in the file, -1 until counted
+}
+
+// NewFileHistory creates a history store at path (e.g. ~/.gussync/history.jsonl).
+// limit <= 0 uses DefaultHistoryLimit.
+func NewFileHistory(path string, limit int) *FileHistory {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	return &FileHistory{path: path, limit: limit, lines: -1}
+}
+
+// Save appends job to the history file
+func (h *FileHistory) Save(job JobSnapshot) error {
+	job.Workers = nil // transient, meaningless once the job is over
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lines < 0 {
+		_, lines, err := h.read()
+		if err != nil {
+			return err
+		}
+		h.lines = lines
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job history: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write job history: %w", err)
+	}
+	h.lines++
+
+	if h.lines > 2*h.limit {
+		return h.compact()
+	}
+	return nil
+}
+
+// List returns a page of finished jobs, newest first
+func (h *FileHistory) List(offset, limit int) ([]*JobSnapshot, int, error) {
+	h.mu.Lock()
+	jobs, lines, err := h.read()
+	if err == nil {
+		h.lines = lines
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := len(jobs)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return jobs[offset:end], total, nil
+}
+
+// Get returns a stored job by ID
+func (h *FileHistory) Get(jobID string) (*JobSnapshot, error) {
+	h.mu.Lock()
+	jobs, _, err := h.read()
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if j.JobID == jobID {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("job not found: %s", jobID)
+}
+
+// read loads the file, keeping the last record of each job, newest first and trimmed to limit.
+// It also returns the number of lines in the file. A missing file is an empty history;
+// unparseable lines (e.g. a torn write) are skipped.
+func (h *FileHistory) read() ([]*JobSnapshot, int, error) {
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read job history: %w", err)
+	}
+
+	var records []*JobSnapshot
+	index := make(map[string]int)
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lines++
+		var job JobSnapshot
+		if json.Unmarshal(line, &job) != nil || job.JobID == "" {
+			continue
+		}
+		if i, ok := index[job.JobID]; ok {
+			// Re-saved job: drop the old record so the job moves to its latest position
+			records[i] = nil
+		}
+		index[job.JobID] = len(records)
+		records = append(records, &job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read job history: %w", err)
+	}
+
+	jobs := make([]*JobSnapshot, 0, len(index))
+	for i := len(records) - 1; i >= 0 && len(jobs) < h.limit; i-- {
+		if records[i] != nil {
+			jobs = append(jobs, records[i])
+		}
+	}
+	return jobs, lines, nil
+}
+
+// compact rewrites the file with only the retained jobs (oldest first), via a temp file and rename
+func (h *FileHistory) compact() error {
+	jobs, _, err := h.read()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i := len(jobs) - 1; i >= 0; i-- {
+		data, err := json.Marshal(jobs[i])
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to compact job history: %w", err)
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact job history: %w", err)
+	}
+	h.lines = len(jobs)
+	return nil
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJobManager_HistorySurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	jm := NewJobManager(NewMockEmitter())
+	jm.SetHistory(NewFileHistory(path, 0), func(err error) { t.Error(err) })
+
+	okID, _, _ := jm.StartJob(context.Background(), "copy.sync", "", map[string]string{"src": "/a"})
+	jm.SetStats(okID, map[string]int64{"completed": 3, "bytes": 42})
+	jm.SetArtifact(okID, JobArtifact{LogPath: "/dest/gus_errors.log"})
+	jm.CompleteJob(okID, "done")
+
+	failID, _, _ := jm.StartJob(context.Background(), "verify.backup", "", nil)
+	jm.FailJob(failID, errors.New("boom"), "details")
+
+	// A new manager on the same file, as after an app restart
+	jm2 := NewJobManager(NewMockEmitter())
+	jm2.SetHistory(NewFileHistory(path, 0), nil)
+
+	page, err := jm2.History(0, 10)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if page.Total != 2 || len(page.Jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got total=%d len=%d", page.Total, len(page.Jobs))
+	}
+	if page.Jobs[0].JobID != failID || page.Jobs[1].JobID != okID {
+		t.Errorf("expected newest first, got %s, %s", page.Jobs[0].JobID, page.Jobs[1].JobID)
+	}
+
+	failed := page.Jobs[0]
+	if failed.State != JobFailed || failed.Error == nil || failed.Error.Message != "boom" {
+		t.Errorf("failed job not persisted correctly: %+v", failed)
+	}
+	ok := page.Jobs[1]
+	if ok.State != JobSucceeded || ok.Stats["completed"] != 3 || ok.Params["src"] != "/a" {
+		t.Errorf("succeeded job not persisted correctly: %+v", ok)
+	}
+	if ok.FinishedAt == nil || ok.Artifact.LogPath != "/dest/gus_errors.log" {
+		t.Errorf("expected finish time and artifact, got %+v", ok)
+	}
+
+	// Finished jobs from earlier runs are still reachable by ID
+	job, err := jm2.GetJob(okID)
+	if err != nil || job.State != JobSucceeded {
+		t.Errorf("GetJob from history: job=%+v err=%v", job, err)
+	}
+}
+
+func TestFileHistory_ResaveReplaces(t *testing.T) {
+	h := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"), 0)
+
+	h.Save(JobSnapshot{JobID: "a", State: JobFailed})
+	h.Save(JobSnapshot{JobID: "b", State: JobSucceeded})
+	h.Save(JobSnapshot{JobID: "a", State: JobCanceled})
+
+	jobs, total, err := h.List(0, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("expected 2 jobs, got %d", total)
+	}
+	if jobs[0].JobID != "a" || jobs[0].State != JobCanceled {
+		t.Errorf("expected re-saved job first with its latest state, got %+v", jobs[0])
+	}
+}
+
+func TestFileHistory_PagingAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := NewFileHistory(path, 5)
+
+	for i := 0; i < 12; i++ {
+		if err := h.Save(JobSnapshot{JobID: fmt.Sprintf("job-%d", i)}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	jobs, total, err := h.List(2, 2)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("expected total capped at 5, got %d", total)
+	}
+	if len(jobs) != 2 || jobs[0].JobID != "job-9" || jobs[1].JobID != "job-8" {
+		t.Errorf("unexpected page: %v", jobs)
+	}
+
+	// Past the end is an empty page, not an error
+	jobs, _, err = h.List(10, 2)
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("expected empty page, got %v (err=%v)", jobs, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines > 10 {
+		t.Errorf("expected the file to be compacted, has %d lines", lines)
+	}
+}
+
+func TestFileHistory_MissingAndCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := NewFileHistory(path, 0)
+
+	if jobs, total, err := h.List(0, 10); err != nil || total != 0 || len(jobs) != 0 {
+		t.Fatalf("missing file should be empty history: %v %d %v", jobs, total, err)
+	}
+
+	// A torn last line (crash mid-write) must not hide the rest
+	os.WriteFile(path, []byte(`{"jobId":"a","state":"succeeded"}`+"\n"+`{"jobId":"b","st`), 0644)
+	jobs, total, err := h.List(0, 10)
+	if err != nil || total != 1 || jobs[0].JobID != "a" {
+		t.Errorf("expected only the intact job, got %v %d %v", jobs, total, err)
+	}
+}
@@ -5,6 +5,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -16,11 +17,23 @@ type JobState string
 const (
 	JobQueued    JobState = "queued"
 	JobRunning   JobState = "running"
+	JobPaused    JobState = "paused" // running but held between files (see PauseGate)
 	JobSucceeded JobState = "succeeded"
 	JobFailed    JobState = "failed"
 	JobCanceled  JobState = "canceled"
 )
 
+// Job phases (JobProgress.Phase, JobPhase.Name), in the order a job goes through them. A
+// job only reports the phases it has; scanning and copying overlap, as files are copied
+// while the scan goes on.
+const (
+	PhaseScanning   = "scanning"
+	PhaseCopying    = "copying"
+	PhaseHashing    = "hashing"
+	PhaseVerifying  = "verifying"
+	PhaseFinalizing = "finalizing"
+)
+
 // JobProgress contains progress information for a running job
 type JobProgress struct {
 	Phase   string  `json:"phase"`
@@ -28,6 +41,34 @@ type JobProgress struct {
 	Total   int64   `json:"total"`
 	Percent float64 `json:"percent"`
 	Rate    float64 `json:"rate"` // e.g., MB/s
+
+	// Phases are the totals of each phase of the job, in order, for a progress bar per
+	// phase; Phase is the one in progress (the last one started)
+	Phases []JobPhase `json:"phases,omitempty"`
+	// ETASeconds is the estimated time left, as computed by the engine (0 = unknown, e.g.
+	// while the scan still finds files)
+	ETASeconds int64 `json:"etaSeconds,omitempty"`
+}
+
+// JobPhase is the progress of one phase of a job
+type JobPhase struct {
+	Name    string `json:"name"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`          // 0 = not known yet
+	Unit    string `json:"unit,omitempty"` // what Current and Total count: "files" or "bytes"
+	Done    bool   `json:"done"`
+}
+
+// Percent returns how much of the phase is done (0-100); a phase of unknown size is at 0
+// until it is done
+func (p JobPhase) Percent() float64 {
+	switch {
+	case p.Done:
+		return 100
+	case p.Total <= 0:
+		return 0
+	}
+	return min(float64(p.Current)/float64(p.Total)*100, 100)
 }
 
 // JobError contains error information when a job fails
@@ -41,6 +82,7 @@ type JobError struct {
 type JobArtifact struct {
 	LogPath     string `json:"logPath"`
 	OpenLogHint string `json:"openLogHint"`
+	ReportPath  string `json:"reportPath,omitempty"` // HTML or CSV run report ("" = none)
 }
 
 // JobSnapshot is the authoritative state of a job at a point in time.
@@ -58,6 +100,14 @@ type JobSnapshot struct {
 	Artifact  JobArtifact       `json:"artifact"`
 	CreatedAt time.Time         `json:"createdAt"`
 	UpdatedAt time.Time         `json:"updatedAt"`
+
+	// Resources the job holds exclusively while running (see JobResources)
+	Resources []string `json:"resources,omitempty"`
+
+	// Set once the job reaches a terminal state
+	Stats      map[string]int64 `json:"stats,omitempty"` // final counters reported by the job (files, bytes, ...)
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	DurationMs int64            `json:"durationMs,omitempty"`
 }
 
 // JobUpdateEvent is emitted when job state changes.
@@ -100,12 +150,19 @@ func DefaultThrottleConfig() ThrottleConfig {
 type JobManager struct {
 	mu           sync.Mutex
 	jobs         map[string]*JobSnapshot
-	activeJob    string // ID of the currently running job (only one at a time)
-	seqCounter   int64  // Global sequence counter for event ordering
+	activeJob    string              // ID of the most recently started running job
+	running      map[string][]string // Running job ID -> resources it holds
+	queue        []*queuedJob        // Jobs waiting for a slot or their resources, in submit order
+	maxRunning   int                 // Max jobs running at once (0 = limited by resources only)
+	seqCounter   int64               // Global sequence counter for event ordering
 	cancels      map[string]context.CancelFunc
-	emitter      JobEventEmitter // Adapter-provided event emitter
-	throttle     ThrottleConfig  // Throttling configuration
-	lastEmitTime map[string]time.Time // Last emit time per job for throttling
+	emitter      JobEventEmitter       // Adapter-provided event emitter
+	throttle     ThrottleConfig        // Throttling configuration
+	lastEmitTime map[string]time.Time  // Last emit time per job for throttling
+	gates        map[string]*PauseGate // Pause gates of jobs that support pausing
+	skippers     map[string]SkipFunc   // Skip-file controls of running jobs that offer one
+	history      HistoryStore          // Optional persistence for finished jobs
+	onHistoryErr func(error)           // Called when saving to history fails
 }
 
 // NewJobManager creates a new JobManager with default throttling
@@ -117,6 +174,10 @@ func NewJobManager(emitter JobEventEmitter) *JobManager {
 func NewJobManagerWithThrottle(emitter JobEventEmitter, throttle ThrottleConfig) *JobManager {
 	return &JobManager{
 		jobs:         make(map[string]*JobSnapshot),
+		running:      make(map[string][]string),
+		gates:        make(map[string]*PauseGate),
+		skippers:     make(map[string]SkipFunc),
+		maxRunning:   1,
 		cancels:      make(map[string]context.CancelFunc),
 		emitter:      emitter,
 		throttle:     throttle,
@@ -131,6 +192,15 @@ func (jm *JobManager) SetEmitter(emitter JobEventEmitter) {
 	jm.emitter = emitter
 }
 
+// SetHistory enables persisting finished jobs to store. onErr (optional) is told about failed saves;
+// a failing history never affects the job itself.
+func (jm *JobManager) SetHistory(store HistoryStore, onErr func(error)) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.history = store
+	jm.onHistoryErr = onErr
+}
+
 // AddEmitter adds an additional emitter. Events will be sent to all registered emitters.
 func (jm *JobManager) AddEmitter(emitter JobEventEmitter) {
 	jm.mu.Lock()
@@ -176,39 +246,21 @@ func (m *MultiEmitter) EmitJobUpdate(event JobUpdateEvent) {
 	}
 }
 
-// StartJob starts a new job and returns the job ID and context.
-// The context is cancelled when CancelJob is called.
+// StartJob starts a new job right away and returns the job ID and context.
+// It fails if no slot is free or another running job holds one of its resources;
+// use EnqueueJob to wait instead. The context is cancelled when CancelJob is called.
 func (jm *JobManager) StartJob(ctx context.Context, jobType string, message string, params map[string]string) (string, context.Context, error) {
-	jm.mu.Lock()
-
-	// Check if a job is already running
-	if jm.activeJob != "" {
-		active := jm.jobs[jm.activeJob]
-		if active != nil && active.State == JobRunning {
-			jm.mu.Unlock()
-			return "", nil, fmt.Errorf("a job is already running: %s (%s)", active.JobID, active.Type)
-		}
-	}
-
-	jobID := fmt.Sprintf("%s-%d", jobType, time.Now().UnixNano())
-	jobCtx, cancel := context.WithCancel(ctx)
+	resources := JobResources(params)
 
-	snapshot := &JobSnapshot{
-		JobID:     jobID,
-		Type:      jobType,
-		State:     JobRunning,
-		Params:    params,
-		Message:   message,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Progress: JobProgress{
-			Phase: "starting",
-		},
+	jm.mu.Lock()
+	if err := jm.blockedLocked(resources, nil); err != nil {
+		jm.mu.Unlock()
+		return "", nil, err
 	}
 
-	jm.jobs[jobID] = snapshot
-	jm.cancels[jobID] = cancel
-	jm.activeJob = jobID
+	snapshot, jobCtx := jm.newJobLocked(ctx, jobType, message, params, resources)
+	jm.startLocked(snapshot)
+	jobID := snapshot.JobID
 	jm.mu.Unlock()
 
 	// Emit initial event
@@ -284,51 +336,151 @@ func (jm *JobManager) UpdateProgress(jobID string, progress JobProgress, message
 	}
 }
 
+// SetStats records the job's counters (e.g. files completed, bytes copied); they end up in the history
+func (jm *JobManager) SetStats(jobID string, stats map[string]int64) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if snapshot, exists := jm.jobs[jobID]; exists {
+		snapshot.Stats = stats
+	}
+}
+
+// SetArtifact records where the job's output (error log, report) can be found
+func (jm *JobManager) SetArtifact(jobID string, artifact JobArtifact) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if snapshot, exists := jm.jobs[jobID]; exists {
+		snapshot.Artifact = artifact
+	}
+}
+
+// finishLocked stamps the end time of a job that just reached a terminal state
+// and releases its slot and resources. Must be called with jm.mu held.
+func (jm *JobManager) finishLocked(snapshot *JobSnapshot) {
+	now := time.Now()
+	snapshot.UpdatedAt = now
+	snapshot.FinishedAt = &now
+	snapshot.DurationMs = now.Sub(snapshot.CreatedAt).Milliseconds()
+	jm.releaseLocked(snapshot.JobID)
+}
+
+// saveHistory persists the job's current snapshot, if a history store is set
+func (jm *JobManager) saveHistory(jobID string) {
+	jm.mu.Lock()
+	snapshot, exists := jm.jobs[jobID]
+	store, onErr := jm.history, jm.onHistoryErr
+	var job JobSnapshot
+	if exists {
+		job = *snapshot
+	}
+	jm.mu.Unlock()
+
+	if !exists || store == nil {
+		return
+	}
+	if err := store.Save(job); err != nil && onErr != nil {
+		onErr(fmt.Errorf("failed to save job %s to history: %w", jobID, err))
+	}
+}
+
+// History returns a page of finished jobs, newest first. Without a history store
+// it pages over the finished jobs still in memory.
+func (jm *JobManager) History(offset, limit int) (HistoryPage, error) {
+	jm.mu.Lock()
+	store := jm.history
+	jm.mu.Unlock()
+
+	page := HistoryPage{Offset: offset, Limit: limit}
+	if store != nil {
+		jobs, total, err := store.List(offset, limit)
+		if err != nil {
+			return page, err
+		}
+		page.Jobs, page.Total = jobs, total
+		return page, nil
+	}
+
+	var finished []*JobSnapshot
+	for _, j := range jm.ListJobs() {
+		if j.FinishedAt != nil {
+			finished = append(finished, j)
+		}
+	}
+	page.Total = len(finished)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(finished) {
+		offset = len(finished)
+	}
+	end := len(finished)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	page.Jobs = finished[offset:end]
+	return page, nil
+}
+
 // CompleteJob marks a job as succeeded
 func (jm *JobManager) CompleteJob(jobID string, message string) {
 	jm.mu.Lock()
 	snapshot, exists := jm.jobs[jobID]
+	exists = exists && !snapshot.finished()
 	if exists {
 		snapshot.State = JobSucceeded
 		if message != "" {
 			snapshot.Message = message
 		}
 		snapshot.Progress.Percent = 100
-		snapshot.UpdatedAt = time.Now()
-		if jm.activeJob == jobID {
-			jm.activeJob = ""
-		}
+		jm.finishLocked(snapshot)
 	}
 	jm.mu.Unlock()
 
 	if exists {
+		jm.saveHistory(jobID)
 		jm.emitUpdate(jobID)
+		jm.dispatch()
 	}
 }
 
-// FailJob marks a job as failed
+// errorCoder is implemented by typed errors that carry a machine-readable code
+// (e.g. engine.ErrConnectionLost). Declared here so core stays free of engine imports.
+type errorCoder interface {
+	ErrorCode() string
+}
+
+// FailJob marks a job as failed.
+// If err (or anything it wraps) carries an error code, it is exposed in JobError.Code.
 func (jm *JobManager) FailJob(jobID string, err error, details string) {
+	jobErr := &JobError{Details: details}
+	if err != nil {
+		jobErr.Message = err.Error()
+		var coder errorCoder
+		if errors.As(err, &coder) {
+			jobErr.Code = coder.ErrorCode()
+		}
+	} else {
+		jobErr.Message = details
+	}
+
 	jm.mu.Lock()
 	snapshot, exists := jm.jobs[jobID]
+	exists = exists && !snapshot.finished()
 	if exists {
 		snapshot.State = JobFailed
-		snapshot.Error = &JobError{
-			Message: err.Error(),
-			Details: details,
-		}
-		snapshot.UpdatedAt = time.Now()
-		if jm.activeJob == jobID {
-			jm.activeJob = ""
-		}
+		snapshot.Error = jobErr
+		jm.finishLocked(snapshot)
 	}
 	jm.mu.Unlock()
 
 	if exists {
+		jm.saveHistory(jobID)
 		jm.emitUpdate(jobID)
+		jm.dispatch()
 	}
 }
 
-// CancelJob cancels a running job
+// CancelJob cancels a running job, or removes a queued one from the queue
 func (jm *JobManager) CancelJob(jobID string) error {
 	jm.mu.Lock()
 	cancel, cancelExists := jm.cancels[jobID]
@@ -344,20 +496,24 @@ func (jm *JobManager) CancelJob(jobID string) error {
 
 	if snapshotExists {
 		jm.mu.Lock()
+		if snapshot.finished() {
+			jm.mu.Unlock()
+			return nil
+		}
+		jm.dequeueLocked(jobID)
 		snapshot.State = JobCanceled
 		snapshot.Message = "Job canceled by user"
-		snapshot.UpdatedAt = time.Now()
-		if jm.activeJob == jobID {
-			jm.activeJob = ""
-		}
+		jm.finishLocked(snapshot)
 		jm.mu.Unlock()
+		jm.saveHistory(jobID)
 		jm.emitUpdate(jobID)
+		jm.dispatch()
 	}
 
 	return nil
 }
 
-// CancelActiveJob cancels the currently active job
+// CancelActiveJob cancels the most recently started running job
 func (jm *JobManager) CancelActiveJob() error {
 	jm.mu.Lock()
 	active := jm.activeJob
@@ -371,16 +527,23 @@ func (jm *JobManager) CancelActiveJob() error {
 // GetJob returns a snapshot of a specific job
 func (jm *JobManager) GetJob(jobID string) (*JobSnapshot, error) {
 	jm.mu.Lock()
-	defer jm.mu.Unlock()
-
 	snapshot, exists := jm.jobs[jobID]
-	if !exists {
-		return nil, fmt.Errorf("job not found: %s", jobID)
+	if exists {
+		// Return a copy to prevent race conditions
+		copySnapshot := *snapshot
+		jm.mu.Unlock()
+		return &copySnapshot, nil
 	}
+	store := jm.history
+	jm.mu.Unlock()
 
-	// Return a copy to prevent race conditions
-	copySnapshot := *snapshot
-	return &copySnapshot, nil
+	// Jobs from earlier runs only live in the history
+	if store != nil {
+		if job, err := store.Get(jobID); err == nil {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job not found: %s", jobID)
 }
 
 // GetActiveJob returns the currently active job snapshot, or nil if none
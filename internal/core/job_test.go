@@ -351,3 +351,28 @@ func (e *testError) Error() string {
 	return e.msg
 }
 
+
+func TestJobManager_Phases(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	jobID, _, _ := jm.StartJob(context.Background(), "test", "Test job", nil)
+
+	phases := []JobPhase{
+		{Name: PhaseScanning, Current: 120, Total: 120, Unit: "files", Done: true},
+		{Name: PhaseCopying, Current: 30, Total: 120, Unit: "files"},
+	}
+	jm.UpdateProgress(jobID, JobProgress{Phase: PhaseCopying, Phases: phases, ETASeconds: 90}, "", nil)
+
+	snapshot, _ := jm.GetJob(jobID)
+	if len(snapshot.Progress.Phases) != 2 || snapshot.Progress.ETASeconds != 90 {
+		t.Fatalf("unexpected progress: %+v", snapshot.Progress)
+	}
+	if got := snapshot.Progress.Phases[0].Percent(); got != 100 {
+		t.Errorf("done phase at %.0f%%, want 100", got)
+	}
+	if got := snapshot.Progress.Phases[1].Percent(); got != 25 {
+		t.Errorf("copying at %.0f%%, want 25", got)
+	}
+	if got := (JobPhase{Name: PhaseScanning, Current: 40}).Percent(); got != 0 {
+		t.Errorf("a phase of unknown size is at %.0f%%, want 0", got)
+	}
+}
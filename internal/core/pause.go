@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PauseGate holds a job's work between units (e.g. files) while the job is paused.
+// The job asks for its gate with JobManager.PauseGate; only such jobs can be paused.
+type PauseGate struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{} // closed on resume; replaced on each pause
+}
+
+// Paused reports whether the job is currently paused
+func (g *PauseGate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// WaitIfPaused returns at once unless the job is paused, then blocks until it is resumed
+// or ctx ends (returning ctx's error)
+func (g *PauseGate) WaitIfPaused(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.paused {
+		g.mu.Unlock()
+		return nil
+	}
+	resume := g.resume
+	g.mu.Unlock()
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *PauseGate) set(paused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if paused == g.paused {
+		return
+	}
+	g.paused = paused
+	if paused {
+		g.resume = make(chan struct{})
+	} else {
+		close(g.resume)
+	}
+}
+
+// PauseGate returns the pause gate of a job, making the job pausable. The job's work
+// should call WaitIfPaused between units. Returns nil for unknown jobs.
+func (jm *JobManager) PauseGate(jobID string) *PauseGate {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if _, exists := jm.jobs[jobID]; !exists {
+		return nil
+	}
+	gate := jm.gates[jobID]
+	if gate == nil {
+		gate = &PauseGate{}
+		jm.gates[jobID] = gate
+	}
+	return gate
+}
+
+// PauseJob holds a running job between units; work in progress finishes first.
+// The job keeps its slot and resources while paused.
+func (jm *JobManager) PauseJob(jobID string) error {
+	return jm.setPaused(jobID, true)
+}
+
+// ResumeJob continues a paused job
+func (jm *JobManager) ResumeJob(jobID string) error {
+	return jm.setPaused(jobID, false)
+}
+
+func (jm *JobManager) setPaused(jobID string, paused bool) error {
+	from, to, message := JobRunning, JobPaused, "Paused"
+	if !paused {
+		from, to, message = JobPaused, JobRunning, "Resuming..."
+	}
+
+	jm.mu.Lock()
+	snapshot, exists := jm.jobs[jobID]
+	if !exists {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	gate := jm.gates[jobID]
+	if gate == nil {
+		jm.mu.Unlock()
+		return fmt.Errorf("job %s (%s) can't be paused", jobID, snapshot.Type)
+	}
+	if snapshot.State == to {
+		jm.mu.Unlock()
+		return nil
+	}
+	if snapshot.State != from {
+		jm.mu.Unlock()
+		return fmt.Errorf("job %s is %s", jobID, snapshot.State)
+	}
+	snapshot.State = to
+	snapshot.Message = message
+	snapshot.UpdatedAt = time.Now()
+	gate.set(paused)
+	jm.mu.Unlock()
+
+	jm.emitUpdate(jobID)
+	return nil
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job params that name the resources a job needs exclusively. Two jobs sharing a device
+// or a destination never run at the same time, whatever the concurrency limit.
+const (
+	ParamDevice     = "device"     // device serial/ID; falls back to the source path
+	ParamSourcePath = "sourcePath" // backup/verify source (cleanup uses "sourceRoot")
+	ParamDestPath   = "destPath"   // backup destination root (cleanup uses "destRoot")
+)
+
+// JobFunc is the body of a queued job, called once the job may run. Returning nil completes
+// the job and an error fails it, unless the function already did so (or the job was canceled).
+type JobFunc func(ctx context.Context, jobID string) error
+
+// queuedJob is a job waiting in JobManager.queue
+type queuedJob struct {
+	id  string
+	ctx context.Context
+	run JobFunc
+}
+
+// JobResources returns the resource classes a job with these params holds while running:
+// "device:<id>" for its source device and "dest:<path>" for its destination.
+func JobResources(params map[string]string) []string {
+	var resources []string
+	device := firstParam(params, ParamDevice, ParamSourcePath, "sourceRoot")
+	if device != "" {
+		resources = append(resources, "device:"+device)
+	}
+	if dest := firstParam(params, ParamDestPath, "destRoot"); dest != "" {
+		resources = append(resources, "dest:"+filepath.Clean(dest))
+	}
+	return resources
+}
+
+func firstParam(params map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := params[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SetMaxConcurrent sets how many jobs may run at once (default 1). n <= 0 lifts the limit,
+// leaving only the per-device and per-destination rules. Raising it starts queued jobs.
+func (jm *JobManager) SetMaxConcurrent(n int) {
+	if n < 0 {
+		n = 0
+	}
+	jm.mu.Lock()
+	jm.maxRunning = n
+	jm.mu.Unlock()
+	jm.dispatch()
+}
+
+// EnqueueJob adds a job that starts as soon as a slot is free and no running job holds its
+// device or destination; jobs waiting on the same resource start in submit order.
+// run is called in its own goroutine with a context that CancelJob cancels.
+func (jm *JobManager) EnqueueJob(ctx context.Context, jobType string, message string, params map[string]string, run JobFunc) (string, error) {
+	if run == nil {
+		return "", fmt.Errorf("job %s has nothing to run", jobType)
+	}
+	resources := JobResources(params)
+
+	jm.mu.Lock()
+	snapshot, jobCtx := jm.newJobLocked(ctx, jobType, message, params, resources)
+	snapshot.State = JobQueued
+	if err := jm.blockedLocked(resources, nil); err != nil {
+		snapshot.Message = "Queued: " + err.Error()
+	}
+	jm.queue = append(jm.queue, &queuedJob{id: snapshot.JobID, ctx: jobCtx, run: run})
+	jobID := snapshot.JobID
+	jm.mu.Unlock()
+
+	jm.emitUpdate(jobID)
+	jm.dispatch()
+	return jobID, nil
+}
+
+// RunningJobs returns the running jobs, newest first
+func (jm *JobManager) RunningJobs() []*JobSnapshot {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	list := make([]*JobSnapshot, 0, len(jm.running))
+	for id := range jm.running {
+		copy := *jm.jobs[id]
+		list = append(list, &copy)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// QueuedJobs returns the waiting jobs in the order they will be considered
+func (jm *JobManager) QueuedJobs() []*JobSnapshot {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	list := make([]*JobSnapshot, 0, len(jm.queue))
+	for _, q := range jm.queue {
+		copy := *jm.jobs[q.id]
+		list = append(list, &copy)
+	}
+	return list
+}
+
+// CancelAll cancels every running and queued job (e.g. on shutdown)
+func (jm *JobManager) CancelAll() {
+	jm.mu.Lock()
+	var ids []string
+	for id := range jm.running {
+		ids = append(ids, id)
+	}
+	for _, q := range jm.queue {
+		ids = append(ids, q.id)
+	}
+	jm.mu.Unlock()
+
+	for _, id := range ids {
+		jm.CancelJob(id)
+	}
+}
+
+// newJobLocked registers a new job snapshot. Must be called with jm.mu held.
+func (jm *JobManager) newJobLocked(ctx context.Context, jobType, message string, params map[string]string, resources []string) (*JobSnapshot, context.Context) {
+	jobID := fmt.Sprintf("%s-%d", jobType, time.Now().UnixNano())
+	for jm.jobs[jobID] != nil {
+		// Two jobs of one type submitted within the clock's resolution
+		jobID = fmt.Sprintf("%s-%d", jobType, time.Now().UnixNano()+1)
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	snapshot := &JobSnapshot{
+		JobID:     jobID,
+		Type:      jobType,
+		State:     JobRunning,
+		Params:    params,
+		Message:   message,
+		Resources: resources,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Progress: JobProgress{
+			Phase: "starting",
+		},
+	}
+
+	jm.jobs[jobID] = snapshot
+	jm.cancels[jobID] = cancel
+	return snapshot, jobCtx
+}
+
+// startLocked marks a job as running and claims its resources. Must be called with jm.mu held.
+func (jm *JobManager) startLocked(snapshot *JobSnapshot) {
+	snapshot.State = JobRunning
+	snapshot.UpdatedAt = time.Now()
+	jm.running[snapshot.JobID] = snapshot.Resources
+	jm.activeJob = snapshot.JobID
+}
+
+// releaseLocked frees a finished job's slot and resources. Must be called with jm.mu held.
+func (jm *JobManager) releaseLocked(jobID string) {
+	delete(jm.running, jobID)
+	delete(jm.gates, jobID)
+	delete(jm.skippers, jobID)
+	if jm.activeJob != jobID {
+		return
+	}
+	jm.activeJob = ""
+	for id := range jm.running {
+		if jm.activeJob == "" || jm.jobs[id].CreatedAt.After(jm.jobs[jm.activeJob].CreatedAt) {
+			jm.activeJob = id
+		}
+	}
+}
+
+// dequeueLocked removes a job from the queue, if it is there. Must be called with jm.mu held.
+func (jm *JobManager) dequeueLocked(jobID string) {
+	for i, q := range jm.queue {
+		if q.id == jobID {
+			jm.queue = append(jm.queue[:i], jm.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// blockedLocked reports why a job needing resources can't start now, or nil if it can.
+// reserved holds resources claimed by jobs ahead of it in the queue.
+// Must be called with jm.mu held.
+func (jm *JobManager) blockedLocked(resources []string, reserved map[string]bool) error {
+	if jm.maxRunning > 0 && len(jm.running) >= jm.maxRunning {
+		if jm.maxRunning == 1 {
+			for id := range jm.running {
+				return fmt.Errorf("a job is already running: %s (%s)", id, jm.jobs[id].Type)
+			}
+		}
+		return fmt.Errorf("%d jobs are already running", len(jm.running))
+	}
+	for _, r := range resources {
+		for id, held := range jm.running {
+			for _, h := range held {
+				if h == r {
+					return fmt.Errorf("%s is in use by %s", strings.SplitN(r, ":", 2)[1], id)
+				}
+			}
+		}
+		if reserved[r] {
+			return fmt.Errorf("%s is reserved by an earlier queued job", strings.SplitN(r, ":", 2)[1])
+		}
+	}
+	return nil
+}
+
+// dispatch starts every queued job that may run now
+func (jm *JobManager) dispatch() {
+	jm.mu.Lock()
+	var started []*queuedJob
+	reserved := make(map[string]bool)
+	remaining := jm.queue[:0]
+	for _, q := range jm.queue {
+		snapshot := jm.jobs[q.id]
+		if jm.blockedLocked(snapshot.Resources, reserved) != nil {
+			// Keep its place: later jobs may not overtake it on the same resources
+			for _, r := range snapshot.Resources {
+				reserved[r] = true
+			}
+			remaining = append(remaining, q)
+			continue
+		}
+		jm.startLocked(snapshot)
+		snapshot.Message = "Starting..."
+		started = append(started, q)
+	}
+	jm.queue = remaining
+	jm.mu.Unlock()
+
+	for _, q := range started {
+		jm.emitUpdate(q.id)
+		go jm.execute(q)
+	}
+}
+
+// execute runs a dequeued job and settles its state if the job function didn't
+func (jm *JobManager) execute(q *queuedJob) {
+	err := q.run(q.ctx, q.id)
+
+	jm.mu.Lock()
+	done := jm.jobs[q.id].finished()
+	jm.mu.Unlock()
+	if done {
+		return
+	}
+	if err != nil {
+		jm.FailJob(q.id, err, "")
+	} else {
+		jm.CompleteJob(q.id, "")
+	}
+}
+
+// finished reports whether the job reached a terminal state
+func (s *JobSnapshot) finished() bool {
+	return s.State == JobSucceeded || s.State == JobFailed || s.State == JobCanceled
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitState polls until the job reaches want or the deadline passes
+func waitState(t *testing.T, jm *JobManager, jobID string, want JobState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := jm.GetJob(jobID)
+		if err == nil && job.State == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: expected state %s, got %+v (err=%v)", jobID, want, job, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// blockingJob returns a JobFunc that runs until release is closed
+func blockingJob(started chan<- string, release <-chan struct{}) JobFunc {
+	return func(ctx context.Context, jobID string) error {
+		started <- jobID
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestJobManager_QueueRunsInOrder(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	ctx := context.Background()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+
+	first, _ := jm.EnqueueJob(ctx, "copy.sync", "", map[string]string{"sourcePath": "/phone1"}, blockingJob(started, release))
+	second, _ := jm.EnqueueJob(ctx, "copy.sync", "", map[string]string{"sourcePath": "/phone2"}, func(ctx context.Context, jobID string) error {
+		started <- jobID
+		return errors.New("boom")
+	})
+
+	if got := <-started; got != first {
+		t.Fatalf("expected %s to start first, got %s", first, got)
+	}
+	// One slot by default: the second job waits even though it uses another device
+	if job, _ := jm.GetJob(second); job.State != JobQueued {
+		t.Fatalf("expected second job queued, got %s", job.State)
+	}
+	if q := jm.QueuedJobs(); len(q) != 1 || q[0].JobID != second {
+		t.Errorf("expected only the second job queued, got %v", q)
+	}
+
+	close(release)
+	waitState(t, jm, first, JobSucceeded)
+	if got := <-started; got != second {
+		t.Fatalf("expected %s to start next, got %s", second, got)
+	}
+	// A returned error fails the job
+	waitState(t, jm, second, JobFailed)
+}
+
+func TestJobManager_ConcurrentByResource(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	jm.SetMaxConcurrent(0)
+	ctx := context.Background()
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	defer close(release)
+
+	a, _ := jm.EnqueueJob(ctx, "copy.sync", "", map[string]string{"sourcePath": "/phone1", "destPath": "/backup/a"}, blockingJob(started, release))
+	b, _ := jm.EnqueueJob(ctx, "verify.backup", "", map[string]string{"sourcePath": "/phone2", "destPath": "/backup/b"}, blockingJob(started, release))
+	// Same destination as a (after cleaning the path): must wait
+	c, _ := jm.EnqueueJob(ctx, "copy.sync", "", map[string]string{"sourcePath": "/phone3", "destPath": "/backup/a/"}, blockingJob(started, release))
+
+	<-started
+	<-started
+	waitState(t, jm, a, JobRunning)
+	waitState(t, jm, b, JobRunning)
+	if job, _ := jm.GetJob(c); job.State != JobQueued {
+		t.Errorf("expected job on a busy destination to be queued, got %s", job.State)
+	}
+	if n := len(jm.RunningJobs()); n != 2 {
+		t.Errorf("expected 2 running jobs, got %d", n)
+	}
+
+	// StartJob refuses rather than queues
+	if _, _, err := jm.StartJob(ctx, "cleanup.sync", "", map[string]string{"sourceRoot": "/phone2"}); err == nil {
+		t.Error("expected StartJob on a busy device to fail")
+	}
+
+	jm.CancelJob(a)
+	waitState(t, jm, c, JobRunning)
+}
+
+func TestJobManager_CancelQueuedJob(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	ctx := context.Background()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+
+	first, _ := jm.EnqueueJob(ctx, "copy.sync", "", nil, blockingJob(started, release))
+	queued, _ := jm.EnqueueJob(ctx, "copy.sync", "", nil, blockingJob(started, release))
+	<-started
+
+	if err := jm.CancelJob(queued); err != nil {
+		t.Fatalf("CancelJob on queued job failed: %v", err)
+	}
+	waitState(t, jm, queued, JobCanceled)
+	if q := jm.QueuedJobs(); len(q) != 0 {
+		t.Errorf("expected empty queue, got %v", q)
+	}
+
+	close(release)
+	waitState(t, jm, first, JobSucceeded)
+	select {
+	case id := <-started:
+		t.Errorf("canceled job %s was started", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestJobManager_TerminalStateIsFinal(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	jobID, _, _ := jm.StartJob(context.Background(), "test", "", nil)
+
+	jm.FailJob(jobID, errors.New("boom"), "")
+	jm.CompleteJob(jobID, "late completion")
+
+	job, _ := jm.GetJob(jobID)
+	if job.State != JobFailed {
+		t.Errorf("expected failed job to stay failed, got %s", job.State)
+	}
+}
+
+func TestJobResources(t *testing.T) {
+	got := JobResources(map[string]string{"device": "ABC123", "sourcePath": "/sdcard", "destPath": "/backup/"})
+	if len(got) != 2 || got[0] != "device:ABC123" || got[1] != "dest:/backup" {
+		t.Errorf("unexpected resources: %v", got)
+	}
+	if got := JobResources(nil); len(got) != 0 {
+		t.Errorf("expected no resources, got %v", got)
+	}
+}
+
+func TestJobManager_PauseResume(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	jobID, ctx, _ := jm.StartJob(context.Background(), "copy.sync", "", nil)
+
+	if err := jm.PauseJob(jobID); err == nil {
+		t.Fatal("expected a job without a pause gate to refuse pausing")
+	}
+	gate := jm.PauseGate(jobID)
+	if err := jm.PauseJob(jobID); err != nil {
+		t.Fatalf("PauseJob failed: %v", err)
+	}
+	if job, _ := jm.GetJob(jobID); job.State != JobPaused {
+		t.Errorf("expected paused state, got %s", job.State)
+	}
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- gate.WaitIfPaused(ctx) }()
+	select {
+	case <-resumed:
+		t.Fatal("WaitIfPaused returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := jm.ResumeJob(jobID); err != nil {
+		t.Fatalf("ResumeJob failed: %v", err)
+	}
+	if err := <-resumed; err != nil {
+		t.Errorf("expected WaitIfPaused to return nil on resume, got %v", err)
+	}
+	if job, _ := jm.GetJob(jobID); job.State != JobRunning {
+		t.Errorf("expected running state, got %s", job.State)
+	}
+
+	// Canceling a paused job releases its waiters
+	jm.PauseJob(jobID)
+	go func() { resumed <- gate.WaitIfPaused(ctx) }()
+	jm.CancelJob(jobID)
+	if err := <-resumed; err == nil {
+		t.Error("expected WaitIfPaused to fail after cancel")
+	}
+}
+
+func TestJobManager_SkipFile(t *testing.T) {
+	jm := NewJobManager(NewMockEmitter())
+	jobID, _, _ := jm.StartJob(context.Background(), "copy.sync", "", nil)
+
+	if _, err := jm.SkipFile(jobID, 0); err == nil {
+		t.Fatal("expected a job without a skipper to refuse skipping")
+	}
+	var skipped []int
+	jm.SetSkipper(jobID, func(worker int) (string, error) {
+		skipped = append(skipped, worker)
+		return "/sdcard/DCIM/big.mp4", nil
+	})
+	if path, err := jm.SkipFile(jobID, 2); err != nil || path != "/sdcard/DCIM/big.mp4" {
+		t.Fatalf("SkipFile = %q, %v", path, err)
+	}
+	if len(skipped) != 1 || skipped[0] != 2 {
+		t.Errorf("skipper called with %v, want worker 2", skipped)
+	}
+	if _, err := jm.SkipFile("nope", 0); err == nil {
+		t.Error("expected an unknown job to fail")
+	}
+
+	// The control goes away with the job
+	jm.CompleteJob(jobID, "done")
+	if _, err := jm.SkipFile(jobID, 2); err == nil {
+		t.Error("expected a finished job to refuse skipping")
+	}
+}
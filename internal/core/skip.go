@@ -0,0 +1,36 @@
+package core
+
+import "fmt"
+
+// SkipFunc abandons the file a worker of a job is working on and returns its path
+type SkipFunc func(worker int) (string, error)
+
+// SetSkipper lets a running job's current files be skipped (SkipFile). The control is
+// dropped when the job ends; jobs that aren't running are ignored.
+func (jm *JobManager) SetSkipper(jobID string, skip SkipFunc) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if _, running := jm.running[jobID]; !running {
+		return
+	}
+	jm.skippers[jobID] = skip
+}
+
+// SkipFile tells a worker of a running job to give up on its current file and move on;
+// the job itself continues. Returns the skipped file's path.
+func (jm *JobManager) SkipFile(jobID string, worker int) (string, error) {
+	jm.mu.Lock()
+	snapshot, exists := jm.jobs[jobID]
+	if !exists {
+		jm.mu.Unlock()
+		return "", fmt.Errorf("job not found: %s", jobID)
+	}
+	skip := jm.skippers[jobID]
+	if skip == nil {
+		jm.mu.Unlock()
+		return "", fmt.Errorf("job %s (%s) can't skip files", jobID, snapshot.Type)
+	}
+	jm.mu.Unlock()
+
+	return skip(worker)
+}
@@ -0,0 +1,244 @@
+// Package crash captures panics from the engine and GUI backend into local
+// crash reports and, only when the user opts in, uploads them to an endpoint.
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DataPolicy describes exactly what a crash report contains. It is shown next to the settings toggle.
+const DataPolicy = `Crash reports are always written locally to ~/.gussync/crashes and never leave
+this machine unless you enable upload. A report contains: the panic message and
+Go stack trace, the component that crashed (engine, scanner, copy service...),
+OS/architecture, Go and GusSync versions, and run settings such as mode and
+worker count. Your home directory, user name and device serial numbers are
+replaced with placeholders before anything is written. File contents, hashes and
+the backup state are never included. Clearing crash reports deletes all local files.`
+
+// Report is a single captured panic
+type Report struct {
+	ID         string            `json:"id"`
+	Time       time.Time         `json:"time"`
+	Component  string            `json:"component"`
+	Panic      string            `json:"panic"`
+	Stack      string            `json:"stack"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	GoVersion  string            `json:"goVersion"`
+	AppVersion string            `json:"appVersion,omitempty"`
+	Context    map[string]string `json:"context,omitempty"`
+}
+
+// Options configures the crash handler
+type Options struct {
+	Dir           string // Where reports are written (default ~/.gussync/crashes)
+	AppVersion    string
+	UploadEnabled bool   // Opt-in: send reports to Endpoint
+	Endpoint      string // HTTP endpoint that accepts a JSON Report via POST
+}
+
+// Handler records panics
+type Handler struct {
+	mu      sync.Mutex
+	opts    Options
+	context map[string]string
+}
+
+var (
+	defaultMu      sync.Mutex
+	defaultHandler *Handler
+)
+
+// DefaultDir returns ~/.gussync/crashes
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "gussync-crashes")
+	}
+	return filepath.Join(home, ".gussync", "crashes")
+}
+
+// New creates a crash handler
+func New(opts Options) *Handler {
+	if opts.Dir == "" {
+		opts.Dir = DefaultDir()
+	}
+	return &Handler{opts: opts, context: make(map[string]string)}
+}
+
+// Install creates a handler and makes it the process-wide default used by Recover and Capture
+func Install(opts Options) *Handler {
+	h := New(opts)
+	defaultMu.Lock()
+	defaultHandler = h
+	defaultMu.Unlock()
+	return h
+}
+
+// Default returns the installed handler (nil if Install was never called)
+func Default() *Handler {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultHandler
+}
+
+// Recover is meant to be deferred at the top of goroutines: it records a panic
+// with the default handler and re-panics so the crash is not silently swallowed.
+func Recover(component string) {
+	if r := recover(); r != nil {
+		Capture(component, r, debug.Stack())
+		panic(r)
+	}
+}
+
+// Capture records a recovered panic with the default handler. It matches the engine's PanicHandler signature.
+func Capture(component string, recovered interface{}, stack []byte) {
+	if h := Default(); h != nil {
+		if _, err := h.Capture(component, recovered, stack); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write crash report: %v\n", err)
+		}
+	}
+}
+
+// SetContext attaches a run setting (e.g. mode, workers) to future reports
+func (h *Handler) SetContext(key, value string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.context[key] = value
+}
+
+// SetUpload changes the opt-in upload setting at runtime
+func (h *Handler) SetUpload(enabled bool, endpoint string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.opts.UploadEnabled = enabled
+	h.opts.Endpoint = endpoint
+}
+
+// Dir returns the directory reports are written to
+func (h *Handler) Dir() string {
+	return h.opts.Dir
+}
+
+// Capture writes a report for a recovered panic and uploads it if enabled.
+// Returns the path of the local report.
+func (h *Handler) Capture(component string, recovered interface{}, stack []byte) (string, error) {
+	h.mu.Lock()
+	ctxCopy := make(map[string]string, len(h.context))
+	for k, v := range h.context {
+		ctxCopy[k] = Anonymize(v)
+	}
+	opts := h.opts
+	h.mu.Unlock()
+
+	now := time.Now()
+	report := Report{
+		ID:         fmt.Sprintf("%s-%s", now.Format("20060102-150405"), component),
+		Time:       now,
+		Component:  component,
+		Panic:      Anonymize(fmt.Sprint(recovered)),
+		Stack:      Anonymize(string(stack)),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		GoVersion:  runtime.Version(),
+		AppVersion: opts.AppVersion,
+		Context:    ctxCopy,
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create crash dir: %w", err)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal crash report: %w", err)
+	}
+	path := filepath.Join(opts.Dir, "crash-"+report.ID+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+
+	if opts.UploadEnabled && opts.Endpoint != "" {
+		if err := upload(opts.Endpoint, data); err != nil {
+			fmt.Fprintf(os.Stderr, "crash report upload failed (kept locally at %s): %v\n", path, err)
+		}
+	}
+
+	return path, nil
+}
+
+// Reports lists local crash report files, newest first
+func (h *Handler) Reports() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(h.opts.Dir, "crash-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+// ClearReports deletes all local crash reports and returns how many were removed
+func (h *Handler) ClearReports() (int, error) {
+	reports, err := h.Reports()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, path := range reports {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func upload(endpoint string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+var (
+	mtpHostPattern = regexp.MustCompile(`(mtp|gphoto2):host=[^/\s]+`)
+	serialPattern  = regexp.MustCompile(`\b(serial|device)=\S+`)
+)
+
+// Anonymize strips personal identifiers (home dir, user name, device IDs) from text
+func Anonymize(s string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		s = strings.ReplaceAll(s, home, "~")
+	}
+	if u, err := user.Current(); err == nil && len(u.Username) > 2 {
+		s = strings.ReplaceAll(s, u.Username, "<user>")
+	}
+	s = mtpHostPattern.ReplaceAllString(s, "$1:host=<device>")
+	s = serialPattern.ReplaceAllString(s, "$1=<id>")
+	return s
+}
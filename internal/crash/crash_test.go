@@ -0,0 +1,77 @@
+package crash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	in := filepath.Join(home, "Backups") + " /run/user/1000/gvfs/mtp:host=SAMSUNG_SM-G991B_R5CR1234/DCIM serial=R5CR1234"
+	out := Anonymize(in)
+
+	if home != "/" && strings.Contains(out, home+"/") {
+		t.Errorf("home dir not removed: %s", out)
+	}
+	if strings.Contains(out, "R5CR1234") {
+		t.Errorf("device id not removed: %s", out)
+	}
+	if !strings.Contains(out, "mtp:host=<device>/DCIM") {
+		t.Errorf("expected device placeholder, got %s", out)
+	}
+}
+
+func TestCaptureWritesLocalReport(t *testing.T) {
+	h := New(Options{Dir: t.TempDir(), AppVersion: "test"})
+	h.SetContext("mode", "mount")
+
+	path, err := h.Capture("worker", "boom", []byte("goroutine 1 [running]:"))
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if r.Component != "worker" || r.Panic != "boom" || r.Context["mode"] != "mount" {
+		t.Errorf("unexpected report: %+v", r)
+	}
+
+	reports, _ := h.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+	if n, err := h.ClearReports(); err != nil || n != 1 {
+		t.Errorf("ClearReports = %d, %v", n, err)
+	}
+}
+
+func TestStartupGuard(t *testing.T) {
+	t.Setenv("GUSSYNC_SAFE_MODE", "")
+	g := NewStartupGuard(filepath.Join(t.TempDir(), "startup.count"), 2)
+
+	// Two crashed startups in a row, third enters safe mode
+	for i := 0; i < 2; i++ {
+		if safe, _, err := g.Begin(); err != nil || safe {
+			t.Fatalf("startup %d: safe=%v err=%v", i+1, safe, err)
+		}
+	}
+	safe, unstable, _ := g.Begin()
+	if !safe || unstable != 2 {
+		t.Errorf("expected safe mode after 2 unstable startups, got safe=%v unstable=%d", safe, unstable)
+	}
+
+	// A stable run resets the counter
+	g.MarkStable()
+	if safe, unstable, _ := g.Begin(); safe || unstable != 0 {
+		t.Errorf("expected normal startup after MarkStable, got safe=%v unstable=%d", safe, unstable)
+	}
+}
@@ -0,0 +1,72 @@
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSafeModeThreshold is how many unfinished startups in a row trigger safe mode
+	DefaultSafeModeThreshold = 3
+)
+
+// StartupGuard detects crash loops: every startup increments a counter file and
+// MarkStable resets it once the process has been running normally. If the counter
+// reaches the threshold, the previous startups all crashed before becoming stable.
+type StartupGuard struct {
+	path      string
+	threshold int
+}
+
+// NewStartupGuard creates a guard backed by the counter file at path
+func NewStartupGuard(path string, threshold int) *StartupGuard {
+	if threshold <= 0 {
+		threshold = DefaultSafeModeThreshold
+	}
+	return &StartupGuard{path: path, threshold: threshold}
+}
+
+// DefaultStartupGuard returns a guard for the given component (e.g. "gui", "daemon")
+// stored under ~/.gussync
+func DefaultStartupGuard(component string) *StartupGuard {
+	return NewStartupGuard(filepath.Join(filepath.Dir(DefaultDir()), "startup_"+component+".count"), DefaultSafeModeThreshold)
+}
+
+// Begin records a startup attempt. It returns whether to start in safe mode and
+// how many previous startups did not become stable. GUSSYNC_SAFE_MODE=1 forces safe mode.
+func (g *StartupGuard) Begin() (safeMode bool, unstable int, err error) {
+	unstable = g.read()
+
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return false, unstable, fmt.Errorf("failed to create startup counter dir: %w", err)
+	}
+	if err := os.WriteFile(g.path, []byte(strconv.Itoa(unstable+1)), 0644); err != nil {
+		return false, unstable, fmt.Errorf("failed to write startup counter: %w", err)
+	}
+
+	safeMode = unstable >= g.threshold || os.Getenv("GUSSYNC_SAFE_MODE") == "1"
+	return safeMode, unstable, nil
+}
+
+// MarkStable resets the counter; call once startup completed and the process ran normally
+func (g *StartupGuard) MarkStable() error {
+	if err := os.Remove(g.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (g *StartupGuard) read() int {
+	data, err := os.ReadFile(g.path)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,371 @@
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is where the API listens when GUSSYNC_API_PORT=8090
+const DefaultBaseURL = "http://localhost:8090"
+
+// Client calls the GusSync HTTP API
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New creates a client for baseURL (e.g. "http://localhost:8090"); nil httpClient uses http.DefaultClient
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: httpClient}
+}
+
+// NewUnix creates a client for a server listening on a Unix socket, such as the control
+// socket of gussync daemon
+func NewUnix(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return New("http://gussync", &http.Client{Transport: transport})
+}
+
+// envelope is the {success, data, error} wrapper around every JSON response
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *Error          `json:"error"`
+}
+
+// do sends a request and decodes the envelope's data into out (if non-nil)
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("gussync api: %s %s: %d, undecodable response: %w", method, path, resp.StatusCode, err)
+	}
+	if !env.Success || resp.StatusCode >= 400 {
+		if env.Error == nil {
+			env.Error = &Error{Code: "unknown", Message: resp.Status}
+		}
+		env.Error.StatusCode = resp.StatusCode
+		return env.Error
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+func jobPath(jobID string, action string) string {
+	p := "/api/jobs/" + url.PathEscape(jobID)
+	if action != "" {
+		p += "/" + action
+	}
+	return p
+}
+
+// GetHealth checks that the server is up
+func (c *Client) GetHealth(ctx context.Context) (*Health, error) {
+	var h Health
+	if err := c.do(ctx, http.MethodGet, "/api/health", nil, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// ListJobs returns the jobs of this session and which are running or queued
+func (c *Client) ListJobs(ctx context.Context) (*JobList, error) {
+	var l JobList
+	if err := c.do(ctx, http.MethodGet, "/api/jobs", nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetActiveJob returns the most recently started running job, or nil when idle
+func (c *Client) GetActiveJob(ctx context.Context) (*Job, error) {
+	var j *Job
+	if err := c.do(ctx, http.MethodGet, "/api/jobs/active", nil, &j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// GetJob returns one job, including finished jobs from the history
+func (c *Client) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	var j Job
+	if err := c.do(ctx, http.MethodGet, jobPath(jobID, ""), nil, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// DeleteJob cancels a job (same as CancelJob)
+func (c *Client) DeleteJob(ctx context.Context, jobID string) error {
+	return c.do(ctx, http.MethodDelete, jobPath(jobID, ""), nil, nil)
+}
+
+// CancelJob cancels a running job or removes a queued one
+func (c *Client) CancelJob(ctx context.Context, jobID string) error {
+	return c.do(ctx, http.MethodPost, jobPath(jobID, "cancel"), nil, nil)
+}
+
+// PauseJob pauses a backup between files
+func (c *Client) PauseJob(ctx context.Context, jobID string) error {
+	return c.do(ctx, http.MethodPost, jobPath(jobID, "pause"), nil, nil)
+}
+
+// ResumeJob resumes a paused backup
+func (c *Client) ResumeJob(ctx context.Context, jobID string) error {
+	return c.do(ctx, http.MethodPost, jobPath(jobID, "resume"), nil, nil)
+}
+
+// SkipFile makes a worker of a running backup give up on its current file, which counts as
+// failed once; returns the file's source path
+func (c *Client) SkipFile(ctx context.Context, jobID string, worker int) (string, error) {
+	var r SkipFileResponse
+	if err := c.do(ctx, http.MethodPost, jobPath(jobID, "skip")+"?worker="+strconv.Itoa(worker), nil, &r); err != nil {
+		return "", err
+	}
+	return r.Path, nil
+}
+
+// ListHistory returns finished jobs, newest first; limit 0 returns all of them
+func (c *Client) ListHistory(ctx context.Context, offset, limit int) (*HistoryPage, error) {
+	q := url.Values{}
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(limit))
+	var p HistoryPage
+	if err := c.do(ctx, http.MethodGet, "/api/history?"+q.Encode(), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPrereqs returns the prerequisites report
+func (c *Client) GetPrereqs(ctx context.Context) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/prereqs", nil, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ListDevices returns the connected devices
+func (c *Client) ListDevices(ctx context.Context) (*Devices, error) {
+	var d Devices
+	if err := c.do(ctx, http.MethodGet, "/api/devices", nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetConfig returns the server's configuration
+func (c *Client) GetConfig(ctx context.Context) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/config", nil, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// StartCopy starts a backup, or queues it behind a running one
+func (c *Client) StartCopy(ctx context.Context, req StartCopyRequest) (*StartCopyResponse, error) {
+	var r StartCopyResponse
+	if err := c.do(ctx, http.MethodPost, "/api/copy/start", req, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RestoreFiles starts (or queues) a job writing backed-up files back to the device
+func (c *Client) RestoreFiles(ctx context.Context, req RestoreRequest) (*StartCopyResponse, error) {
+	var r StartCopyResponse
+	if err := c.do(ctx, http.MethodPost, "/api/restore", req, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListQuarantine returns files quarantined after failed verification
+func (c *Client) ListQuarantine(ctx context.Context) ([]QuarantineItem, error) {
+	var items []QuarantineItem
+	if err := c.do(ctx, http.MethodGet, "/api/quarantine", nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetErrorSummary returns the error summary of each backup mode at the configured destination
+func (c *Client) GetErrorSummary(ctx context.Context) ([]ErrorSummary, error) {
+	var summaries []ErrorSummary
+	if err := c.do(ctx, http.MethodGet, "/api/errors", nil, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// BrowseCatalog returns a page of the backed-up files of a folder, or of a search
+func (c *Client) BrowseCatalog(ctx context.Context, query CatalogQuery) (*CatalogPage, error) {
+	q := url.Values{}
+	for name, v := range map[string]string{"mode": query.Mode, "folder": query.Folder, "name": query.Name} {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	if query.Recursive {
+		q.Set("recursive", "true")
+	}
+	if len(query.Extensions) > 0 {
+		q.Set("ext", strings.Join(query.Extensions, ","))
+	}
+	for name, t := range map[string]time.Time{"after": query.After, "before": query.Before} {
+		if !t.IsZero() {
+			q.Set(name, t.Format(time.RFC3339))
+		}
+	}
+	q.Set("offset", strconv.Itoa(query.Offset))
+	q.Set("limit", strconv.Itoa(query.Limit))
+	var p CatalogPage
+	if err := c.do(ctx, http.MethodGet, "/api/catalog?"+q.Encode(), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetThumbnail returns the JPEG thumbnail of a backed-up file by content hash; a file without
+// one is an *Error with StatusCode 404
+func (c *Client) GetThumbnail(ctx context.Context, hash string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/thumbnails/"+url.PathEscape(hash), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var env envelope
+		if json.NewDecoder(resp.Body).Decode(&env) != nil || env.Error == nil {
+			env.Error = &Error{Code: "unexpected_status", Message: resp.Status}
+		}
+		env.Error.StatusCode = resp.StatusCode
+		return nil, env.Error
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// GetOpenAPI returns the server's OpenAPI document
+func (c *Client) GetOpenAPI(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/openapi.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{StatusCode: resp.StatusCode, Code: "unexpected_status", Message: resp.Status}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// StreamEvents reads /api/events until ctx is canceled or the server closes the stream,
+// calling fn for each event. A non-nil error from fn stops the stream and is returned.
+func (c *Client) StreamEvents(ctx context.Context, fn func(Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &Error{StatusCode: resp.StatusCode, Code: "unexpected_status", Message: resp.Status}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var name, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		case line == "" && name != "":
+			ev, err := parseEvent(name, data)
+			name, data = "", ""
+			if err != nil {
+				return err
+			}
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
+// parseEvent decodes an SSE data payload according to its event name
+func parseEvent(name, data string) (Event, error) {
+	ev := Event{Name: name}
+	if !strings.HasPrefix(name, "job:") {
+		var m struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal([]byte(data), &m)
+		ev.Message = m.Message
+		return ev, nil
+	}
+	ev.Job = &JobEvent{}
+	if err := json.Unmarshal([]byte(data), ev.Job); err != nil {
+		return ev, fmt.Errorf("gussync api: bad %s event: %w", name, err)
+	}
+	return ev, nil
+}
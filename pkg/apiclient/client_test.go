@@ -0,0 +1,237 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"GusSync/internal/adapters/api"
+	"GusSync/internal/core"
+)
+
+func newTestServer(t *testing.T, jm *core.JobManager, opts ...api.ServerOption) *Client {
+	t.Helper()
+	s := api.NewServer(0, log.New(io.Discard, "", 0), jm, opts...)
+	jm.AddEmitter(s)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return New(ts.URL, ts.Client())
+}
+
+// Every documented operation has a client method, except the ones that are not JSON APIs
+func TestClientCoversSpec(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(api.OpenAPISpec(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	skip := map[string]bool{"openWebSocket": true, "getDocs": true}
+	client := reflect.TypeOf(&Client{})
+	for path, item := range spec.Paths {
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			json.Unmarshal(raw, &op)
+			if op.OperationID == "" || skip[op.OperationID] {
+				continue
+			}
+			name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+			if _, ok := client.MethodByName(name); !ok {
+				t.Errorf("%s %s: no Client.%s", method, path, name)
+			}
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	var got api.StartCopyRequest
+	jm := core.NewJobManager(nil)
+	c := newTestServer(t, jm, api.WithStartCopyFunc(func(ctx context.Context, req api.StartCopyRequest) (string, error) {
+		got = req
+		jobID, _, err := jm.StartJob(ctx, "copy.sync", "", nil)
+		return jobID, err
+	}))
+	ctx := context.Background()
+
+	if h, err := c.GetHealth(ctx); err != nil || h.Status != "ok" {
+		t.Fatalf("GetHealth: %+v, %v", h, err)
+	}
+	if j, err := c.GetActiveJob(ctx); err != nil || j != nil {
+		t.Fatalf("expected no active job, got %+v, %v", j, err)
+	}
+
+	started, err := c.StartCopy(ctx, StartCopyRequest{DestinationPath: "/backup"})
+	if err != nil {
+		t.Fatalf("StartCopy: %v", err)
+	}
+	if got.DestinationPath != "/backup" {
+		t.Errorf("request body not passed through: %+v", got)
+	}
+	job, err := c.GetJob(ctx, started.JobID)
+	if err != nil || job.State != JobRunning {
+		t.Fatalf("GetJob: %+v, %v", job, err)
+	}
+	if l, err := c.ListJobs(ctx); err != nil || len(l.Running) != 1 || l.Running[0] != started.JobID {
+		t.Errorf("ListJobs: %+v, %v", l, err)
+	}
+
+	if err := c.CancelJob(ctx, started.JobID); err != nil {
+		t.Fatalf("CancelJob: %v", err)
+	}
+	if job, _ := c.GetJob(ctx, started.JobID); job.State != JobCanceled {
+		t.Errorf("expected canceled job, got %s", job.State)
+	}
+	if p, err := c.ListHistory(ctx, 0, 10); err != nil || p.Total != 1 {
+		t.Errorf("ListHistory: %+v, %v", p, err)
+	}
+
+	// API errors come back as *Error
+	var apiErr *Error
+	if _, err := c.GetJob(ctx, "nope"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 *Error, got %v", err)
+	}
+	if err := c.PauseJob(ctx, started.JobID); !errors.As(err, &apiErr) || apiErr.Code != "pause_failed" {
+		t.Errorf("expected pause_failed, got %v", err)
+	}
+	if _, err := c.SkipFile(ctx, started.JobID, 0); !errors.As(err, &apiErr) || apiErr.Code != "skip_failed" {
+		t.Errorf("expected skip_failed, got %v", err)
+	}
+	running, _, _ := jm.StartJob(ctx, "copy.sync", "", nil)
+	jm.SetSkipper(running, func(worker int) (string, error) { return fmt.Sprintf("/sdcard/w%d.mp4", worker), nil })
+	if path, err := c.SkipFile(ctx, running, 3); err != nil || path != "/sdcard/w3.mp4" {
+		t.Errorf("SkipFile = %q, %v", path, err)
+	}
+}
+
+func TestNewUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gus") // socket paths must stay short
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("no unix sockets: %v", err)
+	}
+	s := api.NewServer(0, log.New(io.Discard, "", 0), core.NewJobManager(nil))
+	go http.Serve(l, s.Handler())
+	defer l.Close()
+
+	if h, err := NewUnix(socket).GetHealth(context.Background()); err != nil || h.Status != "ok" {
+		t.Fatalf("GetHealth: %+v, %v", h, err)
+	}
+}
+
+func TestClientBrowseCatalog(t *testing.T) {
+	var got api.CatalogRequest
+	c := newTestServer(t, core.NewJobManager(nil), api.WithCatalogProvider(func(req api.CatalogRequest) (interface{}, error) {
+		got = req
+		return CatalogPage{Mode: "adb", Folder: req.Folder, Files: []CatalogEntry{{Name: "IMG_1.jpg", Path: "DCIM/IMG_1.jpg", Size: 42}}, Total: 1}, nil
+	}))
+	after := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	page, err := c.BrowseCatalog(context.Background(), CatalogQuery{Folder: "DCIM", Name: "img", Extensions: []string{"jpg", "mp4"}, After: after, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Files[0].Size != 42 || page.Folder != "DCIM" {
+		t.Errorf("page = %+v", page)
+	}
+	want := api.CatalogRequest{Folder: "DCIM", Name: "img", Extensions: []string{"jpg", "mp4"}, After: after, Limit: 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+
+	var apiErr *Error
+	if err := c.do(context.Background(), http.MethodGet, "/api/catalog?after=yesterday", nil, nil); !errors.As(err, &apiErr) || apiErr.Code != "invalid_query" {
+		t.Errorf("expected invalid_query, got %v", err)
+	}
+}
+
+func TestClientGetThumbnail(t *testing.T) {
+	thumb := filepath.Join(t.TempDir(), "ab12.jpg")
+	os.WriteFile(thumb, []byte("jpeg"), 0644)
+	c := newTestServer(t, core.NewJobManager(nil), api.WithThumbnailProvider(func(hash string) (string, error) {
+		if hash != "ab12" {
+			return "", os.ErrNotExist
+		}
+		return thumb, nil
+	}))
+
+	if data, err := c.GetThumbnail(context.Background(), "ab12"); err != nil || string(data) != "jpeg" {
+		t.Errorf("GetThumbnail = %q, %v", data, err)
+	}
+	var apiErr *Error
+	if _, err := c.GetThumbnail(context.Background(), "cd34"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 *Error, got %v", err)
+	}
+	if _, err := c.GetThumbnail(context.Background(), "../secret"); !errors.As(err, &apiErr) || apiErr.Code != "invalid_hash" {
+		t.Errorf("expected invalid_hash, got %v", err)
+	}
+}
+
+func TestClientRestoreFiles(t *testing.T) {
+	var got api.RestoreRequest
+	jm := core.NewJobManager(nil)
+	c := newTestServer(t, jm, api.WithRestoreFunc(func(ctx context.Context, req api.RestoreRequest) (string, error) {
+		got = req
+		jobID, _, err := jm.StartJob(ctx, "restore.files", "", nil)
+		return jobID, err
+	}))
+	ctx := context.Background()
+
+	started, err := c.RestoreFiles(ctx, RestoreRequest{Paths: []string{"DCIM/Camera/IMG_1234.jpg"}, Overwrite: true})
+	if err != nil || started.JobID == "" {
+		t.Fatalf("RestoreFiles: %+v, %v", started, err)
+	}
+	if len(got.Paths) != 1 || got.Paths[0] != "DCIM/Camera/IMG_1234.jpg" || !got.Overwrite {
+		t.Errorf("request body not passed through: %+v", got)
+	}
+	var apiErr *Error
+	if _, err := c.RestoreFiles(ctx, RestoreRequest{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a 400 without paths, got %v", err)
+	}
+}
+
+func TestClientStreamEvents(t *testing.T) {
+	jm := core.NewJobManager(nil)
+	c := newTestServer(t, jm)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var jobID string
+	done := errors.New("done")
+	err := c.StreamEvents(ctx, func(ev Event) error {
+		switch ev.Name {
+		case "connected":
+			jobID, _, _ = jm.StartJob(context.Background(), "copy.sync", "", nil)
+			jm.CompleteJob(jobID, "finished")
+		case "job:completed":
+			if ev.Job.JobID != jobID || ev.Job.State != JobSucceeded {
+				t.Errorf("unexpected completion event: %+v", ev.Job)
+			}
+			return done
+		}
+		return nil
+	})
+	if err != done {
+		t.Errorf("expected the stream to end at job:completed, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package apiclient is a typed Go client for the GusSync HTTP API (GUSSYNC_API_PORT).
+// It follows the OpenAPI document served at /api/openapi.json; each exported method
+// corresponds to one operationId there.
+package apiclient
+
+import (
+	"fmt"
+	"time"
+)
+
+// JobState is the lifecycle state of a job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobPaused    JobState = "paused"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// Finished reports whether the state is terminal
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// JobProgress is a job's progress counters
+type JobProgress struct {
+	Phase   string  `json:"phase"`
+	Current int64   `json:"current"`
+	Total   int64   `json:"total"`
+	Percent float64 `json:"percent"`
+	Rate    float64 `json:"rate"` // MB/s
+
+	Phases     []JobPhase `json:"phases,omitempty"`     // each phase's totals, in order; Phase is the one in progress
+	ETASeconds int64      `json:"etaSeconds,omitempty"` // estimated time left (0 = unknown)
+}
+
+// JobPhase is the progress of one phase of a job ("scanning", "copying", "hashing",
+// "verifying", "finalizing")
+type JobPhase struct {
+	Name    string `json:"name"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`          // 0 = not known yet
+	Unit    string `json:"unit,omitempty"` // "files" or "bytes"
+	Done    bool   `json:"done"`
+}
+
+// JobError describes why a job failed
+type JobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details"`
+}
+
+// JobArtifact points at a job's log and report
+type JobArtifact struct {
+	LogPath     string `json:"logPath"`
+	OpenLogHint string `json:"openLogHint"`
+	ReportPath  string `json:"reportPath,omitempty"`
+}
+
+// Job is the JobSnapshot schema
+type Job struct {
+	JobID      string            `json:"jobId"`
+	Seq        int64             `json:"seq"`
+	Type       string            `json:"type"`
+	State      JobState          `json:"state"`
+	Params     map[string]string `json:"params,omitempty"`
+	Progress   JobProgress       `json:"progress"`
+	Message    string            `json:"message"`
+	Workers    map[string]string `json:"workers,omitempty"`
+	Error      *JobError         `json:"error,omitempty"`
+	Artifact   JobArtifact       `json:"artifact"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+	Resources  []string          `json:"resources,omitempty"`
+	Stats      map[string]int64  `json:"stats,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	DurationMs int64             `json:"durationMs,omitempty"`
+}
+
+// JobList is the response of ListJobs
+type JobList struct {
+	Jobs      []Job    `json:"jobs"`
+	ActiveJob string   `json:"activeJob,omitempty"`
+	Running   []string `json:"running"`
+	Queued    []string `json:"queued"`
+}
+
+// SkipFileResponse is the response of SkipFile
+type SkipFileResponse struct {
+	Message string `json:"message"`
+	Path    string `json:"path"` // source path of the skipped file
+}
+
+// HistoryPage is one page of finished jobs, newest first
+type HistoryPage struct {
+	Jobs   []Job `json:"jobs"`
+	Total  int   `json:"total"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+}
+
+// Health is the response of GetHealth
+type Health struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+}
+
+// StartCopyRequest is the body of StartCopy; empty fields use the server's configuration
+type StartCopyRequest struct {
+	SourcePath      string `json:"sourcePath,omitempty"`
+	DestinationPath string `json:"destinationPath,omitempty"`
+	WorkerCount     int    `json:"workerCount,omitempty"`
+	Profile         string `json:"profile,omitempty"` // saved profile to back up, for gussync daemon
+}
+
+// StartCopyResponse is the response of StartCopy
+type StartCopyResponse struct {
+	JobID   string `json:"jobId"`
+	Message string `json:"message"`
+}
+
+// RestoreRequest is the body of RestoreFiles
+type RestoreRequest struct {
+	Mode      string   `json:"mode,omitempty"` // "mount" or "adb" ("" = the first backup found)
+	Paths     []string `json:"paths"`          // files or folders as listed by BrowseCatalog
+	Overwrite bool     `json:"overwrite,omitempty"`
+	DryRun    bool     `json:"dryRun,omitempty"`
+}
+
+// Device is one connected device
+type Device struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"` // "mtp", "adb", "gphoto2"
+	Path      string `json:"path"`
+	Connected bool   `json:"connected"`
+}
+
+// Devices is the response of ListDevices
+type Devices struct {
+	Devices   []Device `json:"devices"`
+	Connected bool     `json:"connected"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// QuarantineItem is a destination copy that failed verification
+type QuarantineItem struct {
+	Mode       string    `json:"mode"`
+	SourcePath string    `json:"sourcePath"`
+	Path       string    `json:"path"`
+	Reason     string    `json:"reason"`
+	At         time.Time `json:"at"`
+	FullPath   string    `json:"fullPath"`
+}
+
+// ErrorSummary is the error summary of one mode's backup
+type ErrorSummary struct {
+	Mode              string         `json:"mode"`
+	TotalErrors       int            `json:"totalErrors"`
+	CriticalErrors    int            `json:"criticalErrors"`
+	DirectoryTimeouts int            `json:"directoryTimeouts"`
+	DirectoryErrors   int            `json:"directoryErrors"`
+	HashMismatches    int            `json:"hashMismatches"`
+	CopyErrors        int            `json:"copyErrors"`
+	OtherErrors       int            `json:"otherErrors"`
+	TimeoutDirs       []string       `json:"timeoutDirs,omitempty"`
+	ErrorDirs         []string       `json:"errorDirs,omitempty"`
+	Codes             map[string]int `json:"codes,omitempty"` // error code -> count
+}
+
+// CatalogQuery selects a page of a backup's catalog
+type CatalogQuery struct {
+	Mode       string // "mount" or "adb" ("" = the first backup found)
+	Folder     string // e.g. "DCIM/Camera" ("" = the top of the backup)
+	Recursive  bool
+	Name       string   // part of the file name; searching covers all subfolders
+	Extensions []string // e.g. "jpg", "mp4"
+	After      time.Time
+	Before     time.Time
+	Offset     int
+	Limit      int // 0 = the server's default
+}
+
+// CatalogEntry is one backed-up file
+type CatalogEntry struct {
+	Name              string    `json:"name"`
+	Path              string    `json:"path"`
+	SourcePath        string    `json:"sourcePath"`
+	Hash              string    `json:"hash,omitempty"`
+	Size              int64     `json:"size,omitempty"` // 0 if unknown
+	Volume            string    `json:"volume,omitempty"`
+	BackedUpAt        time.Time `json:"backedUpAt"`
+	VerifiedAt        time.Time `json:"verifiedAt"`
+	DeletedFromSource bool      `json:"deletedFromSource,omitempty"`
+}
+
+// CatalogFolder is a subfolder of the listed folder
+type CatalogFolder struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+}
+
+// CatalogPage is the response of BrowseCatalog
+type CatalogPage struct {
+	Mode    string          `json:"mode"`
+	Modes   []string        `json:"modes"`
+	ModeDir string          `json:"modeDir"` // paths are relative to it
+	Folder  string          `json:"folder"`
+	Folders []CatalogFolder `json:"folders"`
+	Files   []CatalogEntry  `json:"files"`
+	Total   int             `json:"total"`
+	Offset  int             `json:"offset"`
+	Limit   int             `json:"limit"`
+}
+
+// Event is one server-sent event; Job is set for job:* events
+type Event struct {
+	Name    string // connected, job:snapshot, job:update, job:completed, job:failed, job:canceled, job:log
+	Job     *JobEvent
+	Message string // set for connected
+}
+
+// JobEvent is the JobUpdateEvent schema
+type JobEvent struct {
+	JobID    string            `json:"jobId"`
+	Seq      int64             `json:"seq"`
+	Type     string            `json:"type"`
+	State    JobState          `json:"state"`
+	Progress JobProgress       `json:"progress"`
+	Message  string            `json:"message"`
+	LogLine  string            `json:"logLine,omitempty"`
+	Workers  map[string]string `json:"workers,omitempty"`
+	Error    *JobError         `json:"error,omitempty"`
+	Artifact JobArtifact       `json:"artifact"`
+}
+
+// Error is an API error response
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gussync api: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
@@ -0,0 +1,5 @@
+// Package controlpb holds the GusSync gRPC control service definition (control.proto)
+// and its generated Go code.
+package controlpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative control.proto
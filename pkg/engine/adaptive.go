@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// AdaptiveInterval is how often the adaptive controller re-evaluates the worker count
+	AdaptiveInterval = 10 * time.Second
+)
+
+// workerLimiter caps how many workers may copy at the same time.
+// The limit can be changed while workers are running.
+type workerLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func newWorkerLimiter(limit int) *workerLimiter {
+	l := &workerLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a slot is free. Returns false if ctx is cancelled first.
+func (l *workerLimiter) acquire(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit && ctx.Err() == nil {
+		l.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *workerLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+func (l *workerLimiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+func (l *workerLimiter) getLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// wake releases any waiters so they can observe a cancelled context
+func (l *workerLimiter) wake() {
+	l.cond.Broadcast()
+}
+
+// adaptiveSample is what the controller observed during one interval
+type adaptiveSample struct {
+	Completed int     // Files copied successfully
+	Stalls    int     // Stalls/timeouts (including retried attempts)
+	Failed    int     // Files that failed for other reasons
+	Rate      float64 // Bytes per second
+}
+
+// adaptiveController decides the worker count from observed throughput and stall rate
+type adaptiveController struct {
+	min, max     int
+	lastRate     float64
+	lastIncrease bool
+}
+
+// next returns the worker count for the next interval.
+// Any stall halves the pool (MTP choking gets worse with more parallel reads);
+// a clean interval adds a worker as long as throughput keeps up.
+func (c *adaptiveController) next(current int, s adaptiveSample) int {
+	n := current
+	attempts := s.Completed + s.Stalls + s.Failed
+
+	switch {
+	case s.Stalls > 0 && s.Stalls*5 >= attempts:
+		// Heavy stalling (>= 20% of attempts): drop straight to the minimum
+		n = c.min
+	case s.Stalls > 0:
+		n = current / 2
+	case attempts == 0:
+		// Nothing happened (e.g. waiting on scanner) - keep current
+	case c.lastIncrease && s.Rate < c.lastRate*0.9:
+		// The last increase made throughput worse - undo it
+		n = current - 1
+	default:
+		n = current + 1
+	}
+
+	if n < c.min {
+		n = c.min
+	}
+	if n > c.max {
+		n = c.max
+	}
+
+	c.lastIncrease = n > current
+	if attempts > 0 {
+		c.lastRate = s.Rate
+	}
+	return n
+}
+
+// runAdaptive periodically samples engine stats and adjusts the limiter until done is closed
+func (e *Engine) runAdaptive(limiter *workerLimiter, done <-chan struct{}) {
+	ctrl := &adaptiveController{min: e.config.MinWorkers, max: e.config.NumWorkers}
+	ticker := time.NewTicker(AdaptiveInterval)
+	defer ticker.Stop()
+
+	e.stats.Lock()
+	lastCompleted, lastFailed, lastBytes := e.stats.completed, e.stats.failed, e.stats.totalBytes
+	lastStalls := e.stats.stalls
+	e.stats.Unlock()
+	lastTime := time.Now()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		e.stats.Lock()
+		now := time.Now()
+		sample := adaptiveSample{
+			Completed: e.stats.completed - lastCompleted,
+			Failed:    e.stats.failed - lastFailed,
+			Stalls:    e.stats.stalls - lastStalls,
+			Rate:      float64(e.stats.totalBytes-lastBytes) / now.Sub(lastTime).Seconds(),
+		}
+		lastCompleted, lastFailed, lastBytes = e.stats.completed, e.stats.failed, e.stats.totalBytes
+		lastStalls = e.stats.stalls
+		e.stats.Unlock()
+		lastTime = now
+
+		current := limiter.getLimit()
+		next := ctrl.next(current, sample)
+		if next != current {
+			limiter.setLimit(next)
+			e.config.Reporter.ReportLog("info", fmt.Sprintf("Adaptive workers: %d -> %d (%d copied, %d stalls, %s/s)",
+				current, next, sample.Completed, sample.Stalls, formatSize(int64(sample.Rate))))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveControllerNext(t *testing.T) {
+	c := &adaptiveController{min: 1, max: 4}
+
+	// Healthy intervals ramp up one worker at a time
+	if n := c.next(2, adaptiveSample{Completed: 20, Rate: 1000}); n != 3 {
+		t.Errorf("expected ramp to 3, got %d", n)
+	}
+	if n := c.next(3, adaptiveSample{Completed: 25, Rate: 1200}); n != 4 {
+		t.Errorf("expected ramp to 4, got %d", n)
+	}
+	// Never above max
+	if n := c.next(4, adaptiveSample{Completed: 25, Rate: 1300}); n != 4 {
+		t.Errorf("expected to stay at max 4, got %d", n)
+	}
+
+	// An occasional stall halves the pool
+	if n := c.next(4, adaptiveSample{Completed: 20, Stalls: 1, Rate: 1000}); n != 2 {
+		t.Errorf("expected halve to 2, got %d", n)
+	}
+	// Heavy stalling drops to min
+	if n := c.next(4, adaptiveSample{Completed: 2, Stalls: 2}); n != 1 {
+		t.Errorf("expected drop to 1, got %d", n)
+	}
+
+	// Throughput drop after an increase undoes it
+	c = &adaptiveController{min: 1, max: 4}
+	c.next(2, adaptiveSample{Completed: 10, Rate: 1000})
+	if n := c.next(3, adaptiveSample{Completed: 8, Rate: 500}); n != 2 {
+		t.Errorf("expected step back to 2, got %d", n)
+	}
+
+	// Idle interval keeps the count
+	if n := c.next(2, adaptiveSample{}); n != 2 {
+		t.Errorf("expected idle to keep 2, got %d", n)
+	}
+}
+
+func TestWorkerLimiter(t *testing.T) {
+	l := newWorkerLimiter(1)
+	ctx := context.Background()
+	if !l.acquire(ctx) {
+		t.Fatal("first acquire should succeed")
+	}
+
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(ctx) }()
+
+	select {
+	case <-acquired:
+		t.Fatal("second acquire should block at limit 1")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.setLimit(2)
+	if !<-acquired {
+		t.Fatal("acquire should succeed after raising limit")
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	go func() { acquired <- l.acquire(cctx) }()
+	cancel()
+	l.wake()
+	if <-acquired {
+		t.Error("acquire should fail after cancellation")
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,7 +21,12 @@ const (
 
 // ADBScanner implements Scanner for ADB-based scanning
 type ADBScanner struct {
-	closeJobChan func() // Function to safely close jobChan (uses sync.Once)
+	closeJobChan func()   // Function to safely close jobChan (uses sync.Once)
+	scanRoots    []string // Folders (relative to root) to scan; empty = whole root
+	filter       *Filter  // User-defined exclude rules (nil = none)
+	mediaStore   bool     // List media folders from the MediaStore instead of find
+	batchSize    int64    // Files smaller than this are sent in batches per folder (0 = off)
+	serial       string   // The device (see adbCommand)
 }
 
 // NewADBScanner creates a new ADB scanner
@@ -31,6 +36,35 @@ func NewADBScanner(closeJobChan func()) *ADBScanner {
 	}
 }
 
+// SetScanRoots limits scanning to the given folders (relative to the scan root)
+func (adb *ADBScanner) SetScanRoots(roots []string) {
+	adb.scanRoots = roots
+}
+
+// SetFilter applies user-defined exclude rules during scanning
+func (adb *ADBScanner) SetFilter(f *Filter) {
+	adb.filter = f
+}
+
+// SetMediaStore lists the media folders (DCIM, Pictures, Movies, ...) from Android's
+// MediaStore in one query instead of walking them with find, which takes seconds instead
+// of minutes on large photo libraries. Folders hidden with .nomedia and all other files
+// are still found with find; without a usable MediaStore everything is.
+func (adb *ADBScanner) SetMediaStore(enabled bool) {
+	adb.mediaStore = enabled
+}
+
+// SetSerial scans the device with this adb serial ("" = the one adb picks)
+func (adb *ADBScanner) SetSerial(serial string) {
+	adb.serial = serial
+}
+
+// SetBatchSmallFiles sends files smaller than size found by find in batch jobs, the small
+// files of one folder together (see EngineConfig.BatchSmallFiles); 0 sends every file alone
+func (adb *ADBScanner) SetBatchSmallFiles(size int64) {
+	adb.batchSize = size
+}
+
 // Scan discovers files using adb shell find with priority paths first
 func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
 	defer func() {
@@ -46,9 +80,13 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 	sentFiles := make(map[string]bool)
 	var mu sync.Mutex
 
+	// Size and time limits are applied by find itself
+	limitArgs := adb.filter.findArgs(time.Now())
+
 	// Helper function to find and send files from a path
 	findAndSend := func(searchPath string) {
-		cmd := exec.CommandContext(ctx, "adb", "shell", "find", searchPath, "-type", "f", "2>/dev/null")
+		args := append(append(append([]string{"shell", "find", searchPath, "-type", "f"}, limitArgs...), adb.findSizeArgs()...), "2>/dev/null")
+		cmd := adbCommand(ctx, adb.serial, args...)
 		
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -61,6 +99,8 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 			return
 		}
 
+		batch := adb.newBatcher(jobs)
+		defer batch.flush(ctx)
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
 			select {
@@ -73,7 +113,7 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 					continue
 				}
 
-				androidPath := line
+				androidPath, size, sized := adb.parseFindLine(line)
 				
 				// Check if we've already sent this file
 				mu.Lock()
@@ -93,31 +133,78 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 
 			// Check if file should be excluded (using normalized path)
 			// ADB paths are already normalized (no /sdcard prefix after calculateRelPathFromAndroid)
-			if shouldExcludeFile(relPath) {
+			if adb.filter.defaultExcluded(relPath) || adb.filter.Excluded(relPath) {
 				// Skip excluded files (cache, temp, system files)
 				continue
 			}
 
-			// Send job immediately (priority paths are processed first)
-			select {
-			case jobs <- FileJob{SourcePath: androidPath, RelPath: relPath}:
-				case <-ctx.Done():
-					cmd.Process.Kill()
-					return
-				}
+			// Send job immediately (priority paths are processed first); small files wait
+			// for the others of their folder
+			if !batch.send(ctx, FileJob{SourcePath: androidPath, RelPath: relPath, Size: size}, sized) {
+				cmd.Process.Kill()
+				return
+			}
 			}
 		}
 
 		if err := scanner.Err(); err != nil {
-			errors <- fmt.Errorf("error reading adb find output for %s: %w", searchPath, err)
+			errors <- pathError(PhaseScan, searchPath, fmt.Errorf("error reading adb find output for %s: %w", searchPath, err))
 		}
 
 		cmd.Wait() // Ignore errors for missing directories
 	}
 
+	// Media folders from the MediaStore (and the .nomedia folders in them it doesn't index)
+	var mediaFolders []string
+	if adb.mediaStore {
+		folders := mediaStoreFolders
+		if len(adb.scanRoots) > 0 {
+			folders = nil
+			for _, scanRoot := range adb.scanRoots {
+				if slices.Contains(mediaStoreFolders, scanRoot) {
+					folders = append(folders, scanRoot)
+				}
+			}
+		}
+		markSent := func(androidPath string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			if sentFiles[androidPath] {
+				return false
+			}
+			sentFiles[androidPath] = true
+			return true
+		}
+		if len(folders) > 0 && adb.scanMediaStore(ctx, androidRoot, folders, markSent, jobs) {
+			mediaFolders = folders
+			for _, dir := range adb.hiddenMediaDirs(ctx, androidRoot, folders) {
+				findAndSend(shellQuote(dir))
+			}
+		}
+	}
+
+	// Selected folders only: no priority pass or general find needed
+	if len(adb.scanRoots) > 0 {
+		for _, scanRoot := range adb.scanRoots {
+			if slices.Contains(mediaFolders, scanRoot) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				findAndSend(androidRoot + "/" + scanRoot)
+			}
+		}
+		return
+	}
+
 	// First, process priority paths in order
 	var wg sync.WaitGroup
-	for _, priorityPath := range PriorityPaths {
+	for _, priorityPath := range adb.filter.PriorityPaths() {
+		if slices.Contains(mediaFolders, priorityPath) {
+			continue
+		}
 		select {
 		case <-ctx.Done():
 			return
@@ -138,7 +225,17 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 	// Then, find all remaining files (excluding already sent ones)
 	// We'll use find with -path exclusion, but that's complex, so instead
 	// just run a general find and skip already-sent files
-	cmd := exec.CommandContext(ctx, "adb", "shell", "find", androidRoot, "-type", "f", "2>/dev/null")
+	findArgs := append([]string{"shell", "find", androidRoot, "-type", "f"}, limitArgs...)
+	if len(mediaFolders) > 0 {
+		// Don't walk the folders the MediaStore already listed
+		findArgs = append(append([]string{"shell", "find", androidRoot}, mediaStorePrune(androidRoot, mediaFolders)...), "-type", "f")
+		findArgs = append(findArgs, limitArgs...)
+		if adb.batchSize <= 0 {
+			findArgs = append(findArgs, "-print")
+		}
+	}
+	findArgs = append(findArgs, adb.findSizeArgs()...)
+	cmd := adbCommand(ctx, adb.serial, append(findArgs, "2>/dev/null")...)
 	
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -151,6 +248,8 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 		return
 	}
 
+	batch := adb.newBatcher(jobs)
+	defer batch.flush(ctx)
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
 		select {
@@ -163,7 +262,7 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 				continue
 			}
 
-			androidPath := line
+			androidPath, size, sized := adb.parseFindLine(line)
 			
 			// Skip if already sent (from priority paths)
 			mu.Lock()
@@ -182,15 +281,13 @@ func (adb *ADBScanner) Scan(ctx context.Context, root string, jobs chan<- FileJo
 			}
 
 			// Check if file should be excluded (using normalized path)
-			if shouldExcludeFile(relPath) {
+			if adb.filter.defaultExcluded(relPath) || adb.filter.Excluded(relPath) {
 				// Skip excluded files (cache, temp, system files)
 				continue
 			}
 
-			// Send job
-			select {
-			case jobs <- FileJob{SourcePath: androidPath, RelPath: relPath}:
-			case <-ctx.Done():
+			// Send job; small files wait for the others of their folder
+			if !batch.send(ctx, FileJob{SourcePath: androidPath, RelPath: relPath, Size: size}, sized) {
 				cmd.Process.Kill()
 				return
 			}
@@ -240,13 +337,28 @@ func calculateRelPathFromAndroid(androidPath, androidRoot string) (string, error
 }
 
 // ADBCopier implements Copier for ADB-based copying
-type ADBCopier struct{}
+type ADBCopier struct {
+	names    destNamer // how copies are named in the destination (zero = as on the source)
+	timeouts CopyTimeouts
+	serial   string // the device (see adbCommand)
+}
 
 // NewADBCopier creates a new ADB copier
 func NewADBCopier() *ADBCopier {
 	return &ADBCopier{}
 }
 
+// SetTimeouts sets when a pull is abandoned as stalled or too slow (see CopyTimeouts); the
+// file's size comes from the scan
+func (ac *ADBCopier) SetTimeouts(t CopyTimeouts) {
+	ac.timeouts = t
+}
+
+// SetSerial pulls from the device with this adb serial ("" = the one adb picks)
+func (ac *ADBCopier) SetSerial(serial string) {
+	ac.serial = serial
+}
+
 // Copy copies a file using adb pull
 func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
 	// Calculate relative path from source root (ADB already normalizes /sdcard prefix)
@@ -264,7 +376,7 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 	}
 
 	// Build destination path using normalized path (protocol-agnostic)
-	destPath := filepath.Join(destRoot, normalizedPath)
+	destPath := ac.names.path(destRoot, sourcePath, normalizedPath)
 
 	// Ensure destination directory exists
 	destDir := filepath.Dir(destPath)
@@ -276,13 +388,17 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 	pullCtx, cancel := context.WithTimeout(ctx, ADBPullTimeout)
 	defer cancel()
 
-	// Use adb pull to copy the file
-	// adb pull /sdcard/path/to/file /local/dest/path
-	cmd := exec.CommandContext(pullCtx, "adb", "pull", sourcePath, destPath)
+	// Use adb pull to copy the file into <dest>.part, renamed once the pull succeeds
+	// adb pull /sdcard/path/to/file /local/dest/path.part
+	partPath := destPath + PartSuffix
+	cmd := adbCommand(pullCtx, ac.serial, "pull", sourcePath, longPath(partPath))
 
 	// Start progress monitoring and connection checking in a goroutine
 	progressDone := make(chan bool, 1)
 	var bytesCopied int64
+	// The growing .part file shows whether the pull stalled or crawls below the minimum throughput
+	watch := newTransferWatch(ac.timeouts.forSize(fileSizeFrom(ctx)), time.Now())
+	abandoned := make(chan error, 1)
 	
 	// Connection checker for ADB: verify device is still connected
 	connTicker := time.NewTicker(10 * time.Second)
@@ -295,18 +411,8 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 			case <-progressDone:
 				return
 			case <-connTicker.C:
-				// Check if ADB device is still connected
-				checkCmd := exec.CommandContext(pullCtx, "adb", "devices")
-				output, err := checkCmd.Output()
-				if err != nil {
-					cancel() // Connection lost
-					return
-				}
-				// Parse output - should contain device ID
-				outputStr := string(output)
-				// Check if our device (from sourceRoot) is in the list
-				if !strings.Contains(outputStr, "device") {
-					// No devices connected or all unauthorized
+				// Check if our ADB device is still connected
+				if !adbConnected(pullCtx, ac.serial) {
 					cancel() // Connection lost
 					return
 				}
@@ -329,7 +435,7 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 				return
 			case <-ticker.C:
 				// Check destination file size
-				if info, err := os.Stat(destPath); err == nil {
+				if info, err := os.Stat(partPath); err == nil {
 					bytesCopied = info.Size()
 					if progressChan != nil {
 						select {
@@ -338,6 +444,13 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 						}
 					}
 				}
+				if err := watch.check(bytesCopied, time.Now()); err != nil {
+					select {
+					case abandoned <- err:
+					default:
+					}
+					cancel()
+				}
 			}
 		}
 	}()
@@ -354,21 +467,31 @@ func (ac *ADBCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot
 
 	// Check if error was due to connection loss
 	if err != nil {
+		select {
+		case reason := <-abandoned:
+			os.Remove(partPath)
+			return 0, reason
+		default:
+		}
 		// Check if context was cancelled due to connection loss
 		if pullCtx.Err() == context.Canceled {
-			// Check if device is still connected
-			checkCmd := exec.Command("adb", "devices")
-			output, checkErr := checkCmd.Output()
-			if checkErr != nil || !strings.Contains(string(output), "device") {
+			// Check if our device is still connected
+			checkCtx, checkCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			connected := adbConnected(checkCtx, ac.serial)
+			checkCancel()
+			if !connected {
 				// Clean up partial file on error
-				os.Remove(destPath)
-				return 0, fmt.Errorf("connection lost during adb pull: device disconnected")
+				os.Remove(partPath)
+				return 0, fmt.Errorf("%w during adb pull: device disconnected", ErrConnectionLost)
 			}
 		}
 		// Clean up partial file on error
-		os.Remove(destPath)
+		os.Remove(partPath)
 		return 0, fmt.Errorf("adb pull failed: %w", err)
 	}
+	if err := finishPart(partPath, destPath); err != nil {
+		return 0, err
+	}
 
 	// Get final file size
 	if info, err := os.Stat(destPath); err == nil {
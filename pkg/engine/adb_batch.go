@@ -0,0 +1,332 @@
+package engine
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// Limits of one batch of small files (EngineConfig.BatchSmallFiles)
+const (
+	adbBatchMaxFiles = 256      // files per batch
+	adbBatchMaxBytes = 32 << 20 // bytes per batch, so a broken stream doesn't cost much
+	// adbBatchMaxArgBytes caps the names of a batch: older adbd versions cut shell
+	// commands longer than 4 KB
+	adbBatchMaxArgBytes = 3000
+)
+
+// findSizeArgs makes find print each file as <size>/<path> when small files are batched,
+// so they can be told apart without a stat per file
+func (adb *ADBScanner) findSizeArgs() []string {
+	if adb.batchSize <= 0 {
+		return nil
+	}
+	return []string{"-exec", "stat", "-c", "'%s/%n'", "'{}'", "+"}
+}
+
+// parseFindLine splits a line of find's output into the path and, when findSizeArgs asked
+// for it, the size of the file; sized is false when the size isn't known
+func (adb *ADBScanner) parseFindLine(line string) (p string, size int64, sized bool) {
+	if adb.batchSize <= 0 {
+		return line, 0, false
+	}
+	sizeText, rest, ok := strings.Cut(line, "/")
+	if !ok {
+		return line, 0, false
+	}
+	size, err := strconv.ParseInt(sizeText, 10, 64)
+	if err != nil || size < 0 {
+		return line, 0, false
+	}
+	return rest, size, true
+}
+
+// smallFileBatcher groups the small files of a folder, as find lists them, into batch jobs
+type smallFileBatcher struct {
+	jobs    chan<- FileJob
+	maxSize int64 // files smaller than this are batched (0 = none)
+
+	dir       string    // source folder of the files held
+	files     []FileJob // files held for the next batch
+	bytes     int64     // their total size
+	nameBytes int       // length of their names in the tar command
+}
+
+// newBatcher returns the batcher the jobs of one find go through
+func (adb *ADBScanner) newBatcher(jobs chan<- FileJob) *smallFileBatcher {
+	return &smallFileBatcher{jobs: jobs, maxSize: adb.batchSize}
+}
+
+// send queues job: a small file of known size waits for the others of its folder, anything
+// else is sent at once. Returns false when ctx is done.
+func (b *smallFileBatcher) send(ctx context.Context, job FileJob, sized bool) bool {
+	if b.maxSize <= 0 || !sized || job.Size >= b.maxSize {
+		return b.sendJob(ctx, job)
+	}
+	dir := path.Dir(job.SourcePath)
+	name := len(path.Base(job.SourcePath)) + 5 // quoted, with ./ and a space
+	if len(b.files) > 0 && (dir != b.dir || len(b.files) >= adbBatchMaxFiles ||
+		b.bytes+job.Size > adbBatchMaxBytes || b.nameBytes+name > adbBatchMaxArgBytes) {
+		if !b.flush(ctx) {
+			return false
+		}
+	}
+	b.dir = dir
+	b.files = append(b.files, job)
+	b.bytes += job.Size
+	b.nameBytes += name
+	return true
+}
+
+// flush sends the files held, as one batch job (a single file as itself). Returns false
+// when ctx is done.
+func (b *smallFileBatcher) flush(ctx context.Context) bool {
+	files := b.files
+	b.files, b.bytes, b.nameBytes = nil, 0, 0
+	switch len(files) {
+	case 0:
+		return true
+	case 1:
+		return b.sendJob(ctx, files[0])
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return b.sendJob(ctx, FileJob{SourcePath: b.dir, RelPath: path.Dir(files[0].RelPath), Size: total, Batch: files})
+}
+
+func (b *smallFileBatcher) sendJob(ctx context.Context, job FileJob) bool {
+	select {
+	case b.jobs <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// copyBatch copies the files of a batch job that aren't backed up yet in one tar stream.
+// Whatever the stream doesn't deliver, and the files that are backed up already, go
+// through copyJob one by one.
+func (e *Engine) copyBatch(ctx context.Context, id int, job FileJob, errorChan chan<- error, statsChan chan<- CopyStats, copier Copier) jobResult {
+	var pending, rest []FileJob
+	var size int64
+	for _, f := range job.Batch {
+		if e.bulkDone[f.SourcePath] {
+			continue
+		}
+		if e.stateManager.IsDoneForSource(f.SourcePath, e.config.SourcePath) || !e.stateManager.ShouldRetry(f.SourcePath) {
+			rest = append(rest, f)
+			continue
+		}
+		pending = append(pending, f)
+		size += f.Size
+	}
+
+	if len(pending) > 1 {
+		if !e.waitToCopy(ctx, id, FileJob{SourcePath: job.SourcePath, Size: size}) {
+			return jobStop
+		}
+		batch := pending[:0]
+		for _, f := range pending {
+			// Names that collide in NFC are told apart before anything is named after them
+			if e.nfcCheck != nil {
+				e.nfcCheck.check(ctx, f.SourcePath, f.RelPath)
+			}
+			// A new file with the content of a backed-up one moved: reuse that copy
+			if e.moves != nil && e.moves.reuse(ctx, f, statsChan) {
+				continue
+			}
+			batch = append(batch, f)
+		}
+		pending = batch
+	}
+
+	if len(pending) > 1 {
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = fmt.Sprintf("Batch: %d files in %s", len(pending), path.Base(job.SourcePath))
+		e.workerStatus.Unlock()
+		done, err := e.pullBatch(ctx, id, job.SourcePath, pending, errorChan, statsChan)
+		if ctx.Err() != nil {
+			return jobStop
+		}
+		if err != nil {
+			e.log("warn", fmt.Sprintf("Batch of %s stopped after %d of %d files (%v); copying the rest one by one",
+				job.SourcePath, len(done), len(pending), err))
+		}
+		for _, f := range pending {
+			if !done[f.SourcePath] {
+				rest = append(rest, f)
+			}
+		}
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = "idle"
+		e.workerStatus.Unlock()
+	} else {
+		rest = append(rest, pending...)
+	}
+
+	for _, f := range rest {
+		if e.copyJob(ctx, id, f, errorChan, statsChan, copier) == jobStop {
+			return jobStop
+		}
+	}
+	return jobDone
+}
+
+// pullBatch streams the files of dir as `adb exec-out tar` and extracts them into the
+// destination. It returns the source paths of the files extracted, even on error. A
+// stream that delivers no bytes for the stall timeout is abandoned with ErrStalled.
+func (e *Engine) pullBatch(ctx context.Context, id int, dir string, files []FileJob, errorChan chan<- error, statsChan chan<- CopyStats) (map[string]bool, error) {
+	done := make(map[string]bool)
+	parts := []string{"tar", "-C", shellQuote(dir), "-cf", "-"}
+	for _, f := range files {
+		// ./ keeps a name starting with - from being read as an option
+		parts = append(parts, shellQuote("./"+path.Base(f.SourcePath)))
+	}
+
+	pullCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// exec-out passes the bytes through untouched; tar's complaints must not end up in the stream
+	cmd := adbCommand(pullCtx, e.config.Serial, "exec-out", strings.Join(parts, " ")+" 2>/dev/null")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return done, err
+	}
+	if err := cmd.Start(); err != nil {
+		return done, err
+	}
+
+	timeout := e.config.StallTimeout
+	if timeout <= 0 {
+		timeout = StallTimeout
+	}
+	guard := newStallGuard(stdout, timeout, cancel)
+	defer guard.stop()
+	err = e.extractBatch(pullCtx, limitReader(pullCtx, bufio.NewReaderSize(guard, 1<<20), e.rateLimiter), id, files, done, errorChan, statsChan)
+	if err != nil {
+		cmd.Process.Kill()
+	}
+	cmd.Wait()
+	if guard.stalled() && ctx.Err() == nil {
+		e.stats.Lock()
+		e.stats.stalls++
+		e.stats.Unlock()
+		return done, fmt.Errorf("%w: no data for %s", ErrStalled, timeout)
+	}
+	if err == nil && len(done) < len(files) {
+		err = fmt.Errorf("%d files missing from the stream", len(files)-len(done))
+	}
+	return done, err
+}
+
+// extractBatch writes the regular files of a batch's tar stream into the destination and
+// records each, like a copied file, once it is complete. Entries that aren't in files are
+// ignored.
+func (e *Engine) extractBatch(ctx context.Context, r io.Reader, id int, files []FileJob, done map[string]bool, errorChan chan<- error, statsChan chan<- CopyStats) error {
+	byName := make(map[string]FileJob, len(files))
+	for _, f := range files {
+		byName[path.Base(f.SourcePath)] = f
+	}
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		started := time.Now()
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f, ok := byName[path.Clean(strings.TrimPrefix(hdr.Name, "./"))]
+		if !ok || hdr.Typeflag != tar.TypeReg || done[f.SourcePath] {
+			continue
+		}
+
+		e.audit(AuditEvent{Op: AuditCopyStart, Path: f.SourcePath, Dest: f.RelPath, Bytes: hdr.Size})
+		e.publish(Event{Kind: EventFileStarted, Path: f.SourcePath, Dest: f.RelPath, Bytes: hdr.Size})
+		hash, err := writeTarEntry(tr, e.destPath(f.SourcePath, f.RelPath), hdr.Size)
+		if err != nil {
+			return err
+		}
+		e.setOwnership(e.destPath(f.SourcePath, f.RelPath))
+		normalizedPath, _ := normalizePhonePath(f.SourcePath, e.config.SourcePath)
+		e.stateManager.MarkCompleted(state.CompletedFile{SourcePath: f.SourcePath, Hash: hash, NormalizedPath: normalizedPath,
+			Volume: volumeID(f.SourcePath), Size: hdr.Size})
+		e.stateManager.MarkSuccess()
+		done[f.SourcePath] = true
+		e.audit(AuditEvent{Op: AuditCopyEnd, Path: f.SourcePath, Dest: f.RelPath, Bytes: hdr.Size, Hash: hash,
+			DurationMS: time.Since(started).Milliseconds(), Attempts: 1, Result: AuditOK})
+		e.publish(Event{Kind: EventFileCompleted, Path: f.SourcePath, Dest: f.RelPath, Bytes: hdr.Size, Duration: time.Since(started), Hash: hash})
+
+		if len(e.config.PostProcessors) > 0 {
+			e.runPostProcessors(ctx, PostCopyFile{
+				SourcePath: f.SourcePath,
+				DestPath:   e.destPath(f.SourcePath, f.RelPath),
+				DestRoot:   e.config.DestRoot,
+				Hash:       hash,
+			}, errorChan)
+		}
+
+		e.workerStatus.Lock()
+		e.workerStatus.bytes[id] += hdr.Size
+		e.workerStatus.Unlock()
+		e.stats.Lock()
+		e.stats.transferred += hdr.Size
+		e.stats.Unlock()
+		statsChan <- CopyStats{Success: true, BytesCopied: hdr.Size, RelPath: f.RelPath, SourcePath: f.SourcePath, Duration: time.Since(started)}
+	}
+}
+
+// stallGuard cancels a stream that delivers no bytes for timeout
+type stallGuard struct {
+	r     io.Reader
+	timer *time.Timer
+
+	mu      sync.Mutex
+	timeout time.Duration
+	fired   bool
+}
+
+func newStallGuard(r io.Reader, timeout time.Duration, cancel context.CancelFunc) *stallGuard {
+	g := &stallGuard{r: r, timeout: timeout}
+	g.timer = time.AfterFunc(timeout, func() {
+		g.mu.Lock()
+		g.fired = true
+		g.mu.Unlock()
+		cancel()
+	})
+	return g
+}
+
+func (g *stallGuard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if n > 0 {
+		g.timer.Reset(g.timeout)
+	}
+	return n, err
+}
+
+// stop ends the watch
+func (g *stallGuard) stop() {
+	g.timer.Stop()
+}
+
+// stalled reports whether the stream was canceled for want of bytes
+func (g *stallGuard) stalled() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.fired
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFindLine(t *testing.T) {
+	adb := &ADBScanner{batchSize: 1 << 20}
+	tests := []struct {
+		line  string
+		path  string
+		size  int64
+		sized bool
+	}{
+		{"1234//sdcard/DCIM/a.jpg", "/sdcard/DCIM/a.jpg", 1234, true},
+		{"0//sdcard/DCIM/empty", "/sdcard/DCIM/empty", 0, true},
+		{"/sdcard/DCIM/a.jpg", "/sdcard/DCIM/a.jpg", 0, false}, // printed without a size
+		{"12x//sdcard/a", "12x//sdcard/a", 0, false},
+	}
+	for _, tt := range tests {
+		p, size, sized := adb.parseFindLine(tt.line)
+		if p != tt.path || size != tt.size || sized != tt.sized {
+			t.Errorf("parseFindLine(%q) = %q, %d, %v; want %q, %d, %v", tt.line, p, size, sized, tt.path, tt.size, tt.sized)
+		}
+	}
+
+	off := &ADBScanner{}
+	if p, _, sized := off.parseFindLine("12//sdcard/a"); p != "12//sdcard/a" || sized {
+		t.Errorf("with batching off the line must be the path, got %q", p)
+	}
+}
+
+func TestSmallFileBatcher(t *testing.T) {
+	jobs := make(chan FileJob, 100)
+	b := (&ADBScanner{batchSize: 1000}).newBatcher(jobs)
+	ctx := context.Background()
+	send := func(p string, size int64) {
+		if !b.send(ctx, FileJob{SourcePath: "/sdcard/" + p, RelPath: p, Size: size}, true) {
+			t.Fatal("send failed")
+		}
+	}
+	send("DCIM/a.jpg", 10)
+	send("DCIM/big.mp4", 5000) // large: sent alone at once
+	send("DCIM/b.jpg", 20)
+	send("Music/c.mp3", 30) // another folder ends the batch
+	b.flush(ctx)
+	close(jobs)
+
+	var got []FileJob
+	for job := range jobs {
+		got = append(got, job)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d jobs, want 3: %+v", len(got), got)
+	}
+	if got[0].SourcePath != "/sdcard/DCIM/big.mp4" || len(got[0].Batch) != 0 {
+		t.Errorf("large file not sent on its own: %+v", got[0])
+	}
+	if batch := got[1]; len(batch.Batch) != 2 || batch.SourcePath != "/sdcard/DCIM" || batch.RelPath != "DCIM" || batch.Size != 30 {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+	if got[2].SourcePath != "/sdcard/Music/c.mp3" || len(got[2].Batch) != 0 {
+		t.Errorf("a lone small file must be sent as itself: %+v", got[2])
+	}
+}
+
+func TestSmallFileBatcherLimits(t *testing.T) {
+	jobs := make(chan FileJob, adbBatchMaxFiles+10)
+	b := (&ADBScanner{batchSize: 1000}).newBatcher(jobs)
+	for i := 0; i < adbBatchMaxFiles+1; i++ {
+		b.send(context.Background(), FileJob{SourcePath: "/sdcard/DCIM/" + string(rune('a'+i%26)), Size: 1}, true)
+	}
+	b.flush(context.Background())
+	close(jobs)
+	var sizes []int
+	for job := range jobs {
+		sizes = append(sizes, len(job.Batch))
+	}
+	if len(sizes) != 2 || sizes[0] > adbBatchMaxFiles {
+		t.Errorf("batches of %v files, want at most %d per batch", sizes, adbBatchMaxFiles)
+	}
+}
+
+func TestExtractBatch(t *testing.T) {
+	e, sm, destRoot := newBulkTestEngine(t)
+	stream := buildTar(t, map[string]string{"./a.jpg": "aaa", "./b.jpg": "bb", "./other.jpg": "x"})
+	files := []FileJob{
+		{SourcePath: "/sdcard/DCIM/a.jpg", RelPath: "DCIM/a.jpg", Size: 3},
+		{SourcePath: "/sdcard/DCIM/b.jpg", RelPath: "DCIM/b.jpg", Size: 2},
+		{SourcePath: "/sdcard/DCIM/c.jpg", RelPath: "DCIM/c.jpg", Size: 1},
+	}
+	statsChan := make(chan CopyStats, len(files))
+	done := make(map[string]bool)
+	if err := e.extractBatch(context.Background(), bytes.NewReader(stream), 0, files, done, make(chan error, 10), statsChan); err != nil {
+		t.Fatalf("extractBatch: %v", err)
+	}
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		if !done["/sdcard/DCIM/"+name] || !sm.IsDone("/sdcard/DCIM/"+name) {
+			t.Errorf("%s not recorded as backed up", name)
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(destRoot, "DCIM", "a.jpg")); string(got) != "aaa" {
+		t.Errorf("a.jpg: got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(destRoot, "DCIM", "other.jpg")); !os.IsNotExist(err) {
+		t.Error("a file that isn't in the batch must be ignored")
+	}
+	if done["/sdcard/DCIM/c.jpg"] || len(statsChan) != 2 {
+		t.Errorf("got %d results, want 2 (c.jpg is missing from the stream)", len(statsChan))
+	}
+}
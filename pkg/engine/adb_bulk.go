@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// bulkTar copies the media folders (or the selected folders) of a new adb backup as a single
+// tar stream, `adb exec-out tar -cf - ...`, extracting and hashing each file as it arrives.
+// One stream avoids an adb pull round trip per file, which dominates small-file transfers.
+// Every extracted file is recorded in the state like a copied one; it returns their source
+// paths so the file-by-file pass that follows doesn't report them again. A broken stream
+// only ends the bulk phase: the files it didn't reach are copied one by one.
+func (e *Engine) bulkTar(ctx context.Context, scanRoots []string, filter *Filter) map[string]bool {
+	if n := e.stateManager.GetStats(); n > 0 {
+		e.log("info", fmt.Sprintf("Bulk transfer skipped: the backup already has %d files, so only new ones are copied", n))
+		return nil
+	}
+	androidRoot := sanitizeAndroidPath(e.config.SourcePath)
+	folders := scanRoots
+	if len(folders) == 0 {
+		folders = mediaStoreFolders
+	}
+	folders = existingAndroidFolders(ctx, e.config.Serial, androidRoot, folders)
+	if len(folders) == 0 {
+		return nil
+	}
+
+	e.log("info", fmt.Sprintf("Bulk transfer of %s as one tar stream", strings.Join(folders, ", ")))
+	parts := []string{"tar", "-C", shellQuote(androidRoot), "-cf", "-"}
+	for _, folder := range folders {
+		parts = append(parts, shellQuote(folder))
+	}
+	// exec-out passes the bytes through untouched; tar's complaints must not end up in the stream
+	cmd := adbCommand(ctx, e.config.Serial, "exec-out", strings.Join(parts, " ")+" 2>/dev/null")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Bulk transfer not possible: %v", err))
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		e.log("warn", fmt.Sprintf("Bulk transfer not possible: %v", err))
+		return nil
+	}
+
+	done := make(map[string]bool)
+	err = e.extractTar(ctx, limitReader(ctx, bufio.NewReaderSize(stdout, 1<<20), e.rateLimiter), androidRoot, filter, done)
+	if err != nil {
+		cmd.Process.Kill()
+	}
+	cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+	case err != nil:
+		e.log("warn", fmt.Sprintf("Bulk transfer stopped after %d files (%v); copying the rest file by file", len(done), err))
+	default:
+		e.log("info", fmt.Sprintf("Bulk transfer finished: %d files", len(done)))
+	}
+	return done
+}
+
+// extractTar writes the regular files of a tar stream of androidRoot's folders into the
+// destination, recording each in the state (and in done) once it is complete
+func (e *Engine) extractTar(ctx context.Context, r io.Reader, androidRoot string, filter *Filter, done map[string]bool) error {
+	tr := tar.NewReader(r)
+	lastReport := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue // directories are created as needed; links and devices aren't backed up
+		}
+		relPath := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if relPath == ".." || strings.HasPrefix(relPath, "../") || path.IsAbs(relPath) {
+			return fmt.Errorf("unsafe path in tar stream: %q", hdr.Name)
+		}
+		if filter.defaultExcluded(relPath) || filter.Excluded(relPath) || filter.OutsideLimits(hdr.Size, hdr.ModTime) {
+			continue
+		}
+
+		// The stream waits while the destination is short of space
+		if e.diskGuard != nil && !e.diskGuard.waitForSpace(ctx, hdr.Size, func() {}) {
+			return ctx.Err()
+		}
+
+		sourcePath := androidRoot + "/" + relPath
+		normalizedPath, err := normalizePhonePath(sourcePath, e.config.SourcePath)
+		if err != nil {
+			normalizedPath = relPath
+		}
+		if e.nfcCheck != nil {
+			e.nfcCheck.check(ctx, sourcePath, normalizedPath)
+		}
+		destPath := e.destPath(sourcePath, normalizedPath)
+		hash, err := writeTarEntry(tr, destPath, hdr.Size)
+		if err != nil {
+			return err
+		}
+		e.setOwnership(destPath)
+		e.stateManager.MarkCompleted(state.CompletedFile{SourcePath: sourcePath, Hash: hash, NormalizedPath: normalizedPath,
+			Volume: volumeID(sourcePath), Size: hdr.Size})
+		e.stateManager.MarkSuccess()
+		done[sourcePath] = true
+		copied := CopyStats{Success: true, BytesCopied: hdr.Size, RelPath: normalizedPath, SourcePath: sourcePath}
+		if e.report != nil {
+			e.report.addStats(copied)
+		}
+		e.audit(AuditEvent{Op: AuditCopyEnd, Path: sourcePath, Dest: normalizedPath, Bytes: hdr.Size, Hash: hash, Result: AuditOK})
+		e.publish(Event{Kind: EventFileCompleted, Path: sourcePath, Dest: normalizedPath, Bytes: hdr.Size, Hash: hash})
+
+		e.stats.Lock()
+		e.stats.totalFiles++
+		e.stats.completed++
+		e.stats.totalBytes += hdr.Size
+		e.stats.transferred += hdr.Size
+		e.addDirStats(copied)
+		e.stats.Unlock()
+		if time.Since(lastReport) >= 2*time.Second {
+			e.reportProgress(false)
+			lastReport = time.Now()
+		}
+	}
+}
+
+// writeTarEntry copies the current entry of tr to destPath, through its .part file, and
+// returns its SHA-256; a partial file is removed
+func writeTarEntry(tr *tar.Reader, destPath string, size int64) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create dest dir: %w", err)
+	}
+	// The stream can't be resumed at an offset, so a .part file is always started over
+	part, err := openPart(destPath, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, tr); err != nil {
+		part.discard()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", fmt.Errorf("failed to extract %s: %w", filepath.Base(destPath), err)
+	}
+	hash := hex.EncodeToString(part.hash.Sum(nil))
+	if err := part.commit(size); err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", filepath.Base(destPath), err)
+	}
+	return hash, nil
+}
+
+// existingAndroidFolders returns the folders (relative to root) that exist on the device
+// with this adb serial
+func existingAndroidFolders(ctx context.Context, serial, root string, folders []string) []string {
+	args := []string{"-d"}
+	for _, folder := range folders {
+		args = append(args, root+"/"+folder)
+	}
+	// ls fails for the missing ones but still lists the others
+	out, _ := adbShellOn(ctx, serial, "ls", args...)
+	var existing []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if rel, ok := strings.CutPrefix(strings.TrimSpace(line), root+"/"); ok && rel != "" {
+			existing = append(existing, rel)
+		}
+	}
+	return existing
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func buildTar(t *testing.T, files map[string]string, extra ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "DCIM/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, hdr := range extra {
+		tw.WriteHeader(hdr)
+	}
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func newBulkTestEngine(t *testing.T) (*Engine, *state.StateManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sm.Close() })
+	destRoot := filepath.Join(dir, "backup")
+	e := NewEngine(EngineConfig{Mode: TransportADB, SourcePath: "/sdcard", DestRoot: destRoot, Reporter: discardReporter{}}, sm)
+	return e, sm, destRoot
+}
+
+func TestExtractTar(t *testing.T) {
+	e, sm, destRoot := newBulkTestEngine(t)
+	files := map[string]string{"DCIM/Camera/a.jpg": "aaa", "Pictures/b.png": "bb"}
+	stream := buildTar(t, files,
+		&tar.Header{Name: "DCIM/link.jpg", Typeflag: tar.TypeSymlink, Linkname: "Camera/a.jpg"},
+		&tar.Header{Name: "DCIM/.nomedia", Typeflag: tar.TypeReg, Size: 0})
+
+	done := make(map[string]bool)
+	if err := e.extractTar(context.Background(), bytes.NewReader(stream), "/sdcard", nil, done); err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	for rel, content := range files {
+		if got, _ := os.ReadFile(filepath.Join(destRoot, rel)); string(got) != content {
+			t.Errorf("%s: got %q, want %q", rel, got, content)
+		}
+		if !sm.IsDone("/sdcard/"+rel) || !done["/sdcard/"+rel] {
+			t.Errorf("%s not recorded as backed up", rel)
+		}
+	}
+	if hash, _ := calculateFileHash(filepath.Join(destRoot, "Pictures/b.png")); sm.GetAllCompletedFiles()["/sdcard/Pictures/b.png"] != hash {
+		t.Error("recorded hash doesn't match the extracted file")
+	}
+	if len(done) != len(files) {
+		t.Errorf("recorded %d files, want %d (symlinks and excluded files are skipped)", len(done), len(files))
+	}
+}
+
+func TestExtractTarTruncated(t *testing.T) {
+	e, sm, destRoot := newBulkTestEngine(t)
+	stream := buildTar(t, map[string]string{"DCIM/big.mp4": string(make([]byte, 4096))})
+	cut := bytes.NewReader(stream[:1024+512]) // header, then part of the data
+
+	err := e.extractTar(context.Background(), cut, "/sdcard", nil, map[string]bool{})
+	if err == nil {
+		t.Fatal("expected an error for a truncated stream")
+	}
+	if _, err := os.Stat(filepath.Join(destRoot, "DCIM/big.mp4")); !os.IsNotExist(err) {
+		t.Error("expected the partial file to be removed")
+	}
+	if sm.IsDone("/sdcard/DCIM/big.mp4") {
+		t.Error("a partial file must not be recorded as backed up")
+	}
+}
+
+func TestExtractTarRejectsTraversal(t *testing.T) {
+	e, _, destRoot := newBulkTestEngine(t)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../../etc/evil", Typeflag: tar.TypeReg, Size: 1})
+	tw.Write([]byte("x"))
+	tw.Close()
+
+	if err := e.extractTar(context.Background(), io.Reader(&buf), "/sdcard", nil, map[string]bool{}); err == nil {
+		t.Fatal("expected a path escaping the destination to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(destRoot)), "etc/evil")); err == nil {
+		t.Error("file written outside the destination")
+	}
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mediaStoreFolders are the folders (relative to the scan root) that adb mode lists from
+// Android's MediaStore when SetMediaStore is on. They hold almost only media, which the
+// MediaStore indexes as it is created; everything else is still found with find.
+var mediaStoreFolders = []string{"DCIM", "Camera", "Pictures", "Movies", "Music", "Videos", "ScreenRecordings", "Screenshots"}
+
+// mediaStoreRow is one file from the MediaStore
+type mediaStoreRow struct {
+	path    string    // absolute path on the device (_data)
+	size    int64     // -1 when the MediaStore doesn't know it
+	modTime time.Time // zero when the MediaStore doesn't know it
+}
+
+// parseMediaStoreRow parses a line of `content query --uri content://media/external/file
+// --projection _size:date_modified:_data`, e.g.
+// "Row: 12 _size=2048, date_modified=1700000000, _data=/storage/emulated/0/DCIM/a, b.jpg"
+// (_data is last so a path containing ", " stays intact)
+func parseMediaStoreRow(line string) (mediaStoreRow, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "Row: ")
+	if !ok {
+		return mediaStoreRow{}, false
+	}
+	_, rest, ok = strings.Cut(rest, " ")
+	if !ok {
+		return mediaStoreRow{}, false
+	}
+	fields, data, ok := strings.Cut(rest, ", _data=")
+	if !ok || !strings.HasPrefix(data, "/") {
+		return mediaStoreRow{}, false
+	}
+	row := mediaStoreRow{path: data, size: -1}
+	for _, field := range strings.Split(fields, ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue // NULL
+		}
+		switch name {
+		case "_size":
+			row.size = n
+		case "date_modified":
+			row.modTime = time.Unix(n, 0)
+		}
+	}
+	return row, true
+}
+
+// androidRootAliases returns the paths under which the device may report files below root:
+// the MediaStore uses /storage/emulated/0 even when the scan root is /sdcard
+func androidRootAliases(root string) []string {
+	aliases := []string{root}
+	for _, pair := range [][2]string{
+		{"/sdcard", "/storage/emulated/0"},
+		{"/sdcard", "/storage/self/primary"},
+		{"/storage/emulated/0", "/sdcard"},
+	} {
+		if rest, ok := strings.CutPrefix(root, pair[0]); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+			aliases = append(aliases, pair[1]+rest)
+		}
+	}
+	return aliases
+}
+
+// mediaStoreRelPath returns p relative to root (given as its aliases), or false if p isn't below it
+func mediaStoreRelPath(p string, aliases []string) (string, bool) {
+	for _, alias := range aliases {
+		if rel, ok := strings.CutPrefix(p, alias+"/"); ok {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// scanMediaStore sends the files in folders (relative to androidRoot) that the MediaStore
+// knows about, marking them in sent. It returns false if the MediaStore can't be queried
+// (old Android, no content command), so the caller falls back to find.
+func (adb *ADBScanner) scanMediaStore(ctx context.Context, androidRoot string, folders []string, sent func(string) bool, jobs chan<- FileJob) bool {
+	// 12289 (0x3001) is the MTP format of a directory
+	cmd := adbCommand(ctx, adb.serial, "shell", "content", "query",
+		"--uri", "content://media/external/file",
+		"--projection", "_size:date_modified:_data",
+		"--where", shellQuote("format!=12289"))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	defer cmd.Wait()
+
+	wanted := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		wanted[folder] = true
+	}
+	aliases := androidRootAliases(androidRoot)
+
+	rows := 0
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		row, ok := parseMediaStoreRow(scanner.Text())
+		if !ok {
+			continue
+		}
+		rows++
+		relPath, ok := mediaStoreRelPath(row.path, aliases)
+		if !ok {
+			continue
+		}
+		top, _, _ := strings.Cut(relPath, "/")
+		if !wanted[top] || adb.filter.defaultExcluded(relPath) || adb.filter.Excluded(relPath) || adb.filter.OutsideLimits(row.size, row.modTime) {
+			continue
+		}
+		// Report the file under the scan root as written, like find does
+		sourcePath := androidRoot + "/" + relPath
+		if !sent(sourcePath) {
+			continue
+		}
+		size := row.size
+		if size < 0 {
+			size = 0
+		}
+		select {
+		case jobs <- FileJob{SourcePath: sourcePath, RelPath: relPath, Size: size}:
+		case <-ctx.Done():
+			cmd.Process.Kill()
+			return true
+		}
+	}
+	// "No result found." (or an error message) and no rows: nothing to trust
+	return scanner.Err() == nil && rows > 0
+}
+
+// hiddenMediaDirs returns the directories below folders that contain a .nomedia file; the
+// MediaStore skips them, so they must be listed with find
+func (adb *ADBScanner) hiddenMediaDirs(ctx context.Context, androidRoot string, folders []string) []string {
+	args := []string{}
+	for _, folder := range folders {
+		args = append(args, androidRoot+"/"+folder)
+	}
+	args = append(args, "-name", ".nomedia", "-type", "f")
+	out, err := adbShellOn(ctx, adb.serial, "find", args...)
+	if err != nil && len(out) == 0 {
+		return nil
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			dirs = append(dirs, path.Dir(line))
+		}
+	}
+	return dirs
+}
+
+// mediaStorePrune returns find arguments that skip the given folders below androidRoot
+func mediaStorePrune(androidRoot string, folders []string) []string {
+	if len(folders) == 0 {
+		return nil
+	}
+	args := []string{"\\("}
+	for i, folder := range folders {
+		if i > 0 {
+			args = append(args, "-o")
+		}
+		args = append(args, "-path", shellQuote(androidRoot+"/"+folder))
+	}
+	return append(args, "\\)", "-prune", "-o")
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMediaStoreRow(t *testing.T) {
+	tests := []struct {
+		line string
+		want mediaStoreRow
+		ok   bool
+	}{
+		{"Row: 0 _size=2048, date_modified=1700000000, _data=/storage/emulated/0/DCIM/Camera/IMG_1.jpg", mediaStoreRow{"/storage/emulated/0/DCIM/Camera/IMG_1.jpg", 2048, time.Unix(1700000000, 0)}, true},
+		{"Row: 12 _size=5, date_modified=NULL, _data=/storage/emulated/0/Music/a, b.mp3", mediaStoreRow{"/storage/emulated/0/Music/a, b.mp3", 5, time.Time{}}, true},
+		{"Row: 3 _size=NULL, date_modified=NULL, _data=/storage/emulated/0/Pictures/x.png", mediaStoreRow{"/storage/emulated/0/Pictures/x.png", -1, time.Time{}}, true},
+		{"Row: 7 _size=9, _data=/sdcard/DCIM/old.jpg", mediaStoreRow{"/sdcard/DCIM/old.jpg", 9, time.Time{}}, true},
+		{"Row: 4 _size=1, _data=NULL", mediaStoreRow{}, false},
+		{"No result found.", mediaStoreRow{}, false},
+		{"Error while accessing provider:media", mediaStoreRow{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseMediaStoreRow(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseMediaStoreRow(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMediaStoreRelPath(t *testing.T) {
+	aliases := androidRootAliases("/sdcard")
+	for p, want := range map[string]string{
+		"/storage/emulated/0/DCIM/a.jpg":   "DCIM/a.jpg",
+		"/sdcard/Pictures/b.png":           "Pictures/b.png",
+		"/storage/self/primary/Movies/c":   "Movies/c",
+		"/storage/1234-ABCD/DCIM/card.jpg": "",
+		"/sdcardx/DCIM/a.jpg":              "",
+	} {
+		got, ok := mediaStoreRelPath(p, aliases)
+		if got != want || ok != (want != "") {
+			t.Errorf("mediaStoreRelPath(%q) = %q, %v; want %q", p, got, ok, want)
+		}
+	}
+	if got := androidRootAliases("/storage/emulated/0/DCIM"); !reflect.DeepEqual(got, []string{"/storage/emulated/0/DCIM", "/sdcard/DCIM"}) {
+		t.Errorf("androidRootAliases = %v", got)
+	}
+}
+
+func TestMediaStorePrune(t *testing.T) {
+	got := mediaStorePrune("/sdcard", []string{"DCIM", "My Pictures"})
+	want := []string{`\(`, "-path", "'/sdcard/DCIM'", "-o", "-path", "'/sdcard/My Pictures'", `\)`, "-prune", "-o"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mediaStorePrune = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// shellQuote quotes s for the device's POSIX shell. adb shell joins its arguments with
+// spaces, so unquoted paths containing spaces or quotes would be split or reinterpreted.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// adbCommand returns an adb command for the device with this serial ("" = the one adb
+// picks: ANDROID_SERIAL, or the only one attached)
+func adbCommand(ctx context.Context, serial string, args ...string) *exec.Cmd {
+	if serial != "" {
+		args = append([]string{"-s", serial}, args...)
+	}
+	return exec.CommandContext(ctx, "adb", args...)
+}
+
+// adbShellOn runs a command on the device with this serial (see adbCommand; arguments are
+// quoted) and returns its stdout. A missing device is reported as ErrConnectionLost.
+func adbShellOn(ctx context.Context, serial, name string, args ...string) ([]byte, error) {
+	parts := []string{name}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	cmd := adbCommand(ctx, serial, "shell", strings.Join(parts, " "))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(string(out))
+		}
+		if isADBDisconnect(msg) {
+			return out, fmt.Errorf("%w: %s", ErrConnectionLost, msg)
+		}
+		if strings.Contains(msg, "No such file") {
+			return out, fmt.Errorf("%s: %w", msg, os.ErrNotExist)
+		}
+		return out, fmt.Errorf("adb shell %s failed: %v: %s", name, err, msg)
+	}
+	return out, nil
+}
+
+// adbConnected reports whether the device with this serial is attached and authorized.
+// `adb devices` can't tell: it lists every device, and its header says "devices" anyway.
+func adbConnected(ctx context.Context, serial string) bool {
+	out, err := adbCommand(ctx, serial, "get-state").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "device"
+}
+
+// isADBDisconnect reports whether adb output means the device is gone (as opposed to a command failing)
+func isADBDisconnect(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "no devices/emulators found") ||
+		strings.Contains(msg, "device offline") ||
+		strings.Contains(msg, "device not found") ||
+		strings.Contains(msg, "device unauthorized")
+}
+
+// adbSource accesses files on the device over adb for cleanup
+type adbSource struct {
+	sourceRoot string
+	destRoot   string
+	names      destNamer
+	serial     string // the device (see adbCommand)
+}
+
+func (s adbSource) Stat(ctx context.Context, path string) (int64, bool, error) {
+	out, err := adbShellOn(ctx, s.serial, "stat", "-c", "%s:%F", path)
+	if err != nil {
+		return 0, false, err
+	}
+	sizeStr, kind, _ := strings.Cut(strings.TrimSpace(string(out)), ":")
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("unexpected stat output for %s: %q", path, out)
+	}
+	return size, kind == "directory", nil
+}
+
+func (s adbSource) Hash(ctx context.Context, path string) (string, error) {
+	out, err := adbShellOn(ctx, s.serial, "sha256sum", path)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("unexpected sha256sum output for %s: %q", path, out)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+func (s adbSource) Restore(ctx context.Context, path string) error {
+	copier := &ADBCopier{names: s.names, serial: s.serial}
+	_, err := copier.Copy(ctx, path, s.sourceRoot, s.destRoot, nil)
+	return err
+}
+
+func (s adbSource) Remove(ctx context.Context, path string) error {
+	_, err := adbShellOn(ctx, s.serial, "rm", path)
+	return err
+}
+
+func (s adbSource) FreeSpace(ctx context.Context, path string) (int64, error) {
+	out, err := adbShellOn(ctx, s.serial, "df", "-k", path)
+	if err != nil {
+		return 0, err
+	}
+	return parseDFAvailable(string(out))
+}
+
+// parseDFAvailable extracts the available bytes from `df -k` output (toybox/busybox/coreutils)
+func parseDFAvailable(out string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("unexpected df output: %q", out)
+	}
+	// "Mounted on" is one column split across two header words
+	header := strings.Fields(strings.Replace(lines[0], "Mounted on", "Mounted", 1))
+	col := -1
+	for i, name := range header {
+		if strings.EqualFold(name, "Available") || strings.EqualFold(name, "Avail") {
+			col = i
+			break
+		}
+	}
+	// The filesystem name can wrap onto its own line; take the last line's fields
+	// counted from the end so wrapping doesn't shift the column
+	fields := strings.Fields(lines[len(lines)-1])
+	if col < 0 || len(fields) < len(header)-col {
+		return 0, fmt.Errorf("unexpected df output: %q", out)
+	}
+	value := fields[len(fields)-(len(header)-col)]
+	kb, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df available value %q", value)
+	}
+	return kb * 1024, nil
+}
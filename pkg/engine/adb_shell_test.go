@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"/sdcard/DCIM/IMG_1.jpg":      "'/sdcard/DCIM/IMG_1.jpg'",
+		"/sdcard/My Photos/a b.jpg":   "'/sdcard/My Photos/a b.jpg'",
+		"/sdcard/Bob's phone/$HOME;x": `'/sdcard/Bob'\''s phone/$HOME;x'`,
+	}
+	for in, want := range cases {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestIsADBDisconnect(t *testing.T) {
+	if !isADBDisconnect("adb: no devices/emulators found") || !isADBDisconnect("error: device offline") {
+		t.Errorf("expected disconnect messages to be recognised")
+	}
+	if isADBDisconnect("rm: /sdcard/x: Permission denied") {
+		t.Errorf("a failed command is not a disconnect")
+	}
+}
+
+func TestADBConnected(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake adb is a shell script")
+	}
+	// Only PHONE1 is attached; `adb devices` would still print a line containing "device"
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1 $2 $3\" = \"-s PHONE1 get-state\" ]; then echo device; exit 0; fi\n" +
+		"echo \"error: device '$2' not found\" >&2; exit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "adb"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	if !adbConnected(context.Background(), "PHONE1") {
+		t.Error("PHONE1 is attached")
+	}
+	if adbConnected(context.Background(), "PHONE2") {
+		t.Error("PHONE2 is not attached")
+	}
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// APKDirName is the folder of the destination EngineConfig.APKs backs apps up to: a folder
+// per app with its APKs (base.apk and any split APKs), and AppListFileName
+const APKDirName = "APKs"
+
+// AppListFileName is the list of the backed-up apps in APKDirName, with the command that
+// installs each one on a phone (see InstalledApp)
+const AppListFileName = "apps.json"
+
+// appListKind is the kind of export (see state.Export) the app list is recorded as
+const appListKind = "apps"
+
+// InstalledApp is an app in the app list
+type InstalledApp struct {
+	Package string   `json:"package"`
+	Version string   `json:"versionCode,omitempty"`
+	APKs    []string `json:"apks"` // relative to APKDirName, base.apk first
+	// Install installs the app on the phone adb is connected to, run from APKDirName
+	Install string `json:"install"`
+}
+
+// adbPM runs Android's package manager on the device; replaced in tests
+var adbPM = func(ctx context.Context, serial string, args ...string) ([]byte, error) {
+	return adbShellOn(ctx, serial, "pm", args...)
+}
+
+// pullAPK copies the APK at devicePath into destDir; replaced in tests
+var pullAPK = func(ctx context.Context, copier *ADBCopier, devicePath, destDir string) error {
+	_, err := copier.Copy(ctx, devicePath, path.Dir(devicePath), destDir, nil)
+	return err
+}
+
+// checkAPKs validates EngineConfig.APKs before a run
+func (e *Engine) checkAPKs() error {
+	if e.config.APKs && e.config.Mode != TransportADB {
+		return fmt.Errorf("backing up apps needs adb mode")
+	}
+	return nil
+}
+
+// backupAPKs copies the APKs of the apps installed by the user (not those that came with
+// the phone) to APKDirName and writes the app list. An APK already backed up from the same
+// place on the device and for the same version isn't copied again. Apps that can't be
+// copied are warnings; the files are backed up all the same.
+func (e *Engine) backupAPKs(ctx context.Context) {
+	if !e.config.APKs {
+		return
+	}
+	apps, err := installedApps(ctx, e.config.Serial)
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Could not list the installed apps: %v", err))
+		return
+	}
+	copier := NewADBCopier()
+	copier.SetTimeouts(e.config.copyTimeouts())
+	copier.SetSerial(e.config.Serial)
+
+	var list []InstalledApp
+	copied, failed := 0, 0
+	for _, app := range apps {
+		if ctx.Err() != nil {
+			return
+		}
+		n, err := e.backupApp(ctx, copier, &app)
+		copied += n
+		if err != nil {
+			failed++
+			e.log("warn", fmt.Sprintf("Could not back up app %s: %v", app.Package, err))
+			continue
+		}
+		list = append(list, app)
+	}
+	e.log("info", fmt.Sprintf("Apps: %d backed up (%d APKs copied), %d failed", len(list), copied, failed))
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err == nil {
+		_, _, err = e.writeExport(appListKind, path.Join(APKDirName, AppListFileName), data, len(list), time.Now())
+	}
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Could not write the app list: %v", err))
+	}
+}
+
+// backupApp copies the APKs of app (device paths in app.APKs, replaced by the copies' paths
+// relative to APKDirName) and returns how many it copied
+func (e *Engine) backupApp(ctx context.Context, copier *ADBCopier, app *InstalledApp) (int, error) {
+	// pm path also lists the split APKs; the base APK from pm list packages -f will do
+	// when it fails
+	sources := app.APKs
+	if out, err := adbPM(ctx, e.config.Serial, "path", app.Package); err == nil {
+		if paths := parsePMPaths(string(out)); len(paths) > 0 {
+			sources = paths
+		}
+	}
+	copied := 0
+	app.APKs = nil
+	for _, source := range sources {
+		rel := path.Join(app.Package, path.Base(source))
+		file := path.Join(APKDirName, rel)
+		dest := filepath.Join(e.config.DestRoot, filepath.FromSlash(file))
+		app.APKs = append(app.APKs, rel)
+		if e.stateManager != nil {
+			if last, ok := e.stateManager.BackedUpAPK(file); ok && last.Source == source && last.Version == app.Version {
+				if info, err := os.Stat(dest); err == nil && info.Size() == last.Size {
+					continue
+				}
+			}
+		}
+		if err := pullAPK(ctx, copier, source, filepath.Dir(dest)); err != nil {
+			return copied, err
+		}
+		hash, err := calculateFileHash(dest)
+		if err != nil {
+			return copied, err
+		}
+		info, err := os.Stat(dest)
+		if err != nil {
+			return copied, err
+		}
+		e.setOwnership(dest)
+		copied++
+		if e.stateManager != nil {
+			err := e.stateManager.MarkAPK(state.APK{Package: app.Package, Version: app.Version, Source: source,
+				File: file, Hash: hash, Size: info.Size(), At: time.Now()})
+			if err != nil {
+				return copied, err
+			}
+		}
+	}
+	if len(app.APKs) == 1 {
+		app.Install = "adb install " + app.APKs[0]
+	} else {
+		app.Install = "adb install-multiple " + strings.Join(app.APKs, " ")
+	}
+	return copied, nil
+}
+
+// installedApps lists the apps installed by the user, by package name
+func installedApps(ctx context.Context, serial string) ([]InstalledApp, error) {
+	out, err := adbPM(ctx, serial, "list", "packages", "-f", "-3", "--show-versioncode")
+	if err != nil || !strings.Contains(string(out), "package:") {
+		// Android 8 and older have no --show-versioncode
+		if out, err = adbPM(ctx, serial, "list", "packages", "-f", "-3"); err != nil {
+			return nil, err
+		}
+	}
+	apps := parsePMPackages(string(out))
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Package < apps[j].Package })
+	return apps, nil
+}
+
+// parsePMPackages parses the output of pm list packages -f [--show-versioncode], e.g.
+// "package:/data/app/~~Ab==/org.example.app-Cd==/base.apk=org.example.app versionCode:42"
+// (the path may contain "=", the package name can't)
+func parsePMPackages(out string) []InstalledApp {
+	var apps []InstalledApp
+	for _, line := range strings.Split(out, "\n") {
+		line, ok := strings.CutPrefix(strings.TrimSpace(line), "package:")
+		if !ok {
+			continue
+		}
+		line, version, _ := strings.Cut(line, " versionCode:")
+		i := strings.LastIndex(line, "=")
+		if i < 0 || i == len(line)-1 {
+			continue
+		}
+		apps = append(apps, InstalledApp{Package: line[i+1:], Version: strings.TrimSpace(version), APKs: []string{line[:i]}})
+	}
+	return apps
+}
+
+// parsePMPaths parses the output of pm path <package>: the base APK and any split APKs,
+// base.apk first
+func parsePMPaths(out string) []string {
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		if p, ok := strings.CutPrefix(strings.TrimSpace(line), "package:"); ok && strings.HasSuffix(p, ".apk") {
+			paths = append(paths, p)
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return path.Base(paths[i]) == "base.apk" && path.Base(paths[j]) != "base.apk" })
+	return paths
+}
+
+// verifyAPKs checks the backed-up APKs against the hashes recorded when they were copied.
+// The device isn't needed.
+func (e *Engine) verifyAPKs(ctx context.Context) VerifyResults {
+	var results VerifyResults
+	if e.stateManager == nil {
+		return results
+	}
+	progress := newVerifyProgress(0)
+	for _, apk := range e.stateManager.APKs() {
+		if ctx.Err() != nil {
+			break
+		}
+		results.Total++
+		results.Sampled++
+		dest := filepath.Join(e.config.DestRoot, filepath.FromSlash(apk.File))
+		hash, err := calculateFileHash(dest)
+		switch {
+		case os.IsNotExist(err):
+			results.MissingDest++
+			e.verifyIssue(progress, VerifyIssue{Kind: VerifyMissingDest, SourcePath: apk.Source})
+		case err != nil:
+			e.log("warn", fmt.Sprintf("Could not verify %s: %v", apk.File, err))
+		case hash != apk.Hash:
+			results.Mismatches++
+			e.verifyIssue(progress, VerifyIssue{Kind: VerifyMismatch, SourcePath: apk.Source, DestPath: dest})
+		default:
+			results.Verified++
+		}
+	}
+	results.Issues = progress.issues
+	return results
+}
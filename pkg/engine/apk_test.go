@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestParsePMPackages(t *testing.T) {
+	out := "package:/data/app/~~Ab==/org.example.app-Cd==/base.apk=org.example.app versionCode:42\r\n" +
+		"package:/data/app/com.old-1/base.apk=com.old\n" +
+		"garbage\n"
+	want := []InstalledApp{
+		{Package: "org.example.app", Version: "42", APKs: []string{"/data/app/~~Ab==/org.example.app-Cd==/base.apk"}},
+		{Package: "com.old", APKs: []string{"/data/app/com.old-1/base.apk"}},
+	}
+	if got := parsePMPackages(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePMPackages = %+v, want %+v", got, want)
+	}
+	paths := parsePMPaths("package:/data/app/x/split_config.en.apk\npackage:/data/app/x/base.apk\n")
+	if want := []string{"/data/app/x/base.apk", "/data/app/x/split_config.en.apk"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("parsePMPaths = %v, want %v", paths, want)
+	}
+}
+
+func TestBackupAPKs(t *testing.T) {
+	version := "42"
+	var pulls []string
+	savedPM, savedPull := adbPM, pullAPK
+	defer func() { adbPM, pullAPK = savedPM, savedPull }()
+	adbPM = func(ctx context.Context, serial string, args ...string) ([]byte, error) {
+		if serial != "PHONE1" {
+			return nil, fmt.Errorf("pm on device %q", serial)
+		}
+		switch strings.Join(args, " ") {
+		case "list packages -f -3 --show-versioncode":
+			return []byte(fmt.Sprintf("package:/data/app/b/org.example.app-1/base.apk=org.example.app versionCode:%s\n"+
+				"package:/data/app/a/com.broken-1/base.apk=com.broken versionCode:1\n", version)), nil
+		case "path org.example.app":
+			return []byte("package:/data/app/b/org.example.app-1/base.apk\npackage:/data/app/b/org.example.app-1/split_config.en.apk\n"), nil
+		case "path com.broken":
+			return nil, fmt.Errorf("adb shell pm failed")
+		}
+		return nil, fmt.Errorf("unexpected pm %v", args)
+	}
+	pullAPK = func(ctx context.Context, copier *ADBCopier, devicePath, destDir string) error {
+		if copier.serial != "PHONE1" {
+			return fmt.Errorf("pull from device %q", copier.serial)
+		}
+		if strings.Contains(devicePath, "com.broken") {
+			return fmt.Errorf("permission denied")
+		}
+		pulls = append(pulls, devicePath)
+		os.MkdirAll(destDir, 0755)
+		return os.WriteFile(filepath.Join(destDir, path.Base(devicePath)), []byte(devicePath+version), 0644)
+	}
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "backup")
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	e := NewEngine(EngineConfig{Mode: TransportADB, SourcePath: "/sdcard", DestRoot: dest, Reporter: discardReporter{}, APKs: true, Serial: "PHONE1"}, sm)
+	ctx := context.Background()
+
+	e.backupAPKs(ctx)
+	if len(pulls) != 2 {
+		t.Fatalf("pulled %v, want base and split APK", pulls)
+	}
+	var list []InstalledApp
+	data, _ := os.ReadFile(filepath.Join(dest, APKDirName, AppListFileName))
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatalf("app list %s: %v", data, err)
+	}
+	want := []InstalledApp{{Package: "org.example.app", Version: "42",
+		APKs:    []string{"org.example.app/base.apk", "org.example.app/split_config.en.apk"},
+		Install: "adb install-multiple org.example.app/base.apk org.example.app/split_config.en.apk"}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("app list = %+v, want %+v (without the app that failed)", list, want)
+	}
+	if apk, ok := sm.BackedUpAPK("APKs/org.example.app/base.apk"); !ok || apk.Version != "42" || apk.Source != "/data/app/b/org.example.app-1/base.apk" {
+		t.Errorf("state records %+v, %v", apk, ok)
+	}
+
+	// Same version: nothing copied again; an update is
+	pulls = nil
+	e.backupAPKs(ctx)
+	if len(pulls) != 0 {
+		t.Errorf("unchanged APKs copied again: %v", pulls)
+	}
+	version = "43"
+	e.backupAPKs(ctx)
+	if len(pulls) != 2 {
+		t.Errorf("updated app: pulled %v", pulls)
+	}
+
+	if results := e.verifyAPKs(ctx); results.Verified != 2 || len(results.Issues) != 0 {
+		t.Errorf("verify = %+v", results)
+	}
+	os.WriteFile(filepath.Join(dest, "APKs", "org.example.app", "base.apk"), []byte("corrupt"), 0644)
+	os.Remove(filepath.Join(dest, "APKs", "org.example.app", "split_config.en.apk"))
+	if results := e.verifyAPKs(ctx); results.Mismatches != 1 || results.MissingDest != 1 {
+		t.Errorf("verify after damage = %+v", results)
+	}
+
+	e.config.Mode = TransportMount
+	if err := e.checkAPKs(); err == nil {
+		t.Error("expected an error outside adb mode")
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AppPreset is a messenger whose chats and media are backed up as one unit (EngineConfig.Apps)
+type AppPreset struct {
+	Name  string // as given to -preset
+	Title string // for display
+	// Folders are where the app keeps its databases, media and backups, relative to the
+	// storage root. Since Android 11 (scoped storage) apps write under Android/media/<package>
+	// instead of a folder of their own at the top, so both layouts are listed; only the ones
+	// on the phone are scanned. Android/data is not readable since Android 11 and isn't listed.
+	Folders []string
+	// Note says what the unit does not cover, for the GUI
+	Note string
+}
+
+// AppPresets are the presets accepted by EngineConfig.Apps and the -preset flag
+var AppPresets = []AppPreset{
+	{
+		Name:  "whatsapp",
+		Title: "WhatsApp",
+		Folders: []string{
+			"Android/media/com.whatsapp/WhatsApp/Databases",
+			"Android/media/com.whatsapp/WhatsApp/Media",
+			"Android/media/com.whatsapp/WhatsApp/Backups",
+			"WhatsApp/Databases",
+			"WhatsApp/Media",
+			"WhatsApp/Backups",
+		},
+		Note: "The chat databases (msgstore*.crypt14/15) are encrypted: restoring them needs WhatsApp's key or end-to-end backup password.",
+	},
+	{
+		Name:  "whatsapp-business",
+		Title: "WhatsApp Business",
+		Folders: []string{
+			"Android/media/com.whatsapp.w4b/WhatsApp Business/Databases",
+			"Android/media/com.whatsapp.w4b/WhatsApp Business/Media",
+			"Android/media/com.whatsapp.w4b/WhatsApp Business/Backups",
+			"WhatsApp Business/Databases",
+			"WhatsApp Business/Media",
+			"WhatsApp Business/Backups",
+		},
+		Note: "The chat databases are encrypted, as for WhatsApp.",
+	},
+	{
+		Name:  "telegram",
+		Title: "Telegram",
+		Folders: []string{
+			"Android/media/org.telegram.messenger/Telegram",
+			"Telegram",
+			"Download/Telegram",
+		},
+		Note: "Chats are kept in Telegram's cloud; this saves the media and files downloaded to the phone.",
+	},
+	{
+		Name:  "signal",
+		Title: "Signal",
+		Folders: []string{
+			"Signal",
+			"Documents/Signal",
+			"Download/Signal",
+		},
+		Note: "Signal keeps messages in private storage: turn on Chats > Chat backups in Signal, pick one of these folders, and keep the 30-digit passphrase.",
+	},
+}
+
+// AppPresetNames returns the names of the AppPresets
+func AppPresetNames() []string {
+	names := make([]string, len(AppPresets))
+	for i, preset := range AppPresets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// LookupAppPreset returns the preset called name (case-insensitive)
+func LookupAppPreset(name string) (AppPreset, error) {
+	for _, preset := range AppPresets {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return preset, nil
+		}
+	}
+	return AppPreset{}, fmt.Errorf("unknown app %q (use %s)", name, strings.Join(AppPresetNames(), ", "))
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestAppFolders(t *testing.T) {
+	folders, err := AppFolders([]string{"Telegram", "", "telegram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Android/media/org.telegram.messenger/Telegram", "Telegram", "Download/Telegram"}; !reflect.DeepEqual(folders, want) {
+		t.Errorf("AppFolders = %v, want %v", folders, want)
+	}
+	if _, err := AppFolders([]string{"whatsapp", "viber"}); err == nil {
+		t.Error("expected an error for an unknown app")
+	}
+}
+
+func TestFilterApps(t *testing.T) {
+	f, err := NewFilter([]string{"*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPresets([]string{"photos"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetApps([]string{"whatsapp"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		// The app's databases and voice notes, which the Android/media allowlist drops
+		{"Android/media/com.whatsapp/WhatsApp/Databases/msgstore.db.crypt14", false},
+		{"Android/media/com.whatsapp/WhatsApp/Media/WhatsApp Voice Notes/202401/PTT-1.opus", false},
+		{"WhatsApp/Backups/chatsettingsbackup.db.crypt14", false},
+		// Built-in exclusions other than the allowlist, and the excludes, still apply
+		{"Android/media/com.whatsapp/WhatsApp/Media/.Statuses/cache/x.tmp", true},
+		{"WhatsApp/Media/WhatsApp Documents/notes.txt", true},
+		// Outside the app the usual rules hold
+		{"Android/media/org.telegram.messenger/Telegram/voice.opus", true},
+		{"Documents/cv.pdf", true},
+	}
+	for _, tt := range tests {
+		if got := f.defaultExcluded(tt.path) || f.Excluded(tt.path); got != tt.want {
+			t.Errorf("excluded(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	var none *Filter
+	if !none.defaultExcluded("Android/media/com.whatsapp/WhatsApp/Databases/msgstore.db.crypt14") {
+		t.Error("without apps the Android/media allowlist applies")
+	}
+}
+
+// errorReporter records the errors a run reports
+type errorReporter struct {
+	discardReporter
+	mu     sync.Mutex
+	errors []error
+}
+
+func (r *errorReporter) ReportError(err error) {
+	r.mu.Lock()
+	r.errors = append(r.errors, err)
+	r.mu.Unlock()
+}
+
+func TestAppsBackup(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	files := map[string]bool{
+		"Android/media/com.whatsapp/WhatsApp/Databases/msgstore.db.crypt14":   true,
+		"Android/media/com.whatsapp/WhatsApp/Media/WhatsApp Images/IMG-1.jpg": true,
+		"Signal/signal-2024-01-01-10-00-00.backup":                            true,
+		"DCIM/Camera/IMG_1.jpg": false,
+	}
+	for rel := range files {
+		path := filepath.Join(source, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(rel), 0644)
+	}
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	reporter := &errorReporter{}
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   dest,
+		NumWorkers: 1,
+		Reporter:   reporter,
+		Apps:       []string{"whatsapp", "signal"},
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for rel, want := range files {
+		_, err := os.Stat(filepath.Join(dest, filepath.FromSlash(rel)))
+		if got := err == nil; got != want {
+			t.Errorf("%s copied = %v, want %v", rel, got, want)
+		}
+	}
+	// The apps' folders the phone doesn't have (WhatsApp/Backups, Documents/Signal, ...)
+	// aren't errors
+	if len(reporter.errors) > 0 {
+		t.Errorf("errors: %v", reporter.errors)
+	}
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// attestationPrefix starts the name of every attestation written next to the backup
+const attestationPrefix = "gus_attestation_"
+
+// AttestationVersion is the format written by NewAttestation
+const AttestationVersion = 1
+
+// Attestation is a signed manifest of a whole backup: every backed-up file with its SHA-256,
+// and the root of a Merkle tree over them. The signature covers the root (and so every file),
+// so the backup can be proven intact offline, and an attestation edited to match a tampered
+// backup no longer verifies.
+type Attestation struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Files     []AttestedFile `json:"files"`     // sorted by path
+	Root      string         `json:"root"`      // hex Merkle root of Files (see MerkleRoot)
+	PublicKey string         `json:"publicKey"` // base64 ed25519 key the attestation was signed with
+	Signature string         `json:"signature"` // base64 ed25519 signature of the root and creation time
+}
+
+// AttestedFile is one file of an Attestation
+type AttestedFile struct {
+	Path string `json:"path"` // relative to the backup's destination directory, with '/'
+	Hash string `json:"hash"`
+	Size int64  `json:"size,omitempty"` // bytes (0 = unknown)
+}
+
+// backedUpFile is a file the state says is in the backup
+type backedUpFile struct {
+	rel  string // relative to the backup's destination directory
+	hash string
+	size int64
+}
+
+// backedUpFiles lists the backed-up files in sm. Files deleted from the source are still in
+// the backup; files whose copy was quarantined and not copied again, and old entries without
+// a recorded destination path, are left out.
+func backedUpFiles(sm *state.StateManager) []backedUpFile {
+	quarantined := make(map[string]state.QuarantineEntry)
+	for _, entry := range sm.GetQuarantined() {
+		quarantined[entry.SourcePath] = entry
+	}
+
+	var files []backedUpFile
+	for _, r := range sm.Records() {
+		if r.Status == state.StatusFailed || r.Hash == "" {
+			continue
+		}
+		if q, ok := quarantined[r.SourcePath]; ok && !r.BackedUpAt.After(q.At) {
+			continue
+		}
+		rel := DestRelPath(r.SourcePath, r.Path)
+		if rel == "" {
+			continue
+		}
+		files = append(files, backedUpFile{rel: rel, hash: r.Hash, size: r.Size})
+	}
+	return files
+}
+
+// NewAttestation signs a manifest of the files backed up according to sm
+func NewAttestation(sm *state.StateManager, key ed25519.PrivateKey, now time.Time) Attestation {
+	a := Attestation{Version: AttestationVersion, CreatedAt: now.UTC().Truncate(time.Second), Files: []AttestedFile{}}
+	for _, f := range backedUpFiles(sm) {
+		a.Files = append(a.Files, AttestedFile{Path: filepath.ToSlash(f.rel), Hash: f.hash, Size: f.size})
+	}
+	sort.Slice(a.Files, func(i, j int) bool { return a.Files[i].Path < a.Files[j].Path })
+	a.Root = MerkleRoot(a.Files)
+	a.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, a.signedMessage()))
+	return a
+}
+
+// signedMessage is what the signature covers
+func (a Attestation) signedMessage() []byte {
+	return []byte(fmt.Sprintf("gussync-attestation v%d\n%s\n%s\n", a.Version, a.CreatedAt.UTC().Format(time.RFC3339), a.Root))
+}
+
+// MerkleRoot returns the hex root of a binary Merkle tree whose leaves are the files in
+// order: a leaf is SHA-256(0x00 path 0x00 hash 0x00 size), a node SHA-256(0x01 left right),
+// and an odd node at the end of a level moves up unchanged. No files give the hash of nothing.
+func MerkleRoot(files []AttestedFile) string {
+	if len(files) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+	level := make([][]byte, len(files))
+	for i, f := range files {
+		sum := sha256.Sum256([]byte("\x00" + f.Path + "\x00" + f.Hash + "\x00" + strconv.FormatInt(f.Size, 10)))
+		level[i] = sum[:]
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h := sha256.New()
+			h.Write([]byte{1})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// Check makes sure the attestation is intact: its root matches its files and the signature
+// matches its root. With a trusted key it must also have been signed with that key (without
+// one, anyone able to rewrite the backup could sign a new attestation of their own).
+func (a Attestation) Check(trusted ed25519.PublicKey) error {
+	if a.Version != AttestationVersion {
+		return fmt.Errorf("unsupported attestation version %d", a.Version)
+	}
+	if root := MerkleRoot(a.Files); root != a.Root {
+		return fmt.Errorf("file list does not match the root hash (%s, recorded %s): the attestation was modified", root, a.Root)
+	}
+	key, err := base64.StdEncoding.DecodeString(a.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key in the attestation")
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil || !ed25519.Verify(key, a.signedMessage(), sig) {
+		return fmt.Errorf("signature does not match: the attestation was modified")
+	}
+	if trusted != nil && !trusted.Equal(ed25519.PublicKey(key)) {
+		return fmt.Errorf("signed with a different key (%s)", a.PublicKey)
+	}
+	return nil
+}
+
+// AttestationResults is what VerifyAttestation found
+type AttestationResults struct {
+	Verified   int
+	Missing    []string // paths of files no longer in the backup
+	Mismatched []string // paths of files whose content changed
+}
+
+// OK reports whether every file is present and unchanged
+func (r AttestationResults) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0
+}
+
+// VerifyAttestation hashes every file of the attestation under dir (the backup's
+// destination directory) and compares it with the attested hash. It does not Check the
+// attestation itself.
+func VerifyAttestation(ctx context.Context, a Attestation, dir string) (AttestationResults, error) {
+	var results AttestationResults
+	for _, f := range a.Files {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		hash, err := calculateFileHash(longPath(filepath.Join(dir, filepath.FromSlash(f.Path))))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			results.Missing = append(results.Missing, f.Path)
+		case err != nil:
+			return results, fmt.Errorf("failed to hash %s: %w", f.Path, err)
+		case hash != f.Hash:
+			results.Mismatched = append(results.Mismatched, f.Path)
+		default:
+			results.Verified++
+		}
+	}
+	return results, nil
+}
+
+// WriteAttestation saves a as gus_attestation_<time>.json in dir and returns its path
+func WriteAttestation(dir string, a Attestation) (string, error) {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, attestationPrefix+a.CreatedAt.Local().Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write attestation: %w", err)
+	}
+	return path, nil
+}
+
+// ReadAttestation loads an attestation written by WriteAttestation
+func ReadAttestation(path string) (Attestation, error) {
+	var a Attestation
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, fmt.Errorf("invalid attestation %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// LatestAttestation returns the newest attestation in dir
+func LatestAttestation(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, attestationPrefix+"*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no attestation in %s; create one with gussync attest", dir)
+	}
+	sort.Strings(matches) // the names sort by time
+	return matches[len(matches)-1], nil
+}
+
+// DefaultAttestKeyPath returns ~/.gussync/attest_key, kept off the backup so whoever can
+// write to the destination can't sign for it
+func DefaultAttestKeyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".gussync", "attest_key"), nil
+}
+
+// LoadAttestKey reads the signing key in path (a hex ed25519 seed). With create, a missing
+// key is generated and saved, readable only by the user.
+func LoadAttestKey(path string, create bool) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(seed)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to save signing key: %w", err)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid signing key in %s", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// attest writes the attestation of the run's backup when AttestKey is set
+func (e *Engine) attest() {
+	if e.config.AttestKey == "" {
+		return
+	}
+	key, err := LoadAttestKey(e.config.AttestKey, true)
+	if err != nil {
+		e.log("warn", fmt.Sprintf("No attestation written: %v", err))
+		return
+	}
+	a := NewAttestation(e.stateManager, key, time.Now())
+	path, err := WriteAttestation(e.config.DestRoot, a)
+	if err != nil {
+		e.log("warn", err.Error())
+		return
+	}
+	e.log("info", fmt.Sprintf("Attestation of %d files written to %s (root %s)", len(a.Files), path, a.Root))
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	files := []AttestedFile{{Path: "a", Hash: "1"}, {Path: "b", Hash: "2"}, {Path: "c", Hash: "3", Size: 5}}
+	root := MerkleRoot(files)
+	if root != MerkleRoot(append([]AttestedFile(nil), files...)) {
+		t.Error("root is not deterministic")
+	}
+	for i, changed := range [][]AttestedFile{
+		files[:2],
+		{{Path: "a", Hash: "1"}, {Path: "b", Hash: "2"}, {Path: "c", Hash: "4", Size: 5}},
+		{{Path: "a", Hash: "1"}, {Path: "b", Hash: "2"}, {Path: "c", Hash: "3", Size: 6}},
+		{{Path: "b", Hash: "2"}, {Path: "a", Hash: "1"}, {Path: "c", Hash: "3", Size: 5}},
+	} {
+		if MerkleRoot(changed) == root {
+			t.Errorf("change %d kept the root", i)
+		}
+	}
+	if len(MerkleRoot(nil)) != 64 {
+		t.Errorf("empty root = %q", MerkleRoot(nil))
+	}
+}
+
+func TestAttestation(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	for rel, content := range map[string]string{"DCIM/a.jpg": "aaa", "Download/b.pdf": "bbb"} {
+		path := filepath.Join(dir, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+		hash, _ := calculateFileHash(path)
+		sm.MarkCompleted(state.CompletedFile{SourcePath: "/sdcard/" + rel, Hash: hash, NormalizedPath: rel, Size: 3})
+	}
+	sm.RecordFailure("/sdcard/DCIM/never.jpg")
+
+	keyPath := filepath.Join(dir, "keys", "attest_key")
+	key, err := LoadAttestKey(keyPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := LoadAttestKey(keyPath, false); err != nil || !again.Equal(key) {
+		t.Fatalf("reloaded key differs: %v", err)
+	}
+
+	a := NewAttestation(sm, key, time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	path, err := WriteAttestation(dir, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := LatestAttestation(dir); err != nil || latest != path {
+		t.Errorf("LatestAttestation = %q, %v; want %q", latest, err, path)
+	}
+	read, err := ReadAttestation(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Files) != 2 || read.Files[0].Path != "DCIM/a.jpg" {
+		t.Errorf("files = %+v", read.Files)
+	}
+	trusted := key.Public().(ed25519.PublicKey)
+	if err := read.Check(trusted); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	results, err := VerifyAttestation(context.Background(), read, dir)
+	if err != nil || !results.OK() || results.Verified != 2 {
+		t.Errorf("VerifyAttestation = %+v, %v", results, err)
+	}
+
+	// A changed and a deleted copy
+	os.WriteFile(filepath.Join(dir, "DCIM/a.jpg"), []byte("xxx"), 0644)
+	os.Remove(filepath.Join(dir, "Download/b.pdf"))
+	results, _ = VerifyAttestation(context.Background(), read, dir)
+	if results.OK() || len(results.Mismatched) != 1 || len(results.Missing) != 1 {
+		t.Errorf("after tampering: %+v", results)
+	}
+
+	// An attestation edited to match
+	edited := read
+	edited.Files = append([]AttestedFile(nil), read.Files...)
+	edited.Files[0].Hash = strings.Repeat("0", 64)
+	if err := edited.Check(trusted); err == nil || !strings.Contains(err.Error(), "root hash") {
+		t.Errorf("edited files: %v", err)
+	}
+	edited.Root = MerkleRoot(edited.Files)
+	if err := edited.Check(trusted); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("edited root: %v", err)
+	}
+
+	// ...or re-signed with another key
+	other, _ := LoadAttestKey(filepath.Join(dir, "other_key"), true)
+	resigned := NewAttestation(sm, other, time.Now())
+	if err := resigned.Check(nil); err != nil {
+		t.Errorf("Check without a trusted key: %v", err)
+	}
+	if err := resigned.Check(trusted); err == nil || !strings.Contains(err.Error(), "different key") {
+		t.Errorf("other key: %v", err)
+	}
+}
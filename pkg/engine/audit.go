@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLogFileName is the audit log written into DestRoot when EngineConfig.Audit is set
+const AuditLogFileName = "gus_audit.jsonl"
+
+// Operations recorded in the audit log (AuditEvent.Op)
+const (
+	AuditCopyStart = "copy_start"
+	AuditCopyEnd   = "copy_end"
+	AuditRetry     = "retry"
+	AuditVerify    = "verify"
+	AuditDelete    = "delete"
+	AuditMove      = "move"    // a moved file was given the backed-up copy of its old path
+	AuditRestore   = "restore" // a backed-up copy was written back to the source
+	AuditMigrate   = "migrate" // a file was copied to a new phone (Path is where)
+)
+
+// Results recorded in the audit log (AuditEvent.Result)
+const (
+	AuditOK            = "ok"
+	AuditFailed        = "failed"
+	AuditCanceled      = "canceled"
+	AuditMissingSource = "missing_source"
+	AuditMissingDest   = "missing_dest"
+	AuditRepaired      = "repaired"    // verify: the copy was bad and was copied again
+	AuditQuarantined   = "quarantined" // verify: the copy was bad and was moved aside
+)
+
+// AuditEvent is one line of the audit log
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	Op         string    `json:"op"`
+	Path       string    `json:"path"`                 // source path
+	Dest       string    `json:"dest,omitempty"`       // relative to the destination directory
+	From       string    `json:"from,omitempty"`       // move: the copy reused, relative to the destination directory
+	Bytes      int64     `json:"bytes,omitempty"`      // copy_start: size if known; otherwise bytes copied or deleted
+	DurationMS int64     `json:"durationMs,omitempty"` // copy_end: from the first attempt to the last
+	Hash       string    `json:"hash,omitempty"`       // SHA-256 of the destination copy
+	Attempts   int       `json:"attempts,omitempty"`   // copy_end and retry: attempts so far
+	Result     string    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorCode  string    `json:"errorCode,omitempty"` // see ErrorCode
+}
+
+// openAudit opens the audit log if EngineConfig.Audit is set; the returned func closes it
+func (e *Engine) openAudit() func() {
+	if !e.config.Audit {
+		return func() {}
+	}
+	audit := newJSONL(e.config.DestRoot, AuditLogFileName)
+	e.auditLog = audit
+	return func() {
+		e.auditLog = nil
+		audit.close()
+	}
+}
+
+// audit records ev in the audit log, if there is one
+func (e *Engine) audit(ev AuditEvent) {
+	if e.auditLog == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if err := e.auditLog.append(ev); err != nil {
+		e.log("warn", err.Error())
+	}
+}
+
+// ReadAuditLog calls fn for each event in an audit log, oldest first. A missing log has no
+// events; lines that don't parse (a write torn by a crash) are skipped.
+func ReadAuditLog(path string, fn func(AuditEvent) error) error {
+	return readJSONL(path, func(line []byte) error {
+		var ev AuditEvent
+		if json.Unmarshal(line, &ev) != nil {
+			return nil
+		}
+		return fn(ev)
+	})
+}
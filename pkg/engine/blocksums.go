@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+const (
+	// spotCheckBlock is the size of the blocks commit compares with the file on disk
+	spotCheckBlock = 1 << 20
+	// spotChecks is how many blocks of a copy are read back: the first, the last and
+	// evenly spaced ones between them
+	spotChecks = 8
+)
+
+// blockSums hashes what is written to a .part file block by block, so commit can read a
+// few blocks back instead of the whole file
+type blockSums struct {
+	sums [][sha256.Size]byte
+	cur  hash.Hash
+	n    int // bytes of the current block hashed so far
+}
+
+// Write implements io.Writer
+func (b *blockSums) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if b.cur == nil {
+			b.cur = sha256.New()
+		}
+		k := min(len(p), spotCheckBlock-b.n)
+		b.cur.Write(p[:k])
+		b.n += k
+		p = p[k:]
+		if b.n == spotCheckBlock {
+			b.end()
+		}
+	}
+	return written, nil
+}
+
+// end records the hash of the current block
+func (b *blockSums) end() {
+	var sum [sha256.Size]byte
+	b.cur.Sum(sum[:0])
+	b.sums = append(b.sums, sum)
+	b.cur.Reset()
+	b.n = 0
+}
+
+// spotCheck reads a sample of the blocks of the file at path back from the disk and checks
+// they hold what was written
+func (b *blockSums) spotCheck(path string) error {
+	if b.n > 0 {
+		b.end()
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read back dest: %w", err)
+	}
+	defer f.Close()
+	dropCached(f)
+
+	n := len(b.sums)
+	checks := min(n, spotChecks)
+	h := sha256.New()
+	for i := 0; i < checks; i++ {
+		block := i
+		if n > spotChecks {
+			block = i * (n - 1) / (spotChecks - 1)
+		}
+		h.Reset()
+		if _, err := io.Copy(h, io.NewSectionReader(f, int64(block)*spotCheckBlock, spotCheckBlock)); err != nil {
+			return fmt.Errorf("failed to read back dest: %w", err)
+		}
+		if got := h.Sum(nil); string(got) != string(b.sums[block][:]) {
+			return fmt.Errorf("%w: block %d of the copy on disk (%s) is not what was written", ErrHashMismatch, block, shortHash(hex.EncodeToString(got)))
+		}
+	}
+	return nil
+}
+
+// readBackHash hashes the file at path as it is on the disk, not as the page cache has it
+func readBackHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	dropCached(f)
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
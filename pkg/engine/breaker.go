@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeoutBreaker is how many files in a row may time out before all workers are held
+// while the source is checked
+const DefaultTimeoutBreaker = 5
+
+// breakerCooldown is how long the workers stay held when the source still answers, to let a
+// struggling device (e.g. an MTP stack busy generating thumbnails) catch up
+var breakerCooldown = 30 * time.Second
+
+// timeoutBreaker stops the workers from burning through the queue marking every file timed out
+// when the source has stopped sending: after threshold timeouts in a row it holds all workers
+// before their next file while check looks at (and reconnects or remounts) the source, then
+// lets them go on. Files already being copied finish or time out on their own.
+type timeoutBreaker struct {
+	threshold int
+	check     func(ctx context.Context) // returns when copying may resume
+	ctx       context.Context
+	cancel    context.CancelFunc
+	checking  sync.WaitGroup
+
+	mu    sync.Mutex
+	held  chan struct{} // non-nil while the workers are held; closed when they may go on
+	trips int
+}
+
+// newTimeoutBreaker returns a breaker whose checks run until ctx ends or stop is called
+func newTimeoutBreaker(ctx context.Context, threshold int, check func(ctx context.Context)) *timeoutBreaker {
+	ctx, cancel := context.WithCancel(ctx)
+	return &timeoutBreaker{threshold: threshold, check: check, ctx: ctx, cancel: cancel}
+}
+
+// trip holds the workers and runs the check in the background; trips while held are ignored
+func (b *timeoutBreaker) trip() {
+	b.mu.Lock()
+	if b.held != nil {
+		b.mu.Unlock()
+		return
+	}
+	held := make(chan struct{})
+	b.held = held
+	b.trips++
+	b.checking.Add(1)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.checking.Done()
+		b.check(b.ctx)
+		b.mu.Lock()
+		b.held = nil
+		b.mu.Unlock()
+		close(held)
+	}()
+}
+
+// stop ends a check in progress and waits for it; nil-safe
+func (b *timeoutBreaker) stop() {
+	if b == nil {
+		return
+	}
+	b.cancel()
+	b.checking.Wait()
+}
+
+// holding reports whether the workers are held; false for a nil breaker (off)
+func (b *timeoutBreaker) holding() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.held != nil
+}
+
+// wait blocks while the workers are held; false if ctx ends first
+func (b *timeoutBreaker) wait(ctx context.Context) bool {
+	b.mu.Lock()
+	held := b.held
+	b.mu.Unlock()
+	if held == nil {
+		return true
+	}
+	select {
+	case <-held:
+		return ctx.Err() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// checkSourceAfterTimeouts is the breaker's check: a source that went away is waited for
+// (ReconnectWait) and a stale mount remounted (RemountStale); one that still answers gets
+// breakerCooldown to recover before copying resumes
+func (e *Engine) checkSourceAfterTimeouts(ctx context.Context) {
+	e.log("warn", fmt.Sprintf("%d files in a row timed out; holding all workers to check the source", e.breaker.threshold))
+	if e.reconnect != nil && e.reconnect.recover(ctx, ErrStalled) {
+		return // it was away and has come back
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := e.probeSource(ctx); err != nil {
+		if e.remountStale(ctx, err) {
+			return
+		}
+		e.log("error", fmt.Sprintf("Source is not responding after repeated timeouts: %v", err))
+	}
+	e.log("info", fmt.Sprintf("Resuming in %v", breakerCooldown))
+	sleepContext(ctx, breakerCooldown)
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestTimeoutBreaker(t *testing.T) {
+	release := make(chan struct{})
+	var checks atomic.Int32
+	ctx := context.Background()
+	b := newTimeoutBreaker(ctx, 3, func(context.Context) {
+		checks.Add(1)
+		<-release
+	})
+	if b.holding() || !b.wait(ctx) {
+		t.Fatal("a breaker that hasn't tripped must not hold the workers")
+	}
+
+	b.trip()
+	b.trip() // already holding: ignored
+	if !b.holding() {
+		t.Fatal("expected the workers to be held")
+	}
+	waited := make(chan bool)
+	go func() { waited <- b.wait(ctx) }()
+	select {
+	case <-waited:
+		t.Fatal("wait returned while the source is being checked")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if !<-waited || b.holding() {
+		t.Error("expected the workers to go on after the check")
+	}
+	if checks.Load() != 1 || b.trips != 1 {
+		t.Errorf("checks = %d, trips = %d; want one of each", checks.Load(), b.trips)
+	}
+
+	b.stop()
+
+	var off *timeoutBreaker
+	if off.holding() {
+		t.Error("a nil breaker holds nothing")
+	}
+	off.stop()
+}
+
+// timingOutCopier times out on every file, like a phone that stopped sending
+type timingOutCopier struct{ copies atomic.Int32 }
+
+func (c *timingOutCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	c.copies.Add(1)
+	return 0, fmt.Errorf("%w: no progress for 10s", ErrStalled)
+}
+
+func TestRunTripsTimeoutBreaker(t *testing.T) {
+	defer func(d time.Duration) { breakerCooldown = d }(breakerCooldown)
+	breakerCooldown = 0
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	for i := 0; i < 6; i++ {
+		os.WriteFile(filepath.Join(source, "DCIM", fmt.Sprintf("%d.jpg", i)), []byte("x"), 0644)
+	}
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	copier := &timingOutCopier{}
+	e := NewEngine(EngineConfig{
+		Mode:           TransportMount,
+		SourcePath:     source,
+		DestRoot:       filepath.Join(dir, "backup"),
+		NumWorkers:     1,
+		Reporter:       discardReporter{},
+		Copier:         copier,
+		Retry:          RetryPolicy{MaxAttempts: 1},
+		TimeoutBreaker: 2,
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if e.breaker == nil || e.breaker.trips == 0 {
+		t.Fatal("expected consecutive timeouts to trip the breaker")
+	}
+	if copier.copies.Load() != 6 {
+		t.Errorf("copied %d files, want all 6 tried once the source was checked", copier.copies.Load())
+	}
+}
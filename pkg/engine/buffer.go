@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxBufferSize is the largest copy buffer a bufferTuner tries
+const MaxBufferSize = 4 << 20
+
+const (
+	// bufferSample is how many bytes a bufferTuner measures at each buffer size
+	bufferSample = 32 << 20
+	// bufferMinFile is the smallest copy a bufferTuner measures: the time of smaller ones
+	// goes mostly into opening the files
+	bufferMinFile = 1 << 20
+	// bufferGain is how much faster copies must get for a bufferTuner to keep a larger buffer
+	bufferGain = 1.1
+)
+
+// bufferTuner picks the copy buffer size of a copier from the throughput it measures:
+// starting at BufferSize, it doubles the size while copies get at least bufferGain faster,
+// up to MaxBufferSize, and then keeps the best size. Safe for concurrent use by the workers.
+type bufferTuner struct {
+	mu       sync.Mutex
+	size     int // being measured, or settled on
+	best     int
+	bestRate float64 // bytes per second
+	bytes    int64   // measured at size so far
+	elapsed  time.Duration
+	settled  bool
+}
+
+func newBufferTuner() *bufferTuner {
+	return &bufferTuner{size: BufferSize}
+}
+
+// get returns the buffer size for the next copy
+func (t *bufferTuner) get() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.size
+}
+
+// record adds a copy of n bytes that took elapsed with a buffer of size bytes. Copies made
+// with another size than the one being measured are left out.
+func (t *bufferTuner) record(size int, n int64, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.settled || size != t.size || n < bufferMinFile || elapsed <= 0 {
+		return
+	}
+	t.bytes += n
+	t.elapsed += elapsed
+	if t.bytes < bufferSample {
+		return
+	}
+	rate := float64(t.bytes) / t.elapsed.Seconds()
+	t.bytes, t.elapsed = 0, 0
+	if rate > t.bestRate*bufferGain {
+		t.best, t.bestRate = t.size, rate
+		if t.size < MaxBufferSize {
+			t.size *= 2
+			return
+		}
+	}
+	t.size, t.settled = t.best, true
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferTuner(t *testing.T) {
+	tuner := newBufferTuner()
+	// Copies at 10 MB/s with 64 KB, twice as fast with 128 KB, no faster beyond
+	rates := map[int]float64{BufferSize: 10e6, 2 * BufferSize: 20e6, 4 * BufferSize: 21e6}
+	for i := 0; i < 100; i++ {
+		size := tuner.get()
+		n := int64(8 << 20)
+		tuner.record(size, n, time.Duration(float64(n)/rates[size]*float64(time.Second)))
+	}
+	if got := tuner.get(); got != 2*BufferSize || !tuner.settled {
+		t.Errorf("settled on %d (%v), want %d", got, tuner.settled, 2*BufferSize)
+	}
+
+	// Small files and copies made with an older size aren't measured
+	tuner = newBufferTuner()
+	for i := 0; i < 100; i++ {
+		tuner.record(BufferSize, 1000, time.Millisecond)
+		tuner.record(MaxBufferSize, 8<<20, time.Millisecond)
+	}
+	if tuner.get() != BufferSize {
+		t.Errorf("buffer tuned to %d from copies that should be left out", tuner.get())
+	}
+}
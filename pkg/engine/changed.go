@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Policies for files that changed on the source after they were backed up (EngineConfig.Changed)
+const (
+	ChangedOverwrite = "overwrite" // copy the new version over the backed-up one
+	ChangedKeepBoth  = "keep-both" // move the backed-up version aside with a date suffix, then copy the new one
+	ChangedReport    = "report"    // keep the backup as is and report the file in the error log
+)
+
+// ChangedPolicies returns the valid EngineConfig.Changed values
+func ChangedPolicies() []string {
+	return []string{ChangedOverwrite, ChangedKeepBoth, ChangedReport}
+}
+
+// sourceChanged reports whether a completed file differs from its backed-up version, and
+// how: by size, by modification time after the backup (mount mode) or, with ChangeRehash, by
+// content hash (mount mode). A missing backup copy is left to verification.
+func (e *Engine) sourceChanged(job FileJob, destPath string) (bool, string) {
+	dest, err := os.Stat(destPath)
+	if err != nil {
+		return false, ""
+	}
+	size, local := job.Size, e.sourceIsLocal()
+	var modified time.Time
+	if local {
+		info, err := os.Stat(job.SourcePath)
+		if err != nil {
+			return false, ""
+		}
+		size, modified = info.Size(), info.ModTime()
+	}
+	if size > 0 && size != dest.Size() {
+		return true, fmt.Sprintf("size %s -> %s", formatSize(dest.Size()), formatSize(size))
+	}
+	if !local {
+		return false, ""
+	}
+	if e.config.ChangeRehash {
+		recorded := e.stateManager.CompletedHash(job.SourcePath)
+		if hash, err := calculateFileHash(job.SourcePath); err == nil && recorded != "" && hash != recorded {
+			return true, "content hash differs"
+		}
+		return false, ""
+	}
+	if backedUp := e.backedUpAt(job.SourcePath); !backedUp.IsZero() && modified.After(backedUp.Add(time.Second)) {
+		return true, "modified " + modified.Format("2006-01-02 15:04")
+	}
+	return false, ""
+}
+
+// recopyChanged applies the Changed policy to a completed file and reports whether it should
+// be copied again
+func (e *Engine) recopyChanged(job FileJob, errorChan chan<- error) bool {
+	if e.config.Changed == "" {
+		return false
+	}
+	destPath := e.destPath(job.SourcePath, job.RelPath)
+	changed, how := e.sourceChanged(job, destPath)
+	if !changed {
+		return false
+	}
+	switch e.config.Changed {
+	case ChangedReport:
+		errorChan <- pathError(PhaseCopy, job.SourcePath, fmt.Errorf("%s: %w (%s); backup kept", job.RelPath, ErrChangedOnSource, how))
+		return false
+	case ChangedKeepBoth:
+		kept, err := keepVersion(destPath, e.backedUpAt(job.SourcePath))
+		if err != nil {
+			errorChan <- pathError(PhaseCopy, job.SourcePath, fmt.Errorf("%s: %w (%s); could not keep the backed-up version: %v", job.RelPath, ErrChangedOnSource, how, err))
+			return false
+		}
+		e.log("info", fmt.Sprintf("%s changed (%s); previous version kept as %s", job.RelPath, how, filepath.Base(kept)))
+	default:
+		e.log("info", fmt.Sprintf("%s changed (%s); copying the new version", job.RelPath, how))
+	}
+	return true
+}
+
+// keepVersion renames a backed-up file to name~<backup time>.ext (plus a counter if that is
+// taken) and returns the new path
+func keepVersion(path string, backedUp time.Time) (string, error) {
+	if backedUp.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		backedUp = info.ModTime()
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "~" + backedUp.Local().Format("20060102-150405")
+	target := base + ext
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			break
+		}
+		target = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	return target, os.Rename(path, target)
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestChangedPolicies(t *testing.T) {
+	for _, policy := range ChangedPolicies() {
+		t.Run(policy, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, "phone")
+			dest := filepath.Join(dir, "backup")
+			os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+			resized := filepath.Join(source, "DCIM", "resized.jpg")
+			edited := filepath.Join(source, "DCIM", "edited.jpg")
+			same := filepath.Join(source, "DCIM", "same.jpg")
+			for _, path := range []string{resized, edited, same} {
+				os.WriteFile(path, []byte("original"), 0644)
+			}
+
+			sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sm.Close()
+			config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, Reporter: discardReporter{}}
+			if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			// One file grows, one is edited in place (same size, newer mtime)
+			os.WriteFile(resized, []byte("a longer new version"), 0644)
+			os.WriteFile(edited, []byte("ORIGINAL"), 0644)
+			later := time.Now().Add(time.Hour)
+			os.Chtimes(edited, later, later)
+
+			config.Changed = policy
+			if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			read := func(rel string) string {
+				data, _ := os.ReadFile(filepath.Join(dest, "DCIM", rel))
+				return string(data)
+			}
+			kept, _ := filepath.Glob(filepath.Join(dest, "DCIM", "*~*.jpg"))
+			var reported int
+			ReadErrorLog(filepath.Join(dest, ErrorLogFileName), func(ev ErrorEvent) error {
+				if ev.Code == CodeChanged {
+					reported++
+				}
+				return nil
+			})
+
+			switch policy {
+			case ChangedReport:
+				if read("resized.jpg") != "original" || read("edited.jpg") != "original" || reported != 2 {
+					t.Errorf("report: backups %q %q, %d reported; want untouched and 2", read("resized.jpg"), read("edited.jpg"), reported)
+				}
+			case ChangedOverwrite, ChangedKeepBoth:
+				if read("resized.jpg") != "a longer new version" || read("edited.jpg") != "ORIGINAL" || reported != 0 {
+					t.Errorf("%s: backups %q %q, %d reported", policy, read("resized.jpg"), read("edited.jpg"), reported)
+				}
+				if want := map[string]int{ChangedOverwrite: 0, ChangedKeepBoth: 2}[policy]; len(kept) != want {
+					t.Errorf("%s: kept versions %v, want %d", policy, kept, want)
+				}
+				for _, path := range kept {
+					if data, _ := os.ReadFile(path); string(data) != "original" {
+						t.Errorf("kept version %s = %q", path, data)
+					}
+				}
+				if sm.CompletedHash(resized) != mustHash(t, resized) {
+					t.Errorf("the state should record the new version's hash")
+				}
+			}
+			if read("same.jpg") != "original" {
+				t.Errorf("unchanged file was touched")
+			}
+		})
+	}
+}
+
+func mustHash(t *testing.T, path string) string {
+	t.Helper()
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Checksum manifest formats accepted by WriteChecksums
+const (
+	ChecksumSHA256Sum = "sha256sum" // "<hash>  <path>", for sha256sum -c
+	ChecksumBSD       = "bsd"       // "SHA256 (<path>) = <hash>", for shasum -c and BSD sha256 -c
+)
+
+// WriteChecksums writes a checksum manifest of the backed-up files in sm, with paths
+// relative to the backup's destination directory, so the backup can be checked with
+// standard tools (cd <dest>/<mode> && sha256sum -c). Files whose copy was quarantined and
+// not copied again, and old entries without a recorded destination path, are left out.
+// It returns the number of files written.
+func WriteChecksums(w io.Writer, sm *state.StateManager, format string) (int, error) {
+	var formatLine func(hash, path string) string
+	switch format {
+	case ChecksumSHA256Sum:
+		formatLine = sha256sumLine
+	case ChecksumBSD:
+		formatLine = func(hash, path string) string {
+			return fmt.Sprintf("SHA256 (%s) = %s", path, hash)
+		}
+	default:
+		return 0, fmt.Errorf("unknown checksum format %q (use %s or %s)", format, ChecksumSHA256Sum, ChecksumBSD)
+	}
+
+	bw := bufio.NewWriter(w)
+	written := 0
+	for _, f := range backedUpFiles(sm) {
+		if _, err := fmt.Fprintln(bw, formatLine(f.hash, f.rel)); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, bw.Flush()
+}
+
+// sha256sumLine formats a line like GNU sha256sum: a name containing a backslash or newline
+// is escaped and the line starts with a backslash
+func sha256sumLine(hash, path string) string {
+	if !strings.ContainsAny(path, "\\\n\r") {
+		return hash + "  " + path
+	}
+	escaped := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(path)
+	return "\\" + hash + "  " + escaped
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestWriteChecksums(t *testing.T) {
+	sm, err := state.NewStateManager(filepath.Join(t.TempDir(), "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	sm.MarkDone("/run/user/1000/gvfs/mtp:host=X/Internal shared storage/DCIM/a.jpg", "aaa", "DCIM/a.jpg")
+	sm.MarkDone("/sdcard/Download/b.pdf", "bbb", "Download/b.pdf")
+	sm.MarkDeleted("/sdcard/Download/b.pdf", "bbb")
+	sm.MarkDone("/sdcard/DCIM/bad.jpg", "ccc", "DCIM/bad.jpg")
+	sm.MarkQuarantined("/sdcard/DCIM/bad.jpg", "_quarantine/DCIM/bad.jpg", "hash mismatch")
+	sm.MarkDone("/sdcard/DCIM/back\\slash.jpg", "ddd", "DCIM/back\\slash.jpg")
+	sm.RecordFailure("/sdcard/DCIM/never.jpg")
+
+	var out bytes.Buffer
+	n, err := WriteChecksums(&out, sm, ChecksumSHA256Sum)
+	if err != nil {
+		t.Fatalf("WriteChecksums: %v", err)
+	}
+	want := strings.Join([]string{
+		"aaa  Internal shared storage/DCIM/a.jpg",
+		"\\ddd  DCIM/back\\\\slash.jpg",
+		"bbb  Download/b.pdf",
+		"",
+	}, "\n")
+	if n != 3 || out.String() != want {
+		t.Errorf("WriteChecksums wrote %d lines:\n%s\nwant:\n%s", n, out.String(), want)
+	}
+
+	out.Reset()
+	if _, err := WriteChecksums(&out, sm, ChecksumBSD); err != nil || !strings.HasPrefix(out.String(), "SHA256 (Internal shared storage/DCIM/a.jpg) = aaa\n") {
+		t.Errorf("bsd format = %q, %v", out.String(), err)
+	}
+	if _, err := WriteChecksums(&out, sm, "md5"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,477 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CleanupResults contains results from the cleanup pass
+type CleanupResults struct {
+	Deleted        int                `json:"deleted"`
+	AlreadyDeleted int                `json:"alreadyDeleted"`
+	Failed         int                `json:"failed"`
+	Skipped        int                `json:"skipped"`
+	IOErrors       int                `json:"ioErrors"`
+	TooRecent      int                `json:"tooRecent"`         // backed up less than CleanupMinAge ago
+	Declined       int                `json:"declined"`          // verified but not confirmed for deletion (interactive)
+	FreedBytes     int64              `json:"freedBytes"`        // bytes deleted from the source
+	Planned        []CleanupCandidate `json:"planned,omitempty"` // dry run: verified files that would be deleted
+}
+
+// CleanupCandidate is a source file verified in the destination and eligible for deletion
+type CleanupCandidate struct {
+	SourcePath string    `json:"sourcePath"`
+	Size       int64     `json:"size"`
+	BackedUpAt time.Time `json:"backedUpAt"`
+}
+
+// CleanupOptions controls which files RunCleanup deletes
+type CleanupOptions struct {
+	// DryRun verifies files and reports them in CleanupResults.Planned without deleting anything
+	DryRun bool
+	// MinAge only deletes files backed up at least this long ago (0 = no limit)
+	MinAge time.Duration
+	// Confirm is asked once per source directory with the files about to be deleted;
+	// returning false keeps them (nil = delete without asking)
+	Confirm func(dir string, files []CleanupCandidate) bool
+	// FreeTarget stops deleting once the source has at least this many bytes free (0 = delete everything eligible)
+	FreeTarget int64
+	// Order picks which files go first when FreeTarget is set: CleanupLargestFirst (default) or CleanupOldestFirst
+	Order string
+	// Workers is how many files of a folder are verified and deleted at once (0 or 1 = one at
+	// a time); the source stays the bottleneck, but round trips to the device overlap
+	Workers int
+}
+
+// Cleanup orders for CleanupOptions.Order
+const (
+	CleanupLargestFirst = "largest"
+	CleanupOldestFirst  = "oldest"
+)
+
+type cleanupFile struct {
+	path, hash string
+}
+
+// cleanupSource is the source-side file access cleanup needs: a local mount, the device over
+// adb or the remote machine over ssh
+type cleanupSource interface {
+	// Stat returns the file size; a missing file is reported with os.ErrNotExist
+	Stat(ctx context.Context, path string) (size int64, isDir bool, err error)
+	Hash(ctx context.Context, path string) (string, error)
+	// Restore copies the file to the destination again
+	Restore(ctx context.Context, path string) error
+	Remove(ctx context.Context, path string) error
+	// FreeSpace returns the bytes available on the filesystem holding path
+	FreeSpace(ctx context.Context, path string) (int64, error)
+}
+
+// localSource accesses source files through the filesystem (mount mode)
+type localSource struct {
+	sourceRoot string
+	destRoot   string
+	names      destNamer
+}
+
+func (s localSource) Stat(ctx context.Context, path string) (int64, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false, err
+	}
+	return info.Size(), info.IsDir(), nil
+}
+
+func (s localSource) Hash(ctx context.Context, path string) (string, error) {
+	return calculateFileHash(path)
+}
+
+func (s localSource) Restore(ctx context.Context, path string) error {
+	if result := robustCopy(path, s.sourceRoot, s.destRoot, s.names, nil); !result.Success {
+		return fmt.Errorf("restore failed: %v", result.Error)
+	}
+	return nil
+}
+
+func (s localSource) Remove(ctx context.Context, path string) error {
+	return os.Remove(path)
+}
+
+func (s localSource) FreeSpace(ctx context.Context, path string) (int64, error) {
+	return DiskFree(path)
+}
+
+// cleanupSource returns how cleanup reaches the source files for the configured mode
+func (e *Engine) cleanupSource() cleanupSource {
+	switch e.config.Mode {
+	case TransportADB:
+		return adbSource{sourceRoot: e.config.SourcePath, destRoot: e.config.DestRoot, names: e.names, serial: e.config.Serial}
+	case TransportSSH:
+		src := newSSHSource(e.config.SSH, e.config.SourcePath, e.config.DestRoot)
+		src.copier.names = e.names
+		return src
+	case TransportSMB:
+		src := newSMBSource(e.config.SMB, e.config.SourcePath, e.config.DestRoot)
+		src.copier.names = e.names
+		return src
+	case TransportKDEConnect:
+		src := newKDEConnectSource(e.config.KDEConnect, e.config.SourcePath, e.config.DestRoot)
+		src.copier.fs.names = e.names
+		return src
+	}
+	return localSource{sourceRoot: e.config.SourcePath, destRoot: e.config.DestRoot, names: e.names}
+}
+
+// RunCleanup deletes source files that are verified in the destination, of each source root
+// (see ExtraSources) in turn
+func (e *Engine) RunCleanup(ctx context.Context) (CleanupResults, error) {
+	roots, err := e.sourceRoots()
+	if err != nil {
+		return CleanupResults{}, err
+	}
+	var results CleanupResults
+	err = e.forEachSource(ctx, roots, func(ctx context.Context) error {
+		rootResults, err := e.runCleanup(ctx)
+		results.add(rootResults)
+		return err
+	})
+	return results, err
+}
+
+// runCleanup cleans up the completed files under SourcePath (all of them for a single source)
+func (e *Engine) runCleanup(ctx context.Context) (CleanupResults, error) {
+	opts := e.config.Cleanup
+	completed := e.stateManager.GetStats()
+
+	if e.config.Reporter != nil {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup: Found %d completed files in state", completed))
+	}
+
+	if completed == 0 {
+		if e.config.Reporter != nil {
+			e.config.Reporter.ReportLog("info", "Cleanup: No completed files to process")
+		}
+		return CleanupResults{}, nil
+	}
+
+	var results CleanupResults
+	source := e.source
+	if source == nil {
+		source = e.cleanupSource()
+		if closer, ok := source.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+	var eligible []cleanupFile
+
+	e.stateManager.IterateCompleted(func(path, hash string) bool {
+		switch {
+		case e.inOtherSource(path):
+		case e.stateManager.IsDeleted(path):
+			results.AlreadyDeleted++
+		case !e.stateManager.ShouldRetryCleanup(path):
+			results.Skipped++
+		case opts.MinAge > 0 && time.Since(e.backedUpAt(path)) < opts.MinAge:
+			results.TooRecent++
+		default:
+			eligible = append(eligible, cleanupFile{path, hash})
+		}
+		return ctx.Err() == nil
+	})
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	totalToProcess := len(eligible)
+
+	if e.config.Reporter != nil {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup: Processing %d files (skipped %d already deleted, %d failed too many times)",
+			totalToProcess, results.AlreadyDeleted, results.Skipped))
+		if results.TooRecent > 0 {
+			e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup: Keeping %d files backed up less than %s ago", results.TooRecent, opts.MinAge))
+		}
+	}
+
+	defer e.openAudit()()
+	progress := &cleanupProgress{total: totalToProcess, lastReport: time.Now()}
+	if opts.FreeTarget > 0 {
+		if err := e.cleanupUntilFree(ctx, source, eligible, &results, progress); err != nil {
+			return results, err
+		}
+	} else if err := e.cleanupBatch(ctx, source, eligible, &results, progress); err != nil {
+		return results, err
+	}
+
+	// Final report
+	if e.config.Reporter != nil {
+		e.config.Reporter.ReportProgress(ProgressUpdate{
+			TotalFiles:   totalToProcess,
+			Completed:    results.Deleted,
+			Failed:       results.Failed,
+			Skipped:      results.Skipped,
+			ScanComplete: true,
+		})
+		if opts.DryRun {
+			var bytes int64
+			for _, c := range results.Planned {
+				bytes += c.Size
+			}
+			e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup dry run: %d files (%.1f MB) would be deleted", len(results.Planned), float64(bytes)/1024/1024))
+		} else {
+			e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup complete: %d deleted, %d failed, %d skipped",
+				results.Deleted, results.Failed, results.Skipped))
+		}
+	}
+
+	return results, nil
+}
+
+type cleanupProgress struct {
+	total      int
+	lastReport time.Time
+}
+
+// cleanupBatch verifies and deletes files directory by directory, so interactive
+// confirmation can batch per folder
+func (e *Engine) cleanupBatch(ctx context.Context, source cleanupSource, files []cleanupFile, results *CleanupResults, progress *cleanupProgress) error {
+	opts := e.config.Cleanup
+	filesByDir := make(map[string][]cleanupFile)
+	for _, file := range files {
+		dir := filepath.Dir(file.path)
+		filesByDir[dir] = append(filesByDir[dir], file)
+	}
+	dirs := make([]string, 0, len(filesByDir))
+	for dir, files := range filesByDir {
+		dirs = append(dirs, dir)
+		sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		select {
+		case <-ctx.Done():
+			return context.Canceled
+		default:
+		}
+
+		// Report progress periodically
+		if e.config.Reporter != nil && time.Since(progress.lastReport) > 2*time.Second {
+			e.config.Reporter.ReportProgress(ProgressUpdate{
+				TotalFiles: progress.total,
+				Completed:  results.Deleted,
+				Failed:     results.Failed,
+				Skipped:    results.Skipped,
+			})
+			progress.lastReport = time.Now()
+		}
+
+		verified, err := e.verifyForCleanup(ctx, source, filesByDir[dir], results)
+		if err != nil {
+			return err
+		}
+		if len(verified) == 0 {
+			continue
+		}
+
+		if opts.DryRun {
+			results.Planned = append(results.Planned, verified...)
+			continue
+		}
+		if opts.Confirm != nil && !opts.Confirm(dir, verified) {
+			results.Declined += len(verified)
+			continue
+		}
+
+		var mu sync.Mutex
+		err = e.cleanupPool(ctx, len(verified), func(i int) error {
+			file := verified[i]
+			err := source.Remove(ctx, file.SourcePath)
+			if IsCritical(err) {
+				return err
+			}
+			hash := e.stateManager.CompletedHash(file.SourcePath)
+			ev := AuditEvent{Op: AuditDelete, Path: file.SourcePath, Bytes: file.Size, Hash: hash, Result: AuditOK}
+			if err != nil {
+				ev.Result, ev.Error, ev.ErrorCode = AuditFailed, err.Error(), ErrorCode(err)
+			}
+			e.audit(ev)
+			if err != nil {
+				e.stateManager.RecordCleanupFailure(file.SourcePath)
+				mu.Lock()
+				results.Failed++
+				mu.Unlock()
+				return nil
+			}
+			e.stateManager.MarkDeleted(file.SourcePath, hash)
+			mu.Lock()
+			defer mu.Unlock()
+			results.Deleted++
+			results.FreedBytes += file.Size
+			if e.config.Reporter != nil && results.Deleted%10 == 0 {
+				e.config.Reporter.ReportLog("info", fmt.Sprintf("Deleted %d files so far...", results.Deleted))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupPool calls fn for the files 0 to n-1 on CleanupOptions.Workers goroutines and
+// returns the first error (a lost connection), after which no new files are started
+func (e *Engine) cleanupPool(ctx context.Context, n int, fn func(i int) error) error {
+	workers := min(max(e.config.Cleanup.Workers, 1), n)
+	var next atomic.Int64
+	var stopped atomic.Bool
+	var firstErr error
+	var once sync.Once
+	work := func() {
+		for !stopped.Load() {
+			i := int(next.Add(1) - 1)
+			if i >= n {
+				return
+			}
+			err := ctx.Err()
+			if err == nil {
+				err = fn(i)
+			}
+			if err != nil {
+				once.Do(func() { firstErr = err })
+				stopped.Store(true)
+			}
+		}
+	}
+	if workers <= 1 {
+		work()
+		return firstErr
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer e.recoverPanic("cleanup worker")
+			work()
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// verifyForCleanup checks that each file's source and destination copies still match the
+// recorded hash (restoring a missing destination copy first) and returns the ones safe to
+// delete. It fails only if the source connection is lost. A dry run changes neither the
+// destination nor the state.
+func (e *Engine) verifyForCleanup(ctx context.Context, source cleanupSource, files []cleanupFile, results *CleanupResults) ([]CleanupCandidate, error) {
+	dryRun := e.config.Cleanup.DryRun
+	checked := make([]*CleanupCandidate, len(files))
+	var mu sync.Mutex
+	count := func(n *int) {
+		mu.Lock()
+		*n++
+		mu.Unlock()
+	}
+	err := e.cleanupPool(ctx, len(files), func(i int) error {
+		sourcePath := files[i].path
+		expectedHash := files[i].hash
+
+		// Stat check
+		size, isDir, err := source.Stat(ctx, sourcePath)
+		if err != nil {
+			if IsCritical(err) {
+				return err
+			}
+			if errors.Is(err, os.ErrNotExist) {
+				count(&results.Skipped)
+				return nil
+			}
+			count(&results.IOErrors)
+			return nil
+		}
+
+		if isDir {
+			count(&results.Skipped)
+			return nil
+		}
+
+		// Determine destination path
+		relPath, _ := filepath.Rel(e.config.SourcePath, sourcePath)
+		destPath := e.destPath(sourcePath, relPath)
+
+		// Check destination
+		restore := false
+		if _, err := os.Stat(destPath); os.IsNotExist(err) {
+			// Restore if missing (as in original logic); a dry run only says it would
+			if dryRun {
+				restore = true
+			} else if err := source.Restore(ctx, sourcePath); err != nil {
+				if IsCritical(err) {
+					return err
+				}
+				e.stateManager.RecordCleanupFailure(sourcePath)
+				count(&results.Failed)
+				return nil
+			}
+		}
+
+		// Verify hashes
+		sourceHash, err2 := source.Hash(ctx, sourcePath)
+		if IsCritical(err2) {
+			return err2
+		}
+		// The restored copy would be the source file itself
+		destHash, err1 := sourceHash, err2
+		if !restore {
+			destHash, err1 = e.hashLocal(destPath)
+		}
+
+		if err1 == nil && err2 == nil && sourceHash == expectedHash && destHash == expectedHash {
+			checked[i] = &CleanupCandidate{
+				SourcePath: sourcePath,
+				Size:       size,
+				BackedUpAt: e.backedUpAt(sourcePath),
+			}
+			if restore {
+				e.log("info", fmt.Sprintf("Cleanup dry run: %s would be copied to the destination again before deleting", sourcePath))
+			}
+		} else {
+			if !dryRun {
+				e.stateManager.RecordCleanupFailure(sourcePath)
+			}
+			count(&results.Failed)
+		}
+		return nil
+	})
+
+	var verified []CleanupCandidate
+	for _, c := range checked {
+		if c != nil {
+			verified = append(verified, *c)
+		}
+	}
+	return verified, err
+}
+
+// backedUpAt returns when a file was backed up. State from older versions has no
+// timestamps; the destination copy's mtime (set at copy time) is used instead.
+func (e *Engine) backedUpAt(sourcePath string) time.Time {
+	if t := e.stateManager.BackedUpAt(sourcePath); !t.IsZero() {
+		return t
+	}
+	relPath, err := filepath.Rel(e.config.SourcePath, sourcePath)
+	if err != nil {
+		return time.Time{}
+	}
+	if info, err := os.Stat(e.destPath(sourcePath, relPath)); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
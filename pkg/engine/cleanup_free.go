@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cleanupUntilFree deletes verified files in CleanupOptions.Order until the source has
+// FreeTarget bytes available. Files are picked in rounds sized to the remaining shortfall,
+// so files that fail verification are replaced by the next candidates.
+func (e *Engine) cleanupUntilFree(ctx context.Context, source cleanupSource, files []cleanupFile, results *CleanupResults, progress *cleanupProgress) error {
+	opts := e.config.Cleanup
+	free, err := source.FreeSpace(ctx, e.config.SourcePath)
+	if err != nil {
+		return fmt.Errorf("cannot determine free space on %s: %w", e.config.SourcePath, err)
+	}
+	need := opts.FreeTarget - free
+	if need <= 0 {
+		e.log("info", fmt.Sprintf("Cleanup: %s already has %s free (target %s), nothing to delete", e.config.SourcePath, formatSize(free), formatSize(opts.FreeTarget)))
+		return nil
+	}
+	e.log("info", fmt.Sprintf("Cleanup: %s free, deleting %s first to free %s", formatSize(free), opts.Order, formatSize(need)))
+
+	candidates := e.orderForFreeing(files, opts.Order)
+	for len(candidates) > 0 {
+		remaining := need - results.FreedBytes - plannedBytes(results.Planned)
+		if remaining <= 0 {
+			break
+		}
+
+		// Take just enough candidates to cover what is still missing
+		var round []cleanupFile
+		var roundBytes int64
+		for len(candidates) > 0 && roundBytes < remaining {
+			round = append(round, candidates[0].file)
+			roundBytes += candidates[0].size
+			candidates = candidates[1:]
+		}
+		if err := e.cleanupBatch(ctx, source, round, results, progress); err != nil {
+			return err
+		}
+	}
+
+	if !opts.DryRun {
+		if after, err := source.FreeSpace(ctx, e.config.SourcePath); err == nil {
+			e.log("info", fmt.Sprintf("Cleanup: %s now has %s free (target %s)", e.config.SourcePath, formatSize(after), formatSize(opts.FreeTarget)))
+		}
+	}
+	if freed := results.FreedBytes + plannedBytes(results.Planned); freed < need {
+		e.log("warn", fmt.Sprintf("Cleanup: could only free %s of %s; no more verified files to delete", formatSize(freed), formatSize(need)))
+	}
+	return nil
+}
+
+type sizedCleanupFile struct {
+	file cleanupFile
+	size int64
+}
+
+// orderForFreeing sorts files for free-space cleanup. Sizes come from the destination
+// copies (identical once verified), so no per-file round trip to the device is needed.
+func (e *Engine) orderForFreeing(files []cleanupFile, order string) []sizedCleanupFile {
+	sized := make([]sizedCleanupFile, 0, len(files))
+	for _, file := range files {
+		var size int64
+		if relPath, err := filepath.Rel(e.config.SourcePath, file.path); err == nil {
+			if info, err := os.Stat(e.destPath(file.path, relPath)); err == nil {
+				size = info.Size()
+			}
+		}
+		sized = append(sized, sizedCleanupFile{file, size})
+	}
+
+	if order == CleanupOldestFirst {
+		backedUp := make(map[string]int64, len(sized))
+		for _, f := range sized {
+			backedUp[f.file.path] = e.backedUpAt(f.file.path).UnixNano()
+		}
+		sort.SliceStable(sized, func(i, j int) bool {
+			if backedUp[sized[i].file.path] != backedUp[sized[j].file.path] {
+				return backedUp[sized[i].file.path] < backedUp[sized[j].file.path]
+			}
+			return sized[i].file.path < sized[j].file.path
+		})
+	} else {
+		sort.SliceStable(sized, func(i, j int) bool {
+			if sized[i].size != sized[j].size {
+				return sized[i].size > sized[j].size
+			}
+			return sized[i].file.path < sized[j].file.path
+		})
+	}
+	return sized
+}
+
+func plannedBytes(planned []CleanupCandidate) int64 {
+	var total int64
+	for _, c := range planned {
+		total += c.Size
+	}
+	return total
+}
@@ -0,0 +1,336 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupCleanup creates backed-up files in two source directories and returns an engine for them
+func setupCleanup(t *testing.T, opts CleanupOptions) (*Engine, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	sourceRoot := filepath.Join(dir, "phone")
+	destRoot := filepath.Join(dir, "backup")
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sm.Close() })
+
+	var sources []string
+	for _, rel := range []string{"DCIM/a.jpg", "DCIM/b.jpg", "Download/c.pdf"} {
+		src := filepath.Join(sourceRoot, rel)
+		dst := filepath.Join(destRoot, rel)
+		for _, p := range []string{src, dst} {
+			os.MkdirAll(filepath.Dir(p), 0755)
+			if err := os.WriteFile(p, []byte("content of "+rel), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, _ := calculateFileHash(src)
+		sm.MarkDone(src, hash, rel)
+		sources = append(sources, src)
+	}
+
+	e := NewEngine(EngineConfig{SourcePath: sourceRoot, DestRoot: destRoot, Cleanup: opts}, sm)
+	return e, sources
+}
+
+func TestCleanupDryRun(t *testing.T) {
+	e, sources := setupCleanup(t, CleanupOptions{DryRun: true})
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != 0 || len(results.Planned) != len(sources) {
+		t.Fatalf("dry run results = %+v, want %d planned and nothing deleted", results, len(sources))
+	}
+	for _, src := range sources {
+		if _, err := os.Stat(src); err != nil {
+			t.Errorf("dry run deleted %s", src)
+		}
+	}
+	if results.Planned[0].Size == 0 {
+		t.Errorf("planned files should carry their size")
+	}
+}
+
+func TestCleanupDryRunHasNoSideEffects(t *testing.T) {
+	e, sources := setupCleanup(t, CleanupOptions{DryRun: true})
+	stateFile := filepath.Join(filepath.Dir(e.config.DestRoot), "gus_state.md")
+	// A lost destination copy and a source file that no longer matches its backup
+	missing := filepath.Join(e.config.DestRoot, "DCIM", "a.jpg")
+	os.Remove(missing)
+	os.WriteFile(sources[2], []byte("edited"), 0644)
+	e.stateManager.Flush()
+	before, _ := os.ReadFile(stateFile)
+
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Planned) != 2 || results.Failed != 1 {
+		t.Errorf("dry run results = %+v, want a.jpg and b.jpg planned and c.pdf failed", results)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("a dry run copied a.jpg back to the destination")
+	}
+	e.stateManager.Flush()
+	if after, _ := os.ReadFile(stateFile); string(after) != string(before) {
+		t.Errorf("a dry run changed the state file:\n%s", after)
+	}
+}
+
+func TestCleanupMinAge(t *testing.T) {
+	e, sources := setupCleanup(t, CleanupOptions{MinAge: 30 * 24 * time.Hour})
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != 0 || results.TooRecent != len(sources) {
+		t.Fatalf("results = %+v, want all %d files kept as too recent", results, len(sources))
+	}
+}
+
+func TestCleanupConfirmPerDirectory(t *testing.T) {
+	var asked []string
+	e, sources := setupCleanup(t, CleanupOptions{Confirm: func(dir string, files []CleanupCandidate) bool {
+		asked = append(asked, filepath.Base(dir))
+		return filepath.Base(dir) == "Download"
+	}})
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 2 || asked[0] != "DCIM" || asked[1] != "Download" {
+		t.Errorf("confirm asked for %v, want [DCIM Download]", asked)
+	}
+	if results.Deleted != 1 || results.Declined != 2 {
+		t.Fatalf("results = %+v, want 1 deleted and 2 declined", results)
+	}
+	if _, err := os.Stat(sources[0]); err != nil {
+		t.Errorf("declined file %s was deleted", sources[0])
+	}
+	if _, err := os.Stat(sources[2]); !os.IsNotExist(err) {
+		t.Errorf("confirmed file %s was not deleted", sources[2])
+	}
+}
+
+// fakeSource is a device whose files live in memory (like a phone reached over adb)
+type fakeSource struct {
+	files   map[string][]byte
+	removed []string
+	offline bool
+	free    int64 // grows as files are removed
+}
+
+func (f *fakeSource) Stat(ctx context.Context, path string) (int64, bool, error) {
+	if f.offline {
+		return 0, false, ErrConnectionLost
+	}
+	data, ok := f.files[path]
+	if !ok {
+		return 0, false, os.ErrNotExist
+	}
+	return int64(len(data)), false, nil
+}
+
+func (f *fakeSource) Hash(ctx context.Context, path string) (string, error) {
+	sum := sha256.Sum256(f.files[path])
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (f *fakeSource) Restore(ctx context.Context, path string) error {
+	return errors.New("not supported")
+}
+
+func (f *fakeSource) Remove(ctx context.Context, path string) error {
+	f.free += int64(len(f.files[path]))
+	delete(f.files, path)
+	f.removed = append(f.removed, path)
+	return nil
+}
+
+func TestCleanupRemoteSource(t *testing.T) {
+	e, sources := setupCleanup(t, CleanupOptions{})
+	device := &fakeSource{files: make(map[string][]byte)}
+	for _, src := range sources {
+		data, _ := os.ReadFile(src)
+		device.files[src] = data
+	}
+	// One file changed on the device since the backup: it must be kept
+	device.files[sources[1]] = []byte("edited on phone")
+	e.source = device
+
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != 2 || results.Failed != 1 || len(device.removed) != 2 {
+		t.Fatalf("results = %+v, removed %v; want 2 deleted and the edited file kept", results, device.removed)
+	}
+	if !e.stateManager.IsDeleted(sources[0]) || e.stateManager.IsDeleted(sources[1]) {
+		t.Errorf("deletions not recorded correctly in state")
+	}
+	if e.stateManager.ShouldRetryCleanup(sources[1]) != true {
+		t.Errorf("a single mismatch should still allow a later cleanup retry")
+	}
+
+	// Losing the device aborts the run instead of counting every file as failed
+	e2, _ := setupCleanup(t, CleanupOptions{})
+	e2.source = &fakeSource{offline: true}
+	if _, err := e2.RunCleanup(context.Background()); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("offline device: err = %v, want ErrConnectionLost", err)
+	}
+}
+
+// poolSource is a fakeSource safe for concurrent use that records how many calls overlap
+type poolSource struct {
+	mu sync.Mutex
+	*fakeSource
+	inFlight, maxInFlight int
+}
+
+func (p *poolSource) call(fn func()) {
+	p.mu.Lock()
+	p.inFlight++
+	p.maxInFlight = max(p.maxInFlight, p.inFlight)
+	p.mu.Unlock()
+	time.Sleep(10 * time.Millisecond) // a round trip to the device
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	fn()
+}
+
+func (p *poolSource) Stat(ctx context.Context, path string) (size int64, isDir bool, err error) {
+	p.call(func() { size, isDir, err = p.fakeSource.Stat(ctx, path) })
+	return
+}
+
+func (p *poolSource) Hash(ctx context.Context, path string) (hash string, err error) {
+	p.call(func() { hash, err = p.fakeSource.Hash(ctx, path) })
+	return
+}
+
+func (p *poolSource) Remove(ctx context.Context, path string) (err error) {
+	p.call(func() { err = p.fakeSource.Remove(ctx, path) })
+	return
+}
+
+func TestCleanupWorkers(t *testing.T) {
+	e, sources := setupCleanup(t, CleanupOptions{Workers: 4})
+	// More files in one folder, so there is something to run side by side
+	for i := 0; i < 6; i++ {
+		rel := filepath.Join("DCIM", fmt.Sprintf("extra%d.jpg", i))
+		src := filepath.Join(e.config.SourcePath, rel)
+		os.WriteFile(src, []byte(rel), 0644)
+		os.WriteFile(filepath.Join(e.config.DestRoot, rel), []byte(rel), 0644)
+		hash, _ := calculateFileHash(src)
+		e.stateManager.MarkDone(src, hash, rel)
+		sources = append(sources, src)
+	}
+	device := &poolSource{fakeSource: &fakeSource{files: make(map[string][]byte)}}
+	for _, src := range sources {
+		data, _ := os.ReadFile(src)
+		device.files[src] = data
+	}
+	e.source = device
+
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != len(sources) || results.Failed != 0 || len(device.removed) != len(sources) {
+		t.Fatalf("results = %+v, removed %d; want all %d deleted", results, len(device.removed), len(sources))
+	}
+	for _, src := range sources {
+		if !e.stateManager.IsDeleted(src) {
+			t.Errorf("%s: deletion not recorded", src)
+		}
+	}
+	if device.maxInFlight < 2 || device.maxInFlight > 4 {
+		t.Errorf("up to %d device calls at once, want 2 to 4", device.maxInFlight)
+	}
+
+	// Losing the device still aborts the run
+	e2, _ := setupCleanup(t, CleanupOptions{Workers: 4})
+	e2.source = &poolSource{fakeSource: &fakeSource{offline: true}}
+	if _, err := e2.RunCleanup(context.Background()); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("offline device: err = %v, want ErrConnectionLost", err)
+	}
+}
+
+func (f *fakeSource) FreeSpace(ctx context.Context, path string) (int64, error) {
+	return f.free, nil
+}
+
+func TestCleanupUntilFree(t *testing.T) {
+	dir := t.TempDir()
+	sourceRoot := filepath.Join(dir, "phone")
+	destRoot := filepath.Join(dir, "backup")
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	device := &fakeSource{files: make(map[string][]byte), free: 100}
+	sizes := map[string]int{"DCIM/small.jpg": 100, "DCIM/video.mp4": 5000, "Movies/clip.mp4": 3000, "Download/doc.pdf": 800}
+	for rel, size := range sizes {
+		src := filepath.Join(sourceRoot, rel)
+		dst := filepath.Join(destRoot, rel)
+		data := make([]byte, size)
+		os.MkdirAll(filepath.Dir(dst), 0755)
+		os.WriteFile(dst, data, 0644)
+		device.files[src] = data
+		sum := sha256.Sum256(data)
+		sm.MarkDone(src, hex.EncodeToString(sum[:]), rel)
+	}
+
+	// Need 6000 free: the 5000 byte video alone is not enough, the 3000 byte clip is next
+	e := NewEngine(EngineConfig{SourcePath: sourceRoot, DestRoot: destRoot, Cleanup: CleanupOptions{FreeTarget: 6000}}, sm)
+	e.source = device
+	results, err := e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != 2 || results.FreedBytes != 8000 {
+		t.Fatalf("results = %+v, want the two largest files deleted", results)
+	}
+	if _, ok := device.files[filepath.Join(sourceRoot, "DCIM/small.jpg")]; !ok {
+		t.Errorf("small file deleted although the target was already met")
+	}
+
+	// Target already met: nothing more is deleted
+	results, err = e.RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Deleted != 0 {
+		t.Errorf("second run deleted %d files with the target already met", results.Deleted)
+	}
+}
+
+func TestParseDFAvailable(t *testing.T) {
+	toybox := "Filesystem     1K-blocks    Used Available Use% Mounted on\n/dev/fuse      113160336 81234560  31925776  72% /storage/emulated\n"
+	if got, err := parseDFAvailable(toybox); err != nil || got != 31925776*1024 {
+		t.Errorf("toybox df = %d, %v", got, err)
+	}
+	wrapped := "Filesystem           1K-blocks      Used Available Use% Mounted on\n/dev/block/very/long/device/name\n                      1000      400       600  40% /sdcard\n"
+	if got, err := parseDFAvailable(wrapped); err != nil || got != 600*1024 {
+		t.Errorf("wrapped df = %d, %v", got, err)
+	}
+	if _, err := parseDFAvailable("df: /sdcard: No such file"); err == nil {
+		t.Errorf("expected an error for unexpected output")
+	}
+}
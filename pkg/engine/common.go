@@ -7,8 +7,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // PriorityPaths are common Android paths that should be processed first
@@ -30,17 +33,63 @@ var PriorityPaths = []string{
 }
 
 const (
-	// StallTimeout is the duration to wait for bytes before considering a transfer stalled
+	// StallTimeout is the default duration to wait for bytes before considering a transfer stalled
 	StallTimeout = 30 * time.Second
-	// BufferSize for copying
+	// BufferSize is the default size of the copy buffer, where tuning starts (see
+	// EngineConfig.BufferSize)
 	BufferSize = 64 * 1024 // 64KB
 	// ProgressUpdateInterval is how often to report progress
 	ProgressUpdateInterval = 2 * time.Second
 )
 
+// NormalizeScanRoots validates folder selections (relative to the source root) and
+// drops duplicates and folders already covered by a selected parent
+func NormalizeScanRoots(roots []string) ([]string, error) {
+	cleaned := make([]string, 0, len(roots))
+	for _, r := range roots {
+		r = strings.Trim(filepath.ToSlash(filepath.Clean(strings.TrimSpace(r))), "/")
+		if r == "" || r == "." {
+			// Selecting the root means everything
+			return nil, nil
+		}
+		if r == ".." || strings.HasPrefix(r, "../") {
+			return nil, fmt.Errorf("scan root %q is outside the source", r)
+		}
+		cleaned = append(cleaned, r)
+	}
+
+	result := make([]string, 0, len(cleaned))
+	for _, r := range cleaned {
+		covered := false
+		for _, other := range cleaned {
+			if other != r && strings.HasPrefix(r, other+"/") {
+				covered = true
+				break
+			}
+		}
+		duplicate := false
+		for _, existing := range result {
+			if existing == r {
+				duplicate = true
+				break
+			}
+		}
+		if !covered && !duplicate {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
 // shouldExcludeFile determines if a file should be excluded from backup
 // Returns true if the file should be skipped
 func shouldExcludeFile(normalizedPath string) bool {
+	return defaultExclusion(normalizedPath, true)
+}
+
+// defaultExclusion implements shouldExcludeFile; without mediaAllowlist, files under
+// Android/media are kept whatever their type (the AppPresets' folders, see Filter.SetApps)
+func defaultExclusion(normalizedPath string, mediaAllowlist bool) bool {
 	// Extract file extension
 	ext := strings.ToLower(filepath.Ext(normalizedPath))
 	// Remove leading dot
@@ -124,7 +173,7 @@ func shouldExcludeFile(normalizedPath string) bool {
 	
 	// 5. Extension allowlist for Android/media (if we got here and path is Android/media)
 	// Only allow specific media/document extensions
-	if strings.HasPrefix(fullPathLower, "android/media/") {
+	if mediaAllowlist && strings.HasPrefix(fullPathLower, "android/media/") {
 		allowedExts := map[string]bool{
 			// Images
 			"jpg": true, "jpeg": true, "png": true, "heic": true, "webp": true,
@@ -163,13 +212,26 @@ func calculateFileHash(filePath string) (string, error) {
 }
 
 // normalizePhonePath extracts the actual phone path from protocol-specific mount paths
-// Returns the logical path on the phone, protocol-agnostic
+// Returns the logical path on the phone, protocol-agnostic: relative to the volume the file is
+// on (see phoneVolume) when the source root is above it, e.g. the MTP device or /storage. The
+// path is in Unicode NFC, so a name doesn't depend on how the phone happened to store it; the
+// original form stays in the state as the source path.
 func normalizePhonePath(sourcePath, sourceRoot string) (string, error) {
 	// Calculate relative path from source root
 	relPath, err := filepath.Rel(sourceRoot, sourcePath)
 	if err != nil {
 		return "", err
 	}
+	relPath = norm.NFC.String(relPath)
+
+	if volume, ok := phoneVolume(sourcePath); ok {
+		volumeRoot := filepath.FromSlash(volume.Root)
+		if filepath.Clean(volumeRoot) != filepath.Clean(sourceRoot) && isUnder(volumeRoot, sourceRoot) {
+			rel, err := filepath.Rel(volumeRoot, sourcePath)
+			return norm.NFC.String(rel), err
+		}
+		return relPath, nil
+	}
 
 	// Protocol-specific path prefixes to strip:
 	if strings.HasPrefix(relPath, "Internal shared storage/") {
@@ -181,6 +243,29 @@ func normalizePhonePath(sourcePath, sourceRoot string) (string, error) {
 	return relPath, nil
 }
 
+// DestRelPath returns where a completed file was copied, relative to the backup's destination
+// directory, from its source path and the normalized path recorded in the state file. It undoes
+// normalizePhonePath's stripping of the MTP storage folder; "" means the entry predates
+// normalized paths.
+func DestRelPath(sourcePath, normalizedPath string) string {
+	if normalizedPath == "" {
+		return ""
+	}
+	// Entries written before paths were normalized may be in another form
+	sourcePath, normalizedPath = norm.NFC.String(sourcePath), norm.NFC.String(normalizedPath)
+	if volume, ok := phoneVolume(sourcePath); ok && volume.Storage != "" {
+		if strings.HasSuffix(filepath.ToSlash(sourcePath), "/"+volume.Storage+"/"+normalizedPath) {
+			return volume.Storage + "/" + normalizedPath
+		}
+	}
+	for _, prefix := range []string{"Internal shared storage/", "SD card/"} {
+		if strings.HasSuffix(sourcePath, "/"+prefix+normalizedPath) {
+			return prefix + normalizedPath
+		}
+	}
+	return normalizedPath
+}
+
 // formatSize formats bytes as human-readable size
 func formatSize(bytes int64) string {
 	const unit = 1024
@@ -195,6 +280,38 @@ func formatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// FormatSize formats bytes as a human-readable size (e.g. "3.2 GB")
+func FormatSize(bytes int64) string {
+	return formatSize(bytes)
+}
+
+// ParseSize parses a size such as "20G", "500MB", "1.5T" or a plain byte count
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:n-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 20G or 500MB)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
 // CopyResult represents the result of a copy operation
 type CopyResult struct {
 	Success     bool
@@ -206,6 +323,11 @@ type CopyResult struct {
 
 // RobustCopy copies a file with stall detection and hash verification
 func RobustCopy(sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) *CopyResult {
+	return robustCopy(sourcePath, sourceRoot, destRoot, destNamer{}, progressChan)
+}
+
+// robustCopy is RobustCopy naming the copy with names (see EngineConfig.DestNames)
+func robustCopy(sourcePath, sourceRoot, destRoot string, names destNamer, progressChan chan<- int64) *CopyResult {
 	result := &CopyResult{}
 
 	normalizedPath, err := normalizePhonePath(sourcePath, sourceRoot)
@@ -214,7 +336,7 @@ func RobustCopy(sourcePath, sourceRoot, destRoot string, progressChan chan<- int
 		return result
 	}
 
-	destPath := filepath.Join(destRoot, normalizedPath)
+	destPath := names.path(destRoot, sourcePath, normalizedPath)
 	destDir := filepath.Dir(destPath)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		result.Error = fmt.Errorf("failed to create dest dir: %w", err)
@@ -228,39 +350,30 @@ func RobustCopy(sourcePath, sourceRoot, destRoot string, progressChan chan<- int
 	}
 	defer sourceFile.Close()
 
-	destFile, err := os.Create(destPath)
+	// Write through <dest>.part, renamed into place once the copy on disk is read back
+	// and matches what was read from the source
+	part, err := openPart(destPath, nil)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create dest: %w", err)
+		result.Error = err
 		return result
 	}
-	defer destFile.Close()
+	part.readBack = true
 
-	result.BytesCopied, result.Error = copyWithTimeout(sourceFile, destFile, StallTimeout, progressChan, nil)
+	result.BytesCopied, result.Error = copyWithTimeout(part.source(sourceFile), part, stallLimits(StallTimeout), progressChan, nil)
 	if result.Error != nil {
+		part.discard()
 		return result
 	}
 
-	if err := destFile.Sync(); err != nil {
-		result.Error = fmt.Errorf("failed to sync dest: %w", err)
-		return result
-	}
-
-	result.SourceHash, err = calculateFileHash(sourcePath)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to hash source: %w", err)
-		return result
-	}
-
-	result.DestHash, err = calculateFileHash(destPath)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to hash dest: %w", err)
-		return result
-	}
+	// Hashed as it was read, so the source is read once
+	result.SourceHash = hex.EncodeToString(part.read.Sum(nil))
 
-	if result.SourceHash != result.DestHash {
-		result.Error = fmt.Errorf("hash mismatch: source=%s, dest=%s", result.SourceHash, result.DestHash)
+	if err := part.commit(result.BytesCopied); err != nil {
+		result.Error = err
 		return result
 	}
+	// commit read the copy back and found this hash
+	result.DestHash = part.sum
 
 	result.Success = true
 	return result
@@ -86,3 +86,21 @@ func TestFormatSize(t *testing.T) {
 	}
 }
 
+
+func TestNormalizeScanRoots(t *testing.T) {
+	roots, err := NormalizeScanRoots([]string{"/DCIM/", "DCIM/Camera", "Pictures", "Pictures"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roots) != 2 || roots[0] != "DCIM" || roots[1] != "Pictures" {
+		t.Errorf("expected [DCIM Pictures], got %v", roots)
+	}
+
+	if roots, _ := NormalizeScanRoots([]string{"DCIM", "."}); roots != nil {
+		t.Errorf("selecting the root should mean everything, got %v", roots)
+	}
+
+	if _, err := NormalizeScanRoots([]string{"../etc"}); err == nil {
+		t.Errorf("expected error for path outside source")
+	}
+}
@@ -2,7 +2,9 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"sync"
 	"time"
@@ -14,16 +16,24 @@ type CopyStats struct {
 	Skipped     bool
 	IsTimeout   bool
 	BytesCopied int64
+	RelPath     string // relative to the source, for the per-folder figures
+	// For the run report: the file, how long it took (retries included) and why it failed
+	SourcePath string
+	Duration   time.Duration
+	Err        error
 }
 
 // ConnectionChecker is a function that checks if the connection is still alive
 // Returns error if connection is dead, nil if connection is alive
 type ConnectionChecker func() error
 
-// copyWithTimeout copies data with stall detection and progress reporting
-func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progressChan chan<- int64, connChecker ConnectionChecker) (int64, error) {
+// copyWithTimeout copies data with stall and minimum throughput detection (see CopyTimeouts)
+// and progress reporting
+func copyWithTimeout(src io.Reader, dst io.Writer, limits transferLimits, progressChan chan<- int64, connChecker ConnectionChecker) (int64, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	watch := newTransferWatch(limits, time.Now())
+	var abandoned error // why the watch gave up on the copy
 
 	// Track progress atomically
 	prog := &progressTracker{
@@ -33,7 +43,7 @@ func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progre
 	}
 
 	// Progress checker and reporter goroutine
-	done := make(chan bool)
+	done := make(chan bool, 1) // the checker may have returned already, after giving up on the copy
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
 		progressTicker := time.NewTicker(ProgressUpdateInterval)
@@ -57,13 +67,11 @@ func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progre
 					}
 				}
 			case <-ticker.C:
-				// Check for stalls: if bytes haven't increased in timeout period
+				// Check for stalls and a rate below the minimum
 				prog.Lock()
 				currentBytes := prog.bytes
-				timeSinceLastUpdate := time.Since(prog.lastTime)
-
-				// If bytes haven't changed AND enough time has passed, consider it stalled
-				if currentBytes == prog.lastBytes && timeSinceLastUpdate > timeout {
+				if err := watch.check(currentBytes, time.Now()); err != nil {
+					abandoned = err
 					prog.Unlock()
 					cancel()
 					return
@@ -101,7 +109,11 @@ func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progre
 	// Perform the copy
 	var totalBytes int64
 	var err error
-	totalBytes, err = io.Copy(dst, progressReader)
+	buffer := limits.buffer
+	if buffer <= 0 {
+		buffer = BufferSize
+	}
+	totalBytes, err = io.CopyBuffer(dst, progressReader, make([]byte, buffer))
 
 	done <- true
 
@@ -113,7 +125,13 @@ func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progre
 		}
 	}
 
+	prog.Lock()
+	reason := abandoned
+	prog.Unlock()
 	if err != nil {
+		if reason != nil && errors.Is(err, ErrStalled) {
+			return totalBytes, reason
+		}
 		return totalBytes, err
 	}
 
@@ -126,15 +144,139 @@ func copyWithTimeout(src io.Reader, dst io.Writer, timeout time.Duration, progre
 		// Check if it was a connection issue or stall
 		if connChecker != nil {
 			if err := connChecker(); err != nil {
-				return totalBytes, fmt.Errorf("connection lost during copy: %w", err)
+				return totalBytes, fmt.Errorf("%w during copy: %v", ErrConnectionLost, err)
 			}
 		}
-		return totalBytes, fmt.Errorf("copy stalled: no progress for %v", elapsed)
+		if reason != nil {
+			return totalBytes, reason
+		}
+		return totalBytes, fmt.Errorf("%w: no progress for %v", ErrStalled, elapsed)
 	default:
 		return totalBytes, nil
 	}
 }
 
+// DefaultChunkSize is the chunk size suggested for chunked copies (EngineConfig.ChunkSize)
+const DefaultChunkSize = 8 << 20
+
+// ChunkRetries is how many times a chunk is read again after a failed read or checksum
+// before the whole copy fails
+const ChunkRetries = 3
+
+// chunkRetryDelay is the pause before a chunk's first retry; it grows with each retry
+var chunkRetryDelay = 500 * time.Millisecond
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ChunkProgress is the progress of a chunked copy
+type ChunkProgress struct {
+	Done    int // chunks written and checked
+	Total   int
+	Retries int // chunk reads repeated after a failed read or checksum
+}
+
+// copyChunked copies size bytes of src to dst in chunks of chunkSize, from start (a chunk
+// boundary; 0 unless resuming a copy whose earlier chunks are in dst). Each chunk is read
+// into memory with the stall detection of copyWithTimeout, written, read back and compared by
+// CRC-32C checksum; a chunk whose read fails with a transient error (anything but a lost
+// connection) or whose copy doesn't match is read again from its offset, up to ChunkRetries
+// times, so a bad read costs one chunk rather than the whole file. progressChan gets the
+// bytes copied so far, onChunk (optional) each chunk checked or retried.
+func copyChunked(ctx context.Context, src io.ReaderAt, dst interface {
+	io.WriterAt
+	io.ReaderAt
+}, start, size, chunkSize int64, limiter *RateLimiter, limits transferLimits, progressChan chan<- int64, connChecker ConnectionChecker, onChunk func(ChunkProgress)) (int64, error) {
+	progress := ChunkProgress{Done: int(start / chunkSize), Total: int((size + chunkSize - 1) / chunkSize)}
+	buf := make([]byte, 0, chunkSize)
+	check := make([]byte, chunkSize)
+	var copied int64
+	for offset := start; offset < size; offset += chunkSize {
+		n := min(chunkSize, size-offset)
+		for retry := 0; ; retry++ {
+			err := copyChunk(ctx, src, dst, offset, n, buf, check[:n], limiter, limits, copied, progressChan, connChecker)
+			if err == nil {
+				break
+			}
+			if retry >= ChunkRetries || ctx.Err() != nil || errors.Is(err, ErrConnectionLost) || !(isTransient(err) || errors.Is(err, errChunkChecksum)) {
+				return copied, fmt.Errorf("chunk %d of %d (at byte %d): %w", progress.Done+1, progress.Total, offset, err)
+			}
+			progress.Retries++
+			if onChunk != nil {
+				onChunk(progress)
+			}
+			if err := sleepContext(ctx, time.Duration(retry+1)*chunkRetryDelay); err != nil {
+				return copied, err
+			}
+		}
+		copied += n
+		progress.Done++
+		if onChunk != nil {
+			onChunk(progress)
+		}
+	}
+	return copied, nil
+}
+
+// errChunkChecksum means a chunk read back from the destination doesn't match what was read
+var errChunkChecksum = errors.New("chunk checksum mismatch")
+
+// copyChunk copies the n bytes of src at offset to the same offset of dst and checks them.
+// Progress is reported as base plus the bytes of this chunk read so far.
+func copyChunk(ctx context.Context, src io.ReaderAt, dst interface {
+	io.WriterAt
+	io.ReaderAt
+}, offset, n int64, buf, check []byte, limiter *RateLimiter, limits transferLimits, base int64, progressChan chan<- int64, connChecker ConnectionChecker) error {
+	var chunkProgress chan int64
+	forwarded := make(chan struct{})
+	if progressChan != nil {
+		chunkProgress = make(chan int64, 10)
+		go func() {
+			defer close(forwarded)
+			for bytes := range chunkProgress {
+				select {
+				case progressChan <- base + bytes:
+				default:
+				}
+			}
+		}()
+	} else {
+		close(forwarded)
+	}
+	chunk := &chunkBuffer{data: buf[:0]}
+	read, err := copyWithTimeout(limitReader(ctx, io.NewSectionReader(src, offset, n), limiter), chunk, limits, chunkProgress, connChecker)
+	if chunkProgress != nil {
+		close(chunkProgress)
+	}
+	<-forwarded
+	if err != nil {
+		return err
+	}
+	if read != n {
+		return fmt.Errorf("%w: read %d of %d bytes", io.ErrUnexpectedEOF, read, n)
+	}
+	sum := crc32.Checksum(chunk.data, crc32c)
+	if _, err := dst.WriteAt(chunk.data, offset); err != nil {
+		return err
+	}
+	if _, err := dst.ReadAt(check, offset); err != nil && err != io.EOF {
+		return err
+	}
+	if crc32.Checksum(check, crc32c) != sum {
+		return errChunkChecksum
+	}
+	return nil
+}
+
+// chunkBuffer collects a chunk in a buffer allocated once per copy
+type chunkBuffer struct {
+	data []byte
+}
+
+func (b *chunkBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
 // progressTracker tracks copy progress for stall detection and reporting
 type progressTracker struct {
 	lastTime     time.Time
@@ -156,12 +298,12 @@ func (pr *progressReader) Read(p []byte) (n int, err error) {
 	// This allows us to abort the read immediately when stalled
 	select {
 	case <-pr.ctx.Done():
-		// Context cancelled (stall detected) - return error to abort io.Copy
-		// Use "stalled" in error message so worker recognizes it as a timeout
+		// Context cancelled (stall detected) - return ErrStalled to abort io.Copy
+		// so the worker classifies it as a timeout
 		pr.progress.Lock()
 		elapsed := time.Since(pr.progress.lastTime)
 		pr.progress.Unlock()
-		return 0, fmt.Errorf("copy stalled: no progress for %v", elapsed)
+		return 0, fmt.Errorf("%w: no progress for %v", ErrStalled, elapsed)
 	default:
 		// Context not cancelled, proceed with read
 	}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// flakyReaderAt fails the first reads at one offset with an I/O error, like a bad MTP read
+type flakyReaderAt struct {
+	*bytes.Reader
+	badOffset int64
+	failures  int
+}
+
+func (r *flakyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == r.badOffset && r.failures > 0 {
+		r.failures--
+		return 0, &os.PathError{Op: "read", Path: "video.mp4", Err: syscall.EIO}
+	}
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestCopyChunked(t *testing.T) {
+	saved := chunkRetryDelay
+	chunkRetryDelay = 0
+	defer func() { chunkRetryDelay = saved }()
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	for _, tc := range []struct {
+		failures int
+		wantErr  bool
+	}{
+		{failures: 2},
+		{failures: ChunkRetries + 1, wantErr: true},
+	} {
+		dst, err := os.Create(filepath.Join(t.TempDir(), "video.mp4"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := &flakyReaderAt{Reader: bytes.NewReader(data), badOffset: 4096, failures: tc.failures}
+		var last ChunkProgress
+		progressChan := make(chan int64, 100)
+		n, err := copyChunked(context.Background(), src, dst, 0, int64(len(data)), 4096, nil, stallLimits(StallTimeout), progressChan, nil, func(p ChunkProgress) { last = p })
+		dst.Close()
+
+		if tc.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "chunk 2 of 3") || n != 4096 {
+				t.Errorf("after %d bad reads: %d bytes, %v; want the copy to fail at chunk 2", tc.failures, n, err)
+			}
+			continue
+		}
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("copyChunked = %d, %v", n, err)
+		}
+		if got, _ := os.ReadFile(dst.Name()); !bytes.Equal(got, data) {
+			t.Errorf("copy differs from the source")
+		}
+		if last != (ChunkProgress{Done: 3, Total: 3, Retries: 2}) {
+			t.Errorf("last chunk progress = %+v", last)
+		}
+		var reported int64
+		for len(progressChan) > 0 {
+			reported = max(reported, <-progressChan)
+		}
+		if reported != int64(len(data)) {
+			t.Errorf("progress reached %d bytes, want %d", reported, len(data))
+		}
+	}
+}
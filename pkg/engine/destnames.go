@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"GusSync/pkg/state"
+)
+
+// Destination name schemes, for EngineConfig.DestNames. Phone file names may hold characters
+// Windows (NTFS, exFAT) rejects, such as ':' or '?', or be reserved there, such as "CON.txt";
+// a scheme rewrites those names so the copy can be written. The scheme a backup uses is
+// recorded in its state, and later runs keep it.
+const (
+	// DestNamesAuto is DestNamesPercent on Windows and DestNamesNone elsewhere
+	DestNamesAuto = ""
+	// DestNamesNone keeps file names as they are on the phone
+	DestNamesNone = "none"
+	// DestNamesPercent writes each rejected character as %XX, e.g. "a:b.txt" as "a%3Ab.txt".
+	// '%' itself is kept, so names without rejected characters never change.
+	DestNamesPercent = "percent"
+	// DestNamesUnicode writes rejected characters as their full-width look-alikes, e.g.
+	// "a:b.txt" as "a：b.txt", which reads the same in a file manager
+	DestNamesUnicode = "unicode"
+)
+
+// DestNameSchemes lists the valid values of EngineConfig.DestNames (besides DestNamesAuto)
+var DestNameSchemes = []string{DestNamesNone, DestNamesPercent, DestNamesUnicode}
+
+// validDestNames reports an unknown scheme
+func validDestNames(scheme string) error {
+	if scheme == DestNamesAuto {
+		return nil
+	}
+	for _, s := range DestNameSchemes {
+		if scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown destination name scheme %q (use %s)", scheme, strings.Join(DestNameSchemes, ", "))
+}
+
+// resolveDestNames returns the scheme DestNamesAuto stands for on this system
+func resolveDestNames(scheme string) string {
+	if scheme != DestNamesAuto {
+		return scheme
+	}
+	if runtime.GOOS == "windows" {
+		return DestNamesPercent
+	}
+	return DestNamesNone
+}
+
+// windowsReserved are the device names Windows won't create a file as, with any extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// escapeDestName rewrites one file or folder name for the scheme: characters Windows rejects
+// (<>:"\|?* and control characters), trailing dots and spaces, which Windows drops, and the
+// last character of a reserved name (CON, NUL, COM1, ...)
+func escapeDestName(name, scheme string) string {
+	if scheme != DestNamesPercent && scheme != DestNamesUnicode || name == "." || name == ".." {
+		return name
+	}
+	runes := []rune(name)
+	escape := make([]bool, len(runes))
+	for i, r := range runes {
+		escape[i] = r < 0x20 || strings.ContainsRune(`<>:"\|?*`, r)
+	}
+	for i := len(runes) - 1; i >= 0 && (runes[i] == '.' || runes[i] == ' '); i-- {
+		escape[i] = true
+	}
+	stem, _, _ := strings.Cut(name, ".")
+	if windowsReserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		escape[len([]rune(stem))-1] = true
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case !escape[i]:
+			b.WriteRune(r)
+		case scheme == DestNamesPercent:
+			fmt.Fprintf(&b, "%%%02X", r)
+		case r < 0x20:
+			b.WriteRune(0x2400 + r) // control pictures, e.g. U+2409 for a tab
+		case r == ' ':
+			b.WriteRune(0x3000) // ideographic space
+		default:
+			b.WriteRune(r + 0xFEE0) // full-width forms of ASCII
+		}
+	}
+	return b.String()
+}
+
+// destNamer names the copies of a backup in the destination
+type destNamer struct {
+	scheme string // escaping of names Windows rejects (see EngineConfig.DestNames)
+	nfc    bool   // names are written in Unicode NFC (see nfcName)
+	// collides reports a source file or folder whose NFC name belongs to a sibling
+	// (state.StateManager.NFCCollision; nil = none)
+	collides func(sourcePath string) bool
+}
+
+// path returns where the file at sourcePath is copied, rel being its path relative to
+// destRoot as on the phone. The names are taken from the end of sourcePath, as they are on
+// the source, so a rel already normalized (see normalizePhonePath) gives the same path.
+func (n destNamer) path(destRoot, sourcePath, rel string) string {
+	names, paths := sourceComponents(sourcePath, rel)
+	parts := make([]string, 0, len(names)+1)
+	parts = append(parts, destRoot)
+	for i, name := range names {
+		if n.nfc {
+			name = nfcName(name, n.collides != nil && paths[i] != "" && n.collides(paths[i]))
+		}
+		parts = append(parts, escapeDestName(name, n.scheme))
+	}
+	return filepath.Join(parts...)
+}
+
+// destFilePath returns where the file at rel (relative to the destination root, as on the
+// phone) is copied when only its names need escaping
+func destFilePath(destRoot, rel, scheme string) string {
+	return destNamer{scheme: scheme}.path(destRoot, "", rel)
+}
+
+// stateDestNamer returns the naming recorded in a backup's state, or for a backup that
+// records none the configured scheme: in NFC for a new backup, while one made before names
+// were normalized keeps the names it has
+func stateDestNamer(sm *state.StateManager, configured string) destNamer {
+	n := destNamer{scheme: resolveDestNames(configured), nfc: true}
+	if sm == nil {
+		return n
+	}
+	if scheme, nfc := sm.DestNames(); scheme != "" {
+		n.scheme, n.nfc = scheme, nfc
+	} else {
+		n.nfc = sm.GetStats() == 0
+	}
+	n.collides = sm.NFCCollision
+	return n
+}
+
+// DestFilePath returns where the backup with the given state keeps the copy of the file at
+// sourcePath, rel being its path in the destination as returned by DestRelPath
+func DestFilePath(sm *state.StateManager, destRoot, sourcePath, rel string) string {
+	return stateDestNamer(sm, DestNamesAuto).path(destRoot, sourcePath, rel)
+}
+
+// destPath returns where the file at sourcePath, at rel relative to the destination root, is copied
+func (e *Engine) destPath(sourcePath, rel string) string {
+	return e.names.path(e.config.DestRoot, sourcePath, rel)
+}
+
+// recordDestNames checks the configured scheme and records the naming in use in the state
+// of a backup that has none yet. A backup that already records one keeps it: switching would
+// copy every file with an escaped name again under its new name.
+func (e *Engine) recordDestNames() error {
+	if err := validDestNames(e.config.DestNames); err != nil {
+		return err
+	}
+	if e.stateManager == nil {
+		return nil
+	}
+	recorded, _ := e.stateManager.DestNames()
+	if recorded == "" {
+		if err := e.stateManager.MarkDestNames(e.names.scheme, e.names.nfc); err != nil {
+			e.log("warn", fmt.Sprintf("Could not record the destination name scheme in the state: %v", err))
+		}
+		return nil
+	}
+	if e.config.DestNames != DestNamesAuto && e.config.DestNames != recorded {
+		e.log("warn", fmt.Sprintf("This backup escapes file names with the %s scheme; keeping it instead of %s", recorded, e.config.DestNames))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestEscapeDestName(t *testing.T) {
+	tests := []struct {
+		name, percent, unicode string
+	}{
+		{"IMG_0001.jpg", "IMG_0001.jpg", "IMG_0001.jpg"},
+		{"a:b?.txt", "a%3Ab%3F.txt", "a：b？.txt"},
+		{`<"|*\>`, "%3C%22%7C%2A%5C%3E", "＜＂｜＊＼＞"},
+		{"tab\there", "tab%09here", "tab␉here"},
+		{"notes. ", "notes%2E%20", "notes．　"},
+		{"CON.txt", "CO%4E.txt", "COＮ.txt"},
+		{"com1", "com%31", "com１"},
+		{"CONSOLE.txt", "CONSOLE.txt", "CONSOLE.txt"},
+		{"100%.jpg", "100%.jpg", "100%.jpg"},
+		{"..", "..", ".."},
+	}
+	for _, tt := range tests {
+		if got := escapeDestName(tt.name, DestNamesPercent); got != tt.percent {
+			t.Errorf("percent %q = %q, want %q", tt.name, got, tt.percent)
+		}
+		if got := escapeDestName(tt.name, DestNamesUnicode); got != tt.unicode {
+			t.Errorf("unicode %q = %q, want %q", tt.name, got, tt.unicode)
+		}
+		if got := escapeDestName(tt.name, DestNamesNone); got != tt.name {
+			t.Errorf("none %q = %q", tt.name, got)
+		}
+	}
+}
+
+func TestDestNamesRecordedInState(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	os.MkdirAll(filepath.Join(source, "Notes:2024"), 0755)
+	os.WriteFile(filepath.Join(source, "Notes:2024", "todo?.txt"), []byte("milk"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, DestNames: DestNamesPercent, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	escaped := filepath.Join(dest, "Notes%3A2024", "todo%3F.txt")
+	if data, err := os.ReadFile(escaped); err != nil || string(data) != "milk" {
+		t.Fatalf("escaped copy = %q, %v", data, err)
+	}
+	if got, _ := sm.DestNames(); got != DestNamesPercent {
+		t.Errorf("recorded scheme = %q", got)
+	}
+
+	// Another scheme doesn't apply to an existing backup: verify finds the same copies
+	config.DestNames = DestNamesUnicode
+	config.VerifyDestOnly = true
+	results, err := NewEngine(config, sm).VerifyBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Verified != 1 || results.MissingDest != 0 {
+		t.Errorf("results = %+v; want the escaped copy verified", results)
+	}
+
+	config.DestNames = "rot13"
+	if err := NewEngine(config, sm).Run(context.Background()); err == nil {
+		t.Errorf("an unknown scheme should be rejected")
+	}
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfinementDiagnosis explains EACCES failures caused by the sandbox/LSM rather than the phone
+type ConfinementDiagnosis struct {
+	Mechanism   string   // "selinux", "apparmor", "flatpak" or "snap"
+	Cause       string   // Human-readable cause
+	Remediation []string // Steps the user can take
+	Evidence    []string // Matching denial lines from the kernel log (may be empty)
+}
+
+// Summary returns a one-line description suitable for error messages
+func (d *ConfinementDiagnosis) Summary() string {
+	if len(d.Remediation) == 0 {
+		return d.Cause
+	}
+	return fmt.Sprintf("%s - %s", d.Cause, d.Remediation[0])
+}
+
+var (
+	confinementOnce   sync.Once
+	confinementResult *ConfinementDiagnosis
+)
+
+// DiagnoseConfinement inspects the environment for confinement that blocks gvfs access.
+// Returns nil when nothing suspicious is found (or on non-Linux systems).
+func DiagnoseConfinement(ctx context.Context) *ConfinementDiagnosis {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+
+	// Sandboxed packages can't see /run/user/<uid>/gvfs unless granted
+	if id := os.Getenv("FLATPAK_ID"); id != "" {
+		return &ConfinementDiagnosis{
+			Mechanism: "flatpak",
+			Cause:     "running inside a Flatpak sandbox without access to gvfs mounts",
+			Remediation: []string{
+				fmt.Sprintf("flatpak override --user --filesystem=xdg-run/gvfs %s", id),
+				"Restart GusSync after applying the override",
+			},
+		}
+	}
+	if os.Getenv("SNAP") != "" {
+		return &ConfinementDiagnosis{
+			Mechanism: "snap",
+			Cause:     "running inside a strictly confined snap that cannot read gvfs mounts",
+			Remediation: []string{
+				"snap connect gussync:gvfs-mounts (if the snap provides the plug)",
+				"Or install GusSync from a non-confined package",
+			},
+		}
+	}
+
+	denials := recentDenials(ctx)
+
+	if readTrimmed("/sys/fs/selinux/enforce") == "1" {
+		if lines := filterDenials(denials, "avc:"); len(lines) > 0 {
+			return &ConfinementDiagnosis{
+				Mechanism: "selinux",
+				Cause:     "SELinux is denying access to the gvfs mount",
+				Remediation: []string{
+					"Inspect denials: sudo ausearch -m avc -ts recent",
+					"Generate a local policy: sudo ausearch -m avc -ts recent | audit2allow -M gussync && sudo semodule -i gussync.pp",
+					"Or test with permissive mode: sudo setenforce 0",
+				},
+				Evidence: lines,
+			}
+		}
+	}
+
+	if readTrimmed("/sys/module/apparmor/parameters/enabled") == "Y" {
+		if lines := filterDenials(denials, `apparmor="DENIED"`); len(lines) > 0 {
+			profile := readTrimmed("/proc/self/attr/current")
+			return &ConfinementDiagnosis{
+				Mechanism: "apparmor",
+				Cause:     fmt.Sprintf("AppArmor is denying access to the gvfs mount (profile: %s)", profile),
+				Remediation: []string{
+					"Inspect denials: sudo journalctl -k -g 'apparmor=\"DENIED\"'",
+					"Allow /run/user/*/gvfs/** r, in the profile and reload: sudo apparmor_parser -r /etc/apparmor.d/<profile>",
+					"Or put the profile in complain mode: sudo aa-complain <profile>",
+				},
+				Evidence: lines,
+			}
+		}
+	}
+
+	return nil
+}
+
+// explainPermissionError returns a targeted explanation when err is a permission
+// error caused by confinement. The (slow) diagnosis runs at most once per process.
+func explainPermissionError(err error) string {
+	if !errors.Is(err, fs.ErrPermission) {
+		return ""
+	}
+	confinementOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		confinementResult = DiagnoseConfinement(ctx)
+	})
+	if confinementResult == nil {
+		return ""
+	}
+	return confinementResult.Summary()
+}
+
+// recentDenials returns recent kernel audit lines mentioning LSM denials
+func recentDenials(ctx context.Context) []string {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "journalctl", "-k", "--since", "-15min", "--no-pager", "-q",
+		"-g", `avc:.*denied|apparmor="DENIED"`)
+	output, err := cmd.Output()
+	if err != nil {
+		// journalctl exits 1 when nothing matches; permission errors just mean no evidence
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n")
+}
+
+// filterDenials keeps denial lines of the given kind that relate to gvfs/fuse access
+func filterDenials(lines []string, marker string) []string {
+	var result []string
+	for _, line := range lines {
+		if !strings.Contains(line, marker) {
+			continue
+		}
+		if strings.Contains(line, "gvfs") || strings.Contains(line, "fuse") || strings.Contains(line, "mtp") {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilterDenials(t *testing.T) {
+	lines := []string{
+		`audit: type=1400 apparmor="DENIED" operation="open" profile="gussync" name="/run/user/1000/gvfs/mtp:host=Pixel/DCIM/"`,
+		`audit: type=1400 apparmor="DENIED" operation="open" profile="cups" name="/etc/printcap"`,
+		`audit: avc:  denied  { read } for pid=42 comm="gussync" name="gvfs" scontext=unconfined_u`,
+	}
+
+	if got := filterDenials(lines, `apparmor="DENIED"`); len(got) != 1 {
+		t.Errorf("expected 1 gvfs apparmor denial, got %d", len(got))
+	}
+	if got := filterDenials(lines, "avc:"); len(got) != 1 {
+		t.Errorf("expected 1 gvfs avc denial, got %d", len(got))
+	}
+}
+
+func TestExplainPermissionErrorIgnoresOtherErrors(t *testing.T) {
+	if hint := explainPermissionError(errors.New("input/output error")); hint != "" {
+		t.Errorf("expected no hint for non-permission error, got %q", hint)
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirStats are a run's figures for one top-level folder of the source (DCIM, WhatsApp,
+// Download, ...), so the folders that dominate a backup or keep failing stand out
+type DirStats struct {
+	Dir       string `json:"dir"` // "." for files directly in the source folder
+	Files     int    `json:"files"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Skipped   int    `json:"skipped"`
+	Bytes     int64  `json:"bytes"` // copied this run
+}
+
+// topLevelDir returns the first element of a path relative to the source
+func topLevelDir(relPath string) string {
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+	if i := strings.Index(relPath, "/"); i > 0 {
+		return relPath[:i]
+	}
+	return "."
+}
+
+// addDirStats counts one file's outcome for its top-level folder. The caller holds e.stats.
+func (e *Engine) addDirStats(s CopyStats) {
+	if s.RelPath == "" {
+		return
+	}
+	dir := topLevelDir(s.RelPath)
+	if e.stats.dirs == nil {
+		e.stats.dirs = make(map[string]*DirStats)
+	}
+	d := e.stats.dirs[dir]
+	if d == nil {
+		d = &DirStats{Dir: dir}
+		e.stats.dirs[dir] = d
+	}
+	d.Files++
+	switch {
+	case s.Success:
+		d.Completed++
+		d.Bytes += s.BytesCopied
+	case s.Skipped:
+		d.Skipped++
+	default:
+		d.Failed++
+	}
+}
+
+// dirStats returns the per-folder figures, most bytes copied first (then most files). The
+// caller holds e.stats.
+func (e *Engine) dirStats() []DirStats {
+	if len(e.stats.dirs) == 0 {
+		return nil
+	}
+	dirs := make([]DirStats, 0, len(e.stats.dirs))
+	for _, d := range e.stats.dirs {
+		dirs = append(dirs, *d)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Bytes != dirs[j].Bytes {
+			return dirs[i].Bytes > dirs[j].Bytes
+		}
+		if dirs[i].Files != dirs[j].Files {
+			return dirs[i].Files > dirs[j].Files
+		}
+		return dirs[i].Dir < dirs[j].Dir
+	})
+	return dirs
+}
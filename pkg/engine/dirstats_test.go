@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestTopLevelDir(t *testing.T) {
+	for rel, want := range map[string]string{
+		"DCIM/Camera/a.jpg":         "DCIM",
+		"WhatsApp/Media/x.opus":     "WhatsApp",
+		"/Download/b.pdf":           "Download",
+		"notes.txt":                 ".",
+		filepath.Join("Music", "s"): "Music",
+	} {
+		if got := topLevelDir(rel); got != want {
+			t.Errorf("topLevelDir(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestProgressDirStats(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	for rel, content := range map[string]string{
+		"DCIM/Camera/a.jpg": "aaaaaaaa",
+		"DCIM/b.jpg":        "bbbb",
+		"Download/c.pdf":    "cc",
+		"readme.txt":        "r",
+	} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	reporter := &lastProgress{}
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   filepath.Join(dir, "backup"),
+		NumWorkers: 2,
+		Reporter:   reporter,
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []DirStats{
+		{Dir: "DCIM", Files: 2, Completed: 2, Bytes: 12},
+		{Dir: "Download", Files: 1, Completed: 1, Bytes: 2},
+		{Dir: ".", Files: 1, Completed: 1, Bytes: 1},
+	}
+	if got := reporter.update.Dirs; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dirs = %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// DiskFree returns the bytes available to unprivileged users on the filesystem holding path
+func DiskFree(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// diskID identifies the filesystem holding path (its device number); "" if it can't be told
+func diskID(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprint(uint64(st.Dev))
+}
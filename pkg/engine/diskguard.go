@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDiskCheckInterval is how often the destination's free space is checked
+	DefaultDiskCheckInterval = 30 * time.Second
+	// DefaultMinFreeSpace is the free space the CLI and app keep on the destination by default
+	DefaultMinFreeSpace = 1 << 30 // 1 GiB
+)
+
+// diskGuard pauses copying while the destination is short of space. Workers check before
+// each file (including the file's size when known); a monitor catches space running out
+// during long copies. Copying resumes on its own once enough space is freed.
+type diskGuard struct {
+	dest     string
+	minFree  int64
+	interval time.Duration
+	free     func(path string) (int64, error) // DiskFree; replaced in tests
+	log      func(level, msg string)
+	onFull   func(free int64) // called when copying pauses
+
+	mu     sync.Mutex
+	paused bool
+}
+
+func newDiskGuard(dest string, minFree int64, interval time.Duration, log func(level, msg string), onFull func(free int64)) *diskGuard {
+	if interval <= 0 {
+		interval = DefaultDiskCheckInterval
+	}
+	return &diskGuard{dest: dest, minFree: minFree, interval: interval, free: DiskFree, log: log, onFull: onFull}
+}
+
+// monitor checks free space every interval until done, pausing when it drops below the threshold
+func (g *diskGuard) monitor(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if free, err := g.free(g.dest); err == nil && free < g.minFree {
+				g.setPaused(true, free, 0)
+			}
+		}
+	}
+}
+
+// waitForSpace blocks until the destination can take need more bytes (0 if unknown) and
+// still keep minFree available. Returns false if ctx ends first.
+func (g *diskGuard) waitForSpace(ctx context.Context, need int64, waiting func()) bool {
+	for {
+		free, err := g.free(g.dest)
+		if err != nil || free-need >= g.minFree {
+			// Unknown free space never blocks the backup
+			g.setPaused(false, free, need)
+			return true
+		}
+		g.setPaused(true, free, need)
+		if waiting != nil {
+			waiting()
+		}
+		if sleepContext(ctx, g.interval) != nil {
+			return false
+		}
+	}
+}
+
+// setPaused records a pause or resume, logging (and notifying) only on changes
+func (g *diskGuard) setPaused(paused bool, free, need int64) {
+	g.mu.Lock()
+	changed := g.paused != paused
+	g.paused = paused
+	g.mu.Unlock()
+	if !changed {
+		return
+	}
+	if !paused {
+		g.log("info", fmt.Sprintf("Destination has %s free again; resuming", formatSize(free)))
+		return
+	}
+	msg := fmt.Sprintf("Destination is nearly full (%s free, keeping at least %s", formatSize(free), formatSize(g.minFree))
+	if need > 0 {
+		msg += fmt.Sprintf(", next file needs %s", formatSize(need))
+	}
+	g.log("warn", msg+"); copying paused until space is freed")
+	if g.onFull != nil {
+		g.onFull(free)
+	}
+}
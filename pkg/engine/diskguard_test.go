@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskGuardPausesUntilSpaceIsFreed(t *testing.T) {
+	var free atomic.Int64
+	free.Store(5 << 20)
+
+	var mu sync.Mutex
+	var logs []string
+	var fullEvents atomic.Int32
+	g := newDiskGuard(t.TempDir(), 10<<20, time.Millisecond, func(level, msg string) {
+		mu.Lock()
+		logs = append(logs, level)
+		mu.Unlock()
+	}, func(int64) { fullEvents.Add(1) })
+	g.free = func(string) (int64, error) { return free.Load(), nil }
+
+	// Below the threshold: blocks until space is freed
+	var waits atomic.Int32
+	done := make(chan bool)
+	go func() {
+		done <- g.waitForSpace(context.Background(), 0, func() {
+			if waits.Add(1) == 5 {
+				free.Store(50 << 20)
+			}
+		})
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("waitForSpace gave up")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitForSpace did not resume after space was freed")
+	}
+	if fullEvents.Load() != 1 {
+		t.Errorf("disk full reported %d times, want once per pause", fullEvents.Load())
+	}
+	mu.Lock()
+	if len(logs) != 2 || logs[0] != "warn" || logs[1] != "info" {
+		t.Errorf("logs = %v, want one pause warning and one resume", logs)
+	}
+	mu.Unlock()
+
+	// A file that would take the destination below the threshold waits too
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if g.waitForSpace(ctx, 45<<20, nil) {
+		t.Error("a 45 MB file should not fit into 50 MB free with 10 MB reserved")
+	}
+	if !g.waitForSpace(context.Background(), 30<<20, nil) {
+		t.Error("a 30 MB file should fit")
+	}
+}
@@ -1,14 +1,21 @@
 package engine
 
 import (
+	"GusSync/pkg/notify"
 	"GusSync/pkg/state"
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,10 +31,62 @@ type ProgressUpdate struct {
 	Rate             float64 // bytes per second
 	DeltaMB          float64 // MB since last report
 	WorkerStatuses   map[int]string
+	ActiveWorkers    int // Current worker limit (changes over time in adaptive mode)
 	ScanComplete     bool
 	JobID            string
+	// ScanDone is set once the scan has found every file, so TotalFiles is final (from the
+	// start with a manifest); files are copied while the scan goes on
+	ScanDone bool
+
+	// Speed history for graphs: live throughput (including in-flight files) per
+	// report interval, oldest first, at most SpeedHistorySize samples
+	SpeedSamples []float64
+	WorkerBytes  map[int]int64 // bytes transferred by each worker this run
+	AvgSpeed     float64       // bytes per second since the run started
+	PeakSpeed    float64       // highest sample seen this run
+	ETASeconds   int64         // estimated time remaining (0 = unknown, e.g. still scanning)
+
+	// ManifestBytes is the size of all files in the scan manifest (manifest-first runs only;
+	// TotalFiles is then the manifest's file count from the start)
+	ManifestBytes int64
+
+	// Whole-backup progress across runs (restored from the state file): files in the backup,
+	// files expected in the source (the last complete scan, or this run's count once it is
+	// larger; 0 = unknown), BackupCompleted as a share of BackupExpected (0-100) and bytes
+	// copied by all runs
+	BackupCompleted int
+	BackupExpected  int
+	BackupPercent   float64
+	BackupBytes     int64
+
+	// Dirs are this run's figures per top-level source folder, most bytes copied first
+	Dirs []DirStats
+
+	// SpecialFiles counts the links and special files the scan found (see EngineConfig.Symlinks)
+	SpecialFiles SpecialFileStats
+
+	// SmallQueue and LargeQueue are how many jobs wait in the small- and large-file queues
+	// (see EngineConfig.LargeFileSize); a batch of small files counts once
+	SmallQueue int
+	LargeQueue int
+
+	// WorkerFiles is the file each busy worker is copying, with its size and the bytes
+	// copied so far (workers that are idle or copying a batch are absent)
+	WorkerFiles map[int]WorkerFile
+}
+
+// WorkerFile is the file a worker is copying (ProgressUpdate.WorkerFiles)
+type WorkerFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"` // 0 = unknown
+	Copied int64  `json:"copied"`
 }
 
+const (
+	// SpeedHistorySize is the number of throughput samples kept in ProgressUpdate.SpeedSamples
+	SpeedHistorySize = 60
+)
+
 // ProgressReporter interface for reporting progress to CLI or GUI
 type ProgressReporter interface {
 	ReportProgress(update ProgressUpdate)
@@ -39,15 +98,242 @@ type ProgressReporter interface {
 type EngineConfig struct {
 	SourcePath string
 	DestRoot   string
-	Mode       string // "mount" or "adb"
-	NumWorkers int
+	Mode       string // "mount", "adb", "ssh", "smb", "kdeconnect" or another registered transport
+	NumWorkers int // Maximum number of copy workers (fixed count unless AdaptiveWorkers is set)
 	Reporter   ProgressReporter
+	// Serial is the adb serial of the device in adb mode ("" = ANDROID_SERIAL, or the only
+	// device attached). Set it whenever several phones can be attached at once.
+	Serial string
+	// AdaptiveWorkers lets the engine tune the active worker count between MinWorkers
+	// and NumWorkers based on throughput and stall rate
+	AdaptiveWorkers bool
+	MinWorkers      int
+	// LargeFileSize is the size from which files go to the large-file queue, served by
+	// workers of their own, so a long video doesn't hold up the photos behind it
+	// (0 = DefaultLargeFileSize). Files whose size the scan doesn't report (adb without
+	// BatchSmallFiles) count as small.
+	LargeFileSize int64
+	// LargeFileWorkers is how many of the NumWorkers workers take large files first; they
+	// take small files while no large one waits (0 = a quarter, at least one; a single
+	// worker serves both queues)
+	LargeFileWorkers int
+	// ScanRoots limits the backup to these folders, relative to SourcePath
+	// (e.g. "DCIM", "Pictures/Screenshots"); empty means the whole source
+	ScanRoots []string
+	// ExtraSources are more folders backed up by the same run after SourcePath (e.g. the SD
+	// card next to internal storage), each into a folder of DestRoot named after it. The roots
+	// are recorded in the state, so VerifyBackup finds their copies later. ScanRoots apply to
+	// each source; not supported with ManifestFirst, FromManifest or Watch.
+	ExtraSources []string
+	// Excludes are glob patterns (see NewFilter) skipped in addition to the built-in exclusions
+	Excludes []string
+	// Apps backs up the folders of these AppPresets ("whatsapp", "telegram", ...), every file
+	// in them, along with the ScanRoots; with no ScanRoots only the apps are backed up
+	Apps []string
+	// Only limits the backup to the file types of these MediaPresets ("photos", "videos",
+	// "documents", "audio"), whose folders are also scanned first; empty means every file
+	Only []string
+	// PriorityPaths are the folders (relative to SourcePath) scanned first, in this order,
+	// after those of the Only presets; nil means the default PriorityPaths
+	PriorityPaths []string
+	// NoPriority scans the folders in their usual order instead, none first
+	NoPriority bool
+	// Limits skips files by size and modification time (zero value = no limits); the scanners
+	// apply them, so skipped files are never queued
+	Limits FileLimits
+	// PostProcessors run after each successful copy (e.g. HEIC/HEVC conversion).
+	// Their failures are logged as warnings and never affect the original.
+	PostProcessors []PostProcessor
+	// Bandwidth limits the aggregate transfer rate by time of day (nil = unlimited).
+	// Enforced for mount, ssh, smb and kdeconnect mode; adb pull cannot be throttled.
+	Bandwidth *BandwidthSchedule
+	// PanicHandler is called when an engine goroutine panics (e.g. to write a crash
+	// report); the panic is re-raised afterwards
+	PanicHandler func(component string, recovered interface{}, stack []byte)
+	// Retry controls in-run retries of transient failures (zero value uses DefaultRetryPolicy)
+	Retry RetryPolicy
+	// VerifySample limits VerifyBackup to this fraction of the completed files (0 = all)
+	VerifySample float64
+	// VerifyScrub makes VerifyBackup check the least recently verified files instead of a
+	// random sample, so repeated runs cycle through the whole backup and catch bitrot
+	// (DefaultScrubFraction per run unless VerifySample is set). Otherwise a copy whose size and
+	// modification time haven't changed since it was last hashed isn't read again.
+	VerifyScrub bool
+	// VerifyWorkers is how many files (or disks, see VerifySequential) VerifyBackup checks at
+	// once (0 = NumWorkers)
+	VerifyWorkers int
+	// HashWorkers is how many files are hashed on this machine at once: backed-up copies read
+	// by verify, cleanup and detect-moves, and copies hashed after adb pulls them (0 =
+	// runtime.NumCPU()). Copies over the mount, ssh and smb are hashed as they are copied.
+	HashWorkers int
+	// VerifySequential makes VerifyBackup read the copies on each destination disk one after
+	// another, in folder order, for spinning disks that slow down when read in several places
+	// at once: only copies on different disks (mount points) are checked in parallel
+	VerifySequential bool
+	// VerifyReadAhead makes VerifyBackup (and cleanup and detect-moves) hash the backed-up
+	// copies in reads of this many bytes, having the kernel read the next ones ahead (Linux);
+	// 0 = the usual small reads
+	VerifyReadAhead int64
+	// VerifyDestOnly makes VerifyBackup check the destination copies against the hashes
+	// recorded in the state, without touching the source: the phone needn't be attached.
+	// Missing and corrupted copies are reported, but can't be copied again or quarantined.
+	VerifyDestOnly bool
+	// OnVerifyIssue is called by VerifyBackup for each file that does not verify, as it is
+	// found (from the verify workers, so it must be safe for concurrent use; nil = not called)
+	OnVerifyIssue func(VerifyIssue)
+	// Changed picks what happens to a backed-up file that changed on the source since:
+	// ChangedOverwrite, ChangedKeepBoth or ChangedReport ("" = not checked, never copied again).
+	// Changes are detected by size, and in mount mode by a modification time after the backup.
+	Changed string
+	// ChangeRehash detects changes by hashing the source file instead of comparing modification
+	// times (mount mode): slower, but catches edits that keep the size and restore the mtime
+	ChangeRehash bool
+	// DetectMoves reuses the backed-up copy of a file found at a new path with the same content
+	// (moved or renamed on the source): it is renamed or hard-linked into its new place in the
+	// destination instead of being transferred again
+	DetectMoves bool
+	// Cleanup controls which verified files RunCleanup deletes (dry run, minimum age, confirmation)
+	Cleanup CleanupOptions
+
+	// Restore selects the backed-up files RunRestore writes back to the source
+	Restore RestoreOptions
+	// Migrate configures RunMigrate: the new phone, and whether to read from the backup or
+	// the old phone
+	Migrate MigrateOptions
+	// ManifestFirst scans the whole source and saves the file list (with sizes) next to the
+	// state file before copying, so totals are known up front and files are copied in a
+	// stable order (priority folders first)
+	ManifestFirst bool
+	// FromManifest copies the files in the saved manifest instead of scanning again
+	FromManifest bool
+	// IncrementalScan (mount mode) skips listing directories that were complete last run and
+	// whose mtime and size haven't changed since; their subdirectories are still visited
+	IncrementalScan bool
+	// DirReadTimeout limits reading one directory in mount mode (0 = DirReadTimeout); slow
+	// MTP devices with huge folders need more
+	DirReadTimeout time.Duration
+	// StallTimeout abandons a copy that receives no bytes for this long (0 = scaled to each
+	// file's size, see StallTimeoutFor)
+	StallTimeout time.Duration
+	// MinThroughput abandons a copy whose bytes, once they flow, arrive slower than this many
+	// per second for a whole ThroughputWindow (0 = DefaultMinThroughput, negative = no minimum)
+	MinThroughput int64
+	// ThroughputWindow is how long a copy may stay below MinThroughput (0 = ThroughputWindow)
+	ThroughputWindow time.Duration
+	// ChunkSize copies mount-mode files larger than this in chunks of this size (e.g.
+	// DefaultChunkSize), each checksummed and retried on its own, so a bad read over a flaky
+	// link repeats one chunk instead of the whole file (0 = whole files)
+	ChunkSize int64
+	// BufferSize is the size of the copy buffer in mount mode; 0 tunes it from the measured
+	// throughput, from BufferSize up to MaxBufferSize (large reads suit gvfs and MTP)
+	BufferSize int
+	// DestNames is how file names Windows can't store (a:b.txt, CON.jpg, trailing dots) are
+	// written in the destination: DestNamesPercent, DestNamesUnicode or DestNamesNone
+	// (DestNamesAuto = percent on Windows). The first run records it in the state; an
+	// existing backup keeps the recorded scheme, so its copies are found under the same names.
+	DestNames string
+	// Symlinks is what happens to symbolic links on the source: SymlinksSkip (""),
+	// SymlinksCopyTarget or SymlinksRecord. Adb mode only supports skipping them. Sockets,
+	// FIFOs and device files are always skipped; ProgressUpdate.SpecialFiles counts both.
+	Symlinks string
+	// ScanWorkers limits how many directories mount mode reads at once (0 = DefaultScanWorkers)
+	ScanWorkers int
+	// MediaStoreScan makes adb mode list the media folders (DCIM, Pictures, ...) from Android's
+	// MediaStore instead of walking them with find; other files are still found with find
+	MediaStoreScan bool
+	// BulkTar starts a new adb backup (no completed files yet) by streaming the media folders,
+	// or the selected folders, as one tar archive instead of pulling each file; whatever it
+	// doesn't cover is then copied file by file as usual
+	BulkTar bool
+	// BatchSmallFiles makes adb mode copy files smaller than this together, one tar stream
+	// per folder, instead of one adb pull each, whose process startup dominates small
+	// files; larger files keep the per-file path with stall detection (0 = off)
+	BatchSmallFiles int64
+	// PhoneData exports these PhoneDataKinds (contacts, sms, calls) through adb after the
+	// files, into PhoneDataDirName in DestRoot; adb mode only
+	PhoneData []string
+	// APKs backs up the APKs of the apps the user installed through adb after the files,
+	// into APKDirName in DestRoot with a list of the apps (AppListFileName); adb mode only
+	APKs bool
+	// Report writes a report of each run into DestRoot when it ends: ReportHTML (summary,
+	// failed files with reasons, slowest files, throughput, errors) or ReportCSV (one row per
+	// file); "" = none. Engine.ReportPath returns where it went.
+	Report string
+	// Audit appends every file operation (copy start and end, retries, verification,
+	// deletion) to AuditLogFileName in DestRoot, one JSON line each (see AuditEvent)
+	Audit bool
+	// AttestKey signs an Attestation of the whole backup written into DestRoot when each run
+	// ends, with the ed25519 key in this file (created if missing; see DefaultAttestKeyPath);
+	// "" = none
+	AttestKey string
+	// Signer signs the state file (as a snapshot, see SnapshotSuffix), the scan manifest and
+	// the attestation when each run ends, and checks the last run's signatures when the next
+	// starts, warning if they don't match; nil = no signing (see NewGPGSigner)
+	Signer Signer
+	// Chown sets the owner of every copied file and folder: "user:group", "user" or ":group",
+	// names or numeric ids (e.g. when running as root from a udev rule); "" = leave
+	Chown string
+	// Chmod sets the permissions of every copied file and folder: octal like "644" (folders
+	// get execute where files get read) or "F644,D755"; "" = leave (ssh sources keep their
+	// own permissions, smb sources their read-only flag)
+	Chmod string
+	// Events receives typed events as they happen (file started, completed or failed, folder
+	// scanned, connection lost, verify mismatch) for the GUI, API and other consumers that
+	// need more than the periodic ProgressUpdate (nil = none)
+	Events *EventBus
+	// ReconnectWait pauses the run when the source becomes unreachable and resumes it if the
+	// mount or adb device comes back within this long (0 = stop copying on connection loss)
+	ReconnectWait time.Duration
+	// TimeoutBreaker holds all workers after this many files in a row time out, checks the
+	// source (reconnecting or remounting it as above) and then resumes, instead of letting the
+	// rest of the queue time out too (0 = DefaultTimeoutBreaker, negative = off)
+	TimeoutBreaker int
+	// RemountStale unmounts and remounts a gvfs (MTP/gphoto2) source with gio when it goes
+	// stale ("Transport endpoint is not connected"): before the run and, with ReconnectWait,
+	// while waiting for the source to come back
+	RemountStale bool
+	// Notifier is told about connection loss and a full destination during the run (nil = none);
+	// job completion and failure are up to the caller, which knows the outcome
+	Notifier notify.Notifier
+	// Hooks are commands run before and after each backup (Run)
+	Hooks Hooks
+	// MinFreeSpace pauses copying while the destination has less than this many bytes free
+	// (or a file would take it below that), resuming once space is freed (0 = no check)
+	MinFreeSpace int64
+	// DiskCheckInterval is how often free space is re-checked (0 = DefaultDiskCheckInterval)
+	DiskCheckInterval time.Duration
+	// Watch controls the continuous sync done by Watch (settle delay, full rescan interval)
+	Watch WatchOptions
+	// Pause holds the copy workers between files while it reports paused (nil = never paused)
+	Pause Pauser
+	// SSH tells ssh mode which machine to back up; SourcePath is a path on that machine
+	SSH SSHOptions
+	// SMB tells smb mode which share to back up; SourcePath is a path inside the share
+	SMB SMBOptions
+	// KDEConnect tells kdeconnect mode which paired phone to back up
+	KDEConnect KDEConnectOptions
+	// Scanner and Copier replace the ones Mode selects for Run (nil = built-in), for embedders with
+	// their own transport. The job channel is closed when a custom Scanner's Scan returns.
+	Scanner Scanner
+	Copier  Copier
 }
 
 // Engine the core backup engine
 type Engine struct {
 	config       EngineConfig
 	stateManager *state.StateManager
+	limiter      *workerLimiter
+	rateLimiter  *RateLimiter
+	reconnect    *reconnector // nil unless ReconnectWait is set
+	prober       Prober       // the transport's reachability check, if it has one
+	diskGuard    *diskGuard   // nil unless MinFreeSpace is set
+	breaker      *timeoutBreaker // nil when TimeoutBreaker is off
+	ownership    *ownership      // Chown and Chmod; nil = leave alone
+	hashSlots    chan struct{}   // one per HashWorkers, taken while hashing a local file
+	notified     struct {
+		lowDisk        atomic.Bool
+		connectionLost atomic.Bool
+	}
 	stats        struct {
 		sync.Mutex
 		totalFiles       int
@@ -56,15 +342,44 @@ type Engine struct {
 		skipped          int
 		timeoutSkips     int
 		consecutiveSkips int
+		stalls           int // stalled attempts, including ones that were retried
 		totalBytes       int64
 		lastTotalBytes   int64
+		transferred      int64 // bytes read so far, including in-flight files
+		manifestFiles    int   // files in the scan manifest (0 = not using one)
+		manifestBytes    int64
+		dirs             map[string]*DirStats // per top-level source folder
+		lastTransferred  int64
+		speedSamples     []float64
+		peakSpeed        float64
 		lastStatsTime    time.Time
 		startTime        time.Time
 	}
 	workerStatus struct {
 		sync.Mutex
 		status map[int]string
+		bytes  map[int]int64
+		files  map[int]WorkerFile // the file each busy worker is copying
+		skip   map[int]context.CancelCauseFunc // abandons that file (SkipFile)
 	}
+	scanDone atomic.Bool
+	queueLen func() int
+	queues   *jobQueues // the small- and large-file queues during a run
+	bulkDone map[string]bool // source paths copied by the bulk tar phase of this run
+	moves    *moveIndex      // backed-up copies moved files can reuse (nil = DetectMoves off)
+	chunks   sync.Map        // source path -> ChunkProgress of the chunked copies in progress
+	filter   *Filter         // the run's exclude rules, presets and limits
+	baseTotals state.Totals  // the backup's statistics before this run
+	report     *runReport    // collects the run report (nil = no report)
+	reportPath string        // the last run's report
+	auditLog   *jsonlFile    // open during a run, verify or cleanup with EngineConfig.Audit
+	errorLog   *jsonlFile    // ErrorLogFileName, open during a run
+	source   cleanupSource // overrides how cleanup reaches source files (tests)
+	remount  func(ctx context.Context, uri string) error // overrides GioRemount (tests)
+	roots    []state.SourceRoot // the source roots while forEachSource runs (nil = single source)
+	names    destNamer          // how copies are named (see EngineConfig.DestNames)
+	nfcCheck *nfcChecker        // records names colliding in NFC before they are copied (nil = off)
+	links    *linkPolicy        // handles links and special files found by the scan
 }
 
 // NewEngine creates a new backup engine
@@ -72,18 +387,102 @@ func NewEngine(config EngineConfig, sm *state.StateManager) *Engine {
 	if config.NumWorkers <= 0 {
 		config.NumWorkers = 1
 	}
+	if config.MinWorkers <= 0 || config.MinWorkers > config.NumWorkers {
+		config.MinWorkers = 1
+	}
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry = DefaultRetryPolicy()
+	}
 	e := &Engine{
 		config:       config,
 		stateManager: sm,
+		names:        stateDestNamer(sm, config.DestNames),
 	}
+	e.links = &linkPolicy{mode: config.Symlinks, record: e.recordSymlink}
+	hashWorkers := config.HashWorkers
+	if hashWorkers <= 0 {
+		hashWorkers = runtime.NumCPU()
+	}
+	e.hashSlots = make(chan struct{}, hashWorkers)
 	e.stats.startTime = time.Now()
 	e.stats.lastStatsTime = time.Now()
 	e.workerStatus.status = make(map[int]string)
+	e.workerStatus.bytes = make(map[int]int64)
+	e.workerStatus.files = make(map[int]WorkerFile)
+	e.workerStatus.skip = make(map[int]context.CancelCauseFunc)
+	e.queueLen = func() int { return 0 }
 	return e
 }
 
-// Run starts the backup process
+// Run starts the backup process, between the pre and post backup hooks
 func (e *Engine) Run(ctx context.Context) error {
+	roots, err := e.sourceRoots()
+	if err != nil {
+		return err
+	}
+	if err := e.recordDestNames(); err != nil {
+		return err
+	}
+	if err := e.checkPhoneData(); err != nil {
+		return err
+	}
+	if err := e.checkAPKs(); err != nil {
+		return err
+	}
+	if err := e.runPreBackupHook(ctx); err != nil {
+		return err
+	}
+	e.checkSignatures(ctx)
+	err = e.forEachSource(ctx, roots, e.run)
+	if err == nil && ctx.Err() == nil {
+		e.exportPhoneData(ctx)
+		e.backupAPKs(ctx)
+	}
+	// The backup so far is attested and signed even when the run was interrupted
+	e.attest()
+	e.signRun(context.WithoutCancel(ctx))
+	e.ownRunFiles()
+	e.runPostBackupHook(ctx, err)
+	return err
+}
+
+// run performs the backup
+func (e *Engine) run(ctx context.Context) error {
+	ctx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	e.reconnect = nil
+	if e.config.ReconnectWait > 0 {
+		e.reconnect = newReconnector(e.probeSource, e.config.ReconnectWait, e.log, func(error) { cancelRun() })
+		e.reconnect.onDown = func(err error) {
+			e.publish(Event{Kind: EventConnectionLost, Path: e.config.SourcePath, Error: err.Error(), Code: ErrorCode(err)})
+			e.notify(ctx, notify.ConnectionLost, "Phone disconnected",
+				fmt.Sprintf("Backup paused: %v. Waiting up to %v for the phone to come back.", err, e.config.ReconnectWait))
+		}
+		e.reconnect.repair = e.remountStale
+	}
+	if e.config.RemountStale && e.sourceIsLocal() {
+		if err := e.probeSource(ctx); err != nil {
+			e.remountStale(ctx, err)
+		}
+	}
+
+	e.breaker = nil
+	if threshold := e.config.TimeoutBreaker; threshold >= 0 {
+		if threshold == 0 {
+			threshold = DefaultTimeoutBreaker
+		}
+		e.breaker = newTimeoutBreaker(ctx, threshold, e.checkSourceAfterTimeouts)
+		defer e.breaker.stop()
+	}
+
+	e.diskGuard = nil
+	if e.config.MinFreeSpace > 0 {
+		e.diskGuard = newDiskGuard(e.config.DestRoot, e.config.MinFreeSpace, e.config.DiskCheckInterval, e.log, func(free int64) {
+			e.notify(ctx, notify.LowDisk, "Backup destination is full",
+				fmt.Sprintf("Backup paused: %s has %s free. It resumes once space is freed.", e.config.DestRoot, formatSize(free)))
+		})
+	}
+
 	// Channels
 	jobChan := make(chan FileJob, 1000)
 	errorChan := make(chan error, 100)
@@ -93,45 +492,240 @@ func (e *Engine) Run(ctx context.Context) error {
 	var jobsChanOnce sync.Once
 	closeJobChan := func() {
 		jobsChanOnce.Do(func() {
+			e.scanDone.Store(true)
 			close(jobChan)
 		})
 	}
+	queues := newJobQueues(e.config.LargeFileSize, e.config.NumWorkers, e.config.LargeFileWorkers)
+	e.queues = queues
+	e.queueLen = func() int { return len(jobChan) + len(queues.small) + len(queues.large) }
+
+	// In manifest-first mode the scanner fills its own channel and the workers are fed
+	// from the finished manifest instead
+	scanChan := jobChan
+	closeScanChan := closeJobChan
+	if e.config.ManifestFirst && !e.config.FromManifest {
+		scanChan = make(chan FileJob, 1000)
+		var scanChanOnce sync.Once
+		closeScanChan = func() { scanChanOnce.Do(func() { close(scanChan) }) }
+	}
+	var manifest []state.ManifestEntry
+	if e.config.FromManifest {
+		var err error
+		if manifest, err = e.loadManifest(); err != nil {
+			return err
+		}
+	}
 
 	// Select scanner and copier based on mode
 	var scanner Scanner
 	var copier Copier
 
-	if e.config.Mode == "adb" {
-		scanner = NewADBScanner(closeJobChan)
-		copier = NewADBCopier()
-	} else {
-		fsScanner := NewFSScanner(closeJobChan)
-		fsScanner.SetStateManager(e.stateManager)
-		scanner = fsScanner
-		copier = NewFSCopier()
+	appFolders, err := AppFolders(e.config.Apps)
+	if err != nil {
+		return err
+	}
+	roots := e.config.ScanRoots
+	if len(appFolders) > 0 {
+		roots = append(append([]string{}, roots...), appFolders...)
+	}
+	scanRoots, err := NormalizeScanRoots(roots)
+	if err != nil {
+		return err
+	}
+	if len(e.config.ScanRoots) > 0 {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Backing up selected folders: %s", strings.Join(e.config.ScanRoots, ", ")))
+	}
+	if len(e.config.Apps) > 0 {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Backing up apps: %s", strings.Join(e.config.Apps, ", ")))
+	}
+	filter, err := NewFilter(e.config.Excludes)
+	if err != nil {
+		return err
+	}
+	filter.SetLimits(e.config.Limits)
+	if err := filter.SetPriorityPaths(e.config.PriorityPaths); err != nil {
+		return err
+	}
+	filter.SetPriorityOrder(!e.config.NoPriority)
+	if err := filter.SetPresets(e.config.Only); err != nil {
+		return err
+	}
+	if err := filter.SetApps(e.config.Apps); err != nil {
+		return err
+	}
+	if len(e.config.Only) > 0 {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Backing up only %s", strings.Join(e.config.Only, ", ")))
+	}
+	e.filter = filter
+	switch e.config.Report {
+	case "", ReportHTML, ReportCSV:
+	default:
+		return fmt.Errorf("unknown report format %q (use %s or %s)", e.config.Report, ReportHTML, ReportCSV)
+	}
+	if err := validSymlinks(e.config.Symlinks); err != nil {
+		return err
+	}
+	if e.ownership, err = newOwnership(e.config.Chown, e.config.Chmod); err != nil {
+		return err
+	}
+	if e.config.Mode == TransportADB && e.config.Symlinks != "" && e.config.Symlinks != SymlinksSkip {
+		e.config.Reporter.ReportLog("warn", fmt.Sprintf("Symlink policy %s is not supported in adb mode; links will be skipped", e.config.Symlinks))
+	}
+
+	e.rateLimiter = nil
+	scanner, copier, err = e.newTransport(TransportEnv{
+		Config:    e.config,
+		ScanRoots: scanRoots,
+		Filter:    filter,
+		State:     e.stateManager,
+		CloseJobs: closeScanChan,
+		Reconnect: e.awaitReconnect,
+	})
+	if err != nil {
+		return err
+	}
+	// Transports holding a connection (ssh) release it when the run ends
+	for _, t := range []interface{}{scanner, copier} {
+		if closer, ok := t.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+
+	if e.config.Scanner != nil {
+		scanner = closingScanner{Scanner: e.config.Scanner, close: closeScanChan}
+	}
+	if e.config.Copier != nil {
+		copier = e.config.Copier
+	}
+	e.prober, _ = scanner.(Prober)
+	if e.prober == nil && !isBuiltinTransport(e.config.Mode) {
+		e.reconnect = nil // nothing to tell whether the source is back
+	}
+
+	if e.config.Bandwidth != nil && e.rateLimiter == nil {
+		e.config.Reporter.ReportLog("warn", fmt.Sprintf("Bandwidth schedule is not supported in %s mode; transfers will run unthrottled", e.config.Mode))
 	}
 
-	// Start workers
+	e.baseTotals = e.stateManager.Totals()
+	defer e.openAudit()()
+	errorLog := newJSONL(e.config.DestRoot, ErrorLogFileName)
+	e.errorLog = errorLog
+	defer func() {
+		e.errorLog = nil
+		errorLog.close()
+	}()
+	e.report, e.reportPath = nil, ""
+	if e.config.Report != "" {
+		e.report = newRunReport(e.config.Report)
+	}
+	if e.nfcCheck = e.newNFCChecker(); e.nfcCheck != nil {
+		defer e.nfcCheck.close()
+	}
+	e.bulkDone = nil
+	if e.config.BulkTar && e.config.Mode == TransportADB && e.config.Scanner == nil && !e.config.FromManifest {
+		e.bulkDone = e.bulkTar(ctx, scanRoots, filter)
+	}
+	if e.moves = e.newMoveIndex(); e.moves != nil {
+		moves := e.moves
+		defer func() {
+			if n := moves.reused.Load(); n > 0 {
+				e.log("info", fmt.Sprintf("Reused the backed-up copies of %d moved files", n))
+			}
+			moves.close()
+			e.moves = nil
+		}()
+	}
+
+	// Start workers; in adaptive mode all NumWorkers goroutines exist but the
+	// limiter decides how many may copy at once
+	initialWorkers := e.config.NumWorkers
+	if e.config.AdaptiveWorkers {
+		initialWorkers = (e.config.NumWorkers + 1) / 2
+		if initialWorkers < e.config.MinWorkers {
+			initialWorkers = e.config.MinWorkers
+		}
+	}
+	e.limiter = newWorkerLimiter(initialWorkers)
+	adaptiveDone := make(chan struct{})
+	defer close(adaptiveDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			e.limiter.wake()
+		case <-adaptiveDone:
+		}
+	}()
+	if e.config.AdaptiveWorkers {
+		go e.runAdaptive(e.limiter, adaptiveDone)
+	}
+	if e.diskGuard != nil {
+		go e.diskGuard.monitor(ctx, adaptiveDone)
+	}
+	if e.rateLimiter != nil {
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Bandwidth limit: %s", FormatRate(e.rateLimiter.Rate())))
+		go e.runBandwidthSchedule(adaptiveDone)
+	}
+
+	// Sort the jobs into the small- and large-file queues; local files the scan didn't
+	// size are looked up
+	var sizeOf func(FileJob) int64
+	if e.sourceIsLocal() {
+		sizeOf = localSize
+	}
+	go queues.dispatch(ctx, jobChan, sizeOf)
+
 	var wg sync.WaitGroup
 	for i := 0; i < e.config.NumWorkers; i++ {
 		wg.Add(1)
-		go e.worker(ctx, i, jobChan, errorChan, statsChan, copier, &wg)
+		go e.worker(ctx, i, queues, errorChan, statsChan, copier, &wg)
 	}
 
-	// Start scanner
-	go scanner.Scan(ctx, e.config.SourcePath, jobChan, errorChan)
+	// Start scanner; it may still be reporting errors after a cancel stops the workers
+	var scanWG sync.WaitGroup
+	scanWG.Add(1)
+	go func() {
+		defer scanWG.Done()
+		defer e.recoverPanic("scanner")
+		switch {
+		case e.config.FromManifest:
+			e.feedManifest(ctx, manifest, jobChan, closeJobChan)
+		case e.config.ManifestFirst:
+			e.scanManifestFirst(ctx, scanner, scanChan, jobChan, errorChan, closeJobChan)
+		default:
+			scanner.Scan(ctx, e.config.SourcePath, jobChan, errorChan)
+		}
+	}()
 
-	// Start reporters
+	// Start reporters; the reporter drains both channels and finishes once the workers are
+	// done and they are closed, so no stats are lost to the final report
 	done := make(chan bool)
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	go func() {
+		defer e.recoverPanic("reporter")
+		statsIn, errorsIn := statsChan, errorChan
+		lastSave := time.Now()
 		for {
 			select {
-			case s := <-statsChan:
+			case s, ok := <-statsIn:
+				if !ok {
+					// Closed after the workers finished; stop reading zero values
+					statsIn = nil
+					if errorsIn == nil {
+						e.reportProgress(true)
+						close(done)
+						return
+					}
+					continue
+				}
+				if e.report != nil {
+					e.report.addStats(s)
+				}
 				e.stats.Lock()
 				e.stats.totalFiles++
+				e.addDirStats(s)
 				if s.Success {
 					e.stats.completed++
 					e.stats.totalBytes += s.BytesCopied
@@ -141,333 +735,387 @@ func (e *Engine) Run(ctx context.Context) error {
 				} else if s.IsTimeout {
 					e.stats.timeoutSkips++
 					e.stats.consecutiveSkips++
+					if e.breaker != nil && e.stats.consecutiveSkips >= e.breaker.threshold {
+						e.stats.consecutiveSkips = 0
+						e.breaker.trip()
+					}
 				} else {
 					e.stats.failed++
 					e.stats.consecutiveSkips = 0
 				}
 				e.stats.Unlock()
 
-			case err := <-errorChan:
+			case err, ok := <-errorsIn:
+				if !ok {
+					errorsIn = nil
+					if statsIn == nil {
+						e.reportProgress(true)
+						close(done)
+						return
+					}
+					continue
+				}
+				if err != nil && e.report != nil {
+					e.report.addError(err)
+				}
 				if err != nil {
+					e.recordError(err)
 					// Distinguish between critical and non-critical errors
-					errStr := err.Error()
-					if strings.Contains(errStr, "CRITICAL") || strings.Contains(errStr, "connection lost") {
+					if IsCritical(err) {
 						e.config.Reporter.ReportError(err)
 					} else {
 						// File-level errors are reported as warnings in the log
-						e.config.Reporter.ReportLog("warn", errStr)
+						e.config.Reporter.ReportLog("warn", err.Error())
 					}
 				}
 
 			case <-ticker.C:
 				e.reportProgress(false)
-
-			case <-done:
-				e.reportProgress(true)
-				return
+				if time.Since(lastSave) >= totalsSaveInterval {
+					e.saveTotals(false)
+					lastSave = time.Now()
+				}
 			}
 		}
 	}()
 
 	// Wait for completion
 	wg.Wait()
+	scanWG.Wait()
 	close(statsChan)
 	close(errorChan)
-	done <- true
+	<-done
 
 	e.stats.Lock()
 	e.config.Reporter.ReportLog("info", fmt.Sprintf("Backup finished: %d completed, %d failed, %d skipped", e.stats.completed, e.stats.failed, e.stats.skipped))
 	e.stats.Unlock()
+	// A run that listed the whole source knows how many files the backup should hold
+	e.saveTotals(e.scanDone.Load() && ctx.Err() == nil)
+	if e.report != nil {
+		e.stats.Lock()
+		dirs := e.dirStats()
+		e.stats.Unlock()
+		if path, err := e.report.write(e.config.DestRoot, time.Now(), dirs); err != nil {
+			e.log("warn", err.Error())
+		} else {
+			e.reportPath = path
+			e.log("info", fmt.Sprintf("Report written to %s", path))
+		}
+	}
 
+	if e.reconnect != nil {
+		return e.reconnect.failure()
+	}
 	return nil
 }
 
 // VerifyResults contains results from the verification pass
 type VerifyResults struct {
+	Total         int // completed files under the source
+	Sampled       int // files selected for this pass (Total unless sampling/scrubbing)
 	Verified      int
 	MissingSource int
 	MissingDest   int
 	Mismatches    int
+	Quarantined   int           // mismatched copies re-copy couldn't fix, moved to QuarantineDirName
+	Issues        []VerifyIssue // the files that did not verify, in the order they were found
 }
 
-// VerifyBackup compares source and destination hashes for all completed files
-func (e *Engine) VerifyBackup(ctx context.Context) (VerifyResults, error) {
-	allCompletedFiles := e.stateManager.GetAllCompletedFiles()
-	
-	if len(allCompletedFiles) == 0 {
-		return VerifyResults{}, nil
+// verifyCopier returns a copier for re-copying mismatched files during verification
+// (nil with an error when the transport can't be set up, e.g. ssh without SSH.Host)
+func (e *Engine) verifyCopier() (Copier, error) {
+	if e.config.Copier != nil {
+		return e.config.Copier, nil
 	}
-	
-	// Filter completed files to only include those under the current sourcePath
-	completedFiles := make(map[string]string)
-	sourceBaseCleaned := filepath.Clean(e.config.SourcePath)
-	for path, hash := range allCompletedFiles {
-		pathCleaned := filepath.Clean(path)
-		if strings.HasPrefix(pathCleaned, sourceBaseCleaned) {
-			completedFiles[path] = hash
-		}
-	}
-	
-	if len(completedFiles) == 0 {
-		return VerifyResults{}, nil
+	_, copier, err := e.newTransport(TransportEnv{
+		Config:    e.config,
+		State:     e.stateManager,
+		CloseJobs: func() {},
+		Reconnect: func(context.Context, error) bool { return false },
+	})
+	return copier, err
+}
+
+// VerifyBackup compares source and destination hashes for all completed files, of each
+// source root (see ExtraSources) in turn
+func (e *Engine) VerifyBackup(ctx context.Context) (VerifyResults, error) {
+	roots, err := e.verifyRoots()
+	if err != nil {
+		return VerifyResults{}, err
 	}
-	
 	var results VerifyResults
-	var mu sync.Mutex
-	var verifiedCount int64
-	
-	verifyChan := make(chan string, 1000)
-	var wg sync.WaitGroup
-	
-	// Start verification workers
-	for i := 0; i < e.config.NumWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			var copier Copier
-			if e.config.Mode == "adb" {
-				copier = NewADBCopier()
-			} else {
-				copier = NewFSCopier()
-			}
-
-			for sourcePath := range verifyChan {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				if e.config.Mode == "mount" {
-					if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-						mu.Lock()
-						results.MissingSource++
-						mu.Unlock()
-						continue
-					}
-				}
-				
-				relPath, err := filepath.Rel(e.config.SourcePath, sourcePath)
-				if err != nil {
-					relPath = filepath.Base(sourcePath)
-				}
-				destPath := filepath.Join(e.config.DestRoot, relPath)
-				
-				if _, err2 := os.Stat(destPath); os.IsNotExist(err2) {
-					mu.Lock()
-					results.MissingDest++
-					mu.Unlock()
-					continue
-				}
-				
-				var sourceHash string
-				if e.config.Mode == "mount" {
-					var err2 error
-					sourceHash, err2 = calculateFileHash(sourcePath)
-					if err2 != nil {
-						continue
-					}
-				}
-				
-				destHash, err2 := calculateFileHash(destPath)
-				if err2 != nil {
-					continue
-				}
-				
-				if e.config.Mode == "adb" {
-					mu.Lock()
-					results.Verified++
-					verifiedCount++
-					mu.Unlock()
-					continue
-				}
-				
-				if sourceHash != destHash {
-					mu.Lock()
-					results.Mismatches++
-					mu.Unlock()
-					
-					// Attempt re-copy
-					_, err3 := copier.Copy(ctx, sourcePath, e.config.SourcePath, e.config.DestRoot, nil)
-					if err3 == nil {
-						newDestHash, err := calculateFileHash(destPath)
-						if err == nil && sourceHash == newDestHash {
-							mu.Lock()
-							results.Verified++
-							mu.Unlock()
-						}
-					}
-				} else {
-					mu.Lock()
-					results.Verified++
-					verifiedCount++
-					mu.Unlock()
-				}
-			}
-		}()
+	err = e.forEachSource(ctx, roots, func(ctx context.Context) error {
+		rootResults, err := e.verifyBackup(ctx)
+		results.add(rootResults)
+		return err
+	})
+	if err == nil && e.config.VerifySample <= 0 && !e.config.VerifyScrub {
+		// Sampling and scrubbing only pick among the files
+		results.add(e.verifyAPKs(ctx))
 	}
-	
-	for sourcePath := range completedFiles {
-		verifyChan <- sourcePath
+	return results, err
+}
+
+// hashLocal hashes a file on this machine, such as a backed-up copy, once one of the
+// HashWorkers is free
+func (e *Engine) hashLocal(path string) (string, error) {
+	e.hashSlots <- struct{}{}
+	defer func() { <-e.hashSlots }()
+	if e.config.VerifyReadAhead > 0 {
+		return hashFileReadAhead(path, e.config.VerifyReadAhead)
 	}
-	close(verifyChan)
-	wg.Wait()
-	
-	return results, nil
+	return calculateFileHash(path)
 }
 
-// CleanupResults contains results from the cleanup pass
-type CleanupResults struct {
-	Deleted        int
-	AlreadyDeleted int
-	Failed         int
-	Skipped        int
-	IOErrors       int
+// verifyWorkers is how many files VerifyBackup checks at once
+func (e *Engine) verifyWorkers() int {
+	if e.config.VerifyWorkers > 0 {
+		return e.config.VerifyWorkers
+	}
+	return e.config.NumWorkers
 }
 
-// RunCleanup deletes source files that are verified in the destination
-func (e *Engine) RunCleanup(ctx context.Context) (CleanupResults, error) {
-	completedFiles := e.stateManager.GetAllCompletedFiles()
-	
-	if e.config.Reporter != nil {
-		e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup: Found %d completed files in state", len(completedFiles)))
+// destHash hashes the backed-up copy of sourcePath, or returns the hash cached in the state
+// while the copy's size and modification time are unchanged. A scrub reads every copy it
+// checks: bitrot leaves both alone.
+func (e *Engine) destHash(sourcePath, destPath string) (string, error) {
+	info, err := os.Stat(destPath)
+	if err != nil {
+		return "", err
 	}
-	
-	if len(completedFiles) == 0 {
-		if e.config.Reporter != nil {
-			e.config.Reporter.ReportLog("info", "Cleanup: No completed files to process")
+	if !e.config.VerifyScrub {
+		if hash, ok := e.stateManager.CachedDestHash(sourcePath, info.Size(), info.ModTime().UnixNano()); ok {
+			return hash, nil
 		}
-		return CleanupResults{}, nil
 	}
+	hash, err := e.hashLocal(destPath)
+	if err != nil {
+		return "", err
+	}
+	if err := e.stateManager.MarkDestHash(sourcePath, state.DestHash{Size: info.Size(), MTime: info.ModTime().UnixNano(), Hash: hash}); err != nil {
+		e.log("warn", fmt.Sprintf("Could not cache the hash of %s: %v", destPath, err))
+	}
+	return hash, nil
+}
 
-	var results CleanupResults
-	filesToProcess := make([]struct{ path, hash string }, 0)
-
-	for path, hash := range completedFiles {
-		if e.stateManager.IsDeleted(path) {
-			results.AlreadyDeleted++
-			continue
-		}
-		if !e.stateManager.ShouldRetryCleanup(path) {
-			results.Skipped++
-			continue
+// verifyBackup verifies the completed files under SourcePath
+func (e *Engine) verifyBackup(ctx context.Context) (VerifyResults, error) {
+	// Only the completed files under the current sourcePath
+	var paths []string
+	sourceBaseCleaned := filepath.Clean(e.config.SourcePath)
+	e.stateManager.IterateCompleted(func(path, hash string) bool {
+		pathCleaned := filepath.Clean(path)
+		if strings.HasPrefix(pathCleaned, sourceBaseCleaned) && !e.inOtherSource(path) {
+			paths = append(paths, path)
 		}
-		filesToProcess = append(filesToProcess, struct{ path, hash string }{path, hash})
+		return ctx.Err() == nil
+	})
+	if err := ctx.Err(); err != nil {
+		return VerifyResults{}, err
 	}
 
-	totalToProcess := len(filesToProcess)
-	if e.config.Reporter != nil {
-		e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup: Processing %d files (skipped %d already deleted, %d failed too many times)", 
-			totalToProcess, results.AlreadyDeleted, results.Skipped))
+	if len(paths) == 0 {
+		return VerifyResults{}, nil
 	}
 
-	lastReport := time.Now()
-	processed := 0
+	fraction := e.config.VerifySample
+	if e.config.VerifyScrub && fraction <= 0 {
+		fraction = DefaultScrubFraction
+	}
+	selected := selectForVerify(paths, fraction, e.config.VerifyScrub, e.stateManager.LastVerified, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if len(selected) < len(paths) && e.config.Reporter != nil {
+		how := "random sample"
+		if e.config.VerifyScrub {
+			how = "scrub, least recently verified first"
+		}
+		e.config.Reporter.ReportLog("info", fmt.Sprintf("Verifying %d of %d files (%s)", len(selected), len(paths), how))
+	}
 
-	for _, file := range filesToProcess {
-		select {
-		case <-ctx.Done():
-			return results, context.Canceled
-		default:
+	destOnly := e.config.VerifyDestOnly
+	var copier Copier
+	if !destOnly {
+		var err error
+		copier, err = e.verifyCopier()
+		if err != nil {
+			// Hashes can still be compared; mismatches are quarantined without a re-copy
+			e.log("warn", fmt.Sprintf("Mismatched files can't be copied again: %v", err))
 		}
+		if closer, ok := copier.(io.Closer); ok && e.config.Copier == nil {
+			defer closer.Close()
+		}
+	}
 
-		sourcePath := file.path
-		expectedHash := file.hash
-		processed++
+	defer e.openAudit()()
+	results := VerifyResults{Total: len(paths), Sampled: len(selected)}
+	var mu sync.Mutex
+	var verifiedCount int64
+	progress := newVerifyProgress(len(selected))
 
-		// Report progress periodically
-		if e.config.Reporter != nil && time.Since(lastReport) > 2*time.Second {
-			e.config.Reporter.ReportProgress(ProgressUpdate{
-				TotalFiles: totalToProcess,
-				Completed:  results.Deleted,
-				Failed:     results.Failed,
-				Skipped:    results.Skipped,
-			})
-			lastReport = time.Now()
+	// verifyDest returns where the copy of a file is
+	verifyDest := func(sourcePath string) string {
+		relPath, err := filepath.Rel(e.config.SourcePath, sourcePath)
+		if err != nil {
+			relPath = filepath.Base(sourcePath)
 		}
+		return e.destPath(sourcePath, relPath)
+	}
 
-		// Stat check
-		info, err := os.Stat(sourcePath)
+	// check verifies one file, repairing or quarantining a bad copy
+	check := func(sourcePath string) {
+		if e.sourceIsLocal() && !destOnly {
+			if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+				mu.Lock()
+				results.MissingSource++
+				mu.Unlock()
+				e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Result: AuditMissingSource})
+				e.verifyIssue(progress, VerifyIssue{Kind: VerifyMissingSource, SourcePath: sourcePath})
+				return
+			}
+		}
+		
+		relPath, err := filepath.Rel(e.config.SourcePath, sourcePath)
 		if err != nil {
-			if os.IsNotExist(err) {
-				results.Skipped++
-				continue
+			relPath = filepath.Base(sourcePath)
+		}
+		destPath := e.destPath(sourcePath, relPath)
+		
+		if _, err2 := os.Stat(destPath); os.IsNotExist(err2) {
+			mu.Lock()
+			results.MissingDest++
+			mu.Unlock()
+			e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Result: AuditMissingDest})
+			e.verifyIssue(progress, VerifyIssue{Kind: VerifyMissingDest, SourcePath: sourcePath})
+			return
+		}
+		
+		var sourceHash string
+		if e.sourceIsLocal() && !destOnly {
+			var err2 error
+			sourceHash, err2 = calculateFileHash(sourcePath)
+			if err2 != nil {
+				e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Result: AuditFailed, Error: err2.Error(), ErrorCode: ErrorCode(err2)})
+				return
 			}
-			results.IOErrors++
-			continue
 		}
-
-		if info.IsDir() {
-			results.Skipped++
-			continue
+		
+		destHash, err2 := e.destHash(sourcePath, destPath)
+		if err2 != nil {
+			e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Result: AuditFailed, Error: err2.Error(), ErrorCode: ErrorCode(err2)})
+			return
+		}
+		
+		expectedHash := sourceHash
+		if !e.sourceIsLocal() || destOnly {
+			// The source can't be hashed on the device; compare against the hash recorded at copy time
+			expectedHash = e.stateManager.CompletedHash(sourcePath)
 		}
 
-		// Determine destination path
-		relPath, _ := filepath.Rel(e.config.SourcePath, sourcePath)
-		destPath := filepath.Join(e.config.DestRoot, relPath)
+		if expectedHash != "" && expectedHash != destHash && destOnly {
+			// No source to copy from: report the corrupted copy and leave it in place
+			mu.Lock()
+			results.Mismatches++
+			mu.Unlock()
+			e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Hash: destHash, Result: AuditFailed,
+				Error: fmt.Sprintf("hash mismatch: expected %s", expectedHash), ErrorCode: CodeHashMismatch})
+			e.verifyIssue(progress, VerifyIssue{Kind: VerifyMismatch, SourcePath: sourcePath, DestPath: destPath})
+		} else if expectedHash != "" && expectedHash != destHash {
+			mu.Lock()
+			results.Mismatches++
+			mu.Unlock()
 
-		// Check destination
-		if _, err := os.Stat(destPath); os.IsNotExist(err) {
-			// Restore if missing (as in original logic)
-			copyResult := RobustCopy(sourcePath, e.config.SourcePath, e.config.DestRoot, nil)
-			if !copyResult.Success {
-				e.stateManager.RecordCleanupFailure(sourcePath)
-				results.Failed++
-				continue
+			// Attempt re-copy; copies that stay bad are quarantined
+			repaired, quarantined := e.repairCopy(ctx, copier, sourcePath, relPath, expectedHash, destHash)
+			if repaired {
+				e.stateManager.MarkVerified(sourcePath, time.Now())
 			}
+			mu.Lock()
+			if repaired {
+				results.Verified++
+			} else if quarantined {
+				results.Quarantined++
+			}
+			mu.Unlock()
+			ev := AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Hash: destHash, Result: AuditFailed,
+				Error: fmt.Sprintf("hash mismatch: expected %s", expectedHash), ErrorCode: CodeHashMismatch}
+			issue := VerifyIssue{Kind: VerifyMismatch, SourcePath: sourcePath, DestPath: destPath, Repaired: repaired, Quarantined: quarantined}
+			if repaired {
+				ev.Result = AuditRepaired
+			} else if quarantined {
+				ev.Result = AuditQuarantined
+				if entry, ok := e.stateManager.Quarantined(sourcePath); ok {
+					issue.DestPath = filepath.Join(e.config.DestRoot, entry.Path)
+				}
+			}
+			e.audit(ev)
+			e.verifyIssue(progress, issue)
+		} else {
+			e.stateManager.MarkVerified(sourcePath, time.Now())
+			e.audit(AuditEvent{Op: AuditVerify, Path: sourcePath, Dest: relPath, Hash: destHash, Result: AuditOK})
+			mu.Lock()
+			results.Verified++
+			verifiedCount++
+			mu.Unlock()
 		}
+	}
+	
+	batches := verifyBatches(selected, verifyDest, diskID, e.config.VerifySequential)
+	workers := e.verifyWorkers()
+	verifyChan := make(chan []string, 1000)
+	var wg sync.WaitGroup
+	
+	// Start verification workers
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
 
-		// Verify hashes
-		destHash, err1 := calculateFileHash(destPath)
-		sourceHash, err2 := calculateFileHash(sourcePath)
+			for batch := range verifyChan {
+				for _, sourcePath := range batch {
+					select {
+					case <-ctx.Done():
+						return
+					default:
+					}
 
-		if err1 == nil && err2 == nil && sourceHash == expectedHash && destHash == expectedHash {
-			if err := os.Remove(sourcePath); err == nil {
-				e.stateManager.MarkDeleted(sourcePath, expectedHash)
-				results.Deleted++
-				if e.config.Reporter != nil && results.Deleted%10 == 0 {
-					e.config.Reporter.ReportLog("info", fmt.Sprintf("Deleted %d files so far...", results.Deleted))
+					progress.start(id, sourcePath)
+					check(sourcePath)
+					progress.done(id)
 				}
-			} else {
-				e.stateManager.RecordCleanupFailure(sourcePath)
-				results.Failed++
 			}
-		} else {
-			e.stateManager.RecordCleanupFailure(sourcePath)
-			results.Failed++
-		}
+		}(i)
 	}
 
-	// Final report
-	if e.config.Reporter != nil {
-		e.config.Reporter.ReportProgress(ProgressUpdate{
-			TotalFiles:   totalToProcess,
-			Completed:    results.Deleted,
-			Failed:       results.Failed,
-			Skipped:      results.Skipped,
-			ScanComplete: true,
-		})
-		e.config.Reporter.ReportLog("info", fmt.Sprintf("Cleanup complete: %d deleted, %d failed, %d skipped", 
-			results.Deleted, results.Failed, results.Skipped))
+	reportDone := make(chan struct{})
+	go e.reportVerifyProgressEvery(progress, reportDone)
+	
+	for _, batch := range batches {
+		verifyChan <- batch
 	}
+	close(verifyChan)
+	wg.Wait()
+	close(reportDone)
+	e.reportVerifyProgress(progress, true)
 
+	results.Issues = progress.issues
 	return results, nil
 }
 
 // ErrorSummary contains a summary of errors found in the log
 type ErrorSummary struct {
-	TotalErrors       int
-	CriticalErrors    int
-	DirectoryTimeouts int
-	DirectoryErrors   int
-	HashMismatches    int
-	CopyErrors        int
-	OtherErrors       int
-	TimeoutDirs       []string
-	ErrorDirs         []string
+	TotalErrors       int            `json:"totalErrors"`
+	CriticalErrors    int            `json:"criticalErrors"`
+	DirectoryTimeouts int            `json:"directoryTimeouts"`
+	DirectoryErrors   int            `json:"directoryErrors"`
+	HashMismatches    int            `json:"hashMismatches"`
+	CopyErrors        int            `json:"copyErrors"`
+	OtherErrors       int            `json:"otherErrors"`
+	TimeoutDirs       []string       `json:"timeoutDirs,omitempty"`
+	ErrorDirs         []string       `json:"errorDirs,omitempty"`
+	Codes             map[string]int `json:"codes,omitempty"` // ErrorCode -> count (nil for a free-form log)
 }
 
-// SummarizeErrorLog reads and summarizes the error log file
+// SummarizeErrorLog summarizes a free-form error log (gus_errors.log) by its wording; see
+// SummarizeErrors for the structured log the engine writes
 func SummarizeErrorLog(errorLogFile string) (ErrorSummary, error) {
 	file, err := os.Open(errorLogFile)
 	if os.IsNotExist(err) {
@@ -555,15 +1203,61 @@ func (e *Engine) reportProgress(final bool) {
 	e.stats.lastTotalBytes = e.stats.totalBytes
 	e.stats.lastStatsTime = now
 
+	// Live throughput sample (counts bytes of files still being copied)
+	if deltaTime.Seconds() > 0 {
+		sample := float64(e.stats.transferred-e.stats.lastTransferred) / deltaTime.Seconds()
+		e.stats.speedSamples = append(e.stats.speedSamples, sample)
+		if len(e.stats.speedSamples) > SpeedHistorySize {
+			e.stats.speedSamples = e.stats.speedSamples[len(e.stats.speedSamples)-SpeedHistorySize:]
+		}
+		if sample > e.stats.peakSpeed {
+			e.stats.peakSpeed = sample
+		}
+		if e.report != nil {
+			e.report.addSample(now, sample)
+		}
+	}
+	e.stats.lastTransferred = e.stats.transferred
+	speedSamples := append([]float64(nil), e.stats.speedSamples...)
+
+	var avgSpeed float64
+	if elapsed := now.Sub(e.stats.startTime).Seconds(); elapsed > 0 {
+		avgSpeed = float64(e.stats.transferred) / elapsed
+	}
+
 	e.workerStatus.Lock()
 	workerStatuses := make(map[int]string)
 	for i, s := range e.workerStatus.status {
 		workerStatuses[i] = s
 	}
+	workerBytes := make(map[int]int64, len(e.workerStatus.bytes))
+	for i, b := range e.workerStatus.bytes {
+		workerBytes[i] = b
+	}
+	workerFiles := make(map[int]WorkerFile, len(e.workerStatus.files))
+	for i, f := range e.workerStatus.files {
+		workerFiles[i] = f
+	}
 	e.workerStatus.Unlock()
 
+	// With a manifest the totals are known before the files are processed
+	totalFiles := e.stats.totalFiles
+	queued := e.queueLen()
+	if e.stats.manifestFiles > 0 {
+		totalFiles = e.stats.manifestFiles
+		queued = e.stats.manifestFiles - e.stats.totalFiles
+	}
+
+	backupCompleted, backupExpected, backupPercent := e.backupProgress(totalFiles)
+	smallQueue, largeQueue := e.queues.depths()
+
+	var eta int64
+	if !final && e.scanDone.Load() {
+		eta = estimateETA(queued, e.stats.completed, e.stats.totalBytes, avgSpeed)
+	}
+
 	update := ProgressUpdate{
-		TotalFiles:       e.stats.totalFiles,
+		TotalFiles:       totalFiles,
 		Completed:        e.stats.completed,
 		Failed:           e.stats.failed,
 		Skipped:          e.stats.skipped,
@@ -573,82 +1267,421 @@ func (e *Engine) reportProgress(final bool) {
 		Rate:             rate,
 		DeltaMB:          deltaMB,
 		WorkerStatuses:   workerStatuses,
+		ActiveWorkers:    e.limiter.getLimit(),
 		ScanComplete:     final,
+		ScanDone:         final || e.scanDone.Load(),
+		SpeedSamples:     speedSamples,
+		WorkerBytes:      workerBytes,
+		AvgSpeed:         avgSpeed,
+		PeakSpeed:        e.stats.peakSpeed,
+		ETASeconds:       eta,
+		ManifestBytes:    e.stats.manifestBytes,
+		BackupCompleted:  backupCompleted,
+		BackupExpected:   backupExpected,
+		BackupPercent:    backupPercent,
+		BackupBytes:      e.baseTotals.Bytes + e.stats.totalBytes,
+		Dirs:             e.dirStats(),
+		SpecialFiles:     e.links.stats(),
+		SmallQueue:       smallQueue,
+		LargeQueue:       largeQueue,
+		WorkerFiles:      workerFiles,
 	}
 
 	e.config.Reporter.ReportProgress(update)
 }
 
-func (e *Engine) worker(ctx context.Context, id int, jobChan <-chan FileJob, errorChan chan<- error, statsChan chan<- CopyStats, copier Copier, wg *sync.WaitGroup) {
+func (e *Engine) worker(ctx context.Context, id int, queues *jobQueues, errorChan chan<- error, statsChan chan<- CopyStats, copier Copier, wg *sync.WaitGroup) {
 	defer wg.Done()
+	defer e.recoverPanic("worker")
 
 	for {
-		select {
-		case <-ctx.Done():
+		if !e.limiter.acquire(ctx) {
 			return
-		case job, ok := <-jobChan:
-			if !ok {
-				return
-			}
+		}
+		if !e.processJob(ctx, id, queues, errorChan, statsChan, copier) {
+			e.limiter.release()
+			return
+		}
+		e.limiter.release()
+	}
+}
+
+// processJob takes the worker's next job from queues and copies it (for a batch, its files). Returns false when the worker should exit.
+func (e *Engine) processJob(ctx context.Context, id int, queues *jobQueues, errorChan chan<- error, statsChan chan<- CopyStats, copier Copier) bool {
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		job, ok := queues.next(ctx, id)
+		if !ok {
+			return false
+		}
+		var result jobResult
+		if len(job.Batch) > 0 {
+			result = e.copyBatch(ctx, id, job, errorChan, statsChan, copier)
+		} else {
+			result = e.copyJob(ctx, id, job, errorChan, statsChan, copier)
+		}
+		switch result {
+		case jobStop:
+			return false
+		case jobDone:
+			return true
+		}
+		// Nothing copied: take the next job in the same worker slot
+	}
+}
 
-			sourcePath := job.SourcePath
-			relPath := job.RelPath
+// jobResult is what became of a job
+type jobResult int
 
-			// Check if already done
-			if e.stateManager.IsDoneForSource(sourcePath, e.config.SourcePath) {
-				statsChan <- CopyStats{Skipped: true}
-				continue
-			}
+const (
+	jobSkipped jobResult = iota // nothing to copy (already backed up, given up on, a moved copy reused)
+	jobDone                     // copied, or failed
+	jobStop                     // the worker must exit (run canceled)
+)
 
-			if !e.stateManager.ShouldRetry(sourcePath) {
-				statsChan <- CopyStats{Skipped: true}
-				continue
-			}
+// copyJob copies the file of job, unless it is backed up already
+func (e *Engine) copyJob(ctx context.Context, id int, job FileJob, errorChan chan<- error, statsChan chan<- CopyStats, copier Copier) jobResult {
+	sourcePath := job.SourcePath
+	relPath := job.RelPath
+
+	// Already counted by the bulk transfer
+	if e.bulkDone[sourcePath] {
+		return jobSkipped
+	}
+
+	// Check if already done; with a Changed policy, files changed since are copied again
+	backedUp := e.stateManager.IsDoneForSource(sourcePath, e.config.SourcePath)
+	if backedUp {
+		if !e.recopyChanged(job, errorChan) {
+			statsChan <- CopyStats{Skipped: true, RelPath: relPath}
+			return jobSkipped
+		}
+	} else if !e.stateManager.ShouldRetry(sourcePath) {
+		statsChan <- CopyStats{Skipped: true, RelPath: relPath}
+		return jobSkipped
+	}
+
+	// Hold new files while paused, reconnecting, short of space or outside the transfer window
+	if !e.waitToCopy(ctx, id, job) {
+		return jobStop
+	}
+
+	// Names that collide in NFC are told apart before anything is named after them
+	if e.nfcCheck != nil {
+		e.nfcCheck.check(ctx, sourcePath, relPath)
+	}
+
+	// A new file with the content of a backed-up one moved: reuse that copy
+	if !backedUp && e.moves != nil && e.moves.reuse(ctx, job, statsChan) {
+		return jobSkipped
+	}
+
+	// The file can be abandoned on its own (SkipFile) without stopping the run
+	// (and carries the scanned size for copiers that scale their timeouts to it)
+	fileCtx, skipFile := context.WithCancelCause(withFileSize(ctx, job.Size))
+	defer skipFile(nil)
 
-			// Report starting
+	// Report starting
+	e.workerStatus.Lock()
+	e.workerStatus.status[id] = fmt.Sprintf("Starting: %s", filepath.Base(sourcePath))
+	e.workerStatus.files[id] = WorkerFile{Path: sourcePath, Size: job.Size}
+	e.workerStatus.skip[id] = skipFile
+	e.workerStatus.Unlock()
+	defer func() {
+		e.workerStatus.Lock()
+		delete(e.workerStatus.files, id)
+		delete(e.workerStatus.skip, id)
+		e.workerStatus.Unlock()
+	}()
+	e.audit(AuditEvent{Op: AuditCopyStart, Path: sourcePath, Dest: relPath, Bytes: job.Size})
+	e.publish(Event{Kind: EventFileStarted, Path: sourcePath, Dest: relPath, Bytes: job.Size})
+
+	// Copy, retrying transient errors (I/O errors, stalls) with backoff, and from
+	// scratch once the source is back after a connection loss
+	// Copiers that hash the file as they copy it hand the hash back, so the copy isn't
+	// hashed again here (commit has spot-checked it on disk)
+	started := time.Now()
+	var copiedHash string
+	bytesCopied, attempts, err := e.copyWithRetry(withCopyHash(fileCtx, &copiedHash), id, sourcePath, copier)
+	for err != nil && e.awaitReconnect(fileCtx, err) {
+		var more int
+		bytesCopied, more, err = e.copyWithRetry(withCopyHash(fileCtx, &copiedHash), id, sourcePath, copier)
+		attempts += more
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(fileCtx), ErrSkippedByUser) {
+		err = ErrSkippedByUser
+	}
+	copyEnd := AuditEvent{Op: AuditCopyEnd, Path: sourcePath, Dest: relPath, Bytes: bytesCopied,
+		DurationMS: time.Since(started).Milliseconds(), Attempts: attempts}
+
+	if err == nil {
+		// Mark done
+		e.setOwnership(e.destPath(sourcePath, relPath))
+		hash := copiedHash
+		if hash == "" {
+			hash, _ = e.hashLocal(e.destPath(sourcePath, relPath))
+		}
+		normalizedPath, _ := normalizePhonePath(sourcePath, e.config.SourcePath)
+		e.stateManager.MarkCompleted(state.CompletedFile{SourcePath: sourcePath, Hash: hash, NormalizedPath: normalizedPath,
+			Volume: volumeID(sourcePath), Size: bytesCopied})
+		e.stateManager.MarkSuccess()
+		copyEnd.Hash, copyEnd.Result = hash, AuditOK
+		e.audit(copyEnd)
+		e.publish(Event{Kind: EventFileCompleted, Path: sourcePath, Dest: relPath, Bytes: bytesCopied, Duration: time.Since(started), Hash: hash})
+
+		if len(e.config.PostProcessors) > 0 {
 			e.workerStatus.Lock()
-			e.workerStatus.status[id] = fmt.Sprintf("Starting: %s", filepath.Base(sourcePath))
+			e.workerStatus.status[id] = fmt.Sprintf("Post-processing: %s", filepath.Base(sourcePath))
 			e.workerStatus.Unlock()
+			e.runPostProcessors(ctx, PostCopyFile{
+				SourcePath: sourcePath,
+				DestPath:   e.destPath(sourcePath, relPath),
+				DestRoot:   e.config.DestRoot,
+				Hash:       hash,
+			}, errorChan)
+		}
+		
+		statsChan <- CopyStats{Success: true, BytesCopied: bytesCopied, RelPath: relPath, SourcePath: sourcePath, Duration: time.Since(started)}
+		
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = "idle"
+		e.workerStatus.Unlock()
+	} else if ctx.Err() != nil {
+		// Interrupted (Ctrl+C, job cancelled): not the file's fault, so don't
+		// spend its retry budget; the next run copies it again
+		copyEnd.Result = AuditCanceled
+		e.audit(copyEnd)
+		return jobStop
+	} else {
+		e.stateManager.RecordFailure(sourcePath)
+		e.notifyCopyError(ctx, err)
+		copyEnd.Result, copyEnd.Error, copyEnd.ErrorCode = AuditFailed, err.Error(), ErrorCode(err)
+		e.audit(copyEnd)
+		e.publish(Event{Kind: EventFileFailed, Path: sourcePath, Dest: relPath, Duration: time.Since(started),
+			Error: err.Error(), Code: ErrorCode(err)})
+		isTimeout := errors.Is(err, ErrStalled)
+		statsChan <- CopyStats{Success: false, IsTimeout: isTimeout, RelPath: relPath, SourcePath: sourcePath, Duration: time.Since(started), Err: err}
+		
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = fmt.Sprintf("Failed: %s", filepath.Base(sourcePath))
+		e.workerStatus.Unlock()
+		errorChan <- pathError(PhaseCopy, sourcePath, err)
+	}
+	return jobDone
+}
 
-			// Create progress channel for this copy
-			progressChan := make(chan int64, 10)
-			
-			// Monitor progress in goroutine
-			go func() {
-				for bytes := range progressChan {
-					e.workerStatus.Lock()
-					e.workerStatus.status[id] = fmt.Sprintf("Copying: %s (%s)", filepath.Base(sourcePath), formatSize(bytes))
-					e.workerStatus.Unlock()
+// SkipFile abandons the file worker is copying, e.g. one that keeps stalling: the copy is
+// stopped, the file recorded as failed once (it is tried again next run) and the worker
+// moves on to the next file without stopping the run. Returns the file's source path.
+func (e *Engine) SkipFile(worker int) (string, error) {
+	e.workerStatus.Lock()
+	skip, ok := e.workerStatus.skip[worker]
+	path := e.workerStatus.files[worker].Path
+	e.workerStatus.Unlock()
+	if !ok {
+		return "", fmt.Errorf("worker %d is not copying a file", worker)
+	}
+	skip(ErrSkippedByUser)
+	e.log("warn", fmt.Sprintf("Skipping %s (worker %d) at the user's request", path, worker))
+	return path, nil
+}
+
+// waitToCopy holds a job while the user has paused the run, the source is reconnecting,
+// the destination is short of space or the schedule is in a paused window. False means
+// the run ended while waiting.
+func (e *Engine) waitToCopy(ctx context.Context, id int, job FileJob) bool {
+	// Hold new files while the user has paused the run
+	if e.config.Pause != nil && e.config.Pause.Paused() {
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = "Paused"
+		e.workerStatus.Unlock()
+		if e.config.Pause.WaitIfPaused(ctx) != nil {
+			return false
+		}
+	}
+
+	// Hold new files while the source is reconnecting
+	if e.reconnect != nil && !e.reconnect.wait(ctx) {
+		return false
+	}
+
+	// Hold new files while the source is checked after files kept timing out
+	if e.breaker.holding() {
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = "Paused: checking the source after repeated timeouts"
+		e.workerStatus.Unlock()
+		if !e.breaker.wait(ctx) {
+			return false
+		}
+	}
+
+	// Hold new files while the destination is short of space
+	if e.diskGuard != nil {
+		need := job.Size
+		if need == 0 && e.sourceIsLocal() {
+			if info, err := os.Stat(job.SourcePath); err == nil {
+				need = info.Size()
+			}
+		}
+		waiting := func() {
+			e.workerStatus.Lock()
+			e.workerStatus.status[id] = "Paused: destination full"
+			e.workerStatus.Unlock()
+		}
+		if !e.diskGuard.waitForSpace(ctx, need, waiting) {
+			return false
+		}
+	}
+
+	// Hold new files while the schedule is in a paused window
+	if e.rateLimiter != nil && e.rateLimiter.Rate() == RatePaused {
+		e.workerStatus.Lock()
+		e.workerStatus.status[id] = "Waiting for transfer window"
+		e.workerStatus.Unlock()
+		if e.rateLimiter.WaitAllowed(ctx) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// chunkProgress records the progress of a chunked copy for the worker status, logging chunks
+// read again
+func (e *Engine) chunkProgress(sourcePath string, p ChunkProgress) {
+	var last ChunkProgress
+	if v, ok := e.chunks.Load(sourcePath); ok {
+		last = v.(ChunkProgress)
+	}
+	if p.Retries > last.Retries {
+		e.log("warn", fmt.Sprintf("Reading chunk %d/%d of %s again", p.Done+1, p.Total, sourcePath))
+	}
+	e.chunks.Store(sourcePath, p)
+}
+
+// runPostProcessors runs the configured post-copy steps for a file.
+// Failures are sent to errorChan as non-critical warnings.
+func (e *Engine) runPostProcessors(ctx context.Context, file PostCopyFile, errorChan chan<- error) {
+	for _, pp := range e.config.PostProcessors {
+		derived, err := pp.Process(ctx, file)
+		if err != nil {
+			errorChan <- pathError(PhasePostProcess, file.SourcePath, fmt.Errorf("%s failed for %s: %w", pp.Name(), file.SourcePath, err))
+			continue
+		}
+		if derived != "" {
+			e.stateManager.MarkConverted(file.SourcePath, derived)
+		}
+	}
+}
+
+// copyWithRetry copies a single file, retrying transient failures according to the retry
+// policy. It returns the bytes copied by the last attempt and the number of attempts.
+func (e *Engine) copyWithRetry(ctx context.Context, id int, sourcePath string, copier Copier) (int64, int, error) {
+	policy := e.config.Retry
+	for attempt := 1; ; attempt++ {
+		// Create progress channel for this attempt
+		progressChan := make(chan int64, 10)
+
+		// Monitor progress in goroutine (progress values are cumulative for this attempt);
+		// it hands back the attempt's byte count when the channel closes
+		attemptBytes := make(chan int64, 1)
+		go func() {
+			var last int64
+			defer func() { attemptBytes <- last }()
+			for bytes := range progressChan {
+				delta := bytes - last
+				last = bytes
+				status := fmt.Sprintf("Copying: %s (%s)", filepath.Base(sourcePath), formatSize(bytes))
+				if p, ok := e.chunks.Load(sourcePath); ok {
+					status = fmt.Sprintf("Copying: %s (%s, chunk %d/%d)", filepath.Base(sourcePath), formatSize(bytes), p.(ChunkProgress).Done+1, p.(ChunkProgress).Total)
 				}
-			}()
-
-			// Copy
-			bytesCopied, err := copier.Copy(ctx, sourcePath, e.config.SourcePath, e.config.DestRoot, progressChan)
-			close(progressChan)
-
-			if err == nil {
-				// Mark done
-				hash, _ := calculateFileHash(filepath.Join(e.config.DestRoot, relPath)) // Simplified
-				normalizedPath, _ := normalizePhonePath(sourcePath, e.config.SourcePath)
-				e.stateManager.MarkDone(sourcePath, hash, normalizedPath)
-				e.stateManager.MarkSuccess()
-				
-				statsChan <- CopyStats{Success: true, BytesCopied: bytesCopied}
-				
-				e.workerStatus.Lock()
-				e.workerStatus.status[id] = "idle"
-				e.workerStatus.Unlock()
-			} else {
-				e.stateManager.RecordFailure(sourcePath)
-				isTimeout := strings.Contains(err.Error(), "stalled")
-				statsChan <- CopyStats{Success: false, IsTimeout: isTimeout}
-				
 				e.workerStatus.Lock()
-				e.workerStatus.status[id] = fmt.Sprintf("Failed: %s", filepath.Base(sourcePath))
+				e.workerStatus.status[id] = status
+				e.workerStatus.bytes[id] += delta
+				if f, ok := e.workerStatus.files[id]; ok {
+					f.Copied = bytes
+					e.workerStatus.files[id] = f
+				}
 				e.workerStatus.Unlock()
-				errorChan <- err
+				e.stats.Lock()
+				e.stats.transferred += delta
+				e.stats.Unlock()
+			}
+		}()
+
+		bytesCopied, err := copier.Copy(ctx, sourcePath, e.config.SourcePath, e.config.DestRoot, progressChan)
+		close(progressChan)
+		counted := <-attemptBytes
+		e.chunks.Delete(sourcePath)
+
+		if errors.Is(err, ErrStalled) {
+			e.stats.Lock()
+			e.stats.stalls++
+			e.stats.Unlock()
+		}
+
+		if err == nil || attempt >= policy.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return bytesCopied, attempt, err
+		}
+
+		// The next attempt counts its bytes from 0 again; take this one's back so they aren't
+		// counted twice (lastTransferred too, so the speed sample doesn't drop below zero)
+		e.stats.Lock()
+		e.stats.transferred -= counted
+		e.stats.lastTransferred -= counted
+		e.stats.Unlock()
+
+		delay := policy.Backoff(attempt, nil)
+		e.workerStatus.Lock()
+		e.workerStatus.bytes[id] -= counted
+		e.workerStatus.status[id] = fmt.Sprintf("Retrying (%d/%d) in %v: %s", attempt+1, policy.MaxAttempts, delay.Round(time.Second), filepath.Base(sourcePath))
+		e.workerStatus.Unlock()
+		e.config.Reporter.ReportLog("warn", fmt.Sprintf("Attempt %d/%d failed for %s: %v (retrying in %v)", attempt, policy.MaxAttempts, sourcePath, err, delay.Round(time.Millisecond)))
+		e.audit(AuditEvent{Op: AuditRetry, Path: sourcePath, Attempts: attempt, Result: AuditFailed, Error: err.Error(), ErrorCode: ErrorCode(err)})
+
+		if sleepContext(ctx, delay) != nil {
+			return bytesCopied, attempt, err
+		}
+	}
+}
+
+// runBandwidthSchedule applies the bandwidth schedule to the rate limiter as window boundaries pass
+func (e *Engine) runBandwidthSchedule(done <-chan struct{}) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			rate := e.config.Bandwidth.RateAt(now)
+			if rate != e.rateLimiter.Rate() {
+				e.rateLimiter.SetRate(rate)
+				e.config.Reporter.ReportLog("info", fmt.Sprintf("Bandwidth window changed: %s", FormatRate(rate)))
 			}
 		}
 	}
 }
 
+// estimateETA returns the seconds needed to copy the queued files, assuming they
+// average the same size as the files copied so far (0 if there is no basis yet)
+func estimateETA(queued, completed int, completedBytes int64, avgSpeed float64) int64 {
+	if queued == 0 || completed == 0 || avgSpeed <= 0 {
+		return 0
+	}
+	avgFileBytes := float64(completedBytes) / float64(completed)
+	return int64(float64(queued) * avgFileBytes / avgSpeed)
+}
+
+// recoverPanic hands a goroutine panic to the configured PanicHandler before re-raising it.
+// Must be deferred directly.
+func (e *Engine) recoverPanic(component string) {
+	if e.config.PanicHandler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		e.config.PanicHandler("engine."+component, r, debug.Stack())
+		panic(r)
+	}
+}
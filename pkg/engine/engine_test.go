@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestEstimateETA(t *testing.T) {
+	// 10 queued files averaging 2MB at 1MB/s -> 20s
+	if eta := estimateETA(10, 5, 10<<20, 1<<20); eta != 20 {
+		t.Errorf("expected 20s, got %d", eta)
+	}
+	if eta := estimateETA(10, 0, 0, 1<<20); eta != 0 {
+		t.Errorf("expected unknown ETA with no completed files, got %d", eta)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"20G":   20 << 30,
+		"20GB":  20 << 30,
+		"500mb": 500 << 20,
+		"1.5T":  3 << 39,
+		"4096":  4096,
+		"2GiB":  2 << 30,
+	}
+	for in, want := range cases {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "lots", "-5G"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDestRelPath(t *testing.T) {
+	cases := []struct{ source, normalized, want string }{
+		{"/sdcard/DCIM/a.jpg", "DCIM/a.jpg", "DCIM/a.jpg"},
+		{"/run/user/1000/gvfs/mtp:host=X/Internal shared storage/DCIM/a.jpg", "DCIM/a.jpg", "Internal shared storage/DCIM/a.jpg"},
+		{"/run/user/1000/gvfs/mtp:host=X/SD card/DCIM/a.jpg", "DCIM/a.jpg", "SD card/DCIM/a.jpg"},
+		{"/sdcard/DCIM/a.jpg", "", ""},
+	}
+	for _, c := range cases {
+		if got := DestRelPath(c.source, c.normalized); got != c.want {
+			t.Errorf("DestRelPath(%q, %q) = %q, want %q", c.source, c.normalized, got, c.want)
+		}
+	}
+}
+
+func TestHashWorkers(t *testing.T) {
+	if e := NewEngine(EngineConfig{}, nil); cap(e.hashSlots) != runtime.NumCPU() {
+		t.Errorf("default hash workers = %d, want one per CPU", cap(e.hashSlots))
+	}
+	e := NewEngine(EngineConfig{NumWorkers: 4, HashWorkers: 1}, nil)
+	if e.verifyWorkers() != 4 {
+		t.Errorf("verify workers = %d, want -workers", e.verifyWorkers())
+	}
+	path := filepath.Join(t.TempDir(), "copy")
+	os.WriteFile(path, []byte("data"), 0644)
+
+	// The only hash worker is busy: hashing waits for it
+	e.hashSlots <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		e.hashLocal(path)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("hashed with no hash worker free")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-e.hashSlots
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hash never ran")
+	}
+}
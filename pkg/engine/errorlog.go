@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrorLogFileName is the error log the engine writes into DestRoot, one ErrorEvent per line
+const ErrorLogFileName = "gus_errors.jsonl"
+
+// Phases of a run an error can come from (ErrorEvent.Phase)
+const (
+	PhaseScan        = "scan"
+	PhaseCopy        = "copy"
+	PhasePostProcess = "postprocess"
+)
+
+// PathError is an error about one file or directory in one phase of a run. Its message is
+// the wrapped error's; errors.Is and ErrorCode see through it.
+type PathError struct {
+	Phase string
+	Path  string
+	Err   error
+}
+
+func (e *PathError) Error() string { return e.Err.Error() }
+func (e *PathError) Unwrap() error { return e.Err }
+
+// pathError attaches the phase and path to err
+func pathError(phase, path string, err error) error {
+	return &PathError{Phase: phase, Path: path, Err: err}
+}
+
+// ErrorEvent is one line of the error log
+type ErrorEvent struct {
+	Time     time.Time `json:"time"`
+	Code     string    `json:"code"` // see ErrorCode
+	Phase    string    `json:"phase,omitempty"`
+	Path     string    `json:"path,omitempty"` // the file or directory
+	Critical bool      `json:"critical,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// NewErrorEvent describes err, with the phase and path of a PathError in its chain
+func NewErrorEvent(err error) ErrorEvent {
+	ev := ErrorEvent{
+		Time:     time.Now().UTC(),
+		Code:     ErrorCode(err),
+		Critical: IsCritical(err),
+		Message:  err.Error(),
+	}
+	var pe *PathError
+	if errors.As(err, &pe) {
+		ev.Phase, ev.Path = pe.Phase, pe.Path
+	}
+	return ev
+}
+
+// recordError appends err to the error log of the run, if it is open
+func (e *Engine) recordError(err error) {
+	if e.errorLog == nil {
+		return
+	}
+	if werr := e.errorLog.append(NewErrorEvent(err)); werr != nil {
+		e.log("warn", werr.Error())
+	}
+}
+
+// ReadErrorLog calls fn for each event in an error log, oldest first. A missing log has no
+// events; lines that don't parse (a write torn by a crash) are skipped.
+func ReadErrorLog(path string, fn func(ErrorEvent) error) error {
+	return readJSONL(path, func(line []byte) error {
+		var ev ErrorEvent
+		if json.Unmarshal(line, &ev) != nil {
+			return nil
+		}
+		return fn(ev)
+	})
+}
+
+// SummarizeErrors summarizes an error log written by the engine (zero if there is none)
+func SummarizeErrors(path string) (ErrorSummary, error) {
+	var summary ErrorSummary
+	timeoutDirs := make(map[string]bool)
+	errorDirs := make(map[string]bool)
+	err := ReadErrorLog(path, func(ev ErrorEvent) error {
+		if summary.Codes == nil {
+			summary.Codes = make(map[string]int)
+		}
+		summary.Codes[ev.Code]++
+		switch {
+		case ev.Critical:
+			summary.CriticalErrors++
+		case ev.Code == CodeDirTimeout:
+			summary.DirectoryTimeouts++
+			if ev.Path != "" {
+				timeoutDirs[ev.Path] = true
+			}
+		case ev.Code == CodeHashMismatch:
+			summary.HashMismatches++
+		case ev.Phase == PhaseScan:
+			summary.DirectoryErrors++
+			if ev.Path != "" {
+				errorDirs[ev.Path] = true
+			}
+		case ev.Phase == PhaseCopy || ev.Code == CodeStalled:
+			summary.CopyErrors++
+		default:
+			summary.OtherErrors++
+		}
+		summary.TotalErrors++
+		return nil
+	})
+	summary.TimeoutDirs = sortedKeys(timeoutDirs)
+	summary.ErrorDirs = sortedKeys(errorDirs)
+	return summary, err
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
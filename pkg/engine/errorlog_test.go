@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeErrors(t *testing.T) {
+	dir := t.TempDir()
+	log := newJSONL(dir, ErrorLogFileName)
+	for _, err := range []error{
+		fmt.Errorf("CRITICAL: %w - source path no longer accessible: /phone", ErrConnectionLost),
+		pathError(PhaseScan, "/phone/DCIM", fmt.Errorf("%w: /phone/DCIM (continuing with discovered entries)", ErrDirTimeout)),
+		pathError(PhaseScan, "/phone/Android", errors.New("permission denied reading /phone/Android")),
+		pathError(PhaseCopy, "/phone/DCIM/a.jpg", fmt.Errorf("copy failed: %w", ErrStalled)),
+		pathError(PhaseCopy, "/phone/DCIM/b.jpg", errors.New("input/output error")),
+		pathError(PhasePostProcess, "/phone/DCIM/c.heic", errors.New("heif-convert failed")),
+		ErrHashMismatch,
+	} {
+		if werr := log.append(NewErrorEvent(err)); werr != nil {
+			t.Fatal(werr)
+		}
+	}
+	log.close()
+
+	summary, err := SummarizeErrors(filepath.Join(dir, ErrorLogFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ErrorSummary{
+		TotalErrors:       7,
+		CriticalErrors:    1,
+		DirectoryTimeouts: 1,
+		DirectoryErrors:   1,
+		HashMismatches:    1,
+		CopyErrors:        2,
+		OtherErrors:       1,
+		TimeoutDirs:       []string{"/phone/DCIM"},
+		ErrorDirs:         []string{"/phone/Android"},
+		Codes:             map[string]int{CodeConnectionLost: 1, CodeDirTimeout: 1, CodeStalled: 1, CodeHashMismatch: 1, CodeUnknown: 3},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary = %+v\nwant      %+v", summary, want)
+	}
+
+	if summary, err := SummarizeErrors(filepath.Join(dir, "missing.jsonl")); err != nil || summary.TotalErrors != 0 {
+		t.Errorf("missing log: %+v, %v", summary, err)
+	}
+}
+
+func TestPathErrorKeepsCode(t *testing.T) {
+	err := pathError(PhaseCopy, "/phone/a.jpg", fmt.Errorf("copy failed: %w", ErrStalled))
+	if !errors.Is(err, ErrStalled) || ErrorCode(err) != CodeStalled {
+		t.Errorf("code = %s, want %s", ErrorCode(err), CodeStalled)
+	}
+	ev := NewErrorEvent(fmt.Errorf("worker: %w", err))
+	if ev.Phase != PhaseCopy || ev.Path != "/phone/a.jpg" || ev.Message != "worker: copy failed: copy stalled" {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestJSONLCreatedOnFirstAppend(t *testing.T) {
+	dir := t.TempDir()
+	log := newJSONL(dir, ErrorLogFileName)
+	log.close()
+	if _, err := os.Stat(filepath.Join(dir, ErrorLogFileName)); !os.IsNotExist(err) {
+		t.Errorf("a log with nothing appended was created: %v", err)
+	}
+}
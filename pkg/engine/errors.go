@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Error codes exposed to JSON/API consumers
+const (
+	CodeConnectionLost = "connection_lost"
+	CodeStalled        = "stalled"
+	CodeDirTimeout     = "dir_timeout"
+	CodeHashMismatch   = "hash_mismatch"
+	CodeChanged        = "changed_on_source"
+	CodeSkipped        = "skipped_by_user"
+	CodeUnknown        = "error"
+)
+
+// engineError is a sentinel error carrying a stable machine-readable code.
+// Wrap it with fmt.Errorf("%w: ...") to add context; errors.Is still matches.
+type engineError struct {
+	code string
+	msg  string
+}
+
+func (e *engineError) Error() string { return e.msg }
+
+// ErrorCode returns the stable code for this error class
+func (e *engineError) ErrorCode() string { return e.code }
+
+var (
+	// ErrConnectionLost means the device/mount went away - the run should abort
+	ErrConnectionLost error = &engineError{code: CodeConnectionLost, msg: "connection lost"}
+	// ErrStalled means a transfer made no progress within the stall timeout - the file can be retried
+	ErrStalled error = &engineError{code: CodeStalled, msg: "copy stalled"}
+	// ErrDirTimeout means a directory listing did not finish in time - the directory is rescanned next run
+	ErrDirTimeout error = &engineError{code: CodeDirTimeout, msg: "directory read timeout"}
+	// ErrHashMismatch means source and destination contents differ after a copy
+	ErrHashMismatch error = &engineError{code: CodeHashMismatch, msg: "hash mismatch"}
+	// ErrChangedOnSource means a backed-up file was modified on the source afterwards and the
+	// backup was left as is (ChangedReport)
+	ErrChangedOnSource error = &engineError{code: CodeChanged, msg: "changed on the source since it was backed up"}
+	// ErrSkippedByUser means the user abandoned the file while it was being copied
+	// (Engine.SkipFile); it counts as one failure and is tried again next run
+	ErrSkippedByUser error = &engineError{code: CodeSkipped, msg: "skipped by the user"}
+)
+
+// ErrorCode returns the machine-readable code for err (CodeUnknown if it is not a typed engine error)
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return CodeUnknown
+}
+
+// IsCritical reports whether err should abort the run rather than just skip a file
+func IsCritical(err error) bool {
+	return errors.Is(err, ErrConnectionLost)
+}
+
+// isConnectionError reports whether a stat error on a source root means the
+// underlying transport (MTP/gvfs, FUSE) has gone away rather than a per-file problem.
+// gvfs removes the mount directory entirely when the phone is unplugged, so a
+// missing root counts as a dropped connection too.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return os.IsNotExist(err) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ENODEV) ||
+		errors.Is(err, syscall.ENOTCONN) ||
+		errors.Is(err, syscall.ESTALE)
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{errors.New("plain"), CodeUnknown},
+		{ErrStalled, CodeStalled},
+		{fmt.Errorf("%w: no progress for 30s", ErrStalled), CodeStalled},
+		{fmt.Errorf("CRITICAL: %w - gone", ErrConnectionLost), CodeConnectionLost},
+		{fmt.Errorf("%w: /sdcard/DCIM", ErrDirTimeout), CodeDirTimeout},
+		{fmt.Errorf("%w: source=a, dest=b", ErrHashMismatch), CodeHashMismatch},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorCode(tt.err); got != tt.expected {
+			t.Errorf("ErrorCode(%v) = %q, expected %q", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestIsCritical(t *testing.T) {
+	if !IsCritical(fmt.Errorf("%w during copy: device gone", ErrConnectionLost)) {
+		t.Error("expected wrapped ErrConnectionLost to be critical")
+	}
+	if IsCritical(fmt.Errorf("%w: no progress", ErrStalled)) {
+		t.Error("expected ErrStalled not to be critical")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&os.PathError{Op: "stat", Path: "/run/user/1000/gvfs/mtp:host=X", Err: syscall.ENOTCONN}, true},
+		{&os.PathError{Op: "stat", Path: "/mnt/phone", Err: syscall.EIO}, true},
+		{&os.PathError{Op: "stat", Path: "/mnt/phone", Err: syscall.ENOENT}, true},
+		{&os.PathError{Op: "stat", Path: "/mnt/phone", Err: syscall.EACCES}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.expected {
+			t.Errorf("isConnectionError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
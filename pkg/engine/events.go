@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// EventKind is the type of an Event
+type EventKind string
+
+// Kinds of Event published on EngineConfig.Events
+const (
+	EventFileStarted    EventKind = "file:started"    // a file is about to be copied
+	EventFileCompleted  EventKind = "file:completed"  // a file was copied and recorded
+	EventFileFailed     EventKind = "file:failed"     // a file could not be copied (after its retries)
+	EventDirScanned     EventKind = "dir:scanned"     // a source folder was listed (mount mode)
+	EventConnectionLost EventKind = "connection:lost" // the source became unreachable; the run waits for it
+	EventVerifyMismatch EventKind = "verify:mismatch" // verification found a copy that differs or is missing
+)
+
+// Event is one thing that happened during a run, verification or cleanup. Which fields are
+// set depends on Kind.
+type Event struct {
+	Kind EventKind `json:"kind"`
+	Time time.Time `json:"time"`
+	// Path is the source file or, for EventDirScanned, the source folder
+	Path     string        `json:"path,omitempty"`
+	Dest     string        `json:"dest,omitempty"`     // relative to the destination directory
+	Bytes    int64         `json:"bytes,omitempty"`    // started: size if known; completed: bytes copied
+	Files    int           `json:"files,omitempty"`    // dir:scanned: files found in the folder itself
+	Duration time.Duration `json:"duration,omitempty"` // completed and failed: from the first attempt to the last
+	Hash     string        `json:"hash,omitempty"`     // completed: SHA-256 of the copy
+	Error    string        `json:"error,omitempty"`
+	Code     string        `json:"code,omitempty"` // see ErrorCode
+	// Issue is the file that did not verify (EventVerifyMismatch)
+	Issue *VerifyIssue `json:"issue,omitempty"`
+}
+
+// EventBus passes the engine's events to the subscribers that asked for their kind. Handlers
+// are called synchronously from the engine's goroutines, often several at once: they must be
+// safe for concurrent use and return quickly (hand slow work to a goroutine or a channel).
+type EventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]subscription
+}
+
+type subscription struct {
+	handler func(Event)
+	kinds   map[EventKind]bool // nil = all kinds
+}
+
+// NewEventBus returns a bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]subscription)}
+}
+
+// Subscribe calls handler for each event of the given kinds (none = every kind) until the
+// returned function is called
+func (b *EventBus) Subscribe(handler func(Event), kinds ...EventKind) (unsubscribe func()) {
+	sub := subscription{handler: handler}
+	if len(kinds) > 0 {
+		sub.kinds = make(map[EventKind]bool, len(kinds))
+		for _, k := range kinds {
+			sub.kinds[k] = true
+		}
+	}
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish passes ev to the subscribers of its kind; a nil bus drops it
+func (b *EventBus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub.kinds == nil || sub.kinds[ev.Kind] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(ev)
+	}
+}
+
+// publish sends ev to EngineConfig.Events, if set
+func (e *Engine) publish(ev Event) {
+	e.config.Events.Publish(ev)
+}
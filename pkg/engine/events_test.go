@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	var all, failed []EventKind
+	unsubscribe := bus.Subscribe(func(ev Event) { all = append(all, ev.Kind) })
+	bus.Subscribe(func(ev Event) {
+		if ev.Time.IsZero() {
+			t.Error("event published without a time")
+		}
+		failed = append(failed, ev.Kind)
+	}, EventFileFailed)
+
+	bus.Publish(Event{Kind: EventFileStarted})
+	bus.Publish(Event{Kind: EventFileFailed})
+	unsubscribe()
+	unsubscribe() // a second call is harmless
+	bus.Publish(Event{Kind: EventFileFailed})
+
+	if len(all) != 2 {
+		t.Errorf("unfiltered subscriber got %v, want the two events before unsubscribing", all)
+	}
+	if len(failed) != 2 || failed[0] != EventFileFailed {
+		t.Errorf("file:failed subscriber got %v", failed)
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(Event{Kind: EventFileStarted}) // no bus, no panic
+}
+
+func TestRunPublishesEvents(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "a.jpg"), []byte("aaaa"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	bus := NewEventBus()
+	var mu sync.Mutex
+	events := make(map[EventKind][]Event)
+	bus.Subscribe(func(ev Event) {
+		mu.Lock()
+		events[ev.Kind] = append(events[ev.Kind], ev)
+		mu.Unlock()
+	})
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   filepath.Join(dir, "backup"),
+		NumWorkers: 1,
+		Reporter:   discardReporter{},
+		Events:     bus,
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	sourcePath := filepath.Join(source, "DCIM", "a.jpg")
+	if got := events[EventFileStarted]; len(got) != 1 || got[0].Path != sourcePath {
+		t.Errorf("file:started events = %+v", got)
+	}
+	if got := events[EventFileCompleted]; len(got) != 1 || got[0].Bytes != 4 || got[0].Hash == "" || got[0].Dest != filepath.Join("DCIM", "a.jpg") {
+		t.Errorf("file:completed events = %+v", got)
+	}
+	var dcim *Event
+	for i, ev := range events[EventDirScanned] {
+		if ev.Path == filepath.Join(source, "DCIM") {
+			dcim = &events[EventDirScanned][i]
+		}
+	}
+	if dcim == nil || dcim.Files != 1 {
+		t.Errorf("dir:scanned events = %+v, want DCIM with 1 file", events[EventDirScanned])
+	}
+	if len(events[EventFileFailed]) != 0 {
+		t.Errorf("unexpected failures: %+v", events[EventFileFailed])
+	}
+}
+
+// stuckCopier hangs on stuck.bin until its context ends, like a transfer that stopped moving
+type stuckCopier struct{ *FSCopier }
+
+func (c stuckCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	if filepath.Base(sourcePath) == "stuck.bin" {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	return c.FSCopier.Copy(ctx, sourcePath, sourceRoot, destRoot, progressChan)
+}
+
+func TestSkipFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "stuck.bin"), []byte("stuck"), 0644)
+	os.WriteFile(filepath.Join(source, "DCIM", "z.jpg"), []byte("zz"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	bus := NewEventBus()
+	var mu sync.Mutex
+	var failed, completed []Event
+	bus.Subscribe(func(ev Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ev.Kind == EventFileFailed {
+			failed = append(failed, ev)
+		} else {
+			completed = append(completed, ev)
+		}
+	}, EventFileFailed, EventFileCompleted)
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   filepath.Join(dir, "backup"),
+		NumWorkers: 1,
+		Reporter:   discardReporter{},
+		Events:     bus,
+		Copier:     stuckCopier{NewFSCopier()},
+	}, sm)
+	if _, err := e.SkipFile(0); err == nil {
+		t.Error("expected an idle worker to have nothing to skip")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- e.Run(context.Background()) }()
+	var skipped string
+	for deadline := time.Now().Add(5 * time.Second); skipped == "" && time.Now().Before(deadline); {
+		skipped, _ = e.SkipFile(0)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if skipped != filepath.Join(source, "DCIM", "stuck.bin") {
+		t.Fatalf("SkipFile skipped %q", skipped)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not finish after the skip")
+	}
+
+	// The skipped file failed once; the run went on to the next file
+	if len(failed) != 1 || failed[0].Path != skipped || failed[0].Code != CodeSkipped {
+		t.Errorf("file:failed events = %+v", failed)
+	}
+	if len(completed) != 1 || filepath.Base(completed[0].Path) != "z.jpg" {
+		t.Errorf("file:completed events = %+v", completed)
+	}
+}
@@ -0,0 +1,331 @@
+package engine
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter applies user-defined exclude rules on top of the built-in exclusions
+// (shouldExcludeFile). A nil *Filter excludes nothing.
+type Filter struct {
+	excludes []*regexp.Regexp
+	limits   FileLimits
+	only     map[string]bool // extensions kept by the media presets (nil = all)
+	priority []string        // the presets' folders, scanned before PriorityPaths
+	custom   []string        // replaces the default PriorityPaths (nil = the defaults)
+	noOrder  bool            // priority ordering disabled
+	apps     []string        // the AppPresets' folders, lower case; their files are all kept
+}
+
+// FileLimits restricts the backup to files by size and modification time; zero fields
+// don't limit anything
+type FileLimits struct {
+	MinSize   int64     // skip files smaller than this many bytes
+	MaxSize   int64     // skip files larger than this many bytes
+	NewerThan time.Time // skip files modified before this
+	OlderThan time.Time // skip files modified after this
+}
+
+// IsZero reports whether l limits nothing
+func (l FileLimits) IsZero() bool {
+	return l.MinSize <= 0 && l.MaxSize <= 0 && l.NewerThan.IsZero() && l.OlderThan.IsZero()
+}
+
+// findArgs returns find(1) tests that select the files within l, for adb mode. Toybox find
+// has no -newermt, so the time limits are given in minutes before now, which is precise
+// enough for limits given in days.
+func (l FileLimits) findArgs(now time.Time) []string {
+	var args []string
+	if l.MinSize > 0 {
+		args = append(args, "-size", "+"+strconv.FormatInt(l.MinSize-1, 10)+"c")
+	}
+	if l.MaxSize > 0 {
+		args = append(args, "-size", "-"+strconv.FormatInt(l.MaxSize+1, 10)+"c")
+	}
+	if !l.NewerThan.IsZero() {
+		minutes := int64(now.Sub(l.NewerThan)/time.Minute) + 1
+		args = append(args, "-mmin", "-"+strconv.FormatInt(minutes, 10))
+	}
+	if !l.OlderThan.IsZero() {
+		if minutes := int64(now.Sub(l.OlderThan) / time.Minute); minutes > 0 {
+			args = append(args, "-mmin", "+"+strconv.FormatInt(minutes-1, 10))
+		}
+	}
+	return args
+}
+
+// NewFilter compiles exclude patterns. Patterns are case-insensitive globs:
+//
+//	"*.mp3"             any file with that extension (no "/" = matched against the file name)
+//	"WhatsApp/**"       everything below a folder (relative to the source root)
+//	"**/Thumbnails/**"  a folder name anywhere in the tree
+//	"DCIM/.trash*"      "*" and "?" never cross "/"
+func NewFilter(excludes []string) (*Filter, error) {
+	f := &Filter{}
+	for _, pattern := range excludes {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		f.excludes = append(f.excludes, re)
+	}
+	return f, nil
+}
+
+// SetPresets restricts the filter to the files of the named MediaPresets ("photos",
+// "videos", ...) and scans their folders first. No names keeps every file.
+func (f *Filter) SetPresets(names []string) error {
+	f.only, f.priority = nil, nil
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		preset, err := lookupMediaPreset(name)
+		if err != nil {
+			return err
+		}
+		if f.only == nil {
+			f.only = make(map[string]bool)
+		}
+		for _, ext := range preset.Extensions {
+			f.only[ext] = true
+		}
+		for _, folder := range preset.Folders {
+			if !slices.Contains(f.priority, folder) {
+				f.priority = append(f.priority, folder)
+			}
+		}
+	}
+	return nil
+}
+
+// SetApps adds the folders of the named AppPresets ("whatsapp", ...): files in them are kept
+// whatever their type, where the built-in rules only keep media files from Android/media
+func (f *Filter) SetApps(names []string) error {
+	folders, err := AppFolders(names)
+	if err != nil {
+		return err
+	}
+	f.apps = nil
+	for _, folder := range folders {
+		f.apps = append(f.apps, strings.ToLower(folder)+"/")
+	}
+	return nil
+}
+
+// AppFolders returns the folders of the named AppPresets, in order
+func AppFolders(names []string) ([]string, error) {
+	var folders []string
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		preset, err := LookupAppPreset(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range preset.Folders {
+			if !slices.Contains(folders, folder) {
+				folders = append(folders, folder)
+			}
+		}
+	}
+	return folders, nil
+}
+
+// SetPriorityPaths replaces the default PriorityPaths with paths (relative to the source
+// root, first listed first); nil restores the defaults
+func (f *Filter) SetPriorityPaths(paths []string) error {
+	if paths == nil {
+		f.custom = nil
+		return nil
+	}
+	custom, err := NormalizePriorityPaths(paths)
+	if err != nil {
+		return err
+	}
+	f.custom = custom
+	return nil
+}
+
+// SetPriorityOrder turns priority ordering on or off; off, scanners list the folders in
+// their usual order, the media presets' ones included
+func (f *Filter) SetPriorityOrder(enabled bool) {
+	f.noOrder = !enabled
+}
+
+// PriorityPaths returns the folders scanners list first: those of the media presets, then
+// the configured priority paths (by default PriorityPaths). None once ordering is off.
+func (f *Filter) PriorityPaths() []string {
+	if f == nil {
+		return PriorityPaths
+	}
+	if f.noOrder {
+		return nil
+	}
+	base := PriorityPaths
+	if f.custom != nil {
+		base = f.custom
+	}
+	if len(f.priority) == 0 {
+		return base
+	}
+	paths := slices.Clone(f.priority)
+	for _, p := range base {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// NormalizePriorityPaths cleans a list of priority folders: relative to the source root,
+// "/"-separated, in the given order, without duplicates. Empty entries are dropped.
+func NormalizePriorityPaths(paths []string) ([]string, error) {
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = strings.Trim(path.Clean(strings.ReplaceAll(p, "\\", "/")), "/")
+		if p == "" || p == "." {
+			continue
+		}
+		if p == ".." || strings.HasPrefix(p, "../") {
+			return nil, fmt.Errorf("priority path %q is outside the source", p)
+		}
+		if !slices.Contains(result, p) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// SetLimits makes the filter skip files outside l as well
+func (f *Filter) SetLimits(l FileLimits) {
+	f.limits = l
+}
+
+// HasLimits reports whether the filter has size or time limits, i.e. whether scanners
+// need each file's size and modification time
+func (f *Filter) HasLimits() bool {
+	return f != nil && !f.limits.IsZero()
+}
+
+// OutsideLimits reports whether a file with this size and modification time is outside the
+// size and time limits. A negative size or zero time means unknown and isn't checked.
+func (f *Filter) OutsideLimits(size int64, modTime time.Time) bool {
+	if !f.HasLimits() {
+		return false
+	}
+	l := f.limits
+	if size >= 0 {
+		if l.MinSize > 0 && size < l.MinSize {
+			return true
+		}
+		if l.MaxSize > 0 && size > l.MaxSize {
+			return true
+		}
+	}
+	if !modTime.IsZero() {
+		if !l.NewerThan.IsZero() && modTime.Before(l.NewerThan) {
+			return true
+		}
+		if !l.OlderThan.IsZero() && modTime.After(l.OlderThan) {
+			return true
+		}
+	}
+	return false
+}
+
+// findArgs returns the find(1) tests for the filter's limits (none for a nil filter)
+func (f *Filter) findArgs(now time.Time) []string {
+	if !f.HasLimits() {
+		return nil
+	}
+	return f.limits.findArgs(now)
+}
+
+// inApp reports whether a file or folder is in one of the apps' folders
+func (f *Filter) inApp(normalizedPath string) bool {
+	if f == nil || len(f.apps) == 0 {
+		return false
+	}
+	p := strings.ToLower(strings.TrimPrefix(strings.ReplaceAll(normalizedPath, "\\", "/"), "/")) + "/"
+	for _, folder := range f.apps {
+		if strings.HasPrefix(p, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultExcluded applies the built-in exclusions (shouldExcludeFile) to a file, except that
+// those in the apps' folders are kept whatever their type
+func (f *Filter) defaultExcluded(normalizedPath string) bool {
+	if f.inApp(normalizedPath) {
+		return defaultExclusion(normalizedPath, false)
+	}
+	return shouldExcludeFile(normalizedPath)
+}
+
+// Excluded reports whether a file (path relative to the source root) matches an exclude rule
+// or isn't one of the media presets' types (files of the apps are of every type)
+func (f *Filter) Excluded(normalizedPath string) bool {
+	if f == nil {
+		return false
+	}
+	p := strings.TrimPrefix(strings.ReplaceAll(normalizedPath, "\\", "/"), "/")
+	base := path.Base(p)
+	if f.only != nil && !f.only[strings.ToLower(path.Ext(base))] && !f.inApp(p) {
+		return true
+	}
+	for _, re := range f.excludes {
+		if re.MatchString(p) || re.MatchString(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp converts a glob pattern to an anchored, case-insensitive regexp.
+// Patterns without "/" only match file names (callers test the base name too).
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterExcluded(t *testing.T) {
+	f, err := NewFilter([]string{"*.mp3", "WhatsApp/**", "**/Thumbnails/**", "DCIM/.trash*"})
+	if err != nil {
+		t.Fatalf("NewFilter failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"Music/song.MP3", true},
+		{"WhatsApp/Media/img.jpg", true},
+		{"Pictures/WhatsApp/img.jpg", false},
+		{"DCIM/Thumbnails/a.jpg", true},
+		{"Thumbnails/a.jpg", true},
+		{"DCIM/.trashed-123.jpg", true},
+		{"DCIM/Camera/.trash.jpg", false},
+		{"DCIM/Camera/IMG_1.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := f.Excluded(tt.path); got != tt.want {
+			t.Errorf("Excluded(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	var nilFilter *Filter
+	if nilFilter.Excluded("anything") {
+		t.Error("nil filter should exclude nothing")
+	}
+}
+
+func TestFilterLimits(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	f, _ := NewFilter(nil)
+	if f.HasLimits() || f.OutsideLimits(1, now) {
+		t.Fatal("a filter without limits should keep everything")
+	}
+	f.SetLimits(FileLimits{MinSize: 10, MaxSize: 100, NewerThan: now.AddDate(0, 0, -30), OlderThan: now.AddDate(0, 0, -1)})
+
+	tests := []struct {
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{50, now.AddDate(0, 0, -7), false},
+		{10, now.AddDate(0, 0, -7), false},
+		{100, now.AddDate(0, 0, -7), false},
+		{9, now.AddDate(0, 0, -7), true},
+		{101, now.AddDate(0, 0, -7), true},
+		{50, now.AddDate(0, 0, -31), true},
+		{50, now, true},
+		{-1, now.AddDate(0, 0, -7), false}, // size unknown
+		{50, time.Time{}, false},           // time unknown
+	}
+	for _, tt := range tests {
+		if got := f.OutsideLimits(tt.size, tt.modTime); got != tt.want {
+			t.Errorf("OutsideLimits(%d, %v) = %v, want %v", tt.size, tt.modTime, got, tt.want)
+		}
+	}
+
+	var nilFilter *Filter
+	if nilFilter.HasLimits() || nilFilter.OutsideLimits(0, now) || nilFilter.findArgs(now) != nil {
+		t.Error("nil filter should have no limits")
+	}
+}
+
+func TestFileLimitsFindArgs(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	limits := FileLimits{MinSize: 1024, MaxSize: 4 << 30, NewerThan: now.Add(-48 * time.Hour), OlderThan: now.Add(-time.Hour)}
+	want := []string{"-size", "+1023c", "-size", "-4294967297c", "-mmin", "-2881", "-mmin", "+59"}
+	if got := limits.findArgs(now); !reflect.DeepEqual(got, want) {
+		t.Errorf("findArgs = %v, want %v", got, want)
+	}
+	if got := (FileLimits{}).findArgs(now); got != nil {
+		t.Errorf("no limits should give no find args, got %v", got)
+	}
+}
+
+func TestFilterPresets(t *testing.T) {
+	f, err := NewFilter([]string{"WhatsApp/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPresets([]string{"Photos", "audio"}); err != nil {
+		t.Fatalf("SetPresets failed: %v", err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"DCIM/Camera/IMG_1.JPG", false},
+		{"Pictures/a.heic", false},
+		{"Music/song.flac", false},
+		{"DCIM/Camera/VID_1.mp4", true},
+		{"Documents/cv.pdf", true},
+		{"Download/noext", true},
+		{"WhatsApp/Media/img.jpg", true},
+	}
+	for _, tt := range tests {
+		if got := f.Excluded(tt.path); got != tt.want {
+			t.Errorf("Excluded(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	priority := f.PriorityPaths()
+	if want := []string{"DCIM", "Camera", "Pictures", "Screenshots", "Music", "Recordings"}; !reflect.DeepEqual(priority[:len(want)], want) {
+		t.Errorf("PriorityPaths starts with %v, want %v", priority[:len(want)], want)
+	}
+	if len(priority) != len(PriorityPaths)+3 { // Recordings, Podcasts and Audiobooks aren't default priority paths
+		t.Errorf("PriorityPaths has %d entries, want %d", len(priority), len(PriorityPaths)+3)
+	}
+
+	if err := f.SetPresets([]string{"ebooks"}); err == nil {
+		t.Error("SetPresets should reject an unknown preset")
+	}
+	if err := f.SetPresets(nil); err != nil || f.Excluded("Documents/cv.pdf") || !reflect.DeepEqual(f.PriorityPaths(), PriorityPaths) {
+		t.Error("no presets should keep every file and the default priority paths")
+	}
+}
+
+func TestFilterPriorityPaths(t *testing.T) {
+	f, _ := NewFilter(nil)
+	if err := f.SetPriorityPaths([]string{" WhatsApp/Media/ ", "DCIM", "DCIM", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"WhatsApp/Media", "DCIM"}; !reflect.DeepEqual(f.PriorityPaths(), want) {
+		t.Errorf("PriorityPaths = %v, want %v", f.PriorityPaths(), want)
+	}
+
+	// The presets' folders still come first
+	f.SetPresets([]string{"audio"})
+	if got := f.PriorityPaths(); got[0] != "Music" || got[len(got)-2] != "WhatsApp/Media" {
+		t.Errorf("PriorityPaths = %v, want the audio folders, then WhatsApp/Media and DCIM", got)
+	}
+
+	f.SetPriorityOrder(false)
+	if got := f.PriorityPaths(); len(got) != 0 {
+		t.Errorf("with ordering off PriorityPaths = %v, want none", got)
+	}
+	f.SetPriorityOrder(true)
+	f.SetPresets(nil)
+	if err := f.SetPriorityPaths(nil); err != nil || !reflect.DeepEqual(f.PriorityPaths(), PriorityPaths) {
+		t.Errorf("nil should restore the default PriorityPaths, got %v", f.PriorityPaths())
+	}
+	if err := f.SetPriorityPaths([]string{"../Music"}); err == nil {
+		t.Error("SetPriorityPaths should reject a folder outside the source")
+	}
+}
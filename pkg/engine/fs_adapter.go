@@ -4,6 +4,7 @@ import (
 	"GusSync/pkg/state"
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -16,13 +17,16 @@ import (
 // normalizePhonePath is defined in copy.go - we import it here for use
 
 const (
-	// DirReadTimeout is the timeout for reading a single directory (important for MTP)
+	// DirReadTimeout is the default timeout for reading a single directory (important for MTP)
 	DirReadTimeout = 60 * time.Second
+	// DefaultScanWorkers is how many directories are read concurrently by default; MTP
+	// serialises requests, so more mostly adds queueing (and timeouts)
+	DefaultScanWorkers = 4
 )
 
 // getPathPriority returns a priority score for a path (lower = higher priority)
 // Priority paths (DCIM, Camera, Pictures, etc.) get lower scores
-func getPathPriority(relPath string, rootPath string) int {
+func getPathPriority(relPath string, rootPath string, priorityPaths []string) int {
 	// Calculate relative path from root
 	rel, err := filepath.Rel(rootPath, relPath)
 	if err != nil {
@@ -38,7 +42,7 @@ func getPathPriority(relPath string, rootPath string) int {
 	firstDir := parts[0]
 	
 	// Check if this is a priority path
-	for i, priorityPath := range PriorityPaths {
+	for i, priorityPath := range priorityPaths {
 		// Check exact match or if path starts with priority path
 		if firstDir == priorityPath || strings.HasPrefix(rel, priorityPath) {
 			return i // Lower number = higher priority
@@ -53,6 +57,15 @@ func getPathPriority(relPath string, rootPath string) int {
 type FSScanner struct {
 	closeJobChan func() // Function to safely close jobChan (uses sync.Once)
 	stateManager *state.StateManager // State manager for directory tracking
+	scanRoots    []string            // Folders (relative to root) to scan; empty = whole root
+	filter       *Filter             // User-defined exclude rules (nil = none)
+	incremental  bool                // Skip listing completed directories whose mtime/size are unchanged
+	dirTimeout   time.Duration       // Per-directory read timeout (0 = DirReadTimeout)
+	scanWorkers  int                 // Max directories read concurrently (0 = DefaultScanWorkers)
+	scanSlots    chan struct{}       // Semaphore for concurrent directory scans (per Scan)
+	links        *linkPolicy         // Handles links and special files (nil = skipped)
+	reconnect    func(ctx context.Context, err error) bool // Waits out a connection loss; true = retry
+	dirScanned   func(dir string, files int)               // Told about each directory listed (nil = nobody)
 }
 
 // NewFSScanner creates a new filesystem scanner
@@ -67,6 +80,47 @@ func (fs *FSScanner) SetStateManager(sm *state.StateManager) {
 	fs.stateManager = sm
 }
 
+// SetScanRoots limits scanning to the given folders (relative to the scan root)
+func (fs *FSScanner) SetScanRoots(roots []string) {
+	fs.scanRoots = roots
+}
+
+// SetFilter applies user-defined exclude rules during scanning
+func (fs *FSScanner) SetFilter(f *Filter) {
+	fs.filter = f
+}
+
+// SetIncremental skips re-reading completed directories whose mtime and size haven't changed
+// since they were last read completely (requires a state manager). Only safe where the
+// filesystem updates directory mtimes when entries are added or removed.
+func (fs *FSScanner) SetIncremental(incremental bool) {
+	fs.incremental = incremental
+}
+
+// SetDirReadTimeout sets how long reading one directory may take before the scan moves on
+// with the entries found so far (0 = DirReadTimeout)
+func (fs *FSScanner) SetDirReadTimeout(d time.Duration) {
+	fs.dirTimeout = d
+}
+
+// SetScanWorkers limits how many directories are read concurrently (0 = DefaultScanWorkers)
+func (fs *FSScanner) SetScanWorkers(n int) {
+	fs.scanWorkers = n
+}
+
+// SetReconnect makes the scanner wait for the source to come back when a directory read
+// fails because the connection was lost, then read the directory again. reconnect returns
+// true once the source is reachable again.
+func (fs *FSScanner) SetReconnect(reconnect func(ctx context.Context, err error) bool) {
+	fs.reconnect = reconnect
+}
+
+// SetDirScanned calls fn with each directory once it has been listed and the number of files
+// found in it (not counting subdirectories); fn must be safe for concurrent use
+func (fs *FSScanner) SetDirScanned(fn func(dir string, files int)) {
+	fs.dirScanned = fn
+}
+
 // Scan discovers files using filesystem traversal
 func (fs *FSScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
 	defer func() {
@@ -90,18 +144,12 @@ func (fs *FSScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob,
 				// Check if root is still accessible (connection alive check)
 				_, err := os.Stat(root)
 				if err != nil {
-					if os.IsNotExist(err) {
-						errors <- fmt.Errorf("CRITICAL: Source path no longer exists - connection may have dropped: %s", root)
-						return
-					}
 					// Check for connection errors - these indicate the device disconnected
-					errStr := err.Error()
-					if strings.Contains(errStr, "input/output error") || 
-					   strings.Contains(errStr, "No such device") ||
-					   strings.Contains(errStr, "Transport endpoint is not connected") ||
-					   (strings.Contains(errStr, "No such file or directory") && strings.Contains(root, "gvfs")) ||
-					   strings.Contains(errStr, "Stale file handle") {
-						errors <- fmt.Errorf("CRITICAL: Connection dropped - source path no longer accessible: %s: %v", root, err)
+					if isConnectionError(err) {
+						if fs.reconnect != nil && fs.reconnect(ctx, err) {
+							continue
+						}
+						errors <- fmt.Errorf("CRITICAL: %w - source path no longer accessible: %s: %v", ErrConnectionLost, root, err)
 						return
 					}
 					// Other errors (permissions, etc.) are logged but don't kill the process
@@ -111,10 +159,33 @@ func (fs *FSScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob,
 		}
 	}()
 
+	// The scanning goroutine itself is one worker; the slots are for the extra ones
+	workers := fs.scanWorkers
+	if workers <= 0 {
+		workers = DefaultScanWorkers
+	}
+	fs.scanSlots = make(chan struct{}, workers-1)
+
 	var wg sync.WaitGroup
 	fmt.Fprintf(os.Stderr, "[DEBUG FSScanner] Starting scan from root: %s\n", root)
-	wg.Add(1)
-	fs.scanDir(ctx, root, root, jobs, errors, &wg)
+	if len(fs.scanRoots) == 0 {
+		wg.Add(1)
+		fs.scanDir(ctx, root, root, jobs, errors, &wg)
+	} else {
+		// Selected folders only; paths stay relative to root so the backup layout is unchanged
+		for _, scanRoot := range fs.scanRoots {
+			dir := filepath.Join(root, filepath.FromSlash(scanRoot))
+			if _, err := os.Stat(dir); err != nil {
+				// The apps' folders are listed for every layout; the phone has some of them
+				if !(os.IsNotExist(err) && fs.filter.inApp(scanRoot)) {
+					errors <- pathError(PhaseScan, dir, fmt.Errorf("selected folder not available: %s: %w", dir, err))
+				}
+				continue
+			}
+			wg.Add(1)
+			fs.scanDir(ctx, root, dir, jobs, errors, &wg)
+		}
+	}
 	wg.Wait() // Wait for all subdirectories to finish
 	fmt.Fprintf(os.Stderr, "[DEBUG FSScanner] Scan complete\n")
 	
@@ -142,19 +213,39 @@ func (fs *FSScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob,
 func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs chan<- FileJob, errors chan<- error, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	// Check if this directory has already been fully scanned (resumable)
+	// Always list the directory: new files (e.g. today's photos in DCIM/Camera) must be found
+	// even if it was completed before. Unchanged directories skip their files below.
 	if fs.stateManager != nil {
-		if fs.stateManager.IsDirScanned(current) {
-			// Directory already fully scanned, skip it
-			fmt.Fprintf(os.Stderr, "[DEBUG] Skipping directory (marked as scanned): %s\n", current)
-			return
-		} else {
-			fmt.Fprintf(os.Stderr, "[DEBUG] Scanning directory: %s\n", current)
+		fmt.Fprintf(os.Stderr, "[DEBUG] Scanning directory: %s\n", current)
+	}
+
+	// Incremental mode: stat (cheap) before listing (slow over MTP)
+	var stamp *state.DirStamp
+	if fs.incremental && fs.stateManager != nil {
+		if info, err := os.Stat(current); err == nil && !info.ModTime().IsZero() && info.ModTime().Unix() > 0 {
+			if subdirs, unchanged := fs.stateManager.UnchangedDirSubdirs(current, info.ModTime().UnixNano(), info.Size()); unchanged {
+				fmt.Fprintf(os.Stderr, "[DEBUG] Directory unchanged since last run, not listing: %s\n", current)
+				paths := make([]string, len(subdirs))
+				for i, name := range subdirs {
+					paths[i] = filepath.Join(current, name)
+				}
+				fs.scanSubdirs(ctx, root, paths, jobs, errors, wg)
+				return
+			}
+			// Only remember mtimes that are safely in the past: a file created later in the
+			// same second would not change a coarse (1s) mtime
+			if time.Since(info.ModTime()) > 2*time.Second {
+				stamp = &state.DirStamp{MTime: info.ModTime().UnixNano(), Size: info.Size()}
+			}
 		}
 	}
 
 	// Create a context with timeout for this directory read
-	dirCtx, cancel := context.WithTimeout(ctx, DirReadTimeout)
+	dirTimeout := fs.dirTimeout
+	if dirTimeout <= 0 {
+		dirTimeout = DirReadTimeout
+	}
+	dirCtx, cancel := context.WithTimeout(ctx, dirTimeout)
 	defer cancel()
 
 	// Channel to receive directory entries
@@ -194,9 +285,11 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 			if entries[i].IsDir() && entries[j].IsDir() {
 				pathI := filepath.Join(current, entries[i].Name())
 				pathJ := filepath.Join(current, entries[j].Name())
-				priI := getPathPriority(pathI, root)
-				priJ := getPathPriority(pathJ, root)
-				return priI < priJ
+				priI := getPathPriority(pathI, root, fs.filter.PriorityPaths())
+				priJ := getPathPriority(pathJ, root, fs.filter.PriorityPaths())
+				if priI != priJ {
+					return priI < priJ
+				}
 			}
 			return entries[i].Name() < entries[j].Name()
 		})
@@ -204,7 +297,7 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 		for _, entry := range entries {
 			select {
 			case <-dirCtx.Done():
-				entriesChan <- dirEntryResult{err: fmt.Errorf("directory read timeout: %s", current)}
+				entriesChan <- dirEntryResult{err: fmt.Errorf("%w: %s", ErrDirTimeout, current)}
 				return
 			case entriesChan <- dirEntryResult{entry: entry}:
 			}
@@ -213,6 +306,7 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 
 	// Track if we successfully processed all entries
 	allEntriesProcessed := false
+	readFailed := false // listing incomplete (timeout or error)
 	subdirsToProcess := make([]string, 0)
 	filesToProcess := make([]FileJob, 0)
 
@@ -224,7 +318,8 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 			if fs.stateManager != nil {
 				fs.stateManager.MarkDirStatus(current, "timeout")
 			}
-			errors <- fmt.Errorf("directory read timeout: %s (continuing with discovered entries)", current)
+			readFailed = true
+			errors <- pathError(PhaseScan, current, fmt.Errorf("%w: %s (continuing with discovered entries)", ErrDirTimeout, current))
 			// Process what we've collected so far, then return
 			allEntriesProcessed = true
 			break
@@ -236,11 +331,23 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 			}
 
 			if result.err != nil {
+				if fs.reconnect != nil && isConnectionError(result.err) && fs.reconnect(ctx, result.err) {
+					// The device dropped and came back: read the directory again from the start
+					wg.Add(1)
+					fs.scanDir(ctx, root, current, jobs, errors, wg)
+					return
+				}
 				// Error reading directory - mark as error but continue with what we have
 				if fs.stateManager != nil {
 					fs.stateManager.MarkDirStatus(current, "error")
 				}
-				errors <- fmt.Errorf("error reading %s: %w (continuing with discovered entries)", current, result.err)
+				readFailed = true
+				if hint := explainPermissionError(result.err); hint != "" {
+					// EACCES caused by SELinux/AppArmor/sandboxing, not the phone
+					errors <- pathError(PhaseScan, current, fmt.Errorf("permission denied reading %s: %w (likely cause: %s)", current, result.err, hint))
+				} else {
+					errors <- pathError(PhaseScan, current, fmt.Errorf("error reading %s: %w (continuing with discovered entries)", current, result.err))
+				}
 				// Process what we've collected so far, then return
 				allEntriesProcessed = true
 				break
@@ -268,17 +375,41 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 				}
 				
 				// Check if file should be excluded
-				if shouldExcludeFile(normalizedPath) {
-					// Skip excluded files (cache, temp, system files)
+				if fs.filter.defaultExcluded(normalizedPath) || fs.filter.Excluded(normalizedPath) {
+					// Skip excluded files (cache, temp, system files, user excludes)
 					continue
 				}
 				
+				// Links and special files (sockets, FIFOs) go by the symlink policy; only a
+				// link copied as the file it points to is sent
+				var info os.FileInfo
+				if !entry.Type().IsRegular() {
+					info = fs.links.entry(path, relPath, entry.Type(),
+						func() (string, error) { return os.Readlink(path) },
+						func() (os.FileInfo, error) { return os.Stat(path) })
+					if info == nil {
+						continue
+					}
+				}
+
+				// Size and time limits need a stat, so it is only done when some are set
+				job := FileJob{SourcePath: path, RelPath: relPath}
+				if info == nil && fs.filter.HasLimits() {
+					info, _ = entry.Info()
+				}
+				if info != nil {
+					if fs.filter.OutsideLimits(info.Size(), info.ModTime()) {
+						continue
+					}
+					job.Size = info.Size()
+				}
+				
 				// Track discovered file in this directory
 				if fs.stateManager != nil {
 					fs.stateManager.AddDiscoveredFileToDir(current, path)
 				}
 				// Collect files to process
-				filesToProcess = append(filesToProcess, FileJob{SourcePath: path, RelPath: relPath})
+				filesToProcess = append(filesToProcess, job)
 				fmt.Fprintf(os.Stderr, "[DEBUG] Discovered file: %s\n", path)
 			}
 		}
@@ -288,6 +419,19 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 		}
 	}
 
+	discovered := make([]string, len(filesToProcess))
+	for i, fileJob := range filesToProcess {
+		discovered[i] = fileJob.SourcePath
+	}
+	if fs.dirScanned != nil && !readFailed {
+		fs.dirScanned(current, len(discovered))
+	}
+	// Same files as last run and all backed up: nothing to queue
+	if fs.stateManager != nil && !readFailed && len(discovered) > 0 && fs.stateManager.IsDirUnchanged(current, discovered) {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Skipping files in unchanged directory: %s\n", current)
+		filesToProcess = nil
+	}
+
 	// Now process all collected files (send to jobs channel)
 	for _, fileJob := range filesToProcess {
 		select {
@@ -299,30 +443,29 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 	}
 
 	// Process all collected subdirectories
-	for _, subdir := range subdirsToProcess {
-		// For priority paths, process sequentially (to ensure they're discovered first)
-		// For other paths, process concurrently
-		pri := getPathPriority(subdir, root)
-		if pri < 100 {
-			// Priority path - process immediately (sequentially)
-			wg.Add(1)
-			fs.scanDir(ctx, root, subdir, jobs, errors, wg)
-		} else {
-			// Non-priority path - process concurrently
-			wg.Add(1)
-			go fs.scanDir(ctx, root, subdir, jobs, errors, wg)
-		}
-	}
+	fs.scanSubdirs(ctx, root, subdirsToProcess, jobs, errors, wg)
 
 	// Mark directory as completed only if ALL discovered files were successfully copied
 	if allEntriesProcessed && fs.stateManager != nil {
-		// Only mark as completed if we didn't timeout or error
+		// Only mark as completed if this run read the whole directory (an earlier timeout
+		// or error is superseded by a complete listing)
 		status := fs.stateManager.GetDirStatus(current)
-		if status != "timeout" && status != "error" {
+		if !readFailed {
+			// Remember the listing so the next run can tell whether files were added or removed
+			fs.stateManager.MarkDirListing(current, discovered)
+			if stamp != nil {
+				for _, subdir := range subdirsToProcess {
+					stamp.Subdirs = append(stamp.Subdirs, filepath.Base(subdir))
+				}
+				fs.stateManager.MarkDirStamp(current, *stamp)
+			}
 			// Check if ALL discovered files in this directory were successfully copied
-			if fs.stateManager.AreAllDiscoveredFilesCompleted(current) {
+			// (a directory holding only subdirectories has nothing left to do itself)
+			if len(discovered) == 0 || fs.stateManager.AreAllDiscoveredFilesCompleted(current) {
 				// All discovered files are completed - mark as completed
-				fs.stateManager.MarkDirStatus(current, "completed")
+				if status != "completed" {
+					fs.stateManager.MarkDirStatus(current, "completed")
+				}
 			} else {
 				// Some discovered files are not completed - mark as partial (will rescan on next run)
 				if status != "partial" {
@@ -333,6 +476,32 @@ func (fs *FSScanner) scanDir(ctx context.Context, root, current string, jobs cha
 	}
 }
 
+// scanSubdirs scans subdirectories of a directory: priority paths sequentially (to ensure
+// they're discovered first), the rest concurrently while scan slots are free and in the
+// current goroutine otherwise. Never waiting for a slot keeps a directory from blocking on
+// its own subdirectories.
+func (fs *FSScanner) scanSubdirs(ctx context.Context, root string, subdirs []string, jobs chan<- FileJob, errors chan<- error, wg *sync.WaitGroup) {
+	for _, subdir := range subdirs {
+		pri := getPathPriority(subdir, root, fs.filter.PriorityPaths())
+		wg.Add(1)
+		if pri < 100 {
+			// Priority path - process immediately (sequentially)
+			fs.scanDir(ctx, root, subdir, jobs, errors, wg)
+			continue
+		}
+		select {
+		case fs.scanSlots <- struct{}{}:
+			// Non-priority path - process concurrently
+			go func(dir string) {
+				defer func() { <-fs.scanSlots }()
+				fs.scanDir(ctx, root, dir, jobs, errors, wg)
+			}(subdir)
+		default:
+			fs.scanDir(ctx, root, subdir, jobs, errors, wg)
+		}
+	}
+}
+
 // dirEntryResult wraps a directory entry or error
 type dirEntryResult struct {
 	entry fs.DirEntry
@@ -340,13 +509,51 @@ type dirEntryResult struct {
 }
 
 // FSCopier implements Copier for filesystem-based copying
-type FSCopier struct{}
+type FSCopier struct {
+	limiter   *RateLimiter
+	timeouts  CopyTimeouts
+	chunkSize int64 // 0 = copy whole files
+	buffer    int   // copy buffer size (0 = tuned by tuner)
+	tuner     *bufferTuner
+	onChunk   func(sourcePath string, p ChunkProgress)
+	names     destNamer // how copies are named in the destination (zero = as on the source)
+}
 
 // NewFSCopier creates a new filesystem copier
 func NewFSCopier() *FSCopier {
 	return &FSCopier{}
 }
 
+// SetRateLimiter throttles all copies made by this copier through a shared limiter
+func (fc *FSCopier) SetRateLimiter(limiter *RateLimiter) {
+	fc.limiter = limiter
+}
+
+// SetTimeouts sets when a copy is abandoned as stalled or too slow (see CopyTimeouts)
+func (fc *FSCopier) SetTimeouts(t CopyTimeouts) {
+	fc.timeouts = t
+}
+
+// SetBufferSize sets the size of the copy buffer; 0 tunes it from the measured throughput
+// (see bufferTuner)
+func (fc *FSCopier) SetBufferSize(size int) {
+	fc.buffer, fc.tuner = size, nil
+	if size <= 0 {
+		fc.buffer, fc.tuner = 0, newBufferTuner()
+	}
+}
+
+// SetChunkSize copies files larger than size in chunks of that size, each checked and retried
+// on its own (see copyChunked); 0 copies whole files
+func (fc *FSCopier) SetChunkSize(size int64) {
+	fc.chunkSize = size
+}
+
+// SetChunkProgress sets a function told about each chunk of a chunked copy
+func (fc *FSCopier) SetChunkProgress(fn func(sourcePath string, p ChunkProgress)) {
+	fc.onChunk = fn
+}
+
 // Copy copies a file using filesystem operations with stall detection
 func (fc *FSCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
 	// Calculate relative path from source root
@@ -356,7 +563,7 @@ func (fc *FSCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot s
 	}
 
 	// Build destination path preserving directory structure
-	destPath := filepath.Join(destRoot, relPath)
+	destPath := fc.names.path(destRoot, sourcePath, relPath)
 
 	// Ensure destination directory exists
 	destDir := filepath.Dir(destPath)
@@ -364,52 +571,93 @@ func (fc *FSCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot s
 		return 0, fmt.Errorf("failed to create dest dir: %w", err)
 	}
 
+	// Empty files need no read of the source
+	if info, err := os.Stat(sourcePath); err == nil && info.Mode().IsRegular() && info.Size() == 0 {
+		return 0, createEmpty(destPath)
+	}
+
 	// Open source file
 	sourceFile, err := os.Open(sourcePath)
 	if err != nil {
+		if hint := explainPermissionError(err); hint != "" {
+			return 0, fmt.Errorf("failed to open source: %w (likely cause: %s)", err, hint)
+		}
 		return 0, fmt.Errorf("failed to open source: %w", err)
 	}
 	defer sourceFile.Close()
 
-	// Create destination file
-	destFile, err := os.Create(destPath)
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat source: %w", err)
+	}
+
+	// Write through <dest>.part, resuming one left by an interrupted copy; large files chunk
+	// by chunk when chunking is on, resuming at the last whole chunk
+	part, err := openPart(destPath, info)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create dest: %w", err)
+		return 0, err
 	}
-	defer destFile.Close()
+	chunked := fc.chunkSize > 0 && info.Size() > fc.chunkSize
+	if chunked {
+		err = part.rewind(part.offset / fc.chunkSize * fc.chunkSize)
+	} else {
+		// Holes in the source are left as holes instead of written out as zeros
+		part.sparse = isSparse(info)
+		if part.offset > 0 {
+			_, err = sourceFile.Seek(part.offset, io.SeekStart)
+		}
+	}
+	if err != nil {
+		part.discard()
+		return 0, fmt.Errorf("failed to resume partial copy: %w", err)
+	}
+	part.preallocate(info.Size())
 
 	// Create connection checker for mount mode: verify source root is still accessible
 	var connChecker ConnectionChecker
 	if sourceRoot != "" {
 		connChecker = func() error {
 			_, err := os.Stat(sourceRoot)
-			if err != nil {
-				// Check for connection errors
-				errStr := err.Error()
-				if os.IsNotExist(err) || 
-				   strings.Contains(errStr, "input/output error") ||
-				   strings.Contains(errStr, "No such device") ||
-				   strings.Contains(errStr, "Transport endpoint is not connected") ||
-				   (strings.Contains(errStr, "No such file or directory") && strings.Contains(sourceRoot, "gvfs")) ||
-				   strings.Contains(errStr, "Stale file handle") {
-					return fmt.Errorf("connection lost: %w", err)
-				}
+			if isConnectionError(err) {
+				return err
 			}
 			return nil
 		}
 	}
 	
+	limits := fc.timeouts.forSize(info.Size()).throttled(fc.limiter)
+	limits.buffer = fc.buffer
+	if fc.tuner != nil {
+		limits.buffer = fc.tuner.get()
+	}
+
 	// Copy with timeout/stall detection, progress reporting, and connection checking
-	bytesCopied, err := copyWithTimeout(sourceFile, destFile, StallTimeout, progressChan, connChecker)
+	var bytesCopied int64
+	if chunked {
+		var onChunk func(ChunkProgress)
+		if fc.onChunk != nil {
+			onChunk = func(p ChunkProgress) { fc.onChunk(sourcePath, p) }
+		}
+		bytesCopied, err = copyChunked(ctx, sourceFile, part.f, part.offset, info.Size(), fc.chunkSize, fc.limiter, limits, progressChan, connChecker, onChunk)
+	} else {
+		started := time.Now()
+		bytesCopied, err = copyWithTimeout(limitReader(ctx, part.source(sourceFile), fc.limiter), part, limits, progressChan, connChecker)
+		if err == nil && fc.tuner != nil && fc.limiter == nil {
+			// A bandwidth limit, not the buffer, sets the pace of throttled copies
+			fc.tuner.record(limits.buffer, bytesCopied, time.Since(started))
+		}
+	}
 	if err != nil {
+		// Keep what arrived for the retry or the next run to resume
+		part.close(info)
 		return bytesCopied, err
 	}
 
-	// Sync destination to ensure data is written
-	if err := destFile.Sync(); err != nil {
-		return bytesCopied, fmt.Errorf("failed to sync dest: %w", err)
+	// Check the copy and move it into place
+	if err := part.commit(info.Size()); err != nil {
+		return bytesCopied, err
 	}
-
-	return bytesCopied, nil
+	reportCopyHash(ctx, part)
+	return part.offset + bytesCopied, nil
 }
 
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFSScannerScanWorkers(t *testing.T) {
+	root := t.TempDir()
+	var want []string
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 4; j++ {
+			rel := fmt.Sprintf("Folder%d/Sub%d/Deep/file.txt", i, j)
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(rel), 0644); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, filepath.FromSlash(rel))
+		}
+	}
+	sort.Strings(want)
+
+	for _, workers := range []int{1, 2, 16} {
+		jobs := make(chan FileJob, len(want))
+		var once sync.Once
+		scanner := NewFSScanner(func() { once.Do(func() { close(jobs) }) })
+		scanner.SetScanWorkers(workers)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			scanner.Scan(ctx, root, jobs, make(chan error, 100))
+		}()
+		var got []string
+		for job := range jobs {
+			got = append(got, job.RelPath)
+		}
+		<-done
+		cancel()
+
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("workers=%d: found %v, want %v", workers, got, want)
+		}
+	}
+}
+
+func TestFSScannerLimits(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -60)
+	for rel, size := range map[string]int{"small.txt": 10, "big.mp4": 5000, "old.jpg": 500, "new.jpg": 500} {
+		path := filepath.Join(root, rel)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if rel == "old.jpg" {
+			os.Chtimes(path, old, old)
+		}
+	}
+	filter, _ := NewFilter(nil)
+	filter.SetLimits(FileLimits{MinSize: 100, MaxSize: 1000, NewerThan: time.Now().AddDate(0, 0, -30)})
+
+	jobs := make(chan FileJob, 10)
+	var once sync.Once
+	scanner := NewFSScanner(func() { once.Do(func() { close(jobs) }) })
+	scanner.SetFilter(filter)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go scanner.Scan(ctx, root, jobs, make(chan error, 10))
+
+	var got []string
+	for job := range jobs {
+		got = append(got, job.RelPath)
+		if job.Size != 500 {
+			t.Errorf("%s: Size = %d, want 500", job.RelPath, job.Size)
+		}
+	}
+	if fmt.Sprint(got) != "[new.jpg]" {
+		t.Errorf("found %v, want [new.jpg]", got)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultGioMountTimeout is how long GioMount waits for the gvfs path of a new mount
+const DefaultGioMountTimeout = 30 * time.Second
+
+// GioVolume is a phone or camera GIO knows about (gio mount -li), mounted or not
+type GioVolume struct {
+	Name    string `json:"name"`
+	URI     string `json:"uri"` // activation root, e.g. mtp://Google_Pixel_6_1A2B3C/
+	Mounted bool   `json:"mounted"`
+}
+
+// GioVolumes lists the MTP and gphoto2 volumes GIO sees, including phones that are plugged
+// in but not mounted yet
+func GioVolumes(ctx context.Context) ([]GioVolume, error) {
+	out, err := exec.CommandContext(ctx, "gio", "mount", "-li").Output()
+	if err != nil {
+		return nil, fmt.Errorf("gio mount -li failed: %w", err)
+	}
+	return parseGioVolumes(string(out)), nil
+}
+
+// parseGioVolumes reads the MTP and gphoto2 volumes from `gio mount -li` output
+func parseGioVolumes(out string) []GioVolume {
+	var volumes []GioVolume
+	mounted := make(map[string]bool)
+	seen := make(map[string]bool)
+	current := -1
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Volume("):
+			current = -1
+			if _, name, ok := strings.Cut(line, "): "); ok {
+				volumes = append(volumes, GioVolume{Name: name})
+				current = len(volumes) - 1
+			}
+		case strings.HasPrefix(line, "Mount("):
+			if _, uri, ok := strings.Cut(line, " -> "); ok {
+				mounted[strings.TrimSpace(uri)] = true
+			}
+		case strings.HasPrefix(line, "activation_root="):
+			if current >= 0 {
+				volumes[current].URI = strings.TrimPrefix(line, "activation_root=")
+			}
+		}
+	}
+
+	var result []GioVolume
+	for _, v := range volumes {
+		if !isGvfsDeviceURI(v.URI) || seen[v.URI] {
+			continue
+		}
+		seen[v.URI] = true
+		v.Mounted = mounted[v.URI]
+		result = append(result, v)
+	}
+	return result
+}
+
+// isGvfsDeviceURI reports whether uri is a phone or camera gvfs can mount
+func isGvfsDeviceURI(uri string) bool {
+	return strings.HasPrefix(uri, "mtp://") || strings.HasPrefix(uri, "gphoto2://")
+}
+
+// GvfsPath returns where gvfs exposes a mounted device URI on the filesystem, e.g.
+// mtp://Google_Pixel_6_1A2B3C/ -> /run/user/1000/gvfs/mtp:host=Google_Pixel_6_1A2B3C
+func GvfsPath(uri string) (string, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || !isGvfsDeviceURI(uri) {
+		return "", fmt.Errorf("not an MTP or gphoto2 device: %s", uri)
+	}
+	host := strings.Trim(rest, "/")
+	if host == "" {
+		return "", fmt.Errorf("no device in %s", uri)
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join("/run/user", fmt.Sprint(os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, "gvfs", scheme+":host="+host), nil
+}
+
+// GioMount mounts a device URI with gio and waits up to timeout (0 = DefaultGioMountTimeout)
+// for its gvfs path to appear, which it returns. A device that is already mounted is left as is.
+func GioMount(ctx context.Context, uri string, timeout time.Duration) (string, error) {
+	path, err := GvfsPath(uri)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultGioMountTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if out, err := exec.CommandContext(ctx, "gio", "mount", uri).CombinedOutput(); err != nil {
+		msg := strings.TrimSpace(string(out))
+		if !strings.Contains(strings.ToLower(msg), "already mounted") {
+			return "", fmt.Errorf("gio mount %s failed: %v: %s", uri, err, msg)
+		}
+	}
+
+	// gio returns once the mount is set up; the FUSE path can take a moment longer
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("%s did not appear after mounting %s (is the phone unlocked and set to File transfer?)", path, uri)
+		case <-ticker.C:
+		}
+	}
+}
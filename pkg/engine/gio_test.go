@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGioVolumes(t *testing.T) {
+	out := `Drive(0): WDC WD10EZEX
+  Type: GProxyDrive (GProxyVolumeMonitorUDisks2)
+  Volume(0): Data
+    Type: GProxyVolume (GProxyVolumeMonitorUDisks2)
+    activation_root=
+Volume(0): Pixel 6
+  Type: GProxyVolume (GProxyVolumeMonitorMTP)
+  ids:
+   unix-device: '/dev/bus/usb/001/010'
+  activation_root=mtp://Google_Pixel_6_1A2B3C/
+  can_mount=1
+  should_automount=1
+Volume(1): Canon EOS
+  Type: GProxyVolume (GProxyVolumeMonitorGPhoto2)
+  activation_root=gphoto2://Canon_Inc._Canon_Digital_Camera/
+  Mount(0): Canon EOS -> gphoto2://Canon_Inc._Canon_Digital_Camera/
+    Type: GProxyMount (GProxyVolumeMonitorGPhoto2)
+Mount(1): sftp on host -> sftp://host/
+`
+	want := []GioVolume{
+		{Name: "Pixel 6", URI: "mtp://Google_Pixel_6_1A2B3C/"},
+		{Name: "Canon EOS", URI: "gphoto2://Canon_Inc._Canon_Digital_Camera/", Mounted: true},
+	}
+	if got := parseGioVolumes(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("volumes = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGvfsPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	got, err := GvfsPath("mtp://Google_Pixel_6_1A2B3C/")
+	if err != nil || got != "/run/user/1000/gvfs/mtp:host=Google_Pixel_6_1A2B3C" {
+		t.Errorf("GvfsPath = %q, %v", got, err)
+	}
+	if _, err := GvfsPath("sftp://host/"); err == nil {
+		t.Errorf("expected an error for a non-device URI")
+	}
+}
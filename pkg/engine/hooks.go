@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// DefaultHookTimeout bounds a hook command when Hooks.Timeout is not set
+const DefaultHookTimeout = 30 * time.Minute
+
+// Hooks are shell commands (or script paths) run around a backup, e.g. to mount the
+// destination drive first or start an rclone upload afterwards. They get the run's
+// details in GUSSYNC_* environment variables; their output goes to the log. In watch
+// mode they run around every sync pass.
+type Hooks struct {
+	// PreBackup runs before scanning; if it fails the backup doesn't start
+	PreBackup string
+	// PostBackup runs when the backup ends, also after a failure or cancellation;
+	// GUSSYNC_STATUS and the count variables describe the result
+	PostBackup string
+	// Timeout per hook (0 = DefaultHookTimeout)
+	Timeout time.Duration
+}
+
+// runPreBackupHook runs Hooks.PreBackup (if set)
+func (e *Engine) runPreBackupHook(ctx context.Context) error {
+	if e.config.Hooks.PreBackup == "" {
+		return nil
+	}
+	if err := e.runHook(ctx, "pre_backup", e.config.Hooks.PreBackup, e.hookEnv("pre_backup", "", nil)); err != nil {
+		return fmt.Errorf("pre_backup hook failed: %w", err)
+	}
+	return nil
+}
+
+// runPostBackupHook runs Hooks.PostBackup (if set) with the outcome of the run; a failing
+// hook is only logged since the backup itself is over
+func (e *Engine) runPostBackupHook(ctx context.Context, runErr error) {
+	if e.config.Hooks.PostBackup == "" {
+		return
+	}
+	status := "success"
+	switch {
+	case ctx.Err() != nil:
+		status = "cancelled"
+	case runErr != nil:
+		status = "failed"
+	}
+	// Runs even when the backup was cancelled
+	hookCtx := context.WithoutCancel(ctx)
+	if err := e.runHook(hookCtx, "post_backup", e.config.Hooks.PostBackup, e.hookEnv("post_backup", status, runErr)); err != nil {
+		e.log("warn", fmt.Sprintf("post_backup hook failed: %v", err))
+	}
+}
+
+// hookEnv describes the run to a hook
+func (e *Engine) hookEnv(hook, status string, runErr error) []string {
+	env := append(os.Environ(),
+		"GUSSYNC_HOOK="+hook,
+		"GUSSYNC_SOURCE="+e.config.SourcePath,
+		"GUSSYNC_DEST="+e.config.DestRoot,
+		"GUSSYNC_MODE="+e.config.Mode,
+	)
+	if status == "" {
+		return env
+	}
+
+	e.stats.Lock()
+	env = append(env,
+		"GUSSYNC_STATUS="+status,
+		"GUSSYNC_COMPLETED="+strconv.Itoa(e.stats.completed),
+		"GUSSYNC_FAILED="+strconv.Itoa(e.stats.failed),
+		"GUSSYNC_SKIPPED="+strconv.Itoa(e.stats.skipped),
+		"GUSSYNC_BYTES="+strconv.FormatInt(e.stats.totalBytes, 10),
+		"GUSSYNC_DURATION="+strconv.Itoa(int(time.Since(e.stats.startTime).Seconds())),
+	)
+	e.stats.Unlock()
+	if runErr != nil {
+		env = append(env, "GUSSYNC_ERROR="+runErr.Error())
+	}
+	return env
+}
+
+// runHook runs command through the system shell, logging its output line by line
+func (e *Engine) runHook(ctx context.Context, name, command string, env []string) error {
+	timeout := e.config.Hooks.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = env
+	// Both streams go to the log in order; WaitDelay stops a background process the hook
+	// started (and that inherited the pipe) from holding up the backup
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	cmd.WaitDelay = 5 * time.Second
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		e.logHookOutput(name, pr)
+	}()
+
+	e.log("info", fmt.Sprintf("Running %s hook: %s", name, command))
+	err := cmd.Run()
+	pw.Close()
+	<-logged
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+	return err
+}
+
+func (e *Engine) logHookOutput(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.log("info", fmt.Sprintf("[%s] %s", name, scanner.Text()))
+	}
+	io.Copy(io.Discard, r) // a line too long for the scanner must not block the hook
+}
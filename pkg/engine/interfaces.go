@@ -6,6 +6,10 @@ import "context"
 type FileJob struct {
 	SourcePath string // Full source path
 	RelPath    string // Relative path from source root
+	Size       int64  // Size in bytes if the scanner already knows it (0 = unknown)
+	// Batch holds small files of the folder at SourcePath to copy together (adb mode, see
+	// EngineConfig.BatchSmallFiles); Size is then their total
+	Batch []FileJob
 }
 
 // Scanner interface for discovering files
@@ -28,3 +32,14 @@ type Copier interface {
 	Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error)
 }
 
+// closingScanner closes the job channel once a custom Scanner is done, as the built-in
+// scanners do themselves
+type closingScanner struct {
+	Scanner
+	close func()
+}
+
+func (s closingScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
+	defer s.close()
+	s.Scanner.Scan(ctx, root, jobs, errors)
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonlFile appends values to a file, one JSON object per line (the audit and error logs).
+// The file is created on the first append, so a run with nothing to record leaves none.
+type jsonlFile struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	failed bool // opening or a write failed; later values are dropped
+}
+
+func newJSONL(dir, name string) *jsonlFile {
+	return &jsonlFile{path: filepath.Join(dir, name)}
+}
+
+// append writes v as one line; each line is a single write, so a crash leaves at most the
+// last line torn. Only the first failure returns an error.
+func (j *jsonlFile) append(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.failed {
+		return nil
+	}
+	if j.f == nil {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+			j.failed = true
+			return fmt.Errorf("failed to open %s, nothing is recorded: %w", filepath.Base(j.path), err)
+		}
+		if j.f, err = os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			j.failed = true
+			return fmt.Errorf("failed to open %s, nothing is recorded: %w", filepath.Base(j.path), err)
+		}
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		j.failed = true
+		return fmt.Errorf("failed to write %s, nothing more is recorded: %w", filepath.Base(j.path), err)
+	}
+	return nil
+}
+
+func (j *jsonlFile) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// readJSONL calls fn with each line of a JSON lines file. A missing file has no lines.
+func readJSONL(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	kdeConnectService = "org.kde.kdeconnect"
+	kdeConnectDevices = "/modules/kdeconnect/devices/"
+	// kdeConnectMountTimeout bounds asking the phone to start its SFTP server and mounting it
+	kdeConnectMountTimeout = 30 * time.Second
+)
+
+// KDEConnectOptions tells kdeconnect mode which paired phone to back up. SourcePath is then
+// a path inside what the phone shares over KDE Connect ("/" = everything it shares).
+type KDEConnectOptions struct {
+	// Device is the device ID or name as shown by kdeconnect-cli --list-available
+	Device string
+}
+
+// KDEConnectDevice is a paired phone the KDE Connect daemon can reach
+type KDEConnectDevice struct {
+	ID   string
+	Name string
+	// Browsable is false when the phone has its filesystem (SFTP) plugin disabled
+	Browsable bool
+}
+
+// kdeConnectDaemon is the part of the KDE Connect daemon kdeconnect mode uses. The daemon
+// does the pairing and encryption; when asked, the phone starts an SFTP server that the
+// daemon mounts with sshfs.
+type kdeConnectDaemon interface {
+	Devices(ctx context.Context) ([]KDEConnectDevice, error)
+	// Mount makes the device's files available and returns the local mount point
+	Mount(ctx context.Context, id string) (string, error)
+}
+
+// kdeConnect talks to the daemon over the session D-Bus; tests replace it
+var kdeConnect kdeConnectDaemon = dbusKDEConnect{}
+
+// KDEConnectDevices lists the paired phones that are reachable over Wi-Fi right now
+func KDEConnectDevices(ctx context.Context) ([]KDEConnectDevice, error) {
+	return kdeConnect.Devices(ctx)
+}
+
+// ParseKDEConnectSource splits kdeconnect://<device id or name>/path into the device and
+// the path inside it
+func ParseKDEConnectSource(source string) (device, sharePath string, err error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "kdeconnect" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid kdeconnect source %q (want kdeconnect://<device>/path)", source)
+	}
+	return u.Host, path.Clean("/" + u.Path), nil
+}
+
+// kdeConnectMount resolves the configured device and keeps track of where it is mounted.
+// It is shared by kdeconnect mode's scanner and copier.
+type kdeConnectMount struct {
+	device string
+
+	mu    sync.Mutex
+	point string // "" until mounted, and again after the mount died
+}
+
+// root returns the mount point, asking the daemon to mount the device if needed
+func (m *kdeConnectMount) root(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.point != "" {
+		return m.point, nil
+	}
+	devices, err := kdeConnect.Devices(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range devices {
+		if d.ID != m.device && !strings.EqualFold(d.Name, m.device) {
+			continue
+		}
+		if !d.Browsable {
+			return "", fmt.Errorf("KDE Connect device %s doesn't share its files: enable the Filesystem expose plugin on the phone", d.Name)
+		}
+		point, err := kdeConnect.Mount(ctx, d.ID)
+		if err != nil {
+			return "", err
+		}
+		m.point = point
+		return point, nil
+	}
+	// An unreachable phone drops off the list, so this is what a Wi-Fi outage looks like
+	return "", fmt.Errorf("%w: KDE Connect device %q is not reachable", ErrConnectionLost, m.device)
+}
+
+// check returns err from an operation on the mount, as ErrConnectionLost if the mount
+// died (sshfs fails with ENOTCONN or EIO once the phone leaves the network); the mount is
+// then looked up again on the next call, so the daemon can remount it
+func (m *kdeConnectMount) check(err error) error {
+	if err == nil || os.IsNotExist(err) {
+		return err
+	}
+	if !IsCritical(err) && !isConnectionError(err) {
+		return err
+	}
+	m.mu.Lock()
+	m.point = ""
+	m.mu.Unlock()
+	if IsCritical(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrConnectionLost, err)
+}
+
+// local maps a path on the phone to the mounted file
+func (m *kdeConnectMount) local(ctx context.Context, p string) (string, error) {
+	root, err := m.root(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(path.Clean("/"+p))), nil
+}
+
+// KDEConnectScanner implements Scanner over a phone's KDE Connect mount
+type KDEConnectScanner struct {
+	mount        *kdeConnectMount
+	closeJobChan func()   // Function to safely close jobChan (uses sync.Once)
+	scanRoots    []string // Folders (relative to root) to scan; empty = whole root
+	filter       *Filter  // User-defined exclude rules (nil = none)
+	reconnect    func(ctx context.Context, err error) bool
+	links        *linkPolicy // Handles links and special files (nil = skipped)
+}
+
+// NewKDEConnectScanner creates a scanner for the device described by opts
+func NewKDEConnectScanner(opts KDEConnectOptions, closeJobChan func()) *KDEConnectScanner {
+	return &KDEConnectScanner{mount: &kdeConnectMount{device: opts.Device}, closeJobChan: closeJobChan}
+}
+
+// SetScanRoots limits scanning to the given folders (relative to the scan root)
+func (s *KDEConnectScanner) SetScanRoots(roots []string) {
+	s.scanRoots = roots
+}
+
+// SetFilter applies user-defined exclude rules during scanning
+func (s *KDEConnectScanner) SetFilter(f *Filter) {
+	s.filter = f
+}
+
+// SetReconnect makes the scanner wait for the phone to come back (reconnect returns true)
+// and read the directory again instead of giving up on the scan
+func (s *KDEConnectScanner) SetReconnect(reconnect func(ctx context.Context, err error) bool) {
+	s.reconnect = reconnect
+}
+
+// Probe checks that the phone is reachable and root can be read
+func (s *KDEConnectScanner) Probe(ctx context.Context, root string) error {
+	dir, err := s.mount.local(ctx, root)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(dir)
+	if err == nil {
+		_, err = f.Readdirnames(1)
+		f.Close()
+		if err == io.EOF {
+			err = nil // empty but reachable
+		}
+	}
+	return s.mount.check(err)
+}
+
+// Scan lists the phone's files through the mount, priority folders first
+func (s *KDEConnectScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
+	defer s.closeJobChan()
+	scanRemote(ctx, root, s.scanRoots, s.filter, s.readDir, s.links, remoteLinks{readLink: s.readLink, stat: s.stat}, jobs, errors)
+}
+
+// readDir lists dir, waiting for the phone to come back if it drops off the network
+func (s *KDEConnectScanner) readDir(ctx context.Context, dir string) ([]os.FileInfo, error) {
+	for {
+		entries, err := s.readDirOnce(ctx, dir)
+		if err == nil {
+			return entries, nil
+		}
+		if !IsCritical(err) || s.reconnect == nil || !s.reconnect(ctx, err) {
+			return nil, err
+		}
+	}
+}
+
+func (s *KDEConnectScanner) readDirOnce(ctx context.Context, dir string) ([]os.FileInfo, error) {
+	local, err := s.mount.local(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(local)
+	if err != nil {
+		return nil, s.mount.check(err)
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since the listing
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// readLink returns the target of the link at p
+func (s *KDEConnectScanner) readLink(ctx context.Context, p string) (string, error) {
+	local, err := s.mount.local(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(local)
+	if err != nil {
+		return "", s.mount.check(err)
+	}
+	return target, nil
+}
+
+// stat describes the file at p, following links
+func (s *KDEConnectScanner) stat(ctx context.Context, p string) (os.FileInfo, error) {
+	local, err := s.mount.local(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(local)
+	if err != nil {
+		return nil, s.mount.check(err)
+	}
+	return info, nil
+}
+
+// KDEConnectCopier implements Copier over a phone's KDE Connect mount
+type KDEConnectCopier struct {
+	mount *kdeConnectMount
+	fs    *FSCopier
+}
+
+// NewKDEConnectCopier creates a copier for the device described by opts
+func NewKDEConnectCopier(opts KDEConnectOptions) *KDEConnectCopier {
+	return &KDEConnectCopier{mount: &kdeConnectMount{device: opts.Device}, fs: NewFSCopier()}
+}
+
+// SetRateLimiter throttles all copies made by this copier through a shared limiter
+func (kc *KDEConnectCopier) SetRateLimiter(limiter *RateLimiter) {
+	kc.fs.SetRateLimiter(limiter)
+}
+
+// SetTimeouts sets when a copy is abandoned as stalled or too slow (see CopyTimeouts)
+func (kc *KDEConnectCopier) SetTimeouts(t CopyTimeouts) {
+	kc.fs.SetTimeouts(t)
+}
+
+// Copy reads the file through the mount, with mount mode's stall and connection checks
+func (kc *KDEConnectCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	localRoot, err := kc.mount.local(ctx, sourceRoot)
+	if err != nil {
+		return 0, err
+	}
+	relPath, err := filepath.Rel(sourceRoot, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate relative path: %w", err)
+	}
+	n, err := kc.fs.Copy(ctx, filepath.Join(localRoot, relPath), localRoot, destRoot, progressChan)
+	return n, kc.mount.check(err)
+}
+
+// kdeConnectSource accesses the phone's files through the mount for cleanup
+type kdeConnectSource struct {
+	mount  *kdeConnectMount
+	copier *KDEConnectCopier
+	local  localSource
+	root   string
+}
+
+func newKDEConnectSource(opts KDEConnectOptions, sourceRoot, destRoot string) *kdeConnectSource {
+	copier := NewKDEConnectCopier(opts)
+	return &kdeConnectSource{mount: copier.mount, copier: copier, root: sourceRoot, local: localSource{destRoot: destRoot}}
+}
+
+func (s *kdeConnectSource) Stat(ctx context.Context, path string) (int64, bool, error) {
+	local, err := s.mount.local(ctx, path)
+	if err != nil {
+		return 0, false, err
+	}
+	size, isDir, err := s.local.Stat(ctx, local)
+	return size, isDir, s.mount.check(err)
+}
+
+func (s *kdeConnectSource) Hash(ctx context.Context, path string) (string, error) {
+	local, err := s.mount.local(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	hash, err := s.local.Hash(ctx, local)
+	return hash, s.mount.check(err)
+}
+
+func (s *kdeConnectSource) Restore(ctx context.Context, path string) error {
+	_, err := s.copier.Copy(ctx, path, s.root, s.local.destRoot, nil)
+	return err
+}
+
+func (s *kdeConnectSource) Remove(ctx context.Context, path string) error {
+	local, err := s.mount.local(ctx, path)
+	if err != nil {
+		return err
+	}
+	return s.mount.check(s.local.Remove(ctx, local))
+}
+
+func (s *kdeConnectSource) FreeSpace(ctx context.Context, path string) (int64, error) {
+	local, err := s.mount.local(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	free, err := s.local.FreeSpace(ctx, local)
+	return free, s.mount.check(err)
+}
+
+// dbusKDEConnect is the KDE Connect daemon on the session bus
+type dbusKDEConnect struct{}
+
+func (dbusKDEConnect) Devices(ctx context.Context) ([]KDEConnectDevice, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus session bus for KDE Connect: %w", err)
+	}
+	var ids []string
+	daemon := conn.Object(kdeConnectService, "/modules/kdeconnect")
+	// devices(onlyReachable, onlyPaired)
+	if err := daemon.CallWithContext(ctx, kdeConnectService+".daemon.devices", 0, true, true).Store(&ids); err != nil {
+		return nil, fmt.Errorf("KDE Connect is not running: %w", err)
+	}
+	devices := make([]KDEConnectDevice, 0, len(ids))
+	for _, id := range ids {
+		device := conn.Object(kdeConnectService, dbus.ObjectPath(kdeConnectDevices+id))
+		d := KDEConnectDevice{ID: id, Name: id}
+		if v, err := device.GetProperty(kdeConnectService + ".device.name"); err == nil {
+			if name, ok := v.Value().(string); ok {
+				d.Name = name
+			}
+		}
+		device.CallWithContext(ctx, kdeConnectService+".device.hasPlugin", 0, "kdeconnect_sftp").Store(&d.Browsable)
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+func (dbusKDEConnect) Mount(ctx context.Context, id string) (string, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return "", fmt.Errorf("no D-Bus session bus for KDE Connect: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, kdeConnectMountTimeout)
+	defer cancel()
+	sftp := conn.Object(kdeConnectService, dbus.ObjectPath(kdeConnectDevices+id+"/sftp"))
+	iface := kdeConnectService + ".device.sftp."
+	var mounted bool
+	if err := sftp.CallWithContext(ctx, iface+"mountAndWait", 0).Store(&mounted); err != nil {
+		return "", fmt.Errorf("%w: KDE Connect mount of %s failed: %v", ErrConnectionLost, id, err)
+	}
+	if !mounted {
+		var reason string
+		sftp.CallWithContext(ctx, iface+"getMountError", 0).Store(&reason)
+		return "", fmt.Errorf("%w: KDE Connect could not mount %s: %s", ErrConnectionLost, id, reason)
+	}
+	var mountPoint string
+	if err := sftp.CallWithContext(ctx, iface+"mountPoint", 0).Store(&mountPoint); err != nil || mountPoint == "" {
+		return "", fmt.Errorf("KDE Connect did not report where %s is mounted: %v", id, err)
+	}
+	return mountPoint, nil
+}
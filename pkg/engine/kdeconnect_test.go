@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+// fakeKDEConnect stands in for the daemon: its devices are "mounted" at a local directory
+type fakeKDEConnect struct {
+	devices []KDEConnectDevice
+	point   string
+	mounts  int
+}
+
+func (f *fakeKDEConnect) Devices(ctx context.Context) ([]KDEConnectDevice, error) {
+	return f.devices, nil
+}
+
+func (f *fakeKDEConnect) Mount(ctx context.Context, id string) (string, error) {
+	f.mounts++
+	return f.point, nil
+}
+
+func useFakeKDEConnect(t *testing.T, fake *fakeKDEConnect) {
+	t.Helper()
+	saved := kdeConnect
+	kdeConnect = fake
+	t.Cleanup(func() { kdeConnect = saved })
+}
+
+func TestKDEConnectBackupAndCleanup(t *testing.T) {
+	dir := t.TempDir()
+	mountPoint := filepath.Join(dir, "mnt")
+	files := map[string]string{"DCIM/a.jpg": "aaa", "Download/b.pdf": "bb"}
+	for rel, content := range files {
+		os.MkdirAll(filepath.Join(mountPoint, "Internal storage", filepath.Dir(rel)), 0755)
+		os.WriteFile(filepath.Join(mountPoint, "Internal storage", rel), []byte(content), 0644)
+	}
+	fake := &fakeKDEConnect{devices: []KDEConnectDevice{{ID: "a1b2c3", Name: "Pixel 8", Browsable: true}}, point: mountPoint}
+	useFakeKDEConnect(t, fake)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	destRoot := filepath.Join(dir, "backup")
+	e := NewEngine(EngineConfig{
+		Mode:       TransportKDEConnect,
+		SourcePath: "/Internal storage",
+		DestRoot:   destRoot,
+		NumWorkers: 2,
+		Reporter:   discardReporter{},
+		KDEConnect: KDEConnectOptions{Device: "pixel 8"},
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for rel, content := range files {
+		if got, _ := os.ReadFile(filepath.Join(destRoot, rel)); string(got) != content {
+			t.Errorf("%s: got %q, want %q", rel, got, content)
+		}
+		if !sm.IsDone("/Internal storage/" + rel) {
+			t.Errorf("%s not recorded under its path on the phone", rel)
+		}
+	}
+	if fake.mounts != 1 {
+		t.Errorf("mounted %d times, want once for the whole run", fake.mounts)
+	}
+
+	cleaned, err := e.RunCleanup(context.Background())
+	if err != nil || cleaned.Deleted != len(files) {
+		t.Fatalf("RunCleanup = %+v, %v; want %d deleted", cleaned, err, len(files))
+	}
+	if _, err := os.Stat(filepath.Join(mountPoint, "Internal storage/DCIM/a.jpg")); !os.IsNotExist(err) {
+		t.Error("expected cleanup to delete the file on the phone")
+	}
+}
+
+func TestKDEConnectDeviceUnreachable(t *testing.T) {
+	useFakeKDEConnect(t, &fakeKDEConnect{devices: []KDEConnectDevice{{ID: "other", Name: "Tablet", Browsable: true}}})
+	scanner := NewKDEConnectScanner(KDEConnectOptions{Device: "a1b2c3"}, func() {})
+	if err := scanner.Probe(context.Background(), "/"); !IsCritical(err) {
+		t.Fatalf("Probe = %v, want a lost connection for a phone that is off the network", err)
+	}
+
+	useFakeKDEConnect(t, &fakeKDEConnect{devices: []KDEConnectDevice{{ID: "a1b2c3", Name: "Pixel 8"}}})
+	if err := scanner.Probe(context.Background(), "/"); err == nil || IsCritical(err) {
+		t.Fatalf("Probe = %v, want a plain error when file sharing is disabled on the phone", err)
+	}
+}
+
+func TestParseKDEConnectSource(t *testing.T) {
+	tests := []struct {
+		source, device, path string
+		wantErr              bool
+	}{
+		{"kdeconnect://a1b2c3/Internal storage/DCIM", "a1b2c3", "/Internal storage/DCIM", false},
+		{"kdeconnect://a1b2c3", "a1b2c3", "/", false},
+		{"kdeconnect:///DCIM", "", "", true},
+		{"ssh://phone/DCIM", "", "", true},
+	}
+	for _, tt := range tests {
+		device, path, err := ParseKDEConnectSource(tt.source)
+		if (err != nil) != tt.wantErr || device != tt.device || path != tt.path {
+			t.Errorf("ParseKDEConnectSource(%q) = %q, %q, %v; want %q, %q (error: %v)", tt.source, device, path, err, tt.device, tt.path, tt.wantErr)
+		}
+	}
+}
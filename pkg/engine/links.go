@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Symbolic link policies, for EngineConfig.Symlinks. Android keeps links in places like
+// Android/data (an app's "current" folder) and in Termux home folders. Sockets, FIFOs and
+// device files have no content to back up and are always skipped.
+const (
+	// SymlinksSkip leaves links out of the backup (the default)
+	SymlinksSkip = "skip"
+	// SymlinksCopyTarget copies a link to a file as that file. Links to folders are skipped,
+	// so a link pointing up the tree can't make the backup loop.
+	SymlinksCopyTarget = "copy-target"
+	// SymlinksRecord recreates each link in the destination, pointing where it did on the
+	// source, and records it in the state
+	SymlinksRecord = "record-symlink"
+)
+
+// SymlinkPolicies lists the valid values of EngineConfig.Symlinks ("" = SymlinksSkip)
+var SymlinkPolicies = []string{SymlinksSkip, SymlinksCopyTarget, SymlinksRecord}
+
+// validSymlinks reports an unknown policy
+func validSymlinks(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, p := range SymlinkPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown symlink policy %q (use %s)", policy, strings.Join(SymlinkPolicies, ", "))
+}
+
+// SpecialFileStats counts the source entries that are neither regular files nor folders
+type SpecialFileStats struct {
+	SymlinksSkipped  int // links left out: by policy, or pointing to a folder or to nothing
+	SymlinksCopied   int // links copied as the file they point to (SymlinksCopyTarget)
+	SymlinksRecorded int // links recreated in the destination (SymlinksRecord)
+	Special          int // sockets, FIFOs and device files
+}
+
+// linkPolicy decides what a scan does with the entries of the source that are not regular
+// files or folders, and counts them. A nil policy skips them without counting.
+type linkPolicy struct {
+	mode string
+	// record recreates the link at sourcePath (at rel relative to the destination root) in
+	// the destination; false means it could not be
+	record func(sourcePath, rel, target string) bool
+
+	symlinksSkipped, symlinksCopied, symlinksRecorded, special atomic.Int64
+}
+
+// entry handles the source entry at sourcePath, rel being its path relative to the
+// destination root and mode its type as listed (not followed). readLink returns a link's
+// target and stat what it points to. For a link to copy as its target, the target's info
+// is returned; the caller sends the job. Nothing is returned for everything else.
+func (p *linkPolicy) entry(sourcePath, rel string, mode os.FileMode, readLink func() (string, error), stat func() (os.FileInfo, error)) os.FileInfo {
+	if p == nil {
+		return nil
+	}
+	if mode&os.ModeSymlink == 0 {
+		p.special.Add(1)
+		return nil
+	}
+	switch p.mode {
+	case SymlinksCopyTarget:
+		if stat != nil {
+			if info, err := stat(); err == nil && info.Mode().IsRegular() {
+				p.symlinksCopied.Add(1)
+				return info
+			}
+		}
+	case SymlinksRecord:
+		if readLink != nil && p.record != nil {
+			if target, err := readLink(); err == nil && p.record(sourcePath, rel, target) {
+				p.symlinksRecorded.Add(1)
+				return nil
+			}
+		}
+	}
+	p.symlinksSkipped.Add(1)
+	return nil
+}
+
+// stats returns the counts so far
+func (p *linkPolicy) stats() SpecialFileStats {
+	if p == nil {
+		return SpecialFileStats{}
+	}
+	return SpecialFileStats{
+		SymlinksSkipped:  int(p.symlinksSkipped.Load()),
+		SymlinksCopied:   int(p.symlinksCopied.Load()),
+		SymlinksRecorded: int(p.symlinksRecorded.Load()),
+		Special:          int(p.special.Load()),
+	}
+}
+
+// recordSymlink recreates the link at sourcePath in the destination and records it in the
+// state. A link already recorded with the same target is left alone. Systems that can't
+// create links (Windows without the privilege) get a warning for each.
+func (e *Engine) recordSymlink(sourcePath, rel, target string) bool {
+	dest := e.destPath(sourcePath, rel)
+	if recorded, ok := e.stateManager.Symlink(sourcePath); ok && recorded == target {
+		if existing, err := os.Readlink(dest); err == nil && existing == target {
+			return true
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		e.log("warn", fmt.Sprintf("Could not recreate the link %s: %v", sourcePath, err))
+		return false
+	}
+	// Replaces an earlier link, never a file
+	if info, err := os.Lstat(dest); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			e.log("warn", fmt.Sprintf("Could not recreate the link %s: %s is not a link", sourcePath, dest))
+			return false
+		}
+		os.Remove(dest)
+	}
+	if err := os.Symlink(target, dest); err != nil {
+		e.log("warn", fmt.Sprintf("Could not recreate the link %s: %v", sourcePath, err))
+		return false
+	}
+	if err := e.stateManager.MarkSymlink(sourcePath, target); err != nil {
+		e.log("warn", fmt.Sprintf("Could not record the link %s in the state: %v", sourcePath, err))
+	}
+	return true
+}
+
+// remoteLinks reads the symbolic links of a source reached over the network; a nil
+// function means the transport can't, and the links it would need are skipped
+type remoteLinks struct {
+	readLink func(ctx context.Context, p string) (string, error)
+	stat     func(ctx context.Context, p string) (os.FileInfo, error)
+}
+
+// bind returns the functions linkPolicy.entry takes for the entry at p
+func (l remoteLinks) bind(ctx context.Context, p string) (readLink func() (string, error), stat func() (os.FileInfo, error)) {
+	if l.readLink != nil {
+		readLink = func() (string, error) { return l.readLink(ctx, p) }
+	}
+	if l.stat != nil {
+		stat = func() (os.FileInfo, error) { return l.stat(ctx, p) }
+	}
+	return readLink, stat
+}
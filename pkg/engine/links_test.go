@@ -0,0 +1,109 @@
+//go:build unix
+
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+// lastProgressReporter keeps the last progress update, the run's totals
+type lastProgressReporter struct {
+	discardReporter
+	mu   sync.Mutex
+	last ProgressUpdate
+}
+
+func (r *lastProgressReporter) ReportProgress(update ProgressUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = update
+}
+
+func TestSymlinkPolicies(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "phone")
+	data := filepath.Join(source, "Documents", "notes")
+	os.MkdirAll(data, 0755)
+	os.WriteFile(filepath.Join(data, "v2.txt"), []byte("milk"), 0644)
+	os.Symlink("v2.txt", filepath.Join(data, "current.txt"))
+	os.Symlink("..", filepath.Join(data, "parent"))
+	os.Symlink("v1.txt", filepath.Join(data, "old.txt"))
+	if err := syscall.Mkfifo(filepath.Join(data, "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy string
+		want   SpecialFileStats
+		check  func(t *testing.T, dest string)
+	}{
+		{"", SpecialFileStats{SymlinksSkipped: 3, Special: 1}, func(t *testing.T, dest string) {
+			if _, err := os.Lstat(filepath.Join(dest, "current.txt")); !os.IsNotExist(err) {
+				t.Errorf("a skipped link was backed up")
+			}
+		}},
+		{SymlinksCopyTarget, SpecialFileStats{SymlinksSkipped: 2, SymlinksCopied: 1, Special: 1}, func(t *testing.T, dest string) {
+			info, err := os.Lstat(filepath.Join(dest, "current.txt"))
+			if err != nil || !info.Mode().IsRegular() {
+				t.Fatalf("the link to a file was not copied as that file: %v", err)
+			}
+			if got, _ := os.ReadFile(filepath.Join(dest, "current.txt")); string(got) != "milk" {
+				t.Errorf("copied link = %q", got)
+			}
+		}},
+		{SymlinksRecord, SpecialFileStats{SymlinksRecorded: 3, Special: 1}, func(t *testing.T, dest string) {
+			for name, want := range map[string]string{"current.txt": "v2.txt", "parent": "..", "old.txt": "v1.txt"} {
+				if got, err := os.Readlink(filepath.Join(dest, name)); err != nil || got != want {
+					t.Errorf("%s: link to %q, %v; want %q", name, got, err, want)
+				}
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "backup")
+			sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sm.Close()
+			reporter := &lastProgressReporter{}
+			config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, Symlinks: tt.policy, Reporter: reporter}
+			if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := reporter.last.SpecialFiles; got != tt.want {
+				t.Errorf("SpecialFiles = %+v, want %+v", got, tt.want)
+			}
+			if reporter.last.Failed != 0 {
+				t.Errorf("%d files failed", reporter.last.Failed)
+			}
+			tt.check(t, filepath.Join(dest, "Documents", "notes"))
+
+			// Recorded links are in the state, and the next run leaves them alone
+			if tt.policy == SymlinksRecord {
+				if target, ok := sm.Symlink(filepath.Join(data, "current.txt")); !ok || target != "v2.txt" {
+					t.Errorf("state: Symlink = %q, %v", target, ok)
+				}
+				if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+					t.Fatalf("second run: %v", err)
+				}
+				tt.check(t, filepath.Join(dest, "Documents", "notes"))
+			}
+		})
+	}
+
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: t.TempDir(), Symlinks: "follow", Reporter: discardReporter{}}
+	sm, _ := state.NewStateManager(filepath.Join(t.TempDir(), "gus_state.md"))
+	defer sm.Close()
+	if err := NewEngine(config, sm).Run(context.Background()); err == nil {
+		t.Errorf("an unknown policy should be rejected")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package engine
+
+// longPath returns path unchanged: only Windows limits path lengths to 260 characters
+func longPath(path string) string {
+	return path
+}
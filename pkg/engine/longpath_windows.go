@@ -0,0 +1,28 @@
+//go:build windows
+
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length from which Windows needs the \\?\ prefix to open a path
+const maxPath = 260
+
+// longPath returns path with the \\?\ prefix that lifts the Windows 260-character limit, if
+// it is that long. The os package does this for its own calls; this is for the paths handed
+// to other programs, such as adb pull.
+func longPath(path string) string {
+	if len(path) < maxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if unc, ok := strings.CutPrefix(abs, `\\`); ok {
+		return `\\?\UNC\` + unc
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// buildManifest runs the scanner to completion and returns everything it found, priority
+// folders first and then by path, so the copy order is the same on every run. scanned must
+// be the channel the scanner closes when it finishes.
+func (e *Engine) buildManifest(ctx context.Context, scanner Scanner, scanned chan FileJob, errorChan chan<- error) ([]state.ManifestEntry, error) {
+	var entries []state.ManifestEntry
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for job := range scanned {
+			files := []FileJob{job}
+			if len(job.Batch) > 0 {
+				files = job.Batch // a batch of small files is listed file by file
+			}
+			for _, f := range files {
+				entries = append(entries, state.ManifestEntry{SourcePath: f.SourcePath, RelPath: f.RelPath, Size: f.Size})
+			}
+		}
+	}()
+	scanner.Scan(ctx, e.config.SourcePath, scanned, errorChan)
+	<-collected
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sizes for accurate totals; adb find doesn't report them and a stat per remote file is too slow
+	if e.sourceIsLocal() {
+		for i := range entries {
+			if info, err := os.Stat(entries[i].SourcePath); err == nil {
+				entries[i].Size = info.Size()
+			}
+		}
+	}
+
+	priorityPaths := e.filter.PriorityPaths()
+	sort.SliceStable(entries, func(i, j int) bool {
+		pi, pj := manifestPriority(entries[i].RelPath, priorityPaths), manifestPriority(entries[j].RelPath, priorityPaths)
+		if pi != pj {
+			return pi < pj
+		}
+		return entries[i].RelPath < entries[j].RelPath
+	})
+	return entries, nil
+}
+
+// manifestPriority ranks a relative path like getPathPriority does for directories
+func manifestPriority(relPath string, priorityPaths []string) int {
+	rel := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(relPath, "\\", "/")), "/")
+	for i, priorityPath := range priorityPaths {
+		if rel == priorityPath || strings.HasPrefix(rel, priorityPath+"/") {
+			return i
+		}
+	}
+	return 100
+}
+
+// loadManifest returns the manifest saved by an earlier manifest-first scan
+func (e *Engine) loadManifest() ([]state.ManifestEntry, error) {
+	manifest, err := e.stateManager.LoadManifest()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no scan manifest at %s; run a manifest-first backup first", e.stateManager.ManifestPath())
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.log("info", fmt.Sprintf("Using scan manifest from %s (%d files)", manifest.ScannedAt.Local().Format("2006-01-02 15:04"), len(manifest.Entries)))
+	return manifest.Entries, nil
+}
+
+// scanManifestFirst scans the whole source, saves the manifest next to the state file and
+// then feeds the workers from it. Closes jobs when done.
+func (e *Engine) scanManifestFirst(ctx context.Context, scanner Scanner, scanned chan FileJob, jobs chan<- FileJob, errorChan chan<- error, closeJobs func()) {
+	e.log("info", "Scanning source to build the file manifest before copying...")
+	entries, err := e.buildManifest(ctx, scanner, scanned, errorChan)
+	if err != nil {
+		closeJobs()
+		return
+	}
+	if err := e.stateManager.SaveManifest(entries); err != nil {
+		// The run can still use the in-memory manifest; only --from-manifest needs the file
+		e.log("warn", fmt.Sprintf("Could not save scan manifest: %v", err))
+	}
+	e.feedManifest(ctx, entries, jobs, closeJobs)
+}
+
+// feedManifest queues the manifest's files in order, making the totals known up front.
+// Closes jobs when done.
+func (e *Engine) feedManifest(ctx context.Context, entries []state.ManifestEntry, jobs chan<- FileJob, closeJobs func()) {
+	defer closeJobs()
+
+	var totalBytes int64
+	for _, entry := range entries {
+		totalBytes += entry.Size
+	}
+	e.stats.Lock()
+	e.stats.manifestFiles = len(entries)
+	e.stats.manifestBytes = totalBytes
+	e.stats.Unlock()
+	e.log("info", fmt.Sprintf("Manifest: %d files, %s", len(entries), formatSize(totalBytes)))
+	e.scanDone.Store(true)
+
+	for _, entry := range entries {
+		select {
+		case jobs <- FileJob{SourcePath: entry.SourcePath, RelPath: entry.RelPath}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
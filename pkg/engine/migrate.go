@@ -0,0 +1,349 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrateOptions configures RunMigrate, which copies the files of an old phone onto a new
+// one connected over adb, in the same folders
+type MigrateOptions struct {
+	// Target is the adb serial of the new phone (see adb devices)
+	Target string
+	// TargetRoot is the storage folder of the new phone the files go under (default "/sdcard")
+	TargetRoot string
+	// Direct reads the files from the old phone at SourcePath instead of from the backup: the
+	// mounted phone in mount mode, or the phone with adb serial From in adb mode
+	Direct bool
+	// From is the adb serial of the old phone in direct adb mode ("" = ANDROID_SERIAL)
+	From string
+	// Paths limit the migration to these folders or files, relative to the phone's storage
+	// (e.g. "DCIM"); empty = everything
+	Paths []string
+	// Overwrite replaces files on the new phone whose content differs; by default they are
+	// left alone and counted as conflicts
+	Overwrite bool
+	// DryRun lists the files that would be copied in MigrateResults.Planned without writing
+	DryRun bool
+}
+
+// MigrateResults summarizes a migration
+type MigrateResults struct {
+	Total     int              `json:"total"`
+	Copied    int              `json:"copied"`    // written to the new phone and verified there
+	Present   int              `json:"present"`   // already on the new phone with the same content
+	Conflicts int              `json:"conflicts"` // on the new phone with other content (see MigrateOptions.Overwrite)
+	Failed    int              `json:"failed"`
+	Bytes     int64            `json:"bytes"` // bytes written to the new phone
+	Folders   []MigrateFolder  `json:"folders"`
+	Failures  []RestoreFailure `json:"failures,omitempty"`
+	Planned   []string         `json:"planned,omitempty"` // dry run: paths that would be copied
+}
+
+// MigrateFolder is the progress of one top-level folder of the phone's storage (DCIM,
+// Pictures, ...; "/" for the files directly in it)
+type MigrateFolder struct {
+	Folder    string `json:"folder"`
+	Files     int    `json:"files"`
+	Copied    int    `json:"copied"`
+	Present   int    `json:"present"`
+	Conflicts int    `json:"conflicts"`
+	Failed    int    `json:"failed"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// migrateFile is a file RunMigrate copies to the new phone
+type migrateFile struct {
+	rel    string // under the storage root, e.g. "DCIM/Camera/IMG_1.jpg"
+	local  string // the backed-up copy or the file on the mounted phone ("" = pull device)
+	device string // on the old phone over adb
+	hash   string // recorded hash of the backed-up copy ("" = hash it)
+}
+
+// migrateTarget returns the new phone; replaced in tests
+var migrateTarget = func(serial string) restoreTarget {
+	return adbSource{serial: serial}
+}
+
+// pullDeviceFile copies a file from the phone with this serial to local; replaced in tests
+var pullDeviceFile = func(ctx context.Context, serial, devicePath, local string) error {
+	out, err := adbCommand(ctx, serial, "pull", devicePath, local).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if isADBDisconnect(msg) {
+			return fmt.Errorf("%w: %s", ErrConnectionLost, msg)
+		}
+		return fmt.Errorf("adb pull failed: %v: %s", err, msg)
+	}
+	return nil
+}
+
+// RunMigrate copies the backed-up files (or, with Direct, the files on the old phone) to the
+// new phone MigrateOptions.Target, checking each one against its hash on the new phone. Files
+// are copied folder by folder; the progress of each folder is logged as it completes.
+func (e *Engine) RunMigrate(ctx context.Context) (MigrateResults, error) {
+	opts := e.config.Migrate
+	var results MigrateResults
+	if opts.Target == "" {
+		return results, fmt.Errorf("no target phone (adb serial) to migrate to")
+	}
+	if opts.Direct && e.config.Mode == TransportADB && opts.From == opts.Target {
+		return results, fmt.Errorf("the old and the new phone are the same device (%s)", opts.Target)
+	}
+	root := opts.TargetRoot
+	if root == "" {
+		root = "/sdcard"
+	}
+
+	var files []migrateFile
+	var err error
+	if opts.Direct {
+		files, err = e.migratePhoneFiles(ctx)
+	} else {
+		files, err = e.migrateBackupFiles()
+	}
+	if err != nil {
+		return results, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].rel < files[j].rel })
+	results.Total = len(files)
+
+	tmp, err := os.MkdirTemp("", "gussync-migrate-")
+	if err != nil {
+		return results, err
+	}
+	defer os.RemoveAll(tmp)
+	target := migrateTarget(opts.Target)
+	defer e.openAudit()()
+
+	e.log("info", fmt.Sprintf("Migrating %d files to %s", len(files), opts.Target))
+	var folder *MigrateFolder
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		name := migrateFolder(f.rel)
+		if folder == nil || folder.Folder != name {
+			e.logMigrateFolder(folder)
+			results.Folders = append(results.Folders, MigrateFolder{Folder: name})
+			folder = &results.Folders[len(results.Folders)-1]
+		}
+		folder.Files++
+
+		before := results
+		err := e.migrateFile(ctx, target, tmp, path.Join(root, f.rel), f, &results)
+		if err != nil && IsCritical(err) {
+			return results, err
+		}
+		if err != nil {
+			results.Failed++
+			results.Failures = append(results.Failures, RestoreFailure{SourcePath: f.rel, Error: err.Error()})
+			e.log("warn", fmt.Sprintf("Could not migrate %s: %v", f.rel, err))
+		}
+		folder.Copied += results.Copied - before.Copied
+		folder.Present += results.Present - before.Present
+		folder.Conflicts += results.Conflicts - before.Conflicts
+		folder.Failed += results.Failed - before.Failed
+		folder.Bytes += results.Bytes - before.Bytes
+		if e.config.Reporter != nil {
+			e.config.Reporter.ReportProgress(ProgressUpdate{
+				TotalFiles:   len(files),
+				Completed:    i + 1 - results.Failed,
+				Failed:       results.Failed,
+				TotalBytes:   results.Bytes,
+				ScanComplete: true,
+			})
+		}
+	}
+	e.logMigrateFolder(folder)
+	return results, nil
+}
+
+// migrateFolder returns the top-level folder of a path under the storage root
+func migrateFolder(rel string) string {
+	if i := strings.Index(rel, "/"); i > 0 {
+		return rel[:i]
+	}
+	return "/"
+}
+
+// logMigrateFolder logs the progress of a folder RunMigrate is done with
+func (e *Engine) logMigrateFolder(f *MigrateFolder) {
+	if f == nil {
+		return
+	}
+	e.log("info", fmt.Sprintf("%s: %d files, %d copied (%s), %d already there, %d conflicts, %d failed",
+		f.Folder, f.Files, f.Copied, FormatSize(f.Bytes), f.Present, f.Conflicts, f.Failed))
+}
+
+// migrateBackupFiles lists the backed-up files at MigrateOptions.Paths (all of them when
+// there are none)
+func (e *Engine) migrateBackupFiles() ([]migrateFile, error) {
+	if e.stateManager == nil {
+		return nil, fmt.Errorf("no backup to migrate from")
+	}
+	roots, err := e.verifyRoots()
+	if err != nil {
+		return nil, err
+	}
+	paths := e.config.Migrate.Paths
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	seen := make(map[string]bool)
+	var files []migrateFile
+	for _, p := range paths {
+		found := e.stateManager.CatalogFiles(p)
+		if len(found) == 0 && p == "" {
+			return nil, fmt.Errorf("the backup is empty")
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("%s is not in the backup", p)
+		}
+		for _, f := range found {
+			if seen[f.SourcePath] {
+				continue
+			}
+			seen[f.SourcePath] = true
+			backup, ok := restoreBackupPath(e.config.DestRoot, e.names, roots, f.SourcePath)
+			if !ok {
+				continue
+			}
+			files = append(files, migrateFile{rel: f.Path, local: backup, hash: f.Hash})
+		}
+	}
+	return files, nil
+}
+
+// migratePhoneFiles lists the files of the old phone at MigrateOptions.Paths, leaving out
+// what a backup leaves out by default (caches, thumbnails, app data)
+func (e *Engine) migratePhoneFiles(ctx context.Context) ([]migrateFile, error) {
+	opts := e.config.Migrate
+	paths := opts.Paths
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	var files []migrateFile
+	switch e.config.Mode {
+	case TransportMount:
+		for _, p := range paths {
+			err := filepath.WalkDir(filepath.Join(e.config.SourcePath, filepath.FromSlash(p)), func(file string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				rel, err := normalizePhonePath(file, e.config.SourcePath)
+				if err != nil || shouldExcludeFile(rel) {
+					return nil
+				}
+				files = append(files, migrateFile{rel: filepath.ToSlash(rel), local: file})
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	case TransportADB:
+		for _, p := range paths {
+			out, err := adbShellOn(ctx, opts.From, "find", path.Join(e.config.SourcePath, p), "-type", "f")
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range strings.Split(string(out), "\n") {
+				file := strings.TrimRight(line, "\r")
+				if file == "" {
+					continue
+				}
+				rel, err := calculateRelPathFromAndroid(file, e.config.SourcePath)
+				if err != nil || shouldExcludeFile(rel) {
+					continue
+				}
+				files = append(files, migrateFile{rel: rel, device: file})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("migrating directly from the phone is not supported in %s mode (only mount and adb)", e.config.Mode)
+	}
+	return files, nil
+}
+
+// migrateFile copies one file to dest on the new phone, counting it in results unless it
+// fails. A file on the old phone over adb is pulled to tmp first.
+func (e *Engine) migrateFile(ctx context.Context, target restoreTarget, tmp, dest string, f migrateFile, results *MigrateResults) error {
+	local := f.local
+	if local == "" {
+		local = filepath.Join(tmp, "file")
+		defer os.Remove(local)
+		if err := pullDeviceFile(ctx, e.config.Migrate.From, f.device, local); err != nil {
+			return err
+		}
+	}
+	hash, err := calculateFileHash(local)
+	if err != nil {
+		return fmt.Errorf("unreadable: %w", err)
+	}
+	// Never copy a backed-up copy that has gone bad
+	if f.hash != "" && hash != f.hash {
+		return fmt.Errorf("backup copy %s does not match its recorded hash (run verify)", local)
+	}
+
+	_, isDir, err := target.Stat(ctx, dest)
+	switch {
+	case err == nil && isDir:
+		results.Conflicts++
+		return nil
+	case err == nil:
+		existing, err := target.Hash(ctx, dest)
+		if err != nil {
+			return err
+		}
+		if existing == hash {
+			results.Present++
+			return nil
+		}
+		if !e.config.Migrate.Overwrite {
+			results.Conflicts++
+			return nil
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	if e.config.Migrate.DryRun {
+		results.Planned = append(results.Planned, f.rel)
+		return nil
+	}
+	started := time.Now()
+	event := AuditEvent{Op: AuditMigrate, Path: dest, Dest: f.rel, Hash: hash}
+	err = target.Push(ctx, local, dest)
+	if err == nil {
+		var pushed string
+		if pushed, err = target.Hash(ctx, dest); err == nil && pushed != hash {
+			err = fmt.Errorf("copy on the new phone does not match (hash %s)", pushed)
+		}
+	}
+	event.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		event.Result, event.Error = AuditFailed, err.Error()
+		e.audit(event)
+		return err
+	}
+	if info, err := os.Stat(local); err == nil {
+		event.Bytes = info.Size()
+		results.Bytes += info.Size()
+	}
+	event.Result = AuditOK
+	e.audit(event)
+	results.Copied++
+	return nil
+}
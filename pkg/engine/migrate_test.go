@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestRunMigrate(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "old")
+	dest := filepath.Join(dir, "backup")
+	phone := filepath.Join(dir, "new")
+	for _, rel := range []string{"DCIM/Camera/a.jpg", "DCIM/Camera/b.jpg", "Download/c.pdf", "notes.txt", "Android/data/com.app/cache.bin"} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+rel), 0644)
+	}
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var serials []string
+	saved := migrateTarget
+	defer func() { migrateTarget = saved }()
+	migrateTarget = func(serial string) restoreTarget {
+		serials = append(serials, serial)
+		return localSource{}
+	}
+	// The new phone already has b, and another c
+	os.MkdirAll(filepath.Join(phone, "DCIM", "Camera"), 0755)
+	os.WriteFile(filepath.Join(phone, "DCIM", "Camera", "b.jpg"), []byte("content of DCIM/Camera/b.jpg"), 0644)
+	os.MkdirAll(filepath.Join(phone, "Download"), 0755)
+	os.WriteFile(filepath.Join(phone, "Download", "c.pdf"), []byte("other"), 0644)
+
+	config.Migrate = MigrateOptions{Target: "NEW1", TargetRoot: phone}
+	results, err := NewEngine(config, sm).RunMigrate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Total != 4 || results.Copied != 2 || results.Present != 1 || results.Conflicts != 1 || results.Failed != 0 {
+		t.Errorf("results = %+v; want a and notes.txt copied, b present, c in conflict", results)
+	}
+	want := []MigrateFolder{
+		{Folder: "DCIM", Files: 2, Copied: 1, Present: 1, Bytes: int64(len("content of DCIM/Camera/a.jpg"))},
+		{Folder: "Download", Files: 1, Conflicts: 1},
+		{Folder: "/", Files: 1, Copied: 1, Bytes: int64(len("content of notes.txt"))},
+	}
+	if !reflect.DeepEqual(results.Folders, want) {
+		t.Errorf("folders = %+v, want %+v", results.Folders, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(phone, "DCIM", "Camera", "a.jpg")); string(data) != "content of DCIM/Camera/a.jpg" {
+		t.Errorf("a.jpg on the new phone = %q", data)
+	}
+	if !reflect.DeepEqual(serials, []string{"NEW1"}) {
+		t.Errorf("target phones %v", serials)
+	}
+
+	// A rotted backup copy is not copied
+	os.WriteFile(filepath.Join(dest, "DCIM", "Camera", "a.jpg"), []byte("bitrot"), 0644)
+	os.Remove(filepath.Join(phone, "DCIM", "Camera", "a.jpg"))
+	config.Migrate = MigrateOptions{Target: "NEW1", TargetRoot: phone, Paths: []string{"DCIM"}}
+	if results, err = NewEngine(config, sm).RunMigrate(context.Background()); err != nil || results.Failed != 1 || results.Total != 2 {
+		t.Errorf("rotted copy: %+v, %v", results, err)
+	}
+
+	// Straight from the old phone: no backup, default exclusions apply
+	phone = filepath.Join(dir, "new2")
+	config.Migrate = MigrateOptions{Target: "NEW2", TargetRoot: phone, Direct: true, DryRun: true}
+	results, err = NewEngine(config, nil).RunMigrate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DCIM/Camera/a.jpg", "DCIM/Camera/b.jpg", "Download/c.pdf", "notes.txt"}; !reflect.DeepEqual(results.Planned, want) {
+		t.Errorf("dry run planned %v, want %v", results.Planned, want)
+	}
+	if _, err := os.Stat(phone); err == nil {
+		t.Errorf("a dry run should not write")
+	}
+
+	config.Migrate = MigrateOptions{}
+	if _, err := NewEngine(config, sm).RunMigrate(context.Background()); err == nil {
+		t.Error("expected an error without a target phone")
+	}
+}
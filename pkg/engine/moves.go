@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// moveIndex finds the backed-up copy of a file that moved on the source (DetectMoves), so it
+// is linked into its new place in the destination instead of being transferred again.
+// Candidates are the completed files of the current source, by size; the index is built on
+// first use and only files of a matching size are hashed on the source.
+type moveIndex struct {
+	e      *Engine
+	source cleanupSource
+	once   sync.Once
+	mu     sync.Mutex
+	bySize map[int64][]*moveCandidate
+	reused atomic.Int64
+}
+
+// moveCandidate is a completed file whose copy may serve a moved file
+type moveCandidate struct {
+	sourcePath string
+	hash       string
+	destPath   string
+	size       int64
+}
+
+// newMoveIndex returns the run's move index, or nil if DetectMoves is off or the source can't
+// be reached for hashing (custom transports)
+func (e *Engine) newMoveIndex() *moveIndex {
+	if !e.config.DetectMoves {
+		return nil
+	}
+	source := e.source
+	if source == nil {
+		if e.config.Scanner != nil {
+			return nil
+		}
+		source = e.cleanupSource()
+	}
+	return &moveIndex{e: e, source: source}
+}
+
+// close releases the source connection, if the index opened one
+func (m *moveIndex) close() {
+	if closer, ok := m.source.(io.Closer); ok && m.e.source == nil {
+		closer.Close()
+	}
+}
+
+func (m *moveIndex) build() {
+	e := m.e
+	m.bySize = make(map[int64][]*moveCandidate)
+	e.stateManager.IterateCompleted(func(path, hash string) bool {
+		if hash == "" || !isUnder(path, e.config.SourcePath) || e.inOtherSource(path) {
+			return true
+		}
+		relPath, err := filepath.Rel(e.config.SourcePath, path)
+		if err != nil {
+			return true
+		}
+		destPath := e.destPath(path, relPath)
+		if info, err := os.Stat(destPath); err == nil && info.Mode().IsRegular() {
+			m.bySize[info.Size()] = append(m.bySize[info.Size()], &moveCandidate{sourcePath: path, hash: hash, destPath: destPath, size: info.Size()})
+		}
+		return true
+	})
+}
+
+// find returns the backed-up copy with the same content as a new source file, and its hash
+func (m *moveIndex) find(ctx context.Context, job FileJob, destPath string) (*moveCandidate, string) {
+	m.once.Do(m.build)
+	size := job.Size
+	if size <= 0 {
+		var err error
+		if size, _, err = m.source.Stat(ctx, job.SourcePath); err != nil || size <= 0 {
+			return nil, ""
+		}
+	}
+	m.mu.Lock()
+	candidates := m.bySize[size]
+	m.mu.Unlock()
+	if len(candidates) == 0 {
+		return nil, ""
+	}
+	hash, err := m.source.Hash(ctx, job.SourcePath)
+	if err != nil {
+		return nil, ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range candidates {
+		if c.hash == hash && c.destPath != destPath {
+			return c, hash
+		}
+	}
+	return nil, ""
+}
+
+// reuse links the backed-up copy of a moved file into place and records the file as done.
+// It returns false if the file has to be copied from the source.
+func (m *moveIndex) reuse(ctx context.Context, job FileJob, statsChan chan<- CopyStats) bool {
+	e := m.e
+	destPath := e.destPath(job.SourcePath, job.RelPath)
+	started := time.Now()
+	c, hash := m.find(ctx, job, destPath)
+	if c == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	from := c.destPath
+	rename := e.sourceIsLocal() && !sourceExists(c.sourcePath)
+	err := placeMovedCopy(from, destPath, rename)
+	if err == nil && rename {
+		c.destPath = destPath // later copies of the same content link from the new place
+	}
+	m.mu.Unlock()
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Could not reuse the backup of %s for %s (%v); copying it", c.sourcePath, job.RelPath, err))
+		return false
+	}
+	got, err := e.hashLocal(destPath)
+	if err != nil {
+		// Whether the copy is still good is unknown: put it back where it was (a renamed
+		// copy is the only one) and transfer the file after all
+		e.log("warn", fmt.Sprintf("Could not check the backup of %s for %s (%v); copying it", c.sourcePath, job.RelPath, err))
+		if rename {
+			m.mu.Lock()
+			if os.Rename(destPath, from) == nil {
+				c.destPath = from
+			}
+			m.mu.Unlock()
+		} else {
+			os.Remove(destPath)
+		}
+		return false
+	}
+	if got != hash {
+		// The old copy has gone bad: transfer the file after all
+		os.Remove(destPath)
+		return false
+	}
+
+	e.setOwnership(destPath)
+	normalizedPath, _ := normalizePhonePath(job.SourcePath, e.config.SourcePath)
+	e.stateManager.MarkCompleted(state.CompletedFile{SourcePath: job.SourcePath, Hash: hash, NormalizedPath: normalizedPath,
+		Volume: volumeID(job.SourcePath), Size: c.size})
+	e.stateManager.MarkSuccess()
+	m.reused.Add(1)
+	fromRel, _ := filepath.Rel(e.config.DestRoot, from)
+	e.audit(AuditEvent{Op: AuditMove, Path: job.SourcePath, Dest: job.RelPath, From: fromRel, Hash: hash, Result: AuditOK,
+		DurationMS: time.Since(started).Milliseconds()})
+	statsChan <- CopyStats{Success: true, RelPath: job.RelPath, SourcePath: job.SourcePath, Duration: time.Since(started)}
+	return true
+}
+
+// placeMovedCopy puts a backed-up copy at its new destination path: renamed if the file is
+// gone from its old place on the source, otherwise hard-linked (copied where links aren't
+// supported) so the old entry keeps its copy
+func placeMovedCopy(from, to string, rename bool) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	os.Remove(to) // a partial copy from an earlier attempt
+	if rename {
+		return os.Rename(from, to)
+	}
+	if err := os.Link(from, to); err == nil {
+		return nil
+	}
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	part, err := openPart(to, nil)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(part, in)
+	if err != nil {
+		part.discard()
+		return err
+	}
+	return part.commit(n)
+}
+
+// sourceExists reports whether a local source file is still there
+func sourceExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
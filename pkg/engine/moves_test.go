@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestDetectMoves(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "moved.jpg"), []byte("moved photo"), 0644)
+	os.WriteFile(filepath.Join(source, "DCIM", "kept.jpg"), []byte("kept photo"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// One file is moved into an album, the other copied there; a new file of the same size
+	// but different content must still be transferred
+	album := filepath.Join(source, "Pictures", "Album")
+	os.MkdirAll(album, 0755)
+	os.Rename(filepath.Join(source, "DCIM", "moved.jpg"), filepath.Join(album, "moved.jpg"))
+	os.WriteFile(filepath.Join(album, "copy.jpg"), []byte("kept photo"), 0644)
+	os.WriteFile(filepath.Join(album, "new.jpg"), []byte("fresh photo"), 0644)
+
+	config.DetectMoves = true
+	config.Audit = true
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	albumDest := filepath.Join(dest, "Pictures", "Album")
+	for name, want := range map[string]string{"moved.jpg": "moved photo", "copy.jpg": "kept photo", "new.jpg": "fresh photo"} {
+		if data, err := os.ReadFile(filepath.Join(albumDest, name)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+		sourcePath := filepath.Join(album, name)
+		if !sm.IsDone(sourcePath) || sm.CompletedHash(sourcePath) != mustHash(t, sourcePath) {
+			t.Errorf("%s not recorded as done with its hash", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "DCIM", "moved.jpg")); !os.IsNotExist(err) {
+		t.Errorf("the backup of the moved file should have been renamed, not duplicated: %v", err)
+	}
+	kept, _ := os.Stat(filepath.Join(dest, "DCIM", "kept.jpg"))
+	linked, _ := os.Stat(filepath.Join(albumDest, "copy.jpg"))
+	if kept == nil || linked == nil || !os.SameFile(kept, linked) {
+		t.Errorf("the copy of a file still on the source should be hard-linked to its backup")
+	}
+
+	var moves, copies int
+	ReadAuditLog(filepath.Join(dest, AuditLogFileName), func(ev AuditEvent) error {
+		switch ev.Op {
+		case AuditMove:
+			moves++
+			if ev.Path == filepath.Join(album, "moved.jpg") && ev.From != filepath.Join("DCIM", "moved.jpg") {
+				t.Errorf("move = %+v", ev)
+			}
+		case AuditCopyEnd:
+			copies++
+		}
+		return nil
+	})
+	if moves != 2 || copies != 1 {
+		t.Errorf("audit has %d moves and %d copies, want 2 and 1", moves, copies)
+	}
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"GusSync/pkg/notify"
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// notify sends an event to the configured notifier; delivery problems are only logged
+func (e *Engine) notify(ctx context.Context, kind notify.Kind, title, message string) {
+	if e.config.Notifier == nil {
+		return
+	}
+	ev := notify.Event{Kind: kind, Title: title, Message: message, Source: e.config.SourcePath, Dest: e.config.DestRoot}
+	// Detached from ctx: a cancelled run still gets its notification out
+	if err := notify.Send(context.WithoutCancel(ctx), e.config.Notifier, ev); err != nil {
+		e.log("warn", err.Error())
+	}
+}
+
+// notifyCopyError raises the one-per-run notifications a failed copy can trigger
+func (e *Engine) notifyCopyError(ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		if e.notified.lowDisk.CompareAndSwap(false, true) {
+			e.notify(ctx, notify.LowDisk, "Backup destination is full", fmt.Sprintf("Copies to %s are failing: %v", e.config.DestRoot, err))
+		}
+	case IsCritical(err) && e.reconnect == nil:
+		if e.notified.connectionLost.CompareAndSwap(false, true) {
+			e.publish(Event{Kind: EventConnectionLost, Path: e.config.SourcePath, Error: err.Error(), Code: ErrorCode(err)})
+			e.notify(ctx, notify.ConnectionLost, "Phone disconnected", fmt.Sprintf("The backup lost its connection to the phone: %v", err))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ownership is what EngineConfig.Chown and Chmod resolve to
+type ownership struct {
+	uid, gid int         // -1 = leave
+	fileMode os.FileMode // 0 = leave
+	dirMode  os.FileMode // 0 = leave
+
+	dirs     sync.Map // directories already done
+	warnOnce sync.Once
+}
+
+// newOwnership parses Chown and Chmod; nil when both are empty
+func newOwnership(chown, chmod string) (*ownership, error) {
+	if chown == "" && chmod == "" {
+		return nil, nil
+	}
+	o := &ownership{uid: -1, gid: -1}
+	var err error
+	if chown != "" {
+		if o.uid, o.gid, err = ParseChown(chown); err != nil {
+			return nil, err
+		}
+	}
+	if chmod != "" {
+		if o.fileMode, o.dirMode, err = ParseChmod(chmod); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// ParseChown parses "user:group", "user" or ":group", each a name or a numeric id, into a
+// uid and gid (-1 = leave unchanged)
+func ParseChown(s string) (uid, gid int, err error) {
+	if runtime.GOOS == "windows" {
+		return -1, -1, fmt.Errorf("changing the owner is not supported on Windows")
+	}
+	userName, groupName, _ := strings.Cut(s, ":")
+	uid, gid = -1, -1
+	if userName != "" {
+		if uid, err = strconv.Atoi(userName); err != nil {
+			u, lookupErr := user.Lookup(userName)
+			if lookupErr != nil {
+				return -1, -1, fmt.Errorf("invalid owner %q: %w", s, lookupErr)
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+	if groupName != "" {
+		if gid, err = strconv.Atoi(groupName); err != nil {
+			g, lookupErr := user.LookupGroup(groupName)
+			if lookupErr != nil {
+				return -1, -1, fmt.Errorf("invalid owner %q: %w", s, lookupErr)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return -1, -1, fmt.Errorf("invalid owner %q (use user:group, user or :group)", s)
+	}
+	return uid, gid, nil
+}
+
+// ParseChmod parses octal permissions for the copied files and their folders: "644" for
+// files, with folders getting execute wherever files get read (755), or rsync-style
+// "F644,D750" to set them separately. A mode left out is 0 (leave unchanged).
+func ParseChmod(s string) (fileMode, dirMode os.FileMode, err error) {
+	parse := func(octal string) (os.FileMode, error) {
+		mode, err := strconv.ParseUint(octal, 8, 32)
+		if err != nil || mode > 0777 || mode == 0 {
+			return 0, fmt.Errorf("invalid mode %q in %q (use octal like 644, or F644,D755)", octal, s)
+		}
+		if mode&0400 == 0 {
+			return 0, fmt.Errorf("mode %q in %q would leave the backup unreadable to its owner", octal, s)
+		}
+		return os.FileMode(mode), nil
+	}
+	if !strings.ContainsAny(s, "FDfd") {
+		if fileMode, err = parse(s); err != nil {
+			return 0, 0, err
+		}
+		return fileMode, fileMode | (fileMode&0444)>>2, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mode, err := parse(part[1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		switch part[0] {
+		case 'F', 'f':
+			fileMode = mode
+		case 'D', 'd':
+			dirMode = mode
+		default:
+			return 0, 0, fmt.Errorf("invalid mode %q in %q (use octal like 644, or F644,D755)", part, s)
+		}
+	}
+	return fileMode, dirMode, nil
+}
+
+// apply sets the owner and mode of path
+func (o *ownership) apply(path string, mode os.FileMode) error {
+	if o.uid != -1 || o.gid != -1 {
+		if err := os.Lchown(path, o.uid, o.gid); err != nil {
+			return err
+		}
+	}
+	if mode != 0 {
+		return os.Chmod(path, mode)
+	}
+	return nil
+}
+
+// setOwnership applies Chown and Chmod to a file just written into the backup and to the
+// folders between it and DestRoot (each folder once per run)
+func (e *Engine) setOwnership(destPath string) {
+	o := e.ownership
+	if o == nil {
+		return
+	}
+	err := o.apply(destPath, o.fileMode)
+	for dir := filepath.Dir(destPath); err == nil && isUnder(dir, e.config.DestRoot); dir = filepath.Dir(dir) {
+		if _, done := o.dirs.LoadOrStore(dir, true); done {
+			break
+		}
+		err = o.apply(dir, o.dirMode)
+	}
+	if err != nil {
+		// Usually one cause (not running as root) for every file: say it once
+		o.warnOnce.Do(func() {
+			e.log("warn", fmt.Sprintf("Could not set the owner or permissions of backed-up files: %v", err))
+		})
+	}
+}
+
+// ownRunFiles applies Chown and Chmod to the files GusSync keeps in DestRoot (state, logs,
+// reports and manifests), so they belong to the same user as the backup
+func (e *Engine) ownRunFiles() {
+	if e.ownership == nil {
+		return
+	}
+	matches, _ := filepath.Glob(filepath.Join(e.config.DestRoot, "gus_*"))
+	for _, path := range matches {
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+			e.setOwnership(path)
+		}
+	}
+}
+
+// keepSourceMode gives a copy the permission bits of its source, for sources that have
+// them (ssh); bits the source doesn't have (setuid and the like) are not copied, and the
+// owner can always read the copy. Not on Windows, where a read-only copy couldn't be
+// replaced by the next version of the file.
+func keepSourceMode(destPath string, src os.FileInfo) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if perm := src.Mode().Perm(); perm != 0 {
+		os.Chmod(destPath, perm|0400)
+	}
+}
+
+// keepReadOnly makes the copy of a read-only source file read-only too, for sources that
+// only know that much about permissions (smb); not on Windows, as keepSourceMode
+func keepReadOnly(destPath string, src os.FileInfo) {
+	if runtime.GOOS == "windows" || src.Mode().Perm()&0222 != 0 {
+		return
+	}
+	if info, err := os.Stat(destPath); err == nil {
+		os.Chmod(destPath, info.Mode().Perm()&^0222)
+	}
+}
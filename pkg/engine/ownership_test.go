@@ -0,0 +1,130 @@
+//go:build !windows
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestParseChown(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		uid, gid int
+	}{
+		{"1000:100", 1000, 100},
+		{"1000", 1000, -1},
+		{":100", -1, 100},
+		{"root:0", 0, 0},
+	} {
+		uid, gid, err := ParseChown(tc.in)
+		if err != nil || uid != tc.uid || gid != tc.gid {
+			t.Errorf("ParseChown(%q) = %d, %d, %v; want %d, %d", tc.in, uid, gid, err, tc.uid, tc.gid)
+		}
+	}
+	for _, bad := range []string{":", "no-such-user-gussync", "1000:no-such-group-gussync"} {
+		if _, _, err := ParseChown(bad); err == nil {
+			t.Errorf("ParseChown(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestParseChmod(t *testing.T) {
+	for _, tc := range []struct {
+		in        string
+		file, dir os.FileMode
+	}{
+		{"644", 0644, 0755},
+		{"640", 0640, 0750},
+		{"600", 0600, 0700},
+		{"F664,D2775", 0, 0}, // setgid is not a permission bit
+		{"F664,D775", 0664, 0775},
+		{"d700", 0, 0700},
+	} {
+		file, dir, err := ParseChmod(tc.in)
+		if tc.file == 0 && tc.dir == 0 {
+			if err == nil {
+				t.Errorf("ParseChmod(%q): expected an error", tc.in)
+			}
+			continue
+		}
+		if err != nil || file != tc.file || dir != tc.dir {
+			t.Errorf("ParseChmod(%q) = %o, %o, %v; want %o, %o", tc.in, file, dir, err, tc.file, tc.dir)
+		}
+	}
+	for _, bad := range []string{"", "abc", "1000", "044", "X644"} {
+		if _, _, err := ParseChmod(bad); err == nil {
+			t.Errorf("ParseChmod(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestRunSetsOwnership(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM", "Camera"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "Camera", "a.jpg"), []byte("aaa"), 0644)
+	destRoot := filepath.Join(dir, "backup")
+	os.MkdirAll(destRoot, 0755)
+	sm, err := state.NewStateManager(filepath.Join(destRoot, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   destRoot,
+		NumWorkers: 1,
+		Reporter:   discardReporter{},
+		Retry:      RetryPolicy{MaxAttempts: 1},
+		Chown:      fmt.Sprint(os.Getuid()), // the only owner a test can change to
+		Chmod:      "F640,D750",
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for path, want := range map[string]os.FileMode{
+		filepath.Join(destRoot, "DCIM", "Camera", "a.jpg"): 0640,
+		filepath.Join(destRoot, "DCIM", "Camera"):          0750,
+		filepath.Join(destRoot, "DCIM"):                    0750,
+		destRoot:                                           0750,
+		filepath.Join(destRoot, "gus_state.md"):            0640,
+	} {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != want {
+			t.Errorf("%s: mode %v, %v; want %o", path, info.Mode().Perm(), err, want)
+		}
+	}
+
+	e.config.Chown = "no-such-user-gussync"
+	if err := e.Run(context.Background()); err == nil {
+		t.Error("expected an invalid -chown to fail the run")
+	}
+}
+
+func TestKeepSourceMode(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	os.WriteFile(src, nil, 0750)
+	os.Chmod(src, 0750)
+	os.WriteFile(dest, nil, 0644)
+	info, _ := os.Stat(src)
+	keepSourceMode(dest, info)
+	if got, _ := os.Stat(dest); got.Mode().Perm() != 0750 {
+		t.Errorf("keepSourceMode: %v, want 0750", got.Mode().Perm())
+	}
+
+	os.Chmod(src, 0444)
+	info, _ = os.Stat(src)
+	os.Chmod(dest, 0664)
+	keepReadOnly(dest, info)
+	if got, _ := os.Stat(dest); got.Mode().Perm() != 0444 {
+		t.Errorf("keepReadOnly: %v, want 0444", got.Mode().Perm())
+	}
+}
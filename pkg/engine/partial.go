@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// PartSuffix marks a file still being copied into the destination. Copiers write to
+// <name>.part and rename it over <name> only once the copy is complete and checked, so an
+// interrupted copy never leaves a truncated file that looks backed up.
+const PartSuffix = ".part"
+
+// partFile is a destination file being written through its .part file. Writes are hashed
+// as they go; before renaming it into place, commit reads the file on disk back, in full or
+// (when what was read from the source is known to be what was written, see source) a sample
+// of its blocks, and checks it against what was written.
+type partFile struct {
+	f        *os.File
+	path     string
+	dest     string
+	hash     hash.Hash  // of everything in the file; nil when it is written out of order (WriteAt)
+	blocks   *blockSums // of each block of the file, for commit to spot-check; nil with hash
+	read     hash.Hash  // of everything read from the source (see source); nil = not hashed
+	readBack bool       // commit reads the whole file back, not a sample
+	sum      string     // hash of the committed file ("" = not known)
+	offset   int64      // bytes kept from an interrupted copy, for the caller to skip in the source
+	sparse   bool       // blocks of zeros are skipped, leaving holes (see writeSparse)
+}
+
+// openPart opens the .part file of destPath for a copy of the source file described by src.
+// A .part file that an interrupted copy of the same version of the source left (see close)
+// is resumed: offset says how much of it is kept, and the caller continues reading the
+// source there. Anything else, such as a .part file left by a crash, is started over. src
+// nil never resumes.
+func openPart(destPath string, src os.FileInfo) (*partFile, error) {
+	p := &partFile{path: destPath + PartSuffix, dest: destPath, hash: sha256.New(), blocks: &blockSums{}}
+	if src != nil {
+		info, err := os.Stat(p.path)
+		if err == nil && info.Mode().IsRegular() && info.Size() > 0 && info.Size() <= src.Size() && info.ModTime().Equal(src.ModTime()) {
+			if f, err := os.OpenFile(p.path, os.O_RDWR, 0644); err == nil {
+				// Reading it leaves the file offset at its end, where the copy continues
+				if n, err := io.Copy(io.MultiWriter(p.hash, p.blocks), f); err == nil {
+					p.f, p.offset = f, n
+					return p, nil
+				}
+				f.Close()
+				p.hash.Reset()
+				p.blocks = &blockSums{}
+			}
+		}
+	}
+	f, err := os.Create(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dest: %w", err)
+	}
+	p.f = f
+	return p, nil
+}
+
+// Write implements io.Writer, appending to the .part file
+func (p *partFile) Write(b []byte) (int, error) {
+	if p.sparse {
+		return p.writeSparse(b)
+	}
+	n, err := p.f.Write(b)
+	p.hashWritten(b[:n])
+	return n, err
+}
+
+// source returns r hashing what is read from it, so commit only needs to read a sample of
+// the copy back from the destination. The kept bytes of a resumed copy count as read.
+func (p *partFile) source(r io.Reader) io.Reader {
+	if p.hash == nil {
+		return r
+	}
+	p.read = sha256.New()
+	if state, err := p.hash.(encoding.BinaryMarshaler).MarshalBinary(); err == nil {
+		p.read.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+	}
+	return io.TeeReader(r, p.read)
+}
+
+// preallocate reserves the space of a copy of size bytes on disk up front, so the file isn't
+// fragmented and a full disk shows before the copy rather than part way (Linux). Holes of a
+// sparse copy are kept.
+func (p *partFile) preallocate(size int64) {
+	if !p.sparse && size > p.offset {
+		preallocate(p.f, size)
+	}
+}
+
+// rewind keeps only the first offset bytes of a resumed .part file and stops hashing, for
+// copies that resume at a chunk boundary and check each chunk themselves
+func (p *partFile) rewind(offset int64) error {
+	p.hash, p.blocks = nil, nil
+	p.offset = min(p.offset, offset)
+	if err := p.f.Truncate(p.offset); err != nil {
+		return err
+	}
+	_, err := p.f.Seek(p.offset, io.SeekStart)
+	return err
+}
+
+// commit checks that the .part file holds size bytes (size < 0: not known) and, unless it
+// was written out of order, the content that was read from the source, then renames it over
+// the destination file. The whole file is read back when what was written can't be matched
+// to what was read (or readBack is set); otherwise a sample of its blocks is (see
+// spotCheck). A .part file that fails the checks is removed.
+func (p *partFile) commit(size int64) error {
+	if p.sparse {
+		// A hole at the end is only skipped over: make it part of the file
+		if end, err := p.f.Seek(0, io.SeekCurrent); err == nil {
+			if err := p.f.Truncate(end); err != nil {
+				p.discard()
+				return fmt.Errorf("failed to extend dest: %w", err)
+			}
+		}
+	}
+	if err := p.f.Sync(); err != nil {
+		p.discard()
+		return fmt.Errorf("failed to sync dest: %w", err)
+	}
+	info, err := p.f.Stat()
+	if err != nil {
+		p.discard()
+		return fmt.Errorf("failed to stat dest: %w", err)
+	}
+	if size >= 0 && info.Size() != size {
+		p.discard()
+		return fmt.Errorf("%w: copied %d of %d bytes", io.ErrUnexpectedEOF, info.Size(), size)
+	}
+	if err := p.f.Close(); err != nil {
+		os.Remove(p.path)
+		return fmt.Errorf("failed to close dest: %w", err)
+	}
+	if p.hash != nil {
+		want := hex.EncodeToString(p.hash.Sum(nil))
+		if p.readBack || p.read == nil || hex.EncodeToString(p.read.Sum(nil)) != want {
+			if p.read != nil {
+				want = hex.EncodeToString(p.read.Sum(nil))
+			}
+			got, err := readBackHash(p.path)
+			if err != nil {
+				os.Remove(p.path)
+				return fmt.Errorf("failed to hash dest: %w", err)
+			}
+			if got != want {
+				os.Remove(p.path)
+				return fmt.Errorf("%w: read %s, wrote %s", ErrHashMismatch, want, got)
+			}
+		} else if err := p.blocks.spotCheck(p.path); err != nil {
+			os.Remove(p.path)
+			return err
+		}
+		p.sum = want
+	}
+	return finishPart(p.path, p.dest)
+}
+
+// copyHashKey carries where a copier hands back the hash of the file it copied
+type copyHashKey struct{}
+
+// withCopyHash asks copiers to store the hash of the file they copy in *hash, when they
+// know it without reading the copy again (see reportCopyHash)
+func withCopyHash(ctx context.Context, hash *string) context.Context {
+	return context.WithValue(ctx, copyHashKey{}, hash)
+}
+
+// reportCopyHash hands the hash of a committed .part file back to the caller of the copy,
+// if it asked for it with withCopyHash
+func reportCopyHash(ctx context.Context, p *partFile) {
+	if hash, ok := ctx.Value(copyHashKey{}).(*string); ok && p.sum != "" {
+		*hash = p.sum
+	}
+}
+
+// close ends a copy that did not finish. With src, the data written so far is kept for the
+// next copy of the same version of the source to resume, which recognizes it by the
+// source's modification time given to the .part file here; without, the file is removed.
+func (p *partFile) close(src os.FileInfo) {
+	if src == nil || p.f.Sync() != nil {
+		p.discard()
+		return
+	}
+	p.f.Close()
+	if os.Chtimes(p.path, src.ModTime(), src.ModTime()) != nil {
+		os.Remove(p.path)
+	}
+}
+
+// discard closes and removes the .part file
+func (p *partFile) discard() {
+	p.f.Close()
+	os.Remove(p.path)
+}
+
+// finishPart renames a complete .part file over its destination file
+func finishPart(partPath, destPath string) error {
+	if err := os.Rename(partPath, destPath); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("failed to finalize dest: %w", err)
+	}
+	return nil
+}
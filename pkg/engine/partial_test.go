@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPartFile(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "IMG_0001.jpg")
+	src := filepath.Join(dir, "source.jpg")
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	os.WriteFile(src, data, 0644)
+	info, _ := os.Stat(src)
+
+	// An interrupted copy keeps its .part file and nothing at the destination
+	part, err := openPart(dest, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data[:3000])
+	part.close(info)
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("an interrupted copy should not create the destination file")
+	}
+
+	// The next copy of the same source resumes it
+	part, err = openPart(dest, info)
+	if err != nil || part.offset != 3000 {
+		t.Fatalf("resume: offset %d, %v", part.offset, err)
+	}
+	part.Write(data[3000:])
+	if err := part.commit(int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Errorf("resumed copy differs from the source")
+	}
+	if _, err := os.Stat(dest + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf(".part file left after commit")
+	}
+
+	// A changed source starts over
+	part, _ = openPart(dest, info)
+	part.Write(data[:3000])
+	part.close(info)
+	later := info.ModTime().Add(time.Minute)
+	os.Chtimes(src, later, later)
+	changed, _ := os.Stat(src)
+	if part, _ = openPart(dest, changed); part.offset != 0 {
+		t.Errorf("a .part file of another version of the source was resumed")
+	}
+
+	// A short copy is not committed and doesn't replace the earlier one
+	part.Write(data[:100])
+	if err := part.commit(int64(len(data))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("short copy: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Errorf("a failed copy replaced the destination file")
+	}
+	if _, err := os.Stat(dest + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf(".part file left after a failed commit")
+	}
+}
+
+func TestFSCopierResumesPartialCopy(t *testing.T) {
+	for _, chunkSize := range []int64{0, 4096} {
+		dir := t.TempDir()
+		sourceRoot, destRoot := filepath.Join(dir, "phone"), filepath.Join(dir, "backup")
+		source := filepath.Join(sourceRoot, "DCIM", "VID_0001.mp4")
+		dest := filepath.Join(destRoot, "DCIM", "VID_0001.mp4")
+		data := bytes.Repeat([]byte("abcdefghij"), 1000)
+		os.MkdirAll(filepath.Dir(source), 0755)
+		os.MkdirAll(filepath.Dir(dest), 0755)
+		os.WriteFile(source, data, 0644)
+		info, _ := os.Stat(source)
+
+		// What an interrupted attempt left behind
+		os.WriteFile(dest+PartSuffix, data[:5000], 0644)
+		os.Chtimes(dest+PartSuffix, info.ModTime(), info.ModTime())
+
+		copier := NewFSCopier()
+		copier.SetChunkSize(chunkSize)
+		progress := make(chan int64, 100)
+		var hash string
+		n, err := copier.Copy(withCopyHash(context.Background(), &hash), source, sourceRoot, destRoot, progress)
+		close(progress)
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("chunk size %d: Copy = %d, %v", chunkSize, n, err)
+		}
+		if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+			t.Errorf("chunk size %d: resumed copy differs from the source", chunkSize)
+		}
+		// Only the missing part was read again: from the kept bytes, or the last whole chunk
+		var read int64
+		for n := range progress {
+			read = max(read, n)
+		}
+		kept := int64(5000)
+		if chunkSize > 0 {
+			kept = 4096
+		}
+		if read != int64(len(data))-kept {
+			t.Errorf("chunk size %d: read %d bytes of the source again", chunkSize, read)
+		}
+		// Hashed as it was copied, kept bytes included; chunked copies check chunk by chunk
+		if want, _ := calculateFileHash(source); chunkSize == 0 && hash != want || chunkSize > 0 && hash != "" {
+			t.Errorf("chunk size %d: copy hash %q, source %s", chunkSize, hash, want)
+		}
+	}
+}
+
+func TestPartFileHashedAsRead(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "IMG_0001.jpg")
+	data := bytes.Repeat([]byte("abcdefghij"), 1000)
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	part, err := openPart(dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(part, part.source(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if err := part.commit(int64(len(data))); err != nil || part.sum != want {
+		t.Errorf("commit = %v, sum %q; want %s", err, part.sum, want)
+	}
+
+	// What was written isn't what was read: the file on disk decides
+	part, _ = openPart(dest, nil)
+	src := part.source(bytes.NewReader(data))
+	io.Copy(io.Discard, src)
+	part.Write(bytes.ToUpper(data))
+	if err := part.commit(int64(len(data))); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("commit of other data = %v, want a hash mismatch", err)
+	}
+}
+
+func TestPartFileSpotCheck(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "VID_0001.mp4")
+	data := bytes.Repeat([]byte("abcdefghij"), 3*spotCheckBlock/10+7)
+
+	// corruptedCopy writes data through the source's hash, then damages the .part file at
+	// offset, as a bad write would
+	corruptedCopy := func(offset int64, readBack bool) error {
+		part, err := openPart(dest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.readBack = readBack
+		if _, err := io.Copy(part, part.source(bytes.NewReader(data))); err != nil {
+			t.Fatal(err)
+		}
+		if offset >= 0 {
+			f, _ := os.OpenFile(dest+PartSuffix, os.O_WRONLY, 0)
+			f.WriteAt([]byte("X"), offset)
+			f.Close()
+		}
+		return part.commit(int64(len(data)))
+	}
+
+	if err := corruptedCopy(-1, false); err != nil {
+		t.Fatalf("intact copy: %v", err)
+	}
+	for _, offset := range []int64{0, spotCheckBlock + 5, int64(len(data)) - 1} {
+		os.Remove(dest)
+		if err := corruptedCopy(offset, false); !errors.Is(err, ErrHashMismatch) {
+			t.Errorf("copy damaged at %d: commit = %v, want a hash mismatch", offset, err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Errorf("a damaged copy was committed")
+		}
+	}
+	if err := corruptedCopy(spotCheckBlock+5, true); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("read back in full: commit = %v, want a hash mismatch", err)
+	}
+}
@@ -0,0 +1,11 @@
+package engine
+
+import "context"
+
+// Pauser lets the caller hold a run between files, e.g. a user pressing pause in the GUI
+// or a remote client. Files already being copied finish first.
+type Pauser interface {
+	Paused() bool
+	// WaitIfPaused returns at once unless paused, then blocks until resumed or ctx ends
+	WaitIfPaused(ctx context.Context) error
+}
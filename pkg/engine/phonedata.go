@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// Phone data EngineConfig.PhoneData can export: what isn't a file on the phone's storage
+const (
+	PhoneDataContacts = "contacts" // every contact, as vCards
+	PhoneDataSMS      = "sms"      // text messages (not MMS), as JSON
+	PhoneDataCalls    = "calls"    // the call log, as JSON
+)
+
+// PhoneDataKinds are the kinds of phone data, in export order
+var PhoneDataKinds = []string{PhoneDataContacts, PhoneDataSMS, PhoneDataCalls}
+
+// PhoneDataDirName is the folder of the destination the phone data is exported to. The
+// latest export of each kind is <kind>.vcf or <kind>.json; an export it replaces is kept
+// next to it, named after the time it was taken (e.g. sms-20260301-100000.json).
+const PhoneDataDirName = "Phone data"
+
+// contactsBatch is how many contacts one content read exports; their lookup keys make up
+// the URI, which must stay short enough for the device's shell
+const contactsBatch = 50
+
+// adbContent runs Android's content tool on the device (content query, content read);
+// replaced in tests
+var adbContent = func(ctx context.Context, serial string, args ...string) ([]byte, error) {
+	return adbShellOn(ctx, serial, "content", args...)
+}
+
+// phoneDataExporter exports one kind of phone data: the file's content and how many
+// contacts, messages or calls it holds
+type phoneDataExporter struct {
+	file   string
+	export func(ctx context.Context, serial string) ([]byte, int, error)
+}
+
+var phoneDataExporters = map[string]phoneDataExporter{
+	PhoneDataContacts: {"contacts.vcf", exportContacts},
+	PhoneDataSMS:      {"sms.json", exportSMS},
+	PhoneDataCalls:    {"calls.json", exportCalls},
+}
+
+// checkPhoneData validates EngineConfig.PhoneData before a run
+func (e *Engine) checkPhoneData() error {
+	for _, kind := range e.config.PhoneData {
+		if _, ok := phoneDataExporters[kind]; !ok {
+			return fmt.Errorf("unknown phone data %q (use %s)", kind, strings.Join(PhoneDataKinds, ", "))
+		}
+	}
+	if len(e.config.PhoneData) > 0 && e.config.Mode != TransportADB {
+		return fmt.Errorf("exporting contacts, messages and calls needs adb mode")
+	}
+	return nil
+}
+
+// exportPhoneData writes the phone data of EngineConfig.PhoneData to PhoneDataDirName and
+// records each export in the state. A kind that can't be exported (e.g. the device doesn't
+// let adb read it) is a warning; the files are backed up all the same.
+func (e *Engine) exportPhoneData(ctx context.Context) {
+	for _, kind := range PhoneDataKinds {
+		if !slices.Contains(e.config.PhoneData, kind) || ctx.Err() != nil {
+			continue
+		}
+		export, changed, err := e.exportPhoneDataKind(ctx, kind, time.Now())
+		switch {
+		case err != nil:
+			e.log("warn", fmt.Sprintf("Could not export %s: %v", kind, err))
+		case changed:
+			e.log("info", fmt.Sprintf("Exported %d %s to %s", export.Items, kind, export.File))
+		default:
+			e.log("info", fmt.Sprintf("%s unchanged since %s", export.File, export.At.Local().Format("2006-01-02 15:04")))
+		}
+	}
+}
+
+// exportPhoneDataKind exports one kind, unless its content is that of the last export
+// (changed false)
+func (e *Engine) exportPhoneDataKind(ctx context.Context, kind string, now time.Time) (state.Export, bool, error) {
+	exporter := phoneDataExporters[kind]
+	data, items, err := exporter.export(ctx, e.config.Serial)
+	if err != nil {
+		return state.Export{}, false, err
+	}
+	return e.writeExport(kind, path.Join(PhoneDataDirName, exporter.file), data, items, now)
+}
+
+// writeExport writes an export to file (relative to DestRoot) and records it in the state,
+// unless its content is that of the last export of its kind (changed false). The export it
+// replaces is kept next to it, named after the time it was taken.
+func (e *Engine) writeExport(kind, file string, data []byte, items int, now time.Time) (export state.Export, changed bool, err error) {
+	sum := sha256.Sum256(data)
+	export = state.Export{
+		Kind:  kind,
+		File:  file,
+		Hash:  hex.EncodeToString(sum[:]),
+		Items: items,
+		At:    now,
+	}
+
+	dest := filepath.Join(e.config.DestRoot, filepath.FromSlash(file))
+	var last state.Export
+	var known bool
+	if e.stateManager != nil {
+		last, known = e.stateManager.LastExport(kind)
+	}
+	if known && last.Hash == export.Hash {
+		if _, err := os.Stat(dest); err == nil {
+			return last, false, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return state.Export{}, false, err
+	}
+	if known {
+		// Keep the export this one replaces: deleting a message on the phone must not
+		// delete it from the backup
+		ext := path.Ext(file)
+		kept := strings.TrimSuffix(dest, ext) + "-" + last.At.Local().Format("20060102-150405") + ext
+		if err := os.Rename(dest, kept); err != nil && !os.IsNotExist(err) {
+			return state.Export{}, false, err
+		}
+	}
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return state.Export{}, false, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return state.Export{}, false, err
+	}
+	e.setOwnership(dest)
+	if e.stateManager != nil {
+		if err := e.stateManager.MarkExport(export); err != nil {
+			return export, true, err
+		}
+	}
+	return export, true, nil
+}
+
+// exportContacts exports every contact as a vCard, as the Contacts app's export does
+func exportContacts(ctx context.Context, serial string) ([]byte, int, error) {
+	rows, err := contentQuery(ctx, serial, "content://com.android.contacts/contacts", []string{"lookup"}, "_id")
+	if err != nil {
+		return nil, 0, err
+	}
+	var keys []string
+	for _, row := range rows {
+		if row["lookup"] != "" {
+			keys = append(keys, url.PathEscape(row["lookup"]))
+		}
+	}
+	var vcards bytes.Buffer
+	for start := 0; start < len(keys); start += contactsBatch {
+		end := min(start+contactsBatch, len(keys))
+		out, err := adbContent(ctx, serial, "read", "--uri",
+			"content://com.android.contacts/contacts/as_multi_vcard/"+strings.Join(keys[start:end], ":"))
+		if err != nil {
+			return nil, 0, err
+		}
+		if !bytes.Contains(out, []byte("BEGIN:VCARD")) {
+			return nil, 0, fmt.Errorf("no vCards from the contacts provider: %s", firstLine(out))
+		}
+		vcards.Write(out)
+	}
+	return vcards.Bytes(), bytes.Count(vcards.Bytes(), []byte("BEGIN:VCARD")), nil
+}
+
+// smsMessage is a text message in the sms.json export
+type smsMessage struct {
+	Thread  int64     `json:"thread"`  // the conversation
+	Address string    `json:"address"` // the other party's number
+	Type    string    `json:"type"`    // "inbox", "sent", "draft", "outbox", "failed" or "queued"
+	Date    time.Time `json:"date"`
+	Read    bool      `json:"read"`
+	Body    string    `json:"body"`
+}
+
+// smsTypes names the values of the sms provider's type column
+var smsTypes = map[string]string{"1": "inbox", "2": "sent", "3": "draft", "4": "outbox", "5": "failed", "6": "queued"}
+
+// exportSMS exports the text messages, oldest first
+func exportSMS(ctx context.Context, serial string) ([]byte, int, error) {
+	// body is last: it may contain ", " and line breaks
+	rows, err := contentQuery(ctx, serial, "content://sms", []string{"_id", "thread_id", "address", "date", "type", "read", "body"}, "_id")
+	if err != nil {
+		return nil, 0, err
+	}
+	messages := make([]smsMessage, 0, len(rows))
+	for _, row := range rows {
+		thread, _ := strconv.ParseInt(row["thread_id"], 10, 64)
+		messages = append(messages, smsMessage{
+			Thread:  thread,
+			Address: row["address"],
+			Type:    lookupOr(smsTypes, row["type"]),
+			Date:    millisTime(row["date"]),
+			Read:    row["read"] == "1",
+			Body:    row["body"],
+		})
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	return data, len(messages), err
+}
+
+// callRecord is a call in the calls.json export
+type callRecord struct {
+	Number   string    `json:"number"`
+	Name     string    `json:"name,omitempty"` // the contact's name when the call was logged
+	Type     string    `json:"type"`           // "incoming", "outgoing", "missed", "voicemail", "rejected" or "blocked"
+	Date     time.Time `json:"date"`
+	Duration int64     `json:"duration"` // seconds
+}
+
+// callTypes names the values of the call log's type column
+var callTypes = map[string]string{"1": "incoming", "2": "outgoing", "3": "missed", "4": "voicemail", "5": "rejected", "6": "blocked", "7": "answered elsewhere"}
+
+// exportCalls exports the call log, oldest first
+func exportCalls(ctx context.Context, serial string) ([]byte, int, error) {
+	rows, err := contentQuery(ctx, serial, "content://call_log/calls", []string{"_id", "number", "date", "duration", "type", "name"}, "_id")
+	if err != nil {
+		return nil, 0, err
+	}
+	calls := make([]callRecord, 0, len(rows))
+	for _, row := range rows {
+		duration, _ := strconv.ParseInt(row["duration"], 10, 64)
+		calls = append(calls, callRecord{
+			Number:   row["number"],
+			Name:     row["name"],
+			Type:     lookupOr(callTypes, row["type"]),
+			Date:     millisTime(row["date"]),
+			Duration: duration,
+		})
+	}
+	data, err := json.MarshalIndent(calls, "", "  ")
+	return data, len(calls), err
+}
+
+// contentQuery queries a content provider on the device for the columns, sorted by sort
+func contentQuery(ctx context.Context, serial, uri string, columns []string, sort string) ([]map[string]string, error) {
+	out, err := adbContent(ctx, serial, "query", "--uri", uri, "--projection", strings.Join(columns, ":"), "--sort", sort)
+	if err != nil {
+		return nil, err
+	}
+	rows := parseContentRows(string(out), columns)
+	if len(rows) == 0 && !strings.Contains(string(out), "No result found.") && strings.TrimSpace(string(out)) != "" {
+		// e.g. "Error while accessing provider:sms" when the shell may not read it
+		return nil, fmt.Errorf("%s", firstLine(out))
+	}
+	return rows, nil
+}
+
+// parseContentRows parses the output of content query, e.g.
+// "Row: 0 _id=1, address=+15550100, body=Hi, see you at 5"
+// The columns are given in the projection's order; the last one's value runs to the next
+// row, so it may contain ", " and line breaks. NULL values are empty.
+func parseContentRows(out string, columns []string) []map[string]string {
+	var raw []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if strings.HasPrefix(line, "Row: ") {
+			raw = append(raw, line)
+		} else if len(raw) > 0 {
+			raw[len(raw)-1] += "\n" + line
+		}
+	}
+	rows := make([]map[string]string, 0, len(raw))
+	for _, r := range raw {
+		_, rest, _ := strings.Cut(strings.TrimPrefix(r, "Row: "), " ")
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			value, ok := strings.CutPrefix(rest, column+"=")
+			if !ok {
+				break
+			}
+			if i+1 < len(columns) {
+				value, rest, _ = strings.Cut(value, ", "+columns[i+1]+"=")
+				rest = columns[i+1] + "=" + rest
+			}
+			if value != "NULL" {
+				row[column] = strings.TrimRight(value, "\r")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// millisTime converts a provider's date (milliseconds since 1970) to a time
+func millisTime(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// lookupOr returns the name of value in names, or value itself
+func lookupOr(names map[string]string, value string) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return value
+}
+
+// firstLine returns the first line of a command's output, for error messages
+func firstLine(out []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestParseContentRows(t *testing.T) {
+	out := "Row: 0 _id=1, address=+15550100, body=Hi, see you at 5\n" +
+		"Row: 1 _id=2, address=NULL, body=Two\nlines\n" +
+		"Row: 2 _id=3, address=Bank, body=Code: 1234, address=none\n"
+	rows := parseContentRows(out, []string{"_id", "address", "body"})
+	want := []map[string]string{
+		{"_id": "1", "address": "+15550100", "body": "Hi, see you at 5"},
+		{"_id": "2", "body": "Two\nlines"},
+		{"_id": "3", "address": "Bank", "body": "Code: 1234, address=none"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("parseContentRows = %q, want %q", rows, want)
+	}
+	if rows := parseContentRows("No result found.\n", []string{"_id"}); len(rows) != 0 {
+		t.Errorf("rows from an empty result: %q", rows)
+	}
+}
+
+func TestExportPhoneData(t *testing.T) {
+	body := "Hello"
+	var reads []string
+	saved := adbContent
+	defer func() { adbContent = saved }()
+	adbContent = func(ctx context.Context, serial string, args ...string) ([]byte, error) {
+		uri := args[2]
+		switch {
+		case args[0] == "read":
+			reads = append(reads, uri)
+			return []byte("BEGIN:VCARD\r\nFN:Ann\r\nEND:VCARD\r\nBEGIN:VCARD\r\nFN:Bob\r\nEND:VCARD\r\n"), nil
+		case uri == "content://com.android.contacts/contacts":
+			return []byte("Row: 0 lookup=0r1-A\nRow: 1 lookup=0r2-B C\n"), nil
+		case uri == "content://sms":
+			return []byte(fmt.Sprintf("Row: 0 _id=7, thread_id=3, address=+15550100, date=1700000000000, type=1, read=1, body=%s\n", body)), nil
+		case uri == "content://call_log/calls":
+			return []byte("Error while accessing provider:call_log\njava.lang.SecurityException: Permission Denial\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected content %v", args)
+	}
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "backup")
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	e := NewEngine(EngineConfig{Mode: TransportADB, SourcePath: "/sdcard", DestRoot: dest, Reporter: discardReporter{},
+		PhoneData: []string{PhoneDataContacts, PhoneDataSMS, PhoneDataCalls}}, sm)
+	if err := e.checkPhoneData(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	contacts, changed, err := e.exportPhoneDataKind(ctx, PhoneDataContacts, now)
+	if err != nil || !changed || contacts.Items != 2 {
+		t.Fatalf("contacts export = %+v, %v, %v", contacts, changed, err)
+	}
+	if want := []string{"content://com.android.contacts/contacts/as_multi_vcard/0r1-A:0r2-B%20C"}; !reflect.DeepEqual(reads, want) {
+		t.Errorf("vCards read from %v, want %v", reads, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "Phone data", "contacts.vcf")); strings.Count(string(data), "BEGIN:VCARD") != 2 {
+		t.Errorf("contacts.vcf = %q", data)
+	}
+
+	if _, _, err := e.exportPhoneDataKind(ctx, PhoneDataSMS, now); err != nil {
+		t.Fatal(err)
+	}
+	var messages []smsMessage
+	data, _ := os.ReadFile(filepath.Join(dest, "Phone data", "sms.json"))
+	if err := json.Unmarshal(data, &messages); err != nil || len(messages) != 1 ||
+		messages[0].Type != "inbox" || messages[0].Body != "Hello" || !messages[0].Date.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("sms.json = %s (%v)", data, err)
+	}
+
+	// Nothing new: the export is left alone
+	if _, changed, err := e.exportPhoneDataKind(ctx, PhoneDataSMS, now.Add(time.Hour)); err != nil || changed {
+		t.Errorf("unchanged messages exported again: %v, %v", changed, err)
+	}
+	// A message deleted on the phone stays in the export kept from before
+	body = "Bye"
+	if _, changed, err := e.exportPhoneDataKind(ctx, PhoneDataSMS, now.Add(2*time.Hour)); err != nil || !changed {
+		t.Fatalf("changed messages not exported: %v, %v", changed, err)
+	}
+	kept := filepath.Join(dest, "Phone data", "sms-"+now.Local().Format("20060102-150405")+".json")
+	if data, err := os.ReadFile(kept); err != nil || !strings.Contains(string(data), "Hello") {
+		t.Errorf("previous export not kept as %s: %v", kept, err)
+	}
+	if last, ok := sm.LastExport(PhoneDataSMS); !ok || !last.At.Equal(now.Add(2*time.Hour)) || last.File != "Phone data/sms.json" {
+		t.Errorf("state records %+v, %v", last, ok)
+	}
+
+	if _, _, err := e.exportPhoneDataKind(ctx, PhoneDataCalls, now); err == nil || !strings.Contains(err.Error(), "Error while accessing provider") {
+		t.Errorf("expected the provider's error, got %v", err)
+	}
+
+	e.config.PhoneData = []string{"photos"}
+	if err := e.checkPhoneData(); err == nil {
+		t.Error("expected an error for unknown phone data")
+	}
+	e.config.PhoneData, e.config.Mode = []string{PhoneDataSMS}, TransportMount
+	if err := e.checkPhoneData(); err == nil {
+		t.Error("expected an error outside adb mode")
+	}
+}
@@ -0,0 +1,22 @@
+package engine
+
+import "context"
+
+// PostCopyFile describes a file that has just been copied into the backup
+type PostCopyFile struct {
+	SourcePath string // Original path on the device
+	DestPath   string // Absolute path of the copied original in the backup
+	DestRoot   string // Backup root the original was copied into
+	Hash       string // SHA256 of the copied original
+}
+
+// PostProcessor runs an optional step after a file has been copied and recorded in state.
+// Implementations must never modify or replace file.DestPath - they may only create
+// additional artifacts next to the backup.
+type PostProcessor interface {
+	// Name identifies the processor in logs and state records
+	Name() string
+	// Process handles a copied file and returns the path of any derived artifact
+	// relative to DestRoot ("" if the file was not applicable)
+	Process(ctx context.Context, file PostCopyFile) (string, error)
+}
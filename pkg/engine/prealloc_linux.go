@@ -0,0 +1,13 @@
+package engine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves size bytes on disk for f without changing its length; filesystems
+// that can't are left alone
+func preallocate(f *os.File, size int64) {
+	unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+}
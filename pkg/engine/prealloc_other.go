@@ -0,0 +1,8 @@
+//go:build !linux
+
+package engine
+
+import "os"
+
+// preallocate does nothing: space is only reserved ahead on Linux
+func preallocate(f *os.File, size int64) {}
@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severities of a HealthProblem (the same words as the GUI's prerequisite checks)
+const (
+	HealthWarn = "warn" // the backup can run but may not go well
+	HealthFail = "fail" // the backup would find nothing or fail
+)
+
+// BatteryLowPercent is the battery level below which a phone that isn't charging gets a warning
+const BatteryLowPercent = 20
+
+// Lock states of DeviceHealth.Lock
+const (
+	LockUnknown  = ""
+	LockLocked   = "locked"
+	LockUnlocked = "unlocked"
+)
+
+// preflightListTimeout bounds listing the source root; a locked MTP phone can hang instead
+// of returning an empty listing
+const preflightListTimeout = 10 * time.Second
+
+// DeviceHealth is the state of the phone before a backup: what can be queried over the
+// transport, and the problems found
+type DeviceHealth struct {
+	BatteryPercent int    `json:"batteryPercent"` // -1 if unknown (only adb reports it)
+	Charging       bool   `json:"charging"`
+	FreeBytes      int64  `json:"freeBytes"` // free space on the phone, -1 if unknown
+	Lock           string `json:"lock"`      // LockLocked, LockUnlocked or LockUnknown
+	// Entries is the number of entries in the source folder, -1 if it couldn't be listed
+	Entries  int             `json:"entries"`
+	Problems []HealthProblem `json:"problems,omitempty"`
+}
+
+// HealthProblem is something about the device that stands in the way of a backup
+type HealthProblem struct {
+	Severity string `json:"severity"` // HealthWarn or HealthFail
+	Message  string `json:"message"`
+}
+
+// Failures returns the messages of the problems that should stop the backup
+func (h DeviceHealth) Failures() []string {
+	var msgs []string
+	for _, p := range h.Problems {
+		if p.Severity == HealthFail {
+			msgs = append(msgs, p.Message)
+		}
+	}
+	return msgs
+}
+
+// Warnings returns the messages of the problems the backup can go ahead with
+func (h DeviceHealth) Warnings() []string {
+	var msgs []string
+	for _, p := range h.Problems {
+		if p.Severity == HealthWarn {
+			msgs = append(msgs, p.Message)
+		}
+	}
+	return msgs
+}
+
+func (h *DeviceHealth) problem(severity, format string, args ...interface{}) {
+	h.Problems = append(h.Problems, HealthProblem{Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// CheckDeviceHealth checks the phone behind sourcePath before a backup: battery and lock
+// state over adb, free space, and whether the source lists any files at all. A locked
+// phone's MTP mount lists nothing, which would otherwise make the backup silently find 0
+// files. Other transports are not checked.
+func CheckDeviceHealth(ctx context.Context, mode, sourcePath string) DeviceHealth {
+	return CheckDeviceHealthOn(ctx, "", mode, sourcePath)
+}
+
+// CheckDeviceHealthOn is CheckDeviceHealth for the phone with this adb serial in adb mode
+// ("" = the one adb picks: ANDROID_SERIAL, or the only one attached)
+func CheckDeviceHealthOn(ctx context.Context, serial, mode, sourcePath string) DeviceHealth {
+	health := DeviceHealth{BatteryPercent: -1, FreeBytes: -1, Entries: -1}
+	switch mode {
+	case TransportADB:
+		checkADBHealth(ctx, serial, sourcePath, &health)
+	case TransportMount:
+		checkMountHealth(ctx, sourcePath, &health)
+	}
+	return health
+}
+
+func checkADBHealth(ctx context.Context, serial, androidRoot string, health *DeviceHealth) {
+	if androidRoot == "" {
+		androidRoot = "/sdcard"
+	}
+	out, err := adbShellOn(ctx, serial, "dumpsys", "battery")
+	if IsCritical(err) {
+		health.problem(HealthFail, "the device is not reachable over adb: %v", err)
+		return
+	}
+	if err == nil {
+		health.BatteryPercent, health.Charging = parseDumpsysBattery(string(out))
+	}
+	if out, err := adbShellOn(ctx, serial, "dumpsys", "window", "policy"); err == nil {
+		health.Lock = parseKeyguardState(string(out))
+	}
+	if free, err := (adbSource{serial: serial}).FreeSpace(ctx, androidRoot); err == nil {
+		health.FreeBytes = free
+	}
+	if out, err := adbShellOn(ctx, serial, "ls", "-A", androidRoot+"/"); err == nil {
+		health.Entries = 0
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.TrimSpace(line) != "" {
+				health.Entries++
+			}
+		}
+	} else if os.IsNotExist(err) {
+		health.problem(HealthFail, "%s does not exist on the device", androidRoot)
+	}
+
+	if health.BatteryPercent >= 0 && health.BatteryPercent < BatteryLowPercent && !health.Charging {
+		health.problem(HealthWarn, "the phone's battery is at %d%% and not charging; plug it in so it doesn't die mid-backup", health.BatteryPercent)
+	}
+	if health.Lock == LockLocked {
+		health.problem(HealthWarn, "the phone is locked; unlock it if files are missing from the backup")
+	}
+	if health.Entries == 0 {
+		health.problem(HealthFail, "%s on the device is empty; unlock the phone and try again", androidRoot)
+	}
+}
+
+func checkMountHealth(ctx context.Context, root string, health *DeviceHealth) {
+	type listing struct {
+		n   int
+		err error
+	}
+	done := make(chan listing, 1)
+	go func() {
+		entries, err := os.ReadDir(root)
+		done <- listing{len(entries), err}
+	}()
+	select {
+	case l := <-done:
+		switch {
+		case os.IsNotExist(l.err):
+			health.problem(HealthFail, "%s does not exist; is the phone still connected?", root)
+		case l.err != nil:
+			health.problem(HealthFail, "cannot list %s: %v", root, l.err)
+		default:
+			health.Entries = l.n
+		}
+	case <-time.After(preflightListTimeout):
+		health.problem(HealthWarn, "listing %s took over %s; the phone may be locked or busy", root, preflightListTimeout)
+	case <-ctx.Done():
+		return
+	}
+
+	if health.Entries == 0 {
+		health.problem(HealthFail, "%s lists no files; unlock the phone and pick \"File transfer\" in its USB notification", root)
+	}
+	if health.Entries > 0 {
+		if free, err := DiskFree(root); err == nil {
+			health.FreeBytes = free
+		}
+	}
+}
+
+// parseDumpsysBattery reads the level (percent) and whether the phone is charging from
+// `dumpsys battery`; the level is -1 if missing
+func parseDumpsysBattery(out string) (int, bool) {
+	level, scale := -1, 100
+	charging := false
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "level":
+			if n, err := strconv.Atoi(value); err == nil {
+				level = n
+			}
+		case "scale":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				scale = n
+			}
+		case "AC powered", "USB powered", "Wireless powered", "Dock powered":
+			if value == "true" {
+				charging = true
+			}
+		case "status":
+			if value == "2" || value == "5" { // BATTERY_STATUS_CHARGING, BATTERY_STATUS_FULL
+				charging = true
+			}
+		}
+	}
+	if level >= 0 && scale != 100 {
+		level = level * 100 / scale
+	}
+	return level, charging
+}
+
+// parseKeyguardState reads whether the lock screen is showing from `dumpsys window policy`
+// (the field names differ between Android versions)
+func parseKeyguardState(out string) string {
+	for _, key := range []string{"isKeyguardShowing=", "mKeyguardShowing=", "mShowingLockscreen=", "mDreamingLockscreen="} {
+		i := strings.Index(out, key)
+		if i < 0 {
+			continue
+		}
+		value := out[i+len(key):]
+		if strings.HasPrefix(value, "true") {
+			return LockLocked
+		}
+		if strings.HasPrefix(value, "false") {
+			return LockUnlocked
+		}
+	}
+	return LockUnknown
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDumpsysBattery(t *testing.T) {
+	out := `Current Battery Service state:
+  AC powered: false
+  USB powered: false
+  Wireless powered: false
+  status: 3
+  health: 2
+  present: true
+  level: 12
+  scale: 100
+  voltage: 3700
+`
+	if level, charging := parseDumpsysBattery(out); level != 12 || charging {
+		t.Errorf("got %d%%, charging %v; want 12%%, not charging", level, charging)
+	}
+	if level, charging := parseDumpsysBattery("  USB powered: true\n  level: 50\n  scale: 50\n"); level != 100 || !charging {
+		t.Errorf("got %d%%, charging %v; want 100%%, charging", level, charging)
+	}
+	if level, _ := parseDumpsysBattery("Can't find service: battery"); level != -1 {
+		t.Errorf("level = %d, want -1 when missing", level)
+	}
+}
+
+func TestParseKeyguardState(t *testing.T) {
+	for out, want := range map[string]string{
+		"    mShowingLockscreen=true mShowingDream=false":        LockLocked,
+		"  KeyguardServiceDelegate\n    isKeyguardShowing=false": LockUnlocked,
+		"WINDOW MANAGER POLICY STATE":                            LockUnknown,
+	} {
+		if got := parseKeyguardState(out); got != want {
+			t.Errorf("parseKeyguardState(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestCheckMountHealth(t *testing.T) {
+	// A locked MTP phone lists an empty root: the backup must not start
+	root := t.TempDir()
+	health := CheckDeviceHealth(context.Background(), TransportMount, root)
+	if health.Entries != 0 || len(health.Failures()) != 1 {
+		t.Errorf("empty source: %+v, want one failure", health)
+	}
+
+	os.WriteFile(filepath.Join(root, "a.jpg"), []byte("x"), 0644)
+	health = CheckDeviceHealth(context.Background(), TransportMount, root)
+	if health.Entries != 1 || len(health.Problems) != 0 || health.FreeBytes <= 0 {
+		t.Errorf("unlocked source: %+v, want no problems", health)
+	}
+
+	health = CheckDeviceHealth(context.Background(), TransportMount, filepath.Join(root, "gone"))
+	if len(health.Failures()) != 1 {
+		t.Errorf("missing source: %+v, want one failure", health)
+	}
+}
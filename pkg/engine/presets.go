@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MediaPreset selects one kind of file for a selective backup (see Filter.SetPresets)
+type MediaPreset struct {
+	Name       string
+	Extensions []string // lower case, with the dot
+	Folders    []string // where this kind of file usually lives; scanned first
+}
+
+// MediaPresets are the presets accepted by Filter.SetPresets and the -only flag
+var MediaPresets = []MediaPreset{
+	{
+		Name:       "photos",
+		Extensions: []string{".jpg", ".jpeg", ".png", ".heic", ".heif", ".webp", ".gif", ".bmp", ".tif", ".tiff", ".dng", ".raw"},
+		Folders:    []string{"DCIM", "Camera", "Pictures", "Screenshots"},
+	},
+	{
+		Name:       "videos",
+		Extensions: []string{".mp4", ".mov", ".m4v", ".3gp", ".3g2", ".mkv", ".webm", ".avi", ".ts"},
+		Folders:    []string{"DCIM", "Camera", "Movies", "Videos", "ScreenRecordings"},
+	},
+	{
+		Name:       "documents",
+		Extensions: []string{".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".odt", ".ods", ".odp", ".rtf", ".txt", ".md", ".csv", ".epub"},
+		Folders:    []string{"Documents", "Download"},
+	},
+	{
+		Name:       "audio",
+		Extensions: []string{".mp3", ".m4a", ".aac", ".flac", ".ogg", ".opus", ".wav", ".amr", ".wma"},
+		Folders:    []string{"Music", "Recordings", "Podcasts", "Audiobooks"},
+	},
+}
+
+// MediaPresetNames returns the names of the MediaPresets
+func MediaPresetNames() []string {
+	names := make([]string, len(MediaPresets))
+	for i, preset := range MediaPresets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// lookupMediaPreset returns the preset called name (case-insensitive)
+func lookupMediaPreset(name string) (MediaPreset, error) {
+	for _, preset := range MediaPresets {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return preset, nil
+		}
+	}
+	return MediaPreset{}, fmt.Errorf("unknown media type %q (use %s)", name, strings.Join(MediaPresetNames(), ", "))
+}
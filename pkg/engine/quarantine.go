@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QuarantineDirName is the directory (under the destination root) where copies that
+// failed verification are moved for triage
+const QuarantineDirName = "_quarantine"
+
+// quarantineFile moves destRoot/relPath (as named in the destination) to destRoot/_quarantine/relPath and returns the
+// new path relative to destRoot. An existing quarantined copy is kept; the newer one
+// gets a timestamp suffix.
+func quarantineFile(destRoot, relPath string) (string, error) {
+	quarantined := filepath.Join(QuarantineDirName, relPath)
+	target := filepath.Join(destRoot, quarantined)
+	if _, err := os.Lstat(target); err == nil {
+		quarantined = fmt.Sprintf("%s.%s", quarantined, time.Now().Format("20060102-150405"))
+		target = filepath.Join(destRoot, quarantined)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if err := os.Rename(filepath.Join(destRoot, relPath), target); err != nil {
+		return "", fmt.Errorf("failed to move %s to quarantine: %w", relPath, err)
+	}
+	return quarantined, nil
+}
+
+// repairCopy handles a destination copy whose hash doesn't match: the bad copy is moved
+// to quarantine and the file is copied again. If the fresh copy matches, the quarantined
+// one is discarded (repaired); otherwise it stays quarantined and the event is recorded
+// in state so it can be triaged (gussync quarantine list).
+func (e *Engine) repairCopy(ctx context.Context, copier Copier, sourcePath, relPath, expected, actual string) (repaired, quarantined bool) {
+	destPath := e.destPath(sourcePath, relPath)
+	destRel, err := filepath.Rel(e.config.DestRoot, destPath)
+	if err != nil {
+		destRel = relPath
+	}
+	quarantinedRel, err := quarantineFile(e.config.DestRoot, destRel)
+	if err != nil {
+		e.log("warn", err.Error())
+		return false, false
+	}
+	quarantinedPath := filepath.Join(e.config.DestRoot, quarantinedRel)
+
+	if copier == nil {
+		err = fmt.Errorf("source not available for a re-copy")
+	} else {
+		_, err = copier.Copy(ctx, sourcePath, e.config.SourcePath, e.config.DestRoot, nil)
+	}
+	if err == nil {
+		newHash, hashErr := calculateFileHash(destPath)
+		if hashErr == nil && newHash == expected {
+			os.Remove(quarantinedPath)
+			return true, false
+		}
+		err = fmt.Errorf("re-copy still does not match")
+	}
+
+	// Whatever the failed re-copy left behind is not trustworthy either
+	os.Remove(destPath)
+	if ctx.Err() != nil {
+		// Interrupted: put the original back, the next verify will try again
+		os.Rename(quarantinedPath, destPath)
+		return false, false
+	}
+
+	reason := fmt.Sprintf("hash mismatch (expected %s, got %s); %v", shortHash(expected), shortHash(actual), err)
+	e.stateManager.MarkQuarantined(sourcePath, quarantinedRel, reason)
+	e.log("warn", fmt.Sprintf("Quarantined %s: %s", quarantinedRel, reason))
+	return false, true
+}
+
+// log reports a message if a reporter is configured
+func (e *Engine) log(level, message string) {
+	if e.config.Reporter != nil {
+		e.config.Reporter.ReportLog(level, message)
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type failingCopier struct{}
+
+func (failingCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	return 0, errors.New("device unreadable")
+}
+
+func TestRepairCopy(t *testing.T) {
+	dir := t.TempDir()
+	sourceRoot := filepath.Join(dir, "phone")
+	destRoot := filepath.Join(dir, "backup")
+	sourcePath := filepath.Join(sourceRoot, "DCIM", "IMG_0001.jpg")
+	destPath := filepath.Join(destRoot, "DCIM", "IMG_0001.jpg")
+	for _, p := range []string{sourcePath, destPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(sourcePath, []byte("original photo"), 0644)
+	expected, _ := calculateFileHash(sourcePath)
+
+	sm, err := state.NewStateManager(filepath.Join(destRoot, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	e := NewEngine(EngineConfig{SourcePath: sourceRoot, DestRoot: destRoot}, sm)
+
+	// A working re-copy repairs the file and leaves nothing in quarantine
+	os.WriteFile(destPath, []byte("bitrot"), 0644)
+	repaired, quarantined := e.repairCopy(context.Background(), NewFSCopier(), sourcePath, "DCIM/IMG_0001.jpg", expected, "bad")
+	if !repaired || quarantined {
+		t.Fatalf("repairCopy = %v, %v; want repaired", repaired, quarantined)
+	}
+	if got, _ := calculateFileHash(destPath); got != expected {
+		t.Errorf("destination not repaired")
+	}
+	if _, err := os.Stat(filepath.Join(destRoot, QuarantineDirName)); err == nil {
+		if entries, _ := os.ReadDir(filepath.Join(destRoot, QuarantineDirName, "DCIM")); len(entries) > 0 {
+			t.Errorf("repaired copy left %d files in quarantine", len(entries))
+		}
+	}
+
+	// A failed re-copy keeps the bad copy in quarantine and records it
+	os.WriteFile(destPath, []byte("bitrot"), 0644)
+	repaired, quarantined = e.repairCopy(context.Background(), failingCopier{}, sourcePath, "DCIM/IMG_0001.jpg", expected, "bad")
+	if repaired || !quarantined {
+		t.Fatalf("repairCopy = %v, %v; want quarantined", repaired, quarantined)
+	}
+	data, err := os.ReadFile(filepath.Join(destRoot, QuarantineDirName, "DCIM", "IMG_0001.jpg"))
+	if err != nil || string(data) != "bitrot" {
+		t.Errorf("quarantined copy = %q, %v; want the bad file", data, err)
+	}
+	entries := sm.GetQuarantined()
+	if len(entries) != 1 || entries[0].SourcePath != sourcePath || entries[0].Path != filepath.Join(QuarantineDirName, "DCIM", "IMG_0001.jpg") {
+		t.Fatalf("quarantine entries = %+v", entries)
+	}
+	if entries[0].Reason == "" {
+		t.Errorf("expected a quarantine reason")
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// RateUnlimited disables throttling
+	RateUnlimited int64 = 0
+	// RatePaused stops new files from starting (in-flight files finish)
+	RatePaused int64 = -1
+
+	// minRateChunk is the smallest read size handed out under a limit
+	minRateChunk = 4 * 1024
+)
+
+// RateLimiter is a token bucket shared by all workers so the limit applies to
+// the aggregate transfer rate. The rate can be changed while copies are running.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    int64 // bytes per second; RateUnlimited or RatePaused are special
+	tokens  float64
+	last    time.Time
+	changed chan struct{} // closed and replaced whenever the rate changes
+}
+
+// NewRateLimiter creates a limiter with the given initial rate
+func NewRateLimiter(rate int64) *RateLimiter {
+	return &RateLimiter{rate: rate, last: time.Now(), changed: make(chan struct{})}
+}
+
+// SetRate changes the limit; waiters pick up the new rate immediately
+func (l *RateLimiter) SetRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rate == l.rate {
+		return
+	}
+	l.rate = rate
+	l.tokens = 0
+	l.last = time.Now()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Rate returns the current limit
+func (l *RateLimiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// WaitAllowed blocks while transfers are paused
+func (l *RateLimiter) WaitAllowed(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.rate != RatePaused {
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// chunkSize returns how many bytes a single read may request (at most one second of budget)
+func (l *RateLimiter) chunkSize(want int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 || int64(want) <= l.rate {
+		return want
+	}
+	if l.rate < minRateChunk {
+		return minRateChunk
+	}
+	return int(l.rate)
+}
+
+// WaitN blocks until n bytes may be transferred
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			// Unlimited, or paused (pause only gates new files, see WaitAllowed)
+			l.mu.Unlock()
+			return nil
+		}
+
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+		l.last = now
+		burst := float64(l.rate)
+		if burst < minRateChunk {
+			burst = minRateChunk
+		}
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+		if l.tokens >= float64(n) {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((float64(n) - l.tokens) / float64(l.rate) * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimitedReader throttles reads through a shared RateLimiter
+type rateLimitedReader struct {
+	io.Reader
+	limiter *RateLimiter
+	ctx     context.Context
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	p = p[:r.limiter.chunkSize(len(p))]
+	if err := r.limiter.WaitN(r.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	return r.Reader.Read(p)
+}
+
+// limitReader wraps src with the limiter (nil limiter returns src unchanged)
+func limitReader(ctx context.Context, src io.Reader, limiter *RateLimiter) io.Reader {
+	if limiter == nil {
+		return src
+	}
+	return &rateLimitedReader{Reader: src, limiter: limiter, ctx: ctx}
+}
@@ -0,0 +1,23 @@
+package engine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// adviseReadAhead tells the kernel the file is read sequentially and that the length bytes
+// at offset are needed next, so they are read from disk while the current ones are hashed
+func adviseReadAhead(f *os.File, offset, length int64) {
+	fd := int(f.Fd())
+	if offset == length {
+		unix.Fadvise(fd, 0, 0, unix.FADV_SEQUENTIAL)
+	}
+	unix.Fadvise(fd, offset, length, unix.FADV_WILLNEED)
+}
+
+// dropCached asks the kernel to drop the file's cached pages, so it is read back from the
+// disk rather than from memory. The file must have been synced.
+func dropCached(f *os.File) {
+	unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package engine
+
+import "os"
+
+// adviseReadAhead does nothing: read-ahead hints are only given on Linux; the reads stay large
+func adviseReadAhead(f *os.File, offset, length int64) {}
+
+// dropCached does nothing: outside Linux a copy read back may come from the page cache
+func dropCached(f *os.File) {}
@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReconnectWait is how long the CLI and app wait for a disconnected phone by default
+	DefaultReconnectWait = 5 * time.Minute
+	// reconnectProbeTimeout bounds one reachability check (a dead MTP mount can hang a stat)
+	reconnectProbeTimeout = 10 * time.Second
+	// reconnectMaxBackoff caps the delay between reachability checks
+	reconnectMaxBackoff = 30 * time.Second
+)
+
+// reconnector pauses the run while the source is unreachable and resumes it when the
+// device comes back. One goroutine polls; every worker or scanner that hits the outage
+// waits for the same result.
+type reconnector struct {
+	probe    func(ctx context.Context) error // nil when the source is reachable
+	maxWait  time.Duration
+	log      func(level, msg string)
+	giveUp   func(err error) // cancels the run when the source doesn't come back
+	interval time.Duration   // first delay between checks (doubles up to reconnectMaxBackoff)
+	onDown   func(err error) // optional, called (from the polling goroutine) when an outage starts
+	// repair is optional, called with a failed check's error; true if it fixed the source
+	// (e.g. remounted it), which is then checked again
+	repair func(ctx context.Context, err error) bool
+
+	mu     sync.Mutex
+	down   chan struct{} // non-nil while waiting for the source; closed when resolved
+	failed error         // set once the source didn't come back within maxWait
+}
+
+// newReconnector waits up to maxWait for the source to come back after a connection loss
+func newReconnector(probe func(ctx context.Context) error, maxWait time.Duration, log func(level, msg string), giveUp func(err error)) *reconnector {
+	return &reconnector{probe: probe, maxWait: maxWait, log: log, giveUp: giveUp, interval: time.Second}
+}
+
+// recover is called after an operation failed with err. If the source is unreachable it
+// blocks until the source is back and returns true: the operation should be retried.
+// It returns false if the source is reachable (err is the operation's own failure), if
+// ctx ends, or if the source didn't come back in time (the run is then cancelled).
+func (r *reconnector) recover(ctx context.Context, err error) bool {
+	r.mu.Lock()
+	if r.failed != nil {
+		r.mu.Unlock()
+		return false
+	}
+	down := r.down
+	r.mu.Unlock()
+
+	if down == nil {
+		if r.probe(ctx) == nil {
+			return false
+		}
+		r.mu.Lock()
+		if r.down == nil && r.failed == nil {
+			r.down = make(chan struct{})
+			r.log("warn", fmt.Sprintf("Source unreachable (%v); pausing and waiting up to %v for it to come back", err, r.maxWait))
+			go r.poll(ctx, r.down, err)
+		}
+		down = r.down
+		r.mu.Unlock()
+		if down == nil {
+			// Resolved (or given up) between the probe and the lock
+			return r.failure() == nil
+		}
+	}
+
+	select {
+	case <-down:
+	case <-ctx.Done():
+		return false
+	}
+	return r.failure() == nil && ctx.Err() == nil
+}
+
+// wait blocks while a reconnect is in progress; false if the run should stop
+func (r *reconnector) wait(ctx context.Context) bool {
+	r.mu.Lock()
+	down, failed := r.down, r.failed
+	r.mu.Unlock()
+	if failed != nil {
+		return false
+	}
+	if down == nil {
+		return true
+	}
+	select {
+	case <-down:
+		return r.failure() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// failure returns the error the run was given up with, if any
+func (r *reconnector) failure() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failed
+}
+
+// poll checks the source with exponential backoff until it is reachable, maxWait passes or ctx ends
+func (r *reconnector) poll(ctx context.Context, down chan struct{}, cause error) {
+	start := time.Now()
+	if r.onDown != nil {
+		r.onDown(cause)
+	}
+	delay := r.interval
+	for {
+		if sleepContext(ctx, delay) != nil {
+			r.resolve(down, nil)
+			return
+		}
+		err := r.probe(ctx)
+		if err != nil && r.repair != nil && r.repair(ctx, err) {
+			err = r.probe(ctx)
+		}
+		if err == nil {
+			r.log("info", fmt.Sprintf("Source is back after %v; resuming", time.Since(start).Round(time.Second)))
+			r.resolve(down, nil)
+			return
+		}
+		if time.Since(start) >= r.maxWait {
+			err := fmt.Errorf("%w: source did not come back within %v", ErrConnectionLost, r.maxWait)
+			r.log("error", err.Error())
+			r.giveUp(err) // cancel the run before waking the waiters
+			r.resolve(down, err)
+			return
+		}
+		if delay *= 2; delay > reconnectMaxBackoff {
+			delay = reconnectMaxBackoff
+		}
+	}
+}
+
+// resolve ends the outage, recording err if the run is given up
+func (r *reconnector) resolve(down chan struct{}, err error) {
+	r.mu.Lock()
+	r.down = nil
+	r.failed = err
+	r.mu.Unlock()
+	close(down)
+}
+
+// probeSource checks that the backup source is reachable: the mount point (mount mode)
+// or the device and its storage (adb mode)
+func (e *Engine) probeSource(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, reconnectProbeTimeout)
+	defer cancel()
+
+	if e.prober != nil {
+		return e.prober.Probe(ctx, e.config.SourcePath)
+	}
+	if e.config.Mode == "adb" {
+		// Only a missing device counts; a command error means adb is talking to the phone
+		if _, err := adbShellOn(ctx, e.config.Serial, "ls", "-d", sanitizeAndroidPath(e.config.SourcePath)); IsCritical(err) {
+			return err
+		}
+		return ctx.Err()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		// Read one entry: a stat alone can be answered from a stale gvfs cache
+		dir, err := os.Open(e.config.SourcePath)
+		if err == nil {
+			_, err = dir.Readdirnames(1)
+			dir.Close()
+			if err == io.EOF {
+				err = nil // empty but reachable
+			}
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("source not responding: %w", ctx.Err())
+	}
+}
+
+// awaitReconnect handles a failed copy or directory read: if the source went away and
+// came back within ReconnectWait it returns true and the operation should be retried
+func (e *Engine) awaitReconnect(ctx context.Context, err error) bool {
+	if err == nil || e.reconnect == nil || ctx.Err() != nil {
+		return false
+	}
+	return e.reconnect.recover(ctx, err)
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconnector(t *testing.T) {
+	errGone := errors.New("gone")
+	ctx := context.Background()
+	logf := func(level, msg string) { t.Logf("[%s] %s", level, msg) }
+
+	// Reachable source: the failure is the operation's own
+	r := newReconnector(func(context.Context) error { return nil }, time.Second, logf, func(error) { t.Error("gave up") })
+	if r.recover(ctx, errGone) {
+		t.Error("recover with a reachable source should not ask for a retry")
+	}
+
+	// Source comes back after a few checks: every waiter resumes
+	var probes atomic.Int32
+	r = newReconnector(func(context.Context) error {
+		if probes.Add(1) < 4 {
+			return errGone
+		}
+		return nil
+	}, 10*time.Second, logf, func(error) { t.Error("gave up") })
+	r.interval = time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !r.recover(ctx, errGone) {
+				t.Error("recover should succeed once the source is back")
+			}
+		}()
+	}
+	wg.Wait()
+	if !r.wait(ctx) {
+		t.Error("wait after reconnecting should not block the run")
+	}
+
+	// Source never comes back: the run is given up
+	var gaveUp error
+	r = newReconnector(func(context.Context) error { return errGone }, 20*time.Millisecond, logf, func(err error) { gaveUp = err })
+	r.interval = time.Millisecond
+	if r.recover(ctx, errGone) {
+		t.Error("recover should fail when the source doesn't come back")
+	}
+	if !errors.Is(gaveUp, ErrConnectionLost) || !errors.Is(r.failure(), ErrConnectionLost) {
+		t.Errorf("give-up error = %v, failure = %v; want ErrConnectionLost", gaveUp, r.failure())
+	}
+	if r.wait(ctx) {
+		t.Error("wait after giving up should stop the run")
+	}
+}
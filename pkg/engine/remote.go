@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+)
+
+// listDirFunc lists one directory of a source reached over the network ("/"-separated path)
+type listDirFunc func(ctx context.Context, dir string) ([]os.FileInfo, error)
+
+// scanRemote sends the regular files under root, listed with readDir: the selected folders,
+// or the priority folders first and then everything else. Links and special files are
+// handled by policy, following or reading links with follow. A critical error (lost
+// connection) is sent to errors and ends the scan; unreadable directories are reported
+// and skipped, missing ones silently.
+func scanRemote(ctx context.Context, root string, scanRoots []string, filter *Filter, readDir listDirFunc, policy *linkPolicy, follow remoteLinks, jobs chan<- FileJob, errors chan<- error) {
+	// Folders listed on their own (priority or selected) aren't listed again by the full walk
+	walked := make(map[string]bool)
+	tops := scanRoots
+	if len(tops) == 0 {
+		tops = filter.PriorityPaths()
+	}
+	for _, rel := range tops {
+		if !walkRemote(ctx, root, rel, walked, filter, readDir, policy, follow, jobs, errors) {
+			return
+		}
+		walked[rel] = true
+	}
+	if len(scanRoots) == 0 {
+		walkRemote(ctx, root, "", walked, filter, readDir, policy, follow, jobs, errors)
+	}
+}
+
+// walkRemote sends the files under root/rel, skipping the directories in skip; false
+// means the scan must stop
+func walkRemote(ctx context.Context, root, rel string, skip map[string]bool, filter *Filter, readDir listDirFunc, policy *linkPolicy, follow remoteLinks, jobs chan<- FileJob, errors chan<- error) bool {
+	pending := []string{rel}
+	for len(pending) > 0 {
+		dir := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		entries, err := readDir(ctx, path.Join(root, dir))
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			if IsCritical(err) {
+				errors <- err
+				return false
+			}
+			if !os.IsNotExist(err) {
+				errors <- pathError(PhaseScan, path.Join(root, dir), fmt.Errorf("failed to read %s: %w", path.Join(root, dir), err))
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			relPath := path.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if !skip[relPath] {
+					pending = append(pending, relPath)
+				}
+				continue
+			}
+			if filter.defaultExcluded(relPath) || filter.Excluded(relPath) {
+				continue
+			}
+			sourcePath := path.Join(root, relPath)
+			info := entry
+			if !entry.Mode().IsRegular() {
+				// Links and special files go by the symlink policy
+				readLink, stat := follow.bind(ctx, sourcePath)
+				if info = policy.entry(sourcePath, relPath, entry.Mode(), readLink, stat); info == nil {
+					continue
+				}
+			}
+			if filter.OutsideLimits(info.Size(), info.ModTime()) {
+				continue
+			}
+			select {
+			case jobs <- FileJob{SourcePath: sourcePath, RelPath: relPath, Size: info.Size()}:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	return true
+}
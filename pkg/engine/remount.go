@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// isStaleMount reports whether err comes from a gvfs mount whose daemon lost the device:
+// the directory is still there but every access fails until it is remounted
+func isStaleMount(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, syscall.ENOTCONN) || errors.Is(err, syscall.ESTALE) ||
+		strings.Contains(strings.ToLower(err.Error()), "transport endpoint is not connected")
+}
+
+// gvfsURI returns the device URI of a path inside a gvfs MTP or gphoto2 mount, e.g.
+// /run/user/1000/gvfs/mtp:host=Google_Pixel_6/Internal shared storage -> mtp://Google_Pixel_6/
+func gvfsURI(path string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		if part != "gvfs" || i+1 >= len(parts) {
+			continue
+		}
+		scheme, host, ok := strings.Cut(parts[i+1], ":host=")
+		if ok && host != "" && (scheme == "mtp" || scheme == "gphoto2") {
+			return scheme + "://" + host + "/", true
+		}
+	}
+	return "", false
+}
+
+// GioRemount unmounts a device URI with gio (ignoring failures: a stale mount may already be
+// half gone) and mounts it again
+func GioRemount(ctx context.Context, uri string) error {
+	exec.CommandContext(ctx, "gio", "mount", "-u", uri).Run()
+	_, err := GioMount(ctx, uri, DefaultGioMountTimeout)
+	return err
+}
+
+// remountStale remounts the source's gvfs mount if err shows it went stale and
+// RemountStale is on. It returns true if the source was remounted.
+func (e *Engine) remountStale(ctx context.Context, err error) bool {
+	if !e.config.RemountStale || !e.sourceIsLocal() || !isStaleMount(err) {
+		return false
+	}
+	uri, ok := gvfsURI(e.config.SourcePath)
+	if !ok {
+		return false
+	}
+	e.log("warn", fmt.Sprintf("Source mount is stale (%v); remounting %s", err, uri))
+	remount := e.remount
+	if remount == nil {
+		remount = GioRemount
+	}
+	if rerr := remount(ctx, uri); rerr != nil {
+		e.log("error", fmt.Sprintf("Remounting %s failed: %v", uri, rerr))
+		return false
+	}
+	e.log("info", fmt.Sprintf("Remounted %s", uri))
+	return true
+}
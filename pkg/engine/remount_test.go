@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestGvfsURI(t *testing.T) {
+	for path, want := range map[string]string{
+		"/run/user/1000/gvfs/mtp:host=Google_Pixel_6_1A2B3C/Internal shared storage": "mtp://Google_Pixel_6_1A2B3C/",
+		"/run/user/1000/gvfs/gphoto2:host=Canon_EOS":                                 "gphoto2://Canon_EOS/",
+		"/run/user/1000/gvfs/sftp:host=nas/photos":                                   "",
+		"/media/phone/DCIM": "",
+	} {
+		got, ok := gvfsURI(path)
+		if got != want || ok != (want != "") {
+			t.Errorf("gvfsURI(%q) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+}
+
+func TestIsStaleMount(t *testing.T) {
+	if !isStaleMount(&os.PathError{Op: "open", Path: "/run/user/1000/gvfs/mtp:host=x", Err: syscall.ENOTCONN}) {
+		t.Errorf("ENOTCONN should count as a stale mount")
+	}
+	if !isStaleMount(fmt.Errorf("readdir: transport endpoint is not connected")) {
+		t.Errorf("the error message should be recognised too")
+	}
+	if isStaleMount(os.ErrNotExist) {
+		t.Errorf("a missing mount is unplugged, not stale")
+	}
+}
+
+func TestReconnectRemountsStaleSource(t *testing.T) {
+	sm, err := state.NewStateManager(t.TempDir() + "/gus_state.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	e := NewEngine(EngineConfig{
+		Mode:         TransportMount,
+		SourcePath:   "/run/user/1000/gvfs/mtp:host=Pixel/Internal shared storage",
+		Reporter:     discardReporter{},
+		RemountStale: true,
+	}, sm)
+	var remounted []string
+	e.remount = func(ctx context.Context, uri string) error {
+		remounted = append(remounted, uri)
+		return nil
+	}
+
+	stale := &os.PathError{Op: "open", Path: e.config.SourcePath, Err: syscall.ENOTCONN}
+	r := newReconnector(func(context.Context) error {
+		if len(remounted) == 0 {
+			return stale
+		}
+		return nil
+	}, 10*time.Second, e.log, func(error) { t.Error("gave up") })
+	r.interval = time.Millisecond
+	r.repair = e.remountStale
+	if !r.recover(context.Background(), stale) {
+		t.Fatal("recover should succeed once the source is remounted")
+	}
+	if len(remounted) != 1 || remounted[0] != "mtp://Pixel/" {
+		t.Errorf("remounted %v, want [mtp://Pixel/]", remounted)
+	}
+
+	// Without RemountStale the mount is left alone
+	e.config.RemountStale = false
+	if e.remountStale(context.Background(), stale) || len(remounted) != 1 {
+		t.Errorf("remounted with RemountStale off")
+	}
+}
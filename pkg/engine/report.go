@@ -0,0 +1,291 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report formats for EngineConfig.Report
+const (
+	ReportHTML = "html"
+	ReportCSV  = "csv"
+)
+
+const (
+	reportSlowestFiles = 20   // files listed as slowest in the HTML report
+	reportMaxMessages  = 200  // run errors listed in the HTML report
+	reportMaxSamples   = 1000 // throughput points kept; older ones are merged pairwise beyond that
+)
+
+// ReportPath returns the report written by the last run ("" = none, see EngineConfig.Report)
+func (e *Engine) ReportPath() string {
+	return e.reportPath
+}
+
+// reportFile is one copied or failed file of a run
+type reportFile struct {
+	Path     string
+	Bytes    int64
+	Duration time.Duration
+	Err      string // "" = copied
+}
+
+// throughputPoint is the average transfer rate over one progress interval
+type throughputPoint struct {
+	At   time.Duration // since the run started
+	Rate float64       // bytes per second
+}
+
+// runReport collects what a run did for the report written when it ends
+type runReport struct {
+	mu         sync.Mutex
+	format     string
+	started    time.Time
+	files      []reportFile
+	skipped    int
+	errorCodes map[string]int // ErrorCode -> count
+	messages   []string
+	throughput []throughputPoint
+}
+
+func newRunReport(format string) *runReport {
+	return &runReport{format: format, started: time.Now(), errorCodes: make(map[string]int)}
+}
+
+// addStats records the outcome of one file
+func (r *runReport) addStats(s CopyStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case s.Skipped:
+		r.skipped++
+	case s.Success:
+		r.files = append(r.files, reportFile{Path: s.SourcePath, Bytes: s.BytesCopied, Duration: s.Duration})
+	default:
+		reason := "failed"
+		if s.Err != nil {
+			reason = s.Err.Error()
+		}
+		r.files = append(r.files, reportFile{Path: s.SourcePath, Duration: s.Duration, Err: reason})
+	}
+}
+
+// addError records an error or warning reported during the run
+func (r *runReport) addError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errorCodes[ErrorCode(err)]++
+	if len(r.messages) < reportMaxMessages {
+		r.messages = append(r.messages, err.Error())
+	}
+}
+
+// addSample records the transfer rate of one progress interval
+func (r *runReport) addSample(at time.Time, rate float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.throughput = append(r.throughput, throughputPoint{At: at.Sub(r.started), Rate: rate})
+	if len(r.throughput) > reportMaxSamples {
+		// Halve the resolution instead of dropping the start of a long run
+		merged := r.throughput[:0]
+		for i := 0; i+1 < len(r.throughput); i += 2 {
+			a, b := r.throughput[i], r.throughput[i+1]
+			merged = append(merged, throughputPoint{At: b.At, Rate: (a.Rate + b.Rate) / 2})
+		}
+		r.throughput = merged
+	}
+}
+
+// write saves the report into dir and returns its path; dirs are the run's per-folder figures
+func (r *runReport) write(dir string, finished time.Time, dirs []DirStats) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := fmt.Sprintf("gus_report_%s.%s", r.started.Format("20060102-150405"), r.format)
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report: %w", err)
+	}
+	switch r.format {
+	case ReportCSV:
+		err = r.writeCSV(f)
+	default:
+		err = r.writeHTML(f, finished, dirs)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// writeCSV writes one row per copied or failed file
+func (r *runReport) writeCSV(f *os.File) error {
+	w := csv.NewWriter(f)
+	w.Write([]string{"status", "path", "bytes", "seconds", "error"})
+	for _, file := range r.files {
+		status := "copied"
+		if file.Err != "" {
+			status = "failed"
+		}
+		w.Write([]string{status, file.Path, strconv.FormatInt(file.Bytes, 10),
+			strconv.FormatFloat(file.Duration.Seconds(), 'f', 3, 64), file.Err})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// reportData is what the HTML template shows
+type reportData struct {
+	Started, Finished string
+	Elapsed           string
+	Copied, Failed    int
+	Skipped           int
+	Bytes             string
+	AvgRate           string
+	Dirs              []DirStats
+	Failures          []reportFile
+	Slowest           []reportFile
+	ErrorCodes        []errorCodeCount
+	Messages          []string
+	MoreMessages      bool
+	Chart             template.HTML // throughput as an inline SVG
+}
+
+type errorCodeCount struct {
+	Code  string
+	Count int
+}
+
+func (r *runReport) writeHTML(f *os.File, finished time.Time, dirs []DirStats) error {
+	data := reportData{
+		Dirs:         dirs,
+		Started:      r.started.Format("2006-01-02 15:04:05"),
+		Finished:     finished.Format("2006-01-02 15:04:05"),
+		Elapsed:      finished.Sub(r.started).Round(time.Second).String(),
+		Skipped:      r.skipped,
+		Messages:     r.messages,
+		MoreMessages: len(r.messages) == reportMaxMessages,
+		Chart:        throughputChart(r.throughput),
+	}
+	var bytes int64
+	var copied []reportFile
+	for _, file := range r.files {
+		if file.Err != "" {
+			data.Failures = append(data.Failures, file)
+			continue
+		}
+		copied = append(copied, file)
+		bytes += file.Bytes
+	}
+	data.Copied, data.Failed = len(copied), len(data.Failures)
+	data.Bytes = formatSize(bytes)
+	if secs := finished.Sub(r.started).Seconds(); secs > 0 {
+		data.AvgRate = formatSize(int64(float64(bytes)/secs)) + "/s"
+	}
+	sort.Slice(copied, func(i, j int) bool { return copied[i].Duration > copied[j].Duration })
+	if len(copied) > reportSlowestFiles {
+		copied = copied[:reportSlowestFiles]
+	}
+	data.Slowest = copied
+	for code, n := range r.errorCodes {
+		data.ErrorCodes = append(data.ErrorCodes, errorCodeCount{Code: code, Count: n})
+	}
+	sort.Slice(data.ErrorCodes, func(i, j int) bool { return data.ErrorCodes[i].Count > data.ErrorCodes[j].Count })
+	return reportTemplate.Execute(f, data)
+}
+
+// throughputChart draws the throughput samples as an SVG line chart
+func throughputChart(points []throughputPoint) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+	const width, height = 800.0, 200.0
+	maxRate := 0.0
+	for _, p := range points {
+		if p.Rate > maxRate {
+			maxRate = p.Rate
+		}
+	}
+	span := points[len(points)-1].At.Seconds()
+	if maxRate <= 0 || span <= 0 {
+		return ""
+	}
+	coords := make([]string, len(points))
+	for i, p := range points {
+		x := p.At.Seconds() / span * width
+		y := height - p.Rate/maxRate*height
+		coords[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return template.HTML(fmt.Sprintf(`<svg viewBox="0 0 %.0f %.0f" width="100%%" height="%.0f" preserveAspectRatio="none">`+
+		`<polyline fill="none" stroke="#2b7de9" stroke-width="2" points="%s"/></svg>`+
+		`<p class="note">Peak %s over %s</p>`,
+		width, height, height, strings.Join(coords, " "), formatSize(int64(maxRate))+"/s", points[len(points)-1].At.Round(time.Second)))
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size":     formatSize,
+	"duration": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GusSync backup report {{.Started}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+td.num { text-align: right; }
+.failed { color: #b00020; }
+.note { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Backup report</h1>
+<table>
+<tr><th>Started</th><td>{{.Started}}</td></tr>
+<tr><th>Finished</th><td>{{.Finished}} ({{.Elapsed}})</td></tr>
+<tr><th>Copied</th><td>{{.Copied}} files, {{.Bytes}}{{if .AvgRate}} at {{.AvgRate}} on average{{end}}</td></tr>
+<tr><th>Skipped</th><td>{{.Skipped}} (already backed up or out of retries)</td></tr>
+<tr><th>Failed</th><td{{if .Failed}} class="failed"{{end}}>{{.Failed}}</td></tr>
+</table>
+{{if .Chart}}<h2>Throughput</h2>
+{{.Chart}}{{end}}
+{{if .Dirs}}<h2>Folders</h2>
+<table>
+<tr><th>Folder</th><th>Files</th><th>Copied</th><th>Size</th><th>Skipped</th><th>Failed</th></tr>
+{{range .Dirs}}<tr><td>{{.Dir}}</td><td class="num">{{.Files}}</td><td class="num">{{.Completed}}</td><td class="num">{{size .Bytes}}</td><td class="num">{{.Skipped}}</td><td class="num{{if .Failed}} failed{{end}}">{{.Failed}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Failures}}<h2>Failed files</h2>
+<table>
+<tr><th>File</th><th>Reason</th></tr>
+{{range .Failures}}<tr><td>{{.Path}}</td><td class="failed">{{.Err}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Slowest}}<h2>Slowest files</h2>
+<table>
+<tr><th>File</th><th>Size</th><th>Time</th></tr>
+{{range .Slowest}}<tr><td>{{.Path}}</td><td class="num">{{size .Bytes}}</td><td class="num">{{duration .Duration}}</td></tr>
+{{end}}</table>{{end}}
+{{if .ErrorCodes}}<h2>Errors and warnings</h2>
+<table>
+<tr><th>Kind</th><th>Count</th></tr>
+{{range .ErrorCodes}}<tr><td>{{.Code}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+<ul>
+{{range .Messages}}<li>{{.}}</li>
+{{end}}</ul>
+{{if .MoreMessages}}<p class="note">Only the first messages are listed; the error log has all of them.</p>{{end}}{{end}}
+</body>
+</html>
+`))
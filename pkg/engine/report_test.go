@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestRunReportWrite(t *testing.T) {
+	dir := t.TempDir()
+	newReport := func(format string) *runReport {
+		r := newRunReport(format)
+		r.addStats(CopyStats{Success: true, SourcePath: "DCIM/fast.jpg", BytesCopied: 100, Duration: time.Millisecond})
+		r.addStats(CopyStats{Success: true, SourcePath: "DCIM/slow.mp4", BytesCopied: 5000, Duration: 3 * time.Second})
+		r.addStats(CopyStats{SourcePath: "DCIM/bad.jpg", Err: errors.New("permission denied")})
+		r.addStats(CopyStats{Skipped: true, SourcePath: "DCIM/old.jpg"})
+		r.addError(errors.New("copy failed: DCIM/bad.jpg: permission denied"))
+		r.addSample(r.started.Add(2*time.Second), 1000)
+		r.addSample(r.started.Add(4*time.Second), 3000)
+		return r
+	}
+
+	dirs := []DirStats{{Dir: "DCIM", Files: 4, Completed: 2, Failed: 1, Skipped: 1, Bytes: 5100}}
+	path, err := newReport(ReportHTML).write(dir, time.Now(), dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "gus_report_") || filepath.Ext(path) != ".html" {
+		t.Errorf("report path = %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	html := string(data)
+	for _, want := range []string{"DCIM/bad.jpg", "permission denied", "DCIM/slow.mp4", "<polyline", "2 files", "<td>DCIM</td>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report is missing %q", want)
+		}
+	}
+	if strings.Index(html, "DCIM/slow.mp4") > strings.Index(html, "DCIM/fast.jpg") {
+		t.Error("slowest files are not listed slowest first")
+	}
+
+	path, err = newReport(ReportCSV).write(dir, time.Now(), dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("CSV report has %d rows, want a header and 3 files: %v", len(rows), rows)
+	}
+	if got := rows[3]; got[0] != "failed" || got[1] != "DCIM/bad.jpg" || got[4] != "permission denied" {
+		t.Errorf("failed row = %v", got)
+	}
+}
+
+func TestRunReportSamplesBounded(t *testing.T) {
+	r := newRunReport(ReportHTML)
+	for i := 0; i < 3*reportMaxSamples; i++ {
+		r.addSample(r.started.Add(time.Duration(i+1)*time.Second), float64(i))
+	}
+	if n := len(r.throughput); n > reportMaxSamples || n < reportMaxSamples/2 {
+		t.Errorf("kept %d samples, want at most %d", n, reportMaxSamples)
+	}
+	if last := r.throughput[len(r.throughput)-1].At; last < time.Duration(3*reportMaxSamples-1)*time.Second {
+		t.Errorf("latest sample is at %v, the end of the run was lost", last)
+	}
+}
+
+func TestEngineWritesReport(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "a.jpg"), []byte("aaaa"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	dest := filepath.Join(dir, "backup")
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   dest,
+		NumWorkers: 1,
+		Reporter:   discardReporter{},
+		Report:     ReportCSV,
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if e.ReportPath() == "" || filepath.Dir(e.ReportPath()) != dest {
+		t.Fatalf("ReportPath = %q, want a file in %s", e.ReportPath(), dest)
+	}
+	data, err := os.ReadFile(e.ReportPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "copied,"+filepath.Join(source, "DCIM", "a.jpg")+",4,") {
+		t.Errorf("report does not list the copied file:\n%s", data)
+	}
+
+	e = NewEngine(EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, Reporter: discardReporter{}, Report: "pdf"}, sm)
+	if err := e.Run(context.Background()); err == nil {
+		t.Error("Run accepted an unknown report format")
+	}
+}
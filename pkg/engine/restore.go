@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// RestoreOptions selects the backed-up files RunRestore writes back to the source
+type RestoreOptions struct {
+	// Paths are catalog paths (see state.CatalogQuery) of files or folders, e.g.
+	// "DCIM/Camera/IMG_1234.jpg" or "DCIM/Camera"
+	Paths []string
+	// Overwrite replaces source files whose content differs from the backup; by default they
+	// are left alone and counted as conflicts
+	Overwrite bool
+	// DryRun lists the files that would be restored in RestoreResults.Planned without writing
+	DryRun bool
+}
+
+// RestoreResults summarizes a restore
+type RestoreResults struct {
+	Total     int              `json:"total"`     // backed-up files at the requested paths
+	Restored  int              `json:"restored"`  // written to the source and verified there
+	Present   int              `json:"present"`   // already on the source with the backed-up content
+	Conflicts int              `json:"conflicts"` // on the source with other content (see RestoreOptions.Overwrite)
+	Failed    int              `json:"failed"`
+	Bytes     int64            `json:"bytes"` // bytes written to the source
+	Failures  []RestoreFailure `json:"failures,omitempty"`
+	Planned   []string         `json:"planned,omitempty"` // dry run: source paths that would be restored
+}
+
+// RestoreFailure is a file RunRestore could not restore
+type RestoreFailure struct {
+	SourcePath string `json:"sourcePath"`
+	Error      string `json:"error"`
+}
+
+// restoreTarget is a source RunRestore can write to: the mounted device or the device over adb
+type restoreTarget interface {
+	cleanupSource
+	// Push copies the local file to path on the source, creating its folders
+	Push(ctx context.Context, local, path string) error
+}
+
+// restoreTarget returns how RunRestore writes to the source for the configured mode
+func (e *Engine) restoreTarget() (restoreTarget, error) {
+	if target, ok := e.source.(restoreTarget); ok {
+		return target, nil
+	}
+	switch e.config.Mode {
+	case TransportMount:
+		return localSource{sourceRoot: e.config.SourcePath, destRoot: e.config.DestRoot, names: e.names}, nil
+	case TransportADB:
+		return adbSource{sourceRoot: e.config.SourcePath, destRoot: e.config.DestRoot, names: e.names, serial: e.config.Serial}, nil
+	}
+	return nil, fmt.Errorf("restore is not supported in %s mode (only mount and adb)", e.config.Mode)
+}
+
+// RunRestore writes the backed-up copies of the files at RestoreOptions.Paths back to where
+// they were on the source, checking each copy against its recorded hash before and after.
+// Files still on the source are left alone unless their content differs and Overwrite is set.
+func (e *Engine) RunRestore(ctx context.Context) (RestoreResults, error) {
+	opts := e.config.Restore
+	var results RestoreResults
+	if len(opts.Paths) == 0 {
+		return results, fmt.Errorf("no paths to restore")
+	}
+	roots, err := e.verifyRoots()
+	if err != nil {
+		return results, err
+	}
+
+	seen := make(map[string]bool)
+	var files []state.CatalogEntry
+	for _, p := range opts.Paths {
+		found := e.stateManager.CatalogFiles(p)
+		if len(found) == 0 {
+			return results, fmt.Errorf("%s is not in the backup", p)
+		}
+		for _, f := range found {
+			if !seen[f.SourcePath] {
+				seen[f.SourcePath] = true
+				files = append(files, f)
+			}
+		}
+	}
+	results.Total = len(files)
+
+	target, err := e.restoreTarget()
+	if err != nil {
+		return results, err
+	}
+	if closer, ok := target.(io.Closer); ok {
+		defer closer.Close()
+	}
+	defer e.openAudit()()
+
+	e.log("info", fmt.Sprintf("Restoring %d files", len(files)))
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		err := e.restoreFile(ctx, target, roots, f, &results)
+		if err != nil && IsCritical(err) {
+			return results, err
+		}
+		if err != nil {
+			results.Failed++
+			results.Failures = append(results.Failures, RestoreFailure{SourcePath: f.SourcePath, Error: err.Error()})
+			e.log("warn", fmt.Sprintf("Could not restore %s: %v", f.Path, err))
+		}
+		if e.config.Reporter != nil {
+			e.config.Reporter.ReportProgress(ProgressUpdate{
+				TotalFiles:   len(files),
+				Completed:    i + 1 - results.Failed,
+				Failed:       results.Failed,
+				TotalBytes:   results.Bytes,
+				ScanComplete: true,
+			})
+		}
+	}
+	return results, nil
+}
+
+// restoreFile restores one file, counting it in results unless it fails
+func (e *Engine) restoreFile(ctx context.Context, target restoreTarget, roots []state.SourceRoot, f state.CatalogEntry, results *RestoreResults) error {
+	backup, ok := restoreBackupPath(e.config.DestRoot, e.names, roots, f.SourcePath)
+	if !ok {
+		return fmt.Errorf("not under any backed-up source folder")
+	}
+	// Never push a copy that has gone bad
+	if hash, err := calculateFileHash(backup); err != nil {
+		return fmt.Errorf("backup copy unreadable: %w", err)
+	} else if hash != f.Hash {
+		return fmt.Errorf("backup copy %s does not match its recorded hash (run verify)", backup)
+	}
+
+	_, isDir, err := target.Stat(ctx, f.SourcePath)
+	switch {
+	case err == nil && isDir:
+		results.Conflicts++
+		return nil
+	case err == nil:
+		hash, err := target.Hash(ctx, f.SourcePath)
+		if err != nil {
+			return err
+		}
+		if hash == f.Hash {
+			results.Present++
+			return nil
+		}
+		if !e.config.Restore.Overwrite {
+			results.Conflicts++
+			return nil
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	if e.config.Restore.DryRun {
+		results.Planned = append(results.Planned, f.SourcePath)
+		return nil
+	}
+	started := time.Now()
+	event := AuditEvent{Op: AuditRestore, Path: f.SourcePath, Dest: f.Path, Hash: f.Hash}
+	err = target.Push(ctx, backup, f.SourcePath)
+	if err == nil {
+		var hash string
+		if hash, err = target.Hash(ctx, f.SourcePath); err == nil && hash != f.Hash {
+			err = fmt.Errorf("restored file does not match the backup (hash %s)", hash)
+		}
+	}
+	event.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		event.Result, event.Error = AuditFailed, err.Error()
+		e.audit(event)
+		return err
+	}
+	info, _ := os.Stat(backup)
+	if info != nil {
+		event.Bytes = info.Size()
+		results.Bytes += info.Size()
+	}
+	event.Result = AuditOK
+	e.audit(event)
+	results.Restored++
+	return nil
+}
+
+// restoreBackupPath returns the backed-up copy of a source file: under the destination folder
+// of the most specific source root holding it
+func restoreBackupPath(destRoot string, names destNamer, roots []state.SourceRoot, sourcePath string) (string, bool) {
+	var best state.SourceRoot
+	for _, root := range roots {
+		if isUnder(sourcePath, root.Path) && (best.Path == "" || len(filepath.Clean(root.Path)) > len(filepath.Clean(best.Path))) {
+			best = root
+		}
+	}
+	if best.Path == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(best.Path, sourcePath)
+	if err != nil {
+		return "", false
+	}
+	return names.path(filepath.Join(destRoot, best.Dest), sourcePath, rel), true
+}
+
+// Push implements restoreTarget by writing through the mount. The file is written next to
+// path and renamed over it once complete, so a failed push never touches a file already there.
+func (s localSource) Push(ctx context.Context, local, path string) error {
+	in, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".gussync-*")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Push implements restoreTarget with adb push, which creates the folders
+func (s adbSource) Push(ctx context.Context, local, path string) error {
+	out, err := adbCommand(ctx, s.serial, "push", local, path).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if isADBDisconnect(msg) {
+			return fmt.Errorf("%w: %s", ErrConnectionLost, msg)
+		}
+		return fmt.Errorf("adb push failed: %v: %s", err, msg)
+	}
+	return nil
+}
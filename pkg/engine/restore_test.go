@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestRunRestore(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	for _, rel := range []string{"DCIM/Camera/a.jpg", "DCIM/Camera/b.jpg", "DCIM/Camera/c.jpg", "DCIM/Camera/d.jpg", "Download/e.pdf"} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+rel), 0644)
+	}
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// a and b are gone from the phone, c was edited there, d is untouched; b's backup has rotted
+	camera := filepath.Join(source, "DCIM", "Camera")
+	os.Remove(filepath.Join(camera, "a.jpg"))
+	os.Remove(filepath.Join(camera, "b.jpg"))
+	os.WriteFile(filepath.Join(camera, "c.jpg"), []byte("edited"), 0644)
+	os.WriteFile(filepath.Join(dest, "DCIM", "Camera", "b.jpg"), []byte("bitrot"), 0644)
+
+	config.Restore = RestoreOptions{Paths: []string{"DCIM/Camera"}, DryRun: true}
+	results, err := NewEngine(config, sm).RunRestore(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Planned) != 1 || results.Planned[0] != filepath.Join(camera, "a.jpg") {
+		t.Errorf("dry run planned %v", results.Planned)
+	}
+	if _, err := os.Stat(filepath.Join(camera, "a.jpg")); err == nil {
+		t.Fatalf("a dry run should not write")
+	}
+
+	config.Restore = RestoreOptions{Paths: []string{"DCIM/Camera", "DCIM/Camera/a.jpg"}}
+	results, err = NewEngine(config, sm).RunRestore(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Total != 4 || results.Restored != 1 || results.Present != 1 || results.Conflicts != 1 || results.Failed != 1 ||
+		len(results.Failures) != 1 || results.Failures[0].SourcePath != filepath.Join(camera, "b.jpg") {
+		t.Errorf("results = %+v; want a restored, d present, c in conflict, b failed", results)
+	}
+	if data, _ := os.ReadFile(filepath.Join(camera, "a.jpg")); string(data) != "content of DCIM/Camera/a.jpg" {
+		t.Errorf("a.jpg restored as %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(camera, "b.jpg")); err == nil {
+		t.Errorf("a corrupted backup copy should not be restored")
+	}
+
+	config.Restore = RestoreOptions{Paths: []string{"DCIM/Camera/c.jpg"}, Overwrite: true}
+	if results, err = NewEngine(config, sm).RunRestore(context.Background()); err != nil || results.Restored != 1 {
+		t.Errorf("overwrite: %+v, %v", results, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(camera, "c.jpg")); string(data) != "content of DCIM/Camera/c.jpg" {
+		t.Errorf("c.jpg = %q after overwrite", data)
+	}
+
+	config.Restore = RestoreOptions{Paths: []string{"DCIM/Nope"}}
+	if _, err := NewEngine(config, sm).RunRestore(context.Background()); err == nil {
+		t.Errorf("a path that is not in the backup should be an error")
+	}
+}
+
+func TestLocalSourcePushKeepsFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "phone", "c.jpg")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	os.WriteFile(target, []byte("original"), 0644)
+
+	// Reading a directory fails part way through the copy
+	if err := (localSource{}).Push(context.Background(), dir, target); err == nil {
+		t.Fatal("expected the push to fail")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "original" {
+		t.Errorf("c.jpg = %q after a failed push, want it untouched", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+
+	local := filepath.Join(dir, "backup.jpg")
+	os.WriteFile(local, []byte("restored"), 0644)
+	if err := (localSource{}).Push(context.Background(), local, target); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "restored" {
+		t.Errorf("c.jpg = %q after push", data)
+	}
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls in-run retries of a single file before a failure is recorded in state
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts per file (1 disables retries)
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the exponential delay
+	Jitter         float64       // Random +/- fraction applied to each delay (0.2 = ±20%)
+}
+
+// DefaultRetryPolicy returns the policy used when EngineConfig.Retry is left empty
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 2 * time.Second,
+		MaxBackoff:     30 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay before retry number attempt (1-based).
+// rnd must return values in [0,1); nil uses math/rand.
+func (p RetryPolicy) Backoff(attempt int, rnd func() float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		if rnd == nil {
+			rnd = rand.Float64
+		}
+		// Scale by a factor in [1-Jitter, 1+Jitter)
+		factor := 1 + p.Jitter*(2*rnd()-1)
+		delay = time.Duration(float64(delay) * factor)
+	}
+	return delay
+}
+
+// isTransient reports whether a copy error is worth retrying within the same run.
+// Connection loss is not transient - it aborts the run instead.
+func isTransient(err error) bool {
+	if err == nil || IsCritical(err) {
+		return false
+	}
+	return errors.Is(err, ErrStalled) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EAGAIN)
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := p.Backoff(i+1, nil); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	if got := p.Backoff(1, func() float64 { return 0 }); got != 500*time.Millisecond {
+		t.Errorf("min jitter: expected 500ms, got %v", got)
+	}
+	if got := p.Backoff(1, func() float64 { return 0.5 }); got != time.Second {
+		t.Errorf("mid jitter: expected 1s, got %v", got)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("%w: no progress for 30s", ErrStalled), true},
+		{&os.PathError{Op: "read", Path: "/x", Err: syscall.EIO}, true},
+		{fmt.Errorf("%w during copy: %v", ErrConnectionLost, syscall.EIO), false},
+		{fmt.Errorf("%w: source=a, dest=b", ErrHashMismatch), false},
+		{errors.New("permission denied"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// flakyCopier reports 600 bytes and fails with EIO on its first attempt, then copies the file
+type flakyCopier struct {
+	*FSCopier
+	calls atomic.Int32
+}
+
+func (c *flakyCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	progressChan <- 600
+	if c.calls.Add(1) == 1 {
+		return 0, &os.PathError{Op: "read", Path: sourcePath, Err: syscall.EIO}
+	}
+	progressChan <- 1000
+	return c.FSCopier.Copy(ctx, sourcePath, sourceRoot, destRoot, nil)
+}
+
+func TestRetryCountsBytesOnce(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "a.jpg"), bytes.Repeat([]byte("x"), 1000), 0644)
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	copier := &flakyCopier{FSCopier: NewFSCopier()}
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   filepath.Join(dir, "backup"),
+		NumWorkers: 1,
+		Reporter:   discardReporter{},
+		Copier:     copier,
+		Retry:      RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if copier.calls.Load() != 2 {
+		t.Fatalf("copied in %d attempts, want 2", copier.calls.Load())
+	}
+	// The failed attempt's 600 bytes are not added to the retry's 1000
+	if e.stats.transferred != 1000 {
+		t.Errorf("transferred = %d, want 1000", e.stats.transferred)
+	}
+	if got := e.workerStatus.bytes[0]; got != 1000 {
+		t.Errorf("worker 0 bytes = %d, want 1000", got)
+	}
+}
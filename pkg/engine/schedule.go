@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BandwidthWindow is a daily time window with its own transfer rate
+type BandwidthWindow struct {
+	Start time.Duration // Offset from midnight (inclusive)
+	End   time.Duration // Offset from midnight (exclusive); End < Start wraps past midnight
+	Rate  int64         // Bytes per second, RateUnlimited or RatePaused
+}
+
+// BandwidthSchedule maps the time of day to a rate limit.
+// The first matching window wins; Default applies outside all windows.
+type BandwidthSchedule struct {
+	Windows []BandwidthWindow
+	Default int64
+}
+
+// ParseBandwidthSchedule parses a schedule such as
+//
+//	"01:00-06:00=unlimited,*=5MB"
+//	"22:00-07:00=10MB,*=pause"
+//	"2MB"  (same limit all day)
+//
+// Rates accept B/KB/MB/GB suffixes (per second), "unlimited" or "pause".
+func ParseBandwidthSchedule(spec string) (*BandwidthSchedule, error) {
+	s := &BandwidthSchedule{Default: RateUnlimited}
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return s, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		window, rateStr, hasWindow := strings.Cut(part, "=")
+		if !hasWindow {
+			// Bare rate means the default
+			window, rateStr = "*", part
+		}
+
+		rate, err := parseRate(rateStr)
+		if err != nil {
+			return nil, err
+		}
+
+		window = strings.TrimSpace(window)
+		if window == "*" {
+			s.Default = rate
+			continue
+		}
+
+		startStr, endStr, ok := strings.Cut(window, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q (expected HH:MM-HH:MM)", window)
+		}
+		start, err := parseClock(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(endStr)
+		if err != nil {
+			return nil, err
+		}
+		s.Windows = append(s.Windows, BandwidthWindow{Start: start, End: end, Rate: rate})
+	}
+
+	return s, nil
+}
+
+// RateAt returns the rate that applies at time t (local time of day)
+func (s *BandwidthSchedule) RateAt(t time.Time) int64 {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	for _, w := range s.Windows {
+		if w.Start <= w.End {
+			if offset >= w.Start && offset < w.End {
+				return w.Rate
+			}
+		} else if offset >= w.Start || offset < w.End {
+			// Window wraps past midnight
+			return w.Rate
+		}
+	}
+	return s.Default
+}
+
+// FormatRate renders a rate for logs
+func FormatRate(rate int64) string {
+	switch {
+	case rate == RateUnlimited:
+		return "unlimited"
+	case rate < 0:
+		return "paused"
+	default:
+		return formatSize(rate) + "/s"
+	}
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseRate(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	switch s {
+	case "UNLIMITED", "FULL", "0":
+		return RateUnlimited, nil
+	case "PAUSE", "OFF":
+		return RatePaused, nil
+	}
+
+	s = strings.TrimSuffix(s, "/S")
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.mult
+			s = strings.TrimSuffix(s, unit.suffix)
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
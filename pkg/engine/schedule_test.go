@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidthSchedule(t *testing.T) {
+	s, err := ParseBandwidthSchedule("01:00-06:00=unlimited, 22:00-01:00=pause, *=5MB")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 1, hour, min, 0, 0, time.Local)
+	}
+	tests := []struct {
+		t    time.Time
+		want int64
+	}{
+		{at(3, 0), RateUnlimited},
+		{at(6, 0), 5 << 20},
+		{at(12, 30), 5 << 20},
+		{at(23, 15), RatePaused},
+		{at(0, 59), RatePaused},
+		{at(1, 0), RateUnlimited},
+	}
+	for _, tt := range tests {
+		if got := s.RateAt(tt.t); got != tt.want {
+			t.Errorf("RateAt(%s) = %d, want %d", tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+
+	if s, err := ParseBandwidthSchedule("512KB"); err != nil || s.Default != 512<<10 {
+		t.Errorf("bare rate: got %+v, %v", s, err)
+	}
+
+	for _, bad := range []string{"25:00-06:00=1MB", "01:00=1MB", "*=fast"} {
+		if _, err := ParseBandwidthSchedule(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(100 * 1024)
+	ctx := context.Background()
+
+	// Drain the initial budget, then the next 50KB takes ~0.5s
+	start := time.Now()
+	if err := l.WaitN(ctx, 50*1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected throttling, waited only %v", elapsed)
+	}
+
+	// Switching to unlimited releases immediately
+	l.SetRate(RateUnlimited)
+	start = time.Now()
+	l.WaitN(ctx, 10<<20)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited should not wait, waited %v", elapsed)
+	}
+
+	// Paused blocks WaitAllowed until the rate changes
+	l.SetRate(RatePaused)
+	released := make(chan struct{})
+	go func() {
+		l.WaitAllowed(ctx)
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("WaitAllowed should block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.SetRate(1 << 20)
+	<-released
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"context"
+	"os"
+)
+
+// DefaultLargeFileSize is the size from which a file goes to the large-file queue
+const DefaultLargeFileSize int64 = 64 << 20
+
+// jobQueues splits the jobs of a run into a queue of small files and one of large files,
+// each with workers of its own, so a few long videos can't hold up thousands of photos
+type jobQueues struct {
+	small, large chan FileJob
+	threshold    int64 // files of at least this size are large
+	largeWorkers int   // workers dedicated to the large queue (0 = none, with one worker)
+}
+
+// newJobQueues sizes the queues for numWorkers workers. largeWorkers workers are dedicated
+// to large files (0 = a quarter of them, at least one); a single worker serves both queues.
+func newJobQueues(threshold int64, numWorkers, largeWorkers int) *jobQueues {
+	if threshold <= 0 {
+		threshold = DefaultLargeFileSize
+	}
+	switch {
+	case numWorkers < 2:
+		largeWorkers = 0
+	case largeWorkers <= 0:
+		largeWorkers = max(numWorkers/4, 1)
+	case largeWorkers >= numWorkers:
+		largeWorkers = numWorkers - 1 // at least one worker stays on small files
+	}
+	return &jobQueues{
+		small:        make(chan FileJob, 1000),
+		large:        make(chan FileJob, 100),
+		threshold:    threshold,
+		largeWorkers: largeWorkers,
+	}
+}
+
+// dispatch sorts the jobs of in into the two queues until in is closed, then closes them.
+// sizeOf returns the size of a job whose scanner didn't know it (0 = unknown, small).
+func (q *jobQueues) dispatch(ctx context.Context, in <-chan FileJob, sizeOf func(FileJob) int64) {
+	defer close(q.small)
+	defer close(q.large)
+	for job := range in {
+		queue := q.small
+		if len(job.Batch) == 0 {
+			if job.Size == 0 && sizeOf != nil {
+				job.Size = sizeOf(job)
+			}
+			if job.Size >= q.threshold {
+				queue = q.large
+			}
+		}
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			// Let the scanner finish instead of blocking on a full channel
+			for range in {
+			}
+			return
+		}
+	}
+}
+
+// isLarge reports whether worker id serves the large queue first
+func (q *jobQueues) isLarge(id int) bool {
+	return id < q.largeWorkers
+}
+
+// next returns the next job for worker id, or false once both queues are done or ctx is.
+// Each worker takes from its own queue first. A large-file worker takes small files while
+// no large one waits; a small-file worker takes large files only when there are no
+// large-file workers or no small files left.
+func (q *jobQueues) next(ctx context.Context, id int) (FileJob, bool) {
+	large := q.isLarge(id)
+	own, other := q.small, q.large
+	if large {
+		own, other = q.large, q.small
+	}
+	shareOther := large || q.largeWorkers == 0
+	for own != nil || other != nil {
+		select {
+		case job, ok := <-own:
+			if ok {
+				return job, true
+			}
+			own = nil
+			continue
+		default:
+		}
+		var alt chan FileJob
+		if shareOther || own == nil {
+			alt = other
+		}
+		select {
+		case job, ok := <-own:
+			if !ok {
+				own = nil
+				continue
+			}
+			return job, true
+		case job, ok := <-alt:
+			if !ok {
+				other = nil
+				continue
+			}
+			return job, true
+		case <-ctx.Done():
+			return FileJob{}, false
+		}
+	}
+	return FileJob{}, false
+}
+
+// depths returns how many jobs wait in each queue
+func (q *jobQueues) depths() (small, large int) {
+	if q == nil {
+		return 0, 0
+	}
+	return len(q.small), len(q.large)
+}
+
+// localSize stats a job of a local source, for jobQueues.dispatch
+func localSize(job FileJob) int64 {
+	if info, err := os.Stat(job.SourcePath); err == nil {
+		return info.Size()
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJobQueuesDispatch(t *testing.T) {
+	q := newJobQueues(100, 4, 0)
+	if q.largeWorkers != 1 {
+		t.Errorf("largeWorkers = %d, want 1 for 4 workers", q.largeWorkers)
+	}
+	in := make(chan FileJob, 10)
+	in <- FileJob{SourcePath: "small.jpg", Size: 10}
+	in <- FileJob{SourcePath: "movie.mp4", Size: 500}
+	in <- FileJob{SourcePath: "unsized.jpg"}
+	in <- FileJob{SourcePath: "sized-later.mp4"}
+	in <- FileJob{SourcePath: "DCIM", Size: 1000, Batch: []FileJob{{SourcePath: "DCIM/a"}, {SourcePath: "DCIM/b"}}}
+	close(in)
+	sizes := map[string]int64{"sized-later.mp4": 200}
+	q.dispatch(context.Background(), in, func(job FileJob) int64 { return sizes[job.SourcePath] })
+
+	if small, large := q.depths(); small != 3 || large != 2 {
+		t.Fatalf("depths = %d small, %d large; want 3, 2", small, large)
+	}
+	for job := range q.large {
+		if job.Size < 100 {
+			t.Errorf("%s (%d bytes) in the large queue", job.SourcePath, job.Size)
+		}
+	}
+}
+
+func TestJobQueuesNext(t *testing.T) {
+	ctx := context.Background()
+	q := newJobQueues(100, 2, 1) // worker 0 takes large files first, worker 1 only small ones
+	q.small <- FileJob{SourcePath: "a.jpg"}
+	q.large <- FileJob{SourcePath: "movie.mp4", Size: 500}
+
+	if job, _ := q.next(ctx, 1); job.SourcePath != "a.jpg" {
+		t.Errorf("small-file worker got %s", job.SourcePath)
+	}
+	if job, _ := q.next(ctx, 0); job.SourcePath != "movie.mp4" {
+		t.Errorf("large-file worker got %s", job.SourcePath)
+	}
+
+	// With no large file waiting, the large-file worker helps with small ones
+	q.small <- FileJob{SourcePath: "b.jpg"}
+	if job, _ := q.next(ctx, 0); job.SourcePath != "b.jpg" {
+		t.Errorf("large-file worker got %s, want b.jpg", job.SourcePath)
+	}
+
+	// Once the small files are done, the small-file worker helps with large ones
+	q.large <- FileJob{SourcePath: "movie2.mp4", Size: 500}
+	close(q.small)
+	if job, ok := q.next(ctx, 1); !ok || job.SourcePath != "movie2.mp4" {
+		t.Errorf("small-file worker got %s, %v; want movie2.mp4", job.SourcePath, ok)
+	}
+	close(q.large)
+	if _, ok := q.next(ctx, 0); ok {
+		t.Error("expected no job once both queues are closed")
+	}
+}
+
+func TestJobQueuesSingleWorker(t *testing.T) {
+	q := newJobQueues(0, 1, 3)
+	if q.largeWorkers != 0 || q.threshold != DefaultLargeFileSize {
+		t.Fatalf("got %d large workers, threshold %d", q.largeWorkers, q.threshold)
+	}
+	q.large <- FileJob{SourcePath: "movie.mp4"}
+	if job, ok := q.next(context.Background(), 0); !ok || job.SourcePath != "movie.mp4" {
+		t.Errorf("a single worker must serve the large queue too, got %s", job.SourcePath)
+	}
+}
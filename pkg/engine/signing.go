@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SignatureSuffix is appended to a file's name for its detached signature
+const SignatureSuffix = ".asc"
+
+// SnapshotSuffix is appended to the state file's name for the snapshot that gets signed:
+// the state file only ever grows, so the snapshot records its size and the hash of that many
+// bytes, and later runs check that what was signed is still there
+const SnapshotSuffix = ".snapshot"
+
+// Signer makes and checks detached signatures (EngineConfig.Signer)
+type Signer interface {
+	// Sign writes the signature of path to path+SignatureSuffix
+	Sign(ctx context.Context, path string) error
+	// Verify checks path against path+SignatureSuffix and returns who signed it
+	Verify(ctx context.Context, path string) (string, error)
+}
+
+// GPGSigner implements Signer with gpg
+type GPGSigner struct {
+	key string
+	gpg string
+}
+
+// NewGPGSigner signs with the given gpg key (id, fingerprint or email; "" = gpg's default key)
+func NewGPGSigner(key string) (*GPGSigner, error) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		return nil, fmt.Errorf("gpg not found in PATH (required for signing)")
+	}
+	return &GPGSigner{key: key, gpg: gpg}, nil
+}
+
+// Sign implements Signer with an ASCII-armored detached signature
+func (s *GPGSigner) Sign(ctx context.Context, path string) error {
+	args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--output", path + SignatureSuffix}
+	if s.key != "" {
+		args = append(args, "--local-user", s.key)
+	}
+	cmd := exec.CommandContext(ctx, s.gpg, append(args, path)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gpg failed to sign %s: %w: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Verify implements Signer; the signer is the key's user id as gpg reports it
+func (s *GPGSigner) Verify(ctx context.Context, path string) (string, error) {
+	cmd := exec.CommandContext(ctx, s.gpg, "--batch", "--status-fd", "1", "--verify", path+SignatureSuffix, path)
+	output, err := cmd.Output()
+	for _, line := range strings.Split(string(output), "\n") {
+		if rest, ok := strings.CutPrefix(line, "[GNUPG:] GOODSIG "); ok && err == nil {
+			if _, uid, ok := strings.Cut(rest, " "); ok {
+				return uid, nil
+			}
+			return rest, nil
+		}
+	}
+	if err == nil {
+		err = errors.New("no good signature")
+	}
+	return "", fmt.Errorf("gpg could not verify %s: %w", path, err)
+}
+
+// stateSnapshot is what the state file looked like when it was signed
+type stateSnapshot struct {
+	size int64
+	hash string // SHA-256 of the first size bytes
+}
+
+// takeStateSnapshot records the current size and hash of the state file
+func takeStateSnapshot(stateFile string) (stateSnapshot, error) {
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return stateSnapshot{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return stateSnapshot{}, err
+	}
+	return stateSnapshot{size: n, hash: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// write saves the snapshot in the format read by readStateSnapshot
+func (s stateSnapshot) write(path string) error {
+	content := fmt.Sprintf("# GusSync state snapshot\nsize %d\nsha256 %s\n", s.size, s.hash)
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func readStateSnapshot(path string) (stateSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stateSnapshot{}, err
+	}
+	defer f.Close()
+	var s stateSnapshot
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "size":
+			s.size, err = strconv.ParseInt(value, 10, 64)
+		case "sha256":
+			s.hash = value
+		}
+		if err != nil {
+			return s, fmt.Errorf("invalid state snapshot %s: %w", path, err)
+		}
+	}
+	if s.hash == "" {
+		return s, fmt.Errorf("invalid state snapshot %s", path)
+	}
+	return s, scanner.Err()
+}
+
+// checkStateSnapshot makes sure the first snapshot.size bytes of the state file are still
+// the ones that were signed; entries appended since are fine
+func checkStateSnapshot(stateFile string, snapshot stateSnapshot) error {
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(f, snapshot.size))
+	if err != nil {
+		return err
+	}
+	if n < snapshot.size {
+		return fmt.Errorf("the state file is shorter (%d bytes) than when it was signed (%d bytes): history was removed", n, snapshot.size)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != snapshot.hash {
+		return fmt.Errorf("the state file's signed history was rewritten (after gussync state compact, the next signed run signs it again)")
+	}
+	return nil
+}
+
+// signedFiles are the files Signer signs when a run ends and checks when the next starts:
+// the state snapshot, the scan manifest and the newest attestation (only those that exist)
+func (e *Engine) signedFiles() []string {
+	stateFile := e.stateManager.Path()
+	files := []string{stateFile + SnapshotSuffix}
+	if _, err := os.Stat(e.stateManager.ManifestPath()); err == nil {
+		files = append(files, e.stateManager.ManifestPath())
+	}
+	if path, err := LatestAttestation(e.config.DestRoot); err == nil {
+		files = append(files, path)
+	}
+	return files
+}
+
+// checkSignatures verifies the signatures left by the last signed run and warns about any
+// that don't match, so a rewritten history doesn't go unnoticed. It returns the number of
+// problems found.
+func (e *Engine) checkSignatures(ctx context.Context) int {
+	if e.config.Signer == nil {
+		return 0
+	}
+	stateFile := e.stateManager.Path()
+	snapshotPath := stateFile + SnapshotSuffix
+	if _, err := os.Stat(snapshotPath); errors.Is(err, os.ErrNotExist) {
+		e.log("warn", fmt.Sprintf("No signed snapshot of the state file (%s): expected on the first signed run, otherwise it was removed", snapshotPath))
+		return 0
+	}
+
+	problems := 0
+	warn := func(format string, args ...interface{}) {
+		problems++
+		e.log("warn", "SIGNATURE CHECK FAILED: "+fmt.Sprintf(format, args...))
+	}
+	for _, path := range e.signedFiles() {
+		if _, err := os.Stat(path + SignatureSuffix); errors.Is(err, os.ErrNotExist) {
+			if path == snapshotPath {
+				warn("%s has no signature; it may have been replaced", snapshotPath)
+			}
+			continue // a manifest or attestation from before signing was turned on
+		}
+		signer, err := e.config.Signer.Verify(ctx, path)
+		if err != nil {
+			warn("%v", err)
+			continue
+		}
+		if path != snapshotPath {
+			e.log("info", fmt.Sprintf("Signature of %s OK (%s)", path, signer))
+			continue
+		}
+		snapshot, err := readStateSnapshot(snapshotPath)
+		if err == nil {
+			err = checkStateSnapshot(stateFile, snapshot)
+		}
+		if err != nil {
+			warn("%v", err)
+			continue
+		}
+		e.log("info", fmt.Sprintf("State file signature OK (%s)", signer))
+	}
+	return problems
+}
+
+// signRun signs the state as this run leaves it, and the manifests, with Signer
+func (e *Engine) signRun(ctx context.Context) {
+	if e.config.Signer == nil {
+		return
+	}
+	if err := e.stateManager.Flush(); err != nil {
+		e.log("warn", fmt.Sprintf("State not signed: %v", err))
+		return
+	}
+	stateFile := e.stateManager.Path()
+	snapshot, err := takeStateSnapshot(stateFile)
+	if err == nil {
+		err = snapshot.write(stateFile + SnapshotSuffix)
+	}
+	if err != nil {
+		e.log("warn", fmt.Sprintf("State not signed: %v", err))
+		return
+	}
+	files := e.signedFiles()
+	signed := 0
+	for _, path := range files {
+		if err := e.config.Signer.Sign(ctx, path); err != nil {
+			e.log("warn", err.Error())
+			continue
+		}
+		signed++
+	}
+	e.log("info", fmt.Sprintf("Signed %d of %d files (state snapshot and manifests)", signed, len(files)))
+}
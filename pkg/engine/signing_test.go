@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+// hashSigner "signs" a file with its SHA-256, which is enough to tell a changed file
+type hashSigner struct{}
+
+func (hashSigner) Sign(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	return os.WriteFile(path+SignatureSuffix, []byte(hex.EncodeToString(sum[:])), 0644)
+}
+
+func (hashSigner) Verify(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig, err := os.ReadFile(path + SignatureSuffix)
+	if err != nil {
+		return "", err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != string(sig) {
+		return "", errors.New("bad signature")
+	}
+	return "test", nil
+}
+
+// warnReporter keeps the warnings logged
+type warnReporter struct {
+	discardReporter
+	mu    sync.Mutex
+	warns []string
+}
+
+func (r *warnReporter) ReportLog(level, message string) {
+	if level == "warn" {
+		r.mu.Lock()
+		r.warns = append(r.warns, message)
+		r.mu.Unlock()
+	}
+}
+
+func TestSignedRuns(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	os.MkdirAll(filepath.Join(source, "DCIM"), 0755)
+	os.WriteFile(filepath.Join(source, "DCIM", "a.jpg"), []byte("aaa"), 0644)
+	destRoot := filepath.Join(dir, "backup")
+	os.MkdirAll(destRoot, 0755)
+	stateFile := filepath.Join(destRoot, "gus_state.md")
+
+	run := func() (*Engine, *warnReporter) {
+		t.Helper()
+		sm, err := state.NewStateManager(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sm.Close()
+		reporter := &warnReporter{}
+		e := NewEngine(EngineConfig{
+			Mode:       TransportMount,
+			SourcePath: source,
+			DestRoot:   destRoot,
+			NumWorkers: 1,
+			Reporter:   reporter,
+			Retry:      RetryPolicy{MaxAttempts: 1},
+			AttestKey:  filepath.Join(dir, "attest_key"),
+			Signer:     hashSigner{},
+		}, sm)
+		if err := e.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return e, reporter
+	}
+
+	_, reporter := run()
+	if len(reporter.warns) != 1 || !strings.Contains(reporter.warns[0], "No signed snapshot") {
+		t.Errorf("first run warned %q", reporter.warns)
+	}
+	attestation, _ := LatestAttestation(destRoot)
+	for _, path := range []string{stateFile + SnapshotSuffix, attestation} {
+		if _, err := os.Stat(path + SignatureSuffix); err != nil {
+			t.Errorf("not signed: %v", err)
+		}
+	}
+
+	// Entries appended since the last signed run are fine
+	os.WriteFile(filepath.Join(source, "DCIM", "b.jpg"), []byte("bbb"), 0644)
+	e, reporter := run()
+	if len(reporter.warns) != 0 {
+		t.Errorf("second run warned %q", reporter.warns)
+	}
+
+	check := func(want string) {
+		t.Helper()
+		sm, _ := state.OpenReadOnly(stateFile)
+		defer sm.Close()
+		e.stateManager = sm
+		reporter.warns = nil
+		if e.checkSignatures(context.Background()) == 0 || !strings.Contains(strings.Join(reporter.warns, "\n"), want) {
+			t.Errorf("checkSignatures warned %q, want %q", reporter.warns, want)
+		}
+	}
+
+	data, _ := os.ReadFile(stateFile)
+	os.WriteFile(stateFile, []byte(strings.Replace(string(data), "a.jpg", "x.jpg", 1)), 0644)
+	check("rewritten")
+	os.WriteFile(stateFile, data[:len(data)/2], 0644)
+	check("shorter")
+	os.WriteFile(stateFile, data, 0644)
+	os.WriteFile(attestation, []byte("{}"), 0644)
+	check("bad signature")
+}
+
+func TestGPGSigner(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home, err := os.MkdirTemp("", "gpg") // gpg-agent's socket path must stay short
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	t.Setenv("GNUPGHOME", home)
+	gen := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "GusSync Test <test@example.com>", "ed25519", "sign", "never")
+	if output, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("could not generate a gpg key: %v: %s", err, output)
+	}
+	defer exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+
+	signer, err := NewGPGSigner("test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "gus_state.md.snapshot")
+	os.WriteFile(path, []byte("size 3\n"), 0644)
+	ctx := context.Background()
+	if err := signer.Sign(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	if who, err := signer.Verify(ctx, path); err != nil || !strings.Contains(who, "test@example.com") {
+		t.Errorf("Verify = %q, %v", who, err)
+	}
+	os.WriteFile(path, []byte("size 4\n"), 0644)
+	if _, err := signer.Verify(ctx, path); err == nil {
+		t.Error("expected a changed file to fail verification")
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+func TestParseSMBSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		opts    SMBOptions
+		path    string
+		wantErr bool
+	}{
+		{"smb://nas.local/photos", SMBOptions{Server: "nas.local", Share: "photos"}, "/", false},
+		{"smb://alice@nas.local:1445/photos/Phone/DCIM/", SMBOptions{Server: "nas.local:1445", Share: "photos", User: "alice"}, "/Phone/DCIM", false},
+		{"smb://CORP;bob:pw@fileserver/home", SMBOptions{Server: "fileserver", Share: "home", User: "bob", Domain: "CORP", Password: "pw"}, "/", false},
+		{"smb://nas.local/", SMBOptions{}, "", true},
+		{"ssh://nas.local/photos", SMBOptions{}, "", true},
+		{"/mnt/nas", SMBOptions{}, "", true},
+	}
+	for _, tt := range tests {
+		opts, path, err := ParseSMBSource(tt.source)
+		if (err != nil) != tt.wantErr || opts != tt.opts || path != tt.path {
+			t.Errorf("ParseSMBSource(%q) = %+v, %q, %v; want %+v, %q (error: %v)", tt.source, opts, path, err, tt.opts, tt.path, tt.wantErr)
+		}
+	}
+}
+
+func TestSMBPath(t *testing.T) {
+	for in, want := range map[string]string{"/": "", "": "", "/DCIM/a.jpg": "DCIM/a.jpg", "DCIM//x/../a.jpg": "DCIM/a.jpg"} {
+		if got := smbPath(in); got != want {
+			t.Errorf("smbPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSMBErrorClassification(t *testing.T) {
+	client := newSMBClient(SMBOptions{Server: "nas", Share: "photos"})
+	lost := []error{
+		&smb2.TransportError{Err: io.EOF},
+		&os.PathError{Op: "open", Path: "a.jpg", Err: io.ErrUnexpectedEOF},
+		&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")},
+	}
+	for _, err := range lost {
+		if got := client.check(nil, err); !IsCritical(got) {
+			t.Errorf("check(%v) = %v, want a lost connection", err, got)
+		}
+	}
+	for _, err := range []error{&os.PathError{Op: "open", Path: "a.jpg", Err: os.ErrNotExist}, os.ErrPermission} {
+		if got := client.check(nil, err); IsCritical(got) || got != err {
+			t.Errorf("check(%v) = %v, want the error unchanged", err, got)
+		}
+	}
+}
+
+func TestSMBUnreachableServerIsConnectionLoss(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close() // nothing listens there any more
+
+	scanner := NewSMBScanner(SMBOptions{Server: addr, Share: "photos"}, func() {})
+	if err := scanner.Probe(context.Background(), "/"); !IsCritical(err) {
+		t.Fatalf("Probe = %v, want a lost connection", err)
+	}
+}
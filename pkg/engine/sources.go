@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"GusSync/pkg/state"
+)
+
+// sourceDestDir returns the folder of DestRoot an extra source is backed up into, named after
+// the source folder (e.g. .../mtp:host=Pixel/SD card -> "SD card")
+func sourceDestDir(root string) string {
+	name := filepath.Base(filepath.Clean(root))
+	if name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:*?"<>|\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// isUnder reports whether path is root or inside it
+func isUnder(path, root string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// sourceRoots returns the folders Run backs up: SourcePath into DestRoot itself ("."),
+// then each of ExtraSources into its own folder of DestRoot
+func (e *Engine) sourceRoots() ([]state.SourceRoot, error) {
+	roots := []state.SourceRoot{{Path: e.config.SourcePath, Dest: "."}}
+	if len(e.config.ExtraSources) == 0 {
+		return roots, nil
+	}
+	if e.config.ManifestFirst || e.config.FromManifest {
+		return nil, fmt.Errorf("manifest-first backups support a single source")
+	}
+	dests := make(map[string]string)
+	for _, path := range e.config.ExtraSources {
+		for _, root := range roots {
+			if isUnder(path, root.Path) || isUnder(root.Path, path) {
+				return nil, fmt.Errorf("source %s overlaps %s", path, root.Path)
+			}
+		}
+		dest := sourceDestDir(path)
+		if dest == "" {
+			return nil, fmt.Errorf("source %s has no folder name to back it up under", path)
+		}
+		if other, ok := dests[strings.ToLower(dest)]; ok {
+			return nil, fmt.Errorf("sources %s and %s would both be backed up into %s", other, path, dest)
+		}
+		dests[strings.ToLower(dest)] = path
+		roots = append(roots, state.SourceRoot{Path: path, Dest: dest})
+	}
+	return roots, nil
+}
+
+// forEachSource runs fn once per source root with SourcePath and DestRoot pointing at that
+// root and its destination folder, recording the extra roots in the state. With a single
+// source fn just runs once.
+func (e *Engine) forEachSource(ctx context.Context, roots []state.SourceRoot, fn func(ctx context.Context) error) error {
+	if len(roots) == 1 {
+		return fn(ctx)
+	}
+	sourcePath, destRoot := e.config.SourcePath, e.config.DestRoot
+	e.roots = roots
+	defer func() {
+		e.config.SourcePath, e.config.DestRoot = sourcePath, destRoot
+		e.roots = nil
+	}()
+
+	var errs []error
+	for i, root := range roots {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if root.Dest != "." {
+			if err := e.stateManager.MarkSourceRoot(root.Path, root.Dest); err != nil {
+				e.log("warn", fmt.Sprintf("Could not record source %s in the state: %v", root.Path, err))
+			}
+		}
+		e.config.SourcePath, e.config.DestRoot = root.Path, filepath.Join(destRoot, root.Dest)
+		e.log("info", fmt.Sprintf("Source %d of %d: %s", i+1, len(roots), root.Path))
+		if err := fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", root.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyRoots returns the source roots VerifyBackup checks: the configured ones plus extra
+// roots recorded in the state by earlier multi-source backups, so their copies are verified
+// without passing every source again
+func (e *Engine) verifyRoots() ([]state.SourceRoot, error) {
+	roots, err := e.sourceRoots()
+	if err != nil {
+		return nil, err
+	}
+	for _, recorded := range e.stateManager.SourceRoots() {
+		known := false
+		for _, root := range roots {
+			if filepath.Clean(root.Path) == filepath.Clean(recorded.Path) || isUnder(root.Path, recorded.Path) || isUnder(recorded.Path, root.Path) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			roots = append(roots, recorded)
+		}
+	}
+	return roots, nil
+}
+
+// inOtherSource reports whether path belongs to another source root than SourcePath: the
+// most specific root holding it, among the run's roots and those recorded in the state, is a
+// different one. Its copy is elsewhere in the destination and it is handled with that root.
+func (e *Engine) inOtherSource(path string) bool {
+	candidates := make([]string, 0, len(e.roots))
+	for _, root := range e.roots {
+		candidates = append(candidates, root.Path)
+	}
+	for _, root := range e.stateManager.SourceRoots() {
+		candidates = append(candidates, root.Path)
+	}
+	best := ""
+	for _, root := range candidates {
+		root = filepath.Clean(root)
+		if isUnder(path, root) && len(root) > len(best) {
+			best = root
+		}
+	}
+	return best != "" && best != filepath.Clean(e.config.SourcePath)
+}
+
+func (r *VerifyResults) add(other VerifyResults) {
+	r.Total += other.Total
+	r.Sampled += other.Sampled
+	r.Verified += other.Verified
+	r.MissingSource += other.MissingSource
+	r.MissingDest += other.MissingDest
+	r.Mismatches += other.Mismatches
+	r.Quarantined += other.Quarantined
+	r.Issues = append(r.Issues, other.Issues...)
+}
+
+func (r *CleanupResults) add(other CleanupResults) {
+	r.Deleted += other.Deleted
+	r.AlreadyDeleted += other.AlreadyDeleted
+	r.Failed += other.Failed
+	r.Skipped += other.Skipped
+	r.IOErrors += other.IOErrors
+	r.TooRecent += other.TooRecent
+	r.Declined += other.Declined
+	r.FreedBytes += other.FreedBytes
+	r.Planned = append(r.Planned, other.Planned...)
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestMultiSourceBackup(t *testing.T) {
+	dir := t.TempDir()
+	internal := filepath.Join(dir, "mtp:host=Pixel", "Internal shared storage")
+	sdcard := filepath.Join(dir, "mtp:host=Pixel", "SD card")
+	dest := filepath.Join(dir, "backup")
+	files := map[string]string{
+		filepath.Join(internal, "DCIM", "a.jpg"): filepath.Join(dest, "DCIM", "a.jpg"),
+		filepath.Join(sdcard, "DCIM", "a.jpg"):   filepath.Join(dest, "SD card", "DCIM", "a.jpg"),
+		filepath.Join(sdcard, "Music", "b.mp3"):  filepath.Join(dest, "SD card", "Music", "b.mp3"),
+	}
+	for source := range files {
+		os.MkdirAll(filepath.Dir(source), 0755)
+		os.WriteFile(source, []byte("content of "+source), 0644)
+	}
+
+	stateFile := filepath.Join(dir, "gus_state.md")
+	sm, err := state.NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := EngineConfig{
+		Mode:         TransportMount,
+		SourcePath:   internal,
+		ExtraSources: []string{sdcard},
+		DestRoot:     dest,
+		NumWorkers:   2,
+		Reporter:     discardReporter{},
+	}
+	e := NewEngine(config, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for source, copied := range files {
+		if data, err := os.ReadFile(copied); err != nil || string(data) != "content of "+source {
+			t.Errorf("%s was not copied to %s: %v", source, copied, err)
+		}
+	}
+	if e.config.SourcePath != internal || e.config.DestRoot != dest {
+		t.Errorf("config not restored after the run: %s -> %s", e.config.SourcePath, e.config.DestRoot)
+	}
+	sm.Close()
+
+	// A later verify of the primary source alone still finds the SD card from the state
+	sm, err = state.NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	if roots := sm.SourceRoots(); len(roots) != 1 || roots[0] != (state.SourceRoot{Path: sdcard, Dest: "SD card"}) {
+		t.Errorf("recorded roots = %+v", roots)
+	}
+	config.ExtraSources = nil
+	results, err := NewEngine(config, sm).VerifyBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Total != 3 || results.Verified != 3 || len(results.Issues) != 0 {
+		t.Errorf("verify = %+v, want all 3 files verified", results)
+	}
+
+	// Cleanup only touches the sources it is given
+	config.Cleanup.DryRun = true
+	cleaned, err := NewEngine(config, sm).RunCleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cleaned.Planned) != 1 || cleaned.Planned[0].SourcePath != filepath.Join(internal, "DCIM", "a.jpg") {
+		t.Errorf("planned = %+v, want only the internal storage file", cleaned.Planned)
+	}
+}
+
+func TestSourceRootsRejectsClashes(t *testing.T) {
+	for _, extras := range [][]string{
+		{"/phone/Internal/DCIM"},
+		{"/a/SD card", "/b/sd card"},
+	} {
+		e := NewEngine(EngineConfig{SourcePath: "/phone/Internal", ExtraSources: extras}, nil)
+		if _, err := e.sourceRoots(); err == nil {
+			t.Errorf("extra sources %v should be rejected", extras)
+		}
+	}
+}
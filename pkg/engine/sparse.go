@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+)
+
+// sparseBlock is the size of the runs of zeros a sparse copy leaves as holes, the block
+// size of most filesystems
+const sparseBlock = 4096
+
+var zeroBlock = make([]byte, sparseBlock)
+
+// writeSparse writes b to the .part file, seeking over whole blocks of zeros instead of
+// writing them, so the holes of a sparse source stay holes in the copy. commit sets the
+// file's size, which a trailing hole doesn't.
+func (p *partFile) writeSparse(b []byte) (int, error) {
+	written := 0 // b[:written] is in the file (or skipped as zeros)
+	for i := 0; i < len(b); i += sparseBlock {
+		end := min(i+sparseBlock, len(b))
+		if end-i < sparseBlock || !bytes.Equal(b[i:end], zeroBlock) {
+			continue
+		}
+		if i > written {
+			n, err := p.f.Write(b[written:i])
+			p.hashWritten(b[written : written+n])
+			if written += n; err != nil {
+				return written, err
+			}
+		}
+		if _, err := p.f.Seek(sparseBlock, io.SeekCurrent); err != nil {
+			return written, err
+		}
+		p.hashWritten(b[i:end])
+		written = end
+	}
+	if written < len(b) {
+		n, err := p.f.Write(b[written:])
+		p.hashWritten(b[written : written+n])
+		return written + n, err
+	}
+	return written, nil
+}
+
+// hashWritten adds bytes now in the file to its hash
+func (p *partFile) hashWritten(b []byte) {
+	if p.hash != nil {
+		p.hash.Write(b)
+		p.blocks.Write(b)
+	}
+}
+
+// createEmpty creates an empty destination file, for a source file of 0 bytes, which is
+// then never opened: over MTP each open starts a read stream, and phones hold thousands of
+// empty files (.nomedia and the like)
+func createEmpty(destPath string) error {
+	part, err := openPart(destPath, nil)
+	if err != nil {
+		return err
+	}
+	return part.commit(0)
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSCopierEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	sourceRoot, destRoot := filepath.Join(dir, "phone"), filepath.Join(dir, "backup")
+	os.MkdirAll(filepath.Join(sourceRoot, "Pictures"), 0755)
+	os.WriteFile(filepath.Join(sourceRoot, "Pictures", ".nomedia"), nil, 0644)
+	// A stale copy is replaced
+	os.MkdirAll(filepath.Join(destRoot, "Pictures"), 0755)
+	os.WriteFile(filepath.Join(destRoot, "Pictures", ".nomedia"), []byte("old"), 0644)
+
+	n, err := NewFSCopier().Copy(context.Background(), filepath.Join(sourceRoot, "Pictures", ".nomedia"), sourceRoot, destRoot, nil)
+	if err != nil || n != 0 {
+		t.Fatalf("Copy = %d, %v", n, err)
+	}
+	info, err := os.Stat(filepath.Join(destRoot, "Pictures", ".nomedia"))
+	if err != nil || info.Size() != 0 {
+		t.Errorf("empty copy: %v, %v", info, err)
+	}
+}
+
+func TestFSCopierKeepsHoles(t *testing.T) {
+	dir := t.TempDir()
+	sourceRoot, destRoot := filepath.Join(dir, "phone"), filepath.Join(dir, "backup")
+	os.MkdirAll(sourceRoot, 0755)
+	source := filepath.Join(sourceRoot, "disk.img")
+
+	// Data, a 1 MiB hole, data, and a hole at the end
+	const size = 3 << 20
+	f, _ := os.Create(source)
+	f.Write(bytes.Repeat([]byte("head"), 1000))
+	f.WriteAt(bytes.Repeat([]byte("tail"), 1000), 1<<20+4000)
+	f.Truncate(size)
+	f.Close()
+	info, _ := os.Stat(source)
+	if !isSparse(info) {
+		t.Skip("no sparse files on this filesystem")
+	}
+
+	n, err := NewFSCopier().Copy(context.Background(), source, sourceRoot, destRoot, nil)
+	if err != nil || n != size {
+		t.Fatalf("Copy = %d, %v", n, err)
+	}
+	want, _ := os.ReadFile(source)
+	got, _ := os.ReadFile(filepath.Join(destRoot, "disk.img"))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("sparse copy differs from the source")
+	}
+	if copied, _ := os.Stat(filepath.Join(destRoot, "disk.img")); !isSparse(copied) {
+		t.Errorf("the holes of the source were written out")
+	}
+}
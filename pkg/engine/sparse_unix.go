@@ -0,0 +1,15 @@
+//go:build !windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// isSparse reports whether the file described by info takes less space on disk than its
+// size, having holes (or unallocated runs of zeros) a copy can leave unwritten
+func isSparse(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && info.Size() > sparseBlock && int64(st.Blocks)*512 < info.Size()
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package engine
+
+import "os"
+
+// isSparse reports whether the file described by info has holes; sparse files are rare on
+// Windows and not detected, so copies are written in full
+func isSparse(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,599 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// DefaultSSHPort is used when SSHOptions.Host has no port (Termux's sshd listens on 8022)
+	DefaultSSHPort = 22
+	// sshDialTimeout bounds connecting and logging in to the source
+	sshDialTimeout = 30 * time.Second
+	// sshKeepAlive is how often an idle connection is checked; a connection that doesn't
+	// answer within the same time is dropped so hung transfers fail instead of stalling
+	sshKeepAlive = 15 * time.Second
+	// sshReadBuffer is how much of a remote file is requested at once (SFTP pipelines the
+	// requests, so large reads hide the network round trip)
+	sshReadBuffer = 1 << 20
+)
+
+// SSHOptions tells ssh mode how to reach the source machine; SourcePath is then a path on it
+type SSHOptions struct {
+	// Host is [user@]host[:port] (user defaults to the local user, port to DefaultSSHPort)
+	Host string
+	// KeyFile is the private key to log in with; without it the ssh agent and the default
+	// ~/.ssh keys are tried. Keys protected by a passphrase must be loaded into the agent.
+	KeyFile string
+	// Password is tried after the keys, when the server allows password logins
+	Password string
+	// KnownHostsFile verifies the server's host key (empty = ~/.ssh/known_hosts)
+	KnownHostsFile string
+	// InsecureIgnoreHostKey skips host key verification; only for trusted networks
+	InsecureIgnoreHostKey bool
+}
+
+// ParseSSHSource splits an ssh source, ssh://[user@]host[:port]/path or scp-style
+// [user@]host:path, into SSHOptions.Host and the path on the remote machine
+// (relative paths are relative to the remote home directory)
+func ParseSSHSource(source string) (host, remotePath string, err error) {
+	if strings.HasPrefix(source, "ssh://") || strings.HasPrefix(source, "sftp://") {
+		u, err := url.Parse(source)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("invalid ssh source %q", source)
+		}
+		host = u.Host
+		if u.User != nil {
+			host = u.User.Username() + "@" + host
+		}
+		remotePath = u.Path
+		if remotePath == "" {
+			remotePath = "."
+		}
+		return host, remotePath, nil
+	}
+	host, remotePath, ok := strings.Cut(source, ":")
+	if !ok || host == "" || strings.Contains(host, "/") {
+		return "", "", fmt.Errorf("invalid ssh source %q (want [user@]host:path or ssh://[user@]host[:port]/path)", source)
+	}
+	if remotePath == "" {
+		remotePath = "."
+	}
+	return host, remotePath, nil
+}
+
+// sshClient is an SFTP session on the source machine, shared by ssh mode's scanner and
+// copier. It connects on first use and again after the connection was lost.
+type sshClient struct {
+	opts SSHOptions
+
+	mu   sync.Mutex
+	conn *ssh.Client
+	sftp *sftp.Client
+}
+
+func newSSHClient(opts SSHOptions) *sshClient {
+	return &sshClient{opts: opts}
+}
+
+// get returns the SFTP session, connecting if there is none.
+// An unreachable host is reported as ErrConnectionLost.
+func (c *sshClient) get(ctx context.Context) (*sftp.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sftp != nil {
+		return c.sftp, nil
+	}
+	conn, err := dialSSH(ctx, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sftp is not available on %s: %w", c.opts.Host, err)
+	}
+	c.conn, c.sftp = conn, client
+	go c.keepAlive(conn)
+	return client, nil
+}
+
+// check returns err from an operation on client, as ErrConnectionLost if the connection
+// broke; the broken session is dropped so the next call reconnects
+func (c *sshClient) check(client *sftp.Client, err error) error {
+	if err == nil || !isSSHDisconnect(err) {
+		return err
+	}
+	c.mu.Lock()
+	if c.sftp == client {
+		c.closeLocked()
+	}
+	c.mu.Unlock()
+	return fmt.Errorf("%w: %v", ErrConnectionLost, err)
+}
+
+// keepAlive closes conn when the server stops answering, failing the requests waiting on it
+func (c *sshClient) keepAlive(conn *ssh.Client) {
+	ticker := time.NewTicker(sshKeepAlive)
+	defer ticker.Stop()
+	for range ticker.C {
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+		select {
+		case err := <-reply:
+			if err == nil {
+				continue
+			}
+		case <-time.After(sshKeepAlive):
+		}
+		conn.Close()
+		return
+	}
+}
+
+// run executes a command on the source machine (arguments are quoted) and returns its stdout
+func (c *sshClient) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := c.get(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return nil, fmt.Errorf("%w: ssh session closed", ErrConnectionLost)
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+	defer session.Close()
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	parts := []string{name}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return session.Output(strings.Join(parts, " "))
+}
+
+// Close ends the session; a later call connects again
+func (c *sshClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked()
+	return nil
+}
+
+func (c *sshClient) closeLocked() {
+	if c.sftp != nil {
+		c.sftp.Close()
+		c.conn.Close()
+		c.sftp, c.conn = nil, nil
+	}
+}
+
+// isSSHDisconnect reports whether err means the connection is gone (as opposed to a file error)
+func isSSHDisconnect(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.As(err, &netErr)
+}
+
+// dialSSH connects and logs in to opts.Host
+func dialSSH(ctx context.Context, opts SSHOptions) (*ssh.Client, error) {
+	username, addr := splitSSHHost(opts.Host)
+	if addr == "" {
+		return nil, fmt.Errorf("ssh mode needs a host")
+	}
+	hostKey, err := sshHostKeyCallback(opts)
+	if err != nil {
+		return nil, err
+	}
+	auth, closeAgent := sshAuthMethods(opts)
+	defer closeAgent()
+	config := &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         sshDialTimeout,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sshDialTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+	// The handshake itself has no context; a deadline keeps a silent server from hanging it
+	deadline, _ := ctx.Deadline()
+	netConn.SetDeadline(deadline)
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if err != nil {
+		netConn.Close()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return nil, fmt.Errorf("host key of %s is unknown: connect once with ssh to add it to known_hosts, or skip the check on a trusted network", addr)
+		}
+		return nil, fmt.Errorf("ssh login to %s failed: %w", addr, err)
+	}
+	netConn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// splitSSHHost returns the login name and host:port of [user@]host[:port]
+func splitSSHHost(host string) (username, addr string) {
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		username, host = host[:i], host[i+1:]
+	}
+	if username == "" {
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	if host == "" {
+		return username, ""
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(DefaultSSHPort))
+	}
+	return username, host
+}
+
+// sshHostKeyCallback verifies the server against known_hosts unless the check is disabled
+func sshHostKeyCallback(opts SSHOptions) (ssh.HostKeyCallback, error) {
+	if opts.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	file := opts.KnownHostsFile
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate known_hosts: %w", err)
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read known hosts %s: %w", file, err)
+	}
+	return callback, nil
+}
+
+// sshAuthMethods returns the ways to log in, in order: ssh agent, key files, password.
+// The returned function closes the agent connection once the login is done.
+func sshAuthMethods(opts SSHOptions) ([]ssh.AuthMethod, func()) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" && opts.KeyFile == "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+
+	keyFiles := []string{opts.KeyFile}
+	if opts.KeyFile == "" {
+		keyFiles = nil
+		if home, err := os.UserHomeDir(); err == nil {
+			for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+				keyFiles = append(keyFiles, filepath.Join(home, ".ssh", name))
+			}
+		}
+	}
+	var signers []ssh.Signer
+	for _, file := range keyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if opts.Password != "" {
+		methods = append(methods, ssh.Password(opts.Password))
+	}
+	return methods, closeAgent
+}
+
+// SSHScanner implements Scanner over SFTP
+type SSHScanner struct {
+	client       *sshClient
+	closeJobChan func()   // Function to safely close jobChan (uses sync.Once)
+	scanRoots    []string // Folders (relative to root) to scan; empty = whole root
+	filter       *Filter  // User-defined exclude rules (nil = none)
+	reconnect    func(ctx context.Context, err error) bool
+	links        *linkPolicy // Handles links and special files (nil = skipped)
+}
+
+// NewSSHScanner creates a scanner for the machine described by opts
+func NewSSHScanner(opts SSHOptions, closeJobChan func()) *SSHScanner {
+	return &SSHScanner{client: newSSHClient(opts), closeJobChan: closeJobChan}
+}
+
+// SetScanRoots limits scanning to the given folders (relative to the scan root)
+func (s *SSHScanner) SetScanRoots(roots []string) {
+	s.scanRoots = roots
+}
+
+// SetFilter applies user-defined exclude rules during scanning
+func (s *SSHScanner) SetFilter(f *Filter) {
+	s.filter = f
+}
+
+// SetReconnect makes the scanner wait for a lost connection to come back (reconnect returns
+// true) and read the directory again instead of giving up on the scan
+func (s *SSHScanner) SetReconnect(reconnect func(ctx context.Context, err error) bool) {
+	s.reconnect = reconnect
+}
+
+// Probe checks that the source machine is reachable and root can be listed
+func (s *SSHScanner) Probe(ctx context.Context, root string) error {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.Stat(root)
+	return s.client.check(client, err)
+}
+
+// Close ends the scanner's SFTP session
+func (s *SSHScanner) Close() error {
+	return s.client.Close()
+}
+
+// Scan lists the remote tree over SFTP, priority folders first
+func (s *SSHScanner) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
+	defer s.closeJobChan()
+	scanRemote(ctx, root, s.scanRoots, s.filter, s.readDir, s.links, remoteLinks{readLink: s.readLink, stat: s.stat}, jobs, errors)
+}
+
+// readDir lists dir, waiting for the connection to come back if it is lost
+func (s *SSHScanner) readDir(ctx context.Context, dir string) ([]os.FileInfo, error) {
+	for {
+		client, err := s.client.get(ctx)
+		if err == nil {
+			var entries []os.FileInfo
+			entries, err = client.ReadDir(dir)
+			if err = s.client.check(client, err); err == nil {
+				return entries, nil
+			}
+		}
+		if !IsCritical(err) || s.reconnect == nil || !s.reconnect(ctx, err) {
+			return nil, err
+		}
+	}
+}
+
+// readLink returns the target of the link at p
+func (s *SSHScanner) readLink(ctx context.Context, p string) (string, error) {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	target, err := client.ReadLink(p)
+	return target, s.client.check(client, err)
+}
+
+// stat describes the file at p, following links
+func (s *SSHScanner) stat(ctx context.Context, p string) (os.FileInfo, error) {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Stat(p)
+	return info, s.client.check(client, err)
+}
+
+// SSHCopier implements Copier over SFTP
+type SSHCopier struct {
+	client   *sshClient
+	limiter  *RateLimiter
+	timeouts CopyTimeouts
+	names    destNamer // how copies are named in the destination (zero = as on the source)
+}
+
+// NewSSHCopier creates a copier for the machine described by opts
+func NewSSHCopier(opts SSHOptions) *SSHCopier {
+	return &SSHCopier{client: newSSHClient(opts)}
+}
+
+// SetRateLimiter throttles all copies made by this copier through a shared limiter
+func (sc *SSHCopier) SetRateLimiter(limiter *RateLimiter) {
+	sc.limiter = limiter
+}
+
+// SetTimeouts sets when a copy is abandoned as stalled or too slow (see CopyTimeouts)
+func (sc *SSHCopier) SetTimeouts(t CopyTimeouts) {
+	sc.timeouts = t
+}
+
+// Close ends the copier's SFTP session
+func (sc *SSHCopier) Close() error {
+	return sc.client.Close()
+}
+
+// Copy downloads a file over SFTP with stall detection
+func (sc *SSHCopier) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progressChan chan<- int64) (int64, error) {
+	relPath, err := filepath.Rel(sourceRoot, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate relative path: %w", err)
+	}
+	destPath := sc.names.path(destRoot, sourcePath, relPath)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create dest dir: %w", err)
+	}
+
+	client, err := sc.client.get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sourceFile, err := client.Open(sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open source: %w", sc.client.check(client, err))
+	}
+	defer sourceFile.Close()
+	// Closing the remote file is what unblocks a read when the copy is cancelled
+	stop := context.AfterFunc(ctx, func() { sourceFile.Close() })
+	defer stop()
+
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat source: %w", sc.client.check(client, err))
+	}
+
+	// Write through <dest>.part, resuming one left by an interrupted copy
+	part, err := openPart(destPath, info)
+	if err != nil {
+		return 0, err
+	}
+	if part.offset > 0 {
+		if _, err := sourceFile.Seek(part.offset, io.SeekStart); err != nil {
+			part.discard()
+			return 0, fmt.Errorf("failed to resume partial copy: %w", sc.client.check(client, err))
+		}
+	}
+	part.preallocate(info.Size())
+
+	connChecker := func() error {
+		_, err := client.Stat(sourceRoot)
+		if err = sc.client.check(client, err); IsCritical(err) {
+			return err
+		}
+		return nil
+	}
+	limits := sc.timeouts.forSize(info.Size()).throttled(sc.limiter)
+
+	src := part.source(bufio.NewReaderSize(sourceFile, sshReadBuffer))
+	bytesCopied, err := copyWithTimeout(limitReader(ctx, src, sc.limiter), part, limits, progressChan, connChecker)
+	if err != nil {
+		// Keep what arrived for the retry or the next run to resume
+		part.close(info)
+		return bytesCopied, sc.client.check(client, err)
+	}
+	if err := part.commit(info.Size()); err != nil {
+		return bytesCopied, err
+	}
+	reportCopyHash(ctx, part)
+	keepSourceMode(destPath, info)
+	return part.offset + bytesCopied, nil
+}
+
+// sshSource accesses files on the source machine over SFTP for cleanup
+type sshSource struct {
+	client     *sshClient
+	copier     *SSHCopier
+	sourceRoot string
+	destRoot   string
+}
+
+func newSSHSource(opts SSHOptions, sourceRoot, destRoot string) *sshSource {
+	client := newSSHClient(opts)
+	return &sshSource{
+		client:     client,
+		copier:     &SSHCopier{client: client},
+		sourceRoot: sourceRoot,
+		destRoot:   destRoot,
+	}
+}
+
+func (s *sshSource) Stat(ctx context.Context, path string) (int64, bool, error) {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	info, err := client.Stat(path)
+	if err != nil {
+		return 0, false, s.client.check(client, err)
+	}
+	return info.Size(), info.IsDir(), nil
+}
+
+// Hash runs sha256sum on the source machine, or reads the file over SFTP if it can't
+func (s *sshSource) Hash(ctx context.Context, path string) (string, error) {
+	if out, err := s.client.run(ctx, "sha256sum", path); err == nil {
+		if fields := strings.Fields(string(out)); len(fields) > 0 && len(fields[0]) == 64 {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	file, err := client.Open(path)
+	if err != nil {
+		return "", s.client.check(client, err)
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, bufio.NewReaderSize(file, sshReadBuffer)); err != nil {
+		return "", s.client.check(client, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (s *sshSource) Restore(ctx context.Context, path string) error {
+	_, err := s.copier.Copy(ctx, path, s.sourceRoot, s.destRoot, nil)
+	return err
+}
+
+func (s *sshSource) Remove(ctx context.Context, path string) error {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return err
+	}
+	return s.client.check(client, client.Remove(path))
+}
+
+// FreeSpace asks the server (OpenSSH's statvfs extension), falling back to df
+func (s *sshSource) FreeSpace(ctx context.Context, path string) (int64, error) {
+	client, err := s.client.get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if vfs, err := client.StatVFS(path); err == nil {
+		return int64(vfs.Bavail * vfs.Frsize), nil
+	} else if err = s.client.check(client, err); IsCritical(err) {
+		return 0, err
+	}
+	out, err := s.client.run(ctx, "df", "-k", path)
+	if err != nil {
+		return 0, err
+	}
+	return parseDFAvailable(string(out))
+}
+
+func (s *sshSource) Close() error {
+	return s.client.Close()
+}
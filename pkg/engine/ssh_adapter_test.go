@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GusSync/pkg/state"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSFTPServer serves the local filesystem over SFTP on localhost (password "secret", no
+// shell commands) and returns its host and a known_hosts file listing its key
+func startSFTPServer(t *testing.T) (host, knownHostsFile string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	knownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{lis.Addr().String()}, signer.PublicKey())
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return "phone@" + lis.Addr().String(), knownHostsFile
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					if server, err := sftp.NewServer(channel); err == nil {
+						go func() { server.Serve(); channel.Close() }()
+					}
+				}
+			}
+		}()
+	}
+}
+
+func TestSSHBackupVerifyCleanup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // no default keys
+	t.Setenv("SSH_AUTH_SOCK", "")
+	host, knownHosts := startSFTPServer(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "phone")
+	files := map[string]string{"DCIM/a.jpg": "aaa", "Download/b.pdf": "bb", "Notes/c.txt": "c"}
+	for rel, content := range files {
+		os.MkdirAll(filepath.Join(src, filepath.Dir(rel)), 0755)
+		os.WriteFile(filepath.Join(src, rel), []byte(content), 0644)
+	}
+	os.Symlink(filepath.Join(src, "DCIM/a.jpg"), filepath.Join(src, "link.jpg"))
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	destRoot := filepath.Join(dir, "backup")
+	e := NewEngine(EngineConfig{
+		Mode:       TransportSSH,
+		SourcePath: src,
+		DestRoot:   destRoot,
+		NumWorkers: 2,
+		Reporter:   discardReporter{},
+		SSH:        SSHOptions{Host: host, Password: "secret", KnownHostsFile: knownHosts},
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for rel, content := range files {
+		if got, _ := os.ReadFile(filepath.Join(destRoot, rel)); string(got) != content {
+			t.Errorf("%s: got %q, want %q", rel, got, content)
+		}
+		if !sm.IsDone(src + "/" + rel) {
+			t.Errorf("%s not recorded as backed up", rel)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(destRoot, "link.jpg")); err == nil {
+		t.Error("expected the symlink to be skipped")
+	}
+
+	results, err := e.VerifyBackup(context.Background())
+	if err != nil || results.Verified != len(files) {
+		t.Fatalf("VerifyBackup = %+v, %v; want %d verified", results, err, len(files))
+	}
+
+	// Cleanup hashes the source over SFTP (the test server has no sha256sum) and deletes it
+	cleaned, err := e.RunCleanup(context.Background())
+	if err != nil || cleaned.Deleted != len(files) {
+		t.Fatalf("RunCleanup = %+v, %v; want %d deleted", cleaned, err, len(files))
+	}
+	if _, err := os.Stat(filepath.Join(src, "DCIM/a.jpg")); !os.IsNotExist(err) {
+		t.Error("expected cleanup to delete the source file")
+	}
+}
+
+func TestSSHRejectsUnknownHostKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+	host, _ := startSFTPServer(t)
+	empty := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(empty, nil, 0600)
+
+	scanner := NewSSHScanner(SSHOptions{Host: host, Password: "secret", KnownHostsFile: empty}, func() {})
+	err := scanner.Probe(context.Background(), "/")
+	if err == nil || !strings.Contains(err.Error(), "host key") {
+		t.Fatalf("expected an unknown host key error, got %v", err)
+	}
+	if IsCritical(err) {
+		t.Error("an unknown host key must not be treated as a lost connection")
+	}
+}
+
+func TestParseSSHSource(t *testing.T) {
+	tests := []struct {
+		source, host, path string
+		wantErr            bool
+	}{
+		{"ssh://u0_a123@192.168.1.20:8022/storage/emulated/0", "u0_a123@192.168.1.20:8022", "/storage/emulated/0", false},
+		{"sftp://phone.local", "phone.local", ".", false},
+		{"pi@backup-box:/home/pi/Photos", "pi@backup-box", "/home/pi/Photos", false},
+		{"phone:storage/shared", "phone", "storage/shared", false},
+		{"/mnt/phone", "", "", true},
+		{"relative/dir:x", "", "", true},
+	}
+	for _, tt := range tests {
+		host, path, err := ParseSSHSource(tt.source)
+		if (err != nil) != tt.wantErr || host != tt.host || path != tt.path {
+			t.Errorf("ParseSSHSource(%q) = %q, %q, %v; want %q, %q (error: %v)", tt.source, host, path, err, tt.host, tt.path, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StorageUsage is what takes up space on a device: the size of its folders (two levels deep)
+// and of each kind of file, so the user can see where the space goes before picking what to
+// back up or clean
+type StorageUsage struct {
+	Root      string        `json:"root"`
+	Bytes     int64         `json:"bytes"`
+	Files     int           `json:"files"`
+	FreeBytes int64         `json:"freeBytes"` // free space on the device (0 if unknown)
+	Folders   []FolderUsage `json:"folders"`   // largest first
+	Types     []TypeUsage   `json:"types"`     // largest first
+	// Complete is false if the walk was cut short (timeout, unreadable folders), so the
+	// figures are lower bounds
+	Complete  bool      `json:"complete"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+// FolderUsage is the size of one folder of a StorageUsage
+type FolderUsage struct {
+	Path  string `json:"path"`  // relative to the root, e.g. "DCIM" or "DCIM/Camera" ("." for files in the root)
+	Depth int    `json:"depth"` // 1 for top-level folders, 2 for their subfolders
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+// TypeUsage is the size of one kind of file of a StorageUsage: a MediaPreset name or "other"
+type TypeUsage struct {
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+// StorageTypeOther is the TypeUsage of files no MediaPreset covers
+const StorageTypeOther = "other"
+
+// storageUsageBuilder adds up files into a StorageUsage
+type storageUsageBuilder struct {
+	usage   StorageUsage
+	folders map[string]*FolderUsage
+	types   map[string]*TypeUsage
+}
+
+func newStorageUsageBuilder(root string) *storageUsageBuilder {
+	return &storageUsageBuilder{
+		usage:   StorageUsage{Root: root, Complete: true, ScannedAt: time.Now().UTC()},
+		folders: make(map[string]*FolderUsage),
+		types:   make(map[string]*TypeUsage),
+	}
+}
+
+// add counts a file (path relative to the root)
+func (b *storageUsageBuilder) add(relPath string, size int64) {
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+	b.usage.Bytes += size
+	b.usage.Files++
+
+	parts := strings.Split(relPath, "/")
+	b.addFolder(topLevelDir(relPath), 1, size)
+	if len(parts) > 2 {
+		b.addFolder(parts[0]+"/"+parts[1], 2, size)
+	}
+
+	kind := mediaType(relPath)
+	t := b.types[kind]
+	if t == nil {
+		t = &TypeUsage{Type: kind}
+		b.types[kind] = t
+	}
+	t.Bytes += size
+	t.Files++
+}
+
+func (b *storageUsageBuilder) addFolder(path string, depth int, size int64) {
+	f := b.folders[path]
+	if f == nil {
+		f = &FolderUsage{Path: path, Depth: depth}
+		b.folders[path] = f
+	}
+	f.Bytes += size
+	f.Files++
+}
+
+func (b *storageUsageBuilder) result() StorageUsage {
+	usage := b.usage
+	usage.Folders = []FolderUsage{}
+	for _, f := range b.folders {
+		usage.Folders = append(usage.Folders, *f)
+	}
+	sort.Slice(usage.Folders, func(i, j int) bool {
+		if usage.Folders[i].Bytes != usage.Folders[j].Bytes {
+			return usage.Folders[i].Bytes > usage.Folders[j].Bytes
+		}
+		return usage.Folders[i].Path < usage.Folders[j].Path
+	})
+	usage.Types = []TypeUsage{}
+	for _, t := range b.types {
+		usage.Types = append(usage.Types, *t)
+	}
+	sort.Slice(usage.Types, func(i, j int) bool {
+		if usage.Types[i].Bytes != usage.Types[j].Bytes {
+			return usage.Types[i].Bytes > usage.Types[j].Bytes
+		}
+		return usage.Types[i].Type < usage.Types[j].Type
+	})
+	return usage
+}
+
+// mediaType returns the MediaPreset a file belongs to by its extension, or StorageTypeOther
+func mediaType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, preset := range MediaPresets {
+		for _, e := range preset.Extensions {
+			if ext == e {
+				return preset.Name
+			}
+		}
+	}
+	return StorageTypeOther
+}
+
+// AnalyzeStorage walks a mounted device (or any folder) and adds up its files. It stops
+// early when ctx ends, returning what it counted so far with Complete false.
+func AnalyzeStorage(ctx context.Context, root string) (StorageUsage, error) {
+	if _, err := os.Stat(root); err != nil {
+		return StorageUsage{}, err
+	}
+	b := newStorageUsageBuilder(root)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			b.usage.Complete = false
+			return filepath.SkipAll
+		}
+		if err != nil {
+			b.usage.Complete = false
+			return nil // Skip unreadable entries
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			b.usage.Complete = false
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		b.add(rel, info.Size())
+		return nil
+	})
+	if err != nil {
+		return StorageUsage{}, err
+	}
+	if free, err := DiskFree(root); err == nil {
+		b.usage.FreeBytes = free
+	}
+	return b.result(), nil
+}
+
+// AnalyzeADBStorage adds up the files under androidRoot (e.g. /sdcard) on the device with
+// this adb serial ("" = the one adb picks), with one find on the device
+func AnalyzeADBStorage(ctx context.Context, serial, androidRoot string) (StorageUsage, error) {
+	if androidRoot == "" {
+		androidRoot = "/sdcard"
+	}
+	androidRoot = strings.TrimSuffix(androidRoot, "/")
+	out, err := adbShellOn(ctx, serial, "find", androidRoot+"/", "-type", "f", "-exec", "stat", "-c", "%s %n", "{}", "+")
+	if err != nil && (IsCritical(err) || len(out) == 0) {
+		return StorageUsage{}, err
+	}
+	b := newStorageUsageBuilder(androidRoot)
+	if parseStatSizes(b, androidRoot, string(out)) || err != nil || ctx.Err() != nil {
+		b.usage.Complete = false // some folders couldn't be read, or find was cut short
+	}
+	if free, ferr := (adbSource{serial: serial}).FreeSpace(ctx, androidRoot); ferr == nil {
+		b.usage.FreeBytes = free
+	}
+	return b.result(), nil
+}
+
+// ADBFolderSizes returns the size of each folder directly under androidRoot (e.g. /sdcard)
+// on the device with this adb serial ("" = the one adb picks), by name; hidden folders are
+// left out. Folders du can't read in full are still listed with what it could.
+func ADBFolderSizes(ctx context.Context, serial, androidRoot string) ([]FolderUsage, error) {
+	if androidRoot == "" {
+		androidRoot = "/sdcard"
+	}
+	androidRoot = strings.TrimSuffix(androidRoot, "/")
+	out, err := adbShellOn(ctx, serial, "find", androidRoot+"/", "-mindepth", "1", "-maxdepth", "1", "-type", "d",
+		"-exec", "du", "-sk", "{}", "+")
+	if err != nil && (IsCritical(err) || len(out) == 0) {
+		return nil, err
+	}
+	return parseDuSizes(string(out)), nil
+}
+
+// parseDuSizes parses `du -sk` output into top-level folders, sorted by name
+func parseDuSizes(out string) []FolderUsage {
+	var folders []FolderUsage
+	for _, line := range strings.Split(out, "\n") {
+		kb, dir, ok := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseInt(kb, 10, 64)
+		name := path.Base(dir)
+		if err != nil || strings.HasPrefix(name, ".") {
+			continue
+		}
+		folders = append(folders, FolderUsage{Path: name, Depth: 1, Bytes: size * 1024})
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Path < folders[j].Path })
+	return folders
+}
+
+// parseStatSizes adds the files of `stat -c '%s %n'` output under root to b and reports
+// whether any line couldn't be parsed
+func parseStatSizes(b *storageUsageBuilder, root, out string) bool {
+	bad := false
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		sizeField, path, ok := strings.Cut(line, " ")
+		size, err := strconv.ParseInt(sizeField, 10, 64)
+		if !ok || err != nil {
+			bad = true
+			continue
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, root), "/")
+		if rel == path || rel == "" {
+			bad = true
+			continue
+		}
+		b.add(rel, size)
+	}
+	return bad || scanner.Err() != nil
+}
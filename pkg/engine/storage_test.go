@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeStorage(t *testing.T) {
+	root := t.TempDir()
+	for rel, size := range map[string]int{
+		"DCIM/Camera/a.jpg":       400,
+		"DCIM/Camera/b.mp4":       1000,
+		"DCIM/c.png":              100,
+		"Download/doc.pdf":        50,
+		"Download/app.apk":        300,
+		"notes.txt":               5,
+		"Android/media/x/y/z.ogg": 20,
+	} {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, make([]byte, size), 0644)
+	}
+
+	usage, err := AnalyzeStorage(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Bytes != 1875 || usage.Files != 7 || !usage.Complete {
+		t.Errorf("totals = %d bytes, %d files, complete %v", usage.Bytes, usage.Files, usage.Complete)
+	}
+	wantFolders := []FolderUsage{
+		{Path: "DCIM", Depth: 1, Bytes: 1500, Files: 3},
+		{Path: "DCIM/Camera", Depth: 2, Bytes: 1400, Files: 2},
+		{Path: "Download", Depth: 1, Bytes: 350, Files: 2},
+		{Path: "Android", Depth: 1, Bytes: 20, Files: 1},
+		{Path: "Android/media", Depth: 2, Bytes: 20, Files: 1},
+		{Path: ".", Depth: 1, Bytes: 5, Files: 1},
+	}
+	if !reflect.DeepEqual(usage.Folders, wantFolders) {
+		t.Errorf("folders = %+v\nwant %+v", usage.Folders, wantFolders)
+	}
+	wantTypes := []TypeUsage{
+		{Type: "videos", Bytes: 1000, Files: 1},
+		{Type: "photos", Bytes: 500, Files: 2},
+		{Type: StorageTypeOther, Bytes: 300, Files: 1},
+		{Type: "documents", Bytes: 55, Files: 2},
+		{Type: "audio", Bytes: 20, Files: 1},
+	}
+	if !reflect.DeepEqual(usage.Types, wantTypes) {
+		t.Errorf("types = %+v\nwant %+v", usage.Types, wantTypes)
+	}
+}
+
+func TestParseStatSizes(t *testing.T) {
+	b := newStorageUsageBuilder("/sdcard")
+	out := "2048 /sdcard/DCIM/Camera/IMG 1.jpg\n" +
+		"10 /sdcard/readme.txt\r\n" +
+		"stat: /sdcard/Android/data: Permission denied\n" +
+		"\n"
+	if !parseStatSizes(b, "/sdcard", out) {
+		t.Errorf("an unparsable line should mark the result incomplete")
+	}
+	usage := b.result()
+	if usage.Bytes != 2058 || usage.Files != 2 {
+		t.Errorf("totals = %d bytes, %d files", usage.Bytes, usage.Files)
+	}
+	if usage.Folders[0].Path != "DCIM" || usage.Folders[1].Path != "DCIM/Camera" || usage.Folders[2].Path != "." {
+		t.Errorf("folders = %+v", usage.Folders)
+	}
+}
+
+func TestParseDuSizes(t *testing.T) {
+	out := "2048\t/sdcard/DCIM\n" +
+		"4\t/sdcard/Bob's stuff\r\n" +
+		"8\t/sdcard/.thumbnails\n" +
+		"du: /sdcard/Android/obb: Permission denied\n"
+	want := []FolderUsage{
+		{Path: "Bob's stuff", Depth: 1, Bytes: 4096},
+		{Path: "DCIM", Depth: 1, Bytes: 2048 * 1024},
+	}
+	if got := parseDuSizes(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDuSizes = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ThumbDirName is the directory (under a mode's backup folder) that caches previews of
+	// backed-up images and videos, keyed by content hash. It can be deleted at any time.
+	ThumbDirName = ".guscache/thumbs"
+	// ThumbSize is the longest edge of a thumbnail, in pixels
+	ThumbSize = 256
+)
+
+// ThumbPath returns where the thumbnail of the file with the given hash is cached in a
+// mode's backup folder; hashes share a directory by their first two characters
+func ThumbPath(backupDir, hash string) string {
+	return filepath.Join(backupDir, filepath.FromSlash(ThumbDirName), hash[:min(2, len(hash))], hash+".jpg")
+}
+
+// FrameGrabber renders a still of a file Go cannot decode itself (videos, HEIC)
+type FrameGrabber interface {
+	// GrabFrame writes a JPEG of a representative frame of src, scaled to fit size x size, to dst
+	GrabFrame(ctx context.Context, src, dst string, size int) error
+}
+
+// Thumbnailer is a PostProcessor that caches a small JPEG preview of each copied image and
+// video under ThumbDirName, for the GUI's backup browser. JPEG, PNG and GIF are decoded in
+// Go; videos and HEIC need a FrameGrabber and are skipped without one. Thumbnails are a
+// cache, not part of the backup, so nothing is recorded in the state.
+type Thumbnailer struct {
+	backupDir string // the mode's backup folder, which holds the cache for all its sources
+	frames    FrameGrabber
+}
+
+// NewThumbnailer creates a Thumbnailer caching into backupDir (e.g. <dest>/mount); frames
+// may be nil
+func NewThumbnailer(backupDir string, frames FrameGrabber) *Thumbnailer {
+	return &Thumbnailer{backupDir: backupDir, frames: frames}
+}
+
+// Name implements PostProcessor
+func (t *Thumbnailer) Name() string {
+	return "thumbnail"
+}
+
+// Process implements PostProcessor
+func (t *Thumbnailer) Process(ctx context.Context, file PostCopyFile) (string, error) {
+	if file.Hash == "" {
+		return "", nil
+	}
+	var render func(ctx context.Context, src, dst string, size int) error
+	switch strings.ToLower(filepath.Ext(file.DestPath)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		render = thumbnailImage
+	case ".heic", ".heif", ".mp4", ".mov", ".mkv", ".3gp", ".webm":
+		if t.frames == nil {
+			return "", nil
+		}
+		render = t.frames.GrabFrame
+	default:
+		return "", nil
+	}
+
+	// Identical files share one thumbnail
+	thumbPath := ThumbPath(t.backupDir, file.Hash)
+	if _, err := os.Stat(thumbPath); err == nil {
+		return "", nil
+	}
+	if err := os.MkdirAll(filepath.Dir(thumbPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail dir: %w", err)
+	}
+	tmpPath := thumbPath + ".tmp.jpg"
+	if err := render(ctx, file.DestPath, tmpPath, ThumbSize); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, thumbPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to finalize thumbnail: %w", err)
+	}
+	return "", nil
+}
+
+// thumbnailImage decodes a JPEG, PNG or GIF image and writes it scaled to fit size x size
+func thumbnailImage(ctx context.Context, src, dst string, size int) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	img, _, err := image.Decode(in)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(out, scaleDown(img, size), &jpeg.Options{Quality: 80}); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return out.Close()
+}
+
+// scaleDown returns img scaled to fit size x size (never enlarged). Each pixel averages a
+// grid of at most 4x4 samples of its area, which is smooth enough for a preview and fast on
+// camera-sized images.
+func scaleDown(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, max(1, h*size/w)
+	if h > w {
+		tw, th = max(1, w*size/h), size
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy += max(1, (y1-y0)/4) {
+				for sx := x0; sx < x1; sx += max(1, (x1-x0)/4) {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+cr, g+cg, bl+cb, a+ca, n+1
+				}
+			}
+			thumb.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return thumb
+}
+
+// GrabFrame implements FrameGrabber with ffmpeg's thumbnail filter
+func (c *ExecConverter) GrabFrame(ctx context.Context, src, dst string, size int) error {
+	scale := fmt.Sprintf("thumbnail,scale=%d:%d:force_original_aspect_ratio=decrease", size, size)
+	cmd := exec.CommandContext(ctx, c.ffmpeg, "-y", "-loglevel", "error", "-i", src,
+		"-vf", scale, "-frames:v", "1", "-q:v", "5", dst)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("frame extraction failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
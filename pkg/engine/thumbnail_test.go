@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeFrameGrabber struct{ frames int }
+
+func (f *fakeFrameGrabber) GrabFrame(ctx context.Context, src, dst string, size int) error {
+	f.frames++
+	return os.WriteFile(dst, []byte("jpeg"), 0644)
+}
+
+func TestThumbnailerProcess(t *testing.T) {
+	destRoot := t.TempDir()
+	original := filepath.Join(destRoot, "DCIM", "Camera", "IMG_0001.png")
+	os.MkdirAll(filepath.Dir(original), 0755)
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	f, err := os.Create(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	frames := &fakeFrameGrabber{}
+	tn := NewThumbnailer(destRoot, frames)
+	file := PostCopyFile{SourcePath: "/sdcard/DCIM/Camera/IMG_0001.png", DestPath: original, DestRoot: destRoot, Hash: "ab12cd"}
+	if rel, err := tn.Process(context.Background(), file); err != nil || rel != "" {
+		t.Fatalf("Process = %q, %v; thumbnails are not recorded as derived files", rel, err)
+	}
+	thumbPath := ThumbPath(destRoot, "ab12cd")
+	if thumbPath != filepath.Join(destRoot, ".guscache", "thumbs", "ab", "ab12cd.jpg") {
+		t.Errorf("thumbnail path = %s", thumbPath)
+	}
+	tf, err := os.Open(thumbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := jpeg.Decode(tf)
+	tf.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbSize || b.Dy() != ThumbSize/2 {
+		t.Errorf("thumbnail is %dx%d, want %dx%d", b.Dx(), b.Dy(), ThumbSize, ThumbSize/2)
+	}
+	if r, g, _, _ := thumb.At(10, 10).RGBA(); r>>8 < 180 || g>>8 > 30 {
+		t.Errorf("thumbnail colour = %v, want red", thumb.At(10, 10))
+	}
+
+	// Videos go to the frame grabber, once per content
+	video := PostCopyFile{DestPath: filepath.Join(destRoot, "clip.mp4"), DestRoot: destRoot, Hash: "ef34"}
+	for range 2 {
+		if _, err := tn.Process(context.Background(), video); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if frames.frames != 1 {
+		t.Errorf("frames grabbed = %d, want 1", frames.frames)
+	}
+
+	// Without a frame grabber, and for other files, there is nothing to do
+	for _, file := range []PostCopyFile{
+		{DestPath: filepath.Join(destRoot, "clip.mov"), DestRoot: destRoot, Hash: "9999"},
+		{DestPath: filepath.Join(destRoot, "notes.pdf"), DestRoot: destRoot, Hash: "8888"},
+	} {
+		if _, err := NewThumbnailer(destRoot, nil).Process(context.Background(), file); err != nil {
+			t.Errorf("%s: %v", file.DestPath, err)
+		}
+		if _, err := os.Stat(ThumbPath(destRoot, file.Hash)); err == nil {
+			t.Errorf("%s: unexpected thumbnail", file.DestPath)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMinThroughput is the slowest rate, in bytes per second, a copy may keep up for a
+	// whole ThroughputWindow before it is abandoned and retried
+	DefaultMinThroughput = 50 << 10
+	// ThroughputWindow is how long a copy must stay below its minimum throughput to be abandoned
+	ThroughputWindow = 60 * time.Second
+	// MinStallTimeout and MaxStallTimeout bound the stall timeout scaled to a file's size
+	MinStallTimeout = 10 * time.Second
+	MaxStallTimeout = 10 * time.Minute
+)
+
+// CopyTimeouts decide when a copy is given up on (and retried): when no bytes arrive for its
+// stall timeout, or when, once bytes flow, fewer than MinThroughput bytes per second arrive
+// over a whole Window
+type CopyTimeouts struct {
+	// Stall is a fixed stall timeout for every file; 0 scales it to each file's size
+	// (StallTimeoutFor), so tiny files fail fast and huge ones may be slow to start
+	Stall time.Duration
+	// MinThroughput in bytes per second (0 = DefaultMinThroughput, negative = no minimum)
+	MinThroughput int64
+	// Window is how long the throughput must stay below the minimum (0 = ThroughputWindow)
+	Window time.Duration
+}
+
+// StallTimeoutFor is how long a copy of size bytes may wait for bytes: MinStallTimeout plus
+// the time the file takes at minThroughput (0 = DefaultMinThroughput), at most MaxStallTimeout.
+// A size of 0 (unknown) gets StallTimeout.
+func StallTimeoutFor(size, minThroughput int64) time.Duration {
+	if size <= 0 {
+		return StallTimeout
+	}
+	if minThroughput <= 0 {
+		minThroughput = DefaultMinThroughput
+	}
+	seconds := size / minThroughput
+	if seconds >= int64(MaxStallTimeout/time.Second) {
+		return MaxStallTimeout
+	}
+	return min(MinStallTimeout+time.Duration(seconds)*time.Second, MaxStallTimeout)
+}
+
+// forSize resolves the timeouts of copying a file of size bytes (0 = unknown)
+func (t CopyTimeouts) forSize(size int64) transferLimits {
+	limits := transferLimits{stall: t.Stall, minRate: t.MinThroughput, window: t.Window}
+	if limits.stall <= 0 {
+		limits.stall = StallTimeoutFor(size, t.MinThroughput)
+	}
+	if limits.minRate == 0 {
+		limits.minRate = DefaultMinThroughput
+	}
+	if limits.window <= 0 {
+		limits.window = ThroughputWindow
+	}
+	return limits
+}
+
+// copyTimeouts are the CopyTimeouts set by the config
+func (c EngineConfig) copyTimeouts() CopyTimeouts {
+	return CopyTimeouts{Stall: c.StallTimeout, MinThroughput: c.MinThroughput, Window: c.ThroughputWindow}
+}
+
+// transferLimits are the resolved CopyTimeouts of one copy
+type transferLimits struct {
+	stall   time.Duration
+	minRate int64 // bytes per second; negative = no minimum
+	window  time.Duration
+	buffer  int // size of the copy buffer (0 = BufferSize)
+}
+
+// throttled drops the minimum throughput of a copy held back by a bandwidth limit, which the
+// user may well have set below it
+func (l transferLimits) throttled(limiter *RateLimiter) transferLimits {
+	if limiter != nil {
+		l.minRate = -1
+	}
+	return l
+}
+
+// stallLimits is a fixed stall timeout without a minimum throughput
+func stallLimits(stall time.Duration) transferLimits {
+	return transferLimits{stall: stall, minRate: -1}
+}
+
+// transferWatch tells from a copy's byte count, checked about once a second, when the copy
+// should be abandoned
+type transferWatch struct {
+	limits      transferLimits
+	lastBytes   int64
+	lastChange  time.Time
+	windowStart time.Time // zero until the first bytes arrive
+	windowBytes int64
+}
+
+func newTransferWatch(limits transferLimits, now time.Time) *transferWatch {
+	return &transferWatch{limits: limits, lastChange: now}
+}
+
+// check returns why the copy should be abandoned, wrapping ErrStalled, or nil to go on
+func (w *transferWatch) check(bytes int64, now time.Time) error {
+	if bytes > w.lastBytes {
+		w.lastBytes = bytes
+		w.lastChange = now
+		if w.windowStart.IsZero() {
+			// The slow start is the stall timeout's business; the minimum applies from here
+			w.windowStart, w.windowBytes = now, bytes
+		}
+	} else if now.Sub(w.lastChange) > w.limits.stall {
+		return fmt.Errorf("%w: no progress for %v", ErrStalled, now.Sub(w.lastChange).Round(time.Second))
+	}
+
+	if w.limits.minRate <= 0 || w.windowStart.IsZero() {
+		return nil
+	}
+	if elapsed := now.Sub(w.windowStart); elapsed >= w.limits.window {
+		rate := float64(bytes-w.windowBytes) / elapsed.Seconds()
+		if rate < float64(w.limits.minRate) {
+			return fmt.Errorf("%w: %s/s for %v, below the minimum of %s/s", ErrStalled,
+				formatSize(int64(rate)), elapsed.Round(time.Second), formatSize(w.limits.minRate))
+		}
+		w.windowStart, w.windowBytes = now, bytes
+	}
+	return nil
+}
+
+// fileSizeKey carries the size of the file being copied to copiers that can't cheaply look
+// it up themselves (adb pull)
+type fileSizeKey struct{}
+
+// withFileSize records the size of the file a copy is for (0 = unknown)
+func withFileSize(ctx context.Context, size int64) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, fileSizeKey{}, size)
+}
+
+// fileSizeFrom is the size recorded by withFileSize, or 0
+func fileSizeFrom(ctx context.Context) int64 {
+	size, _ := ctx.Value(fileSizeKey{}).(int64)
+	return size
+}
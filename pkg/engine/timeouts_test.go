@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallTimeoutFor(t *testing.T) {
+	tests := []struct {
+		size int64
+		want time.Duration
+	}{
+		{0, StallTimeout},          // unknown size
+		{4 << 10, MinStallTimeout}, // a thumbnail arrives at once or not at all
+		{1 << 20, MinStallTimeout + 20*time.Second},
+		{4 << 30, MaxStallTimeout}, // a long video may take a while to start
+	}
+	for _, tt := range tests {
+		if got := StallTimeoutFor(tt.size, 0); got != tt.want {
+			t.Errorf("StallTimeoutFor(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+	if got := (CopyTimeouts{Stall: time.Minute}).forSize(4 << 10).stall; got != time.Minute {
+		t.Errorf("a fixed stall timeout became %v", got)
+	}
+}
+
+func TestTransferWatch(t *testing.T) {
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	limits := CopyTimeouts{MinThroughput: 1000, Window: 10 * time.Second}.forSize(1 << 20)
+
+	// A slow start is fine up to the stall timeout, however long the window
+	w := newTransferWatch(limits, start)
+	if err := w.check(0, at(limits.stall-time.Second)); err != nil {
+		t.Fatalf("slow start abandoned: %v", err)
+	}
+	if err := w.check(0, at(limits.stall+time.Second)); !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected a stall, got %v", err)
+	}
+
+	// Once bytes flow, a whole window below the minimum gives up
+	w = newTransferWatch(limits, start)
+	w.check(100, at(time.Second))
+	if err := w.check(20000, at(6*time.Second)); err != nil {
+		t.Fatalf("fast enough, got %v", err)
+	}
+	if err := w.check(30000, at(11*time.Second)); err != nil {
+		t.Fatalf("first window was fast enough, got %v", err)
+	}
+	if err := w.check(31000, at(21*time.Second)); !errors.Is(err, ErrStalled) || !strings.Contains(err.Error(), "below the minimum") {
+		t.Fatalf("expected the minimum throughput to trip, got %v", err)
+	}
+
+	// Without a minimum, only stalls count
+	w = newTransferWatch(CopyTimeouts{MinThroughput: -1}.forSize(1<<20), start)
+	w.check(1, at(time.Second))
+	if err := w.check(2, at(time.Hour)); err != nil {
+		t.Errorf("no minimum, got %v", err)
+	}
+}
+
+// tricklingReader sends a byte every interval
+type tricklingReader struct {
+	interval time.Duration
+}
+
+func (r tricklingReader) Read(p []byte) (int, error) {
+	time.Sleep(r.interval)
+	p[0] = 'x'
+	return 1, nil
+}
+
+func TestCopyWithTimeoutMinThroughput(t *testing.T) {
+	limits := transferLimits{stall: time.Minute, minRate: 1 << 20, window: time.Second}
+	var dst bytes.Buffer
+	_, err := copyWithTimeout(io.LimitReader(tricklingReader{10 * time.Millisecond}, 1000), &dst, limits, nil, nil)
+	if !errors.Is(err, ErrStalled) || !strings.Contains(err.Error(), "below the minimum") {
+		t.Errorf("expected the copy to be abandoned as too slow, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"GusSync/pkg/state"
+	"fmt"
+	"time"
+)
+
+// totalsSaveInterval is how often a run saves the backup's statistics to the state file, so
+// a crash loses at most this much of them
+const totalsSaveInterval = 30 * time.Second
+
+// runTotals returns the backup's statistics with this run included. complete means the run
+// scanned the whole source, so its file count replaces the expected total. The caller holds
+// e.stats.
+func (e *Engine) runTotals(complete bool) state.Totals {
+	t := e.baseTotals
+	t.Runs++
+	t.Bytes += e.stats.totalBytes
+	t.Failed = e.stats.failed
+	if complete {
+		t.ExpectedFiles = e.stats.totalFiles
+		t.ExpectedBytes = 0
+		if e.stats.manifestFiles > 0 {
+			t.ExpectedFiles = e.stats.manifestFiles
+			t.ExpectedBytes = e.stats.manifestBytes
+		}
+	}
+	return t
+}
+
+// saveTotals saves the backup's statistics with this run included
+func (e *Engine) saveTotals(complete bool) {
+	e.stats.Lock()
+	t := e.runTotals(complete)
+	e.stats.Unlock()
+	if err := e.stateManager.SaveTotals(t); err != nil {
+		e.log("warn", fmt.Sprintf("Could not save backup statistics: %v", err))
+	}
+}
+
+// backupProgress returns the whole-backup figures of a progress report: files in the backup,
+// files expected in the source (0 = unknown) and the share done in percent. totalFiles is
+// this run's count so far. The caller holds e.stats.
+func (e *Engine) backupProgress(totalFiles int) (completed, expected int, percent float64) {
+	completed = e.stateManager.GetStats()
+	expected = e.baseTotals.ExpectedFiles
+	if totalFiles > expected {
+		expected = totalFiles // the source grew since the last complete scan
+	}
+	if expected > 0 {
+		percent = float64(completed) / float64(expected) * 100
+		if percent > 100 {
+			percent = 100 // the backup keeps files since deleted from the source
+		}
+	}
+	return completed, expected, percent
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+// lastProgress keeps the latest progress report
+type lastProgress struct {
+	discardReporter
+	mu     sync.Mutex
+	update ProgressUpdate
+}
+
+func (r *lastProgress) ReportProgress(update ProgressUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.update = update
+}
+
+func TestBackupTotalsAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	stateFile := filepath.Join(dir, "gus_state.md")
+	write := func(rel, content string) {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	run := func() ProgressUpdate {
+		t.Helper()
+		sm, err := state.NewStateManager(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sm.Close()
+		reporter := &lastProgress{}
+		e := NewEngine(EngineConfig{
+			Mode:       TransportMount,
+			SourcePath: source,
+			DestRoot:   filepath.Join(dir, "backup"),
+			NumWorkers: 2,
+			Reporter:   reporter,
+		}, sm)
+		if err := e.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return reporter.update
+	}
+
+	write("DCIM/a.jpg", "aaaa")
+	write("DCIM/b.jpg", "bb")
+	write("Download/c.pdf", "cccccc")
+	first := run()
+	if first.BackupCompleted != 3 || first.BackupExpected != 3 || first.BackupPercent != 100 || first.BackupBytes != 12 {
+		t.Errorf("first run: backup %d/%d (%.0f%%), %d bytes; want 3/3 (100%%), 12 bytes",
+			first.BackupCompleted, first.BackupExpected, first.BackupPercent, first.BackupBytes)
+	}
+
+	// A restart only copies the new file but reports the whole backup
+	write("DCIM/d.jpg", "ddd")
+	second := run()
+	if second.Completed != 1 || second.BackupCompleted != 4 || second.BackupExpected != 4 || second.BackupBytes != 15 {
+		t.Errorf("second run: %d copied, backup %d/%d, %d bytes; want 1 copied, 4/4, 15 bytes",
+			second.Completed, second.BackupCompleted, second.BackupExpected, second.BackupBytes)
+	}
+
+	sm, err := state.OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	if totals := sm.Totals(); totals.Runs != 2 || totals.Bytes != 15 || totals.ExpectedFiles != 4 || totals.Failed != 0 {
+		t.Errorf("saved totals = %+v", totals)
+	}
+}
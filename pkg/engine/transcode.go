@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ConvertedDirName is the directory under the backup root that holds compatibility copies
+	ConvertedDirName = "_converted"
+)
+
+// MediaConverter is the backend that performs the actual format conversion.
+// It is an interface so libheif/ffmpeg can be swapped (or faked in tests).
+type MediaConverter interface {
+	// ConvertImage converts a HEIC/HEIF image at src into a JPEG at dst
+	ConvertImage(ctx context.Context, src, dst string) error
+	// IsHEVC reports whether the video at path is encoded with HEVC/H.265
+	IsHEVC(ctx context.Context, path string) (bool, error)
+	// ConvertVideo re-encodes the video at src to H.264 at dst
+	ConvertVideo(ctx context.Context, src, dst string) error
+}
+
+// Transcoder is a PostProcessor that stores an additional JPEG/H.264 copy of
+// HEIC images and HEVC videos under ConvertedDirName for compatibility.
+// The verified original is never touched.
+type Transcoder struct {
+	converter MediaConverter
+}
+
+// NewTranscoder creates a Transcoder using the given converter backend
+func NewTranscoder(converter MediaConverter) *Transcoder {
+	return &Transcoder{converter: converter}
+}
+
+// Name implements PostProcessor
+func (t *Transcoder) Name() string {
+	return "convert"
+}
+
+// Process implements PostProcessor
+func (t *Transcoder) Process(ctx context.Context, file PostCopyFile) (string, error) {
+	relPath, err := filepath.Rel(file.DestRoot, file.DestPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate relative path: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.DestPath))
+	var newExt string
+	var convert func(ctx context.Context, src, dst string) error
+
+	switch ext {
+	case ".heic", ".heif":
+		newExt = ".jpg"
+		convert = t.converter.ConvertImage
+	case ".mp4", ".mov", ".mkv", ".3gp":
+		hevc, err := t.converter.IsHEVC(ctx, file.DestPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to probe video codec: %w", err)
+		}
+		if !hevc {
+			return "", nil
+		}
+		newExt = ".mp4"
+		convert = t.converter.ConvertVideo
+	default:
+		return "", nil
+	}
+
+	convertedRel := filepath.Join(ConvertedDirName, strings.TrimSuffix(relPath, filepath.Ext(relPath))+newExt)
+	convertedPath := filepath.Join(file.DestRoot, convertedRel)
+
+	// Already converted on a previous run
+	if _, err := os.Stat(convertedPath); err == nil {
+		return convertedRel, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(convertedPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create converted dir: %w", err)
+	}
+
+	// Convert into a temp file so an interrupted conversion never looks complete
+	tmpPath := convertedPath + ".tmp" + newExt
+	if err := convert(ctx, file.DestPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, convertedPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to finalize converted file: %w", err)
+	}
+
+	return convertedRel, nil
+}
+
+// ExecConverter implements MediaConverter using external tools:
+// heif-convert (libheif) for images when available, ffmpeg/ffprobe otherwise and for video.
+type ExecConverter struct {
+	heifConvert string
+	ffmpeg      string
+	ffprobe     string
+}
+
+// NewExecConverter locates the conversion tools in PATH.
+// ffmpeg and ffprobe are required; heif-convert is optional.
+func NewExecConverter() (*ExecConverter, error) {
+	c := &ExecConverter{}
+	var err error
+	if c.ffmpeg, err = exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH (required for media conversion)")
+	}
+	if c.ffprobe, err = exec.LookPath("ffprobe"); err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH (required for media conversion)")
+	}
+	c.heifConvert, _ = exec.LookPath("heif-convert")
+	return c, nil
+}
+
+// ConvertImage implements MediaConverter
+func (c *ExecConverter) ConvertImage(ctx context.Context, src, dst string) error {
+	var cmd *exec.Cmd
+	if c.heifConvert != "" {
+		cmd = exec.CommandContext(ctx, c.heifConvert, "-q", "92", src, dst)
+	} else {
+		cmd = exec.CommandContext(ctx, c.ffmpeg, "-y", "-loglevel", "error", "-i", src, "-q:v", "2", dst)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("image conversion failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// IsHEVC implements MediaConverter
+func (c *ExecConverter) IsHEVC(ctx context.Context, path string) (bool, error) {
+	cmd := exec.CommandContext(ctx, c.ffprobe, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=codec_name", "-of", "default=noprint_wrappers=1:nokey=1", path)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+	codec := strings.TrimSpace(string(output))
+	return codec == "hevc" || codec == "h265", nil
+}
+
+// ConvertVideo implements MediaConverter
+func (c *ExecConverter) ConvertVideo(ctx context.Context, src, dst string) error {
+	cmd := exec.CommandContext(ctx, c.ffmpeg, "-y", "-loglevel", "error", "-i", src,
+		"-map_metadata", "0", "-c:v", "libx264", "-crf", "20", "-preset", "medium",
+		"-c:a", "copy", "-movflags", "+faststart", dst)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("video conversion failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
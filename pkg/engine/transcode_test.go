@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeConverter struct {
+	hevc   bool
+	images int
+	videos int
+}
+
+func (f *fakeConverter) ConvertImage(ctx context.Context, src, dst string) error {
+	f.images++
+	return os.WriteFile(dst, []byte("jpeg"), 0644)
+}
+
+func (f *fakeConverter) IsHEVC(ctx context.Context, path string) (bool, error) {
+	return f.hevc, nil
+}
+
+func (f *fakeConverter) ConvertVideo(ctx context.Context, src, dst string) error {
+	f.videos++
+	return os.WriteFile(dst, []byte("h264"), 0644)
+}
+
+func TestTranscoderProcess(t *testing.T) {
+	destRoot := t.TempDir()
+	original := filepath.Join(destRoot, "DCIM", "Camera", "IMG_0001.HEIC")
+	if err := os.MkdirAll(filepath.Dir(original), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(original, []byte("heic"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := &fakeConverter{}
+	tc := NewTranscoder(fc)
+	file := PostCopyFile{SourcePath: "/src/DCIM/Camera/IMG_0001.HEIC", DestPath: original, DestRoot: destRoot}
+
+	rel, err := tc.Process(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	want := filepath.Join(ConvertedDirName, "DCIM", "Camera", "IMG_0001.jpg")
+	if rel != want {
+		t.Errorf("expected %s, got %s", want, rel)
+	}
+	if _, err := os.Stat(filepath.Join(destRoot, want)); err != nil {
+		t.Errorf("converted file missing: %v", err)
+	}
+	if data, _ := os.ReadFile(original); string(data) != "heic" {
+		t.Errorf("original was modified")
+	}
+
+	// Second run reuses the existing conversion
+	if _, err := tc.Process(context.Background(), file); err != nil {
+		t.Fatalf("second Process failed: %v", err)
+	}
+	if fc.images != 1 {
+		t.Errorf("expected 1 image conversion, got %d", fc.images)
+	}
+
+	// Non-HEVC video is left alone
+	video := filepath.Join(destRoot, "clip.mp4")
+	os.WriteFile(video, []byte("avc"), 0644)
+	rel, err = tc.Process(context.Background(), PostCopyFile{DestPath: video, DestRoot: destRoot})
+	if err != nil || rel != "" || fc.videos != 0 {
+		t.Errorf("expected non-HEVC video to be skipped, got rel=%q err=%v videos=%d", rel, err, fc.videos)
+	}
+}
@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"GusSync/pkg/state"
+)
+
+// Built-in transports, selected by EngineConfig.Mode
+const (
+	TransportMount = "mount" // a mounted filesystem (MTP via gvfs, USB storage, any folder)
+	TransportADB   = "adb"   // adb shell find + adb pull
+	TransportSSH   = "ssh"   // SFTP to a machine running sshd (Termux, a Linux box); see SSHOptions
+	TransportSMB   = "smb"   // a Windows or NAS share over SMB2/3, without mounting it; see SMBOptions
+	// TransportKDEConnect is a phone paired with KDE Connect, over Wi-Fi; see KDEConnectOptions
+	TransportKDEConnect = "kdeconnect"
+)
+
+// TransportEnv is what a transport gets to build its scanner and copier for one run
+type TransportEnv struct {
+	Config    EngineConfig        // the engine's configuration (SourcePath, timeouts, ...)
+	ScanRoots []string            // Config.ScanRoots, normalized
+	Filter    *Filter             // exclusions the scanner must apply
+	State     *state.StateManager // the backup's state (e.g. for incremental scans)
+	// CloseJobs closes the job channel; the scanner must call it once it has sent its last job
+	CloseJobs func()
+	// Reconnect waits for a lost source to come back and reports whether the failed
+	// operation should be retried (always false unless ReconnectWait is set)
+	Reconnect func(ctx context.Context, err error) bool
+
+	engine *Engine
+}
+
+// RateLimiter returns the run's bandwidth limiter for the copier to honor, or nil when
+// Config.Bandwidth is unset. Transports that don't call it run unthrottled (with a warning).
+func (env TransportEnv) RateLimiter() *RateLimiter {
+	e := env.engine
+	if e == nil || e.config.Bandwidth == nil {
+		return nil
+	}
+	if e.rateLimiter == nil {
+		e.rateLimiter = NewRateLimiter(e.config.Bandwidth.RateAt(time.Now()))
+	}
+	return e.rateLimiter
+}
+
+// ChunkProgress returns the function a copier reports chunked copies to (see
+// FSCopier.SetChunkProgress), so the engine can show them in the worker status
+func (env TransportEnv) ChunkProgress() func(sourcePath string, p ChunkProgress) {
+	if env.engine == nil {
+		return nil
+	}
+	return env.engine.chunkProgress
+}
+
+// DirScanned returns the function a scanner reports each folder it has listed to, with the
+// number of files found in it, or nil when nobody subscribed to EventDirScanned
+func (env TransportEnv) DirScanned() func(dir string, files int) {
+	if env.engine == nil || env.engine.config.Events == nil {
+		return nil
+	}
+	e := env.engine
+	return func(dir string, files int) {
+		e.publish(Event{Kind: EventDirScanned, Path: dir, Files: files})
+	}
+}
+
+// destNamer returns how the built-in copiers name the copies (see EngineConfig.DestNames)
+func (env TransportEnv) destNamer() destNamer {
+	if env.engine == nil {
+		return stateDestNamer(env.State, env.Config.DestNames)
+	}
+	return env.engine.names
+}
+
+// linkPolicy returns what the built-in scanners do with links and special files (see
+// EngineConfig.Symlinks)
+func (env TransportEnv) linkPolicy() *linkPolicy {
+	if env.engine == nil {
+		return nil
+	}
+	return env.engine.links
+}
+
+// TransportFactory builds the scanner and copier of a transport
+type TransportFactory func(env TransportEnv) (Scanner, Copier, error)
+
+// Prober is optionally implemented by a transport's Scanner to tell whether the source is
+// reachable; without it, ReconnectWait is ignored for that transport
+type Prober interface {
+	Probe(ctx context.Context, sourcePath string) error
+}
+
+var transports = struct {
+	sync.RWMutex
+	factories map[string]TransportFactory
+}{factories: make(map[string]TransportFactory)}
+
+// RegisterTransport makes a transport available as EngineConfig.Mode. Call it from an init
+// function (e.g. in a file behind a build tag); it panics if the name is empty or taken.
+func RegisterTransport(name string, factory TransportFactory) {
+	transports.Lock()
+	defer transports.Unlock()
+	if name == "" || factory == nil {
+		panic("engine: RegisterTransport needs a name and a factory")
+	}
+	if _, dup := transports.factories[name]; dup {
+		panic("engine: RegisterTransport called twice for " + name)
+	}
+	transports.factories[name] = factory
+}
+
+// HasTransport reports whether name is a registered transport
+func HasTransport(name string) bool {
+	transports.RLock()
+	defer transports.RUnlock()
+	_, ok := transports.factories[name]
+	return ok
+}
+
+// Transports returns the names of the registered transports, sorted
+func Transports() []string {
+	transports.RLock()
+	defer transports.RUnlock()
+	names := make([]string, 0, len(transports.factories))
+	for name := range transports.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newTransport builds the scanner and copier for the configured mode ("" = mount)
+func (e *Engine) newTransport(env TransportEnv) (Scanner, Copier, error) {
+	mode := e.config.Mode
+	if mode == "" {
+		mode = TransportMount
+	}
+	transports.RLock()
+	factory, ok := transports.factories[mode]
+	transports.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown transport %q (registered: %v)", mode, Transports())
+	}
+	env.engine = e
+	return factory(env)
+}
+
+// sourceIsLocal reports whether source files can be opened directly (mount mode); other
+// transports reach them through the device, so verification and sizing rely on the state
+func (e *Engine) sourceIsLocal() bool {
+	return e.config.Mode == "" || e.config.Mode == TransportMount
+}
+
+// isBuiltinTransport reports whether mode is handled by the engine's own probe, cleanup and
+// verification code (mount and adb)
+func isBuiltinTransport(mode string) bool {
+	return mode == "" || mode == TransportMount || mode == TransportADB
+}
+
+func init() {
+	RegisterTransport(TransportMount, func(env TransportEnv) (Scanner, Copier, error) {
+		scanner := NewFSScanner(env.CloseJobs)
+		scanner.SetStateManager(env.State)
+		scanner.SetScanRoots(env.ScanRoots)
+		scanner.SetFilter(env.Filter)
+		scanner.SetIncremental(env.Config.IncrementalScan)
+		scanner.SetDirReadTimeout(env.Config.DirReadTimeout)
+		scanner.SetScanWorkers(env.Config.ScanWorkers)
+		scanner.SetDirScanned(env.DirScanned())
+		scanner.links = env.linkPolicy()
+		if env.Config.ReconnectWait > 0 {
+			scanner.SetReconnect(env.Reconnect)
+		}
+		copier := NewFSCopier()
+		copier.SetTimeouts(env.Config.copyTimeouts())
+		copier.names = env.destNamer()
+		copier.SetChunkSize(env.Config.ChunkSize)
+		copier.SetBufferSize(env.Config.BufferSize)
+		copier.SetChunkProgress(env.ChunkProgress())
+		if limiter := env.RateLimiter(); limiter != nil {
+			copier.SetRateLimiter(limiter)
+		}
+		return scanner, copier, nil
+	})
+	RegisterTransport(TransportADB, func(env TransportEnv) (Scanner, Copier, error) {
+		scanner := NewADBScanner(env.CloseJobs)
+		scanner.SetScanRoots(env.ScanRoots)
+		scanner.SetFilter(env.Filter)
+		scanner.SetMediaStore(env.Config.MediaStoreScan)
+		scanner.SetBatchSmallFiles(env.Config.BatchSmallFiles)
+		scanner.SetSerial(env.Config.Serial)
+		copier := NewADBCopier()
+		copier.SetTimeouts(env.Config.copyTimeouts())
+		copier.SetSerial(env.Config.Serial)
+		copier.names = env.destNamer()
+		return scanner, copier, nil
+	})
+	RegisterTransport(TransportSSH, func(env TransportEnv) (Scanner, Copier, error) {
+		if env.Config.SSH.Host == "" {
+			return nil, nil, fmt.Errorf("ssh mode needs SSH.Host")
+		}
+		scanner := NewSSHScanner(env.Config.SSH, env.CloseJobs)
+		scanner.SetScanRoots(env.ScanRoots)
+		scanner.SetFilter(env.Filter)
+		scanner.links = env.linkPolicy()
+		if env.Config.ReconnectWait > 0 {
+			scanner.SetReconnect(env.Reconnect)
+		}
+		// One SFTP session serves the scan and all workers
+		copier := &SSHCopier{client: scanner.client}
+		copier.SetTimeouts(env.Config.copyTimeouts())
+		copier.names = env.destNamer()
+		if limiter := env.RateLimiter(); limiter != nil {
+			copier.SetRateLimiter(limiter)
+		}
+		return scanner, copier, nil
+	})
+	RegisterTransport(TransportSMB, func(env TransportEnv) (Scanner, Copier, error) {
+		if env.Config.SMB.Server == "" || env.Config.SMB.Share == "" {
+			return nil, nil, fmt.Errorf("smb mode needs SMB.Server and SMB.Share")
+		}
+		scanner := NewSMBScanner(env.Config.SMB, env.CloseJobs)
+		scanner.SetScanRoots(env.ScanRoots)
+		scanner.SetFilter(env.Filter)
+		scanner.links = env.linkPolicy()
+		if env.Config.ReconnectWait > 0 {
+			scanner.SetReconnect(env.Reconnect)
+		}
+		// One SMB session serves the scan and all workers
+		copier := &SMBCopier{client: scanner.client}
+		copier.SetTimeouts(env.Config.copyTimeouts())
+		copier.names = env.destNamer()
+		if limiter := env.RateLimiter(); limiter != nil {
+			copier.SetRateLimiter(limiter)
+		}
+		return scanner, copier, nil
+	})
+	RegisterTransport(TransportKDEConnect, func(env TransportEnv) (Scanner, Copier, error) {
+		if env.Config.KDEConnect.Device == "" {
+			return nil, nil, fmt.Errorf("kdeconnect mode needs KDEConnect.Device")
+		}
+		scanner := NewKDEConnectScanner(env.Config.KDEConnect, env.CloseJobs)
+		scanner.SetScanRoots(env.ScanRoots)
+		scanner.SetFilter(env.Filter)
+		scanner.links = env.linkPolicy()
+		if env.Config.ReconnectWait > 0 {
+			scanner.SetReconnect(env.Reconnect)
+		}
+		// Both look up the mount point once and notice together when it dies
+		copier := &KDEConnectCopier{mount: scanner.mount, fs: NewFSCopier()}
+		copier.SetTimeouts(env.Config.copyTimeouts())
+		copier.fs.names = env.destNamer()
+		if limiter := env.RateLimiter(); limiter != nil {
+			copier.SetRateLimiter(limiter)
+		}
+		return scanner, copier, nil
+	})
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+type discardReporter struct{}
+
+func (discardReporter) ReportProgress(ProgressUpdate)   {}
+func (discardReporter) ReportError(error)               {}
+func (discardReporter) ReportLog(level, message string) {}
+
+// memTransport serves files from memory; it closes the job channel it was built with
+type memTransport struct {
+	files     map[string]string // relative path -> content
+	closeJobs func()
+}
+
+func (m *memTransport) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
+	defer m.closeJobs()
+	for rel := range m.files {
+		jobs <- FileJob{SourcePath: filepath.Join(root, rel), RelPath: rel}
+	}
+}
+
+func (m *memTransport) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progress chan<- int64) (int64, error) {
+	rel, err := filepath.Rel(sourceRoot, sourcePath)
+	if err != nil {
+		return 0, err
+	}
+	dest := filepath.Join(destRoot, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, err
+	}
+	return int64(len(m.files[rel])), os.WriteFile(dest, []byte(m.files[rel]), 0644)
+}
+
+// registerMemTransport keeps repeated runs (-count) from registering the name twice
+var registerMemTransport sync.Once
+
+func TestRegisteredTransport(t *testing.T) {
+	files := map[string]string{"DCIM/a.jpg": "aaa", "Download/b.pdf": "bb"}
+	registerMemTransport.Do(func() {
+		RegisterTransport("test-mem", func(env TransportEnv) (Scanner, Copier, error) {
+			m := &memTransport{files: files, closeJobs: env.CloseJobs}
+			return m, m, nil
+		})
+	})
+	if !HasTransport("test-mem") {
+		t.Fatal("expected the transport to be registered")
+	}
+
+	dir := t.TempDir()
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	destRoot := filepath.Join(dir, "backup")
+	e := NewEngine(EngineConfig{Mode: "test-mem", SourcePath: "/remote", DestRoot: destRoot, NumWorkers: 2, Reporter: discardReporter{}}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for rel, content := range files {
+		if got, _ := os.ReadFile(filepath.Join(destRoot, rel)); string(got) != content {
+			t.Errorf("%s: got %q, want %q", rel, got, content)
+		}
+	}
+	if !sm.IsDone("/remote/DCIM/a.jpg") {
+		t.Error("expected a.jpg to be recorded as backed up")
+	}
+}
+
+func TestUnknownTransport(t *testing.T) {
+	e := NewEngine(EngineConfig{Mode: "carrier-pigeon", SourcePath: "/x", DestRoot: t.TempDir(), Reporter: discardReporter{}}, nil)
+	if err := e.Run(context.Background()); err == nil {
+		t.Error("expected Run to fail for an unregistered mode")
+	}
+}
+
+func TestRegisterTransportTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a taken name to panic")
+		}
+	}()
+	RegisterTransport(TransportMount, func(env TransportEnv) (Scanner, Copier, error) { return nil, nil, nil })
+}
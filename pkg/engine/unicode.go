@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"GusSync/pkg/state"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// nfcName returns name in Unicode NFC, the form most systems write. Android keeps names as
+// they were created, so a file that came from a Mac may be named in NFD ("e" followed by a
+// combining accent) and look the same as, yet not match, an "é" written elsewhere. A name
+// whose NFC form belongs to a sibling on the source (collides) gets a suffix derived from its
+// original bytes, e.g. "Café~1a2b3c4d.jpg", so both files are kept and the one already in NFC
+// keeps its name whichever is copied first.
+func nfcName(name string, collides bool) string {
+	if norm.NFC.IsNormalString(name) {
+		return name
+	}
+	nfc := norm.NFC.String(name)
+	if !collides {
+		return nfc
+	}
+	sum := sha256.Sum256([]byte(name))
+	ext := filepath.Ext(nfc)
+	return strings.TrimSuffix(nfc, ext) + "~" + hex.EncodeToString(sum[:4]) + ext
+}
+
+// sourceComponents splits rel, the path of a file relative to the destination root, into
+// names as they are on the source, taken from the end of sourcePath, each with the source
+// path up to it. When sourcePath doesn't end with rel (in any Unicode form), the names of
+// rel are returned without paths.
+func sourceComponents(sourcePath, rel string) (names, paths []string) {
+	names = splitPath(rel)
+	paths = make([]string, len(names))
+	if sourcePath == "" || len(names) == 0 {
+		return names, paths
+	}
+
+	// The source components, each with the index where it ends
+	var src []string
+	var ends []int
+	start := 0
+	for i := 0; i <= len(sourcePath); i++ {
+		if i < len(sourcePath) && !isPathSeparator(sourcePath[i]) {
+			continue
+		}
+		if part := sourcePath[start:i]; part != "" && part != "." {
+			src = append(src, part)
+			ends = append(ends, i)
+		}
+		start = i + 1
+	}
+	if len(src) < len(names) {
+		return names, paths
+	}
+	offset := len(src) - len(names)
+	for i, name := range names {
+		if norm.NFC.String(src[offset+i]) != norm.NFC.String(name) {
+			return names, paths
+		}
+	}
+	for i := range names {
+		names[i] = src[offset+i]
+		paths[i] = sourcePath[:ends[offset+i]]
+	}
+	return names, paths
+}
+
+// splitPath returns the names of a path, with either separator
+func splitPath(p string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(p, func(r rune) bool { return r < 0x80 && isPathSeparator(byte(r)) }) {
+		if name != "." {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func isPathSeparator(c byte) bool {
+	return c == '/' || c == filepath.Separator
+}
+
+// nfcChecker finds the files and folders of the source whose NFC name belongs to a sibling,
+// and records them in the state before they are copied (see nfcName)
+type nfcChecker struct {
+	source cleanupSource
+	owned  bool // source was opened for the checker
+	sm     *state.StateManager
+
+	mu      sync.Mutex
+	checked map[string]bool // source paths of names not in NFC already looked at
+}
+
+// newNFCChecker returns the run's checker, or nil when names are kept as they are or the
+// source can't be looked at (a custom Scanner)
+func (e *Engine) newNFCChecker() *nfcChecker {
+	if !e.names.nfc {
+		return nil
+	}
+	source := e.source
+	if source == nil {
+		if e.config.Scanner != nil {
+			return nil
+		}
+		source = e.cleanupSource()
+	}
+	return &nfcChecker{source: source, sm: e.stateManager, checked: make(map[string]bool), owned: e.source == nil}
+}
+
+// close releases the source connection, if the checker opened one
+func (c *nfcChecker) close() {
+	if closer, ok := c.source.(io.Closer); ok && c.owned {
+		closer.Close()
+	}
+}
+
+// check looks for an NFC sibling of each name of sourcePath (with rel as for destNamer.path)
+// that is not in NFC. Names in NFC, nearly all of them, cost nothing.
+func (c *nfcChecker) check(ctx context.Context, sourcePath, rel string) {
+	names, paths := sourceComponents(sourcePath, rel)
+	for i, name := range names {
+		if paths[i] == "" || norm.NFC.IsNormalString(name) {
+			continue
+		}
+		// Held while looking, so no copy under the same folder is named before the answer is in
+		c.mu.Lock()
+		if !c.checked[paths[i]] {
+			c.checked[paths[i]] = true
+			sibling := strings.TrimSuffix(paths[i], name) + norm.NFC.String(name)
+			if _, _, err := c.source.Stat(ctx, sibling); err == nil {
+				c.sm.MarkNFCCollision(paths[i])
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+const (
+	cafeNFC = "Caf\u00e9"  // é as one code point
+	cafeNFD = "Cafe\u0301" // e and a combining accent
+)
+
+func TestNFCName(t *testing.T) {
+	if got := nfcName(cafeNFC+".jpg", true); got != cafeNFC+".jpg" {
+		t.Errorf("a name in NFC is kept: %q", got)
+	}
+	if got := nfcName(cafeNFD+".jpg", false); got != cafeNFC+".jpg" {
+		t.Errorf("NFD name = %q, want its NFC form", got)
+	}
+	renamed := nfcName(cafeNFD+".jpg", true)
+	if renamed == cafeNFC+".jpg" || filepath.Ext(renamed) != ".jpg" || renamed != nfcName(cafeNFD+".jpg", true) {
+		t.Errorf("colliding name = %q; want a stable name of its own with the extension kept", renamed)
+	}
+}
+
+func TestSourceComponents(t *testing.T) {
+	// An adb path under the normalized path of the file
+	names, paths := sourceComponents("/sdcard/Music/"+cafeNFD+"/song.mp3", "Music/"+cafeNFC+"/song.mp3")
+	if len(names) != 3 || names[1] != cafeNFD || paths[1] != "/sdcard/Music/"+cafeNFD || paths[2] != "/sdcard/Music/"+cafeNFD+"/song.mp3" {
+		t.Errorf("names %q, paths %q", names, paths)
+	}
+	// A source path that doesn't end with rel only gives the names of rel
+	names, paths = sourceComponents("/sdcard/other.mp3", "Music/song.mp3")
+	if len(names) != 2 || names[1] != "song.mp3" || paths[1] != "" {
+		t.Errorf("unrelated source path: names %q, paths %q", names, paths)
+	}
+}
+
+func TestNFCNames(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	os.MkdirAll(filepath.Join(source, "Pictures"), 0755)
+	os.MkdirAll(filepath.Join(source, "Music", cafeNFD), 0755)
+	os.WriteFile(filepath.Join(source, "Pictures", cafeNFC+".jpg"), []byte("nfc"), 0644)
+	os.WriteFile(filepath.Join(source, "Pictures", cafeNFD+".jpg"), []byte("nfd"), 0644)
+	os.WriteFile(filepath.Join(source, "Music", cafeNFD, "song.mp3"), []byte("song"), 0644)
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file already in NFC keeps its name, the other one is kept apart; a name without a
+	// twin is just normalized
+	if data, _ := os.ReadFile(filepath.Join(dest, "Pictures", cafeNFC+".jpg")); string(data) != "nfc" {
+		t.Errorf("%s.jpg = %q, want the NFC file", cafeNFC, data)
+	}
+	renamed := filepath.Join(dest, "Pictures", nfcName(cafeNFD+".jpg", true))
+	if data, _ := os.ReadFile(renamed); string(data) != "nfd" {
+		t.Errorf("the NFD twin should be at %s: %q", renamed, data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "Music", cafeNFC, "song.mp3")); string(data) != "song" {
+		t.Errorf("an NFD folder should be copied under its NFC name")
+	}
+	if !sm.NFCCollision(filepath.Join(source, "Pictures", cafeNFD+".jpg")) {
+		t.Errorf("the collision should be recorded with the original name")
+	}
+
+	config.VerifyDestOnly = true
+	results, err := NewEngine(config, sm).VerifyBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Verified != 3 || results.Mismatches != 0 || results.MissingDest != 0 {
+		t.Errorf("verify = %+v; want all three copies found", results)
+	}
+}
+
+func TestNFCNamesKeepOlderBackups(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	os.MkdirAll(source, 0755)
+	os.WriteFile(filepath.Join(source, "old.txt"), []byte("old"), 0644)
+	os.WriteFile(filepath.Join(source, cafeNFD+".txt"), []byte("new"), 0644)
+
+	// A backup made before names were recorded
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	sm.MarkDone(filepath.Join(source, "old.txt"), mustHash(t, filepath.Join(source, "old.txt")), "old.txt")
+
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, cafeNFD+".txt")); err != nil {
+		t.Errorf("an existing backup should keep names as they are: %v", err)
+	}
+	if _, nfc := sm.DestNames(); nfc {
+		t.Errorf("an existing backup should not be recorded as normalized")
+	}
+}
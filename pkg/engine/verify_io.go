@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// verifyBatches splits the files VerifyBackup checks into the batches its workers take one at
+// a time, each verified in order. Files are sorted by where their copies are (destOf), so a
+// disk reads them in folder order. With sequential, a batch holds every copy on one disk
+// (diskOf a folder): disks are read in parallel, but each by one worker. Otherwise each file
+// is a batch of its own.
+func verifyBatches(paths []string, destOf func(string) string, diskOf func(string) string, sequential bool) [][]string {
+	dests := make(map[string]string, len(paths))
+	for _, p := range paths {
+		dests[p] = destOf(p)
+	}
+	sorted := append([]string(nil), paths...)
+	sort.SliceStable(sorted, func(i, j int) bool { return dests[sorted[i]] < dests[sorted[j]] })
+
+	var batches [][]string
+	if !sequential {
+		for _, p := range sorted {
+			batches = append(batches, []string{p})
+		}
+		return batches
+	}
+	disks := make(map[string]string) // folder -> disk
+	index := make(map[string]int)    // disk -> batch
+	for _, p := range sorted {
+		dir := filepath.Dir(dests[p])
+		disk, ok := disks[dir]
+		if !ok {
+			disk = diskOf(dir)
+			disks[dir] = disk
+		}
+		i, ok := index[disk]
+		if !ok {
+			i = len(batches)
+			index[disk] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], p)
+	}
+	return batches
+}
+
+// hashFileReadAhead computes the SHA256 hash of a file like calculateFileHash, in reads of
+// readAhead bytes while the kernel is asked to read the next ones (see adviseReadAhead)
+func hashFileReadAhead(filePath string, readAhead int64) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	buf := make([]byte, readAhead)
+	for offset := int64(0); ; {
+		adviseReadAhead(file, offset+readAhead, readAhead)
+		n, err := io.ReadFull(file, buf)
+		hash.Write(buf[:n])
+		offset += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerifyBatches(t *testing.T) {
+	paths := []string{"/p/DCIM/b.jpg", "/p/Music/x.mp3", "/p/DCIM/a.jpg", "/p/Movies/m.mp4"}
+	destOf := func(p string) string { return "/backup" + strings.TrimPrefix(p, "/p") }
+	// Music is on another disk
+	diskOf := func(dir string) string {
+		if strings.HasPrefix(dir, "/backup/Music") {
+			return "disk2"
+		}
+		return "disk1"
+	}
+	want := [][]string{{"/p/DCIM/a.jpg"}, {"/p/DCIM/b.jpg"}, {"/p/Movies/m.mp4"}, {"/p/Music/x.mp3"}}
+	if got := verifyBatches(paths, destOf, diskOf, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+	want = [][]string{{"/p/DCIM/a.jpg", "/p/DCIM/b.jpg", "/p/Movies/m.mp4"}, {"/p/Music/x.mp3"}}
+	if got := verifyBatches(paths, destOf, diskOf, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("sequential batches = %v, want %v", got, want)
+	}
+}
+
+func TestHashFileReadAhead(t *testing.T) {
+	dir := t.TempDir()
+	for _, size := range []int{0, 1, 4096, 4097, 3*4096 + 5} {
+		path := filepath.Join(dir, "file")
+		os.WriteFile(path, bytes.Repeat([]byte("gus"), size)[:size], 0644)
+		want, _ := calculateFileHash(path)
+		if got, err := hashFileReadAhead(path, 4096); err != nil || got != want {
+			t.Errorf("size %d: hash %s, %v; want %s", size, got, err, want)
+		}
+	}
+	if _, err := hashFileReadAhead(filepath.Join(dir, "missing"), 4096); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+	if diskID(dir) == "" || diskID(dir) != diskID(filepath.Join(dir, ".")) {
+		t.Errorf("diskID(%s) = %q", dir, diskID(dir))
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Kinds of VerifyIssue
+const (
+	VerifyMismatch      = "mismatch"       // the destination copy's hash differs
+	VerifyMissingSource = "missing_source" // the file is gone from the source
+	VerifyMissingDest   = "missing_dest"   // the destination copy is gone
+)
+
+// VerifyIssue is a file that did not verify, as reported to EngineConfig.OnVerifyIssue and
+// listed in VerifyResults.Issues
+type VerifyIssue struct {
+	Kind       string `json:"kind"`
+	SourcePath string `json:"sourcePath"`
+	// DestPath is the destination copy: the quarantined file when Quarantined, empty when
+	// the copy is missing
+	DestPath    string `json:"destPath,omitempty"`
+	Repaired    bool   `json:"repaired,omitempty"`    // mismatch fixed by copying the file again
+	Quarantined bool   `json:"quarantined,omitempty"` // mismatch moved aside (see QuarantineDirName)
+}
+
+// verifyProgressInterval is how often VerifyBackup reports progress
+const verifyProgressInterval = 2 * time.Second
+
+// verifyProgress tracks a verification for the progress reports and the issue list
+type verifyProgress struct {
+	mu       sync.Mutex
+	total    int
+	checked  int
+	issues   []VerifyIssue
+	statuses map[int]string // worker -> file being checked
+	started  time.Time
+}
+
+func newVerifyProgress(total int) *verifyProgress {
+	return &verifyProgress{total: total, statuses: make(map[int]string), started: time.Now()}
+}
+
+// start records that a worker is checking sourcePath
+func (p *verifyProgress) start(worker int, sourcePath string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[worker] = fmt.Sprintf("Verifying: %s", filepath.Base(sourcePath))
+}
+
+// done records that a worker finished a file
+func (p *verifyProgress) done(worker int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checked++
+	p.statuses[worker] = "idle"
+}
+
+// issue records a file that did not verify and passes it to EngineConfig.OnVerifyIssue and
+// EngineConfig.Events
+func (e *Engine) verifyIssue(p *verifyProgress, issue VerifyIssue) {
+	p.mu.Lock()
+	p.issues = append(p.issues, issue)
+	p.mu.Unlock()
+	if e.config.OnVerifyIssue != nil {
+		e.config.OnVerifyIssue(issue)
+	}
+	e.publish(Event{Kind: EventVerifyMismatch, Path: issue.SourcePath, Dest: issue.DestPath, Issue: &issue})
+}
+
+// reportVerifyProgress sends a progress report: files checked of those selected, files with
+// issues and what each worker is checking
+func (e *Engine) reportVerifyProgress(p *verifyProgress, final bool) {
+	if e.config.Reporter == nil {
+		return
+	}
+	p.mu.Lock()
+	statuses := make(map[int]string, len(p.statuses))
+	for id, s := range p.statuses {
+		statuses[id] = s
+	}
+	update := ProgressUpdate{
+		TotalFiles:     p.total,
+		Completed:      p.checked - len(p.issues),
+		Failed:         len(p.issues),
+		WorkerStatuses: statuses,
+		ActiveWorkers:  e.verifyWorkers(),
+		ScanComplete:   final,
+	}
+	if elapsed := time.Since(p.started).Seconds(); elapsed > 0 && !final && p.checked > 0 {
+		perFile := elapsed / float64(p.checked)
+		update.ETASeconds = int64(perFile * float64(p.total-p.checked))
+	}
+	p.mu.Unlock()
+	e.config.Reporter.ReportProgress(update)
+}
+
+// reportVerifyProgressEvery reports progress until done is closed
+func (e *Engine) reportVerifyProgressEvery(p *verifyProgress, done <-chan struct{}) {
+	ticker := time.NewTicker(verifyProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			e.reportVerifyProgress(p, false)
+		}
+	}
+}
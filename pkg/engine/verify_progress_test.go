@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"GusSync/pkg/state"
+)
+
+func TestVerifyReportsIssuesAndProgress(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	for _, rel := range []string{"DCIM/ok.jpg", "DCIM/rot.jpg", "DCIM/lost.jpg", "Download/gone.pdf"} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+rel), 0644)
+	}
+
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	var mu sync.Mutex
+	var streamed []VerifyIssue
+	reporter := &lastProgress{}
+	e := NewEngine(EngineConfig{
+		Mode:       TransportMount,
+		SourcePath: source,
+		DestRoot:   dest,
+		NumWorkers: 2,
+		Reporter:   reporter,
+		OnVerifyIssue: func(issue VerifyIssue) {
+			mu.Lock()
+			streamed = append(streamed, issue)
+			mu.Unlock()
+		},
+	}, sm)
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dest, "DCIM", "rot.jpg"), []byte("bitrot"), 0644)
+	os.Remove(filepath.Join(dest, "DCIM", "lost.jpg"))
+	os.Remove(filepath.Join(source, "Download", "gone.pdf"))
+
+	results, err := e.VerifyBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Issues) != 3 || len(streamed) != 3 {
+		t.Fatalf("issues = %+v, streamed = %+v; want 3 of each", results.Issues, streamed)
+	}
+	sort.Slice(results.Issues, func(i, j int) bool { return results.Issues[i].Kind < results.Issues[j].Kind })
+	want := []VerifyIssue{
+		{Kind: VerifyMismatch, SourcePath: filepath.Join(source, "DCIM", "rot.jpg"), DestPath: filepath.Join(dest, "DCIM", "rot.jpg"), Repaired: true},
+		{Kind: VerifyMissingDest, SourcePath: filepath.Join(source, "DCIM", "lost.jpg")},
+		{Kind: VerifyMissingSource, SourcePath: filepath.Join(source, "Download", "gone.pdf")},
+	}
+	for i, issue := range results.Issues {
+		if issue != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issue, want[i])
+		}
+	}
+
+	final := reporter.update
+	if final.TotalFiles != 4 || final.Completed != 1 || final.Failed != 3 || !final.ScanComplete {
+		t.Errorf("final progress = %d/%d completed, %d failed, scan complete %v; want 1/4, 3, true",
+			final.Completed, final.TotalFiles, final.Failed, final.ScanComplete)
+	}
+}
+
+func TestVerifyDestOnly(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	for _, rel := range []string{"DCIM/ok.jpg", "DCIM/rot.jpg", "DCIM/lost.jpg"} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+rel), 0644)
+	}
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The phone is gone; one copy rots, one is lost
+	os.RemoveAll(source)
+	rot := filepath.Join(dest, "DCIM", "rot.jpg")
+	os.WriteFile(rot, []byte("bitrot"), 0644)
+	os.Remove(filepath.Join(dest, "DCIM", "lost.jpg"))
+
+	config.VerifyDestOnly = true
+	results, err := NewEngine(config, sm).VerifyBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Verified != 1 || results.Mismatches != 1 || results.MissingDest != 1 || results.MissingSource != 0 || results.Quarantined != 0 {
+		t.Errorf("results = %+v; want 1 verified, 1 mismatch, 1 missing copy", results)
+	}
+	if data, _ := os.ReadFile(rot); string(data) != "bitrot" {
+		t.Errorf("the corrupted copy should be left in place for the user to deal with")
+	}
+	if sm.LastVerified(filepath.Join(source, "DCIM", "ok.jpg")).IsZero() {
+		t.Errorf("the good copy should be recorded as verified")
+	}
+}
+
+func TestVerifyHashCache(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "phone")
+	dest := filepath.Join(dir, "backup")
+	for _, rel := range []string{"DCIM/a.jpg", "DCIM/b.jpg"} {
+		path := filepath.Join(source, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+rel), 0644)
+	}
+	sm, err := state.NewStateManager(filepath.Join(dir, "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	config := EngineConfig{Mode: TransportMount, SourcePath: source, DestRoot: dest, NumWorkers: 2, Reporter: discardReporter{}, VerifyDestOnly: true}
+	if err := NewEngine(config, sm).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if results, err := NewEngine(config, sm).VerifyBackup(context.Background()); err != nil || results.Verified != 2 {
+		t.Fatalf("first verify = %+v, %v", results, err)
+	}
+
+	// Bitrot: same size and modification time, so the cached hash is used
+	rot := filepath.Join(dest, "DCIM", "a.jpg")
+	info, _ := os.Stat(rot)
+	os.WriteFile(rot, []byte("content of DCIM/X.jpg"), 0644)
+	os.Chtimes(rot, info.ModTime(), info.ModTime())
+	if results, err := NewEngine(config, sm).VerifyBackup(context.Background()); err != nil || results.Verified != 2 {
+		t.Errorf("verify with cached hashes = %+v, %v", results, err)
+	}
+
+	// A scrub reads the copies again
+	config.VerifyScrub, config.VerifySample = true, 1
+	if results, err := NewEngine(config, sm).VerifyBackup(context.Background()); err != nil || results.Mismatches != 1 {
+		t.Errorf("scrub = %+v, %v; want the rotted copy found", results, err)
+	}
+
+	// A copy that changed is hashed again, here one disk at a time in large reads
+	config.VerifyScrub, config.VerifySample = false, 0
+	config.VerifySequential, config.VerifyWorkers, config.VerifyReadAhead = true, 3, 4096
+	os.WriteFile(filepath.Join(dest, "DCIM", "b.jpg"), []byte("edited"), 0644)
+	if results, err := NewEngine(config, sm).VerifyBackup(context.Background()); err != nil || results.Mismatches != 2 {
+		t.Errorf("verify after changes = %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// DefaultScrubFraction is the share of the backup a scrub pass checks when no sample size is given
+const DefaultScrubFraction = 0.10
+
+// selectForVerify picks which completed files a verify pass checks.
+// A fraction <= 0 or >= 1 selects everything. With scrub, the least recently
+// verified files (never-verified first) are picked so repeated runs cycle through
+// the whole backup; otherwise a uniform random sample is taken.
+func selectForVerify(paths []string, fraction float64, scrub bool, lastVerified func(string) time.Time, rnd *rand.Rand) []string {
+	if fraction <= 0 || fraction >= 1 || len(paths) == 0 {
+		return paths
+	}
+	n := int(math.Ceil(float64(len(paths)) * fraction))
+
+	selected := append([]string(nil), paths...)
+	if scrub {
+		verifiedAt := make(map[string]time.Time, len(selected))
+		for _, p := range selected {
+			verifiedAt[p] = lastVerified(p)
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			ti, tj := verifiedAt[selected[i]], verifiedAt[selected[j]]
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return selected[i] < selected[j]
+		})
+	} else {
+		rnd.Shuffle(len(selected), func(i, j int) { selected[i], selected[j] = selected[j], selected[i] })
+	}
+	return selected[:n]
+}
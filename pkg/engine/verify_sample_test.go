@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSelectForVerify(t *testing.T) {
+	var paths []string
+	for i := 0; i < 40; i++ {
+		paths = append(paths, fmt.Sprintf("/sdcard/DCIM/IMG_%02d.jpg", i))
+	}
+	never := func(string) time.Time { return time.Time{} }
+	rnd := rand.New(rand.NewSource(1))
+
+	if got := selectForVerify(paths, 0, false, never, rnd); len(got) != len(paths) {
+		t.Errorf("fraction 0 selected %d files, want all %d", len(got), len(paths))
+	}
+
+	// 5% of 40 rounds up to 2
+	sample := selectForVerify(paths, 0.05, false, never, rnd)
+	if len(sample) != 2 {
+		t.Fatalf("5%% sample selected %d files, want 2", len(sample))
+	}
+	if sample[0] == sample[1] {
+		t.Errorf("sample picked the same file twice: %v", sample)
+	}
+
+	// Scrub prefers never-verified files, then the oldest verifications
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastVerified := func(p string) time.Time {
+		var i int
+		fmt.Sscanf(p, "/sdcard/DCIM/IMG_%02d.jpg", &i)
+		switch {
+		case i == 7:
+			return time.Time{}
+		default:
+			return base.Add(time.Duration(40-i) * time.Hour) // higher index = verified longer ago
+		}
+	}
+	got := selectForVerify(paths, 0.1, true, lastVerified, rnd)
+	sort.Strings(got)
+	want := []string{"/sdcard/DCIM/IMG_07.jpg", "/sdcard/DCIM/IMG_37.jpg", "/sdcard/DCIM/IMG_38.jpg", "/sdcard/DCIM/IMG_39.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scrub selected %v, want %v", got, want)
+	}
+}
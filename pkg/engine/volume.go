@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VolumeInternal is the volume ID of the phone's internal storage, however it is reached:
+// "Internal shared storage" over MTP, /sdcard or /storage/emulated/0 over adb
+const VolumeInternal = "internal"
+
+// internalStorageNames are the (localized) names MTP gives the internal storage
+var internalStorageNames = map[string]bool{
+	"internal shared storage":           true,
+	"internal storage":                  true,
+	"phone":                             true,
+	"interner gemeinsamer speicher":     true,
+	"stockage interne partagé":          true,
+	"almacenamiento interno compartido": true,
+	"memoria condivisa interna":         true,
+	"armazenamento interno partilhado":  true,
+}
+
+// androidFolders are the standard top-level folders of Android storage: found directly under an
+// MTP device they mean it shows a single storage's contents, not a storage folder
+var androidFolders = map[string]bool{
+	"alarms": true, "android": true, "audiobooks": true, "dcim": true, "documents": true,
+	"download": true, "movies": true, "music": true, "notifications": true, "pictures": true,
+	"podcasts": true, "recordings": true, "ringtones": true,
+}
+
+var (
+	adbInternalPattern = regexp.MustCompile(`^(/sdcard|/mnt/sdcard|/storage/self/primary|/storage/emulated/\d+)(?:/|$)`)
+	adbVolumePattern   = regexp.MustCompile(`^/storage/([0-9A-Fa-f]{4}-[0-9A-Fa-f]{4})(?:/|$)`)
+)
+
+// PhoneVolume is the storage area of a phone (internal storage, an SD card, a USB stick)
+// a path is on
+type PhoneVolume struct {
+	// ID identifies the volume in the state: VolumeInternal, the Android volume ID for adb
+	// paths (e.g. "1A2B-3C4D") or the MTP storage name with spaces as "_" (e.g. "SD_card")
+	ID string
+	// Root is the volume's folder in the path, e.g. /storage/1A2B-3C4D or
+	// /run/user/1000/gvfs/mtp:host=X/SD card
+	Root string
+	// Storage is the MTP storage folder name ("" for adb paths)
+	Storage string
+}
+
+// phoneVolume finds the volume a path is on: an Android storage path as adb sees it, or a
+// storage folder of a gvfs MTP mount (whatever its name: MTP lists each storage as a top-level
+// folder of the device, so anything there but a standard Android folder is one). ok is false
+// for other paths, including files directly under the device.
+func phoneVolume(path string) (volume PhoneVolume, ok bool) {
+	slashed := filepath.ToSlash(path)
+	if m := adbInternalPattern.FindStringSubmatch(slashed); m != nil {
+		return PhoneVolume{ID: VolumeInternal, Root: m[1]}, true
+	}
+	if m := adbVolumePattern.FindStringSubmatch(slashed); m != nil {
+		return PhoneVolume{ID: strings.ToUpper(m[1]), Root: "/storage/" + m[1]}, true
+	}
+	parts := strings.Split(slashed, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "mtp:host=") || i+2 >= len(parts) || parts[i+1] == "" {
+			continue
+		}
+		storage := parts[i+1]
+		if androidFolders[strings.ToLower(storage)] {
+			return PhoneVolume{}, false
+		}
+		id := strings.ReplaceAll(storage, " ", "_")
+		if internalStorageNames[strings.ToLower(storage)] {
+			id = VolumeInternal
+		}
+		return PhoneVolume{ID: id, Root: strings.Join(parts[:i+2], "/"), Storage: storage}, true
+	}
+	return PhoneVolume{}, false
+}
+
+// volumeID returns the ID of the volume a source file is on ("" if it isn't on a phone volume)
+func volumeID(sourcePath string) string {
+	volume, _ := phoneVolume(sourcePath)
+	return volume.ID
+}
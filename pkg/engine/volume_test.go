@@ -0,0 +1,47 @@
+package engine
+
+import "testing"
+
+func TestPhoneVolume(t *testing.T) {
+	const device = "/run/user/1000/gvfs/mtp:host=Google_Pixel_6"
+	cases := []struct {
+		path string
+		want PhoneVolume
+		ok   bool
+	}{
+		{device + "/Internal shared storage/DCIM/a.jpg", PhoneVolume{ID: VolumeInternal, Root: device + "/Internal shared storage", Storage: "Internal shared storage"}, true},
+		{device + "/Interner gemeinsamer Speicher/DCIM/a.jpg", PhoneVolume{ID: VolumeInternal, Root: device + "/Interner gemeinsamer Speicher", Storage: "Interner gemeinsamer Speicher"}, true},
+		{device + "/SanDisk SD card/DCIM/a.jpg", PhoneVolume{ID: "SanDisk_SD_card", Root: device + "/SanDisk SD card", Storage: "SanDisk SD card"}, true},
+		{"/storage/emulated/0/DCIM/a.jpg", PhoneVolume{ID: VolumeInternal, Root: "/storage/emulated/0"}, true},
+		{"/sdcard/DCIM/a.jpg", PhoneVolume{ID: VolumeInternal, Root: "/sdcard"}, true},
+		{"/storage/1a2b-3C4D/DCIM/a.jpg", PhoneVolume{ID: "1A2B-3C4D", Root: "/storage/1a2b-3C4D"}, true},
+		{device + "/DCIM/a.jpg", PhoneVolume{}, false},
+		{device + "/a.jpg", PhoneVolume{}, false},
+		{"/media/backup/DCIM/a.jpg", PhoneVolume{}, false},
+	}
+	for _, c := range cases {
+		got, ok := phoneVolume(c.path)
+		if got != c.want || ok != c.ok {
+			t.Errorf("phoneVolume(%q) = %+v, %v; want %+v, %v", c.path, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestNormalizePhonePathAcrossProtocols(t *testing.T) {
+	// The same SD card file over MTP (device or storage as root) and adb normalizes the same
+	cases := []struct{ source, root string }{
+		{"/run/user/1000/gvfs/mtp:host=Pixel/Carte SD/DCIM/a.jpg", "/run/user/1000/gvfs/mtp:host=Pixel"},
+		{"/run/user/1000/gvfs/mtp:host=Pixel/Carte SD/DCIM/a.jpg", "/run/user/1000/gvfs/mtp:host=Pixel/Carte SD"},
+		{"/storage/1A2B-3C4D/DCIM/a.jpg", "/storage"},
+		{"/storage/1A2B-3C4D/DCIM/a.jpg", "/storage/1A2B-3C4D"},
+		{"/storage/emulated/0/DCIM/a.jpg", "/storage"},
+	}
+	for _, c := range cases {
+		if got, err := normalizePhonePath(c.source, c.root); err != nil || got != "DCIM/a.jpg" {
+			t.Errorf("normalizePhonePath(%q, %q) = %q, %v; want DCIM/a.jpg", c.source, c.root, got, err)
+		}
+	}
+	if got := DestRelPath("/run/user/1000/gvfs/mtp:host=Pixel/Carte SD/DCIM/a.jpg", "DCIM/a.jpg"); got != "Carte SD/DCIM/a.jpg" {
+		t.Errorf("DestRelPath = %q, want the storage folder kept", got)
+	}
+}
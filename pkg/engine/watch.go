@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// DefaultWatchSettle is how long the source must be quiet before changed folders are synced
+	DefaultWatchSettle = 5 * time.Second
+	// DefaultWatchPoll is how often Watch rescans the whole source regardless of events
+	DefaultWatchPoll = 15 * time.Minute
+)
+
+// WatchOptions controls continuous sync (see Engine.Watch)
+type WatchOptions struct {
+	// Settle is how long to wait after the last change before copying, so files still
+	// being written are picked up once complete (0 = DefaultWatchSettle)
+	Settle time.Duration
+	// PollInterval rescans the whole source periodically, for changes no event reports
+	// (files added on the phone side of an MTP mount). 0 = DefaultWatchPoll, <0 = never.
+	PollInterval time.Duration
+}
+
+// Watch runs a full backup and then keeps syncing new files while ctx is alive: folders
+// with filesystem events are re-scanned once they settle, and the whole source is rescanned
+// every PollInterval. Mount mode only.
+//
+// gvfs/MTP mounts generally don't deliver events for files created on the phone itself, so
+// there the poll is what picks them up; pair it with IncrementalScan to keep rescans cheap.
+// A file is copied once; later modifications of an already backed-up file are not re-copied.
+func (e *Engine) Watch(ctx context.Context) error {
+	if e.config.Mode != "" && e.config.Mode != TransportMount {
+		return fmt.Errorf("watch mode needs a mounted source (mount mode)")
+	}
+	if len(e.config.ExtraSources) > 0 {
+		return fmt.Errorf("watch mode backs up a single source")
+	}
+	opts := e.config.Watch
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultWatchSettle
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultWatchPoll
+	}
+
+	if err := e.Run(ctx); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Filesystem events unavailable (%v); relying on periodic rescans", err))
+	} else {
+		defer watcher.Close()
+		e.watchTree(watcher, e.config.SourcePath)
+	}
+
+	var poll <-chan time.Time
+	if opts.PollInterval > 0 {
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+	if watcher != nil {
+		events = watcher.Events
+		watchErrors = watcher.Errors
+	}
+
+	settle := time.NewTimer(opts.Settle)
+	settle.Stop()
+	pending := make(map[string]bool) // directories with changes since the last pass
+
+	e.log("info", "Watching for new files (Ctrl+C to stop)...")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event := <-events:
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			dir := filepath.Dir(event.Name)
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				// New folder: watch it (and anything already inside) and scan it
+				e.watchTree(watcher, event.Name)
+				dir = event.Name
+			}
+			pending[dir] = true
+			settle.Reset(opts.Settle)
+
+		case err := <-watchErrors:
+			e.log("warn", fmt.Sprintf("Filesystem watch error: %v", err))
+
+		case <-settle.C:
+			roots := e.watchRoots(pending)
+			pending = make(map[string]bool)
+			e.log("info", fmt.Sprintf("Changes detected; syncing %s", describeRoots(roots)))
+			e.syncPass(ctx, roots)
+
+		case <-poll:
+			e.log("info", "Periodic rescan")
+			e.syncPass(ctx, e.config.ScanRoots)
+		}
+	}
+}
+
+// syncPass runs one backup of the given scan roots with a fresh engine (fresh statistics)
+func (e *Engine) syncPass(ctx context.Context, scanRoots []string) {
+	if _, err := os.Stat(e.config.SourcePath); err != nil {
+		e.log("warn", fmt.Sprintf("Source not available (%v); will retry", err))
+		return
+	}
+	config := e.config
+	config.ScanRoots = scanRoots
+	config.ManifestFirst, config.FromManifest = false, false
+	if err := NewEngine(config, e.stateManager).Run(ctx); err != nil && ctx.Err() == nil {
+		e.log("error", fmt.Sprintf("Sync failed: %v", err))
+	}
+	e.stateManager.Flush()
+}
+
+// watchTree adds watches for dir and every directory below it
+func (e *Engine) watchTree(watcher *fsnotify.Watcher, dir string) {
+	if watcher == nil {
+		return
+	}
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			e.log("warn", fmt.Sprintf("Cannot watch %s (%v); relying on periodic rescans for the rest", path, err))
+			if errors.Is(err, syscall.ENOSPC) {
+				// Out of inotify watches (fs.inotify.max_user_watches); more attempts would fail too
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+}
+
+// watchRoots turns changed directories into scan roots relative to the source, dropping
+// any that lie under another; nil means the whole source (or the configured roots)
+func (e *Engine) watchRoots(dirs map[string]bool) []string {
+	var roots []string
+	for dir := range dirs {
+		rel, err := filepath.Rel(e.config.SourcePath, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return e.config.ScanRoots
+		}
+		roots = append(roots, filepath.ToSlash(rel))
+	}
+	sort.Strings(roots)
+
+	minimal := roots[:0]
+	for _, root := range roots {
+		if len(minimal) > 0 {
+			last := minimal[len(minimal)-1]
+			if root == last || strings.HasPrefix(root, last+"/") {
+				continue
+			}
+		}
+		minimal = append(minimal, root)
+	}
+	return minimal
+}
+
+func describeRoots(roots []string) string {
+	if len(roots) == 0 {
+		return "the whole source"
+	}
+	return strings.Join(roots, ", ")
+}
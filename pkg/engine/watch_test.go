@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatchRoots(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "sdcard")
+	e := NewEngine(EngineConfig{SourcePath: src, ScanRoots: []string{"DCIM"}}, nil)
+	dirs := func(rels ...string) map[string]bool {
+		m := make(map[string]bool)
+		for _, rel := range rels {
+			m[filepath.Join(src, filepath.FromSlash(rel))] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		name string
+		dirs map[string]bool
+		want []string
+	}{
+		{"single", dirs("DCIM/Camera"), []string{"DCIM/Camera"}},
+		{"nested dropped", dirs("DCIM/Camera/2024", "DCIM/Camera", "Pictures"), []string{"DCIM/Camera", "Pictures"}},
+		{"prefix is not a parent", dirs("DCIM/Camera", "DCIM/CameraRoll"), []string{"DCIM/Camera", "DCIM/CameraRoll"}},
+		{"source root means configured roots", dirs("DCIM/Camera", ""), []string{"DCIM"}},
+	}
+	for _, tt := range tests {
+		if got := e.watchRoots(tt.dirs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: watchRoots = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
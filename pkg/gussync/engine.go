@@ -0,0 +1,135 @@
+package gussync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
+)
+
+// Engine runs backups, verification and cleanup for one mode of one destination.
+// It owns the state store; Close it when done.
+type Engine struct {
+	dest   string
+	mode   string
+	store  *StateStore
+	engine *engine.Engine
+}
+
+// Open prepares dest for cfg.Mode (creating <dest>/<mode>) and loads its state file.
+// Loading a large state file can take seconds. cfg.Mode defaults to ModeMount.
+func Open(dest string, cfg Config) (*Engine, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeMount
+	}
+	if !engine.HasTransport(cfg.Mode) {
+		return nil, fmt.Errorf("invalid mode %q (registered: %s)", cfg.Mode, strings.Join(engine.Transports(), ", "))
+	}
+	if cfg.Reporter == nil {
+		cfg.Reporter = nopReporter{}
+	}
+	if err := engine.CheckDestination(dest); err != nil {
+		return nil, err
+	}
+	cfg.DestRoot = ModeDir(dest, cfg.Mode)
+	if err := os.MkdirAll(cfg.DestRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	store, err := state.NewStateManager(StateFile(dest, cfg.Mode))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return &Engine{
+		dest:   dest,
+		mode:   cfg.Mode,
+		store:  store,
+		engine: engine.NewEngine(cfg, store),
+	}, nil
+}
+
+// Dir returns the mode directory the engine backs up into
+func (e *Engine) Dir() string {
+	return ModeDir(e.dest, e.mode)
+}
+
+// Mode returns the engine's mode
+func (e *Engine) Mode() string {
+	return e.mode
+}
+
+// State returns the engine's state store
+func (e *Engine) State() *StateStore {
+	return e.store
+}
+
+// Backup copies new and changed files from the source, between the pre and post backup hooks
+func (e *Engine) Backup(ctx context.Context) error {
+	return e.engine.Run(ctx)
+}
+
+// Watch keeps the backup in sync as files appear on the source until ctx is canceled
+func (e *Engine) Watch(ctx context.Context) error {
+	return e.engine.Watch(ctx)
+}
+
+// SkipFile abandons the file a worker of a running Backup is copying: it is recorded as
+// failed once and the worker moves on. Returns the file's source path.
+func (e *Engine) SkipFile(worker int) (string, error) {
+	return e.engine.SkipFile(worker)
+}
+
+// Verify re-hashes backed-up files against the source
+func (e *Engine) Verify(ctx context.Context) (VerifyResults, error) {
+	return e.engine.VerifyBackup(ctx)
+}
+
+// Cleanup deletes verified files from the source, as configured in Config.Cleanup
+func (e *Engine) Cleanup(ctx context.Context) (CleanupResults, error) {
+	return e.engine.RunCleanup(ctx)
+}
+
+// Restore writes backed-up files back to the source, as configured in Config.Restore
+func (e *Engine) Restore(ctx context.Context) (RestoreResults, error) {
+	return e.engine.RunRestore(ctx)
+}
+
+// Migrate copies the backed-up files to a new phone over adb, as configured in Config.Migrate
+func (e *Engine) Migrate(ctx context.Context) (MigrateResults, error) {
+	return e.engine.RunMigrate(ctx)
+}
+
+// MigratePhone copies the files of the old phone at cfg.SourcePath straight to a new phone
+// over adb, without a backup (cfg.Migrate.Direct is implied)
+func MigratePhone(ctx context.Context, cfg Config) (MigrateResults, error) {
+	if cfg.Reporter == nil {
+		cfg.Reporter = nopReporter{}
+	}
+	cfg.Migrate.Direct = true
+	return engine.NewEngine(cfg, nil).RunMigrate(ctx)
+}
+
+// ErrorSummary summarizes the error log of the runs so far (zero if there is none)
+func (e *Engine) ErrorSummary() (ErrorSummary, error) {
+	return engine.SummarizeErrors(ErrorLogFile(e.dest, e.mode))
+}
+
+// ReportPath returns the report written by the last backup ("" = none, see Config.Report)
+func (e *Engine) ReportPath() string {
+	return e.engine.ReportPath()
+}
+
+// Close flushes and closes the state store
+func (e *Engine) Close() error {
+	return e.store.Close()
+}
+
+// nopReporter discards everything, for embedders that don't pass a Reporter
+type nopReporter struct{}
+
+func (nopReporter) ReportProgress(engine.ProgressUpdate) {}
+func (nopReporter) ReportError(error)                    {}
+func (nopReporter) ReportLog(level, message string)      {}
@@ -0,0 +1,155 @@
+// Package gussync is the importable entry point for running GusSync backups from Go.
+//
+// It ties together the backup engine (pkg/engine) and the backup's state file (pkg/state)
+// the same way the CLI and the desktop app do, so other programs can embed backups,
+// verification and cleanup without exec-ing the gussync binary:
+//
+//	e, err := gussync.Open("/mnt/backup", gussync.Config{SourcePath: "/run/user/1000/gvfs/mtp:host=...", Mode: gussync.ModeMount})
+//	if err != nil { ... }
+//	defer e.Close()
+//	err = e.Backup(ctx)
+//
+// A backup destination holds one directory per mode ("mount", "adb"), each with its own
+// state file (gus_state.md) and error log (gus_errors.jsonl).
+package gussync
+
+import (
+	"os"
+	"path/filepath"
+
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
+)
+
+// Backup modes: how the source is reached and which directory of the destination is used
+const (
+	ModeMount = "mount" // the phone's filesystem is mounted (MTP via gvfs, USB storage, a folder)
+	ModeADB   = "adb"   // files are pulled over adb
+	ModeSSH   = "ssh"   // files are downloaded over SFTP (Config.SSH)
+	ModeSMB   = "smb"   // files are read from a Windows or NAS share (Config.SMB)
+	// ModeKDEConnect reads a phone paired with KDE Connect over Wi-Fi (Config.KDEConnect)
+	ModeKDEConnect = "kdeconnect"
+)
+
+// File names inside a mode directory
+const (
+	StateFileName    = "gus_state.md"
+	ErrorLogFileName = engine.ErrorLogFileName
+	AuditLogFileName = engine.AuditLogFileName
+)
+
+// Config configures an Engine. DestRoot is set by Open; everything else is as documented
+// on engine.EngineConfig (zero values pick the defaults).
+type Config = engine.EngineConfig
+
+// SSHOptions tells ModeSSH which machine to back up (Config.SSH); Config.SourcePath is then
+// a path on that machine
+type SSHOptions = engine.SSHOptions
+
+// SMBOptions tells ModeSMB which share to back up (Config.SMB); Config.SourcePath is then
+// a path inside the share
+type SMBOptions = engine.SMBOptions
+
+// KDEConnectOptions tells ModeKDEConnect which phone to back up (Config.KDEConnect);
+// Config.SourcePath is then a path inside what the phone shares
+type KDEConnectOptions = engine.KDEConnectOptions
+
+// FileLimits skips files by size and modification time (Config.Limits)
+type FileLimits = engine.FileLimits
+
+// Reporter receives progress, errors and log lines (nil in Config = discard them)
+type Reporter = engine.ProgressReporter
+
+// Progress is one periodic progress report
+type Progress = engine.ProgressUpdate
+
+// Scanner discovers source files; set Config.Scanner to back up from a custom transport
+type Scanner = engine.Scanner
+
+// Copier copies one source file into the destination; set Config.Copier alongside Scanner
+type Copier = engine.Copier
+
+// FileJob is one file found by a Scanner
+type FileJob = engine.FileJob
+
+// StateStore records which files are backed up, verified, deleted or quarantined.
+// It is append-only on disk and safe for concurrent use.
+type StateStore = state.StateManager
+
+// Results of the operations an Engine runs
+type (
+	VerifyResults  = engine.VerifyResults
+	CleanupResults = engine.CleanupResults
+	RestoreResults = engine.RestoreResults
+	MigrateResults = engine.MigrateResults
+	ErrorSummary   = engine.ErrorSummary
+)
+
+// ErrorEvent is one error in the error log
+type ErrorEvent = engine.ErrorEvent
+
+// VerifyIssue is a file that did not verify (Config.OnVerifyIssue, VerifyResults.Issues)
+type VerifyIssue = engine.VerifyIssue
+
+// AuditEvent is one file operation in the audit log (Config.Audit)
+type AuditEvent = engine.AuditEvent
+
+// EventBus delivers typed events (file started, completed or failed, ...) to subscribers
+// (Config.Events)
+type EventBus = engine.EventBus
+
+// Event is one event published on an EventBus
+type Event = engine.Event
+
+// NewEventBus returns an EventBus to set as Config.Events
+func NewEventBus() *EventBus {
+	return engine.NewEventBus()
+}
+
+// ModeDir returns the directory of the given mode's backup under dest
+func ModeDir(dest, mode string) string {
+	return filepath.Join(dest, mode)
+}
+
+// StateFile returns the path of the given mode's state file under dest
+func StateFile(dest, mode string) string {
+	return filepath.Join(dest, mode, StateFileName)
+}
+
+// ErrorLogFile returns the path of the given mode's error log under dest
+func ErrorLogFile(dest, mode string) string {
+	return filepath.Join(dest, mode, ErrorLogFileName)
+}
+
+// AuditLogFile returns the path of the given mode's audit log under dest
+func AuditLogFile(dest, mode string) string {
+	return filepath.Join(dest, mode, AuditLogFileName)
+}
+
+// ReadAuditLog calls fn for each event in the given mode's audit log, oldest first
+func ReadAuditLog(dest, mode string, fn func(AuditEvent) error) error {
+	return engine.ReadAuditLog(AuditLogFile(dest, mode), fn)
+}
+
+// DetectModes returns the modes that have a backup (a state file) under dest: mount, adb,
+// then other registered transports by name
+func DetectModes(dest string) []string {
+	var modes []string
+	candidates := []string{ModeMount, ModeADB}
+	for _, mode := range engine.Transports() {
+		if mode != ModeMount && mode != ModeADB {
+			candidates = append(candidates, mode)
+		}
+	}
+	for _, mode := range candidates {
+		if _, err := os.Stat(StateFile(dest, mode)); err == nil {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// OpenState opens a backup's state file read-only, for inspection
+func OpenState(dest, mode string) (*StateStore, error) {
+	return state.OpenReadOnly(StateFile(dest, mode))
+}
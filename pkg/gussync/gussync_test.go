@@ -0,0 +1,81 @@
+package gussync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memSource is a Scanner and Copier over in-memory files
+type memSource map[string]string // relative path -> content
+
+func (m memSource) Scan(ctx context.Context, root string, jobs chan<- FileJob, errors chan<- error) {
+	for rel := range m {
+		jobs <- FileJob{SourcePath: filepath.Join(root, rel), RelPath: rel}
+	}
+}
+
+func (m memSource) Copy(ctx context.Context, sourcePath, sourceRoot, destRoot string, progress chan<- int64) (int64, error) {
+	rel, err := filepath.Rel(sourceRoot, sourcePath)
+	if err != nil {
+		return 0, err
+	}
+	dest := filepath.Join(destRoot, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, err
+	}
+	return int64(len(m[rel])), os.WriteFile(dest, []byte(m[rel]), 0644)
+}
+
+func TestBackupWithCustomTransport(t *testing.T) {
+	dest := t.TempDir()
+	src := memSource{"DCIM/a.jpg": "aaa", "DCIM/b.jpg": "bb"}
+
+	e, err := Open(dest, Config{SourcePath: "/phone", Scanner: src, Copier: src, NumWorkers: 2})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := e.Backup(context.Background()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, ModeMount, "DCIM", "a.jpg")); string(got) != "aaa" {
+		t.Errorf("expected the file in the mount directory, got %q", got)
+	}
+	if n := e.State().GetStats(); n != 2 {
+		t.Errorf("expected 2 files in the state, got %d", n)
+	}
+	if summary, err := e.ErrorSummary(); err != nil || summary.TotalErrors != 0 {
+		t.Errorf("expected no errors, got %+v, %v", summary, err)
+	}
+	e.Close()
+
+	if modes := DetectModes(dest); len(modes) != 1 || modes[0] != ModeMount {
+		t.Errorf("expected a mount backup, got %v", modes)
+	}
+	store, err := OpenState(dest, ModeMount)
+	if err != nil {
+		t.Fatalf("OpenState: %v", err)
+	}
+	if !store.IsDone("/phone/DCIM/b.jpg") {
+		t.Error("expected b.jpg to be recorded as backed up")
+	}
+}
+
+func TestOpenRejectsNetworkDestination(t *testing.T) {
+	_, err := Open("smb://nas.local/backup/Phone", Config{SourcePath: "/phone", Mode: ModeMount})
+	if err == nil || !strings.Contains(err.Error(), "gio mount smb://nas.local/backup/Phone") {
+		t.Errorf("expected a hint to mount the share, got %v", err)
+	}
+	if _, err := os.Stat("smb:"); err == nil {
+		os.RemoveAll("smb:")
+		t.Error("Open created a local smb: directory")
+	}
+}
+
+func TestOpenRejectsUnknownMode(t *testing.T) {
+	if _, err := Open(t.TempDir(), Config{Mode: "ftp"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Desktop shows events as desktop notifications (notify-send on Linux, osascript on macOS)
+type Desktop struct{}
+
+// Notify pops up ev; failures come back as errors (no notification daemon, unsupported OS)
+func (Desktop) Notify(ctx context.Context, ev Event) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		urgency := "normal"
+		if ev.Kind == JobFailed || ev.Kind == ConnectionLost || ev.Kind == LowDisk {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=GusSync", "--urgency="+urgency, ev.Title, ev.Message)
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", ev.Message, ev.Title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("desktop notification: %v: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends each event as a plain-text mail over SMTP (STARTTLS when the server offers it)
+type Email struct {
+	Server   string // host:port, e.g. smtp.example.com:587
+	Username string // empty = no authentication
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify mails ev to all recipients
+func (m Email) Notify(ctx context.Context, ev Event) error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("email: no recipients")
+	}
+	host, _, err := net.SplitHostPort(m.Server)
+	if err != nil {
+		return fmt.Errorf("email: invalid server %q: %w", m.Server, err)
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	// smtp.SendMail has no context; run it aside so ctx still bounds the wait
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Server, auth, m.from(), m.To, m.message(ev))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("email: %w", ctx.Err())
+	}
+}
+
+func (m Email) from() string {
+	if m.From != "" {
+		return m.From
+	}
+	return m.Username
+}
+
+// message builds the RFC 5322 mail for ev
+func (m Email) message(ev Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from())
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: [GusSync] %s\r\n", ev.Title)
+	fmt.Fprintf(&b, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(ev.Message + "\r\n")
+	if ev.Source != "" {
+		fmt.Fprintf(&b, "\r\nSource: %s\r\n", ev.Source)
+	}
+	if ev.Dest != "" {
+		fmt.Fprintf(&b, "Destination: %s\r\n", ev.Dest)
+	}
+	return []byte(b.String())
+}
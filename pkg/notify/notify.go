@@ -0,0 +1,74 @@
+// Package notify tells the user about backup outcomes outside the terminal or window:
+// desktop notifications, webhooks and email. The CLI, daemon and GUI share it.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kind identifies what happened
+type Kind string
+
+const (
+	JobComplete    Kind = "job_complete"
+	JobFailed      Kind = "job_failed"
+	ConnectionLost Kind = "connection_lost"
+	LowDisk        Kind = "low_disk"
+)
+
+// Event is one notification
+type Event struct {
+	Kind    Kind      `json:"kind"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Source  string    `json:"source,omitempty"` // backup source path
+	Dest    string    `json:"dest,omitempty"`   // backup destination path
+	Time    time.Time `json:"time"`
+}
+
+// Notifier delivers events
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
+
+// Func adapts a function to Notifier
+type Func func(ctx context.Context, ev Event) error
+
+// Notify calls f
+func (f Func) Notify(ctx context.Context, ev Event) error {
+	return f(ctx, ev)
+}
+
+// Multi sends every event to all notifiers; one failing doesn't stop the others
+type Multi []Notifier
+
+// Notify delivers ev to each notifier and joins their errors
+func (m Multi) Notify(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send fills in the event time and delivers ev with a timeout, so a slow webhook or mail
+// server can't hold up a backup. A nil notifier does nothing.
+func Send(ctx context.Context, n Notifier, ev Event) error {
+	if n == nil {
+		return nil
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, ev); err != nil {
+		return fmt.Errorf("notification failed: %w", err)
+	}
+	return nil
+}
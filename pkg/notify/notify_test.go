@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		if got.Kind == JobFailed {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	hook := Webhook{URL: srv.URL}
+	if err := Send(context.Background(), hook, Event{Kind: JobComplete, Title: "Backup complete", Message: "done"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Kind != JobComplete || got.Title != "Backup complete" || got.Time.IsZero() {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	if err := hook.Notify(context.Background(), Event{Kind: JobFailed}); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("error status should fail the notification, got %v", err)
+	}
+}
+
+func TestMultiDeliversToAll(t *testing.T) {
+	var calls int
+	ok := Func(func(context.Context, Event) error { calls++; return nil })
+	failing := Func(func(context.Context, Event) error { calls++; return errors.New("boom") })
+
+	err := Multi{failing, ok, ok}.Notify(context.Background(), Event{Kind: LowDisk})
+	if calls != 3 {
+		t.Errorf("delivered to %d notifiers, want 3", calls)
+	}
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Multi error = %v, want the failing notifier's error", err)
+	}
+	if err := Send(context.Background(), nil, Event{}); err != nil {
+		t.Errorf("nil notifier: %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	m := Email{Server: "smtp.example.com:587", Username: "me@example.com", To: []string{"a@example.com", "b@example.com"}}
+	msg := string(m.message(Event{
+		Title:   "Backup failed",
+		Message: "connection lost",
+		Source:  "/sdcard",
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}))
+	for _, want := range []string{
+		"From: me@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: [GusSync] Backup failed\r\n",
+		"\r\n\r\nconnection lost\r\n",
+		"Source: /sdcard\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+	if err := (Email{Server: "smtp.example.com:587"}).Notify(context.Background(), Event{}); err == nil {
+		t.Error("email without recipients should fail")
+	}
+}
@@ -0,0 +1,209 @@
+// Package profile stores named backup presets shared by the CLI and the GUI.
+// Profiles live in ~/.gussync/profiles.json.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Profile is a named backup preset
+type Profile struct {
+	Name         string   `json:"name"`
+	DeviceSerial string   `json:"deviceSerial,omitempty"` // ADB serial or MTP host id used to find the device
+	SourcePath   string   `json:"sourcePath,omitempty"`   // Source root (mount path or Android path); resolved from DeviceSerial if empty
+	ScanRoots    []string `json:"scanRoots,omitempty"`    // Folders relative to SourcePath; empty = everything
+	Destination  string   `json:"destination"`
+	Mode         string   `json:"mode"` // "mount" or "adb"
+	Excludes     []string `json:"excludes,omitempty"`
+	Only         []string `json:"only,omitempty"`       // Media presets ("photos", "videos", ...); empty = every file
+	Apps         []string `json:"apps,omitempty"`       // Messenger apps backed up whole ("whatsapp", ...), see engine.AppPresets
+	PhoneData    []string `json:"phoneData,omitempty"`  // adb mode: contacts, sms and/or calls to export, see engine.PhoneDataKinds
+	APKs         bool     `json:"apks,omitempty"`       // adb mode: back up the installed apps' APKs too
+	Priority     []string `json:"priority,omitempty"`   // Folders scanned first, in order; empty = the built-in order
+	NoPriority   bool     `json:"noPriority,omitempty"` // Scan the folders in their usual order, none first
+	Workers      int      `json:"workers,omitempty"`
+	Bandwidth    string   `json:"bandwidth,omitempty"`  // Bandwidth schedule, see engine.ParseBandwidthSchedule
+	PreBackup    string   `json:"preBackup,omitempty"`  // Shell command run before the backup (see engine.Hooks)
+	PostBackup   string   `json:"postBackup,omitempty"` // Shell command run after the backup
+}
+
+// Validate checks that a profile can drive a backup
+func (p Profile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if strings.ContainsAny(p.Name, "/\\") {
+		return fmt.Errorf("profile name %q must not contain path separators", p.Name)
+	}
+	if p.Destination == "" {
+		return fmt.Errorf("profile %q: destination is required", p.Name)
+	}
+	if p.Mode != "mount" && p.Mode != "adb" {
+		return fmt.Errorf("profile %q: mode must be 'mount' or 'adb'", p.Name)
+	}
+	if p.SourcePath == "" && p.DeviceSerial == "" {
+		return fmt.Errorf("profile %q: source path or device serial is required", p.Name)
+	}
+	return nil
+}
+
+// ResolveSource returns the source root for the profile. In mount mode with no
+// explicit source, the gvfs mount matching DeviceSerial is located.
+func (p Profile) ResolveSource() (string, error) {
+	if p.SourcePath != "" {
+		return p.SourcePath, nil
+	}
+	if p.Mode == "adb" {
+		return "/sdcard", nil
+	}
+
+	gvfsPath := filepath.Join("/run/user", fmt.Sprint(os.Getuid()), "gvfs")
+	entries, err := os.ReadDir(gvfsPath)
+	if err != nil {
+		return "", fmt.Errorf("no gvfs mounts available: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if (strings.HasPrefix(name, "mtp:") || strings.HasPrefix(name, "gphoto2:")) && strings.Contains(name, p.DeviceSerial) {
+			return filepath.Join(gvfsPath, name), nil
+		}
+	}
+	return "", fmt.Errorf("device %s is not mounted", p.DeviceSerial)
+}
+
+// Store loads and saves profiles
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// DefaultPath returns ~/.gussync/profiles.json
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".gussync", "profiles.json"), nil
+}
+
+// NewStore creates a store backed by the given file
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// OpenDefault creates a store at DefaultPath
+func OpenDefault() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(path), nil
+}
+
+// List returns all profiles sorted by name
+func (s *Store) List() ([]Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Get returns a profile by name
+func (s *Store) Get(name string) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return Profile{}, err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found", name)
+	}
+	return p, nil
+}
+
+// Save creates or replaces a profile
+func (s *Store) Save(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	profiles[p.Name] = p
+	return s.write(profiles)
+}
+
+// Delete removes a profile
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	delete(profiles, name)
+	return s.write(profiles)
+}
+
+func (s *Store) load() (map[string]Profile, error) {
+	profiles := make(map[string]Profile)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var list []Profile
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	for _, p := range list {
+		profiles[p.Name] = p
+	}
+	return profiles, nil
+}
+
+func (s *Store) write(profiles map[string]Profile) error {
+	list := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	// Write atomically so a crash never leaves a truncated profiles file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
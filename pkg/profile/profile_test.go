@@ -0,0 +1,42 @@
+package profile
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "profiles.json"))
+
+	p := Profile{
+		Name:        "pixel7",
+		SourcePath:  "/sdcard",
+		Destination: "/backups",
+		Mode:        "adb",
+		ScanRoots:   []string{"DCIM"},
+		Excludes:    []string{"*.tmp"},
+		Workers:     3,
+	}
+	if err := s.Save(p); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := s.Get("pixel7")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Workers != 3 || len(got.ScanRoots) != 1 || got.Excludes[0] != "*.tmp" {
+		t.Errorf("unexpected profile: %+v", got)
+	}
+
+	if err := s.Save(Profile{Name: "bad", Mode: "ftp", Destination: "/x", SourcePath: "/y"}); err == nil {
+		t.Error("expected validation error for invalid mode")
+	}
+
+	if err := s.Delete("pixel7"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if list, _ := s.List(); len(list) != 0 {
+		t.Errorf("expected no profiles, got %d", len(list))
+	}
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// APK is an app's package file backed up from the device (see engine.EngineConfig.APKs)
+type APK struct {
+	Package string    `json:"package"`           // e.g. "org.example.app"
+	Version string    `json:"version,omitempty"` // versionCode ("" = unknown)
+	Source  string    `json:"source"`            // where it was installed on the device
+	File    string    `json:"file"`              // the copy, relative to the destination root
+	Hash    string    `json:"hash"`              // SHA256 of the copy
+	Size    int64     `json:"size"`
+	At      time.Time `json:"at"` // when it was copied
+}
+
+// Pattern for APKs (later lines win):
+// - [apk] <package> | Version: <code or -> | Source: <path> | File: <relPath> | Hash: <sha256> | Size: <n> | At: <RFC3339>
+var apkPattern = regexp.MustCompile(`^\s*-\s+\[apk\]\s+(\S+)\s*\|\s*Version:\s*(\S+)\s*\|\s*Source:\s*(.+?)\s*\|\s*File:\s*(.+?)\s*\|\s*Hash:\s*([a-f0-9]+)\s*\|\s*Size:\s*(\d+)\s*\|\s*At:\s*(\S+)\s*$`)
+
+// parseAPKLine parses an APK line of the state file
+func parseAPKLine(line string) (APK, bool) {
+	matches := apkPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return APK{}, false
+	}
+	a := APK{Package: matches[1], Version: matches[2], Source: matches[3], File: matches[4], Hash: matches[5]}
+	if a.Version == "-" {
+		a.Version = ""
+	}
+	a.Size, _ = strconv.ParseInt(matches[6], 10, 64)
+	a.At, _ = time.Parse(time.RFC3339, matches[7])
+	return a, true
+}
+
+func (a APK) line() string {
+	version := a.Version
+	if version == "" {
+		version = "-"
+	}
+	return "- [apk] " + a.Package + " | Version: " + version + " | Source: " + a.Source + " | File: " + a.File +
+		" | Hash: " + a.Hash + " | Size: " + strconv.FormatInt(a.Size, 10) + " | At: " + a.At.UTC().Format(time.RFC3339)
+}
+
+// MarkAPK records the backup of an APK, replacing the record of an earlier copy to the same
+// file. APKs are kept apart from the files of the backup: they aren't on the source, so
+// there is nothing to clean up.
+func (sm *StateManager) MarkAPK(a APK) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.apkMap[a.File] = a
+	return sm.writeLine(a.line())
+}
+
+// BackedUpAPK returns the record of the APK copied to file (relative to the destination root)
+func (sm *StateManager) BackedUpAPK(file string) (APK, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	a, ok := sm.apkMap[file]
+	return a, ok
+}
+
+// APKs returns the recorded APKs, by file
+func (sm *StateManager) APKs() []APK {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	apks := make([]APK, 0, len(sm.apkMap))
+	for _, a := range sm.apkMap {
+		apks = append(apks, a)
+	}
+	sort.Slice(apks, func(i, j int) bool { return apks[i].File < apks[j].File })
+	return apks
+}
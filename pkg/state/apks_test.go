@@ -0,0 +1,52 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAPKs(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	old := APK{Package: "org.example.app", Version: "41", Source: "/data/app/~~a==/org.example.app-b==/base.apk",
+		File: "APKs/org.example.app/base.apk", Hash: "aa11", Size: 1000, At: at}
+	updated := old
+	updated.Version, updated.Source, updated.Hash, updated.At = "42", "/data/app/~~c==/org.example.app-d==/base.apk", "bb22", at.Add(time.Hour)
+	split := APK{Package: "org.example.app", Source: "/data/app/org.example.app-1/split_config.en.apk",
+		File: "APKs/org.example.app/split_config.en.apk", Hash: "cc33", Size: 10, At: at}
+	for _, a := range []APK{old, updated, split} {
+		if err := sm.MarkAPK(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm.Close()
+
+	reopened, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.BackedUpAPK(old.File); !ok || got != updated {
+		t.Errorf("BackedUpAPK = %+v, %v; want the last copy recorded", got, ok)
+	}
+	if reopened.GetStats() != 0 {
+		t.Error("APKs were counted as backed-up files")
+	}
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	reopened.Close()
+
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := compacted.APKs(), []APK{updated, split}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after Compact: APKs = %+v, want %+v", got, want)
+	}
+}
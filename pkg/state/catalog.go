@@ -0,0 +1,186 @@
+package state
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCatalogLimit is the page size of Catalog when the query sets none
+const DefaultCatalogLimit = 100
+
+// CatalogQuery selects backed-up files for Catalog. Folders and paths are relative to the
+// backup, with forward slashes: the normalized phone paths (see MarkDone).
+type CatalogQuery struct {
+	Folder     string    `json:"folder,omitempty"`     // folder to list ("" = the top of the backup)
+	Recursive  bool      `json:"recursive,omitempty"`  // list the files of all subfolders too
+	Name       string    `json:"name,omitempty"`       // part of the file name, any case
+	Extensions []string  `json:"extensions,omitempty"` // e.g. "jpg" or ".MP4", any case
+	After      time.Time `json:"after"`                // backed up at or after (zero = any time)
+	Before     time.Time `json:"before"`               // backed up before (zero = any time)
+	Offset     int       `json:"offset,omitempty"`
+	Limit      int       `json:"limit,omitempty"` // 0 = DefaultCatalogLimit
+}
+
+// searching reports whether the query filters files; a search covers the subfolders of
+// Folder and lists no folders
+func (q CatalogQuery) searching() bool {
+	return q.Name != "" || len(q.Extensions) > 0 || !q.After.IsZero() || !q.Before.IsZero()
+}
+
+// CatalogEntry is one backed-up file
+type CatalogEntry struct {
+	Name              string    `json:"name"`
+	Path              string    `json:"path"` // e.g. "DCIM/Camera/IMG_0001.jpg"
+	SourcePath        string    `json:"sourcePath"`
+	Hash              string    `json:"hash,omitempty"`
+	Size              int64     `json:"size,omitempty"`   // bytes (0 if unknown: backed up before sizes were recorded)
+	Volume            string    `json:"volume,omitempty"` // phone volume ID ("" if unknown)
+	BackedUpAt        time.Time `json:"backedUpAt"`       // zero if unknown
+	VerifiedAt        time.Time `json:"verifiedAt"`       // zero if never verified
+	DeletedFromSource bool      `json:"deletedFromSource,omitempty"`
+}
+
+// CatalogFolder is a subfolder of the listed folder
+type CatalogFolder struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Files int    `json:"files"` // in the folder and all its subfolders
+}
+
+// CatalogPage is one page of Catalog's result. Folders are listed in full on every page;
+// Offset, Limit and Total count files.
+type CatalogPage struct {
+	Folder  string          `json:"folder"`
+	Folders []CatalogFolder `json:"folders"`
+	Files   []CatalogEntry  `json:"files"`
+	Total   int             `json:"total"` // matching files on all pages
+	Offset  int             `json:"offset"`
+	Limit   int             `json:"limit"`
+}
+
+// Catalog lists the backed-up files of a folder, or searches them, from the state alone:
+// the subfolders of q.Folder and a page of its files (of its whole tree when recursive or
+// searching), sorted by path
+func (sm *StateManager) Catalog(q CatalogQuery) CatalogPage {
+	page := CatalogPage{
+		Folder:  strings.Trim(path.Clean("/"+filepath.ToSlash(q.Folder)), "/"),
+		Folders: []CatalogFolder{},
+		Files:   []CatalogEntry{},
+		Offset:  max(q.Offset, 0),
+		Limit:   q.Limit,
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultCatalogLimit
+	}
+	prefix := ""
+	if page.Folder != "" {
+		prefix = page.Folder + "/"
+	}
+	name := strings.ToLower(q.Name)
+	extensions := make(map[string]bool)
+	for _, ext := range q.Extensions {
+		if ext = strings.ToLower(strings.TrimPrefix(ext, ".")); ext != "" {
+			extensions["."+ext] = true
+		}
+	}
+	search := q.searching()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	type match struct{ path, sourcePath string }
+	var matches []match
+	folders := make(map[string]int)
+	for sourcePath, hash := range sm.stateMap {
+		p := sm.catalogPath(sourcePath, hash)
+		rest, ok := strings.CutPrefix(p, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if !search && !q.Recursive {
+			if sub, _, nested := strings.Cut(rest, "/"); nested {
+				folders[sub]++
+				continue
+			}
+		}
+		if search {
+			base := strings.ToLower(path.Base(p))
+			doneAt := sm.doneAtMap[sourcePath]
+			if !strings.Contains(base, name) ||
+				len(extensions) > 0 && !extensions[path.Ext(base)] ||
+				!q.After.IsZero() && doneAt.Before(q.After) ||
+				!q.Before.IsZero() && (doneAt.IsZero() || !doneAt.Before(q.Before)) {
+				continue
+			}
+		}
+		matches = append(matches, match{p, sourcePath})
+	}
+
+	for sub, count := range folders {
+		page.Folders = append(page.Folders, CatalogFolder{Name: sub, Path: prefix + sub, Files: count})
+	}
+	sort.Slice(page.Folders, func(i, j int) bool { return page.Folders[i].Name < page.Folders[j].Name })
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].path != matches[j].path {
+			return matches[i].path < matches[j].path
+		}
+		return matches[i].sourcePath < matches[j].sourcePath
+	})
+	page.Total = len(matches)
+	for _, m := range matches[min(page.Offset, len(matches)):min(page.Offset+page.Limit, len(matches))] {
+		page.Files = append(page.Files, sm.catalogEntry(m.path, m.sourcePath))
+	}
+	return page
+}
+
+// CatalogFiles returns the backed-up files at a catalog path: the file itself, or every file
+// in the folder and its subfolders, sorted by path
+func (sm *StateManager) CatalogFiles(p string) []CatalogEntry {
+	p = strings.Trim(path.Clean("/"+filepath.ToSlash(p)), "/")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var files []CatalogEntry
+	for sourcePath, hash := range sm.stateMap {
+		entryPath := sm.catalogPath(sourcePath, hash)
+		if p == "" || entryPath == p || strings.HasPrefix(entryPath, p+"/") {
+			files = append(files, sm.catalogEntry(entryPath, sourcePath))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Path != files[j].Path {
+			return files[i].Path < files[j].Path
+		}
+		return files[i].SourcePath < files[j].SourcePath
+	})
+	return files
+}
+
+// catalogEntry describes a completed file; the caller holds sm.mu
+func (sm *StateManager) catalogEntry(p, sourcePath string) CatalogEntry {
+	_, deleted := sm.deletedMap[sourcePath]
+	return CatalogEntry{
+		Name:              path.Base(p),
+		Path:              p,
+		SourcePath:        sourcePath,
+		Hash:              sm.stateMap[sourcePath],
+		Size:              sm.sizeMap[sourcePath],
+		Volume:            sm.volumeMap[sourcePath],
+		BackedUpAt:        sm.doneAtMap[sourcePath],
+		VerifiedAt:        sm.verifiedMap[sourcePath],
+		DeletedFromSource: deleted,
+	}
+}
+
+// catalogPath is where a completed file is in the backup: its normalized path, or for old
+// entries without one its source path; the caller holds sm.mu
+func (sm *StateManager) catalogPath(sourcePath, hash string) string {
+	if p := sm.hashMap[hash]; p != "" {
+		return strings.TrimPrefix(p, "/")
+	}
+	return strings.TrimPrefix(filepath.ToSlash(sourcePath), "/")
+}
@@ -0,0 +1,73 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCatalog(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range []string{"DCIM/Camera/IMG_1.jpg", "DCIM/Camera/VID_2.mp4", "DCIM/Screenshots/shot.png", "Download/manual.pdf", "notes.txt"} {
+		sm.MarkCompleted(CompletedFile{SourcePath: "/sdcard/" + p, Hash: "hash-" + p, NormalizedPath: p, Size: int64(100 + i)})
+	}
+	sm.MarkVerified("/sdcard/DCIM/Camera/IMG_1.jpg", time.Now())
+	sm.MarkDeleted("/sdcard/DCIM/Camera/IMG_1.jpg", "hash-DCIM/Camera/IMG_1.jpg")
+	sm.Close()
+
+	// Sizes survive a reload
+	sm, err = OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := sm.Catalog(CatalogQuery{})
+	if len(top.Folders) != 2 || top.Folders[0] != (CatalogFolder{Name: "DCIM", Path: "DCIM", Files: 3}) || top.Folders[1].Name != "Download" {
+		t.Errorf("top folders = %+v", top.Folders)
+	}
+	if top.Total != 1 || top.Files[0].Path != "notes.txt" || top.Files[0].Size != 104 {
+		t.Errorf("top files = %+v", top.Files)
+	}
+
+	camera := sm.Catalog(CatalogQuery{Folder: "/DCIM/Camera/", Limit: 1})
+	if camera.Total != 2 || len(camera.Files) != 1 || len(camera.Folders) != 0 {
+		t.Fatalf("camera page = %+v", camera)
+	}
+	img := camera.Files[0]
+	if img.Name != "IMG_1.jpg" || img.Size != 100 || img.BackedUpAt.IsZero() || img.VerifiedAt.IsZero() || !img.DeletedFromSource {
+		t.Errorf("IMG_1.jpg = %+v", img)
+	}
+	if next := sm.Catalog(CatalogQuery{Folder: "DCIM/Camera", Offset: 1, Limit: 1}); len(next.Files) != 1 || next.Files[0].Name != "VID_2.mp4" {
+		t.Errorf("second page = %+v", next.Files)
+	}
+
+	for _, tc := range []struct {
+		query CatalogQuery
+		want  int
+	}{
+		{CatalogQuery{Folder: "DCIM", Recursive: true}, 3},
+		{CatalogQuery{Name: "img"}, 1},
+		{CatalogQuery{Extensions: []string{".JPG", "png"}}, 2},
+		{CatalogQuery{Folder: "Download", Extensions: []string{"jpg"}}, 0},
+		{CatalogQuery{After: time.Now().Add(-time.Hour)}, 5},
+		{CatalogQuery{Before: time.Now().Add(-time.Hour)}, 0},
+	} {
+		if got := sm.Catalog(tc.query); got.Total != tc.want || len(got.Folders) != 0 {
+			t.Errorf("%+v: %d files, %d folders; want %d files", tc.query, got.Total, len(got.Folders), tc.want)
+		}
+	}
+
+	if files := sm.CatalogFiles("DCIM/Camera/IMG_1.jpg"); len(files) != 1 || files[0].SourcePath != "/sdcard/DCIM/Camera/IMG_1.jpg" {
+		t.Errorf("CatalogFiles(file) = %+v", files)
+	}
+	if files := sm.CatalogFiles("DCIM/"); len(files) != 3 || files[2].Name != "shot.png" {
+		t.Errorf("CatalogFiles(folder) = %+v", files)
+	}
+	if files := sm.CatalogFiles("DCIM/Cam"); len(files) != 0 {
+		t.Errorf("a partial folder name should match nothing, got %+v", files)
+	}
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CompactStats describes the result of a state file compaction
+type CompactStats struct {
+	LinesBefore int    `json:"linesBefore"`
+	LinesAfter  int    `json:"linesAfter"`
+	BackupPath  string `json:"backupPath"` // copy of the file as it was before compacting
+}
+
+// Compact rewrites the state file from the in-memory maps: one line per file and status,
+// latest value only. Failure and cleanup-failure counts for files that later succeeded are
+// dropped. The old file is kept as <stateFile>.<timestamp>.bak and the new one is swapped
+// in with an atomic rename, so a crash leaves either the old or the new file in place.
+//
+// Legacy "completed" directory lines without a recorded listing are already discarded on
+// load (they force a rescan anyway), so they do not survive compaction.
+func (sm *StateManager) Compact() (CompactStats, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var stats CompactStats
+	if err := sm.writer.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to flush state file: %w", err)
+	}
+
+	// Back up the current file (copy, not rename, so the state file never disappears)
+	stats.BackupPath = fmt.Sprintf("%s.%s.bak", sm.stateFile, time.Now().Format("20060102-150405"))
+	lines, err := copyCountingLines(sm.stateFile, stats.BackupPath)
+	if err != nil {
+		return stats, fmt.Errorf("failed to back up state file: %w", err)
+	}
+	stats.LinesBefore = lines
+
+	tmpFile := sm.stateFile + ".compact.tmp"
+	lines, err = sm.writeCompacted(tmpFile)
+	if err != nil {
+		os.Remove(tmpFile)
+		return stats, err
+	}
+	stats.LinesAfter = lines
+
+	// Windows refuses to rename over an open file
+	if err := sm.fileHandle.Close(); err != nil {
+		os.Remove(tmpFile)
+		return stats, fmt.Errorf("failed to close state file: %w", err)
+	}
+	renameErr := os.Rename(tmpFile, sm.stateFile)
+	if renameErr != nil {
+		os.Remove(tmpFile)
+	}
+
+	// Reopen for appending whether or not the swap worked, so the manager stays usable
+	sm.fileHandle, err = os.OpenFile(sm.stateFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return stats, fmt.Errorf("failed to reopen state file: %w", err)
+	}
+	sm.writer = bufio.NewWriter(sm.fileHandle)
+
+	if renameErr != nil {
+		return stats, fmt.Errorf("failed to replace state file: %w", renameErr)
+	}
+	return stats, nil
+}
+
+// writeCompacted writes the current state to path (fsynced) and returns the number of lines written
+func (sm *StateManager) writeCompacted(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create compacted state file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	lines := 0
+	writeLine := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format, args...)
+		lines++
+	}
+
+	if sm.totals.Runs > 0 {
+		writeLine("%s\n", sm.totals.line())
+	}
+	if sm.destNames != "" {
+		writeLine("%s\n", destNamesLine(sm.destNames, sm.destNFC))
+	}
+	for _, path := range sortedKeys(sm.nfcCollisions) {
+		writeLine("- [nfc] %s\n", path)
+	}
+	for _, path := range sortedKeys(sm.symlinkMap) {
+		writeLine("%s\n", symlinkLine(path, sm.symlinkMap[path]))
+	}
+	for _, kind := range sortedKeys(sm.exportMap) {
+		writeLine("%s\n", sm.exportMap[kind].line())
+	}
+	for _, file := range sortedKeys(sm.apkMap) {
+		writeLine("%s\n", sm.apkMap[file].line())
+	}
+	for _, root := range sortedKeys(sm.sourceRootMap) {
+		writeLine("%s\n", SourceRoot{Path: root, Dest: sm.sourceRootMap[root]}.line())
+	}
+	for _, dir := range sortedKeys(sm.dirMap) {
+		writeLine("- [dir] %s | Status: %s\n", dir, sm.dirMap[dir])
+	}
+	for _, dir := range sortedKeys(sm.dirListingMap) {
+		listing := sm.dirListingMap[dir]
+		writeLine("- [dl] %s | Files: %d | Listing: %s\n", dir, listing.Files, listing.Hash)
+	}
+	for _, dir := range sortedKeys(sm.dirStampMap) {
+		stamp := sm.dirStampMap[dir]
+		writeLine("- [dm] %s | MTime: %d | Size: %d | Subdirs: %s\n", dir, stamp.MTime, stamp.Size, strings.Join(stamp.Subdirs, "/"))
+	}
+
+	// Completed files, in the newest format the entry has enough information for
+	referenced := make(map[string]bool, len(sm.stateMap))
+	destPaths := make(map[string]bool, len(sm.stateMap))
+	for _, path := range sortedKeys(sm.stateMap) {
+		hash := sm.stateMap[path]
+		referenced[hash] = true
+		normalized := sm.hashMap[hash]
+		destPaths[normalized] = true
+		switch {
+		case hash == "":
+			writeLine("- [x] %s\n", path)
+		case normalized == "":
+			writeLine("- [x] %s | Hash: %s\n", path, hash)
+		default:
+			line := fmt.Sprintf("- [x] Hash: %s | Path: %s | SourcePath: %s", hash, normalized, path)
+			if doneAt, ok := sm.doneAtMap[path]; ok {
+				line += " | Completed: " + doneAt.UTC().Format(time.RFC3339)
+				if volume := sm.volumeMap[path]; volume != "" {
+					line += " | Volume: " + volume
+				}
+				if size := sm.sizeMap[path]; size > 0 {
+					line += fmt.Sprintf(" | Size: %d", size)
+				}
+			}
+			writeLine("%s\n", line)
+		}
+	}
+	// Hash entries written without a source path, unless a newer copy replaced that destination file
+	for _, hash := range sortedKeys(sm.hashMap) {
+		if !referenced[hash] && sm.hashMap[hash] != "" && !destPaths[sm.hashMap[hash]] {
+			writeLine("- [x] Hash: %s | Path: %s\n", hash, sm.hashMap[hash])
+		}
+	}
+
+	for _, path := range sortedKeys(sm.failureMap) {
+		if _, done := sm.stateMap[path]; !done {
+			writeLine("- [ ] %s | Failures: %d\n", path, sm.failureMap[path])
+		}
+	}
+	for _, path := range sortedKeys(sm.convertedMap) {
+		writeLine("- [t] %s | Converted: %s\n", path, sm.convertedMap[path])
+	}
+	for _, path := range sortedKeys(sm.verifiedMap) {
+		writeLine("- [v] %s | Verified: %s\n", path, sm.verifiedMap[path].UTC().Format(time.RFC3339))
+	}
+	for _, path := range sortedKeys(sm.destHashMap) {
+		if _, done := sm.stateMap[path]; done {
+			writeLine("%s\n", destHashLine(path, sm.destHashMap[path]))
+		}
+	}
+	for _, path := range sortedKeys(sm.quarantineMap) {
+		entry := sm.quarantineMap[path]
+		writeLine("- [q] %s | Quarantined: %s | Reason: %s | At: %s\n", path, entry.Path, entry.Reason, entry.At.UTC().Format(time.RFC3339))
+	}
+	for _, path := range sortedKeys(sm.deletedMap) {
+		line := fmt.Sprintf("- [d] %s | Hash: %s", path, sm.deletedMap[path])
+		if at := sm.deletedAtMap[path]; at != "" {
+			line += " | Deleted: " + at
+		}
+		writeLine("%s\n", line)
+	}
+	for _, path := range sortedKeys(sm.cleanupFailureMap) {
+		if _, deleted := sm.deletedMap[path]; !deleted {
+			writeLine("- [c] %s | CleanupFailures: %d\n", path, sm.cleanupFailureMap[path])
+		}
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write compacted state file: %w", err)
+	}
+	return lines, nil
+}
+
+// copyCountingLines copies src to dst and returns the number of lines in src
+func copyCountingLines(src, dst string) (int, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	counter := &lineCounter{}
+	_, err = io.Copy(io.MultiWriter(out, counter), in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return 0, err
+	}
+	return counter.lines, nil
+}
+
+type lineCounter struct {
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' {
+			c.lines++
+		}
+	}
+	return len(p), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkSuccess()
+
+	// A file that failed twice and then succeeded, one that keeps failing, and a re-copy
+	sm.RecordFailure("/sdcard/DCIM/a.jpg")
+	sm.RecordFailure("/sdcard/DCIM/a.jpg")
+	sm.MarkDone("/sdcard/DCIM/a.jpg", "hash-a", "DCIM/a.jpg")
+	sm.RecordFailure("/sdcard/DCIM/broken.jpg")
+	sm.MarkDone("/sdcard/DCIM/b.jpg", "hash-b-old", "DCIM/b.jpg")
+	sm.MarkDone("/sdcard/DCIM/b.jpg", "hash-b", "DCIM/b.jpg")
+	sm.MarkDirStatus("/sdcard/DCIM", "timeout")
+	sm.MarkDirStatus("/sdcard/DCIM", "partial")
+	sm.MarkVerified("/sdcard/DCIM/a.jpg", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	sm.MarkVerified("/sdcard/DCIM/a.jpg", time.Date(2026, 2, 2, 3, 4, 5, 0, time.UTC))
+	sm.MarkQuarantined("/sdcard/DCIM/b.jpg", "_quarantine/DCIM/b.jpg", "hash mismatch")
+	sm.RecordCleanupFailure("/sdcard/DCIM/a.jpg")
+	sm.MarkDeleted("/sdcard/DCIM/a.jpg", "hash-a")
+	sm.MarkConverted("/sdcard/DCIM/c.heic", "_converted/DCIM/c.jpg")
+
+	stats, err := sm.Compact()
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if stats.LinesBefore != 14 || stats.LinesAfter != 8 {
+		t.Errorf("lines before/after = %d/%d, want 14/8", stats.LinesBefore, stats.LinesAfter)
+	}
+	if backup, err := os.ReadFile(stats.BackupPath); err != nil || strings.Count(string(backup), "\n") != 14 {
+		t.Errorf("backup not kept intact: %v", err)
+	}
+
+	// The manager keeps appending to the new file
+	if err := sm.MarkDone("/sdcard/DCIM/d.jpg", "hash-d", "DCIM/d.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	sm.Close()
+
+	data, _ := os.ReadFile(stateFile)
+	if strings.Contains(string(data), "Failures: 2") || strings.Contains(string(data), "hash-b-old") || strings.Contains(string(data), "CleanupFailures") {
+		t.Errorf("superseded lines survived compaction:\n%s", data)
+	}
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if sm2.GetStats() != 3 || !sm2.IsDoneByHash("hash-b") || !sm2.IsDoneByHash("hash-d") {
+		t.Errorf("completed files lost: %v", sm2.GetAllCompletedFiles())
+	}
+	if sm2.BackedUpAt("/sdcard/DCIM/a.jpg").IsZero() {
+		t.Errorf("completion time lost")
+	}
+	if !sm2.ShouldRetry("/sdcard/DCIM/broken.jpg") || sm2.failureMap["/sdcard/DCIM/broken.jpg"] != 1 {
+		t.Errorf("pending failure lost")
+	}
+	if got := sm2.GetDirStatus("/sdcard/DCIM"); got != "partial" {
+		t.Errorf("dir status = %q, want partial", got)
+	}
+	if got := sm2.LastVerified("/sdcard/DCIM/a.jpg"); !got.Equal(time.Date(2026, 2, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("last verified = %v", got)
+	}
+	if q := sm2.GetQuarantined(); len(q) != 1 || q[0].Reason != "hash mismatch" {
+		t.Errorf("quarantine = %+v", q)
+	}
+	if !sm2.IsDeleted("/sdcard/DCIM/a.jpg") || sm2.deletedAtMap["/sdcard/DCIM/a.jpg"] == "" {
+		t.Errorf("deletion lost")
+	}
+	if sm2.GetConvertedPath("/sdcard/DCIM/c.heic") != "_converted/DCIM/c.jpg" {
+		t.Errorf("converted copy lost")
+	}
+}
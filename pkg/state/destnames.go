@@ -0,0 +1,69 @@
+package state
+
+import "regexp"
+
+// Pattern for the destination name scheme (later lines win): - [names] <scheme> [| Unicode: NFC]
+var destNamesPattern = regexp.MustCompile(`^\s*-\s+\[names\]\s+(\S+)\s*(?:\|\s*Unicode:\s*(NFC)\s*)?$`)
+
+// Pattern for names renamed to keep them apart from another file with the same NFC name:
+// - [nfc] <sourcePath>
+var nfcCollisionPattern = regexp.MustCompile(`^\s*-\s+\[nfc\]\s+(.+?)\s*$`)
+
+// parseDestNamesLine parses a destination name scheme line of the state file
+func parseDestNamesLine(line string) (scheme string, nfc bool, ok bool) {
+	matches := destNamesPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return "", false, false
+	}
+	return matches[1], matches[2] != "", true
+}
+
+func destNamesLine(scheme string, nfc bool) string {
+	if nfc {
+		return "- [names] " + scheme + " | Unicode: NFC"
+	}
+	return "- [names] " + scheme
+}
+
+// MarkDestNames records how file names are written in the destination: escaped with scheme,
+// and in Unicode NFC if nfc is set. Later runs (and verify, cleanup and restore) use the
+// recorded naming to find the copies. Nothing is written if it is already recorded.
+func (sm *StateManager) MarkDestNames(scheme string, nfc bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.destNames == scheme && sm.destNFC == nfc {
+		return nil
+	}
+	sm.destNames, sm.destNFC = scheme, nfc
+	return sm.writeLine(destNamesLine(scheme, nfc))
+}
+
+// DestNames returns the recorded destination name scheme ("" if none is recorded) and whether
+// names are written in Unicode NFC
+func (sm *StateManager) DestNames() (scheme string, nfc bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.destNames, sm.destNFC
+}
+
+// MarkNFCCollision records that the file or folder at path, whose name is not in Unicode NFC,
+// sits next to one named with the NFC form of that name, so its copy keeps a name of its own.
+// The path is recorded in its original form.
+func (sm *StateManager) MarkNFCCollision(path string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.nfcCollisions[path] {
+		return nil
+	}
+	sm.nfcCollisions[path] = true
+	return sm.writeLine("- [nfc] " + path)
+}
+
+// NFCCollision reports whether MarkNFCCollision recorded path
+func (sm *StateManager) NFCCollision(path string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.nfcCollisions[path]
+}
@@ -0,0 +1,44 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDestNames(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, nfc := sm.DestNames(); got != "" || nfc {
+		t.Errorf("new state: DestNames = %q, %v", got, nfc)
+	}
+	sm.MarkDestNames("unicode", false)
+	sm.MarkDestNames("percent", true)
+	sm.MarkNFCCollision("/phone/Music/Cafe\u0301.mp3")
+	sm.Close()
+
+	reopened, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, nfc := reopened.DestNames(); got != "percent" || !nfc {
+		t.Errorf("DestNames = %q, %v; want the last one recorded", got, nfc)
+	}
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	reopened.Close()
+
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, nfc := compacted.DestNames(); got != "percent" || !nfc {
+		t.Errorf("after Compact: DestNames = %q, %v", got, nfc)
+	}
+	if !compacted.NFCCollision("/phone/Music/Cafe\u0301.mp3") || compacted.NFCCollision("/phone/Music/Caf\u00e9.mp3") {
+		t.Errorf("after Compact: NFC collisions not kept")
+	}
+}
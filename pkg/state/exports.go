@@ -0,0 +1,68 @@
+package state
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Export is the latest export of phone data that isn't a file (contacts, messages, ...)
+// into the destination
+type Export struct {
+	Kind  string    `json:"kind"`  // what was exported, e.g. "contacts"
+	File  string    `json:"file"`  // the export, relative to the destination root
+	Hash  string    `json:"hash"`  // SHA256 of the file
+	Items int       `json:"items"` // contacts, messages or calls in it
+	At    time.Time `json:"at"`    // when it was written
+}
+
+// Pattern for exports (later lines win):
+// - [export] <kind> | File: <relPath> | Hash: <sha256> | Items: <n> | At: <RFC3339>
+var exportPattern = regexp.MustCompile(`^\s*-\s+\[export\]\s+(\S+)\s*\|\s*File:\s*(.+?)\s*\|\s*Hash:\s*([a-f0-9]+)\s*\|\s*Items:\s*(\d+)\s*\|\s*At:\s*(\S+)\s*$`)
+
+// parseExportLine parses an export line of the state file
+func parseExportLine(line string) (Export, bool) {
+	matches := exportPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return Export{}, false
+	}
+	items, _ := strconv.Atoi(matches[4])
+	at, _ := time.Parse(time.RFC3339, matches[5])
+	return Export{Kind: matches[1], File: matches[2], Hash: matches[3], Items: items, At: at}, true
+}
+
+func (e Export) line() string {
+	return "- [export] " + e.Kind + " | File: " + e.File + " | Hash: " + e.Hash +
+		" | Items: " + strconv.Itoa(e.Items) + " | At: " + e.At.UTC().Format(time.RFC3339)
+}
+
+// MarkExport records the latest export of its kind
+func (sm *StateManager) MarkExport(e Export) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.exportMap[e.Kind] = e
+	return sm.writeLine(e.line())
+}
+
+// LastExport returns the export MarkExport last recorded for kind
+func (sm *StateManager) LastExport(kind string) (Export, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	e, ok := sm.exportMap[kind]
+	return e, ok
+}
+
+// Exports returns the latest export of each kind, by kind
+func (sm *StateManager) Exports() []Export {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	exports := make([]Export, 0, len(sm.exportMap))
+	for _, e := range sm.exportMap {
+		exports = append(exports, e)
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].Kind < exports[j].Kind })
+	return exports
+}
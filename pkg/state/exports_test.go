@@ -0,0 +1,52 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExports(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	first := Export{Kind: "sms", File: "Phone data/sms.json", Hash: "aa11", Items: 10, At: at}
+	latest := Export{Kind: "sms", File: "Phone data/sms.json", Hash: "bb22", Items: 12, At: at.Add(time.Hour)}
+	contacts := Export{Kind: "contacts", File: "Phone data/My Contacts | all.vcf", Hash: "cc33", Items: 3, At: at}
+	for _, e := range []Export{first, latest, contacts} {
+		if err := sm.MarkExport(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm.Close()
+
+	reopened, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.LastExport("sms"); !ok || got != latest {
+		t.Errorf("LastExport(sms) = %+v, %v; want the last one recorded", got, ok)
+	}
+	if reopened.GetStats() != 0 {
+		t.Error("exports were counted as backed-up files")
+	}
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	reopened.Close()
+
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := compacted.Exports(), []Export{contacts, latest}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after Compact: Exports = %+v, want %+v", got, want)
+	}
+	if _, ok := compacted.LastExport("calls"); ok {
+		t.Error("LastExport reported an export that was never recorded")
+	}
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// DestHash is the hash of a file's backed-up copy, with the size and modification time the
+// copy had when it was hashed: while both are unchanged, the copy needn't be hashed again
+type DestHash struct {
+	Size  int64
+	MTime int64 // unix nanoseconds
+	Hash  string
+}
+
+// Pattern for cached destination hashes (later lines win):
+// - [h] <sourcePath> | Size: <bytes> | MTime: <unix nanoseconds> | Hash: <sha256>
+var destHashPattern = regexp.MustCompile(`^\s*-\s+\[h\]\s+(.+?)\s*\|\s*Size:\s*(\d+)\s*\|\s*MTime:\s*(-?\d+)\s*\|\s*Hash:\s*([a-f0-9]+)\s*$`)
+
+// parseDestHashLine parses a cached destination hash line of the state file
+func parseDestHashLine(line string) (string, DestHash, bool) {
+	matches := destHashPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return "", DestHash{}, false
+	}
+	h := DestHash{Hash: matches[4]}
+	h.Size, _ = strconv.ParseInt(matches[2], 10, 64)
+	h.MTime, _ = strconv.ParseInt(matches[3], 10, 64)
+	return matches[1], h, true
+}
+
+func destHashLine(sourcePath string, h DestHash) string {
+	return "- [h] " + sourcePath + " | Size: " + strconv.FormatInt(h.Size, 10) + " | MTime: " +
+		strconv.FormatInt(h.MTime, 10) + " | Hash: " + h.Hash
+}
+
+// MarkDestHash caches the hash of the backed-up copy of sourcePath. Nothing is written if the
+// same entry is already cached.
+func (sm *StateManager) MarkDestHash(sourcePath string, h DestHash) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if cached, ok := sm.destHashMap[sourcePath]; ok && cached == h {
+		return nil
+	}
+	sm.destHashMap[sourcePath] = h
+	return sm.writeLine(destHashLine(sourcePath, h))
+}
+
+// CachedDestHash returns the cached hash of the backed-up copy of sourcePath if the copy
+// still has this size and modification time
+func (sm *StateManager) CachedDestHash(sourcePath string, size, mtime int64) (string, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	h, ok := sm.destHashMap[sourcePath]
+	if !ok || h.Size != size || h.MTime != mtime {
+		return "", false
+	}
+	return h.Hash, true
+}
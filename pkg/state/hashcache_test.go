@@ -0,0 +1,46 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDestHashCache(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkCompleted(CompletedFile{SourcePath: "/sdcard/DCIM/a.jpg", Hash: "aa11", NormalizedPath: "DCIM/a.jpg", Size: 10})
+	if err := sm.MarkDestHash("/sdcard/DCIM/a.jpg", DestHash{Size: 10, MTime: 1700000000000000000, Hash: "aa11"}); err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkDestHash("/sdcard/DCIM/gone.jpg", DestHash{Size: 5, MTime: 1, Hash: "bb22"})
+	sm.Close()
+
+	reopened, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, ok := reopened.CachedDestHash("/sdcard/DCIM/a.jpg", 10, 1700000000000000000); !ok || hash != "aa11" {
+		t.Errorf("CachedDestHash = %q, %v", hash, ok)
+	}
+	if _, ok := reopened.CachedDestHash("/sdcard/DCIM/a.jpg", 10, 1700000000000000001); ok {
+		t.Error("a copy with another modification time should not use the cached hash")
+	}
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	reopened.Close()
+
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := compacted.CachedDestHash("/sdcard/DCIM/a.jpg", 10, 1700000000000000000); !ok {
+		t.Error("cached hash lost by Compact")
+	}
+	if _, ok := compacted.CachedDestHash("/sdcard/DCIM/gone.jpg", 5, 1); ok {
+		t.Error("the hash of a file that is not backed up should be dropped by Compact")
+	}
+}
@@ -0,0 +1,32 @@
+package state
+
+import "strings"
+
+// internTable keeps one copy of each path and hash read from the state file. Regexp
+// submatches point into their line, so storing them as they are keeps every line alive, and
+// a path found on several lines (completed, verified, failed, ...) once per line; with
+// millions of files that is most of the memory a loaded state takes. The table is only
+// needed while loading.
+type internTable map[string]string
+
+// get returns the table's copy of s, detached from the line it was read from
+func (t internTable) get(s string) string {
+	if s == "" {
+		return ""
+	}
+	if c, ok := t[s]; ok {
+		return c
+	}
+	c := strings.Clone(s)
+	t[c] = c
+	return c
+}
+
+// suffix returns s as a suffix of of when it is one (a normalized path is usually the end of
+// its source path), so both share memory; otherwise the table's copy of s
+func (t internTable) suffix(s, of string) string {
+	if s != "" && strings.HasSuffix(of, s) {
+		return of[len(of)-len(s):]
+	}
+	return t.get(s)
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+)
+
+func TestLoadStateSharesStrings(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	const source = "/sdcard/DCIM/Camera/IMG_0001.jpg"
+	os.WriteFile(stateFile, []byte(
+		"- [x] Hash: abc123 | Path: DCIM/Camera/IMG_0001.jpg | SourcePath: "+source+" | Completed: 2026-01-02T03:04:05Z\n"+
+			"- [v] "+source+" | Verified: 2026-01-03T03:04:05Z\n"+
+			"- [ ] "+source+" | Failures: 1\n"), 0644)
+
+	sm, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(m map[string]string) string {
+		for k := range m {
+			return k
+		}
+		return ""
+	}
+	completed := key(sm.stateMap)
+	var verified, failed string
+	for k := range sm.verifiedMap {
+		verified = k
+	}
+	for k := range sm.failureMap {
+		failed = k
+	}
+	if completed != source || unsafe.StringData(completed) != unsafe.StringData(verified) || unsafe.StringData(completed) != unsafe.StringData(failed) {
+		t.Errorf("a path read from several lines should be stored once")
+	}
+	if unsafe.StringData(sm.stateMap[source]) != unsafe.StringData(key(sm.hashMap)) {
+		t.Errorf("the hash should be stored once")
+	}
+	normalized := sm.hashMap["abc123"]
+	if normalized != "DCIM/Camera/IMG_0001.jpg" || unsafe.StringData(normalized) != unsafe.StringData(completed[len("/sdcard/"):]) {
+		t.Errorf("the normalized path should share the source path's memory")
+	}
+}
@@ -0,0 +1,33 @@
+package state
+
+import "regexp"
+
+// Pattern for symbolic links recreated in the destination (later lines win):
+// - [link] <sourcePath> | Target: <target>
+var symlinkPattern = regexp.MustCompile(`^\s*-\s+\[link\]\s+(.+?)\s+\|\s+Target:\s+(.+?)\s*$`)
+
+func symlinkLine(path, target string) string {
+	return "- [link] " + path + " | Target: " + target
+}
+
+// MarkSymlink records that the symbolic link at path, pointing to target, was recreated in
+// the destination. Links are kept apart from the files of the backup: they have no content
+// to verify or clean up. Nothing is written if the same target is already recorded.
+func (sm *StateManager) MarkSymlink(path, target string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if recorded, ok := sm.symlinkMap[path]; ok && recorded == target {
+		return nil
+	}
+	sm.symlinkMap[path] = target
+	return sm.writeLine(symlinkLine(path, target))
+}
+
+// Symlink returns the target MarkSymlink recorded for the link at path
+func (sm *StateManager) Symlink(path string) (target string, ok bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	target, ok = sm.symlinkMap[path]
+	return target, ok
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinks(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := "/phone/Android/data/com.app/files/current"
+	sm.MarkSymlink(link, "v1")
+	sm.MarkSymlink(link, "v2")
+	sm.MarkSymlink("/phone/Music/My Songs | old", "../Old Songs")
+	sm.Close()
+
+	reopened, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, ok := reopened.Symlink(link); !ok || target != "v2" {
+		t.Errorf("Symlink = %q, %v; want the last target recorded", target, ok)
+	}
+	if reopened.GetStats() != 0 {
+		t.Errorf("links were counted as backed-up files")
+	}
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	reopened.Close()
+
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := compacted.Symlink(link); target != "v2" {
+		t.Errorf("after Compact: Symlink = %q", target)
+	}
+	if target, _ := compacted.Symlink("/phone/Music/My Songs | old"); target != "../Old Songs" {
+		t.Errorf("after Compact: Symlink with a separator in its path = %q", target)
+	}
+	if _, ok := compacted.Symlink("/phone/DCIM"); ok {
+		t.Errorf("Symlink reported a link that was never recorded")
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the scan manifest kept next to the state file
+const ManifestFileName = "gus_manifest.md"
+
+// ManifestEntry is one file found by a manifest-first scan
+type ManifestEntry struct {
+	SourcePath string
+	RelPath    string // relative to the source root (where the copy goes under the destination)
+	Size       int64  // bytes; 0 if the scanner could not tell (adb)
+}
+
+// Manifest is the complete file list of the last manifest-first scan, in copy order
+type Manifest struct {
+	Entries   []ManifestEntry
+	ScannedAt time.Time
+}
+
+// TotalBytes sums the known file sizes
+func (m Manifest) TotalBytes() int64 {
+	var total int64
+	for _, entry := range m.Entries {
+		total += entry.Size
+	}
+	return total
+}
+
+// ManifestPath returns where the manifest for this state file lives
+func (sm *StateManager) ManifestPath() string {
+	return filepath.Join(filepath.Dir(sm.stateFile), ManifestFileName)
+}
+
+// SaveManifest replaces the stored manifest (written to a temp file and renamed into place)
+func (sm *StateManager) SaveManifest(entries []ManifestEntry) error {
+	path := sm.ManifestPath()
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+
+	// Format: - [f] <sourcePath> | Size: <bytes> | Rel: <relPath>
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# GusSync scan manifest | Scanned: %s | Files: %d\n", time.Now().UTC().Format(time.RFC3339), len(entries))
+	for _, entry := range entries {
+		fmt.Fprintf(w, "- [f] %s | Size: %d | Rel: %s\n", entry.SourcePath, entry.Size, entry.RelPath)
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads the stored manifest; the error wraps os.ErrNotExist if there is none
+func (sm *StateManager) LoadManifest() (Manifest, error) {
+	var manifest Manifest
+	file, err := os.Open(sm.ManifestPath())
+	if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+
+	headerPattern := regexp.MustCompile(`^#.*\|\s*Scanned:\s*(\S+)`)
+	entryPattern := regexp.MustCompile(`^-\s+\[f\]\s+(.+?)\s*\|\s*Size:\s*(\d+)\s*\|\s*Rel:\s*(.+?)\s*$`)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if matches := headerPattern.FindStringSubmatch(line); matches != nil {
+			manifest.ScannedAt, _ = time.Parse(time.RFC3339, matches[1])
+			continue
+		}
+		if matches := entryPattern.FindStringSubmatch(line); matches != nil {
+			var size int64
+			fmt.Sscanf(matches[2], "%d", &size)
+			manifest.Entries = append(manifest.Entries, ManifestEntry{SourcePath: matches[1], RelPath: matches[3], Size: size})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return manifest, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return manifest, nil
+}
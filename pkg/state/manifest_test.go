@@ -0,0 +1,38 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	sm, err := NewStateManager(filepath.Join(t.TempDir(), "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	if _, err := sm.LoadManifest(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadManifest without a manifest: %v, want ErrNotExist", err)
+	}
+
+	entries := []ManifestEntry{
+		{SourcePath: "/sdcard/DCIM/Camera/a b.jpg", RelPath: "DCIM/Camera/a b.jpg", Size: 1234},
+		{SourcePath: "/sdcard/Download/x.pdf", RelPath: "Download/x.pdf"},
+	}
+	if err := sm.SaveManifest(entries); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := sm.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Entries) != 2 || manifest.Entries[0] != entries[0] || manifest.Entries[1] != entries[1] {
+		t.Errorf("entries = %+v, want %+v", manifest.Entries, entries)
+	}
+	if manifest.ScannedAt.IsZero() || manifest.TotalBytes() != 1234 {
+		t.Errorf("scanned at %v, total %d", manifest.ScannedAt, manifest.TotalBytes())
+	}
+}
@@ -0,0 +1,175 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// MergeStats describes what Merge changed
+type MergeStats struct {
+	Added     int             `json:"added"`     // source paths the state did not know about
+	Updated   int             `json:"updated"`   // known paths that gained information
+	Unchanged int             `json:"unchanged"` // nothing new
+	Conflicts []MergeConflict `json:"conflicts"`
+}
+
+// MergeConflict is a source path recorded as completed with different contents on each side
+type MergeConflict struct {
+	SourcePath string `json:"sourcePath"`
+	Hash       string `json:"hash"`      // hash kept
+	OtherHash  string `json:"otherHash"` // hash dropped
+}
+
+// Merge folds records exported from another state file (e.g. another destination drive)
+// into this one and appends the resulting lines. Entries are matched by source path and
+// compared by hash:
+//   - same hash: the entry is already known; later verification times are taken over
+//   - different hash: the copy backed up later wins (the existing one if either time is
+//     unknown) and the pair is reported as a conflict
+//   - failures only count for files neither side completed
+//
+// Quarantine and cleanup-failure entries describe the other destination or device session
+// and are not merged. Merge only touches the state; copying the files themselves to this
+// destination is up to the caller.
+func (sm *StateManager) Merge(records []FileRecord) (MergeStats, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	stats := MergeStats{Conflicts: []MergeConflict{}}
+	for _, r := range records {
+		_, known := sm.stateMap[r.SourcePath]
+		if !known {
+			_, known = sm.failureMap[r.SourcePath]
+		}
+
+		changed, err := sm.mergeRecord(r, &stats)
+		if err != nil {
+			return stats, err
+		}
+		switch {
+		case !changed:
+			stats.Unchanged++
+		case known:
+			stats.Updated++
+		default:
+			stats.Added++
+		}
+	}
+
+	if err := sm.writer.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to write merged state: %w", err)
+	}
+	return stats, nil
+}
+
+// mergeRecord applies one record; the caller holds sm.mu
+func (sm *StateManager) mergeRecord(r FileRecord, stats *MergeStats) (bool, error) {
+	path := r.SourcePath
+	changed := false
+
+	if r.Status == StatusCompleted || r.Status == StatusDeleted {
+		existing, done := sm.stateMap[path]
+		switch {
+		case !done:
+			changed = true
+		case existing == r.Hash:
+			// Same content already tracked
+		default:
+			// Prefer the more recent copy of the file at this path
+			ours := sm.doneAtMap[path]
+			if !ours.IsZero() && !r.BackedUpAt.IsZero() && r.BackedUpAt.After(ours) {
+				stats.Conflicts = append(stats.Conflicts, MergeConflict{SourcePath: path, Hash: r.Hash, OtherHash: existing})
+				changed = true
+			} else {
+				stats.Conflicts = append(stats.Conflicts, MergeConflict{SourcePath: path, Hash: existing, OtherHash: r.Hash})
+			}
+		}
+		if changed {
+			if err := sm.writeCompleted(path, r.Hash, r.Path, r.Volume, r.Size, r.BackedUpAt); err != nil {
+				return false, err
+			}
+		}
+
+		if _, deleted := sm.deletedMap[path]; r.Status == StatusDeleted && !deleted {
+			sm.deletedMap[path] = r.Hash
+			sm.deletedAtMap[path] = r.DeletedAt
+			line := fmt.Sprintf("- [d] %s | Hash: %s", path, r.Hash)
+			if r.DeletedAt != "" {
+				line += " | Deleted: " + r.DeletedAt
+			}
+			if err := sm.writeLine(line); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+
+	if r.Status == StatusFailed {
+		if _, done := sm.stateMap[path]; !done && r.Failures > sm.failureMap[path] {
+			sm.failureMap[path] = r.Failures
+			if err := sm.writeLine(fmt.Sprintf("- [ ] %s | Failures: %d", path, r.Failures)); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+
+	// A verification only vouches for the content it checked
+	if !r.VerifiedAt.IsZero() && sm.stateMap[path] == r.Hash && r.VerifiedAt.After(sm.verifiedMap[path]) {
+		sm.verifiedMap[path] = r.VerifiedAt
+		if err := sm.writeLine(fmt.Sprintf("- [v] %s | Verified: %s", path, r.VerifiedAt.UTC().Format(time.RFC3339))); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if _, converted := sm.convertedMap[path]; r.Converted != "" && !converted {
+		sm.convertedMap[path] = r.Converted
+		if err := sm.writeLine(fmt.Sprintf("- [t] %s | Converted: %s", path, r.Converted)); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// writeCompleted records a completed file with a known (possibly zero) completion time,
+// volume and size; the caller holds sm.mu
+func (sm *StateManager) writeCompleted(path, hash, normalizedPath, volume string, size int64, doneAt time.Time) error {
+	sm.stateMap[path] = hash
+	if normalizedPath != "" || sm.hashMap[hash] == "" {
+		sm.hashMap[hash] = normalizedPath
+	}
+	if !doneAt.IsZero() {
+		sm.doneAtMap[path] = doneAt
+	}
+
+	switch {
+	case hash == "":
+		return sm.writeLine("- [x] " + path)
+	case normalizedPath == "":
+		return sm.writeLine(fmt.Sprintf("- [x] %s | Hash: %s", path, hash))
+	}
+	line := fmt.Sprintf("- [x] Hash: %s | Path: %s | SourcePath: %s", hash, normalizedPath, path)
+	if !doneAt.IsZero() {
+		line += " | Completed: " + doneAt.UTC().Format(time.RFC3339)
+		if volume != "" {
+			sm.volumeMap[path] = volume
+			line += " | Volume: " + volume
+		}
+		if size > 0 {
+			sm.sizeMap[path] = size
+			line += fmt.Sprintf(" | Size: %d", size)
+		}
+	}
+	return sm.writeLine(line)
+}
+
+// writeLine appends one line to the state file; the caller holds sm.mu
+func (sm *StateManager) writeLine(line string) error {
+	if _, err := sm.writer.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write to state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkSuccess()
+	sm.MarkDone("/sdcard/DCIM/same.jpg", "hash-same", "DCIM/same.jpg")
+	sm.MarkDone("/sdcard/DCIM/edited.jpg", "hash-ours", "DCIM/edited.jpg")
+	sm.RecordFailure("/sdcard/DCIM/flaky.jpg")
+
+	verified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	stats, err := sm.Merge([]FileRecord{
+		{SourcePath: "/sdcard/DCIM/same.jpg", Status: StatusCompleted, Hash: "hash-same", Path: "DCIM/same.jpg", VerifiedAt: verified},
+		{SourcePath: "/sdcard/DCIM/edited.jpg", Status: StatusCompleted, Hash: "hash-theirs", Path: "DCIM/edited.jpg", BackedUpAt: older},
+		{SourcePath: "/sdcard/DCIM/flaky.jpg", Status: StatusCompleted, Hash: "hash-flaky", Path: "DCIM/flaky.jpg", BackedUpAt: older},
+		{SourcePath: "/sdcard/DCIM/old.jpg", Status: StatusDeleted, Hash: "hash-old", Path: "DCIM/old.jpg", DeletedAt: "2025-01-02 10:00:00"},
+		{SourcePath: "/sdcard/DCIM/broken.jpg", Status: StatusFailed, Failures: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 2 || stats.Updated != 2 || stats.Unchanged != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	// Ours was backed up more recently than theirs
+	if len(stats.Conflicts) != 1 || stats.Conflicts[0].Hash != "hash-ours" {
+		t.Errorf("conflicts = %+v", stats.Conflicts)
+	}
+
+	// A newer copy on the other side wins
+	stats, _ = sm.Merge([]FileRecord{{SourcePath: "/sdcard/DCIM/edited.jpg", Status: StatusCompleted, Hash: "hash-newest", Path: "DCIM/edited.jpg", BackedUpAt: newer}})
+	if len(stats.Conflicts) != 1 || stats.Conflicts[0].Hash != "hash-newest" || stats.Updated != 1 {
+		t.Errorf("newer copy: stats = %+v", stats)
+	}
+	sm.Close()
+
+	// Everything merged survives a reload
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if got := sm2.GetAllCompletedFiles()["/sdcard/DCIM/edited.jpg"]; got != "hash-newest" {
+		t.Errorf("edited.jpg hash = %q", got)
+	}
+	if !sm2.IsDoneByHash("hash-flaky") || sm2.ShouldRetry("/sdcard/DCIM/flaky.jpg") {
+		t.Errorf("flaky.jpg should be completed from the other state")
+	}
+	if !sm2.IsDeleted("/sdcard/DCIM/old.jpg") || sm2.BackedUpAt("/sdcard/DCIM/flaky.jpg") != older {
+		t.Errorf("deletion or completion time lost")
+	}
+	if sm2.failureMap["/sdcard/DCIM/broken.jpg"] != 4 {
+		t.Errorf("failure count = %d, want 4", sm2.failureMap["/sdcard/DCIM/broken.jpg"])
+	}
+	if !sm2.LastVerified("/sdcard/DCIM/same.jpg").Equal(verified) {
+		t.Errorf("verification time not merged")
+	}
+}
@@ -0,0 +1,147 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// File statuses reported by Records
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusDeleted   = "deleted"
+)
+
+// OpenReadOnly loads a state file for inspection without opening it for appending and
+// without printing load progress. Only the query methods may be used on the result;
+// the state file must exist.
+func OpenReadOnly(stateFile string) (*StateManager, error) {
+	if _, err := os.Stat(stateFile); err != nil {
+		return nil, err
+	}
+	sm := newStateManager(stateFile)
+	sm.quiet = true
+	if err := sm.loadState(); err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return sm, nil
+}
+
+// Path returns the state file
+func (sm *StateManager) Path() string {
+	return sm.stateFile
+}
+
+// FileRecord is the current state of one source file, merged from all line types
+type FileRecord struct {
+	SourcePath      string    `json:"sourcePath"`
+	Status          string    `json:"status"` // completed, failed or deleted (from the source, after cleanup)
+	Hash            string    `json:"hash,omitempty"`
+	Path            string    `json:"path,omitempty"`   // normalized phone path ("" for old-format entries)
+	Volume          string    `json:"volume,omitempty"` // phone volume ID ("" if unknown)
+	Size            int64     `json:"size,omitempty"`   // bytes (0 if unknown)
+	Failures        int       `json:"failures,omitempty"`
+	CleanupFailures int       `json:"cleanupFailures,omitempty"`
+	BackedUpAt      time.Time `json:"backedUpAt"`
+	VerifiedAt      time.Time `json:"verifiedAt"`
+	DeletedAt       string    `json:"deletedAt,omitempty"` // as written in the state file (local time)
+	Converted       string    `json:"converted,omitempty"`
+	Quarantined     string    `json:"quarantined,omitempty"`
+}
+
+// Records returns one record per tracked source file, sorted by source path
+func (sm *StateManager) Records() []FileRecord {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	byPath := make(map[string]*FileRecord)
+	get := func(path string) *FileRecord {
+		r, ok := byPath[path]
+		if !ok {
+			r = &FileRecord{SourcePath: path, Status: StatusFailed}
+			byPath[path] = r
+		}
+		return r
+	}
+
+	for path, hash := range sm.stateMap {
+		r := get(path)
+		r.Status = StatusCompleted
+		r.Hash = hash
+		r.Path = sm.hashMap[hash]
+		r.BackedUpAt = sm.doneAtMap[path]
+		r.Volume = sm.volumeMap[path]
+		r.Size = sm.sizeMap[path]
+	}
+	for path, count := range sm.failureMap {
+		get(path).Failures = count
+	}
+	for path, hash := range sm.deletedMap {
+		r := get(path)
+		r.Status = StatusDeleted
+		if r.Hash == "" {
+			r.Hash = hash
+		}
+		r.DeletedAt = sm.deletedAtMap[path]
+	}
+	for path, count := range sm.cleanupFailureMap {
+		get(path).CleanupFailures = count
+	}
+	for path, at := range sm.verifiedMap {
+		get(path).VerifiedAt = at
+	}
+	for path, converted := range sm.convertedMap {
+		get(path).Converted = converted
+	}
+	for path, entry := range sm.quarantineMap {
+		get(path).Quarantined = entry.Path
+	}
+
+	records := make([]FileRecord, 0, len(byPath))
+	for _, r := range byPath {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].SourcePath < records[j].SourcePath })
+	return records
+}
+
+// Summary counts files and directories by status
+type Summary struct {
+	Completed       int            `json:"completed"` // includes files since deleted from the source
+	Failed          int            `json:"failed"`    // never completed
+	Deleted         int            `json:"deleted"`
+	CleanupFailures int            `json:"cleanupFailures"`
+	Verified        int            `json:"verified"`
+	Quarantined     int            `json:"quarantined"`
+	Converted       int            `json:"converted"`
+	Dirs            map[string]int `json:"dirs"` // directory status -> count
+	Totals          Totals         `json:"totals"`
+}
+
+// Summary returns counts by status
+func (sm *StateManager) Summary() Summary {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	summary := Summary{
+		Completed:       len(sm.stateMap),
+		Deleted:         len(sm.deletedMap),
+		CleanupFailures: len(sm.cleanupFailureMap),
+		Verified:        len(sm.verifiedMap),
+		Quarantined:     len(sm.quarantineMap),
+		Converted:       len(sm.convertedMap),
+		Dirs:            make(map[string]int),
+		Totals:          sm.totals,
+	}
+	for path := range sm.failureMap {
+		if _, done := sm.stateMap[path]; !done {
+			summary.Failed++
+		}
+	}
+	for _, status := range sm.dirMap {
+		summary.Dirs[status]++
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordsAndSummary(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkSuccess()
+	sm.MarkDone("/sdcard/DCIM/a.jpg", "hash-a", "DCIM/a.jpg")
+	sm.MarkDone("/sdcard/DCIM/b.jpg", "hash-b", "DCIM/b.jpg")
+	sm.MarkDeleted("/sdcard/DCIM/b.jpg", "hash-b")
+	sm.RecordFailure("/sdcard/Movies/big.mp4")
+	sm.RecordFailure("/sdcard/Movies/big.mp4")
+	sm.MarkDirStatus("/sdcard/DCIM", "completed")
+	sm.MarkDirStatus("/sdcard/Movies", "timeout")
+	sm.Close()
+
+	ro, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+
+	records := ro.Records()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	want := []struct {
+		path, status string
+		failures     int
+	}{
+		{"/sdcard/DCIM/a.jpg", StatusCompleted, 0},
+		{"/sdcard/DCIM/b.jpg", StatusDeleted, 0},
+		{"/sdcard/Movies/big.mp4", StatusFailed, 2},
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.SourcePath != w.path || r.Status != w.status || r.Failures != w.failures {
+			t.Errorf("record %d = %+v, want %+v", i, r, w)
+		}
+	}
+	if records[0].Path != "DCIM/a.jpg" || records[0].BackedUpAt.IsZero() || records[1].DeletedAt == "" {
+		t.Errorf("record details missing: %+v", records[:2])
+	}
+
+	summary := ro.Summary()
+	if summary.Completed != 2 || summary.Failed != 1 || summary.Deleted != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	// Read-only loading keeps directory lines as written
+	if summary.Dirs["completed"] != 1 || summary.Dirs["timeout"] != 1 {
+		t.Errorf("dir summary = %v", summary.Dirs)
+	}
+}
+
+func TestOpenReadOnlyMissingFile(t *testing.T) {
+	if _, err := OpenReadOnly(filepath.Join(t.TempDir(), "gus_state.md")); err == nil {
+		t.Errorf("expected an error for a missing state file")
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"regexp"
+	"sort"
+)
+
+// SourceRoot is a source folder a multi-source backup copies into its own folder of the
+// destination, so the copies of its files can be found again later
+type SourceRoot struct {
+	Path string `json:"path"` // the folder on the source
+	Dest string `json:"dest"` // where its files go, relative to the destination root
+}
+
+// Pattern for source roots (later lines win): - [root] <sourceRoot> | Dest: <relPath>
+var sourceRootPattern = regexp.MustCompile(`^\s*-\s+\[root\]\s+(.+?)\s*\|\s*Dest:\s*(.+?)\s*$`)
+
+// parseSourceRootLine parses a source root line of the state file
+func parseSourceRootLine(line string) (SourceRoot, bool) {
+	matches := sourceRootPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return SourceRoot{}, false
+	}
+	return SourceRoot{Path: matches[1], Dest: matches[2]}, true
+}
+
+func (r SourceRoot) line() string {
+	return "- [root] " + r.Path + " | Dest: " + r.Dest
+}
+
+// MarkSourceRoot records that files under root are backed up into dest (relative to the
+// destination root). Nothing is written if the root is already recorded with that dest.
+func (sm *StateManager) MarkSourceRoot(root, dest string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.sourceRootMap[root] == dest {
+		return nil
+	}
+	sm.sourceRootMap[root] = dest
+	return sm.writeLine(SourceRoot{Path: root, Dest: dest}.line())
+}
+
+// SourceRoots returns the recorded source roots, by path
+func (sm *StateManager) SourceRoots() []SourceRoot {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	roots := make([]SourceRoot, 0, len(sm.sourceRootMap))
+	for path, dest := range sm.sourceRootMap {
+		roots = append(roots, SourceRoot{Path: path, Dest: dest})
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Path < roots[j].Path })
+	return roots
+}
@@ -0,0 +1,34 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSourceRoots(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkSourceRoot("/gvfs/mtp:host=Pixel/SD card", "SD card")
+	sm.MarkSourceRoot("/gvfs/mtp:host=Pixel/SD card", "SD card")
+	sm.MarkSourceRoot("/gvfs/mtp:host=Pixel/Audio", "Audio")
+	if _, err := sm.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	sm.Close()
+
+	reopened, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SourceRoot{
+		{Path: "/gvfs/mtp:host=Pixel/Audio", Dest: "Audio"},
+		{Path: "/gvfs/mtp:host=Pixel/SD card", Dest: "SD card"},
+	}
+	if got := reopened.SourceRoots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceRoots = %+v, want %+v", got, want)
+	}
+}
@@ -2,10 +2,13 @@ package state
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,33 +18,42 @@ import (
 type StateManager struct {
 	mu                 sync.Mutex
 	stateFile          string
-	stateMap           map[string]string   // path -> hash (for completed files) - OLD FORMAT
-	hashMap            map[string]string   // hash -> normalizedPath (for hash-based lookup) - NEW FORMAT
-	failureMap         map[string]int      // path -> failure count
-	deletedMap         map[string]string   // path -> hash (for deleted files)
-	cleanupFailureMap  map[string]int      // path -> cleanup failure count
-	dirMap             map[string]string   // directory path -> status (completed, timeout, error, partial)
-	dirDiscoveredFiles map[string][]string // directory path -> list of discovered file paths
-	hasSuccess         bool                // track if we've had any success in this run
-	lastCompletedPath  string              // last file path that was completed (for resume)
-	resumePointReached bool                // flag to track if we've passed the resume point
+	stateMap           map[string]string          // path -> hash (for completed files) - OLD FORMAT
+	hashMap            map[string]string          // hash -> normalizedPath (for hash-based lookup) - NEW FORMAT
+	failureMap         map[string]int             // path -> failure count
+	deletedMap         map[string]string          // path -> hash (for deleted files)
+	deletedAtMap       map[string]string          // path -> deletion timestamp as written
+	cleanupFailureMap  map[string]int             // path -> cleanup failure count
+	dirMap             map[string]string          // directory path -> status (completed, timeout, error, partial)
+	dirDiscoveredFiles map[string][]string        // directory path -> list of discovered file paths
+	dirListingMap      map[string]DirListing      // directory path -> files it held when last read completely
+	dirStampMap        map[string]DirStamp        // directory path -> mtime/size/subdirs when last read completely
+	convertedMap       map[string]string          // source path -> converted copy path (relative to dest root)
+	verifiedMap        map[string]time.Time       // source path -> last successful verification
+	destHashMap        map[string]DestHash        // source path -> cached hash of its backed-up copy
+	doneAtMap          map[string]time.Time       // source path -> when it was backed up (unknown for old entries)
+	volumeMap          map[string]string          // source path -> ID of the phone volume it is on (unknown for old entries)
+	sizeMap            map[string]int64           // source path -> size in bytes (unknown for old entries)
+	quarantineMap      map[string]QuarantineEntry // source path -> latest quarantined copy
+	sourceRootMap      map[string]string          // source root -> its folder in the destination (multi-source backups)
+	destNames          string                     // how file names are escaped in the destination ("" = not recorded)
+	destNFC            bool                       // file names are written in Unicode NFC in the destination
+	nfcCollisions      map[string]bool            // source paths whose NFC name is taken by a sibling (see MarkNFCCollision)
+	symlinkMap         map[string]string          // source path -> target of a link recreated in the destination
+	exportMap          map[string]Export          // kind -> latest export of phone data (contacts, sms, ...)
+	apkMap             map[string]APK             // APK copy (relative to dest root) -> its record
+	totals             Totals                     // statistics across runs, as last saved
+	hasSuccess         bool                       // track if we've had any success in this run
+	lastCompletedPath  string                     // last file path that was completed (for resume)
+	resumePointReached bool                       // flag to track if we've passed the resume point
+	quiet              bool                       // read-only: no progress output (see OpenReadOnly)
 	fileHandle         *os.File
 	writer             *bufio.Writer
 }
 
 // NewStateManager creates a new StateManager and loads existing state
 func NewStateManager(stateFile string) (*StateManager, error) {
-	sm := &StateManager{
-		stateFile:          stateFile,
-		stateMap:           make(map[string]string),
-		hashMap:            make(map[string]string), // NEW: hash-based lookup
-		failureMap:         make(map[string]int),
-		deletedMap:         make(map[string]string),
-		cleanupFailureMap:  make(map[string]int),
-		dirMap:             make(map[string]string),
-		dirDiscoveredFiles: make(map[string][]string),
-		hasSuccess:         false,
-	}
+	sm := newStateManager(stateFile)
 
 	// Load existing state if file exists
 	if err := sm.loadState(); err != nil {
@@ -53,8 +65,8 @@ func NewStateManager(stateFile string) (*StateManager, error) {
 	for dirPath, status := range sm.dirMap {
 		if status == "completed" {
 			// Check if this directory has discovered files tracking
-			// dirDiscoveredFiles is initialized, but old directories won't have entries
-			if _, hasTracking := sm.dirDiscoveredFiles[dirPath]; !hasTracking {
+			// Directories completed by older versions have no recorded listing
+			if _, hasTracking := sm.dirListingMap[dirPath]; !hasTracking {
 				// Old format - no discovered files tracking - clear status to force rescan
 				dirsToClear = append(dirsToClear, dirPath)
 			}
@@ -97,9 +109,46 @@ func NewStateManager(stateFile string) (*StateManager, error) {
 	return sm, nil
 }
 
+// newStateManager returns a manager with empty maps (no file loaded or opened)
+func newStateManager(stateFile string) *StateManager {
+	return &StateManager{
+		stateFile:          stateFile,
+		stateMap:           make(map[string]string),
+		hashMap:            make(map[string]string), // NEW: hash-based lookup
+		failureMap:         make(map[string]int),
+		deletedMap:         make(map[string]string),
+		deletedAtMap:       make(map[string]string),
+		cleanupFailureMap:  make(map[string]int),
+		dirMap:             make(map[string]string),
+		dirDiscoveredFiles: make(map[string][]string),
+		dirListingMap:      make(map[string]DirListing),
+		dirStampMap:        make(map[string]DirStamp),
+		convertedMap:       make(map[string]string),
+		verifiedMap:        make(map[string]time.Time),
+		destHashMap:        make(map[string]DestHash),
+		doneAtMap:          make(map[string]time.Time),
+		volumeMap:          make(map[string]string),
+		sizeMap:            make(map[string]int64),
+		quarantineMap:      make(map[string]QuarantineEntry),
+		sourceRootMap:      make(map[string]string),
+		nfcCollisions:      make(map[string]bool),
+		symlinkMap:         make(map[string]string),
+		exportMap:          make(map[string]Export),
+		apkMap:             make(map[string]APK),
+		hasSuccess:         false,
+	}
+}
+
+// logf prints load progress unless the manager was opened read-only
+func (sm *StateManager) logf(format string, args ...interface{}) {
+	if !sm.quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
 // loadState parses the markdown file and populates the state map
 func (sm *StateManager) loadState() error {
-	fmt.Printf("Loading backup state from %s...\n", filepath.Base(sm.stateFile))
+	sm.logf("Loading backup state from %s...\n", filepath.Base(sm.stateFile))
 	startTime := time.Now()
 
 	file, err := os.Open(sm.stateFile)
@@ -112,45 +161,68 @@ func (sm *StateManager) loadState() error {
 	defer file.Close()
 
 	// Pattern for completed: - [x] /path/to/file | Hash: <hash>
-	// Pattern for completed (new hash-based): - [x] Hash: <hash> | Path: <normalizedPath> | SourcePath: <sourcePath>
+	// Pattern for completed (new hash-based): - [x] Hash: <hash> | Path: <normalizedPath> | SourcePath: <sourcePath> [| Completed: <RFC3339 timestamp>] [| Volume: <volume ID>] [| Size: <bytes>]
 	// Pattern for failed: - [ ] /path/to/file | Failures: <count>
 	// Pattern for deleted: - [d] /path/to/file | Hash: <hash> | Deleted: <timestamp>
 	// Pattern for cleanup failures: - [c] /path/to/file | CleanupFailures: <count>
 	// Pattern for directories: - [dir] /path/to/dir | Status: <status>
+	// Pattern for directory listings: - [dl] /path/to/dir | Files: <count> | Listing: <hash>
+	// Pattern for directory stamps: - [dm] /path/to/dir | MTime: <unix nanoseconds> | Size: <bytes> | Subdirs: <name>/<name>...
+	// Pattern for converted copies: - [t] /path/to/file | Converted: <relPath>
+	// Pattern for verifications: - [v] /path/to/file | Verified: <RFC3339 timestamp>
+	// Pattern for quarantined copies: - [q] /path/to/file | Quarantined: <relPath> | Reason: <reason> | At: <RFC3339 timestamp>
+	// Pattern for run totals: see totalsPattern
+	// Pattern for source roots: see sourceRootPattern
 	completedPattern := regexp.MustCompile(`^\s*-\s+\[x\]\s+(.+?)(?:\s*\|\s*Hash:\s*(\S+))?\s*$`)
-	completedHashPattern := regexp.MustCompile(`^\s*-\s+\[x\]\s+Hash:\s*(\S+)\s*\|\s*Path:\s*(.+?)(?:\s*\|\s*SourcePath:\s*(.+?))?\s*$`)
+	completedHashPattern := regexp.MustCompile(`^\s*-\s+\[x\]\s+Hash:\s*(\S+)\s*\|\s*Path:\s*(.+?)(?:\s*\|\s*SourcePath:\s*(.+?))?(?:\s*\|\s*Completed:\s*(\S+))?(?:\s*\|\s*Volume:\s*(\S+))?(?:\s*\|\s*Size:\s*(\d+))?\s*$`)
 	failedPattern := regexp.MustCompile(`^\s*-\s+\[\s\]\s+(.+?)(?:\s*\|\s*Failures:\s*(\d+))?\s*$`)
-	deletedPattern := regexp.MustCompile(`^\s*-\s+\[d\]\s+(.+?)(?:\s*\|\s*Hash:\s*(\S+))?\s*$`)
+	deletedPattern := regexp.MustCompile(`^\s*-\s+\[d\]\s+(.+?)(?:\s*\|\s*Hash:\s*(\S+))?(?:\s*\|\s*Deleted:\s*(.+?))?\s*$`)
 	cleanupFailurePattern := regexp.MustCompile(`^\s*-\s+\[c\]\s+(.+?)(?:\s*\|\s*CleanupFailures:\s*(\d+))?\s*$`)
 	dirPattern := regexp.MustCompile(`^\s*-\s+\[dir\]\s+(.+?)(?:\s*\|\s*Status:\s*(\S+))?\s*$`)
+	dirListingPattern := regexp.MustCompile(`^\s*-\s+\[dl\]\s+(.+?)\s*\|\s*Files:\s*(\d+)\s*\|\s*Listing:\s*(\S+)\s*$`)
+	dirStampPattern := regexp.MustCompile(`^\s*-\s+\[dm\]\s+(.+?)\s*\|\s*MTime:\s*(-?\d+)\s*\|\s*Size:\s*(\d+)\s*\|\s*Subdirs:(.*)$`)
+	convertedPattern := regexp.MustCompile(`^\s*-\s+\[t\]\s+(.+?)\s*\|\s*Converted:\s*(.+?)\s*$`)
+	verifiedPattern := regexp.MustCompile(`^\s*-\s+\[v\]\s+(.+?)\s*\|\s*Verified:\s*(\S+)\s*$`)
+	quarantinePattern := regexp.MustCompile(`^\s*-\s+\[q\]\s+(.+?)\s*\|\s*Quarantined:\s*(.+?)\s*\|\s*Reason:\s*(.*?)\s*\|\s*At:\s*(\S+)\s*$`)
 
+	names := make(internTable) // keeps paths and hashes, not the lines holding them
 	lineCount := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		lineCount++
 		if lineCount % 5000 == 0 {
-			fmt.Printf("...processed %d lines of state\n", lineCount)
+			sm.logf("...processed %d lines of state\n", lineCount)
 		}
 		line := strings.TrimSpace(scanner.Text())
 
 		// Check for completed files (new hash-based format first)
 		if matches := completedHashPattern.FindStringSubmatch(line); matches != nil {
-			hash := matches[1]
-			normalizedPath := matches[2]
-			sourcePath := matches[3]
+			hash := names.get(matches[1])
+			sourcePath := names.get(matches[3])
+			normalizedPath := names.suffix(matches[2], sourcePath)
 			// Store in hash map (new format)
 			sm.hashMap[hash] = normalizedPath
 			// Also store in old format for backward compatibility
 			if sourcePath != "" {
 				sm.stateMap[sourcePath] = hash
+				if doneAt, err := time.Parse(time.RFC3339, matches[4]); err == nil {
+					sm.doneAtMap[sourcePath] = doneAt
+				}
+				if matches[5] != "" {
+					sm.volumeMap[sourcePath] = names.get(matches[5])
+				}
+				var size int64
+				if fmt.Sscanf(matches[6], "%d", &size); size > 0 {
+					sm.sizeMap[sourcePath] = size
+				}
 			}
 			continue
 		}
 
 		// Check for completed files (old path-based format)
 		if matches := completedPattern.FindStringSubmatch(line); matches != nil {
-			path := matches[1]
-			hash := matches[2]
+			path := names.get(matches[1])
+			hash := names.get(matches[2])
 			sm.stateMap[path] = hash
 			// Also add to hash map for hash-based lookup (backward compatibility)
 			if hash != "" {
@@ -161,7 +233,7 @@ func (sm *StateManager) loadState() error {
 
 		// Check for failed files
 		if matches := failedPattern.FindStringSubmatch(line); matches != nil {
-			path := matches[1]
+			path := names.get(matches[1])
 			var count int
 			if len(matches) > 2 && matches[2] != "" {
 				fmt.Sscanf(matches[2], "%d", &count)
@@ -174,15 +246,16 @@ func (sm *StateManager) loadState() error {
 
 		// Check for deleted files
 		if matches := deletedPattern.FindStringSubmatch(line); matches != nil {
-			path := matches[1]
-			hash := matches[2]
+			path := names.get(matches[1])
+			hash := names.get(matches[2])
 			sm.deletedMap[path] = hash
+			sm.deletedAtMap[path] = names.get(matches[3])
 			continue
 		}
 
 		// Check for cleanup failures
 		if matches := cleanupFailurePattern.FindStringSubmatch(line); matches != nil {
-			path := matches[1]
+			path := names.get(matches[1])
 			var count int
 			if len(matches) > 2 && matches[2] != "" {
 				fmt.Sscanf(matches[2], "%d", &count)
@@ -195,12 +268,108 @@ func (sm *StateManager) loadState() error {
 
 		// Check for directory status
 		if matches := dirPattern.FindStringSubmatch(line); matches != nil {
-			path := matches[1]
-			status := matches[2]
+			path := names.get(matches[1])
+			status := names.get(matches[2])
 			if status == "" {
 				status = "completed" // Default to completed if not specified
 			}
 			sm.dirMap[path] = status
+			continue
+		}
+
+		// Check for directory listings (later lines win)
+		if matches := dirListingPattern.FindStringSubmatch(line); matches != nil {
+			var count int
+			fmt.Sscanf(matches[2], "%d", &count)
+			sm.dirListingMap[names.get(matches[1])] = DirListing{Files: count, Hash: strings.Clone(matches[3])}
+			continue
+		}
+
+		// Check for directory stamps (later lines win)
+		if matches := dirStampPattern.FindStringSubmatch(line); matches != nil {
+			var mtime, size int64
+			fmt.Sscanf(matches[2], "%d", &mtime)
+			fmt.Sscanf(matches[3], "%d", &size)
+			subdirs := splitSubdirs(matches[4])
+			for i, name := range subdirs {
+				subdirs[i] = names.get(name)
+			}
+			sm.dirStampMap[names.get(matches[1])] = DirStamp{MTime: mtime, Size: size, Subdirs: subdirs}
+			continue
+		}
+
+		// Check for converted copies
+		if matches := convertedPattern.FindStringSubmatch(line); matches != nil {
+			sm.convertedMap[names.get(matches[1])] = strings.Clone(matches[2])
+			continue
+		}
+
+		// Check for verifications (later lines win)
+		if matches := verifiedPattern.FindStringSubmatch(line); matches != nil {
+			if verifiedAt, err := time.Parse(time.RFC3339, matches[2]); err == nil {
+				sm.verifiedMap[names.get(matches[1])] = verifiedAt
+			}
+			continue
+		}
+
+		// Check for cached destination hashes (later lines win)
+		if path, h, ok := parseDestHashLine(line); ok {
+			sm.destHashMap[names.get(path)] = h
+			continue
+		}
+
+		// Check for run totals (later lines win)
+		if totals, ok := parseTotalsLine(line); ok {
+			sm.totals = totals
+			continue
+		}
+
+		// Check for source roots (later lines win)
+		if root, ok := parseSourceRootLine(line); ok {
+			sm.sourceRootMap[root.Path] = root.Dest
+			continue
+		}
+
+		// Check for the destination name scheme (later lines win)
+		if scheme, nfc, ok := parseDestNamesLine(line); ok {
+			sm.destNames, sm.destNFC = scheme, nfc
+			continue
+		}
+
+		// Check for names kept apart from a sibling with the same NFC name
+		if matches := nfcCollisionPattern.FindStringSubmatch(line); matches != nil {
+			sm.nfcCollisions[matches[1]] = true
+			continue
+		}
+
+		// Check for symbolic links recreated in the destination
+		if matches := symlinkPattern.FindStringSubmatch(line); matches != nil {
+			sm.symlinkMap[matches[1]] = matches[2]
+			continue
+		}
+
+		// Check for exports of phone data (later lines win)
+		if export, ok := parseExportLine(line); ok {
+			sm.exportMap[export.Kind] = export
+			continue
+		}
+
+		// Check for APKs (later lines win)
+		if apk, ok := parseAPKLine(line); ok {
+			sm.apkMap[apk.File] = apk
+			continue
+		}
+
+		// Check for quarantined copies
+		if matches := quarantinePattern.FindStringSubmatch(line); matches != nil {
+			at, _ := time.Parse(time.RFC3339, matches[4])
+			path := names.get(matches[1])
+			sm.quarantineMap[path] = QuarantineEntry{
+				SourcePath: path,
+				Path:       strings.Clone(matches[2]),
+				Reason:     strings.Clone(matches[3]),
+				At:         at,
+			}
 		}
 	}
 
@@ -208,7 +377,7 @@ func (sm *StateManager) loadState() error {
 		return err
 	}
 
-	fmt.Printf("Finished loading state: %d lines processed in %v\n", lineCount, time.Since(startTime))
+	sm.logf("Finished loading state: %d lines processed in %v\n", lineCount, time.Since(startTime))
 	return nil
 }
 
@@ -326,8 +495,31 @@ func (sm *StateManager) MarkSuccess() {
 // hash: file hash (SHA256)
 // normalizedPath: protocol-agnostic normalized path (for new format)
 func (sm *StateManager) MarkDone(sourcePath, hash, normalizedPath string) error {
+	return sm.MarkDoneOnVolume(sourcePath, hash, normalizedPath, "")
+}
+
+// MarkDoneOnVolume is MarkDone for a file on a known phone volume (internal storage, an SD
+// card, ...), so files with the same normalized path on different volumes stay apart.
+// volume must not contain spaces; "" = unknown.
+func (sm *StateManager) MarkDoneOnVolume(sourcePath, hash, normalizedPath, volume string) error {
+	return sm.MarkCompleted(CompletedFile{SourcePath: sourcePath, Hash: hash, NormalizedPath: normalizedPath, Volume: volume})
+}
+
+// CompletedFile is a file copied to the destination, as recorded by MarkCompleted
+type CompletedFile struct {
+	SourcePath     string
+	Hash           string // SHA-256 of the copy
+	NormalizedPath string // protocol-agnostic phone path (see MarkDone)
+	Volume         string // phone volume ID, without spaces ("" = unknown; see MarkDoneOnVolume)
+	Size           int64  // bytes (0 = unknown)
+}
+
+// MarkCompleted marks a file as done with everything known about it and appends it to the
+// state file
+func (sm *StateManager) MarkCompleted(f CompletedFile) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sourcePath, hash, normalizedPath, volume := f.SourcePath, f.Hash, f.NormalizedPath, f.Volume
 
 	// Update in-memory maps
 	sm.stateMap[sourcePath] = hash    // Old format (backward compatibility)
@@ -339,8 +531,19 @@ func (sm *StateManager) MarkDone(sourcePath, hash, normalizedPath string) error
 	}
 
 	// Append to file using new hash-based format (more efficient and protocol-agnostic)
-	// Format: - [x] Hash: <hash> | Path: <normalizedPath> | SourcePath: <sourcePath>
-	line := fmt.Sprintf("- [x] Hash: %s | Path: %s | SourcePath: %s\n", hash, normalizedPath, sourcePath)
+	// Format: - [x] Hash: <hash> | Path: <normalizedPath> | SourcePath: <sourcePath> | Completed: <timestamp> [| Volume: <volume ID>] [| Size: <bytes>]
+	doneAt := time.Now().UTC().Truncate(time.Second)
+	sm.doneAtMap[sourcePath] = doneAt
+	line := fmt.Sprintf("- [x] Hash: %s | Path: %s | SourcePath: %s | Completed: %s", hash, normalizedPath, sourcePath, doneAt.Format(time.RFC3339))
+	if volume != "" {
+		sm.volumeMap[sourcePath] = volume
+		line += " | Volume: " + volume
+	}
+	if f.Size > 0 {
+		sm.sizeMap[sourcePath] = f.Size
+		line += fmt.Sprintf(" | Size: %d", f.Size)
+	}
+	line += "\n"
 	if _, err := sm.writer.WriteString(line); err != nil {
 		return fmt.Errorf("failed to write to state file: %w", err)
 	}
@@ -362,13 +565,17 @@ func (sm *StateManager) Close() error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if sm.writer == nil {
+		return nil // opened read-only
+	}
 	if err := sm.writer.Flush(); err != nil {
 		return err
 	}
 	return sm.fileHandle.Close()
 }
 
-// GetStats returns the number of completed files
+// GetStats returns the number of completed files, without copying them like
+// GetAllCompletedFiles
 func (sm *StateManager) GetStats() int {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -387,6 +594,40 @@ func (sm *StateManager) GetAllCompletedFiles() map[string]string {
 	return result
 }
 
+// IterateCompleted calls fn with each completed file's path and hash, in no particular order,
+// until fn returns false. Unlike GetAllCompletedFiles it builds no map: only the (shared)
+// strings are listed up front, and fn runs without the manager locked, so it may call other
+// methods. Files marked done during the iteration may be left out.
+func (sm *StateManager) IterateCompleted(fn func(path, hash string) bool) {
+	sm.mu.Lock()
+	entries := make([]string, 0, 2*len(sm.stateMap))
+	for path, hash := range sm.stateMap {
+		entries = append(entries, path, hash)
+	}
+	sm.mu.Unlock()
+
+	for i := 0; i < len(entries); i += 2 {
+		if !fn(entries[i], entries[i+1]) {
+			return
+		}
+	}
+}
+
+// BackedUpAt returns when a file was backed up (zero time if the state predates timestamps)
+func (sm *StateManager) BackedUpAt(sourcePath string) time.Time {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.doneAtMap[sourcePath]
+}
+
+// CompletedHash returns the hash recorded when a file was backed up ("" if it isn't completed
+// or the entry has no hash)
+func (sm *StateManager) CompletedHash(sourcePath string) string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.stateMap[sourcePath]
+}
+
 // IsDeleted checks if a file path is already marked as deleted
 func (sm *StateManager) IsDeleted(path string) bool {
 	sm.mu.Lock()
@@ -395,6 +636,13 @@ func (sm *StateManager) IsDeleted(path string) bool {
 	return exists
 }
 
+// Volume returns the ID of the phone volume a completed file was on ("" if unknown)
+func (sm *StateManager) Volume(sourcePath string) string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.volumeMap[sourcePath]
+}
+
 // MarkDeleted marks a file as deleted and appends to the state file
 func (sm *StateManager) MarkDeleted(sourcePath, hash string) error {
 	sm.mu.Lock()
@@ -405,6 +653,7 @@ func (sm *StateManager) MarkDeleted(sourcePath, hash string) error {
 
 	// Append to file with timestamp
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	sm.deletedAtMap[sourcePath] = timestamp
 	line := fmt.Sprintf("- [d] %s | Hash: %s | Deleted: %s\n", sourcePath, hash, timestamp)
 	if _, err := sm.writer.WriteString(line); err != nil {
 		return fmt.Errorf("failed to write deletion to state file: %w", err)
@@ -413,10 +662,108 @@ func (sm *StateManager) MarkDeleted(sourcePath, hash string) error {
 	return nil
 }
 
+// MarkConverted records that an additional converted copy exists for a source file.
+// The converted path is relative to the destination root; the original is never replaced.
+func (sm *StateManager) MarkConverted(sourcePath, convertedPath string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.convertedMap[sourcePath] = convertedPath
+
+	line := fmt.Sprintf("- [t] %s | Converted: %s\n", sourcePath, convertedPath)
+	if _, err := sm.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write conversion to state file: %w", err)
+	}
+
+	return nil
+}
+
+// GetConvertedPath returns the converted copy recorded for a source file ("" if none)
+func (sm *StateManager) GetConvertedPath(sourcePath string) string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.convertedMap[sourcePath]
+}
+
+// MarkVerified records that a file's backup copy was checked and matched at the given time
+func (sm *StateManager) MarkVerified(sourcePath string, verifiedAt time.Time) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.verifiedMap[sourcePath] = verifiedAt
+
+	line := fmt.Sprintf("- [v] %s | Verified: %s\n", sourcePath, verifiedAt.UTC().Format(time.RFC3339))
+	if _, err := sm.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write verification to state file: %w", err)
+	}
+
+	return nil
+}
+
+// LastVerified returns when a file was last verified (zero time if never)
+func (sm *StateManager) LastVerified(sourcePath string) time.Time {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.verifiedMap[sourcePath]
+}
+
+// QuarantineEntry describes a destination copy that failed verification and was set aside
+type QuarantineEntry struct {
+	SourcePath string    `json:"sourcePath"`
+	Path       string    `json:"path"` // quarantined file, relative to the destination root
+	Reason     string    `json:"reason"`
+	At         time.Time `json:"at"`
+}
+
+// MarkQuarantined records that the destination copy of a file was moved to quarantine
+func (sm *StateManager) MarkQuarantined(sourcePath, quarantinedPath, reason string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	// Keep the line parseable: the reason is free text
+	reason = strings.ReplaceAll(strings.ReplaceAll(reason, "|", "/"), "\n", " ")
+	entry := QuarantineEntry{SourcePath: sourcePath, Path: quarantinedPath, Reason: reason, At: time.Now().UTC().Truncate(time.Second)}
+	sm.quarantineMap[sourcePath] = entry
+
+	line := fmt.Sprintf("- [q] %s | Quarantined: %s | Reason: %s | At: %s\n", sourcePath, quarantinedPath, reason, entry.At.Format(time.RFC3339))
+	if _, err := sm.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write quarantine to state file: %w", err)
+	}
+
+	return nil
+}
+
+// GetQuarantined returns all quarantined copies, oldest first
+func (sm *StateManager) GetQuarantined() []QuarantineEntry {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	entries := make([]QuarantineEntry, 0, len(sm.quarantineMap))
+	for _, entry := range sm.quarantineMap {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].At.Equal(entries[j].At) {
+			return entries[i].At.Before(entries[j].At)
+		}
+		return entries[i].SourcePath < entries[j].SourcePath
+	})
+	return entries
+}
+
+// Quarantined returns the quarantine entry of a file, if its copy was quarantined
+func (sm *StateManager) Quarantined(sourcePath string) (QuarantineEntry, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	entry, ok := sm.quarantineMap[sourcePath]
+	return entry, ok
+}
+
 // IsDirScanned checks if a directory has been fully scanned (completed status)
 // IMPORTANT: If a directory is marked as "completed" but we don't have discovered files
 // tracking for it (backward compatibility), we return false to force a rescan.
 // This ensures directories from old versions get rescanned to find missed files.
+// Only files discovered in this run count; use IsDirUnchanged to compare against earlier runs.
 func (sm *StateManager) IsDirScanned(dirPath string) bool {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -509,6 +856,135 @@ func (sm *StateManager) AreAllDiscoveredFilesCompleted(dirPath string) bool {
 	return true // All discovered files are completed
 }
 
+// DirListing fingerprints the files a directory held when it was last read completely
+type DirListing struct {
+	Files int
+	Hash  string // SHA256 of the sorted file paths
+}
+
+// newDirListing fingerprints a list of file paths (order-independent)
+func newDirListing(files []string) DirListing {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, f := range sorted {
+		h.Write([]byte(f))
+		h.Write([]byte{'\n'})
+	}
+	return DirListing{Files: len(files), Hash: hex.EncodeToString(h.Sum(nil))}
+}
+
+// MarkDirListing records the files a completely read directory contains, so the next run can
+// tell whether any were added or removed. Nothing is written if the listing is unchanged.
+func (sm *StateManager) MarkDirListing(dirPath string, files []string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	listing := newDirListing(files)
+	if sm.dirListingMap[dirPath] == listing {
+		return nil
+	}
+	sm.dirListingMap[dirPath] = listing
+
+	line := fmt.Sprintf("- [dl] %s | Files: %d | Listing: %s\n", dirPath, listing.Files, listing.Hash)
+	if _, err := sm.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write directory listing to state file: %w", err)
+	}
+
+	return nil
+}
+
+// IsDirUnchanged reports whether a directory is marked completed, still holds exactly the files
+// recorded by MarkDirListing in an earlier run, and all of them are backed up, so its files need
+// no work. Subdirectories are tracked separately.
+func (sm *StateManager) IsDirUnchanged(dirPath string, files []string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.dirMap[dirPath] != "completed" {
+		return false
+	}
+	recorded, ok := sm.dirListingMap[dirPath]
+	if !ok || recorded != newDirListing(files) {
+		return false
+	}
+	for _, filePath := range files {
+		if _, completed := sm.stateMap[filePath]; !completed {
+			return false
+		}
+	}
+	return true
+}
+
+// DirStamp is what a directory looked like when it was last read completely: enough to
+// skip reading it again if its stat is unchanged
+type DirStamp struct {
+	MTime   int64    // modification time, unix nanoseconds
+	Size    int64    // directory size from stat (grows with the entry count on most filesystems)
+	Subdirs []string // names of its subdirectories, which still have to be visited
+}
+
+func (d DirStamp) equal(other DirStamp) bool {
+	if d.MTime != other.MTime || d.Size != other.Size || len(d.Subdirs) != len(other.Subdirs) {
+		return false
+	}
+	for i := range d.Subdirs {
+		if d.Subdirs[i] != other.Subdirs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSubdirs parses the Subdirs field ("/"-separated names, which cannot contain "/")
+func splitSubdirs(field string) []string {
+	var names []string
+	for _, name := range strings.Split(strings.TrimSpace(field), "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// MarkDirStamp records a completely read directory's stat and subdirectories.
+// Nothing is written if the stamp is unchanged.
+func (sm *StateManager) MarkDirStamp(dirPath string, stamp DirStamp) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	stamp.Subdirs = append([]string(nil), stamp.Subdirs...)
+	sort.Strings(stamp.Subdirs)
+	if existing, ok := sm.dirStampMap[dirPath]; ok && existing.equal(stamp) {
+		return nil
+	}
+	sm.dirStampMap[dirPath] = stamp
+
+	line := fmt.Sprintf("- [dm] %s | MTime: %d | Size: %d | Subdirs: %s\n", dirPath, stamp.MTime, stamp.Size, strings.Join(stamp.Subdirs, "/"))
+	if _, err := sm.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write directory stamp to state file: %w", err)
+	}
+
+	return nil
+}
+
+// UnchangedDirSubdirs reports whether a directory is marked completed and its stat still matches
+// the recorded stamp, in which case its files need not be listed again. It returns the recorded
+// subdirectory names, which must still be visited (their own changes don't touch the parent).
+func (sm *StateManager) UnchangedDirSubdirs(dirPath string, mtime, size int64) ([]string, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.dirMap[dirPath] != "completed" {
+		return nil, false
+	}
+	stamp, ok := sm.dirStampMap[dirPath]
+	if !ok || stamp.MTime != mtime || stamp.Size != size {
+		return nil, false
+	}
+	return append([]string(nil), stamp.Subdirs...), true
+}
+
 // ShouldRetryCleanup checks if a cleanup operation should be retried (hasn't failed 10 times yet)
 func (sm *StateManager) ShouldRetryCleanup(path string) bool {
 	sm.mu.Lock()
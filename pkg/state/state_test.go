@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestStateManager(t *testing.T) {
@@ -86,3 +87,247 @@ func TestStateManager(t *testing.T) {
 	}
 }
 
+
+func TestDeletedSurvivesReload(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/sdcard/DCIM/Camera/IMG_0001.jpg"
+	sm.MarkDone(path, "abc123", "DCIM/Camera/IMG_0001.jpg")
+	sm.MarkDeleted(path, "abc123")
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if !sm2.IsDeleted(path) {
+		t.Errorf("expected %s to still be marked deleted after reload", path)
+	}
+}
+
+func TestVerifiedSurvivesReload(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/sdcard/DCIM/Camera/IMG_0001.jpg"
+	if !sm.LastVerified(path).IsZero() {
+		t.Errorf("expected a new file to have no verification time")
+	}
+	first := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(30 * 24 * time.Hour)
+	sm.MarkVerified(path, first)
+	sm.MarkVerified(path, second)
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if got := sm2.LastVerified(path); !got.Equal(second) {
+		t.Errorf("LastVerified after reload = %v, want %v", got, second)
+	}
+}
+
+func TestQuarantineSurvivesReload(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/sdcard/DCIM/Camera/IMG_0001.jpg"
+	sm.MarkQuarantined(path, "_quarantine/DCIM/Camera/IMG_0001.jpg", "hash mismatch | re-copy failed")
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	entries := sm2.GetQuarantined()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 quarantine entry after reload, got %d", len(entries))
+	}
+	if entries[0].SourcePath != path || entries[0].Path != "_quarantine/DCIM/Camera/IMG_0001.jpg" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+	if entries[0].Reason != "hash mismatch / re-copy failed" || entries[0].At.IsZero() {
+		t.Errorf("reason/time not preserved: %+v", entries[0])
+	}
+}
+
+func TestBackedUpAtSurvivesReload(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/sdcard/DCIM/Camera/IMG_0001.jpg"
+	before := time.Now().Add(-time.Second)
+	sm.MarkDone(path, "abc123", "DCIM/Camera/IMG_0001.jpg")
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if got := sm2.GetNormalizedPathByHash("abc123"); got != "DCIM/Camera/IMG_0001.jpg" {
+		t.Errorf("normalized path = %q", got)
+	}
+	if got := sm2.BackedUpAt(path); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("BackedUpAt after reload = %v, want about now", got)
+	}
+	if !sm2.IsDoneForSource(path, "/sdcard") {
+		t.Errorf("expected %s to be done after reload", path)
+	}
+}
+
+func TestDirListingSurvivesReload(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	dir := "/sdcard/DCIM/Camera"
+	files := []string{dir + "/a.jpg", dir + "/b.jpg"}
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		sm.MarkDone(f, "hash-"+filepath.Base(f), "DCIM/Camera/"+filepath.Base(f))
+	}
+	sm.MarkDirListing(dir, files)
+	sm.MarkDirStatus(dir, "completed")
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	if sm2.GetDirStatus(dir) != "completed" {
+		t.Errorf("completed status with a recorded listing should survive a reload")
+	}
+	if !sm2.IsDirUnchanged(dir, []string{files[1], files[0]}) {
+		t.Errorf("same files in a different order should count as unchanged")
+	}
+	if sm2.IsDirUnchanged(dir, append(files, dir+"/new.jpg")) {
+		t.Errorf("a new file must make the directory count as changed")
+	}
+
+	// An unchanged listing is not written again
+	before, _ := os.ReadFile(stateFile)
+	sm2.MarkDirListing(dir, files)
+	sm2.Flush()
+	after, _ := os.ReadFile(stateFile)
+	if len(after) != len(before) {
+		t.Errorf("unchanged listing was appended again")
+	}
+}
+
+func TestDirStamp(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	dir := "/sdcard/DCIM"
+
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkDirStamp(dir, DirStamp{MTime: 1700000000123456789, Size: 4096, Subdirs: []string{"Camera", ".thumbnails"}})
+	sm.MarkDirListing(dir, nil)
+	sm.MarkDirStatus(dir, "completed")
+	sm.Close()
+
+	sm2, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm2.Close()
+	subdirs, ok := sm2.UnchangedDirSubdirs(dir, 1700000000123456789, 4096)
+	if !ok || len(subdirs) != 2 || subdirs[0] != ".thumbnails" || subdirs[1] != "Camera" {
+		t.Errorf("UnchangedDirSubdirs = %v, %v", subdirs, ok)
+	}
+	if _, ok := sm2.UnchangedDirSubdirs(dir, 1700000000999999999, 4096); ok {
+		t.Errorf("a changed mtime must not count as unchanged")
+	}
+	sm2.MarkDirStatus(dir, "partial")
+	if _, ok := sm2.UnchangedDirSubdirs(dir, 1700000000123456789, 4096); ok {
+		t.Errorf("a directory that isn't completed must be listed again")
+	}
+}
+
+func TestMarkDoneOnVolume(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MarkDoneOnVolume("/storage/1A2B-3C4D/DCIM/a.jpg", "hash-sd", "DCIM/a.jpg", "1A2B-3C4D")
+	sm.MarkDone("/sdcard/DCIM/a.jpg", "hash-internal", "DCIM/a.jpg")
+	if _, err := sm.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	sm.Close()
+
+	sm2, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sm2.Volume("/storage/1A2B-3C4D/DCIM/a.jpg"); v != "1A2B-3C4D" {
+		t.Errorf("Volume = %q, want 1A2B-3C4D", v)
+	}
+	if v := sm2.Volume("/sdcard/DCIM/a.jpg"); v != "" {
+		t.Errorf("Volume = %q, want unknown", v)
+	}
+	if !sm2.IsDone("/storage/1A2B-3C4D/DCIM/a.jpg") || sm2.GetNormalizedPathByHash("hash-sd") != "DCIM/a.jpg" {
+		t.Errorf("the volume must not disturb the rest of the entry")
+	}
+}
+
+func TestIterateCompleted(t *testing.T) {
+	sm, err := NewStateManager(filepath.Join(t.TempDir(), "gus_state.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	want := map[string]string{"/sdcard/a.jpg": "ha", "/sdcard/b.jpg": "hb", "/sdcard/c.jpg": "hc"}
+	for path, hash := range want {
+		sm.MarkDone(path, hash, path)
+	}
+
+	got := make(map[string]string)
+	sm.IterateCompleted(func(path, hash string) bool {
+		// The manager isn't locked while fn runs
+		if !sm.IsDone(path) {
+			t.Errorf("%s not done", path)
+		}
+		got[path] = hash
+		return true
+	})
+	if len(got) != len(want) {
+		t.Errorf("iterated %v, want %v", got, want)
+	}
+	for path, hash := range want {
+		if got[path] != hash {
+			t.Errorf("%s: hash %q, want %q", path, got[path], hash)
+		}
+	}
+
+	calls := 0
+	sm.IterateCompleted(func(string, string) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("fn called %d times after returning false, want 1", calls)
+	}
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Totals are a backup's statistics across runs, so progress can be shown for the whole
+// backup instead of only the current run. The engine saves them during and after each run.
+type Totals struct {
+	Runs          int       `json:"runs"`          // runs that saved totals
+	Bytes         int64     `json:"bytes"`         // bytes copied by all runs
+	Failed        int       `json:"failed"`        // files that failed in the latest run
+	ExpectedFiles int       `json:"expectedFiles"` // files found by the latest complete scan (0 = unknown)
+	ExpectedBytes int64     `json:"expectedBytes"` // their size, when the scan knew it (0 = unknown)
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Pattern for totals (later lines win):
+// - [s] Runs: <n> | Bytes: <n> | Failed: <n> | Expected: <files> | ExpectedBytes: <n> | Updated: <RFC3339 timestamp>
+var totalsPattern = regexp.MustCompile(`^\s*-\s+\[s\]\s+Runs:\s*(\d+)\s*\|\s*Bytes:\s*(\d+)\s*\|\s*Failed:\s*(\d+)\s*\|\s*Expected:\s*(\d+)\s*\|\s*ExpectedBytes:\s*(\d+)\s*\|\s*Updated:\s*(\S+)\s*$`)
+
+// parseTotalsLine parses a totals line of the state file
+func parseTotalsLine(line string) (Totals, bool) {
+	matches := totalsPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return Totals{}, false
+	}
+	var t Totals
+	t.Runs, _ = strconv.Atoi(matches[1])
+	t.Bytes, _ = strconv.ParseInt(matches[2], 10, 64)
+	t.Failed, _ = strconv.Atoi(matches[3])
+	t.ExpectedFiles, _ = strconv.Atoi(matches[4])
+	t.ExpectedBytes, _ = strconv.ParseInt(matches[5], 10, 64)
+	t.UpdatedAt, _ = time.Parse(time.RFC3339, matches[6])
+	return t, true
+}
+
+func (t Totals) line() string {
+	return fmt.Sprintf("- [s] Runs: %d | Bytes: %d | Failed: %d | Expected: %d | ExpectedBytes: %d | Updated: %s",
+		t.Runs, t.Bytes, t.Failed, t.ExpectedFiles, t.ExpectedBytes, t.UpdatedAt.UTC().Format(time.RFC3339))
+}
+
+// Totals returns the statistics saved by the latest run (zero if no run saved any)
+func (sm *StateManager) Totals() Totals {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.totals
+}
+
+// SaveTotals records the backup's statistics and flushes them to disk, so they survive a crash
+func (sm *StateManager) SaveTotals(t Totals) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	t.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	sm.totals = t
+	if err := sm.writeLine(t.line()); err != nil {
+		return err
+	}
+	return sm.writer.Flush()
+}
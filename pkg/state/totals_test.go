@@ -0,0 +1,42 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveTotals(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gus_state.md")
+	sm, err := NewStateManager(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.SaveTotals(Totals{Runs: 1, Bytes: 100, ExpectedFiles: 10})
+	sm.MarkDone("/sdcard/DCIM/a.jpg", "hash-a", "DCIM/a.jpg")
+	sm.SaveTotals(Totals{Runs: 2, Bytes: 250, Failed: 1, ExpectedFiles: 12, ExpectedBytes: 4096})
+
+	// Saved totals are on disk right away, without Close
+	reopened, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.Totals()
+	if got.Runs != 2 || got.Bytes != 250 || got.Failed != 1 || got.ExpectedFiles != 12 || got.ExpectedBytes != 4096 || got.UpdatedAt.IsZero() {
+		t.Errorf("Totals after reload = %+v", got)
+	}
+	if s := reopened.Summary(); s.Totals != got {
+		t.Errorf("Summary().Totals = %+v, want %+v", s.Totals, got)
+	}
+
+	if _, err := sm.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	sm.Close()
+	compacted, err := OpenReadOnly(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compacted.Totals() != got {
+		t.Errorf("Totals after compaction = %+v, want %+v", compacted.Totals(), got)
+	}
+}
@@ -0,0 +1,137 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// adbTestDir is where fixture files are pushed on the device; it is removed afterwards
+const adbTestDir = "GusSyncE2E"
+
+// TestADBBackupResume runs backup -> interrupt -> resume -> verify -> cleanup against a real
+// device or emulator. Start one (e.g. `emulator -avd test -no-window`) and set
+// GUSSYNC_E2E_ADB_SERIAL to its serial to enable it.
+func TestADBBackupResume(t *testing.T) {
+	serial := os.Getenv("GUSSYNC_E2E_ADB_SERIAL")
+	if serial == "" {
+		t.Skip("GUSSYNC_E2E_ADB_SERIAL not set")
+	}
+	if _, err := exec.LookPath("adb"); err != nil {
+		t.Skip("adb not installed")
+	}
+	// The device is chosen through EngineConfig.Serial; an adb call that leaves out -s
+	// fails instead of finding the device through the environment
+	t.Setenv("ANDROID_SERIAL", "gussync-e2e-no-such-device")
+
+	phone := newFakePhone(t)
+	remote := "/sdcard/" + adbTestDir
+	adb(t, serial, "shell", "rm", "-rf", remote)
+	adb(t, serial, "push", phone.root+"/.", remote)
+	t.Cleanup(func() { exec.Command("adb", "-s", serial, "shell", "rm", "-rf", remote).Run() })
+
+	dest := newBackupDir(t)
+	total := len(phone.files)
+	run := func(ctx context.Context, pp ...engine.PostProcessor) {
+		reporter := &testReporter{t: t}
+		dest.withState(t, func(sm *state.StateManager) {
+			e := engine.NewEngine(engine.EngineConfig{
+				SourcePath:     "/sdcard",
+				DestRoot:       dest.root,
+				Mode:           "adb",
+				Serial:         serial,
+				NumWorkers:     1,
+				Reporter:       reporter,
+				ScanRoots:      []string{adbTestDir},
+				PostProcessors: pp,
+			}, sm)
+			if err := e.Run(ctx); err != nil {
+				t.Fatalf("backup: %v", err)
+			}
+		})
+		if errs := reporter.criticalErrors(); len(errs) > 0 {
+			t.Fatalf("backup reported critical errors: %v", errs)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	run(ctx, &interruptAfter{n: 3, cancel: cancel})
+	cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	run(ctx)
+
+	want := make([]string, 0, total)
+	for _, rel := range phone.relPaths() {
+		want = append(want, adbTestDir+"/"+rel)
+	}
+	if got := dest.listFiles(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("destination files:\n got %v\nwant %v", got, want)
+	}
+	for rel, data := range phone.files {
+		got, err := os.ReadFile(filepath.Join(dest.root, adbTestDir, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sha256Hex(got) != sha256Hex(data) {
+			t.Errorf("%s: destination content differs from source", rel)
+		}
+	}
+
+	dest.withState(t, func(sm *state.StateManager) {
+		results, err := engine.NewEngine(engine.EngineConfig{
+			SourcePath: "/sdcard",
+			DestRoot:   dest.root,
+			Mode:       "adb",
+			Serial:     serial,
+			NumWorkers: 1,
+			Reporter:   &testReporter{t: t},
+		}, sm).VerifyBackup(ctx)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if results.Verified != total || results.MissingDest != 0 {
+			t.Fatalf("verify results = %+v, want %d verified", results, total)
+		}
+	})
+
+	// Cleanup hashes each file on the device and removes it with adb shell rm
+	dest.withState(t, func(sm *state.StateManager) {
+		results, err := engine.NewEngine(engine.EngineConfig{
+			SourcePath: "/sdcard",
+			DestRoot:   dest.root,
+			Mode:       "adb",
+			Serial:     serial,
+			NumWorkers: 1,
+			Reporter:   &testReporter{t: t},
+		}, sm).RunCleanup(ctx)
+		if err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+		if results.Deleted != total || results.Failed != 0 {
+			t.Fatalf("cleanup results = %+v, want %d deleted", results, total)
+		}
+	})
+	for rel := range phone.files {
+		if err := exec.Command("adb", "-s", serial, "shell", "ls", "'"+remote+"/"+rel+"'").Run(); err == nil {
+			t.Errorf("%s still on device after cleanup", rel)
+		}
+	}
+}
+
+// adb runs an adb command on the device with this serial
+func adb(t *testing.T, serial string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("adb", append([]string{"-s", serial}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("adb %v: %v\n%s", args, err, out)
+	}
+}
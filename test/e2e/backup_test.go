@@ -0,0 +1,416 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newEngine(t *testing.T, phone *fakePhone, dest *backupDir, sm *state.StateManager, reporter *testReporter, pp ...engine.PostProcessor) *engine.Engine {
+	return engine.NewEngine(engine.EngineConfig{
+		SourcePath:     phone.root,
+		DestRoot:       dest.root,
+		Mode:           "mount",
+		NumWorkers:     1,
+		Reporter:       reporter,
+		PostProcessors: pp,
+	}, sm)
+}
+
+func runBackup(t *testing.T, phone *fakePhone, dest *backupDir, ctx context.Context, pp ...engine.PostProcessor) {
+	t.Helper()
+	reporter := &testReporter{t: t}
+	dest.withState(t, func(sm *state.StateManager) {
+		if err := newEngine(t, phone, dest, sm, reporter, pp...).Run(ctx); err != nil {
+			t.Fatalf("backup: %v", err)
+		}
+	})
+	if errs := reporter.criticalErrors(); len(errs) > 0 {
+		t.Fatalf("backup reported critical errors: %v", errs)
+	}
+}
+
+// runBackupWith runs a backup with extra engine options
+func runBackupWith(t *testing.T, phone *fakePhone, dest *backupDir, ctx context.Context, configure func(*engine.EngineConfig)) {
+	t.Helper()
+	reporter := &testReporter{t: t}
+	dest.withState(t, func(sm *state.StateManager) {
+		config := engine.EngineConfig{SourcePath: phone.root, DestRoot: dest.root, Mode: "mount", NumWorkers: 2, Reporter: reporter}
+		configure(&config)
+		if err := engine.NewEngine(config, sm).Run(ctx); err != nil {
+			t.Fatalf("backup: %v", err)
+		}
+	})
+	if errs := reporter.criticalErrors(); len(errs) > 0 {
+		t.Fatalf("backup reported critical errors: %v", errs)
+	}
+}
+
+// assertMirrored checks the destination holds exactly the phone's backed-up files with identical content
+func assertMirrored(t *testing.T, phone *fakePhone, dest *backupDir) {
+	t.Helper()
+	if got, want := dest.listFiles(t), phone.relPaths(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("destination files:\n got %v\nwant %v", got, want)
+	}
+	for rel, want := range phone.files {
+		got, err := os.ReadFile(filepath.Join(dest.root, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: destination content differs from source", rel)
+		}
+	}
+}
+
+// assertStateComplete checks every phone file is recorded done with its real hash
+func assertStateComplete(t *testing.T, phone *fakePhone, dest *backupDir) {
+	t.Helper()
+	dest.withState(t, func(sm *state.StateManager) {
+		completed := sm.GetAllCompletedFiles()
+		if len(completed) != len(phone.files) {
+			t.Fatalf("state has %d completed files, want %d", len(completed), len(phone.files))
+		}
+		for rel, data := range phone.files {
+			hash, ok := completed[phone.path(rel)]
+			if !ok {
+				t.Errorf("%s missing from state", rel)
+				continue
+			}
+			if hash != sha256Hex(data) {
+				t.Errorf("%s: state hash %s, want %s", rel, hash, sha256Hex(data))
+			}
+		}
+	})
+}
+
+func TestBackupInterruptResumeVerifyCleanup(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	total := len(phone.files)
+
+	// 1. Backup, interrupted part way through
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	runBackup(t, phone, dest, ctx, &interruptAfter{n: 5, cancel: cancel})
+	cancel()
+
+	dest.withState(t, func(sm *state.StateManager) {
+		done := len(sm.GetAllCompletedFiles())
+		if done < 5 || done >= total {
+			t.Fatalf("after interrupt: %d of %d files done, want a partial backup", done, total)
+		}
+	})
+	// Files cut off by the interrupt must not count against their retry budget
+	if data, err := os.ReadFile(dest.stateFile); err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(data, []byte("- [ ] ")) {
+		t.Errorf("interrupt recorded copy failures in state:\n%s", data)
+	}
+
+	// 2. Resume picks up the rest without re-copying or losing anything
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	runBackup(t, phone, dest, ctx)
+	assertMirrored(t, phone, dest)
+	assertStateComplete(t, phone, dest)
+
+	// 3. Verify passes, then catches (and repairs) a corrupted copy
+	dest.withState(t, func(sm *state.StateManager) {
+		results, err := newEngine(t, phone, dest, sm, &testReporter{t: t}).VerifyBackup(ctx)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if results.Verified != total || results.Mismatches != 0 || results.MissingDest != 0 || results.MissingSource != 0 {
+			t.Fatalf("verify results = %+v, want %d verified", results, total)
+		}
+	})
+
+	corrupted := filepath.Join(dest.root, "DCIM", "Camera", "IMG_0003.jpg")
+	if err := os.WriteFile(corrupted, []byte("bitrot"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest.withState(t, func(sm *state.StateManager) {
+		results, err := newEngine(t, phone, dest, sm, &testReporter{t: t}).VerifyBackup(ctx)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if results.Mismatches != 1 || results.Verified != total {
+			t.Fatalf("verify after corruption = %+v, want 1 mismatch repaired", results)
+		}
+	})
+	assertMirrored(t, phone, dest)
+
+	// 4. Cleanup deletes exactly the verified files from the phone
+	dest.withState(t, func(sm *state.StateManager) {
+		results, err := newEngine(t, phone, dest, sm, &testReporter{t: t}).RunCleanup(ctx)
+		if err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+		if results.Deleted != total || results.Failed != 0 {
+			t.Fatalf("cleanup results = %+v, want %d deleted", results, total)
+		}
+	})
+	for rel := range phone.files {
+		if _, err := os.Stat(phone.path(rel)); !os.IsNotExist(err) {
+			t.Errorf("%s still on phone after cleanup", rel)
+		}
+	}
+	for _, junk := range []string{"DCIM/.thumbnails/thumb_0001.jpg", "Android/data/com.example/cache/blob.cache", "Download/movie.mp4.part"} {
+		if _, err := os.Stat(phone.path(junk)); err != nil {
+			t.Errorf("cleanup touched file that was never backed up: %s", junk)
+		}
+	}
+	assertMirrored(t, phone, dest)
+
+	// 5. Cleanup is idempotent: a second run only reports already-deleted files
+	dest.withState(t, func(sm *state.StateManager) {
+		for rel := range phone.files {
+			if !sm.IsDeleted(phone.path(rel)) {
+				t.Errorf("%s not marked deleted in state", rel)
+			}
+		}
+		results, err := newEngine(t, phone, dest, sm, &testReporter{t: t}).RunCleanup(ctx)
+		if err != nil {
+			t.Fatalf("second cleanup: %v", err)
+		}
+		if results.AlreadyDeleted != total || results.Deleted != 0 {
+			t.Fatalf("second cleanup = %+v, want %d already deleted", results, total)
+		}
+	})
+}
+
+func TestIncrementalBackupCopiesOnlyNewFiles(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	runBackup(t, phone, dest, ctx)
+	assertMirrored(t, phone, dest)
+
+	// Remember when existing copies were written; a second run must not rewrite them
+	before := make(map[string]time.Time)
+	for rel := range phone.files {
+		info, err := os.Stat(filepath.Join(dest.root, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[rel] = info.ModTime()
+	}
+
+	phone.add(t, "DCIM/Camera/IMG_9999.jpg", 70*1024)
+	runBackup(t, phone, dest, ctx)
+	assertMirrored(t, phone, dest)
+	assertStateComplete(t, phone, dest)
+
+	for rel, mtime := range before {
+		info, err := os.Stat(filepath.Join(dest.root, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("%s was copied again on the incremental run", rel)
+		}
+	}
+
+	// The second run marked the directories completed (and recorded their listings);
+	// a photo taken afterwards must still be found in the completed directory
+	phone.add(t, "DCIM/Camera/IMG_10000.jpg", 50*1024)
+	runBackup(t, phone, dest, ctx)
+	assertMirrored(t, phone, dest)
+	assertStateComplete(t, phone, dest)
+}
+
+func TestManifestFirstAndFromManifest(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	runBackupWith(t, phone, dest, ctx, func(c *engine.EngineConfig) { c.ManifestFirst = true })
+	assertMirrored(t, phone, dest)
+
+	// The manifest lists every file with its size
+	var manifest state.Manifest
+	dest.withState(t, func(sm *state.StateManager) {
+		var err error
+		if manifest, err = sm.LoadManifest(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(manifest.Entries) != len(phone.files) {
+		t.Fatalf("manifest has %d files, want %d", len(manifest.Entries), len(phone.files))
+	}
+	var wantBytes int64
+	for _, data := range phone.files {
+		wantBytes += int64(len(data))
+	}
+	if manifest.TotalBytes() != wantBytes {
+		t.Errorf("manifest bytes = %d, want %d", manifest.TotalBytes(), wantBytes)
+	}
+
+	// --from-manifest does not rescan: a file added since is not picked up
+	phone.add(t, "DCIM/Camera/IMG_9999.jpg", 1024)
+	runBackupWith(t, phone, dest, ctx, func(c *engine.EngineConfig) { c.FromManifest = true })
+	if _, err := os.Stat(filepath.Join(dest.root, "DCIM", "Camera", "IMG_9999.jpg")); !os.IsNotExist(err) {
+		t.Errorf("file added after the manifest was copied by a --from-manifest run")
+	}
+}
+
+func TestIncrementalScanSkipsUnchangedDirectories(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	incremental := func(c *engine.EngineConfig) { c.IncrementalScan = true }
+
+	// Directory mtimes must be safely in the past before they are recorded
+	backdate := func() {
+		old := time.Now().Add(-time.Hour)
+		filepath.Walk(phone.root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() {
+				os.Chtimes(path, old, old)
+			}
+			return nil
+		})
+	}
+	backdate()
+	runBackupWith(t, phone, dest, ctx, incremental) // copies everything
+	runBackupWith(t, phone, dest, ctx, incremental) // marks the directories completed and records their stamps
+	assertMirrored(t, phone, dest)
+
+	// A file slipped into a directory without changing its mtime proves the listing is skipped
+	camera := phone.path("DCIM/Camera")
+	info, _ := os.Stat(camera)
+	phone.write(t, "DCIM/Camera/hidden.jpg", 1024)
+	os.Chtimes(camera, info.ModTime(), info.ModTime())
+	runBackupWith(t, phone, dest, ctx, incremental)
+	if _, err := os.Stat(filepath.Join(dest.root, "DCIM", "Camera", "hidden.jpg")); !os.IsNotExist(err) {
+		t.Fatalf("unchanged directory was listed again")
+	}
+
+	// A new photo changes the mtime, so the directory is read again
+	phone.add(t, "DCIM/Camera/IMG_9999.jpg", 2048)
+	runBackupWith(t, phone, dest, ctx, incremental)
+	if _, err := os.Stat(filepath.Join(dest.root, "DCIM", "Camera", "IMG_9999.jpg")); err != nil {
+		t.Errorf("new file in a changed directory was not copied: %v", err)
+	}
+
+	// Subdirectories of an unchanged directory are still visited: a new screenshot changes
+	// Pictures/Screenshots' mtime but not that of Pictures
+	phone.add(t, "Pictures/Screenshots/Screenshot_2.png", 2048)
+	runBackupWith(t, phone, dest, ctx, incremental)
+	if _, err := os.Stat(filepath.Join(dest.root, "Pictures", "Screenshots", "Screenshot_2.png")); err != nil {
+		t.Errorf("new file below an unchanged directory was not copied: %v", err)
+	}
+}
+
+func TestWatchCopiesNewFiles(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// waitFor polls until rel shows up in the destination
+	waitFor := func(rel string) {
+		t.Helper()
+		deadline := time.Now().Add(20 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(filepath.Join(dest.root, filepath.FromSlash(rel))); err == nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("%s was not copied while watching", rel)
+	}
+
+	reporter := &testReporter{t: t}
+	dest.withState(t, func(sm *state.StateManager) {
+		watchCtx, stop := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- engine.NewEngine(engine.EngineConfig{
+				SourcePath: phone.root,
+				DestRoot:   dest.root,
+				Mode:       "mount",
+				NumWorkers: 2,
+				Reporter:   reporter,
+				Watch:      engine.WatchOptions{Settle: 200 * time.Millisecond, PollInterval: -1},
+			}, sm).Watch(watchCtx)
+		}()
+		defer func() {
+			stop()
+			if err := <-done; err != nil {
+				t.Errorf("watch: %v", err)
+			}
+		}()
+
+		// Initial backup
+		waitFor("DCIM/Camera/IMG_0000.jpg")
+
+		phone.add(t, "DCIM/Camera/IMG_9999.jpg", 70*1024)
+		waitFor("DCIM/Camera/IMG_9999.jpg")
+
+		// A folder created while watching is watched too
+		phone.add(t, "Pictures/Holiday/beach.jpg", 4096)
+		waitFor("Pictures/Holiday/beach.jpg")
+		phone.add(t, "Pictures/Holiday/sunset.jpg", 4096)
+		waitFor("Pictures/Holiday/sunset.jpg")
+	})
+	if errs := reporter.criticalErrors(); len(errs) > 0 {
+		t.Fatalf("watch reported critical errors: %v", errs)
+	}
+	assertMirrored(t, phone, dest)
+	assertStateComplete(t, phone, dest)
+}
+
+func TestBackupHooks(t *testing.T) {
+	phone := newFakePhone(t)
+	dest := newBackupDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	out := filepath.Join(t.TempDir(), "hooks.log")
+
+	// A failing pre_backup hook keeps the backup from starting
+	dest.withState(t, func(sm *state.StateManager) {
+		err := engine.NewEngine(engine.EngineConfig{
+			SourcePath: phone.root, DestRoot: dest.root, Mode: "mount", Reporter: &testReporter{t: t},
+			Hooks: engine.Hooks{PreBackup: "exit 3", PostBackup: "echo ran >> " + out},
+		}, sm).Run(ctx)
+		if err == nil {
+			t.Fatal("backup ran despite a failing pre_backup hook")
+		}
+	})
+	if files := dest.listFiles(t); len(files) != 0 {
+		t.Fatalf("files copied despite a failing pre_backup hook: %v", files)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("post_backup hook ran for a backup that never started")
+	}
+
+	runBackupWith(t, phone, dest, ctx, func(c *engine.EngineConfig) {
+		c.Hooks = engine.Hooks{
+			PreBackup:  `echo "pre $GUSSYNC_HOOK $GUSSYNC_MODE" >> ` + out,
+			PostBackup: `echo "post $GUSSYNC_STATUS $GUSSYNC_COMPLETED $GUSSYNC_FAILED" >> ` + out,
+		}
+	})
+	assertMirrored(t, phone, dest)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pre pre_backup mount\n" + fmt.Sprintf("post success %d 0\n", len(phone.files))
+	if string(data) != want {
+		t.Errorf("hook output:\n%s\nwant:\n%s", data, want)
+	}
+}
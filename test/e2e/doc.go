@@ -0,0 +1,9 @@
+// Package e2e holds end-to-end tests that drive the engine, state and cleanup
+// together against a fake phone (a generated /sdcard-like tree accessed in mount
+// mode) or, when GUSSYNC_E2E_ADB_SERIAL is set, a real device or emulator over adb.
+//
+// The tests are behind the e2e build tag:
+//
+//	make test-e2e
+//	go test -tags e2e ./test/e2e/...
+package e2e
@@ -0,0 +1,184 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"GusSync/pkg/engine"
+	"GusSync/pkg/state"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// fakePhone is a directory laid out like an Android /sdcard
+type fakePhone struct {
+	root  string
+	files map[string][]byte // rel path -> content, for files the backup should copy
+}
+
+// newFakePhone creates a phone tree with photos, videos, documents and the
+// kind of junk the engine must skip (caches, thumbnails, app data)
+func newFakePhone(t *testing.T) *fakePhone {
+	t.Helper()
+	p := &fakePhone{root: filepath.Join(t.TempDir(), "sdcard"), files: make(map[string][]byte)}
+
+	for i := 0; i < 12; i++ {
+		p.add(t, fmt.Sprintf("DCIM/Camera/IMG_%04d.jpg", i), 64*1024+i)
+	}
+	for i := 0; i < 3; i++ {
+		p.add(t, fmt.Sprintf("DCIM/Camera/VID_%04d.mp4", i), 512*1024+i)
+	}
+	p.add(t, "Pictures/Screenshots/Screenshot_1.png", 20*1024)
+	p.add(t, "Documents/notes.txt", 300)
+	p.add(t, "Download/manual.pdf", 40*1024)
+
+	// Skipped by the built-in exclusions
+	p.write(t, "DCIM/.thumbnails/thumb_0001.jpg", 512)
+	p.write(t, "Android/data/com.example/cache/blob.cache", 1024)
+	p.write(t, "Download/movie.mp4.part", 2048)
+	p.write(t, "DCIM/Camera/.nomedia", 0)
+	return p
+}
+
+// add writes a file the backup is expected to copy
+func (p *fakePhone) add(t *testing.T, rel string, size int) {
+	t.Helper()
+	p.files[rel] = p.write(t, rel, size)
+}
+
+func (p *fakePhone) write(t *testing.T, rel string, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte((i*31 + len(rel)) % 251)
+	}
+	path := filepath.Join(p.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func (p *fakePhone) path(rel string) string {
+	return filepath.Join(p.root, filepath.FromSlash(rel))
+}
+
+// relPaths returns the expected file list, sorted
+func (p *fakePhone) relPaths() []string {
+	paths := make([]string, 0, len(p.files))
+	for rel := range p.files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// backupDir is the destination side of a test run: dest root plus its state file
+type backupDir struct {
+	root      string
+	stateFile string
+}
+
+func newBackupDir(t *testing.T) *backupDir {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "backup", "mount")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &backupDir{root: root, stateFile: filepath.Join(root, "gus_state.md")}
+}
+
+// withState loads the state file, runs fn and closes it again, like one CLI invocation
+func (b *backupDir) withState(t *testing.T, fn func(sm *state.StateManager)) {
+	t.Helper()
+	sm, err := state.NewStateManager(b.stateFile)
+	if err != nil {
+		t.Fatalf("open state: %v", err)
+	}
+	defer sm.Close()
+	fn(sm)
+}
+
+// listFiles returns every regular file under the backup root except the state
+// and log files, as slash-separated relative paths
+func (b *backupDir) listFiles(t *testing.T) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(b.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Dir(path) == b.root {
+			return nil
+		}
+		rel, _ := filepath.Rel(b.root, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// testReporter records engine output so failures can be explained
+type testReporter struct {
+	t      *testing.T
+	mu     sync.Mutex
+	errors []error
+}
+
+func (r *testReporter) ReportProgress(update engine.ProgressUpdate) {}
+
+func (r *testReporter) ReportError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, err)
+	r.t.Logf("engine error: %v", err)
+}
+
+func (r *testReporter) ReportLog(level, message string) {
+	r.t.Logf("[%s] %s", level, message)
+}
+
+func (r *testReporter) criticalErrors() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errors...)
+}
+
+// interruptAfter is a post-processor that cancels the run once n files have been
+// copied, simulating the user hitting Ctrl+C (or the cable dropping) mid-backup
+type interruptAfter struct {
+	n      int
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	seen   int
+}
+
+func (p *interruptAfter) Name() string { return "interrupt" }
+
+func (p *interruptAfter) Process(ctx context.Context, file engine.PostCopyFile) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seen++
+	if p.seen == p.n {
+		p.cancel()
+	}
+	return "", nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}